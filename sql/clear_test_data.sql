@@ -13,6 +13,16 @@ BEGIN TRANSACTION;
 -- ================================
 DELETE FROM user_info;
 DELETE FROM api_key;
+DELETE FROM app_password;
+DELETE FROM user_session;
+DELETE FROM refresh_token;
+DELETE FROM user_two_factor;
+DELETE FROM user_identity;
+DELETE FROM security_event;
+DELETE FROM audit_log;
+DELETE FROM password_history;
+DELETE FROM user_token;
+DELETE FROM invitation;
 
 -- ================================
 -- 2. 删除文件相关数据
@@ -21,6 +31,14 @@ DELETE FROM user_files;
 DELETE FROM file_info;
 DELETE FROM file_chunk;
 DELETE FROM virtual_path;
+DELETE FROM webdav_lock;
+DELETE FROM encrypt_policy;
+DELETE FROM webdav_property;
+DELETE FROM search_posting;
+DELETE FROM search_doc;
+DELETE FROM search_content;
+DELETE FROM smart_folder;
+DELETE FROM file_metadata;
 
 -- ================================
 -- 3. 删除上传下载任务数据
@@ -48,6 +66,14 @@ DELETE FROM sys_config;
 DELETE FROM sqlite_sequence WHERE name IN (
     'user_info',
     'api_key',
+    'app_password',
+    'user_identity',
+    'security_event',
+    'audit_log',
+    'password_history',
+    'refresh_token',
+    'user_token',
+    'invitation',
     'user_files',
     'file_info',
     'file_chunk',
@@ -58,7 +84,9 @@ DELETE FROM sqlite_sequence WHERE name IN (
     'shares',
     'recycled',
     'disk',
-    'sys_config'
+    'sys_config',
+    'encrypt_policy',
+    'webdav_property'
 );
 
 -- ================================
@@ -13,6 +13,16 @@ START TRANSACTION;
 -- ================================
 DELETE FROM `user_info`;
 DELETE FROM `api_key`;
+DELETE FROM `app_password`;
+DELETE FROM `user_session`;
+DELETE FROM `refresh_token`;
+DELETE FROM `user_two_factor`;
+DELETE FROM `user_identity`;
+DELETE FROM `security_event`;
+DELETE FROM `audit_log`;
+DELETE FROM `password_history`;
+DELETE FROM `user_token`;
+DELETE FROM `invitation`;
 
 -- ================================
 -- 2. 删除文件相关数据
@@ -21,6 +31,14 @@ DELETE FROM `user_files`;
 DELETE FROM `file_info`;
 DELETE FROM `file_chunk`;
 DELETE FROM `virtual_path`;
+DELETE FROM `webdav_lock`;
+DELETE FROM `encrypt_policy`;
+DELETE FROM `webdav_property`;
+DELETE FROM `search_posting`;
+DELETE FROM `search_doc`;
+DELETE FROM `search_content`;
+DELETE FROM `smart_folder`;
+DELETE FROM `file_metadata`;
 
 -- ================================
 -- 3. 删除上传下载任务数据
@@ -46,9 +64,19 @@ DELETE FROM `sys_config`;
 -- ================================
 -- MySQL 的自增主键重置
 ALTER TABLE `api_key` AUTO_INCREMENT = 1;
+ALTER TABLE `app_password` AUTO_INCREMENT = 1;
+ALTER TABLE `user_identity` AUTO_INCREMENT = 1;
+ALTER TABLE `security_event` AUTO_INCREMENT = 1;
+ALTER TABLE `audit_log` AUTO_INCREMENT = 1;
+ALTER TABLE `password_history` AUTO_INCREMENT = 1;
+ALTER TABLE `refresh_token` AUTO_INCREMENT = 1;
+ALTER TABLE `user_token` AUTO_INCREMENT = 1;
+ALTER TABLE `invitation` AUTO_INCREMENT = 1;
 ALTER TABLE `virtual_path` AUTO_INCREMENT = 1;
 ALTER TABLE `shares` AUTO_INCREMENT = 1;
 ALTER TABLE `sys_config` AUTO_INCREMENT = 1;
+ALTER TABLE `encrypt_policy` AUTO_INCREMENT = 1;
+ALTER TABLE `webdav_property` AUTO_INCREMENT = 1;
 
 -- ================================
 -- 7. 保留的表（不做任何操作）
@@ -18,6 +18,14 @@ DROP TABLE IF EXISTS `groups`;
 DROP TABLE IF EXISTS `user_files`;
 DROP TABLE IF EXISTS `file_chunk`;
 DROP TABLE IF EXISTS `virtual_path`;
+DROP TABLE IF EXISTS `webdav_lock`;
+DROP TABLE IF EXISTS `encrypt_policy`;
+DROP TABLE IF EXISTS `webdav_property`;
+DROP TABLE IF EXISTS `search_posting`;
+DROP TABLE IF EXISTS `search_doc`;
+DROP TABLE IF EXISTS `search_content`;
+DROP TABLE IF EXISTS `smart_folder`;
+DROP TABLE IF EXISTS `file_metadata`;
 DROP TABLE IF EXISTS `upload_chunk`;
 DROP TABLE IF EXISTS `upload_task`;
 DROP TABLE IF EXISTS `download_task`;
@@ -25,6 +33,16 @@ DROP TABLE IF EXISTS `shares`;
 DROP TABLE IF EXISTS `recycled`;
 DROP TABLE IF EXISTS `disk`;
 DROP TABLE IF EXISTS `sys_config`;
+DROP TABLE IF EXISTS `audit_log`;
+DROP TABLE IF EXISTS `invitation`;
+DROP TABLE IF EXISTS `user_token`;
+DROP TABLE IF EXISTS `password_history`;
+DROP TABLE IF EXISTS `security_event`;
+DROP TABLE IF EXISTS `user_identity`;
+DROP TABLE IF EXISTS `user_two_factor`;
+DROP TABLE IF EXISTS `refresh_token`;
+DROP TABLE IF EXISTS `user_session`;
+DROP TABLE IF EXISTS `app_password`;
 DROP TABLE IF EXISTS `api_key`;
 DROP TABLE IF EXISTS `user_info`;
 DROP TABLE IF EXISTS `file_info`;
@@ -51,6 +69,8 @@ CREATE TABLE `groups` (
     `created_at` DATETIME NOT NULL COMMENT '创建时间',
     `group_default` INT NOT NULL COMMENT '是否为默认组 0-否 1-是',
     `space` BIGINT DEFAULT NULL COMMENT '组默认可用存储空间',
+    `require_2fa` TINYINT(1) DEFAULT 0 COMMENT '是否要求组内用户开启两步验证',
+    `disable_local_login` TINYINT(1) DEFAULT 0 COMMENT '是否禁止组内用户使用本地密码登录',
     PRIMARY KEY (`id`),
     UNIQUE KEY `uk_id` (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='组表';
@@ -80,7 +100,11 @@ CREATE TABLE `user_info` (
     `space` BIGINT DEFAULT NULL COMMENT '用户可用存储空间',
     `file_password` TEXT DEFAULT NULL COMMENT '用户文件密码',
     `free_space` BIGINT DEFAULT NULL COMMENT '用户剩余存储空间',
-    `state` INT NOT NULL DEFAULT 0 COMMENT '用户状态 0正常 1禁用',
+    `state` INT NOT NULL DEFAULT 0 COMMENT '用户状态 0正常 1禁用 2待验证邮箱',
+    `auth_source` VARCHAR(16) DEFAULT '' COMMENT '账户来源 空-本地账户 ldap-LDAP目录账户 oidc-单点登录账户',
+    `password_changed_at` DATETIME DEFAULT NULL COMMENT '登录密码修改时间',
+    `file_password_changed_at` DATETIME DEFAULT NULL COMMENT '文件密码修改时间',
+    `email_verified_at` DATETIME DEFAULT NULL COMMENT '邮箱验证时间',
     PRIMARY KEY (`id`),
     UNIQUE KEY `uk_id` (`id`),
     KEY `idx_group_id` (`group_id`)
@@ -94,11 +118,174 @@ CREATE TABLE `api_key` (
     `expires_at` DATETIME DEFAULT NULL COMMENT '过期时间',
     `created_at` DATETIME NOT NULL COMMENT '创建时间',
     `private_key` TEXT NOT NULL COMMENT '私钥',
+    `name` VARCHAR(255) DEFAULT NULL COMMENT '名称',
+    `scopes` TEXT DEFAULT NULL COMMENT '允许的权限标识（逗号分隔，为空表示继承用户组全部权限）',
+    `root_dir_id` INT NOT NULL DEFAULT 0 COMMENT '可访问的目录ID（0表示整个空间）',
+    `allowed_ips` TEXT DEFAULT NULL COMMENT '允许使用的IP或网段（逗号分隔，为空表示不限制）',
+    `last_used_at` DATETIME DEFAULT NULL COMMENT '最近使用时间',
+    `last_used_ip` VARCHAR(64) DEFAULT NULL COMMENT '最近使用IP',
+    `usage_count` BIGINT NOT NULL DEFAULT 0 COMMENT '累计调用次数',
     PRIMARY KEY (`id`),
     UNIQUE KEY `uk_id` (`id`),
     KEY `idx_user_id` (`user_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='API密钥表';
 
+-- 应用专用密码表（WebDAV、SFTP 等同步协议使用，不能用于网页登录）
+CREATE TABLE `app_password` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '应用专用密码ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `name` VARCHAR(255) NOT NULL COMMENT '名称（设备或客户端名称）',
+    `password_hash` VARCHAR(64) NOT NULL COMMENT '密码哈希（SHA-256）',
+    `prefix` VARCHAR(16) DEFAULT NULL COMMENT '密码前缀（用于列表展示）',
+    `read_only` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否只读',
+    `root_dir_id` INT NOT NULL DEFAULT 0 COMMENT '可访问的目录ID（0表示整个空间）',
+    `last_used_at` DATETIME DEFAULT NULL COMMENT '最近使用时间',
+    `last_used_ip` VARCHAR(64) DEFAULT NULL COMMENT '最近使用IP',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_app_password_password_hash` (`password_hash`),
+    KEY `idx_app_password_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='应用专用密码表';
+
+-- 用户登录会话表（按用户索引，用于会话列表与定向注销）
+CREATE TABLE `user_session` (
+    `id` VARCHAR(64) NOT NULL COMMENT '会话ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `token_hash` VARCHAR(64) NOT NULL COMMENT '当前访问令牌哈希（SHA-256）',
+    `device` VARCHAR(128) DEFAULT NULL COMMENT '设备',
+    `ip` VARCHAR(64) DEFAULT NULL COMMENT '最近访问IP',
+    `user_agent` VARCHAR(512) DEFAULT NULL COMMENT 'User-Agent',
+    `created_at` DATETIME DEFAULT NULL COMMENT '登录时间',
+    `last_seen_at` DATETIME DEFAULT NULL COMMENT '最近访问时间',
+    `expires_at` DATETIME DEFAULT NULL COMMENT '过期时间',
+    `absolute_expires_at` DATETIME DEFAULT NULL COMMENT '绝对过期时间',
+    `remember_me` TINYINT(1) DEFAULT 0 COMMENT '是否记住登录',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_session_token_hash` (`token_hash`),
+    KEY `idx_user_session_user_id` (`user_id`),
+    KEY `idx_user_session_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户登录会话表';
+
+-- 刷新令牌表（已轮换的令牌保留到会话结束，用于发现重复使用）
+CREATE TABLE `refresh_token` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '令牌ID',
+    `session_id` VARCHAR(64) NOT NULL COMMENT '会话ID',
+    `token_hash` VARCHAR(64) NOT NULL COMMENT '刷新令牌哈希（SHA-256）',
+    `created_at` DATETIME DEFAULT NULL COMMENT '签发时间',
+    `rotated_at` DATETIME DEFAULT NULL COMMENT '轮换时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_refresh_token_token_hash` (`token_hash`),
+    KEY `idx_refresh_token_session_id` (`session_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='刷新令牌表';
+
+-- 用户两步验证表（TOTP 密钥与一次性恢复码）
+CREATE TABLE `user_two_factor` (
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `secret` VARCHAR(64) NOT NULL COMMENT 'TOTP密钥（Base32）',
+    `enabled` TINYINT(1) DEFAULT 0 COMMENT '是否已启用',
+    `recovery_codes` TEXT DEFAULT NULL COMMENT '未使用的恢复码哈希（JSON数组）',
+    `last_step` BIGINT DEFAULT 0 COMMENT '最近验证通过的时间步',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    `enabled_at` DATETIME DEFAULT NULL COMMENT '启用时间',
+    PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户两步验证表';
+
+-- 外部身份绑定表（OIDC 单点登录，issuer + subject 唯一）
+CREATE TABLE `user_identity` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '绑定ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `issuer` VARCHAR(255) NOT NULL COMMENT '身份提供方',
+    `subject` VARCHAR(255) NOT NULL COMMENT '身份提供方中的用户标识',
+    `username` VARCHAR(255) DEFAULT NULL COMMENT '绑定时的用户名',
+    `email` VARCHAR(255) DEFAULT NULL COMMENT '绑定时的邮箱',
+    `created_at` DATETIME DEFAULT NULL COMMENT '绑定时间',
+    `last_login_at` DATETIME DEFAULT NULL COMMENT '最近登录时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_identity_subject` (`issuer`, `subject`),
+    KEY `idx_user_identity_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='外部身份绑定表';
+
+-- 安全事件日志表（登录失败、锁定与解锁）
+CREATE TABLE `security_event` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '事件ID',
+    `type` VARCHAR(32) NOT NULL COMMENT '事件类型',
+    `channel` VARCHAR(16) DEFAULT NULL COMMENT '来源渠道',
+    `target` VARCHAR(255) DEFAULT NULL COMMENT '事件对象（用户名或分享token）',
+    `ip` VARCHAR(64) DEFAULT NULL COMMENT '客户端IP',
+    `detail` VARCHAR(512) DEFAULT NULL COMMENT '详细说明',
+    `created_at` DATETIME DEFAULT NULL COMMENT '发生时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_security_event_type` (`type`),
+    KEY `idx_security_event_target` (`target`),
+    KEY `idx_security_event_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='安全事件日志表';
+
+-- 审计日志表
+CREATE TABLE `audit_log` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '日志ID',
+    `actor_id` VARCHAR(64) DEFAULT NULL COMMENT '操作人用户ID',
+    `actor_name` VARCHAR(255) DEFAULT NULL COMMENT '操作人用户名',
+    `api_key_id` INT DEFAULT NULL COMMENT 'API Key ID',
+    `source` VARCHAR(16) DEFAULT NULL COMMENT '来源',
+    `action` VARCHAR(64) NOT NULL COMMENT '操作',
+    `target_type` VARCHAR(32) DEFAULT NULL COMMENT '操作对象类型',
+    `target_id` VARCHAR(512) DEFAULT NULL COMMENT '操作对象ID或路径',
+    `detail` VARCHAR(1024) DEFAULT NULL COMMENT '详细说明',
+    `ip` VARCHAR(64) DEFAULT NULL COMMENT '客户端IP',
+    `result` VARCHAR(16) DEFAULT NULL COMMENT '结果',
+    `created_at` DATETIME DEFAULT NULL COMMENT '操作时间',
+    `prev_hash` VARCHAR(64) DEFAULT NULL COMMENT '上一条记录的哈希',
+    `hash` VARCHAR(64) DEFAULT NULL COMMENT '本条记录的哈希',
+    PRIMARY KEY (`id`),
+    KEY `idx_audit_log_actor_id` (`actor_id`),
+    KEY `idx_audit_log_action` (`action`),
+    KEY `idx_audit_log_target_type` (`target_type`),
+    KEY `idx_audit_log_result` (`result`),
+    KEY `idx_audit_log_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='审计日志表';
+
+-- 历史密码表
+CREATE TABLE `password_history` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '记录ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `kind` VARCHAR(16) NOT NULL COMMENT '密码类型 login-登录密码 file-文件密码',
+    `password_hash` TEXT NOT NULL COMMENT '密码哈希',
+    `created_at` DATETIME DEFAULT NULL COMMENT '设置时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_password_history_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='历史密码表';
+
+-- 邮件一次性令牌表（邮箱验证、重置密码，使用后删除）
+CREATE TABLE `user_token` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '令牌ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `kind` VARCHAR(32) NOT NULL COMMENT '令牌类型 verify_email-邮箱验证 reset_password-重置密码',
+    `token_hash` VARCHAR(64) NOT NULL COMMENT '令牌哈希（SHA-256）',
+    `email` VARCHAR(255) DEFAULT NULL COMMENT '邮件发送到的邮箱',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    `expires_at` DATETIME DEFAULT NULL COMMENT '过期时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_token_token_hash` (`token_hash`),
+    KEY `idx_user_token_user_id` (`user_id`),
+    KEY `idx_user_token_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='邮件一次性令牌表';
+
+-- 注册邀请表
+CREATE TABLE `invitation` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '邀请ID',
+    `email` VARCHAR(255) NOT NULL COMMENT '受邀邮箱',
+    `group_id` INT NOT NULL COMMENT '预设用户组ID',
+    `space` BIGINT DEFAULT 0 COMMENT '预设存储空间（字节，0 表示使用用户组的空间）',
+    `token_hash` VARCHAR(64) NOT NULL COMMENT '令牌哈希（SHA-256）',
+    `created_by` VARCHAR(64) DEFAULT NULL COMMENT '邀请人用户ID',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    `expires_at` DATETIME DEFAULT NULL COMMENT '过期时间',
+    `used_at` DATETIME DEFAULT NULL COMMENT '使用时间',
+    `used_by` VARCHAR(64) DEFAULT NULL COMMENT '通过邀请注册的用户ID',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_invitation_token_hash` (`token_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='注册邀请表';
+
 -- ================================
 -- 4. 创建文件相关表
 -- ================================
@@ -110,6 +297,7 @@ CREATE TABLE `file_info` (
     `random_name` VARCHAR(255) NOT NULL COMMENT '文件存储名（随机生成）',
     `size` BIGINT NOT NULL COMMENT '文件大小',
     `mime` VARCHAR(255) NOT NULL COMMENT '文件MIME类型',
+    `category` VARCHAR(16) DEFAULT NULL COMMENT '文件分类（image、video、audio、document、archive、code、other）',
     `thumbnail_img` TEXT DEFAULT NULL COMMENT '缩略图路径',
     `path` TEXT DEFAULT NULL COMMENT '文件实际存储路径',
     `file_hash` TEXT NOT NULL COMMENT '文件哈希值（全量hash）',
@@ -130,6 +318,7 @@ CREATE TABLE `file_info` (
     KEY `idx_file_hash` (`file_hash`(255)),
     KEY `idx_chunk_signature` (`chunk_signature`(255)),
     KEY `idx_mime` (`mime`),
+    KEY `file_info_category_index` (`category`),
     KEY `idx_name` (`name`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='文件信息表';
 
@@ -171,11 +360,128 @@ CREATE TABLE `virtual_path` (
     `parent_level` TEXT DEFAULT NULL COMMENT '父级层级信息',
     `created_time` DATETIME NOT NULL COMMENT '创建时间',
     `update_time` DATETIME NOT NULL COMMENT '更新时间',
+    `deleted_at` DATETIME DEFAULT NULL COMMENT '删除时间（移入回收站）',
     PRIMARY KEY (`id`),
     UNIQUE KEY `uk_id` (`id`),
-    KEY `idx_user_id` (`user_id`)
+    KEY `idx_user_id` (`user_id`),
+    KEY `idx_virtual_path_deleted_at` (`deleted_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='虚拟路径表';
 
+-- WebDAV 锁表
+CREATE TABLE `webdav_lock` (
+    `token` VARCHAR(128) NOT NULL COMMENT '锁令牌',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `root` VARCHAR(1024) NOT NULL COMMENT '锁定的资源路径',
+    `shared` TINYINT(1) DEFAULT 0 COMMENT '是否为共享锁',
+    `zero_depth` TINYINT(1) DEFAULT 0 COMMENT '是否只锁定资源本身（Depth: 0）',
+    `owner_xml` TEXT DEFAULT NULL COMMENT '锁持有者信息',
+    `timeout` BIGINT NOT NULL COMMENT '超时时长（秒）',
+    `expires_at` DATETIME DEFAULT NULL COMMENT '过期时间',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    PRIMARY KEY (`token`),
+    KEY `idx_user_id` (`user_id`),
+    KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='WebDAV 锁表';
+
+-- 写入加密策略表
+CREATE TABLE `encrypt_policy` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '策略ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `dir_id` INT NOT NULL DEFAULT 0 COMMENT '目录ID（0 表示用户所有目录）',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='写入加密策略表';
+
+-- WebDAV 自定义属性表
+CREATE TABLE `webdav_property` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '属性ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `path` VARCHAR(1024) NOT NULL COMMENT '资源路径',
+    `path_hash` CHAR(64) NOT NULL COMMENT '资源路径的 SHA-256',
+    `namespace` VARCHAR(255) NOT NULL COMMENT '属性命名空间',
+    `name` VARCHAR(255) NOT NULL COMMENT '属性名',
+    `lang` VARCHAR(32) DEFAULT NULL COMMENT 'xml:lang',
+    `inner_xml` TEXT DEFAULT NULL COMMENT '属性值（原始 XML）',
+    `updated_at` DATETIME DEFAULT NULL COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    KEY `webdav_property_user_path_index` (`user_id`, `path_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='WebDAV 自定义属性表';
+
+-- 全文索引：文件内容提取结果表（按文件信息保存，秒传与复制出的文件共享）
+CREATE TABLE `search_content` (
+    `file_id` VARCHAR(64) NOT NULL COMMENT '文件信息ID',
+    `status` VARCHAR(16) NOT NULL COMMENT '提取状态（ok、empty、unsupported、encrypted、too_large、failed）',
+    `content` MEDIUMTEXT DEFAULT NULL COMMENT '提取出的文本',
+    `updated_at` DATETIME DEFAULT NULL COMMENT '提取时间',
+    PRIMARY KEY (`file_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='全文索引文件内容表';
+
+-- 全文索引：文档表（一个用户文件一条）
+CREATE TABLE `search_doc` (
+    `uf_id` VARCHAR(64) NOT NULL COMMENT '用户文件ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `file_id` VARCHAR(64) NOT NULL COMMENT '文件信息ID',
+    `name` VARCHAR(255) DEFAULT NULL COMMENT '建立索引时的文件名',
+    `category` VARCHAR(16) DEFAULT NULL COMMENT '文件分类',
+    `size` BIGINT DEFAULT 0 COMMENT '文件大小',
+    `length` INT DEFAULT 0 COMMENT '文档长度（词条总数）',
+    `indexed_at` DATETIME DEFAULT NULL COMMENT '建立索引时间',
+    PRIMARY KEY (`uf_id`),
+    KEY `idx_search_doc_user_id` (`user_id`),
+    KEY `idx_search_doc_file_id` (`file_id`),
+    KEY `idx_search_doc_category` (`category`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='全文索引文档表';
+
+-- 全文索引：倒排表（词条区分大小写与重音，使用 utf8mb4_bin）
+CREATE TABLE `search_posting` (
+    `term` VARCHAR(64) COLLATE utf8mb4_bin NOT NULL COMMENT '词条',
+    `uf_id` VARCHAR(64) NOT NULL COMMENT '用户文件ID',
+    `name_tf` INT DEFAULT 0 COMMENT '在文件名中出现的次数',
+    `content_tf` INT DEFAULT 0 COMMENT '在内容中出现的次数',
+    PRIMARY KEY (`term`, `uf_id`),
+    KEY `idx_search_posting_uf_id` (`uf_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='全文索引倒排表';
+
+-- 智能文件夹表（保存的文件过滤条件）
+CREATE TABLE `smart_folder` (
+    `id` INT NOT NULL AUTO_INCREMENT COMMENT '智能文件夹ID',
+    `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
+    `name` VARCHAR(255) NOT NULL COMMENT '名称',
+    `filter` TEXT NOT NULL COMMENT '过滤与排序条件（JSON）',
+    `created_at` DATETIME DEFAULT NULL COMMENT '创建时间',
+    `updated_at` DATETIME DEFAULT NULL COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_smart_folder_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='智能文件夹表';
+
+-- 媒体元数据表（按文件信息保存，秒传与复制出的文件共享）
+CREATE TABLE `file_metadata` (
+    `file_id` VARCHAR(64) NOT NULL COMMENT '文件信息ID',
+    `status` VARCHAR(16) NOT NULL COMMENT '提取状态（ok、unsupported、encrypted、failed）',
+    `kind` VARCHAR(16) DEFAULT NULL COMMENT '类型（image、audio、video）',
+    `width` INT DEFAULT NULL COMMENT '宽度（图片按 EXIF 方向校正后的显示宽度）',
+    `height` INT DEFAULT NULL COMMENT '高度',
+    `duration` DOUBLE DEFAULT NULL COMMENT '时长（秒）',
+    `taken_at` DATETIME DEFAULT NULL COMMENT '拍摄时间（EXIF）',
+    `taken_date` VARCHAR(10) DEFAULT NULL COMMENT '时间线日期（yyyy-mm-dd）',
+    `camera_make` VARCHAR(64) DEFAULT NULL COMMENT '相机厂商',
+    `camera_model` VARCHAR(128) DEFAULT NULL COMMENT '相机型号',
+    `orientation` INT DEFAULT NULL COMMENT 'EXIF 方向（1-8）',
+    `latitude` DOUBLE DEFAULT NULL COMMENT '纬度',
+    `longitude` DOUBLE DEFAULT NULL COMMENT '经度',
+    `title` VARCHAR(255) DEFAULT NULL COMMENT '标题（音频标签）',
+    `artist` VARCHAR(255) DEFAULT NULL COMMENT '艺术家',
+    `album` VARCHAR(255) DEFAULT NULL COMMENT '专辑',
+    `genre` VARCHAR(64) DEFAULT NULL COMMENT '流派',
+    `year` INT DEFAULT NULL COMMENT '年份',
+    `track` INT DEFAULT NULL COMMENT '音轨号',
+    `extracted_at` DATETIME DEFAULT NULL COMMENT '提取时间',
+    PRIMARY KEY (`file_id`),
+    KEY `idx_file_metadata_kind` (`kind`),
+    KEY `idx_file_metadata_taken_date` (`taken_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='媒体元数据表';
+
 -- ================================
 -- 5. 创建上传下载任务表
 -- ================================
@@ -269,10 +575,14 @@ CREATE TABLE `recycled` (
     `file_id` VARCHAR(64) NOT NULL COMMENT '文件ID',
     `user_id` VARCHAR(64) NOT NULL COMMENT '用户ID',
     `created_at` DATETIME NOT NULL COMMENT '删除时间',
+    `dir_id` INT DEFAULT 0 COMMENT '目录ID（目录条目）',
+    `parent_id` VARCHAR(64) DEFAULT '' COMMENT '所属目录条目ID（随目录一起删除的文件和子目录）',
+    `original_path` TEXT DEFAULT NULL COMMENT '删除前的完整路径',
     PRIMARY KEY (`id`),
     UNIQUE KEY `uk_id` (`id`),
     KEY `idx_user_id` (`user_id`),
-    KEY `idx_file_id` (`file_id`)
+    KEY `idx_file_id` (`file_id`),
+    KEY `idx_parent_id` (`parent_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='回收站表';
 
 -- ================================
@@ -335,7 +645,8 @@ INSERT INTO `power` (`id`, `name`, `description`, `created_at`, `characteristic`
 (22, '用户文件密码', '设置，修改文件密码', '2025-11-13 19:14:46', 'file:update:filePassword'),
 (23, '移动文件', '移动文件至其他虚拟目录', '2025-11-18 01:17:59', 'file:move'),
 (24, '删除文件', '删除文件（移动到回收站）', '2025-12-11 19:02:02', 'file:delete'),
-(25, 'WebDAV访问', '允许通过WebDAV协议访问文件系统', '2025-12-30 07:34:05', 'webdav:access');
+(25, 'WebDAV访问', '允许通过WebDAV协议访问文件系统', '2025-12-30 07:34:05', 'webdav:access'),
+(26, 'SFTP访问', '允许通过SFTP协议访问文件系统', '2026-01-05 10:00:00', 'sftp:access');
 
 -- 插入组权限关联数据
 INSERT INTO `group_power` (`group_id`, `power_id`) VALUES
@@ -364,6 +675,7 @@ INSERT INTO `group_power` (`group_id`, `power_id`) VALUES
 (1, 23),
 (1, 24),
 (1, 25),
+(1, 26),
 (2, 9),
 (2, 10),
 (2, 11),
@@ -7,9 +7,12 @@ import (
 	"myobj/src/config"
 	"myobj/src/internal/repository/database"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/auth"
 	"myobj/src/pkg/cache"
+	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/task"
 	"myobj/src/pkg/util"
 	"os"
 	"strings"
@@ -86,6 +89,18 @@ func main() {
 						ArgsUsage: "<username>",
 						Action:    kickUserAction,
 					},
+					{
+						Name:      "reset-2fa",
+						Usage:     "重置用户两步验证（丢失验证器时使用）",
+						ArgsUsage: "<username>",
+						Action:    resetTwoFactorAction,
+					},
+					{
+						Name:      "unlock",
+						Usage:     "解除用户登录锁定（登录失败次数过多时使用）",
+						ArgsUsage: "<username>",
+						Action:    unlockUserAction,
+					},
 				},
 			},
 			{
@@ -116,6 +131,17 @@ func main() {
 						Usage:  "查看系统统计",
 						Action: systemStatsAction,
 					},
+					{
+						Name:  "thumbnails",
+						Usage: "为缺少缩略图的图片生成缩略图",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "重新生成所有图片的缩略图",
+							},
+						},
+						Action: thumbnailBackfillAction,
+					},
 				},
 			},
 		},
@@ -297,10 +323,6 @@ func resetPasswordAction(c *cli.Context) error {
 	username := c.Args().Get(0)
 	newPassword := c.Args().Get(1)
 
-	if len(newPassword) < 6 {
-		return fmt.Errorf("密码长度不能少于6位")
-	}
-
 	ctx := context.Background()
 
 	// 查询用户
@@ -309,6 +331,12 @@ func resetPasswordAction(c *cli.Context) error {
 		return fmt.Errorf("用户不存在: %w", err)
 	}
 
+	// 校验密码策略（含历史密码）
+	passwordPolicy := auth.NewPasswordPolicyManager(db.SysConfig(), db.PasswordHistory())
+	if err := passwordPolicy.Check(ctx, models.PasswordKindLogin, user, newPassword); err != nil {
+		return err
+	}
+
 	// 确认操作
 	confirm := false
 	prompt := &survey.Confirm{
@@ -330,11 +358,15 @@ func resetPasswordAction(c *cli.Context) error {
 	}
 
 	user.Password = hashedPassword
+	user.PasswordChangedAt = custom_type.Now()
 
 	// 更新数据库
 	if err := db.User().Update(ctx, user); err != nil {
 		return fmt.Errorf("更新密码失败: %w", err)
 	}
+	if err := passwordPolicy.Record(ctx, models.PasswordKindLogin, user.ID, hashedPassword); err != nil {
+		pterm.Warning.Printf("记录历史密码失败: %v\n", err)
+	}
 
 	pterm.Success.Printf("用户 '%s' 的密码已重置\n", username)
 	return nil
@@ -516,6 +548,22 @@ func kickUserAction(c *cli.Context) error {
 		return fmt.Errorf("用户不存在: %w", err)
 	}
 
+	sessions := auth.NewSessionManager(db.UserSession(), db.RefreshToken())
+	list, err := sessions.List(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("查询会话失败: %w", err)
+	}
+	if len(list) == 0 {
+		pterm.Info.Printf("用户 '%s' 当前没有登录会话\n", username)
+		return nil
+	}
+
+	tableData := pterm.TableData{{"设备", "IP", "登录时间", "最近访问"}}
+	for _, s := range list {
+		tableData = append(tableData, []string{s.Device, s.IP, s.CreatedAt.Format("2006-01-02 15:04:05"), s.LastSeenAt.Format("2006-01-02 15:04:05")})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
 	// 确认操作
 	confirm := false
 	prompt := &survey.Confirm{
@@ -530,13 +578,83 @@ func kickUserAction(c *cli.Context) error {
 		return nil
 	}
 
-	// 清除缓存中与该用户相关的JWT tokens
-	// 由于缓存中key是token本身，我们需要清除所有缓存（或使用特定前缀）
-	// 这里采用简单策略：清除所有缓存
-	cacheStore.Clear()
+	// 会话登记在数据库中，删除记录后服务端下一次校验即失效，不影响其他用户
+	count, err := sessions.RevokeAll(ctx, user.ID, "")
+	if err != nil {
+		return fmt.Errorf("注销会话失败: %w", err)
+	}
+
+	pterm.Success.Printf("用户 '%s' (ID: %s) 的 %d 个登录会话已被清除\n", username, user.ID, count)
+	return nil
+}
+
+// resetTwoFactorAction 重置用户两步验证
+func resetTwoFactorAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("请指定用户名")
+	}
+
+	username := c.Args().Get(0)
+	ctx := context.Background()
+
+	user, err := db.User().GetByUserName(ctx, username)
+	if err != nil {
+		return fmt.Errorf("用户不存在: %w", err)
+	}
+
+	twoFactor := auth.NewTwoFactorManager(db.TwoFactor(), db.Group())
+	enabled, err := twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("查询两步验证状态失败: %w", err)
+	}
+	if !enabled {
+		pterm.Warning.Printf("用户 '%s' 未开启两步验证\n", username)
+		return nil
+	}
+
+	// 确认操作
+	confirm := false
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("确定要重置用户 '%s' 的两步验证吗？", username),
+	}
+	if err := survey.AskOne(prompt, &confirm); err != nil {
+		return err
+	}
+
+	if !confirm {
+		pterm.Info.Println("操作已取消")
+		return nil
+	}
+
+	if err := twoFactor.Reset(ctx, user.ID); err != nil {
+		return fmt.Errorf("重置两步验证失败: %w", err)
+	}
 
-	pterm.Success.Printf("用户 '%s' (ID: %s) 的所有登录会话已被清除\n", username, user.ID)
-	pterm.Info.Println("注意：为确保完全清除，已清空所有缓存")
+	pterm.Success.Printf("用户 '%s' 的两步验证已重置\n", username)
+	return nil
+}
+
+// unlockUserAction 解除用户登录锁定
+// CLI 与服务端不共享本地缓存，解锁通过安全事件日志通知服务端，服务端下次检查锁定时生效
+func unlockUserAction(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("请指定用户名")
+	}
+
+	username := c.Args().Get(0)
+	ctx := context.Background()
+
+	user, err := db.User().GetByUserName(ctx, username)
+	if err != nil {
+		return fmt.Errorf("用户不存在: %w", err)
+	}
+
+	limiter := auth.NewLoginLimiter(cacheStore, db.SecurityEvent(), config.CONFIG.Auth.LoginLimit)
+	if err := limiter.Unlock(ctx, user.UserName, auth.LimitChannelCLI, "myobj-cli"); err != nil {
+		return fmt.Errorf("解除锁定失败: %w", err)
+	}
+
+	pterm.Success.Printf("用户 '%s' 的登录锁定已解除\n", username)
 	return nil
 }
 
@@ -658,3 +776,15 @@ func systemStatsAction(c *cli.Context) error {
 
 	return nil
 }
+
+// thumbnailBackfillAction 生成缺少的缩略图（--all 时重新生成所有图片的缩略图）
+func thumbnailBackfillAction(c *cli.Context) error {
+	spinner, _ := pterm.DefaultSpinner.Start("正在生成缩略图...")
+	count, err := task.NewThumbnailTask(db).BackfillThumbnails(c.Bool("all"))
+	if err != nil {
+		spinner.Fail("生成缩略图失败")
+		return err
+	}
+	spinner.Success(fmt.Sprintf("已生成 %d 张缩略图", count))
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"myobj/src/internal/repository/impl"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/sftp"
 	"myobj/src/pkg/webdav"
 	"os"
 	"os/signal"
@@ -61,13 +62,14 @@ func main() {
 		logger.LOG.Error("数据库初始化失败", "error", err)
 		os.Exit(1)
 	}
+	// 缓存在各服务间共用（登录失败次数在 HTTP、WebDAV 与 SFTP 中统一计数）
 	localCache := cache.InitCache()
 
 	// 4. 启动 WebDAV 服务（如果启用）
 	if config.CONFIG.WebDAV.Enable {
 		logger.LOG.Info("WebDAV 服务已启用，正在启动...")
 		factory := impl.NewRepositoryFactory(database.GetDB())
-		webdavServer := webdav.NewServer(factory)
+		webdavServer := webdav.NewServer(factory, localCache)
 		go func() {
 			if err := webdavServer.Start(); err != nil {
 				logger.LOG.Error("WebDAV 服务器启动失败", "error", err)
@@ -77,10 +79,23 @@ func main() {
 		logger.LOG.Info("WebDAV 服务未启用（可在 config.toml 中设置 webdav.enable=true 启用）")
 	}
 
-	// 5. 注册关闭信号处理
-	setupGracefulShutdown(localCache)
+	// 5. 启动 SFTP 服务（如果启用）
+	var sftpServer *sftp.Server
+	if config.CONFIG.SFTP.Enable {
+		logger.LOG.Info("SFTP 服务已启用，正在启动...")
+		factory := impl.NewRepositoryFactory(database.GetDB())
+		sftpServer = sftp.NewServer(factory, localCache)
+		go func() {
+			if err := sftpServer.Start(); err != nil {
+				logger.LOG.Error("SFTP 服务器启动失败", "error", err)
+			}
+		}()
+	}
+
+	// 6. 注册关闭信号处理
+	setupGracefulShutdown(localCache, sftpServer)
 	fmt.Printf("apiKey开启情况: %v", config.CONFIG.Auth.ApiKey)
-	// 6. 启动HTTP服务器
+	// 7. 启动HTTP服务器
 	if err := startServer(localCache); err != nil {
 		logger.LOG.Error("服务器启动失败", "error", err)
 		os.Exit(1)
@@ -116,7 +131,7 @@ func initLogger() error {
 }
 
 // initDatabase 初始化数据库连接
-// 根据配置类型(MySQL/SQLite)建立数据库连接并测试连通性
+// 根据配置类型(MySQL/SQLite)建立数据库连接并测试连通性，随后执行数据库迁移
 func initDatabase() error {
 	logger.LOG.Info("[初始化] 正在连接数据库...", "type", config.CONFIG.Database.Type)
 	defer func() {
@@ -128,6 +143,9 @@ func initDatabase() error {
 
 	database.InitDataBase()
 	logger.LOG.Info("[成功] 数据库连接已建立")
+	if err := database.Migrate(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -144,7 +162,7 @@ func startServer(cacheLocal cache.Cache) error {
 
 // setupGracefulShutdown 设置优雅关闭信号处理
 // 监听系统中断信号，在收到信号时优雅关闭应用
-func setupGracefulShutdown(cacheLocal cache.Cache) {
+func setupGracefulShutdown(cacheLocal cache.Cache, sftpServer *sftp.Server) {
 	// 创建信号通道
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
@@ -154,6 +172,13 @@ func setupGracefulShutdown(cacheLocal cache.Cache) {
 		sig := <-sigChan
 		logger.LOG.Warn("收到关闭信号，系统即将关闭", "signal", sig.String())
 
+		// 停止接受 SFTP 连接
+		if sftpServer != nil {
+			if err := sftpServer.Stop(); err != nil {
+				logger.LOG.Error("关闭 SFTP 服务失败", "error", err)
+			}
+		}
+
 		// 关闭数据库连接
 		db := database.GetDB()
 		if db != nil {
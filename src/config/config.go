@@ -22,6 +22,13 @@ type MyObjConfig struct {
 	Cors     Cors     `toml:"cors"`     // 跨域配置
 	Cache    Cache    `toml:"cache"`    // 缓存配置
 	WebDAV   WebDAV   `toml:"webdav"`   // WebDAV配置
+	SFTP     SFTP     `toml:"sftp"`     // SFTP配置
+	LDAP     LDAP     `toml:"ldap"`     // LDAP 认证配置
+	OIDC     OIDC     `toml:"oidc"`     // OIDC 单点登录配置
+	Audit    Audit    `toml:"audit"`    // 审计日志配置
+	Mail     Mail     `toml:"mail"`     // 邮件配置
+	Search   Search   `toml:"search"`   // 全文检索配置
+	Convert  Convert  `toml:"convert"`  // 下载格式转换配置
 }
 
 // Server 服务器配置
@@ -48,8 +55,39 @@ type Auth struct {
 	Secret string `toml:"secret"`
 	// ApiKey 启用ApiKey
 	ApiKey bool `toml:"api_key"`
-	// JwtExpire JWT过期时间
+	// JwtExpire 会话空闲超时（小时），超过该时间未刷新令牌需重新登录
 	JwtExpire int `toml:"jwt_expire"`
+	// AccessTokenExpire 访问令牌有效期（分钟），过期后使用刷新令牌换取新的访问令牌
+	AccessTokenExpire int `toml:"access_token_expire"`
+	// SessionMaxAge 会话绝对有效期（小时），到期后无论是否活跃都需重新登录
+	SessionMaxAge int `toml:"session_max_age"`
+	// RememberMeDays 登录时勾选"记住我"的会话绝对有效期（天），期间不受空闲超时限制
+	RememberMeDays int `toml:"remember_me_days"`
+	// CookieSecure 登录 Cookie 只通过 HTTPS 发送
+	CookieSecure bool `toml:"cookie_secure"`
+	// LoginLimit 登录失败限制
+	LoginLimit LoginLimit `toml:"login_limit"`
+}
+
+// LoginLimit 登录失败限制配置（网页登录、WebDAV、SFTP 与分享密码共用）
+// 按用户名与 IP 分别计数，连续失败时逐次延长等待时间，达到上限后临时锁定
+type LoginLimit struct {
+	// Enable 是否启用
+	Enable bool `toml:"enable"`
+	// MaxAttempts 同一账户在统计窗口内允许的失败次数
+	MaxAttempts int `toml:"max_attempts"`
+	// IPMaxAttempts 同一 IP 在统计窗口内允许的失败次数
+	IPMaxAttempts int `toml:"ip_max_attempts"`
+	// ShareMaxAttempts 同一分享在统计窗口内允许的密码错误次数
+	ShareMaxAttempts int `toml:"share_max_attempts"`
+	// Window 统计窗口（分钟），超过窗口未再失败时重新计数
+	Window int `toml:"window"`
+	// LockMinutes 首次锁定时长（分钟），再次锁定时加倍
+	LockMinutes int `toml:"lock_minutes"`
+	// MaxLockMinutes 最长锁定时长（分钟）
+	MaxLockMinutes int `toml:"max_lock_minutes"`
+	// EventRetentionDays 安全事件日志保留天数
+	EventRetentionDays int `toml:"event_retention_days"`
 }
 
 // Log 日志配置
@@ -88,6 +126,20 @@ type File struct {
 	DatDir string `toml:"data_dir"`
 	// TempDir 文件临时存储目录
 	TempDir string `toml:"temp_dir"`
+	// CopyQuota 复制文件的配额策略：full 按文件大小扣除配额（与秒传一致），none 不占用配额
+	CopyQuota string `toml:"copy_quota"`
+	// CopyAsyncThreshold 复制的文件数超过该值时转为后台任务执行
+	CopyAsyncThreshold int `toml:"copy_async_threshold"`
+	// TextEditMaxSize 在线预览与编辑文本文件的大小上限MB
+	TextEditMaxSize int64 `toml:"text_edit_max_size"`
+	// ImageSizes 图片衍生图允许的宽高（像素），请求的宽高必须在列表中
+	ImageSizes []int `toml:"image_sizes"`
+	// ImageQuality 图片衍生图未指定质量时的 JPEG/WebP 编码质量（1-100）
+	ImageQuality int `toml:"image_quality"`
+	// ImageCacheSize 图片衍生图缓存的总大小上限MB，超过后删除最久未使用的衍生图
+	ImageCacheSize int `toml:"image_cache_size"`
+	// StripGPS 公开文件与分享下载时去除照片中的位置信息（JPEG 的 EXIF GPS 与 XMP 坐标），文件所有者下载时保留
+	StripGPS bool `toml:"strip_gps"`
 }
 
 // Cors 跨域配置
@@ -135,6 +187,189 @@ type WebDAV struct {
 	Prefix string `toml:"prefix"`
 }
 
+// SFTP SFTP服务配置
+type SFTP struct {
+	// Enable 是否启用SFTP服务
+	Enable bool `toml:"enable"`
+	// Host 监听地址
+	Host string `toml:"host"`
+	// Port 监听端口
+	Port int `toml:"port"`
+	// HostKey 主机私钥文件路径（不存在时自动生成）
+	HostKey string `toml:"host_key"`
+}
+
+// LDAP LDAP / Active Directory 认证配置
+type LDAP struct {
+	// Enable 是否启用 LDAP 认证
+	Enable bool `toml:"enable"`
+	// URL 服务器地址（ldap://host:389 或 ldaps://host:636）
+	URL string `toml:"url"`
+	// StartTLS 使用 ldap:// 连接时是否升级为 TLS
+	StartTLS bool `toml:"start_tls"`
+	// CACert 自签名证书的 CA 文件路径（为空时使用系统证书）
+	CACert string `toml:"ca_cert"`
+	// InsecureSkipVerify 跳过证书校验（仅用于测试环境）
+	InsecureSkipVerify bool `toml:"insecure_skip_verify"`
+	// Timeout 连接与操作超时时间（秒）
+	Timeout int `toml:"timeout"`
+	// BindDN 用于搜索用户的服务账号 DN（为空时匿名搜索）
+	BindDN string `toml:"bind_dn"`
+	// BindPassword 服务账号密码
+	BindPassword string `toml:"bind_password"`
+	// BaseDN 用户搜索起点
+	BaseDN string `toml:"base_dn"`
+	// UserFilter 用户搜索过滤器，{username} 会被替换为转义后的用户名
+	UserFilter string `toml:"user_filter"`
+	// UsernameAttr 用户名属性（OpenLDAP 为 uid，AD 为 sAMAccountName）
+	UsernameAttr string `toml:"username_attr"`
+	// NameAttr 昵称属性
+	NameAttr string `toml:"name_attr"`
+	// EmailAttr 邮箱属性
+	EmailAttr string `toml:"email_attr"`
+	// PhoneAttr 手机号属性
+	PhoneAttr string `toml:"phone_attr"`
+	// MemberOfAttr 用户条目上记录所属组 DN 的属性（AD 与启用 memberof 的 OpenLDAP 为 memberOf）
+	MemberOfAttr string `toml:"member_of_attr"`
+	// GroupBaseDN 组搜索起点（为空时不搜索组，只使用 MemberOfAttr）
+	GroupBaseDN string `toml:"group_base_dn"`
+	// GroupFilter 组搜索过滤器，{dn} 替换为用户 DN，{username} 替换为用户名
+	GroupFilter string `toml:"group_filter"`
+	// DisabledFilter 判断账户禁用的过滤器（在用户条目上求值，匹配即视为禁用）
+	// AD 的 userAccountControl 禁用位与 OpenLDAP ppolicy 的锁定属性会自动识别，无需配置
+	DisabledFilter string `toml:"disabled_filter"`
+	// GroupMapping LDAP 组到 MyObj 用户组的映射，按顺序匹配，第一个命中的生效
+	GroupMapping []LDAPGroupMapping `toml:"group_mapping"`
+	// DefaultGroupID 未命中任何映射时使用的用户组（0 表示系统默认组）
+	DefaultGroupID int `toml:"default_group_id"`
+	// SyncInterval 定时同步目录账户状态与组的间隔（分钟，0 表示只在登录时同步）
+	SyncInterval int `toml:"sync_interval"`
+}
+
+// LDAPGroupMapping LDAP 组映射
+type LDAPGroupMapping struct {
+	// LDAPGroup LDAP 组的 DN 或 CN（不区分大小写）
+	LDAPGroup string `toml:"ldap_group"`
+	// GroupID MyObj 用户组ID
+	GroupID int `toml:"group_id"`
+}
+
+// OIDC OpenID Connect 单点登录配置
+type OIDC struct {
+	// Enable 是否启用 OIDC 单点登录
+	Enable bool `toml:"enable"`
+	// DisplayName 登录页按钮显示的名称
+	DisplayName string `toml:"display_name"`
+	// Issuer 身份提供方地址（通过 {issuer}/.well-known/openid-configuration 自动发现端点）
+	Issuer string `toml:"issuer"`
+	// ClientID 客户端ID
+	ClientID string `toml:"client_id"`
+	// ClientSecret 客户端密钥（公共客户端可为空，仅使用 PKCE）
+	ClientSecret string `toml:"client_secret"`
+	// RedirectURL 回调地址，需在身份提供方登记，指向 /api/user/oidc/callback
+	RedirectURL string `toml:"redirect_url"`
+	// FrontendURL 登录完成后跳转的前端地址，会附带 oidc_code 或 oidc_error 参数
+	FrontendURL string `toml:"frontend_url"`
+	// Scopes 申请的权限范围（openid 会自动添加）
+	Scopes []string `toml:"scopes"`
+	// UsernameClaim 用户名声明
+	UsernameClaim string `toml:"username_claim"`
+	// NameClaim 昵称声明
+	NameClaim string `toml:"name_claim"`
+	// EmailClaim 邮箱声明
+	EmailClaim string `toml:"email_claim"`
+	// PhoneClaim 手机号声明
+	PhoneClaim string `toml:"phone_claim"`
+	// GroupsClaim 组声明（字符串或字符串数组）
+	GroupsClaim string `toml:"groups_claim"`
+	// AutoCreate 首次登录时是否自动创建账户（关闭时只允许已绑定的账户登录）
+	AutoCreate bool `toml:"auto_create"`
+	// LinkByEmail 首次登录时是否按已验证的邮箱（email_verified）绑定已有账户
+	LinkByEmail bool `toml:"link_by_email"`
+	// GroupMapping 组声明到 MyObj 用户组的映射，按顺序匹配，第一个命中的生效
+	GroupMapping []OIDCGroupMapping `toml:"group_mapping"`
+	// DefaultGroupID 未命中任何映射时使用的用户组（0 表示系统默认组）
+	DefaultGroupID int `toml:"default_group_id"`
+}
+
+// OIDCGroupMapping OIDC 组映射
+type OIDCGroupMapping struct {
+	// Group 组声明中的值（不区分大小写，忽略开头的 /）
+	Group string `toml:"group"`
+	// GroupID MyObj 用户组ID
+	GroupID int `toml:"group_id"`
+}
+
+// Audit 审计日志配置
+type Audit struct {
+	// Enable 是否记录审计日志
+	Enable bool `toml:"enable"`
+	// RetentionDays 审计日志保留天数（0 表示永久保留）
+	RetentionDays int `toml:"retention_days"`
+	// HashChain 是否启用哈希链（每条记录包含上一条记录的哈希，用于发现篡改或删除）
+	HashChain bool `toml:"hash_chain"`
+}
+
+// Mail 邮件配置（注册邮箱验证、找回密码、邀请注册）
+type Mail struct {
+	// Enable 是否启用邮件
+	Enable bool `toml:"enable"`
+	// Transport 发送方式：smtp 或 file（写入本地文件，用于测试或没有 SMTP 服务器的环境）
+	Transport string `toml:"transport"`
+	// Host SMTP 服务器地址
+	Host string `toml:"host"`
+	// Port SMTP 端口
+	Port int `toml:"port"`
+	// Username SMTP 用户名（为空时不认证）
+	Username string `toml:"username"`
+	// Password SMTP 密码
+	Password string `toml:"password"`
+	// Encryption 加密方式：starttls、ssl（隐式 TLS，通常为 465 端口）或 none
+	Encryption string `toml:"encryption"`
+	// From 发件人地址
+	From string `toml:"from"`
+	// FromName 发件人名称
+	FromName string `toml:"from_name"`
+	// FilePath file 方式写入的文件
+	FilePath string `toml:"file_path"`
+	// TemplateDir 邮件模板目录
+	TemplateDir string `toml:"template_dir"`
+	// SiteURL 邮件中链接使用的站点地址（如 https://pan.example.com），不从请求头推断以免被伪造
+	SiteURL string `toml:"site_url"`
+	// RequireVerification 注册后必须验证邮箱才能登录
+	RequireVerification bool `toml:"require_verification"`
+	// VerifyExpire 邮箱验证链接有效期（小时）
+	VerifyExpire int `toml:"verify_expire"`
+	// ResetExpire 重置密码链接有效期（分钟）
+	ResetExpire int `toml:"reset_expire"`
+	// InviteExpire 邀请链接有效期（天）
+	InviteExpire int `toml:"invite_expire"`
+}
+
+// Search 全文检索配置
+type Search struct {
+	// Enable 是否启用全文索引（关闭时搜索只按文件名匹配）
+	Enable bool `toml:"enable"`
+	// MaxFileSize 提取内容的文件大小上限（MB），超过时只索引文件名
+	MaxFileSize int `toml:"max_file_size"`
+	// MaxTextSize 每个文件最多索引的文本长度（KB），超出部分截断
+	MaxTextSize int `toml:"max_text_size"`
+	// SyncInterval 补建与清理索引的间隔（分钟）
+	SyncInterval int `toml:"sync_interval"`
+}
+
+// Convert 下载格式转换配置
+type Convert struct {
+	// MaxFileSize 参与转换的文件大小上限（MB）
+	MaxFileSize int `toml:"max_file_size"`
+	// FFmpegPath ffmpeg 可执行文件路径，为空时不提供音视频转换
+	FFmpegPath string `toml:"ffmpeg_path"`
+	// FFmpegTimeout 单次音视频转换的超时时间（分钟）
+	FFmpegTimeout int `toml:"ffmpeg_timeout"`
+	// CacheDays 转换结果缓存的保留天数（按最后一次使用时间）
+	CacheDays int `toml:"cache_days"`
+}
+
 // InitConfig 初始化配置
 // 自动搜索并加载 config.toml 文件
 // 搜索顺序:
@@ -248,12 +483,190 @@ func validateConfig(cfg *MyObjConfig) error {
 	if len(cfg.Auth.Secret) < 32 {
 		return fmt.Errorf("JWT密钥长度至少32字符")
 	}
+	if cfg.Auth.JwtExpire <= 0 {
+		cfg.Auth.JwtExpire = 2
+	}
+	if cfg.Auth.AccessTokenExpire <= 0 {
+		cfg.Auth.AccessTokenExpire = 15
+	}
+	if cfg.Auth.SessionMaxAge <= 0 {
+		cfg.Auth.SessionMaxAge = 24
+	}
+	if cfg.Auth.RememberMeDays <= 0 {
+		cfg.Auth.RememberMeDays = 30
+	}
 
 	// 验证日志配置
 	if cfg.Log.LogPath == "" {
 		cfg.Log.LogPath = "./logs/" // 使用默认路径
 	}
 
+	// 验证登录失败限制配置
+	if cfg.Auth.LoginLimit.MaxAttempts <= 0 {
+		cfg.Auth.LoginLimit.MaxAttempts = 5
+	}
+	if cfg.Auth.LoginLimit.IPMaxAttempts <= 0 {
+		cfg.Auth.LoginLimit.IPMaxAttempts = 20
+	}
+	if cfg.Auth.LoginLimit.ShareMaxAttempts <= 0 {
+		cfg.Auth.LoginLimit.ShareMaxAttempts = 10
+	}
+	if cfg.Auth.LoginLimit.Window <= 0 {
+		cfg.Auth.LoginLimit.Window = 15
+	}
+	if cfg.Auth.LoginLimit.LockMinutes <= 0 {
+		cfg.Auth.LoginLimit.LockMinutes = 15
+	}
+	if cfg.Auth.LoginLimit.MaxLockMinutes < cfg.Auth.LoginLimit.LockMinutes {
+		cfg.Auth.LoginLimit.MaxLockMinutes = 24 * 60
+	}
+	if cfg.Auth.LoginLimit.EventRetentionDays <= 0 {
+		cfg.Auth.LoginLimit.EventRetentionDays = 90
+	}
+
+	// 验证 LDAP 配置
+	if cfg.LDAP.Enable {
+		if cfg.LDAP.URL == "" || cfg.LDAP.BaseDN == "" {
+			return fmt.Errorf("启用 LDAP 时 url 与 base_dn 不能为空")
+		}
+		if cfg.LDAP.UsernameAttr == "" {
+			cfg.LDAP.UsernameAttr = "uid"
+		}
+		if cfg.LDAP.UserFilter == "" {
+			cfg.LDAP.UserFilter = "(" + cfg.LDAP.UsernameAttr + "={username})"
+		}
+		if cfg.LDAP.NameAttr == "" {
+			cfg.LDAP.NameAttr = "cn"
+		}
+		if cfg.LDAP.EmailAttr == "" {
+			cfg.LDAP.EmailAttr = "mail"
+		}
+		if cfg.LDAP.Timeout <= 0 {
+			cfg.LDAP.Timeout = 10
+		}
+	}
+
+	// 验证 OIDC 配置
+	if cfg.OIDC.Enable {
+		if cfg.OIDC.Issuer == "" || cfg.OIDC.ClientID == "" || cfg.OIDC.RedirectURL == "" {
+			return fmt.Errorf("启用 OIDC 时 issuer、client_id 与 redirect_url 不能为空")
+		}
+		if cfg.OIDC.DisplayName == "" {
+			cfg.OIDC.DisplayName = "单点登录"
+		}
+		if cfg.OIDC.FrontendURL == "" {
+			cfg.OIDC.FrontendURL = "/"
+		}
+		if len(cfg.OIDC.Scopes) == 0 {
+			cfg.OIDC.Scopes = []string{"profile", "email"}
+		}
+		if cfg.OIDC.UsernameClaim == "" {
+			cfg.OIDC.UsernameClaim = "preferred_username"
+		}
+		if cfg.OIDC.NameClaim == "" {
+			cfg.OIDC.NameClaim = "name"
+		}
+		if cfg.OIDC.EmailClaim == "" {
+			cfg.OIDC.EmailClaim = "email"
+		}
+	}
+
+	// 验证文件复制配置
+	if cfg.File.CopyQuota == "" {
+		cfg.File.CopyQuota = "full"
+	}
+	if cfg.File.CopyQuota != "full" && cfg.File.CopyQuota != "none" {
+		return fmt.Errorf("不支持的复制配额策略: %s", cfg.File.CopyQuota)
+	}
+	if cfg.File.CopyAsyncThreshold <= 0 {
+		cfg.File.CopyAsyncThreshold = 200
+	}
+	if cfg.File.TextEditMaxSize <= 0 {
+		cfg.File.TextEditMaxSize = 5
+	}
+	if len(cfg.File.ImageSizes) == 0 {
+		cfg.File.ImageSizes = []int{64, 128, 256, 512, 1024, 2048}
+	}
+	if cfg.File.ImageQuality <= 0 || cfg.File.ImageQuality > 100 {
+		cfg.File.ImageQuality = 80
+	}
+	if cfg.File.ImageCacheSize <= 0 {
+		cfg.File.ImageCacheSize = 1024
+	}
+
+	// 验证审计日志配置
+	if cfg.Audit.RetentionDays < 0 {
+		cfg.Audit.RetentionDays = 0
+	}
+
+	// 验证邮件配置
+	if cfg.Mail.Transport == "" {
+		cfg.Mail.Transport = "smtp"
+	}
+	if cfg.Mail.Transport != "smtp" && cfg.Mail.Transport != "file" {
+		return fmt.Errorf("不支持的邮件发送方式: %s", cfg.Mail.Transport)
+	}
+	if cfg.Mail.Enable {
+		if cfg.Mail.SiteURL == "" {
+			return fmt.Errorf("启用邮件时 site_url 不能为空")
+		}
+		if cfg.Mail.Transport == "smtp" && (cfg.Mail.Host == "" || cfg.Mail.From == "") {
+			return fmt.Errorf("使用 SMTP 发送邮件时 host 与 from 不能为空")
+		}
+	}
+	if cfg.Mail.Encryption == "" {
+		cfg.Mail.Encryption = "starttls"
+	}
+	if cfg.Mail.Port <= 0 {
+		cfg.Mail.Port = 587
+		if cfg.Mail.Encryption == "ssl" {
+			cfg.Mail.Port = 465
+		}
+	}
+	if cfg.Mail.From == "" {
+		cfg.Mail.From = "noreply@localhost"
+	}
+	if cfg.Mail.FromName == "" {
+		cfg.Mail.FromName = "MyObj"
+	}
+	if cfg.Mail.FilePath == "" {
+		cfg.Mail.FilePath = "./logs/mail.log"
+	}
+	if cfg.Mail.TemplateDir == "" {
+		cfg.Mail.TemplateDir = "templates/mail"
+	}
+	if cfg.Mail.VerifyExpire <= 0 {
+		cfg.Mail.VerifyExpire = 24
+	}
+	if cfg.Mail.ResetExpire <= 0 {
+		cfg.Mail.ResetExpire = 30
+	}
+	if cfg.Mail.InviteExpire <= 0 {
+		cfg.Mail.InviteExpire = 7
+	}
+
+	// 验证全文检索配置
+	if cfg.Search.MaxFileSize <= 0 {
+		cfg.Search.MaxFileSize = 20
+	}
+	if cfg.Search.MaxTextSize <= 0 {
+		cfg.Search.MaxTextSize = 1024
+	}
+	if cfg.Search.SyncInterval <= 0 {
+		cfg.Search.SyncInterval = 10
+	}
+
+	// 验证下载格式转换配置
+	if cfg.Convert.MaxFileSize <= 0 {
+		cfg.Convert.MaxFileSize = 200
+	}
+	if cfg.Convert.FFmpegTimeout <= 0 {
+		cfg.Convert.FFmpegTimeout = 30
+	}
+	if cfg.Convert.CacheDays <= 0 {
+		cfg.Convert.CacheDays = 7
+	}
+
 	return nil
 }
 
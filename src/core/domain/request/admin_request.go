@@ -50,6 +50,9 @@ type AdminCreateGroupRequest struct {
 	Name         string `json:"name" binding:"required"`
 	Space        int64  `json:"space"`         // 存储空间（字节），0表示无限
 	GroupDefault int    `json:"group_default"` // 0-否 1-是
+	Require2FA   bool   `json:"require_2fa"`   // 是否要求组内用户开启两步验证
+	// 是否禁止组内用户使用本地密码登录（需启用 OIDC 或 LDAP）
+	DisableLocalLogin bool `json:"disable_local_login"`
 }
 
 // AdminUpdateGroupRequest 管理员更新组请求
@@ -58,6 +61,9 @@ type AdminUpdateGroupRequest struct {
 	Name         string `json:"name"`
 	Space        int64  `json:"space"`
 	GroupDefault int    `json:"group_default"` // 0-否 1-是
+	Require2FA   *bool  `json:"require_2fa"`   // 是否要求组内用户开启两步验证，不传则不修改
+	// 是否禁止组内用户使用本地密码登录，不传则不修改
+	DisableLocalLogin *bool `json:"disable_local_login"`
 }
 
 // AdminDeleteGroupRequest 管理员删除组请求
@@ -139,6 +145,110 @@ type AdminUpdateSystemConfigRequest struct {
 	WebdavEnabled bool `json:"webdav_enabled"`
 }
 
+// PasswordPolicyRequest 密码策略
+type PasswordPolicyRequest struct {
+	MinLength      int  `json:"min_length" binding:"min=1,max=128"`    // 最小长度
+	RequireUpper   bool `json:"require_upper"`                         // 必须包含大写字母
+	RequireLower   bool `json:"require_lower"`                         // 必须包含小写字母
+	RequireDigit   bool `json:"require_digit"`                         // 必须包含数字
+	RequireSymbol  bool `json:"require_symbol"`                        // 必须包含特殊字符
+	DisallowCommon bool `json:"disallow_common"`                       // 禁止使用常见弱密码
+	HistoryCount   int  `json:"history_count" binding:"min=0,max=24"`  // 禁止重复使用最近 N 次的密码，0 表示不限制
+	MaxAgeDays     int  `json:"max_age_days" binding:"min=0,max=3650"` // 密码最长有效天数，0 表示永不过期
+}
+
+// AdminUpdatePasswordPolicyRequest 更新密码策略请求（登录密码与文件密码分别配置）
+type AdminUpdatePasswordPolicyRequest struct {
+	Policy     PasswordPolicyRequest `json:"policy"`
+	FilePolicy PasswordPolicyRequest `json:"file_policy"`
+}
+
+// AdminUserSessionListRequest 管理员查看用户会话请求
+type AdminUserSessionListRequest struct {
+	UserID string `json:"user_id" form:"user_id" binding:"required"`
+}
+
+// AdminRevokeUserSessionRequest 管理员注销用户会话请求（session_id 为空时注销该用户的所有会话）
+type AdminRevokeUserSessionRequest struct {
+	UserID    string `json:"user_id" binding:"required"`
+	SessionID string `json:"session_id"`
+}
+
+// AdminResetTwoFactorRequest 管理员重置用户两步验证请求
+type AdminResetTwoFactorRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// AdminUnlockUserRequest 管理员解除用户登录锁定请求
+type AdminUnlockUserRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// AdminSecurityEventListRequest 管理员安全事件日志列表请求
+type AdminSecurityEventListRequest struct {
+	Page     int    `json:"page" form:"page" binding:"required,min=1"`
+	PageSize int    `json:"pageSize" form:"pageSize" binding:"required,min=1,max=100"`
+	Type     string `json:"type" form:"type"`
+	Target   string `json:"target" form:"target"`
+}
+
+// AdminWebDAVLockListRequest 管理员 WebDAV 锁列表请求
+type AdminWebDAVLockListRequest struct {
+	Page     int    `json:"page" form:"page" binding:"required,min=1"`
+	PageSize int    `json:"pageSize" form:"pageSize" binding:"required,min=1,max=100"`
+	UserID   string `json:"user_id" form:"user_id"`
+}
+
+// AdminBreakWebDAVLockRequest 管理员强制解除 WebDAV 锁请求
+type AdminBreakWebDAVLockRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// AdminInvitationListRequest 管理员注册邀请列表请求
+type AdminInvitationListRequest struct {
+	Page     int `json:"page" form:"page" binding:"required,min=1"`
+	PageSize int `json:"pageSize" form:"pageSize" binding:"required,min=1,max=100"`
+}
+
+// AdminCreateInvitationRequest 管理员创建注册邀请请求
+type AdminCreateInvitationRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	GroupID int    `json:"group_id" binding:"required"`
+	Space   int64  `json:"space" binding:"min=0"` // 存储空间（字节），0 表示使用用户组的空间
+}
+
+// AdminDeleteInvitationRequest 管理员删除注册邀请请求
+type AdminDeleteInvitationRequest struct {
+	ID int `json:"id" binding:"required"`
+}
+
+// AdminAuditLogFilter 审计日志查询条件
+type AdminAuditLogFilter struct {
+	ActorID    string `json:"actor_id" form:"actor_id"`
+	ActorName  string `json:"actor_name" form:"actor_name"`
+	Action     string `json:"action" form:"action"` // 以 . 结尾时按前缀匹配，如 share.
+	TargetType string `json:"target_type" form:"target_type"`
+	TargetID   string `json:"target_id" form:"target_id"`
+	Result     string `json:"result" form:"result" binding:"omitempty,oneof=success failure"`
+	Source     string `json:"source" form:"source"`
+	IP         string `json:"ip" form:"ip"`
+	Start      string `json:"start" form:"start"` // 开始时间（2006-01-02 或 2006-01-02 15:04:05）
+	End        string `json:"end" form:"end"`     // 结束时间（只有日期时包含当天）
+}
+
+// AdminAuditLogListRequest 管理员审计日志列表请求
+type AdminAuditLogListRequest struct {
+	AdminAuditLogFilter
+	Page     int `json:"page" form:"page" binding:"required,min=1"`
+	PageSize int `json:"pageSize" form:"pageSize" binding:"required,min=1,max=100"`
+}
+
+// AdminAuditLogExportRequest 管理员导出审计日志请求
+type AdminAuditLogExportRequest struct {
+	AdminAuditLogFilter
+	Format string `json:"format" form:"format" binding:"omitempty,oneof=csv json"` // 默认 csv
+}
+
 // PackageCreateRequest 创建打包下载请求
 type PackageCreateRequest struct {
 	FileIDs     []string `json:"file_ids" binding:"required,min=1"`
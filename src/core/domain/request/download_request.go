@@ -44,6 +44,12 @@ type CreateLocalFileDownloadRequest struct {
 	FilePassword string `json:"file_password"`
 }
 
+// ConvertOptionsRequest 查询可转换格式请求
+type ConvertOptionsRequest struct {
+	// 文件ID
+	FileID string `form:"file_id" binding:"required"`
+}
+
 // CreateVideoPlayRequest 创建视频播放任务请求
 type CreateVideoPlayRequest struct {
 	// 视频文件ID
@@ -15,27 +15,56 @@ type UploadPrecheckRequest struct {
 	FilesMd5 []string `json:"files_md5"`
 }
 
+// FileFilterRequest 文件的结构化过滤条件，用于文件列表、搜索与智能文件夹
+type FileFilterRequest struct {
+	// 文件分类，多个用逗号分隔（image、video、audio、document、archive、code、other，all 表示不过滤；doc 等同于 document）
+	Type string `form:"type" json:"type"`
+	// 扩展名，多个用逗号分隔（如 "jpg,png"）
+	Ext string `form:"ext" json:"ext"`
+	// 文件大小范围（字节）
+	MinSize int64 `form:"minSize" json:"min_size" binding:"omitempty,min=0"`
+	MaxSize int64 `form:"maxSize" json:"max_size" binding:"omitempty,min=0"`
+	// 创建时间范围（"2006-01-02" 或 "2006-01-02 15:04:05"，只有日期时包含当天）
+	Start string `form:"start" json:"start"`
+	End   string `form:"end" json:"end"`
+	// 修改时间范围
+	UpdatedStart string `form:"updatedStart" json:"updated_start"`
+	UpdatedEnd   string `form:"updatedEnd" json:"updated_end"`
+	// 是否加密、是否公开、是否有未过期的分享链接，为空时不过滤
+	Encrypted *bool `form:"encrypted" json:"encrypted"`
+	Public    *bool `form:"public" json:"public"`
+	Shared    *bool `form:"shared" json:"shared"`
+}
+
 // FileSearchRequest 文件搜索请求
 type FileSearchRequest struct {
-	Keyword  string `form:"keyword" binding:"required"`
-	Type     string `form:"type"`
+	Keyword string `form:"keyword" binding:"required"`
+	FileFilterRequest
+	// 排序，见 FileListRequest.SortBy（启用全文索引时按相关度排序，不使用该参数）
 	SortBy   string `form:"sortBy"`
 	Page     int    `form:"page"`
 	PageSize int    `form:"pageSize"`
+	// 游标（上一页返回的 next_cursor），指定时忽略页码（启用全文索引时不支持）
+	Cursor string `form:"cursor"`
+	// 路径前缀，只搜索该目录及其子目录中的文件（如 "/文档/2024"，只用于搜索自己的文件）
+	Path string `form:"path"`
 }
 
 // FileListRequest 文件列表请求
 type FileListRequest struct {
 	// 虚拟路径（当前所在目录）
 	VirtualPath string `form:"virtualPath"`
-	// 文件类型
-	Type string `form:"type"`
-	// 排序字段（name, size, time）
+	// 文件过滤条件（只作用于文件，不过滤目录）
+	FileFilterRequest
+	// 排序字段（name, size, created, updated, type；time 同 created），多个用逗号分隔，
+	// 字段后可加 ":asc" 或 ":desc"，如 "type,size:desc"；默认 name、type 升序，其他降序
 	SortBy string `form:"sortBy"`
 	// 页码（从1开始）
-	Page int `form:"page" binding:"required,min=1"`
+	Page int `form:"page" binding:"omitempty,min=1"`
 	// 每页数量
 	PageSize int `form:"pageSize" binding:"required,min=1,max=100"`
+	// 游标（上一页返回的 next_cursor），指定时忽略页码，只返回文件
+	Cursor string `form:"cursor"`
 }
 
 // MakeDirRequest 创建文件夹请求
@@ -56,6 +85,18 @@ type MoveFileRequest struct {
 	TargetPath string `json:"target_path"`
 }
 
+// BatchMoveRequest 批量移动文件与目录请求
+type BatchMoveRequest struct {
+	// 要移动的文件ID（uf_id）
+	FileIDs []string `json:"file_ids"`
+	// 要移动的目录ID
+	DirIDs []int `json:"dir_ids"`
+	// 目标目录ID
+	TargetDirID int `json:"target_dir_id" binding:"required"`
+	// 同名处理方式：skip、rename、overwrite，为空时存在同名项则返回 409
+	Conflict string `json:"conflict" binding:"omitempty,oneof=skip rename overwrite"`
+}
+
 // DeleteFileRequest 删除文件请求
 type DeleteFileRequest struct {
 	// 文件ID列表
@@ -88,14 +129,57 @@ type VideoPlayPrecheckRequest struct {
 
 // PublicFileListRequest 公开文件列表请求
 type PublicFileListRequest struct {
-	// 文件类型
-	Type string `form:"type"`
-	// 排序字段（name, size, time）
+	// 文件过滤条件（public 固定为公开）
+	FileFilterRequest
+	// 排序字段，见 FileListRequest.SortBy
 	SortBy string `form:"sortBy"`
 	// 页码（从1开始）
-	Page int `form:"page" binding:"required,min=1"`
+	Page int `form:"page" binding:"omitempty,min=1"`
+	// 每页数量
+	PageSize int `form:"pageSize" binding:"required,min=1,max=100"`
+	// 游标（上一页返回的 next_cursor），指定时忽略页码
+	Cursor string `form:"cursor"`
+}
+
+// SmartFolderRequest 创建或修改智能文件夹请求
+type SmartFolderRequest struct {
+	// 智能文件夹ID（修改时必填）
+	ID int `json:"id"`
+	// 名称
+	Name string `json:"name" binding:"required,max=255"`
+	// 文件名包含的关键词
+	Keyword string `json:"keyword"`
+	// 路径前缀，只包含该目录及其子目录中的文件
+	Path string `json:"path"`
+	FileFilterRequest
+	// 排序，见 FileListRequest.SortBy
+	SortBy string `json:"sort_by"`
+}
+
+// SmartFolderIDRequest 智能文件夹ID请求
+type SmartFolderIDRequest struct {
+	ID int `json:"id" binding:"required"`
+}
+
+// SmartFolderFilesRequest 智能文件夹文件列表请求
+type SmartFolderFilesRequest struct {
+	ID int `form:"id" binding:"required"`
 	// 每页数量
 	PageSize int `form:"pageSize" binding:"required,min=1,max=100"`
+	// 游标（上一页返回的 next_cursor），为空时从第一个文件开始
+	Cursor string `form:"cursor"`
+}
+
+// CategoryFilesRequest 分类文件列表请求（分类名称在路径中）
+type CategoryFilesRequest struct {
+	// 排序，见 FileListRequest.SortBy
+	SortBy string `form:"sortBy"`
+	// 页码（从1开始）
+	Page int `form:"page" binding:"omitempty,min=1"`
+	// 每页数量
+	PageSize int `form:"pageSize" binding:"required,min=1,max=100"`
+	// 游标（上一页返回的 next_cursor），指定时忽略页码
+	Cursor string `form:"cursor"`
 }
 
 // UploadProgressRequest 上传进度查询请求
@@ -148,6 +232,23 @@ type DeleteDirRequest struct {
 	DirID int `json:"dir_id" binding:"required"`
 }
 
+// CopyFileRequest 复制文件与目录请求
+type CopyFileRequest struct {
+	// 要复制的文件ID（uf_id）
+	FileIDs []string `json:"file_ids"`
+	// 要复制的目录ID
+	DirIDs []int `json:"dir_ids"`
+	// 目标目录ID
+	TargetDirID int `json:"target_dir_id" binding:"required"`
+	// 同名处理方式：rename（默认）、skip、overwrite
+	Conflict string `json:"conflict" binding:"omitempty,oneof=rename skip overwrite"`
+}
+
+// CopyProgressRequest 获取复制任务进度请求
+type CopyProgressRequest struct {
+	TaskID string `json:"task_id" form:"task_id" binding:"required"`
+}
+
 // UploadTaskListRequest 上传任务列表请求
 type UploadTaskListRequest struct {
 	// 页码（从1开始）
@@ -155,3 +256,67 @@ type UploadTaskListRequest struct {
 	// 每页数量
 	PageSize int `form:"pageSize" binding:"required,min=1,max=100"`
 }
+
+// SetEncryptPolicyRequest 设置写入加密策略请求
+type SetEncryptPolicyRequest struct {
+	// 目录ID（0 表示对所有目录生效）
+	DirID int `json:"dir_id"`
+	// 是否开启写入加密
+	Enable bool `json:"enable"`
+}
+
+// TextFileReadRequest 读取文本文件请求
+type TextFileReadRequest struct {
+	// 文件ID（uf_id）
+	FileID string `json:"file_id" binding:"required"`
+	// 文件密码（加密文件必填）
+	FilePassword string `json:"file_password"`
+	// 文本编码，为空时自动检测（utf-8、utf-8-bom、utf-16le、utf-16be、gbk、gb18030）
+	Charset string `json:"charset"`
+}
+
+// TextFileSaveRequest 保存文本文件请求
+type TextFileSaveRequest struct {
+	// 文件ID（uf_id）
+	FileID string `json:"file_id" binding:"required"`
+	// 文本内容
+	Content string `json:"content"`
+	// 读取时返回的 etag，文件已被修改时保存失败
+	ETag string `json:"etag" binding:"required"`
+	// 保存使用的文本编码，默认 utf-8
+	Charset string `json:"charset"`
+	// 文件密码（加密文件必填）
+	FilePassword string `json:"file_password"`
+}
+
+// ImageDerivativeRequest 图片衍生图请求
+type ImageDerivativeRequest struct {
+	// 宽度（像素，需为配置的 image_sizes 之一），为空时按高度等比缩放
+	Width int `form:"w" binding:"omitempty,min=0"`
+	// 高度（像素，需为配置的 image_sizes 之一），为空时按宽度等比缩放
+	Height int `form:"h" binding:"omitempty,min=0"`
+	// 缩放方式：contain（默认，完整显示）、cover（铺满并居中裁剪，需同时指定宽高）
+	Fit string `form:"fit" binding:"omitempty,oneof=contain cover"`
+	// JPEG/WebP 编码质量（1-100），为空时使用配置的 image_quality
+	Quality int `form:"q" binding:"omitempty,min=1,max=100"`
+	// 输出格式：jpeg、png、webp，为空时按 Accept 请求头选择
+	Format string `form:"format" binding:"omitempty,oneof=jpeg png webp"`
+	// 文件密码（加密文件必填，只能在登录会话中使用）
+	FilePassword string `form:"file_password"`
+}
+
+// TimelineRequest 照片时间线分组请求
+type TimelineRequest struct {
+	// 分组方式：year、month（默认）、day
+	Group string `form:"group" binding:"omitempty,oneof=year month day"`
+}
+
+// TimelineFilesRequest 时间线中某一天、月或年的照片列表请求
+type TimelineFilesRequest struct {
+	// 日期：yyyy、yyyy-mm 或 yyyy-mm-dd（与分组返回的 date 相同）
+	Date string `form:"date" binding:"required"`
+	// 页码（从1开始）
+	Page int `form:"page" binding:"omitempty,min=1"`
+	// 每页数量
+	PageSize int `form:"pageSize" binding:"required,min=1,max=100"`
+}
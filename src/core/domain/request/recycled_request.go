@@ -9,6 +9,9 @@ type RecycledListRequest struct {
 // RestoreFileRequest 还原文件请求
 type RestoreFileRequest struct {
 	RecycledID string `json:"recycled_id" binding:"required"`
+	// Conflict 还原目录时原位置已存在同名目录的处理方式：rename 重命名、merge 合并、overwrite 覆盖（原目录移入回收站）
+	// 为空时遇到同名目录返回 409，由用户选择处理方式后重新提交
+	Conflict string `json:"conflict" binding:"omitempty,oneof=rename merge overwrite"`
 }
 
 // DeleteRecycledRequest 永久删除文件请求
@@ -29,3 +29,12 @@ type ShareDownloadRequest struct {
 	// 分享密码（如果有）
 	Password string `json:"password"`
 }
+
+// ShareImageRequest 分享图片衍生图请求
+type ShareImageRequest struct {
+	ImageDerivativeRequest
+	// 分享Token
+	Token string `form:"token" binding:"required"`
+	// 分享密码（如果有）
+	Password string `form:"password"`
+}
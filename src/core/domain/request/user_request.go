@@ -5,6 +5,8 @@ type UserLoginRequest struct {
 	Username  string `json:"username" binding:"required"`
 	Password  string `json:"password" binding:"required"`
 	Challenge string `json:"challenge"`
+	// 记住我：会话绝对有效期延长为 remember_me_days 天，且使用持久 Cookie
+	RememberMe bool `json:"remember_me"`
 }
 
 // UserRegisterRequest 用户注册请求结构体
@@ -15,6 +17,8 @@ type UserRegisterRequest struct {
 	Username  string `json:"username" binding:"required"`
 	Password  string `json:"password" binding:"required"`
 	Challenge string `json:"challenge"` //挑战ID
+	// 邀请令牌：通过管理员邀请注册，不受 allow_register 限制
+	InviteToken string `json:"invite_token"`
 }
 
 // UserUpdateRequest 用户更新请求结构体
@@ -47,10 +51,82 @@ type UserSetFilePasswordRequest struct {
 
 // GenerateApiKeyRequest 生成API Key请求结构体
 type GenerateApiKeyRequest struct {
-	ExpiresDays int `json:"expires_days"` // 过期天数，0表示永不过期
+	ExpiresDays int      `json:"expires_days"`          // 过期天数，0表示永不过期
+	Name        string   `json:"name" binding:"max=64"` // 名称（用途说明）
+	Scopes      []string `json:"scopes"`                // 允许的权限标识，为空表示继承用户组全部权限
+	RootDirID   int      `json:"root_dir_id"`           // 可访问的目录ID，0表示整个空间
+	AllowedIPs  []string `json:"allowed_ips"`           // 允许使用的IP或网段（CIDR），为空表示不限制
 }
 
 // DeleteApiKeyRequest 删除API Key请求结构体
 type DeleteApiKeyRequest struct {
 	ApiKeyID int `json:"api_key_id" binding:"required"` // API Key ID
+}
+
+// RevokeSessionRequest 注销会话请求结构体
+type RevokeSessionRequest struct {
+	SessionID string `json:"session_id" binding:"required"` // 会话ID
+}
+
+// CreateAppPasswordRequest 创建应用专用密码请求结构体
+type CreateAppPasswordRequest struct {
+	Name      string `json:"name" binding:"required,max=64"` // 名称（设备或客户端名称）
+	ReadOnly  bool   `json:"read_only"`                      // 是否只读
+	RootDirID int    `json:"root_dir_id"`                    // 可访问的目录ID，0表示整个空间
+}
+
+// DeleteAppPasswordRequest 删除应用专用密码请求结构体
+type DeleteAppPasswordRequest struct {
+	ID int `json:"id" binding:"required"` // 应用专用密码ID
+}
+// TwoFactorLoginRequest 两步验证登录请求结构体（登录第二步）
+type TwoFactorLoginRequest struct {
+	Ticket    string `json:"ticket" binding:"required"`    // 第一步登录返回的票据
+	Code      string `json:"code" binding:"required"`      // 使用挑战公钥加密的验证码或恢复码
+	Challenge string `json:"challenge" binding:"required"` // 挑战ID
+}
+
+// TwoFactorTicketRequest 两步验证票据请求结构体（所在组要求开启但尚未绑定时，登录过程中获取密钥）
+type TwoFactorTicketRequest struct {
+	Ticket string `json:"ticket" binding:"required"` // 第一步登录返回的票据
+}
+
+// ExpiredPasswordChangeRequest 登录密码过期时修改密码请求结构体
+type ExpiredPasswordChangeRequest struct {
+	Ticket    string `json:"ticket" binding:"required"`     // 登录返回的改密票据
+	NewPasswd string `json:"new_passwd" binding:"required"` // 使用挑战公钥加密的新密码
+	Challenge string `json:"challenge" binding:"required"`  // 挑战ID
+}
+
+// TwoFactorCodeRequest 两步验证码请求结构体（启用、关闭、重新生成恢复码）
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // 验证码或恢复码
+}
+
+// OIDCExchangeRequest 单点登录换取令牌请求结构体
+type OIDCExchangeRequest struct {
+	Code       string `json:"code" binding:"required"` // 回调跳转时附带的一次性登录码（oidc_code）
+	RememberMe bool   `json:"remember_me"`             // 记住我
+}
+
+// RefreshTokenRequest 刷新令牌请求结构体
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"` // 刷新令牌，为空时使用 RefreshToken Cookie
+}
+
+// OIDCUnlinkRequest 解除单点登录绑定请求结构体
+type OIDCUnlinkRequest struct {
+	ID int `json:"id" binding:"required"` // 绑定ID
+}
+
+// MailAccountRequest 重发验证邮件、找回密码请求结构体
+type MailAccountRequest struct {
+	Account string `json:"account" binding:"required"` // 用户名或邮箱
+}
+
+// ResetPasswordRequest 通过邮件链接重置密码请求结构体
+type ResetPasswordRequest struct {
+	Token     string `json:"token" binding:"required"`    // 邮件链接中的 reset_token
+	Password  string `json:"password" binding:"required"` // 使用挑战加密的新密码
+	Challenge string `json:"challenge"`                   // 挑战ID
 }
\ No newline at end of file
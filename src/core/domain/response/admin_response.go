@@ -49,6 +49,53 @@ type AdminSystemConfigResponse struct {
 	Uptime        string `json:"uptime,omitempty"`
 }
 
+// AdminSecurityEventListResponse 管理员安全事件日志列表响应
+type AdminSecurityEventListResponse struct {
+	Events   []*models.SecurityEvent `json:"events"`
+	Total    int64                   `json:"total"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"page_size"`
+}
+
+// AdminWebDAVLockListResponse 管理员 WebDAV 锁列表响应
+type AdminWebDAVLockListResponse struct {
+	Locks    []*AdminWebDAVLockInfo `json:"locks"`
+	Total    int64                  `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"page_size"`
+}
+
+// AdminWebDAVLockInfo WebDAV 锁信息（包含用户名）
+type AdminWebDAVLockInfo struct {
+	models.WebDAVLock
+	UserName string `json:"user_name,omitempty"`
+}
+
+// AdminInvitationListResponse 管理员注册邀请列表响应
+type AdminInvitationListResponse struct {
+	Invitations []*AdminInvitationInfo `json:"invitations"`
+	Total       int64                  `json:"total"`
+	Page        int                    `json:"page"`
+	PageSize    int                    `json:"page_size"`
+}
+
+// AdminInvitationInfo 注册邀请信息（包含用户组名称与状态）
+type AdminInvitationInfo struct {
+	models.Invitation
+	GroupName string `json:"group_name,omitempty"`
+	// 状态：pending 待使用、used 已使用、expired 已过期
+	Status string `json:"status"`
+}
+
+// AdminInvitationCreateResponse 创建注册邀请响应
+type AdminInvitationCreateResponse struct {
+	Invitation *models.Invitation `json:"invitation"`
+	// 邀请链接（邮件未启用或发送失败时可由管理员自行转发）
+	Link string `json:"link"`
+	// 邀请邮件是否已发送
+	Sent bool `json:"sent"`
+}
+
 // PackageCreateResponse 创建打包下载响应
 type PackageCreateResponse struct {
 	PackageID   string `json:"package_id"`
@@ -68,3 +115,27 @@ type PackageProgressResponse struct {
 	ErrorMsg    string `json:"error_msg,omitempty"`
 }
 
+
+// AdminAuditLogListResponse 管理员审计日志列表响应
+type AdminAuditLogListResponse struct {
+	Logs     []*models.AuditLog `json:"logs"`
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+}
+
+// AdminAuditVerifyResponse 审计日志哈希链校验响应
+type AdminAuditVerifyResponse struct {
+	// HashChain 当前是否启用哈希链
+	HashChain bool `json:"hash_chain"`
+	// Valid 是否校验通过
+	Valid bool `json:"valid"`
+	// Checked 检查的记录数
+	Checked int `json:"checked"`
+	// Chained 带哈希的记录数
+	Chained int `json:"chained"`
+	// BrokenID 第一条校验失败的记录ID
+	BrokenID int `json:"broken_id,omitempty"`
+	// Reason 校验失败原因
+	Reason string `json:"reason,omitempty"`
+}
@@ -87,3 +87,27 @@ type StartTorrentDownloadResponse struct {
 	// 创建的任务数量
 	TaskCount int `json:"task_count"`
 }
+
+// ConvertTarget 可转换的目标格式
+type ConvertTarget struct {
+	// 格式标识（下载时的 format 参数）
+	Format string `json:"format"`
+	// 格式名称
+	Name string `json:"name"`
+	// 转换结果的 MIME 类型
+	Mime string `json:"mime"`
+	// 转换后的文件名
+	FileName string `json:"file_name"`
+}
+
+// ConvertOptionsResponse 可转换格式响应
+type ConvertOptionsResponse struct {
+	// 文件ID
+	FileID string `json:"file_id"`
+	// 文件名
+	FileName string `json:"file_name"`
+	// 文件 MIME 类型
+	Mime string `json:"mime"`
+	// 可转换的目标格式（文件超过转换大小上限时为空）
+	Targets []*ConvertTarget `json:"targets"`
+}
@@ -1,6 +1,11 @@
 package response
 
-import "myobj/src/pkg/custom_type"
+import (
+	"encoding/json"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/filecopy"
+	"myobj/src/pkg/models"
+)
 
 // FileListResponse 文件列表响应结构体
 type FileListResponse struct {
@@ -18,6 +23,8 @@ type FileListResponse struct {
 	Page int `json:"page"`
 	// 每页数量
 	PageSize int `json:"page_size"`
+	// 下一页文件的游标（本页已列完目录且还有更多文件时返回）
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // Breadcrumb 面包屑项
@@ -42,10 +49,12 @@ type FileItem struct {
 	FileName     string               `json:"file_name"`
 	FileSize     int                  `json:"file_size"`
 	MimeType     string               `json:"mime_type"`
+	Category     string               `json:"category"` // 文件分类
 	IsEnc        bool                 `json:"is_enc"`
 	HasThumbnail bool                 `json:"has_thumbnail"` // 是否有缩略图
 	Public       bool                 `json:"public"`        // 是否公开
 	CreatedAt    custom_type.JsonTime `json:"created_at"`
+	UpdatedAt    custom_type.JsonTime `json:"updated_at"`
 }
 
 // FileDir 文件目录结构体
@@ -168,6 +177,8 @@ type PublicFileListResponse struct {
 	Page int `json:"page"`
 	// 每页数量
 	PageSize int `json:"page_size"`
+	// 下一页的游标（还有更多文件时返回）
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // UploadTaskItem 上传任务列表项（不包含敏感信息）
@@ -213,3 +224,152 @@ type UploadTaskListResponse struct {
 	// 每页数量
 	PageSize int `json:"page_size"`
 }
+
+// CopyTaskResponse 复制任务响应（文件数超过阈值时转为后台任务）
+type CopyTaskResponse struct {
+	// 任务ID
+	TaskID string `json:"task_id"`
+	// 任务状态（running/done/failed）
+	Status string `json:"status"`
+	// 要复制的文件与目录总数
+	Total int `json:"total"`
+	// 已处理数量
+	Done int `json:"done"`
+	// 进度（0-100）
+	Progress int `json:"progress"`
+	// 要复制的文件总大小
+	TotalSize int64 `json:"total_size"`
+	// 复制结果（完成后返回）
+	Result *filecopy.Result `json:"result,omitempty"`
+	// 错误信息
+	ErrorMsg string `json:"error_msg,omitempty"`
+}
+
+// SmartFolderItem 智能文件夹
+type SmartFolderItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// 过滤与排序条件（keyword、path、type、ext、min_size 等，与保存时提交的字段相同）
+	Filter    json.RawMessage      `json:"filter"`
+	CreatedAt custom_type.JsonTime `json:"created_at"`
+	UpdatedAt custom_type.JsonTime `json:"updated_at"`
+}
+
+// CategoryStatItem 单个文件分类的统计
+type CategoryStatItem struct {
+	Category string `json:"category"`
+	// 文件数量
+	Count int64 `json:"count"`
+	// 占用空间（字节）
+	Size int64 `json:"size"`
+}
+
+// CategoryStatsResponse 文件分类统计响应
+type CategoryStatsResponse struct {
+	// 各分类的统计（包含全部分类，没有文件的分类数量为 0）
+	Categories []*CategoryStatItem `json:"categories"`
+	// 全部文件的数量与占用空间
+	TotalCount int64 `json:"total_count"`
+	TotalSize  int64 `json:"total_size"`
+}
+
+// CategoryFilesResponse 分类文件列表响应
+type CategoryFilesResponse struct {
+	Category string      `json:"category"`
+	Files    []*FileItem `json:"files"`
+	// 该分类的文件总数
+	Total int64 `json:"total"`
+	// 当前页
+	Page int `json:"page"`
+	// 每页数量
+	PageSize int `json:"page_size"`
+	// 下一页的游标（还有更多文件时返回）
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SmartFolderFilesResponse 智能文件夹文件列表响应
+type SmartFolderFilesResponse struct {
+	Folder *SmartFolderItem `json:"folder"`
+	Files  []*FileItem      `json:"files"`
+	// 满足条件的文件总数
+	Total int64 `json:"total"`
+	// 下一页的游标（还有更多文件时返回）
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TextFileResponse 文本文件内容响应
+type TextFileResponse struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	Content  string `json:"content"`
+	// 文本编码（自动检测或请求指定）
+	Charset string `json:"charset"`
+	// 文件内容的哈希，保存时原样传回用于检测并发修改
+	ETag      string               `json:"etag"`
+	Size      int                  `json:"size"`
+	Mime      string               `json:"mime"`
+	UpdatedAt custom_type.JsonTime `json:"updated_at"`
+}
+
+// TextFileSaveResponse 保存文本文件响应
+type TextFileSaveResponse struct {
+	FileID  string `json:"file_id"`
+	ETag    string `json:"etag"`
+	Size    int    `json:"size"`
+	Charset string `json:"charset"`
+	// 文件内容与其他用户文件共享或已公开时，保存为当前用户文件的独立副本
+	Copied bool `json:"copied"`
+}
+
+// TimelineGroupItem 时间线中的一个分组
+type TimelineGroupItem struct {
+	// 日期：按年分组为 yyyy，按月为 yyyy-mm，按天为 yyyy-mm-dd
+	Date string `json:"date"`
+	// 照片数量
+	Count int64 `json:"count"`
+}
+
+// TimelineResponse 照片时间线分组响应
+type TimelineResponse struct {
+	Group string `json:"group"`
+	// 各分组按日期倒序
+	Groups []*TimelineGroupItem `json:"groups"`
+	// 时间线中的照片总数
+	Total int64 `json:"total"`
+}
+
+// TimelineFileItem 时间线中的照片
+type TimelineFileItem struct {
+	*FileItem
+	// 拍摄时间（没有 EXIF 拍摄时间时为空，按上传日期归入时间线）
+	TakenAt custom_type.JsonTime `json:"taken_at"`
+	// 时间线日期（yyyy-mm-dd）
+	TakenDate string `json:"taken_date"`
+	// 按 EXIF 方向校正后的显示宽高
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// 相机厂商与型号
+	CameraMake  string `json:"camera_make,omitempty"`
+	CameraModel string `json:"camera_model,omitempty"`
+}
+
+// TimelineFilesResponse 时间线照片列表响应
+type TimelineFilesResponse struct {
+	Date  string              `json:"date"`
+	Files []*TimelineFileItem `json:"files"`
+	// 该日期的照片总数
+	Total int64 `json:"total"`
+	// 当前页
+	Page int `json:"page"`
+	// 每页数量
+	PageSize int `json:"page_size"`
+}
+
+// FileMetadataResponse 文件媒体元数据响应
+type FileMetadataResponse struct {
+	// 用户文件ID（uf_id）
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	// 元数据（非所有者访问公开文件且启用 strip_gps 时不返回经纬度）
+	Metadata *models.FileMetadata `json:"metadata"`
+}
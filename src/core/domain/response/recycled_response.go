@@ -12,6 +12,11 @@ type RecycledItem struct {
 	IsEnc        bool                 `json:"is_enc"`
 	HasThumbnail bool                 `json:"has_thumbnail"`
 	DeletedAt    custom_type.JsonTime `json:"deleted_at"`
+	IsDir        bool                 `json:"is_dir"` // 是否为目录条目（FileName 为目录名，FileSize 为其中文件的总大小）
+	DirID        int                  `json:"dir_id,omitempty"`
+	OriginalPath string               `json:"original_path,omitempty"` // 删除前的完整路径
+	FileCount    int                  `json:"file_count,omitempty"`    // 目录中的文件数
+	DirCount     int                  `json:"dir_count,omitempty"`     // 目录中的子目录数
 }
 
 // RecycledListResponse 回收站列表响应
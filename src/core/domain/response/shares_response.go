@@ -6,6 +6,8 @@ type SharesDownloadResponse struct {
 	Temp     string `json:"temp"`
 	Err      string `json:"err"`
 	FileName string `json:"file_name"`
+	// RetryAfter 密码错误次数过多时需要等待的秒数
+	RetryAfter int `json:"retry_after"`
 }
 
 // ShareInfoResponse 分享信息响应（不触发下载）
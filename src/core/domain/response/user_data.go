@@ -1,6 +1,7 @@
 package response
 
 import (
+	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/models"
 )
 
@@ -9,6 +10,34 @@ type UserLoginResponse struct {
 	Token string           `json:"token"`
 	User  *models.UserInfo `json:"user_info"`
 	Power []*models.Power  `json:"power"`
+	// 登录过程中首次绑定两步验证时返回的恢复码（只返回一次）
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// 文件密码已超过有效期（仅提醒，不影响使用）
+	FilePasswordExpired bool `json:"file_password_expired,omitempty"`
+	// 刷新令牌，访问令牌过期后用于换取新令牌（每次使用后轮换）
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// 访问令牌有效期（秒）
+	ExpiresIn int `json:"expires_in,omitempty"`
+	// 会话（刷新令牌）剩余有效期（秒）
+	SessionExpiresIn int `json:"session_expires_in,omitempty"`
+	// 是否为"记住我"会话
+	RememberMe bool `json:"remember_me,omitempty"`
+}
+
+// PasswordExpiredResponse 登录密码已过期时的响应，需使用票据修改密码后完成登录
+type PasswordExpiredResponse struct {
+	// 改密票据，调用 /user/login/changePassword 时提交（10 分钟内有效）
+	Ticket string `json:"ticket"`
+	// 登录过程中首次绑定两步验证时返回的恢复码（只返回一次）
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TwoFactorLoginResponse 需要两步验证时第一步登录的响应
+type TwoFactorLoginResponse struct {
+	// 登录票据，第二步登录时提交（5 分钟内有效）
+	Ticket string `json:"ticket"`
+	// 所在组要求开启两步验证但尚未绑定，需先通过票据获取密钥完成绑定
+	SetupRequired bool `json:"setup_required"`
 }
 
 type UserInfoResponse struct {
@@ -26,6 +55,25 @@ type UserInfoResponse struct {
 	Space int64 `json:"space"`
 	//用户剩余存储空间
 	FreeSpace int64 `json:"free_space"`
-	//用户状态 0正常 1禁用
+	//用户状态 0正常 1禁用 2待验证邮箱
 	State int `json:"state"`
+	//邮箱是否已验证
+	EmailVerified bool `json:"email_verified"`
+}
+
+// UserSessionInfo 用户登录会话信息
+type UserSessionInfo struct {
+	models.UserSession
+	// 是否为当前请求所属的会话
+	Current bool `json:"current"`
+}
+
+// InvitationInfoResponse 注册邀请信息（注册页根据邀请令牌预填）
+type InvitationInfoResponse struct {
+	// 受邀邮箱
+	Email string `json:"email"`
+	// 预设用户组名称
+	GroupName string `json:"group_name"`
+	// 过期时间
+	ExpiresAt custom_type.JsonTime `json:"expires_at"`
 }
@@ -4,25 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"myobj/src/config"
 	"myobj/src/core/domain/request"
 	"myobj/src/core/domain/response"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/cache"
 	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/lock"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/mail"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
 	"myobj/src/pkg/util"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type AdminService struct {
-	factory *impl.RepositoryFactory
+	factory    *impl.RepositoryFactory
+	cacheLocal cache.Cache
 }
 
-func NewAdminService(factory *impl.RepositoryFactory) *AdminService {
+func NewAdminService(factory *impl.RepositoryFactory, cacheLocal cache.Cache) *AdminService {
 	return &AdminService{
-		factory: factory,
+		factory:    factory,
+		cacheLocal: cacheLocal,
 	}
 }
 
@@ -96,7 +109,10 @@ func (a *AdminService) AdminUserList(req *request.AdminUserListRequest) (*models
 }
 
 // AdminCreateUser 创建用户
-func (a *AdminService) AdminCreateUser(req *request.AdminCreateUserRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminCreateUser(req *request.AdminCreateUserRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionUserCreate, audit.TargetUser, req.UserName, fmt.Sprintf("用户组: %d", req.GroupID), res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查用户名是否已存在
@@ -122,7 +138,11 @@ func (a *AdminService) AdminCreateUser(req *request.AdminCreateUserRequest) (*mo
 		return nil, fmt.Errorf("用户存储空间不能超过组存储空间限制（组限制：%d 字节）", group.Space)
 	}
 
-	// 生成密码哈希
+	// 校验密码策略并生成密码哈希
+	passwordPolicy := auth.NewPasswordPolicyManager(a.factory.SysConfig(), a.factory.PasswordHistory())
+	if err := passwordPolicy.Check(ctx, models.PasswordKindLogin, nil, req.Password); err != nil {
+		return nil, err
+	}
 	password, err := util.GeneratePassword(req.Password)
 	if err != nil {
 		logger.LOG.Error("生成密码失败", "error", err)
@@ -148,6 +168,8 @@ func (a *AdminService) AdminCreateUser(req *request.AdminCreateUserRequest) (*mo
 		FreeSpace: req.Space,
 		CreatedAt: custom_type.Now(),
 		State:     0,
+
+		PasswordChangedAt: custom_type.Now(),
 	}
 	if req.Space == 0 && group.Space > 0 {
 		user.Space = group.Space * 1024 * 1024 * 1024
@@ -158,6 +180,9 @@ func (a *AdminService) AdminCreateUser(req *request.AdminCreateUserRequest) (*mo
 		logger.LOG.Error("创建用户失败", "error", err)
 		return nil, err
 	}
+	if err := passwordPolicy.Record(ctx, models.PasswordKindLogin, user.ID, password); err != nil {
+		logger.LOG.Warn("记录历史密码失败", "user_id", user.ID, "error", err)
+	}
 	if err := a.factory.VirtualPath().Create(ctx, &models.VirtualPath{
 		UserID:      user.ID,
 		Path:        "home",
@@ -172,7 +197,10 @@ func (a *AdminService) AdminCreateUser(req *request.AdminCreateUserRequest) (*mo
 }
 
 // AdminUpdateUser 更新用户
-func (a *AdminService) AdminUpdateUser(req *request.AdminUpdateUserRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminUpdateUser(req *request.AdminUpdateUserRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionUserUpdate, audit.TargetUser, req.ID, fmt.Sprintf("用户组: %d, 状态: %d, 空间: %d", req.GroupID, req.State, req.Space), res, err)
+	}()
 	ctx := context.Background()
 
 	// 获取用户
@@ -240,7 +268,10 @@ func (a *AdminService) AdminUpdateUser(req *request.AdminUpdateUserRequest) (*mo
 }
 
 // AdminDeleteUser 删除用户
-func (a *AdminService) AdminDeleteUser(req *request.AdminDeleteUserRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminDeleteUser(req *request.AdminDeleteUserRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionUserDelete, audit.TargetUser, req.ID, "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查用户是否存在
@@ -259,12 +290,18 @@ func (a *AdminService) AdminDeleteUser(req *request.AdminDeleteUserRequest) (*mo
 		logger.LOG.Error("删除用户失败", "error", err)
 		return nil, err
 	}
+	if err := a.factory.PasswordHistory().DeleteByUserID(ctx, req.ID); err != nil {
+		logger.LOG.Warn("删除历史密码失败", "user_id", req.ID, "error", err)
+	}
 
 	return models.NewJsonResponse(200, "删除成功", nil), nil
 }
 
 // AdminToggleUserState 启用/禁用用户
-func (a *AdminService) AdminToggleUserState(req *request.AdminToggleUserStateRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminToggleUserState(req *request.AdminToggleUserStateRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionUserState, audit.TargetUser, req.ID, fmt.Sprintf("状态: %d", req.State), res, err)
+	}()
 	ctx := context.Background()
 
 	user, err := a.factory.User().GetByID(ctx, req.ID)
@@ -308,7 +345,10 @@ func (a *AdminService) AdminGroupList() (*models.JsonResponse, error) {
 }
 
 // AdminCreateGroup 创建组
-func (a *AdminService) AdminCreateGroup(req *request.AdminCreateGroupRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminCreateGroup(req *request.AdminCreateGroupRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionGroupCreate, audit.TargetGroup, req.Name, fmt.Sprintf("空间: %d", req.Space), res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查组名是否已存在
@@ -331,12 +371,18 @@ func (a *AdminService) AdminCreateGroup(req *request.AdminCreateGroupRequest) (*
 		}
 	}
 
+	if req.DisableLocalLogin && !externalLoginEnabled() {
+		return nil, fmt.Errorf("未启用单点登录或 LDAP,不能禁止本地密码登录")
+	}
+
 	group := &models.Group{
-		ID:           maxID + 1,
-		Name:         req.Name,
-		GroupDefault: req.GroupDefault,
-		Space:        req.Space,
-		CreatedAt:    custom_type.Now(),
+		ID:                maxID + 1,
+		Name:              req.Name,
+		GroupDefault:      req.GroupDefault,
+		Space:             req.Space,
+		Require2FA:        req.Require2FA,
+		DisableLocalLogin: req.DisableLocalLogin,
+		CreatedAt:         custom_type.Now(),
 	}
 
 	if err = a.factory.Group().Create(ctx, group); err != nil {
@@ -348,7 +394,10 @@ func (a *AdminService) AdminCreateGroup(req *request.AdminCreateGroupRequest) (*
 }
 
 // AdminUpdateGroup 更新组
-func (a *AdminService) AdminUpdateGroup(req *request.AdminUpdateGroupRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminUpdateGroup(req *request.AdminUpdateGroupRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionGroupUpdate, audit.TargetGroup, fmt.Sprint(req.ID), fmt.Sprintf("名称: %s, 空间: %d", req.Name, req.Space), res, err)
+	}()
 	ctx := context.Background()
 
 	group, err := a.factory.Group().GetByID(ctx, req.ID)
@@ -366,6 +415,15 @@ func (a *AdminService) AdminUpdateGroup(req *request.AdminUpdateGroupRequest) (*
 	if req.GroupDefault >= 0 {
 		group.GroupDefault = req.GroupDefault
 	}
+	if req.Require2FA != nil {
+		group.Require2FA = *req.Require2FA
+	}
+	if req.DisableLocalLogin != nil {
+		if *req.DisableLocalLogin && !externalLoginEnabled() {
+			return nil, fmt.Errorf("未启用单点登录或 LDAP,不能禁止本地密码登录")
+		}
+		group.DisableLocalLogin = *req.DisableLocalLogin
+	}
 
 	if err = a.factory.Group().Update(ctx, group); err != nil {
 		logger.LOG.Error("更新组失败", "error", err)
@@ -376,7 +434,10 @@ func (a *AdminService) AdminUpdateGroup(req *request.AdminUpdateGroupRequest) (*
 }
 
 // AdminDeleteGroup 删除组
-func (a *AdminService) AdminDeleteGroup(req *request.AdminDeleteGroupRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminDeleteGroup(req *request.AdminDeleteGroupRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionGroupDelete, audit.TargetGroup, fmt.Sprint(req.ID), "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 不能删除管理员组（ID = 1）
@@ -449,11 +510,14 @@ func (a *AdminService) AdminPowerList(req *request.AdminPowerListRequest) (*mode
 }
 
 // AdminAssignPower 为组分配权限
-func (a *AdminService) AdminAssignPower(req *request.AdminAssignPowerRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminAssignPower(req *request.AdminAssignPowerRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionPowerAssign, audit.TargetGroup, fmt.Sprint(req.GroupID), fmt.Sprintf("权限: %v", req.PowerIDs), res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查组是否存在
-	_, err := a.factory.Group().GetByID(ctx, req.GroupID)
+	_, err = a.factory.Group().GetByID(ctx, req.GroupID)
 	if err != nil {
 		logger.LOG.Error("查询组失败", "error", err)
 		return nil, fmt.Errorf("组不存在")
@@ -510,7 +574,10 @@ func (a *AdminService) AdminGetGroupPowers(req *request.AdminGetGroupPowersReque
 }
 
 // AdminCreatePower 创建权限
-func (a *AdminService) AdminCreatePower(req *request.AdminCreatePowerRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminCreatePower(req *request.AdminCreatePowerRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionPowerCreate, audit.TargetPower, req.Characteristic, req.Name, res, err)
+	}()
 	ctx := context.Background()
 
 	power := &models.Power{
@@ -529,7 +596,10 @@ func (a *AdminService) AdminCreatePower(req *request.AdminCreatePowerRequest) (*
 }
 
 // AdminUpdatePower 更新权限
-func (a *AdminService) AdminUpdatePower(req *request.AdminUpdatePowerRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminUpdatePower(req *request.AdminUpdatePowerRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionPowerUpdate, audit.TargetPower, fmt.Sprint(req.ID), req.Characteristic, res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查权限是否存在
@@ -559,11 +629,14 @@ func (a *AdminService) AdminUpdatePower(req *request.AdminUpdatePowerRequest) (*
 }
 
 // AdminDeletePower 删除权限
-func (a *AdminService) AdminDeletePower(req *request.AdminDeletePowerRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminDeletePower(req *request.AdminDeletePowerRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionPowerDelete, audit.TargetPower, fmt.Sprint(req.ID), "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查权限是否存在
-	_, err := a.factory.Power().GetByID(ctx, req.ID)
+	_, err = a.factory.Power().GetByID(ctx, req.ID)
 	if err != nil {
 		logger.LOG.Error("查询权限失败", "error", err)
 		return nil, fmt.Errorf("权限不存在")
@@ -584,7 +657,10 @@ func (a *AdminService) AdminDeletePower(req *request.AdminDeletePowerRequest) (*
 }
 
 // AdminBatchDeletePower 批量删除权限
-func (a *AdminService) AdminBatchDeletePower(req *request.AdminBatchDeletePowerRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminBatchDeletePower(req *request.AdminBatchDeletePowerRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionPowerDelete, audit.TargetPower, fmt.Sprint(req.IDs), "", res, err)
+	}()
 	ctx := context.Background()
 
 	var successCount int
@@ -664,7 +740,10 @@ func (a *AdminService) AdminDiskList() (*models.JsonResponse, error) {
 }
 
 // AdminCreateDisk 创建磁盘
-func (a *AdminService) AdminCreateDisk(req *request.AdminCreateDiskRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminCreateDisk(req *request.AdminCreateDiskRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionDiskCreate, audit.TargetDisk, req.DiskPath, fmt.Sprintf("数据目录: %s, 大小: %dGB", req.DataPath, req.Size), res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查路径是否已存在
@@ -696,7 +775,10 @@ func (a *AdminService) AdminCreateDisk(req *request.AdminCreateDiskRequest) (*mo
 }
 
 // AdminUpdateDisk 更新磁盘
-func (a *AdminService) AdminUpdateDisk(req *request.AdminUpdateDiskRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminUpdateDisk(req *request.AdminUpdateDiskRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionDiskUpdate, audit.TargetDisk, req.ID, fmt.Sprintf("磁盘: %s, 数据目录: %s, 大小: %dGB", req.DiskPath, req.DataPath, req.Size), res, err)
+	}()
 	ctx := context.Background()
 
 	disk, err := a.factory.Disk().GetByID(ctx, req.ID)
@@ -724,11 +806,14 @@ func (a *AdminService) AdminUpdateDisk(req *request.AdminUpdateDiskRequest) (*mo
 }
 
 // AdminDeleteDisk 删除磁盘
-func (a *AdminService) AdminDeleteDisk(req *request.AdminDeleteDiskRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminDeleteDisk(req *request.AdminDeleteDiskRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionDiskDelete, audit.TargetDisk, req.ID, "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查磁盘是否存在
-	_, err := a.factory.Disk().GetByID(ctx, req.ID)
+	_, err = a.factory.Disk().GetByID(ctx, req.ID)
 	if err != nil {
 		logger.LOG.Error("查询磁盘失败", "error", err)
 		return nil, fmt.Errorf("磁盘不存在")
@@ -770,7 +855,10 @@ func (a *AdminService) AdminGetSystemConfig() (*models.JsonResponse, error) {
 }
 
 // AdminUpdateSystemConfig 更新系统配置
-func (a *AdminService) AdminUpdateSystemConfig(req *request.AdminUpdateSystemConfigRequest) (*models.JsonResponse, error) {
+func (a *AdminService) AdminUpdateSystemConfig(req *request.AdminUpdateSystemConfigRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionConfigUpdate, audit.TargetConfig, "system", fmt.Sprintf("allow_register=%t, webdav_enabled=%t", req.AllowRegister, req.WebdavEnabled), res, err)
+	}()
 	ctx := context.Background()
 
 	configs := make([]*models.SysConfig, 0)
@@ -826,3 +914,472 @@ func (a *AdminService) AdminUpdateSystemConfig(req *request.AdminUpdateSystemCon
 
 	return a.AdminGetSystemConfig()
 }
+
+// ========== 会话管理 ==========
+
+// AdminListUserSessions 获取指定用户的登录会话列表
+func (a *AdminService) AdminListUserSessions(req *request.AdminUserSessionListRequest) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	if _, err := a.factory.User().GetByID(ctx, req.UserID); err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	sessions, err := auth.NewSessionManager(a.factory.UserSession(), a.factory.RefreshToken()).List(ctx, req.UserID)
+	if err != nil {
+		logger.LOG.Error("查询用户会话失败", "user_id", req.UserID, "error", err)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "查询成功", sessions), nil
+}
+
+// AdminRevokeUserSession 注销指定用户的会话，SessionID 为空时注销该用户的所有会话
+func (a *AdminService) AdminRevokeUserSession(req *request.AdminRevokeUserSessionRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionSessionRevoke, audit.TargetSession, req.UserID, "会话: "+req.SessionID, res, err)
+	}()
+	ctx := context.Background()
+	sessions := auth.NewSessionManager(a.factory.UserSession(), a.factory.RefreshToken())
+	if req.SessionID == "" {
+		count, err := sessions.RevokeAll(ctx, req.UserID, "")
+		if err != nil {
+			logger.LOG.Error("注销用户会话失败", "user_id", req.UserID, "error", err)
+			return nil, fmt.Errorf("注销会话失败: %w", err)
+		}
+		logger.LOG.Info("管理员注销用户所有会话", "user_id", req.UserID, "count", count)
+		return models.NewJsonResponse(200, "注销成功", map[string]interface{}{"count": count}), nil
+	}
+	if err := sessions.Revoke(ctx, req.UserID, req.SessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return nil, err
+		}
+		logger.LOG.Error("注销用户会话失败", "user_id", req.UserID, "session_id", req.SessionID, "error", err)
+		return nil, fmt.Errorf("注销会话失败: %w", err)
+	}
+	logger.LOG.Info("管理员注销用户会话", "user_id", req.UserID, "session_id", req.SessionID)
+	return models.NewJsonResponse(200, "注销成功", map[string]interface{}{"count": 1}), nil
+}
+
+// AdminResetTwoFactor 重置用户的两步验证（用户丢失验证器且恢复码用尽时使用）
+// 所在组要求开启两步验证时，用户下次登录需重新绑定
+func (a *AdminService) AdminResetTwoFactor(req *request.AdminResetTwoFactorRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionUserResetTwoFactor, audit.TargetUser, req.UserID, "", res, err)
+	}()
+	ctx := context.Background()
+	if _, err := a.factory.User().GetByID(ctx, req.UserID); err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if err := auth.NewTwoFactorManager(a.factory.TwoFactor(), a.factory.Group()).Reset(ctx, req.UserID); err != nil {
+		logger.LOG.Error("重置两步验证失败", "user_id", req.UserID, "error", err)
+		return nil, fmt.Errorf("重置两步验证失败: %w", err)
+	}
+	logger.LOG.Info("管理员重置用户两步验证", "user_id", req.UserID)
+	return models.NewJsonResponse(200, "重置成功", nil), nil
+}
+
+// AdminUnlockUser 解除用户的登录锁定（账户失败次数过多被临时锁定时使用）
+// actor: 执行操作的管理员，记录到安全事件日志与审计日志
+func (a *AdminService) AdminUnlockUser(req *request.AdminUnlockUserRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionUserUnlock, audit.TargetUser, req.UserID, "", res, err)
+	}()
+	ctx := context.Background()
+	user, err := a.factory.User().GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	operator := actor.UserName
+	if operator == "" {
+		operator = actor.UserID
+	}
+	limiter := auth.NewLoginLimiter(a.cacheLocal, a.factory.SecurityEvent(), config.CONFIG.Auth.LoginLimit)
+	if err := limiter.Unlock(ctx, user.UserName, auth.LimitChannelAdmin, operator); err != nil {
+		return nil, fmt.Errorf("解除锁定失败: %w", err)
+	}
+	return models.NewJsonResponse(200, "解除成功", nil), nil
+}
+
+// ========== 安全事件日志 ==========
+
+// AdminSecurityEventList 分页查询安全事件日志（登录失败、锁定与解锁）
+func (a *AdminService) AdminSecurityEventList(req *request.AdminSecurityEventListRequest) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	offset := (req.Page - 1) * req.PageSize
+
+	total, err := a.factory.SecurityEvent().Count(ctx, req.Type, req.Target)
+	if err != nil {
+		logger.LOG.Error("统计安全事件数量失败", "error", err)
+		return nil, err
+	}
+	events, err := a.factory.SecurityEvent().List(ctx, req.Type, req.Target, offset, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询安全事件列表失败", "error", err)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "查询成功", response.AdminSecurityEventListResponse{
+		Events:   events,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}), nil
+}
+
+// ========== 注册邀请 ==========
+
+// AdminCreateInvitation 创建注册邀请并发送邀请邮件
+// 邮件未启用或发送失败时仍然创建邀请，由管理员自行转发返回的链接
+func (a *AdminService) AdminCreateInvitation(req *request.AdminCreateInvitationRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionInvitationCreate, audit.TargetInvitation, req.Email, fmt.Sprintf("用户组: %d", req.GroupID), res, err)
+	}()
+	ctx := context.Background()
+
+	group, err := a.factory.Group().GetByID(ctx, req.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("用户组不存在")
+	}
+	if group.Space > 0 && req.Space > group.Space {
+		return nil, fmt.Errorf("存储空间不能超过组存储空间限制（组限制：%d 字节）", group.Space)
+	}
+	token, hash, err := newMailToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	invitation := &models.Invitation{
+		Email:     req.Email,
+		GroupID:   req.GroupID,
+		Space:     req.Space,
+		TokenHash: hash,
+		CreatedBy: actor.UserID,
+		CreatedAt: custom_type.JsonTime(now),
+		ExpiresAt: custom_type.JsonTime(now.AddDate(0, 0, config.CONFIG.Mail.InviteExpire)),
+	}
+	if err = a.factory.Invitation().Create(ctx, invitation); err != nil {
+		logger.LOG.Error("创建邀请失败", "error", err)
+		return nil, err
+	}
+
+	mailer := newMailer()
+	link := mailer.Link("/", url.Values{"invite_token": {token}})
+	sent := false
+	if mailer.Enabled() {
+		inviter := actor.UserName
+		if inviter == "" {
+			inviter = "管理员"
+		}
+		if err := mailer.Send(req.Email, mail.TemplateInvitation, map[string]any{
+			"InviterName": inviter,
+			"GroupName":   group.Name,
+			"Link":        link,
+			"ExpireDays":  config.CONFIG.Mail.InviteExpire,
+		}); err != nil {
+			logger.LOG.Error("发送邀请邮件失败", "email", req.Email, "error", err)
+		} else {
+			sent = true
+		}
+	}
+	return models.NewJsonResponse(200, "创建成功", response.AdminInvitationCreateResponse{
+		Invitation: invitation,
+		Link:       link,
+		Sent:       sent,
+	}), nil
+}
+
+// AdminInvitationList 分页查询注册邀请
+func (a *AdminService) AdminInvitationList(req *request.AdminInvitationListRequest) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	offset := (req.Page - 1) * req.PageSize
+
+	total, err := a.factory.Invitation().Count(ctx)
+	if err != nil {
+		logger.LOG.Error("统计邀请数量失败", "error", err)
+		return nil, err
+	}
+	invitations, err := a.factory.Invitation().List(ctx, offset, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询邀请列表失败", "error", err)
+		return nil, err
+	}
+	groupNames := make(map[int]string)
+	if groups, err := a.factory.Group().List(ctx, 0, 1000); err == nil {
+		for _, group := range groups {
+			groupNames[group.ID] = group.Name
+		}
+	}
+	now := time.Now()
+	list := make([]*response.AdminInvitationInfo, 0, len(invitations))
+	for _, invitation := range invitations {
+		status := "pending"
+		if !invitation.UsedAt.IsZero() {
+			status = "used"
+		} else if now.After(time.Time(invitation.ExpiresAt)) {
+			status = "expired"
+		}
+		list = append(list, &response.AdminInvitationInfo{
+			Invitation: *invitation,
+			GroupName:  groupNames[invitation.GroupID],
+			Status:     status,
+		})
+	}
+	return models.NewJsonResponse(200, "查询成功", response.AdminInvitationListResponse{
+		Invitations: list,
+		Total:       total,
+		Page:        req.Page,
+		PageSize:    req.PageSize,
+	}), nil
+}
+
+// AdminDeleteInvitation 删除（撤销）注册邀请
+func (a *AdminService) AdminDeleteInvitation(req *request.AdminDeleteInvitationRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionInvitationDelete, audit.TargetInvitation, strconv.Itoa(req.ID), "", res, err)
+	}()
+	ctx := context.Background()
+	if _, err = a.factory.Invitation().GetByID(ctx, req.ID); err != nil {
+		return nil, fmt.Errorf("邀请不存在")
+	}
+	if err = a.factory.Invitation().Delete(ctx, req.ID); err != nil {
+		logger.LOG.Error("删除邀请失败", "error", err)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "删除成功", nil), nil
+}
+
+// ========== 审计日志 ==========
+
+// maxAuditExportRows 单次导出的最大记录数
+const maxAuditExportRows = 100000
+
+// AdminAuditLogFilter 解析审计日志查询条件
+func (a *AdminService) AdminAuditLogFilter(req *request.AdminAuditLogFilter) (repository.AuditLogFilter, error) {
+	filter := repository.AuditLogFilter{
+		ActorID:    req.ActorID,
+		ActorName:  req.ActorName,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Result:     req.Result,
+		Source:     req.Source,
+		IP:         req.IP,
+	}
+	if req.Start != "" {
+		start, _, err := parseAuditTime(req.Start)
+		if err != nil {
+			return filter, fmt.Errorf("开始时间格式错误")
+		}
+		filter.Start = &start
+	}
+	if req.End != "" {
+		end, dateOnly, err := parseAuditTime(req.End)
+		if err != nil {
+			return filter, fmt.Errorf("结束时间格式错误")
+		}
+		if dateOnly {
+			end = end.Add(24*time.Hour - time.Second)
+		}
+		filter.End = &end
+	}
+	return filter, nil
+}
+
+// parseAuditTime 解析查询时间，支持只有日期
+func parseAuditTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	return t, true, err
+}
+
+// AdminAuditLogList 分页查询审计日志
+func (a *AdminService) AdminAuditLogList(req *request.AdminAuditLogListRequest) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	filter, err := a.AdminAuditLogFilter(&req.AdminAuditLogFilter)
+	if err != nil {
+		return nil, err
+	}
+	offset := (req.Page - 1) * req.PageSize
+
+	total, err := a.factory.AuditLog().Count(ctx, filter)
+	if err != nil {
+		logger.LOG.Error("统计审计日志数量失败", "error", err)
+		return nil, err
+	}
+	logs, err := a.factory.AuditLog().List(ctx, filter, offset, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询审计日志列表失败", "error", err)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "查询成功", response.AdminAuditLogListResponse{
+		Logs:     logs,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}), nil
+}
+
+// AdminExportAuditLogs 按ID顺序分批导出审计日志（最多 maxAuditExportRows 条），导出操作本身也记录审计日志
+func (a *AdminService) AdminExportAuditLogs(filter repository.AuditLogFilter, format string, w io.Writer, actor audit.Actor) (err error) {
+	count := 0
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionAuditExport, audit.TargetAuditLog, "", fmt.Sprintf("格式: %s, 记录数: %d", format, count), nil, err)
+	}()
+	exporter, err := audit.NewExporter(w, format)
+	if err != nil {
+		return err
+	}
+	const batch = 1000
+	ctx := context.Background()
+	afterID := 0
+	for count < maxAuditExportRows {
+		limit := min(batch, maxAuditExportRows-count)
+		logs, err := a.factory.AuditLog().ListAfter(ctx, filter, afterID, limit)
+		if err != nil {
+			logger.LOG.Error("导出审计日志失败", "error", err)
+			return err
+		}
+		if err := exporter.Write(logs); err != nil {
+			return err
+		}
+		count += len(logs)
+		if len(logs) < limit {
+			break
+		}
+		afterID = logs[len(logs)-1].ID
+	}
+	return exporter.Close()
+}
+
+// AdminAuditLogVerify 校验审计日志哈希链
+func (a *AdminService) AdminAuditLogVerify() (*models.JsonResponse, error) {
+	result, err := audit.Verify(context.Background(), a.factory.AuditLog())
+	if err != nil {
+		logger.LOG.Error("校验审计日志失败", "error", err)
+		return nil, fmt.Errorf("校验审计日志失败: %w", err)
+	}
+	if result.BrokenID != 0 {
+		logger.LOG.Warn("审计日志哈希链校验失败", "id", result.BrokenID, "reason", result.Reason)
+	}
+	return models.NewJsonResponse(200, "校验完成", response.AdminAuditVerifyResponse{
+		HashChain: config.CONFIG.Audit.HashChain,
+		Valid:     result.BrokenID == 0,
+		Checked:   result.Checked,
+		Chained:   result.Chained,
+		BrokenID:  result.BrokenID,
+		Reason:    result.Reason,
+	}), nil
+}
+
+// AdminGetPasswordPolicy 获取登录密码与文件密码策略
+func (a *AdminService) AdminGetPasswordPolicy() (*models.JsonResponse, error) {
+	ctx := context.Background()
+	manager := auth.NewPasswordPolicyManager(a.factory.SysConfig(), a.factory.PasswordHistory())
+	policy, err := manager.Policy(ctx, models.PasswordKindLogin)
+	if err != nil {
+		logger.LOG.Error("查询密码策略失败", "error", err)
+		return nil, err
+	}
+	filePolicy, err := manager.Policy(ctx, models.PasswordKindFile)
+	if err != nil {
+		logger.LOG.Error("查询文件密码策略失败", "error", err)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "查询成功", map[string]*auth.PasswordPolicy{
+		"policy":      policy,
+		"file_policy": filePolicy,
+	}), nil
+}
+
+// AdminUpdatePasswordPolicy 更新密码策略
+// 新策略只约束之后设置的密码；有效期按密码修改时间计算，缩短有效期后已超期的用户会在下次登录时被要求修改密码
+func (a *AdminService) AdminUpdatePasswordPolicy(req *request.AdminUpdatePasswordPolicyRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionConfigUpdate, audit.TargetConfig, "password_policy", fmt.Sprintf("登录密码: %+v, 文件密码: %+v", req.Policy, req.FilePolicy), res, err)
+	}()
+	ctx := context.Background()
+	manager := auth.NewPasswordPolicyManager(a.factory.SysConfig(), a.factory.PasswordHistory())
+	if err = manager.SavePolicy(ctx, models.PasswordKindLogin, toPasswordPolicy(req.Policy)); err != nil {
+		logger.LOG.Error("保存密码策略失败", "error", err)
+		return nil, err
+	}
+	if err = manager.SavePolicy(ctx, models.PasswordKindFile, toPasswordPolicy(req.FilePolicy)); err != nil {
+		logger.LOG.Error("保存文件密码策略失败", "error", err)
+		return nil, err
+	}
+	return a.AdminGetPasswordPolicy()
+}
+
+// toPasswordPolicy 请求参数转换为密码策略
+func toPasswordPolicy(req request.PasswordPolicyRequest) *auth.PasswordPolicy {
+	return &auth.PasswordPolicy{
+		MinLength:      req.MinLength,
+		RequireUpper:   req.RequireUpper,
+		RequireLower:   req.RequireLower,
+		RequireDigit:   req.RequireDigit,
+		RequireSymbol:  req.RequireSymbol,
+		DisallowCommon: req.DisallowCommon,
+		HistoryCount:   req.HistoryCount,
+		MaxAgeDays:     req.MaxAgeDays,
+	}
+}
+
+// ========== WebDAV 锁管理 ==========
+
+// AdminWebDAVLockList 获取未过期的 WebDAV 锁列表
+func (a *AdminService) AdminWebDAVLockList(req *request.AdminWebDAVLockListRequest) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	offset := (req.Page - 1) * req.PageSize
+
+	total, err := a.factory.WebDAVLock().Count(ctx, req.UserID)
+	if err != nil {
+		logger.LOG.Error("统计 WebDAV 锁数量失败", "error", err)
+		return nil, err
+	}
+	locks, err := a.factory.WebDAVLock().List(ctx, req.UserID, offset, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询 WebDAV 锁列表失败", "error", err)
+		return nil, err
+	}
+
+	// 填充用户名
+	lockInfos := make([]*response.AdminWebDAVLockInfo, 0, len(locks))
+	userNames := make(map[string]string)
+	for _, lock := range locks {
+		userName, ok := userNames[lock.UserID]
+		if !ok {
+			if user, err := a.factory.User().GetByID(ctx, lock.UserID); err == nil && user != nil {
+				userName = user.UserName
+			}
+			userNames[lock.UserID] = userName
+		}
+		lockInfos = append(lockInfos, &response.AdminWebDAVLockInfo{
+			WebDAVLock: *lock,
+			UserName:   userName,
+		})
+	}
+
+	return models.NewJsonResponse(200, "查询成功", response.AdminWebDAVLockListResponse{
+		Locks:    lockInfos,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}), nil
+}
+
+// AdminBreakWebDAVLock 强制解除 WebDAV 锁
+func (a *AdminService) AdminBreakWebDAVLock(req *request.AdminBreakWebDAVLockRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(a.factory, actor, audit.ActionWebDAVLockBreak, audit.TargetWebDAVLock, req.Token, "", res, err)
+	}()
+	ctx := context.Background()
+	l, err := lock.NewManager(a.factory).Break(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, lock.ErrNoSuchLock) {
+			return nil, err
+		}
+		logger.LOG.Error("解除 WebDAV 锁失败", "token", req.Token, "error", err)
+		return nil, fmt.Errorf("解除锁失败: %w", err)
+	}
+	logger.LOG.Info("管理员解除 WebDAV 锁", "user_id", l.UserID, "root", l.Root)
+	return models.NewJsonResponse(200, "解除成功", nil), nil
+}
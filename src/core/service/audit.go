@@ -0,0 +1,18 @@
+package service
+
+import (
+	"errors"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/models"
+)
+
+// recordAudit 记录审计日志
+// err 不为空或 res 的状态码不是 200 时记为失败
+func recordAudit(factory *impl.RepositoryFactory, actor audit.Actor, action, targetType, targetID, detail string, res *models.JsonResponse, err error) {
+	if err == nil && res != nil && res.Code != 200 {
+		err = errors.New(res.Message)
+	}
+	audit.NewRecorder(factory.AuditLog(), config.CONFIG.Audit).Record(actor, action, targetType, targetID, detail, err)
+}
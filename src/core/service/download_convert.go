@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/convert"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConvertedFile 下载时的格式转换结果
+type ConvertedFile struct {
+	// Path 转换结果路径
+	Path string
+	// FileName 转换后的文件名
+	FileName string
+	// Temp 是否为临时文件（加密文件的转换结果不缓存，传输完成后删除）
+	Temp bool
+}
+
+// ConvertCache 格式转换结果缓存
+func (d *DownloadService) ConvertCache() *convert.Cache {
+	return d.convertCache
+}
+
+// convertMaxSize 参与转换的文件大小上限（字节）
+func convertMaxSize() int64 {
+	maxSize := 200
+	if config.CONFIG != nil && config.CONFIG.Convert.MaxFileSize > 0 {
+		maxSize = config.CONFIG.Convert.MaxFileSize
+	}
+	return int64(maxSize) * 1024 * 1024
+}
+
+// convertedFileName 转换后的文件名：替换扩展名，目标格式没有扩展名时（如文本编码转换）沿用原文件名
+func convertedFileName(fileName string, target convert.Target) string {
+	if target.Ext == "" {
+		return fileName
+	}
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + target.Ext
+}
+
+// GetConvertOptions 查询文件下载时可转换的目标格式（自己的文件或公开文件）
+func (d *DownloadService) GetConvertOptions(req *request.ConvertOptionsRequest, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	userFile, err := d.factory.UserFiles().GetByUfID(ctx, req.FileID)
+	if err != nil {
+		return models.NewJsonResponse(404, "文件不存在", nil), nil
+	}
+	if !userFile.IsPublic && userFile.UserID != userID {
+		return models.NewJsonResponse(403, "无权访问此文件", nil), nil
+	}
+	fileInfo, err := d.factory.FileInfo().GetByID(ctx, userFile.FileID)
+	if err != nil {
+		logger.LOG.Error("获取文件信息失败", "error", err, "fileID", userFile.FileID)
+		return nil, fmt.Errorf("文件不存在")
+	}
+
+	result := &response.ConvertOptionsResponse{
+		FileID:   userFile.UfID,
+		FileName: userFile.FileName,
+		Mime:     fileInfo.Mime,
+		Targets:  []*response.ConvertTarget{},
+	}
+	if int64(fileInfo.Size) <= convertMaxSize() {
+		for _, target := range convert.Targets(fileInfo.Mime) {
+			result.Targets = append(result.Targets, &response.ConvertTarget{
+				Format:   target.Format,
+				Name:     target.Name,
+				Mime:     target.Mime,
+				FileName: convertedFileName(userFile.FileName, target),
+			})
+		}
+	}
+	return models.NewJsonResponse(200, "查询成功", result), nil
+}
+
+// ConvertLocalFile 将已准备完成的网盘文件下载任务转换为 format 格式
+// 转换结果按 (文件hash, 目标格式) 缓存；加密文件或没有全量hash的文件转换到临时文件，不写入缓存
+func (d *DownloadService) ConvertLocalFile(ctx context.Context, task *models.DownloadTask, format string) (*ConvertedFile, error) {
+	// 网盘文件下载任务的 URL 字段存储 uf_id
+	userFile, err := d.factory.UserFiles().GetByUfID(ctx, task.URL)
+	if err != nil {
+		return nil, fmt.Errorf("文件不存在: %w", err)
+	}
+	fileInfo, err := d.factory.FileInfo().GetByID(ctx, userFile.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("文件不存在: %w", err)
+	}
+	stat, err := os.Stat(task.Path)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() > convertMaxSize() {
+		return nil, convert.ErrTooLarge
+	}
+
+	if !fileInfo.IsEnc && fileInfo.HasFullHash && fileInfo.FileHash != "" {
+		path, target, err := d.convertCache.Get(ctx, fileInfo.FileHash, fileInfo.Mime, format, task.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &ConvertedFile{Path: path, FileName: convertedFileName(task.FileName, target)}, nil
+	}
+
+	tmp, err := os.CreateTemp(d.tempDir, "convert-*")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	target, err := convert.Convert(ctx, fileInfo.Mime, format, task.Path, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	return &ConvertedFile{Path: tmpPath, FileName: convertedFileName(task.FileName, target), Temp: true}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"myobj/src/core/domain/request"
 	"myobj/src/core/domain/response"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/convert"
 	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/download"
 	"myobj/src/pkg/enum"
@@ -20,8 +21,9 @@ import (
 
 // DownloadService 下载服务
 type DownloadService struct {
-	factory *impl.RepositoryFactory
-	tempDir string // 临时目录
+	factory      *impl.RepositoryFactory
+	tempDir      string         // 临时目录
+	convertCache *convert.Cache // 格式转换结果缓存
 }
 
 func NewDownloadService(factory *impl.RepositoryFactory) *DownloadService {
@@ -46,8 +48,9 @@ func NewDownloadService(factory *impl.RepositoryFactory) *DownloadService {
 	}
 
 	return &DownloadService{
-		factory: factory,
-		tempDir: tempDir,
+		factory:      factory,
+		tempDir:      tempDir,
+		convertCache: convert.NewCache(filepath.Join(filepath.Dir(tempDir), "convert")),
 	}
 }
 
@@ -25,7 +25,7 @@ func NewServiceFactory(factory *impl.RepositoryFactory, cacheLocal cache.Cache)
 		shareService:    NewSharesService(factory, cacheLocal),
 		downloadService: NewDownloadService(factory),
 		recycledService: NewRecycledService(factory, cacheLocal),
-		adminService:    NewAdminService(factory),
+		adminService:    NewAdminService(factory, cacheLocal),
 	}
 }
 
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+)
+
+// GetCategoryStats 按分类统计当前用户全部目录中的文件数量与占用空间
+func (f *FileService) GetCategoryStats(userID string) (*models.JsonResponse, error) {
+	stats, err := f.factory.UserFiles().StatByCategory(context.Background(), userID)
+	if err != nil {
+		logger.LOG.Error("统计文件分类失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	byCategory := make(map[string]*repository.CategoryStat, len(stats))
+	for _, stat := range stats {
+		byCategory[stat.Category] = stat
+	}
+	resp := &response.CategoryStatsResponse{Categories: make([]*response.CategoryStatItem, 0, len(util.FileCategories))}
+	for _, category := range util.FileCategories {
+		item := &response.CategoryStatItem{Category: category}
+		if stat, ok := byCategory[category]; ok {
+			item.Count, item.Size = stat.Count, stat.Size
+		}
+		resp.Categories = append(resp.Categories, item)
+		resp.TotalCount += item.Count
+		resp.TotalSize += item.Size
+	}
+	return models.NewJsonResponse(200, "获取成功", resp), nil
+}
+
+// ListCategoryFiles 分页查询当前用户全部目录中属于该分类的文件
+func (f *FileService) ListCategoryFiles(name string, req *request.CategoryFilesRequest, userID string) (*models.JsonResponse, error) {
+	category := util.NormalizeFileCategory(name)
+	if category == "" {
+		return models.NewJsonResponse(404, "不支持的文件分类", nil), nil
+	}
+	query, err := parseFileQuery(&request.FileFilterRequest{Type: category}, req.SortBy, req.Cursor)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	query.filter.UserID = userID
+
+	ctx := context.Background()
+	userFiles, total, next, err := f.listFilteredFiles(ctx, query, req.Page, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询分类文件失败", "error", err, "userID", userID, "category", category)
+		return nil, err
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	return models.NewJsonResponse(200, "获取成功", &response.CategoryFilesResponse{
+		Category:   category,
+		Files:      f.fileItems(ctx, userFiles),
+		Total:      total,
+		Page:       page,
+		PageSize:   req.PageSize,
+		NextCursor: next,
+	}), nil
+}
+
+// fileCategory 文件的分类，升级前上传、尚未划分分类的文件按 MIME 类型与文件名即时判断
+func fileCategory(name string, fileInfo *models.FileInfo) string {
+	if fileInfo.Category != "" {
+		return fileInfo.Category
+	}
+	return util.FileCategory(name, fileInfo.Mime)
+}
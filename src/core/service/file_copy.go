@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/filecopy"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 复制任务状态管理（文件数超过 copy_async_threshold 时转为后台任务）
+var copyTasks sync.Map // key: taskID, value: *CopyTask
+
+// copyTaskRetention 复制任务完成后保留进度的时长
+const copyTaskRetention = time.Hour
+
+// CopyTask 后台复制任务
+type CopyTask struct {
+	TaskID    string
+	UserID    string
+	Status    string // running, done, failed
+	Total     int
+	Done      int
+	TotalSize int64
+	Result    *filecopy.Result
+	ErrorMsg  string
+	mu        sync.Mutex
+}
+
+// response 构造任务进度响应
+func (t *CopyTask) response() response.CopyTaskResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := 100
+	if t.Total > 0 && t.Status == "running" {
+		progress = t.Done * 100 / t.Total
+	}
+	return response.CopyTaskResponse{
+		TaskID:    t.TaskID,
+		Status:    t.Status,
+		Total:     t.Total,
+		Done:      t.Done,
+		Progress:  progress,
+		TotalSize: t.TotalSize,
+		Result:    t.Result,
+		ErrorMsg:  t.ErrorMsg,
+	}
+}
+
+// CopyFiles 复制文件与目录
+// 复制只新建用户文件记录并指向同一个文件（不复制物理文件），配额按 copy_quota 策略扣除；
+// 要复制的文件与目录数超过 copy_async_threshold 时转为后台任务，通过 GetCopyProgress 查询进度
+func (f *FileService) CopyFiles(req *request.CopyFileRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	detail := fmt.Sprintf("文件 %d 个，目录 %d 个", len(req.FileIDs), len(req.DirIDs))
+	if req.Conflict != "" {
+		detail += "，冲突处理: " + req.Conflict
+	}
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFileCopy, audit.TargetDir, fmt.Sprint(req.TargetDirID), detail, res, err)
+	}()
+	ctx := context.Background()
+
+	if len(req.FileIDs) == 0 && len(req.DirIDs) == 0 {
+		return models.NewJsonResponse(400, "请选择要复制的文件或目录", nil), nil
+	}
+	sources := make([]filecopy.Source, 0, len(req.FileIDs)+len(req.DirIDs))
+	for _, fileID := range req.FileIDs {
+		sources = append(sources, filecopy.Source{FileID: fileID})
+	}
+	for _, dirID := range req.DirIDs {
+		sources = append(sources, filecopy.Source{DirID: dirID})
+	}
+
+	// 1. 校验复制源与目标目录并统计
+	copier := filecopy.NewCopier(f.factory, userID)
+	plan, err := copier.Plan(ctx, sources, req.TargetDirID)
+	if err != nil {
+		return copyErrorResponse(err)
+	}
+
+	// 2. 目标位置不能被 WebDAV 锁定（覆盖时同名文件会被移入回收站）
+	lockManager := lock.NewManager(f.factory)
+	if dirPath, err := lockManager.DirPath(ctx, req.TargetDirID); err == nil {
+		var targets []string
+		for _, name := range plan.Names() {
+			targets = append(targets, path.Join(dirPath, name))
+		}
+		if resp := webDAVLockResponse(lockManager.CheckUnlocked(ctx, userID, targets...)); resp != nil {
+			return resp, nil
+		}
+	}
+
+	// 3. 数量较少时直接复制
+	if plan.Total() <= config.CONFIG.File.CopyAsyncThreshold {
+		result, err := copier.Copy(ctx, plan, req.Conflict)
+		if err != nil {
+			return copyErrorResponse(err)
+		}
+		return models.NewJsonResponse(200, copyMessage(result), result), nil
+	}
+
+	// 4. 数量较多时转为后台任务
+	task := &CopyTask{
+		TaskID:    uuid.New().String(),
+		UserID:    userID,
+		Status:    "running",
+		Total:     plan.Total(),
+		TotalSize: plan.Size,
+	}
+	copyTasks.Store(task.TaskID, task)
+	copier.Progress = func(done int) {
+		task.mu.Lock()
+		task.Done = done
+		task.mu.Unlock()
+	}
+	go f.runCopyTask(task, copier, plan, req.Conflict)
+
+	detail += "，后台任务: " + task.TaskID
+	return models.NewJsonResponse(200, "复制任务已创建", task.response()), nil
+}
+
+// runCopyTask 执行后台复制任务
+func (f *FileService) runCopyTask(task *CopyTask, copier *filecopy.Copier, plan *filecopy.Plan, conflict string) {
+	defer func() {
+		if r := recover(); r != nil {
+			task.mu.Lock()
+			task.Status = "failed"
+			task.ErrorMsg = fmt.Sprintf("复制失败: %v", r)
+			task.mu.Unlock()
+			logger.LOG.Error("复制任务异常", "taskID", task.TaskID, "error", r)
+		}
+		// 保留一段时间供查询进度，之后从任务列表中移除
+		time.AfterFunc(copyTaskRetention, func() {
+			copyTasks.Delete(task.TaskID)
+		})
+	}()
+
+	result, err := copier.Copy(context.Background(), plan, conflict)
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	if err != nil {
+		task.Status = "failed"
+		task.ErrorMsg = err.Error()
+		logger.LOG.Error("复制任务失败", "taskID", task.TaskID, "userID", task.UserID, "error", err)
+		return
+	}
+	task.Status = "done"
+	task.Done = task.Total
+	task.Result = result
+	logger.LOG.Info("复制任务完成", "taskID", task.TaskID, "userID", task.UserID,
+		"files", result.Files, "dirs", result.Dirs, "skipped", result.Skipped)
+}
+
+// GetCopyProgress 获取后台复制任务进度
+func (f *FileService) GetCopyProgress(taskID, userID string) (*models.JsonResponse, error) {
+	value, ok := copyTasks.Load(taskID)
+	if !ok {
+		return models.NewJsonResponse(404, "复制任务不存在或已过期", nil), nil
+	}
+	task := value.(*CopyTask)
+	if task.UserID != userID {
+		return models.NewJsonResponse(403, "无权限访问该复制任务", nil), nil
+	}
+	return models.NewJsonResponse(200, "查询成功", task.response()), nil
+}
+
+// copyErrorResponse 将复制错误转换为响应
+func copyErrorResponse(err error) (*models.JsonResponse, error) {
+	switch {
+	case errors.Is(err, filecopy.ErrNotFound):
+		return models.NewJsonResponse(404, err.Error(), nil), nil
+	case errors.Is(err, filecopy.ErrIntoSelf), errors.Is(err, filecopy.ErrInsufficientSpace):
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	logger.LOG.Error("复制文件失败", "error", err)
+	return nil, fmt.Errorf("复制文件失败: %w", err)
+}
+
+// copyMessage 复制结果提示
+func copyMessage(result *filecopy.Result) string {
+	message := fmt.Sprintf("已复制 %d 个文件", result.Files)
+	if result.Dirs > 0 {
+		message = fmt.Sprintf("%s、%d 个目录", message, result.Dirs)
+	}
+	if result.Skipped > 0 {
+		message = fmt.Sprintf("%s，跳过 %d 项", message, result.Skipped)
+	}
+	return message
+}
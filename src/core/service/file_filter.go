@@ -0,0 +1,192 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+	"strings"
+	"time"
+)
+
+// fileSortFields 排序参数支持的字段
+var fileSortFields = map[string]string{
+	"name":    repository.FileSortName,
+	"size":    repository.FileSortSize,
+	"created": repository.FileSortCreated,
+	"time":    repository.FileSortCreated,
+	"updated": repository.FileSortUpdated,
+	"type":    repository.FileSortType,
+}
+
+// defaultFileSorts 未指定排序时按创建时间倒序
+var defaultFileSorts = []repository.FileSort{{Field: repository.FileSortCreated, Desc: true}}
+
+// fileQuery 文件查询条件
+type fileQuery struct {
+	filter repository.FileFilter
+	sorts  []repository.FileSort
+	// cursor 请求中的游标，没有时为 nil
+	cursor *repository.FileCursor
+}
+
+// parseFileQuery 解析过滤条件、排序与游标，返回的错误信息可直接提示给用户
+func parseFileQuery(filterReq *request.FileFilterRequest, sortBy, cursor string) (*fileQuery, error) {
+	filter, err := buildFileFilter(filterReq)
+	if err != nil {
+		return nil, err
+	}
+	sorts, err := parseFileSorts(sortBy)
+	if err != nil {
+		return nil, err
+	}
+	query := &fileQuery{filter: filter, sorts: sorts}
+	if cursor != "" {
+		if query.cursor, err = decodeFileCursor(sorts, cursor); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
+
+// buildFileFilter 将请求中的过滤条件转换为仓储层的过滤条件，返回的错误信息可直接提示给用户
+func buildFileFilter(req *request.FileFilterRequest) (repository.FileFilter, error) {
+	filter := repository.FileFilter{Encrypted: req.Encrypted, Public: req.Public, Shared: req.Shared}
+	for _, category := range splitList(req.Type) {
+		if category == "all" {
+			filter.Categories = nil
+			break
+		}
+		name := util.NormalizeFileCategory(category)
+		if name == "" {
+			return filter, fmt.Errorf("不支持的文件类型: %s", category)
+		}
+		filter.Categories = append(filter.Categories, name)
+	}
+	for _, ext := range splitList(req.Ext) {
+		filter.Exts = append(filter.Exts, strings.ToLower(strings.TrimPrefix(ext, ".")))
+	}
+	if req.MaxSize > 0 && req.MinSize > req.MaxSize {
+		return filter, errors.New("文件大小范围错误")
+	}
+	filter.MinSize, filter.MaxSize = req.MinSize, req.MaxSize
+
+	var err error
+	if filter.CreatedStart, err = parseFilterTime(req.Start, false); err != nil {
+		return filter, errors.New("开始时间格式错误")
+	}
+	if filter.CreatedEnd, err = parseFilterTime(req.End, true); err != nil {
+		return filter, errors.New("结束时间格式错误")
+	}
+	if filter.UpdatedStart, err = parseFilterTime(req.UpdatedStart, false); err != nil {
+		return filter, errors.New("修改开始时间格式错误")
+	}
+	if filter.UpdatedEnd, err = parseFilterTime(req.UpdatedEnd, true); err != nil {
+		return filter, errors.New("修改结束时间格式错误")
+	}
+	return filter, nil
+}
+
+// parseFilterTime 解析过滤条件中的时间，end 为 true 且只有日期时包含当天
+func parseFilterTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, dateOnly, err := parseAuditTime(value)
+	if err != nil {
+		return nil, err
+	}
+	if end && dateOnly {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return &t, nil
+}
+
+// parseFileSorts 解析排序参数，如 "type,size:desc"；name、type 默认升序，其他字段默认降序
+// 返回的错误信息可直接提示给用户
+func parseFileSorts(sortBy string) ([]repository.FileSort, error) {
+	items := splitList(sortBy)
+	if len(items) == 0 {
+		return defaultFileSorts, nil
+	}
+	sorts := make([]repository.FileSort, 0, len(items))
+	seen := make(map[string]bool)
+	for _, item := range items {
+		name, direction, _ := strings.Cut(item, ":")
+		field, ok := fileSortFields[name]
+		if !ok {
+			return nil, fmt.Errorf("不支持的排序字段: %s", name)
+		}
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		s := repository.FileSort{Field: field, Desc: field != repository.FileSortName && field != repository.FileSortType}
+		switch direction {
+		case "":
+		case "asc":
+			s.Desc = false
+		case "desc":
+			s.Desc = true
+		default:
+			return nil, fmt.Errorf("不支持的排序方向: %s", direction)
+		}
+		sorts = append(sorts, s)
+	}
+	return sorts, nil
+}
+
+// splitList 拆分逗号分隔的参数，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fileCursorToken 返回给客户端的游标，记录生成游标时的排序，排序改变后游标失效
+type fileCursorToken struct {
+	Sort string `json:"s"`
+	repository.FileCursor
+}
+
+// sortKey 排序条件的标识
+func sortKey(sorts []repository.FileSort) string {
+	keys := make([]string, len(sorts))
+	for i, s := range sorts {
+		keys[i] = s.Field
+		if s.Desc {
+			keys[i] += ":desc"
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// encodeFileCursor 编码游标，cursor 为空时返回空字符串
+func encodeFileCursor(sorts []repository.FileSort, cursor *repository.FileCursor) string {
+	if cursor == nil {
+		return ""
+	}
+	data, _ := json.Marshal(fileCursorToken{Sort: sortKey(sorts), FileCursor: *cursor})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeFileCursor 解码游标并校验与当前排序一致
+func decodeFileCursor(sorts []repository.FileSort, token string) (*repository.FileCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("无效的游标")
+	}
+	var cursor fileCursorToken
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sortKey(sorts) ||
+		(cursor.UfID != "" && len(cursor.Values) != len(sorts)) {
+		return nil, errors.New("无效的游标")
+	}
+	return &cursor.FileCursor, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/download"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/preview"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ImageDerivative 图片衍生图
+type ImageDerivative struct {
+	// Path 衍生图路径
+	Path string
+	// ContentType 衍生图的 MIME 类型
+	ContentType string
+	// TempDir 传输完成后需要删除的临时目录（加密文件的衍生图不缓存）
+	TempDir string
+	// Private 是否禁止浏览器与代理缓存（加密文件）
+	Private bool
+}
+
+var (
+	derivativeCacheOnce sync.Once
+	derivativeCache     *preview.DerivativeCache
+)
+
+// imageDerivativeCache 衍生图缓存，位于最大磁盘的 {DataPath}/temp/derivatives
+func imageDerivativeCache(factory *impl.RepositoryFactory) *preview.DerivativeCache {
+	derivativeCacheOnce.Do(func() {
+		dir := "./obj_temp/derivatives"
+		if disk, err := factory.Disk().GetBigDisk(context.Background()); err == nil && disk != nil {
+			dir = filepath.Join(disk.DataPath, "temp", "derivatives")
+		} else {
+			logger.LOG.Warn("获取最大磁盘失败，使用默认衍生图缓存目录", "error", err)
+		}
+		derivativeCache = preview.NewDerivativeCache(dir, int64(config.CONFIG.File.ImageCacheSize)*1024*1024)
+	})
+	return derivativeCache
+}
+
+// derivativeSources 支持生成衍生图的图片类型
+var derivativeSources = []string{"image/png", "image/jpeg", "image/gif", "image/bmp", "image/x-ms-bmp", "image/tiff", "image/webp"}
+
+// imageDerivativeOptions 校验请求参数并转为衍生图参数，未指定格式时按 Accept 请求头选择：
+// 接受 WebP 且已配置 ffmpeg 时输出 WebP，否则 PNG/GIF 原图输出 PNG（保留透明），其余输出 JPEG
+func imageDerivativeOptions(req *request.ImageDerivativeRequest, accept, sourceMime string) (preview.DerivativeOptions, *models.JsonResponse) {
+	opts := preview.DerivativeOptions{Width: req.Width, Height: req.Height, Fit: req.Fit, Quality: req.Quality, Format: req.Format}
+	if opts.Width == 0 && opts.Height == 0 {
+		return opts, models.NewJsonResponse(400, "需要指定宽度或高度", nil)
+	}
+	for _, size := range []int{opts.Width, opts.Height} {
+		if size != 0 && !slices.Contains(config.CONFIG.File.ImageSizes, size) {
+			return opts, models.NewJsonResponse(400, "不支持的尺寸", config.CONFIG.File.ImageSizes)
+		}
+	}
+	if opts.Fit == "" || opts.Width == 0 || opts.Height == 0 {
+		opts.Fit = preview.FitContain
+	}
+	if opts.Quality == 0 {
+		opts.Quality = config.CONFIG.File.ImageQuality
+	}
+	base, _, _ := mime.ParseMediaType(sourceMime)
+	switch {
+	case opts.Format != "":
+		if !preview.FormatAvailable(opts.Format) {
+			return opts, models.NewJsonResponse(400, "不支持的输出格式", opts.Format)
+		}
+	case strings.Contains(accept, "image/webp") && preview.FormatAvailable(preview.FormatWebP):
+		opts.Format = preview.FormatWebP
+	case base == "image/png" || base == "image/gif":
+		opts.Format = preview.FormatPNG
+	default:
+		opts.Format = preview.FormatJPEG
+	}
+	if opts.Format == preview.FormatPNG {
+		opts.Quality = 0 // PNG 无损，质量不影响结果
+	}
+	return opts, nil
+}
+
+// buildImageDerivative 生成文件的衍生图
+// 未加密且有全量hash的文件按 (hash, 参数) 缓存；加密文件解密到临时目录生成，不写入缓存
+func buildImageDerivative(ctx context.Context, factory *impl.RepositoryFactory, fileInfo *models.FileInfo, ownerID, password string,
+	opts preview.DerivativeOptions) (*ImageDerivative, error) {
+	src := fileInfo.Path
+	var tempDir string
+	if fileInfo.IsEnc || fileInfo.IsChunk {
+		result, err := download.PrepareLocalFileDownload(ctx, fileInfo.ID, ownerID, "", factory,
+			&download.LocalFileDownloadOptions{FilePassword: password})
+		if err != nil {
+			return nil, err
+		}
+		src, tempDir = result.TempFilePath, filepath.Dir(result.TempFilePath)
+	}
+
+	if !fileInfo.IsEnc && fileInfo.HasFullHash && fileInfo.FileHash != "" {
+		if tempDir != "" {
+			defer os.RemoveAll(tempDir)
+		}
+		path, err := imageDerivativeCache(factory).Get(ctx, fileInfo.FileHash, src, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &ImageDerivative{Path: path, ContentType: opts.ContentType()}, nil
+	}
+
+	if tempDir == "" {
+		parent := filepath.Dir(imageDerivativeCache(factory).Dir())
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return nil, err
+		}
+		var err error
+		if tempDir, err = os.MkdirTemp(parent, "derivative_*"); err != nil {
+			return nil, err
+		}
+	}
+	output := filepath.Join(tempDir, "derivative"+opts.Ext())
+	if err := preview.GenerateImageDerivative(ctx, src, output, opts); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	return &ImageDerivative{Path: output, ContentType: opts.ContentType(), TempDir: tempDir, Private: fileInfo.IsEnc}, nil
+}
+
+// GetImageDerivative 获取图片文件（自己的文件或公开文件）指定尺寸的衍生图
+// 加密文件只能由文件所有者在登录会话中提供文件密码查看（API Key 与未登录请求不可用）
+func (f *FileService) GetImageDerivative(ctx context.Context, ufID, userID string, session bool, req *request.ImageDerivativeRequest,
+	accept string) (*ImageDerivative, *models.JsonResponse, error) {
+	userFile, err := f.factory.UserFiles().GetByUfID(ctx, ufID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.NewJsonResponse(404, "文件不存在", nil), nil
+		}
+		return nil, nil, err
+	}
+	if !userFile.IsPublic && userFile.UserID != userID {
+		return nil, models.NewJsonResponse(403, "无权访问此文件", nil), nil
+	}
+	fileInfo, err := f.factory.FileInfo().GetByID(ctx, userFile.FileID)
+	if err != nil {
+		logger.LOG.Error("获取文件信息失败", "error", err, "fileID", userFile.FileID)
+		return nil, nil, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	base, _, _ := mime.ParseMediaType(fileInfo.Mime)
+	if !slices.Contains(derivativeSources, base) {
+		return nil, models.NewJsonResponse(400, "不支持该文件类型", fileInfo.Mime), nil
+	}
+	if fileInfo.IsEnc {
+		if !session || userFile.UserID != userID {
+			return nil, models.NewJsonResponse(403, "加密文件只能由所有者在登录会话中查看", nil), nil
+		}
+		if resp, err := f.checkFilePassword(ctx, fileInfo, userID, req.FilePassword); resp != nil || err != nil {
+			return nil, resp, err
+		}
+	}
+	opts, resp := imageDerivativeOptions(req, accept, fileInfo.Mime)
+	if resp != nil {
+		return nil, resp, nil
+	}
+	derivative, err := buildImageDerivative(ctx, f.factory, fileInfo, userFile.UserID, req.FilePassword, opts)
+	if err != nil {
+		logger.LOG.Error("生成图片衍生图失败", "error", err, "fileID", fileInfo.ID, "params", opts.Key())
+		return nil, nil, fmt.Errorf("生成图片失败: %w", err)
+	}
+	return derivative, nil, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/filemove"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"path"
+)
+
+// MoveBatch 批量移动文件与目录到同一个目标目录
+// 目录连同其中的子目录与文件整体移动，整批在一个事务中完成；
+// 目标目录已存在同名项且未指定 conflict 时返回 409 及冲突的名称
+func (f *FileService) MoveBatch(req *request.BatchMoveRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	detail := fmt.Sprintf("文件 %d 个，目录 %d 个", len(req.FileIDs), len(req.DirIDs))
+	if req.Conflict != "" {
+		detail += "，冲突处理: " + req.Conflict
+	}
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFileMoveBatch, audit.TargetDir, fmt.Sprint(req.TargetDirID), detail, res, err)
+	}()
+	ctx := context.Background()
+
+	if len(req.FileIDs) == 0 && len(req.DirIDs) == 0 {
+		return models.NewJsonResponse(400, "请选择要移动的文件或目录", nil), nil
+	}
+	sources := make([]filemove.Source, 0, len(req.FileIDs)+len(req.DirIDs))
+	for _, fileID := range req.FileIDs {
+		sources = append(sources, filemove.Source{FileID: fileID})
+	}
+	for _, dirID := range req.DirIDs {
+		sources = append(sources, filemove.Source{DirID: dirID})
+	}
+
+	// 1. 校验移动源与目标目录
+	mover := filemove.NewMover(f.factory, userID)
+	plan, err := mover.Plan(ctx, sources, req.TargetDirID)
+	if err != nil {
+		return moveErrorResponse(err)
+	}
+	if req.Conflict == "" && len(plan.Conflicts) > 0 {
+		return models.NewJsonResponse(409, "目标目录已存在同名文件或目录，请选择跳过、重命名或覆盖", map[string]interface{}{
+			"names":     plan.Conflicts,
+			"conflicts": []string{filemove.ConflictSkip, filemove.ConflictRename, filemove.ConflictOverwrite},
+		}), nil
+	}
+
+	// 2. 移动源与目标位置都不能被 WebDAV 锁定
+	lockManager := lock.NewManager(f.factory)
+	var targets []string
+	for _, fileID := range req.FileIDs {
+		userFile, err := f.factory.UserFiles().GetByUserIDAndUfID(ctx, userID, fileID)
+		if err != nil {
+			continue
+		}
+		if filePath, err := lockManager.FilePath(ctx, userFile); err == nil {
+			targets = append(targets, filePath)
+		}
+	}
+	for _, dirID := range req.DirIDs {
+		if dirPath, err := lockManager.DirPath(ctx, dirID); err == nil {
+			targets = append(targets, dirPath)
+		}
+	}
+	if dirPath, err := lockManager.DirPath(ctx, req.TargetDirID); err == nil {
+		for _, name := range plan.Names() {
+			targets = append(targets, path.Join(dirPath, name))
+		}
+	}
+	if resp := webDAVLockResponse(lockManager.CheckUnlocked(ctx, userID, targets...)); resp != nil {
+		return resp, nil
+	}
+
+	// 3. 移动
+	result, err := mover.Move(ctx, plan, req.Conflict)
+	if err != nil {
+		return moveErrorResponse(err)
+	}
+	return models.NewJsonResponse(200, moveMessage(result), result), nil
+}
+
+// moveErrorResponse 将移动错误转换为响应
+func moveErrorResponse(err error) (*models.JsonResponse, error) {
+	switch {
+	case errors.Is(err, filemove.ErrNotFound):
+		return models.NewJsonResponse(404, err.Error(), nil), nil
+	case errors.Is(err, filemove.ErrConflict):
+		return models.NewJsonResponse(409, err.Error(), nil), nil
+	case errors.Is(err, filemove.ErrIntoSelf), errors.Is(err, filemove.ErrRoot):
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	logger.LOG.Error("移动文件失败", "error", err)
+	return nil, fmt.Errorf("移动文件失败: %w", err)
+}
+
+// moveMessage 移动结果提示
+func moveMessage(result *filemove.Result) string {
+	message := fmt.Sprintf("已移动 %d 个文件", result.Files)
+	if result.Dirs > 0 {
+		message = fmt.Sprintf("%s、%d 个目录", message, result.Dirs)
+	}
+	if result.Merged > 0 {
+		message = fmt.Sprintf("%s，合并 %d 个目录", message, result.Merged)
+	}
+	if result.Skipped > 0 {
+		message = fmt.Sprintf("%s，跳过 %d 项", message, result.Skipped)
+	}
+	return message
+}
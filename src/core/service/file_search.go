@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/dirtree"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/search"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SearchResultItem 全文检索结果中的文件
+type SearchResultItem struct {
+	*models.FileInfo
+	UfID      string `json:"uf_id"`
+	FileName  string `json:"file_name"`
+	IsPublic  bool   `json:"public"`
+	OwnerName string `json:"owner_name,omitempty"`
+	// 相关度评分
+	Score float64 `json:"score"`
+	// 文件名与内容摘要的高亮（HTML，命中词条用 <mark> 标记，没有命中时为空）
+	NameHighlight string `json:"name_highlight"`
+	Highlight     string `json:"highlight"`
+}
+
+// searchIndexed 使用全文索引搜索文件，public 为 true 时搜索公开文件，否则搜索 userID 的文件
+func (f *FileService) searchIndexed(req *request.FileSearchRequest, userID string, public bool) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	if req.Cursor != "" {
+		return models.NewJsonResponse(400, "全文检索按相关度排序，不支持游标分页", nil), nil
+	}
+	filter, err := buildFileFilter(&req.FileFilterRequest)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	filter.UserID = userID
+	if public {
+		filter.Public = &public
+	}
+	if req.Path != "" && !public {
+		dirIDs, err := f.searchPathDirs(ctx, userID, req.Path)
+		if err != nil {
+			logger.LOG.Error("查询目录失败", "error", err, "userID", userID, "path", req.Path)
+			return nil, err
+		}
+		if len(dirIDs) == 0 {
+			return models.NewJsonResponse(404, "目录不存在", nil), nil
+		}
+		filter.VirtualPaths = dirIDs
+	}
+
+	result, err := search.NewIndex(f.factory).Search(ctx, search.Query{
+		Keyword: req.Keyword,
+		Filter:  filter,
+		Offset:  (page - 1) * pageSize,
+		Limit:   pageSize,
+	})
+	if err != nil {
+		logger.LOG.Error("全文检索失败", "error", err, "userID", userID, "keyword", req.Keyword)
+		return nil, err
+	}
+
+	files := make([]*SearchResultItem, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		uf, err := f.factory.UserFiles().GetByUfID(ctx, hit.UfID)
+		if err != nil {
+			continue
+		}
+		file, err := f.factory.FileInfo().GetByID(ctx, uf.FileID)
+		if err != nil {
+			continue
+		}
+		item := &SearchResultItem{
+			FileInfo:      file,
+			UfID:          uf.UfID,
+			FileName:      uf.FileName,
+			IsPublic:      uf.IsPublic,
+			Score:         hit.Score,
+			NameHighlight: search.Highlight(uf.FileName, req.Keyword, 0),
+			Highlight:     hit.Highlight,
+		}
+		if public {
+			item.OwnerName = "Unknown"
+			if user, err := f.factory.User().GetByID(ctx, uf.UserID); err == nil && user != nil {
+				item.OwnerName = user.UserName
+			}
+		}
+		files = append(files, item)
+	}
+
+	return models.NewJsonResponse(200, "搜索成功", map[string]interface{}{
+		"files": files,
+		"total": result.Total,
+	}), nil
+}
+
+// searchPathDirs 将路径前缀展开为该目录及其所有子目录的ID，目录不存在时返回空
+func (f *FileService) searchPathDirs(ctx context.Context, userID, dirPath string) ([]string, error) {
+	dirs, err := f.factory.VirtualPath().GetPathByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[string][]*models.VirtualPath)
+	var current *models.VirtualPath
+	for _, dir := range dirs {
+		if dir.ParentLevel == "" {
+			current = dir
+			continue
+		}
+		children[dir.ParentLevel] = append(children[dir.ParentLevel], dir)
+	}
+	if current == nil {
+		return nil, nil
+	}
+	for _, name := range strings.Split(strings.Trim(path.Clean("/"+dirPath), "/"), "/") {
+		if name == "" {
+			continue
+		}
+		var next *models.VirtualPath
+		for _, child := range children[strconv.Itoa(current.ID)] {
+			if dirtree.DirName(child.Path) == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		current = next
+	}
+
+	dirIDs := []string{strconv.Itoa(current.ID)}
+	for i := 0; i < len(dirIDs); i++ {
+		for _, child := range children[dirIDs[i]] {
+			dirIDs = append(dirIDs, strconv.Itoa(child.ID))
+		}
+	}
+	return dirIDs, nil
+}
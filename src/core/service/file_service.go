@@ -9,14 +9,19 @@ import (
 	"myobj/src/core/domain/request"
 	"myobj/src/core/domain/response"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/lock"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/search"
 	"myobj/src/pkg/upload"
 	"os"
+	"path"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -96,6 +101,7 @@ func (f *FileService) Precheck(req *request.UploadPrecheckRequest, c cache.Cache
 					"file_size", req.FileSize,
 					"new_free_space", user.FreeSpace)
 			}
+			search.Notify(user.ID, userFile.UfID)
 			return models.NewJsonResponse(200, "秒传成功", nil), nil
 		}
 	} else {
@@ -130,6 +136,7 @@ func (f *FileService) Precheck(req *request.UploadPrecheckRequest, c cache.Cache
 					"file_size", req.FileSize,
 					"new_free_space", user.FreeSpace)
 			}
+			search.Notify(user.ID, userFile.UfID)
 			return models.NewJsonResponse(200, "秒传成功", nil), nil
 		}
 	}
@@ -227,22 +234,32 @@ func (f *FileService) Precheck(req *request.UploadPrecheckRequest, c cache.Cache
 }
 
 // SearchUserFiles 搜索当前用户的文件
+// 启用全文索引时按文件名与内容检索并按相关度排序，否则只按文件名匹配，支持排序与游标分页
 func (f *FileService) SearchUserFiles(req *request.FileSearchRequest, userID string) (*models.JsonResponse, error) {
+	if search.Enabled() {
+		return f.searchIndexed(req, userID, false)
+	}
 	ctx := context.Background()
-
-	// 默认分页参数
-	page := req.Page
-	if page < 1 {
-		page = 1
+	query, err := parseFileQuery(&req.FileFilterRequest, req.SortBy, req.Cursor)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
 	}
-	pageSize := req.PageSize
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	query.filter.UserID = userID
+	query.filter.Keyword = req.Keyword
+	if req.Path != "" {
+		dirIDs, err := f.searchPathDirs(ctx, userID, req.Path)
+		if err != nil {
+			logger.LOG.Error("查询目录失败", "error", err, "userID", userID, "path", req.Path)
+			return nil, err
+		}
+		if len(dirIDs) == 0 {
+			return models.NewJsonResponse(404, "目录不存在", nil), nil
+		}
+		query.filter.VirtualPaths = dirIDs
 	}
-	offset := (page - 1) * pageSize
 
 	// 搜索用户文件
-	userFiles, err := f.factory.UserFiles().SearchUserFiles(ctx, userID, req.Keyword, offset, pageSize)
+	userFiles, total, next, err := f.listFilteredFiles(ctx, query, req.Page, req.PageSize)
 	if err != nil {
 		logger.LOG.Error("搜索用户文件失败", "error", err, "userID", userID, "keyword", req.Keyword)
 		return nil, err
@@ -270,59 +287,31 @@ func (f *FileService) SearchUserFiles(req *request.FileSearchRequest, userID str
 		})
 	}
 
-	// 统计总数
-	total, err := f.factory.UserFiles().CountUserFilesByKeyword(ctx, userID, req.Keyword)
-	if err != nil {
-		logger.LOG.Error("统计用户文件数量失败", "error", err, "userID", userID, "keyword", req.Keyword)
-		return nil, err
-	}
-
 	result := map[string]interface{}{
-		"files": resultFiles,
-		"total": total,
+		"files":       resultFiles,
+		"total":       total,
+		"next_cursor": next,
 	}
 	return models.NewJsonResponse(200, "搜索成功", result), nil
 }
 
 // SearchPublicFiles 搜索公开文件（广场）
 func (f *FileService) SearchPublicFiles(req *request.FileSearchRequest) (*models.JsonResponse, error) {
-	ctx := context.Background()
-
-	// 默认分页参数
-	page := req.Page
-	if page < 1 {
-		page = 1
-	}
-	pageSize := req.PageSize
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	if search.Enabled() && req.Keyword != "" {
+		return f.searchIndexed(req, "", true)
 	}
-	offset := (page - 1) * pageSize
-
-	var userFiles []*models.UserFiles
-	var total int64
-	var err error
-
-	if req.Keyword != "" {
-		// 根据关键词搜索
-		userFiles, err = f.factory.UserFiles().SearchPublicFiles(ctx, req.Keyword, offset, pageSize)
-		if err != nil {
-			logger.LOG.Error("搜索公开文件失败", "error", err, "keyword", req.Keyword)
-			return nil, err
-		}
-		total, err = f.factory.UserFiles().CountPublicFilesByKeyword(ctx, req.Keyword)
-	} else {
-		// 获取所有公开文件
-		userFiles, err = f.factory.UserFiles().ListPublicFiles(ctx, offset, pageSize)
-		if err != nil {
-			logger.LOG.Error("获取公开文件列表失败", "error", err)
-			return nil, err
-		}
-		total, err = f.factory.UserFiles().CountPublicFiles(ctx)
+	ctx := context.Background()
+	query, err := parseFileQuery(&req.FileFilterRequest, req.SortBy, req.Cursor)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
 	}
+	public := true
+	query.filter.Public = &public
+	query.filter.Keyword = req.Keyword
 
+	userFiles, total, next, err := f.listFilteredFiles(ctx, query, req.Page, req.PageSize)
 	if err != nil {
-		logger.LOG.Error("统计公开文件数量失败", "error", err)
+		logger.LOG.Error("搜索公开文件失败", "error", err, "keyword", req.Keyword)
 		return nil, err
 	}
 
@@ -353,20 +342,52 @@ func (f *FileService) SearchPublicFiles(req *request.FileSearchRequest) (*models
 	}
 
 	result := map[string]interface{}{
-		"files": resultFiles,
-		"total": total,
+		"files":       resultFiles,
+		"total":       total,
+		"next_cursor": next,
 	}
 	return models.NewJsonResponse(200, "搜索成功", result), nil
 }
 
+// listFilteredFiles 按查询条件分页查询文件，请求中有游标时从游标位置开始，否则按页码
+// 返回本页文件、满足条件的文件总数与下一页的游标（没有更多文件时为空）
+func (f *FileService) listFilteredFiles(ctx context.Context, query *fileQuery, page, pageSize int) ([]*models.UserFiles, int64, string, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+	if query.cursor != nil {
+		offset = 0
+	}
+	userFiles, next, err := f.factory.UserFiles().ListByFilter(ctx, query.filter, query.sorts, query.cursor, offset, pageSize)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	total, err := f.factory.UserFiles().CountByFilter(ctx, query.filter)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return userFiles, total, encodeFileCursor(query.sorts, next), nil
+}
+
 // GetFileList 获取文件列表（我的文件页面）
 func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (*models.JsonResponse, error) {
 	ctx := context.Background()
+	query, err := parseFileQuery(&req.FileFilterRequest, req.SortBy, req.Cursor)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
 
 	// 处理虚拟路径ID，空或为0时使用根目录
 	var currentPathID int
 	var currentPath *models.VirtualPath
-	var err error
 
 	if req.VirtualPath == "" || req.VirtualPath == "0" {
 		// 查询用户根目录
@@ -393,7 +414,7 @@ func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (
 		}
 	}
 
-	// 查询总数（子目录 + 文件）
+	// 查询总数（子目录 + 满足条件的文件）
 	folderCount, err := f.factory.VirtualPath().CountSubFoldersByParentID(ctx, userID, currentPathID)
 	if err != nil {
 		logger.LOG.Error("统计子目录数量失败", "error", err, "userID", userID, "pathID", currentPathID)
@@ -401,21 +422,28 @@ func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (
 	}
 	// 文件表中virtual_path字段存的是路径ID（字符串格式）
 	virtualPathIDStr := fmt.Sprintf("%d", currentPathID)
-	fileCount, err := f.factory.FileInfo().CountByVirtualPath(ctx, userID, virtualPathIDStr)
+	query.filter.UserID = userID
+	query.filter.VirtualPaths = []string{virtualPathIDStr}
+	fileCount, err := f.factory.UserFiles().CountByFilter(ctx, query.filter)
 	if err != nil {
 		logger.LOG.Error("统计文件数量失败", "error", err, "userID", userID, "virtualPath", virtualPathIDStr)
 		return nil, err
 	}
 	totalCount := folderCount + fileCount
 
-	// 计算分页偏移量
-	offset := (req.Page - 1) * req.PageSize
-
 	// 优先返回文件夹
 	var folders []*models.VirtualPath
 	var userFiles []*models.UserFiles
+	var next *repository.FileCursor
 
-	if offset < int(folderCount) {
+	if query.cursor != nil {
+		// 游标分页只返回文件，目录已在按页码查询时返回
+		userFiles, next, err = f.factory.UserFiles().ListByFilter(ctx, query.filter, query.sorts, query.cursor, 0, req.PageSize)
+		if err != nil {
+			logger.LOG.Error("查询文件列表失败", "error", err, "userID", userID, "virtualPath", virtualPathIDStr)
+			return nil, err
+		}
+	} else if offset := (page - 1) * req.PageSize; offset < int(folderCount) {
 		// 当前页包含文件夹
 		folderLimit := req.PageSize
 		if offset+req.PageSize > int(folderCount) {
@@ -431,16 +459,18 @@ func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (
 		// 如果还有剩余空间，查询文件（直接从user_files表查询，避免file_id重复问题）
 		remaining := req.PageSize - len(folders)
 		if remaining > 0 {
-			userFiles, err = f.factory.UserFiles().ListByVirtualPath(ctx, userID, virtualPathIDStr, 0, remaining)
+			userFiles, next, err = f.factory.UserFiles().ListByFilter(ctx, query.filter, query.sorts, nil, 0, remaining)
 			if err != nil {
 				logger.LOG.Error("查询文件列表失败", "error", err, "userID", userID, "virtualPath", virtualPathIDStr)
 				return nil, err
 			}
+		} else if offset+len(folders) == int(folderCount) && fileCount > 0 {
+			// 本页恰好列完目录，下一页从第一个文件开始
+			next = &repository.FileCursor{}
 		}
 	} else {
 		// 当前页只包含文件（直接从user_files表查询，避免file_id重复问题）
-		fileOffset := offset - int(folderCount)
-		userFiles, err = f.factory.UserFiles().ListByVirtualPath(ctx, userID, virtualPathIDStr, fileOffset, req.PageSize)
+		userFiles, next, err = f.factory.UserFiles().ListByFilter(ctx, query.filter, query.sorts, nil, offset-int(folderCount), req.PageSize)
 		if err != nil {
 			logger.LOG.Error("查询文件列表失败", "error", err, "userID", userID, "virtualPath", virtualPathIDStr)
 			return nil, err
@@ -459,10 +489,10 @@ func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (
 		Breadcrumbs: breadcrumbs,
 		CurrentPath: fmt.Sprintf("%d", currentPathID),
 		Folders:     make([]*response.FolderItem, 0, len(folders)),
-		Files:       make([]*response.FileItem, 0, len(userFiles)),
 		Total:       totalCount,
-		Page:        req.Page,
+		Page:        page,
 		PageSize:    req.PageSize,
+		NextCursor:  encodeFileCursor(query.sorts, next),
 	}
 
 	// 转换文件夹数据
@@ -476,6 +506,14 @@ func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (
 	}
 
 	// 转换文件数据（直接使用user_files记录，避免file_id重复导致查询错误）
+	resp.Files = f.fileItems(ctx, userFiles)
+
+	return models.NewJsonResponse(200, "获取成功", resp), nil
+}
+
+// fileItems 将用户文件转换为文件列表项，跳过文件信息缺失的记录
+func (f *FileService) fileItems(ctx context.Context, userFiles []*models.UserFiles) []*response.FileItem {
+	items := make([]*response.FileItem, 0, len(userFiles))
 	for _, uf := range userFiles {
 		// 获取file_info详情
 		fileInfo, err := f.factory.FileInfo().GetByID(ctx, uf.FileID)
@@ -484,19 +522,20 @@ func (f *FileService) GetFileList(req *request.FileListRequest, userID string) (
 			continue
 		}
 
-		resp.Files = append(resp.Files, &response.FileItem{
+		items = append(items, &response.FileItem{
 			FileID:       uf.UfID,
 			FileName:     uf.FileName,
 			FileSize:     fileInfo.Size,
 			MimeType:     fileInfo.Mime,
+			Category:     fileCategory(uf.FileName, fileInfo),
 			IsEnc:        fileInfo.IsEnc,
 			HasThumbnail: fileInfo.ThumbnailImg != "",
 			Public:       uf.IsPublic,
 			CreatedAt:    fileInfo.CreatedAt,
+			UpdatedAt:    fileInfo.UpdatedAt,
 		})
 	}
-
-	return models.NewJsonResponse(200, "获取成功", resp), nil
+	return items
 }
 
 // buildBreadcrumbs 构建面包屑导航（只展示当前、上级、上上级）
@@ -588,13 +627,27 @@ func (f *FileService) MakeDir(req *request.MakeDirRequest, userID string) (*mode
 }
 
 // MoveFile 移动文件
-func (f *FileService) MoveFile(req *request.MoveFileRequest, userID string) (*models.JsonResponse, error) {
+func (f *FileService) MoveFile(req *request.MoveFileRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFileMove, audit.TargetFile, req.FileID, fmt.Sprintf("%s -> %s", req.SourcePath, req.TargetPath), res, err)
+	}()
 	ctx := context.Background()
 	userFile, err := f.factory.UserFiles().GetByUserIDAndUfID(ctx, userID, req.FileID)
 	if err != nil {
 		logger.LOG.Error("获取文件失败", "error", err)
 		return nil, err
 	}
+	// 源文件和目标位置都不能被 WebDAV 锁定
+	lockManager := lock.NewManager(f.factory)
+	var targets []string
+	if targetID, err := strconv.Atoi(req.TargetPath); err == nil {
+		if dir, err := lockManager.DirPath(ctx, targetID); err == nil {
+			targets = append(targets, path.Join(dir, userFile.FileName))
+		}
+	}
+	if resp := webDAVLockResponse(lockManager.CheckFileUnlocked(ctx, userFile, targets...)); resp != nil {
+		return resp, nil
+	}
 	userFile.UserID = userID
 	userFile.VirtualPath = req.TargetPath
 	err = f.factory.UserFiles().Update(ctx, userFile)
@@ -616,7 +669,10 @@ func (f *FileService) GetVirtualPath(userID string) (*models.JsonResponse, error
 }
 
 // RenameFile 重命名文件
-func (f *FileService) RenameFile(req *request.RenameFileRequest, userID string) (*models.JsonResponse, error) {
+func (f *FileService) RenameFile(req *request.RenameFileRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFileRename, audit.TargetFile, req.FileID, "新文件名: "+req.NewFileName, res, err)
+	}()
 	ctx := context.Background()
 
 	// 1. 验证用户是否拥有该文件
@@ -651,10 +707,20 @@ func (f *FileService) RenameFile(req *request.RenameFileRequest, userID string)
 		}
 	}
 
-	// 4. 保存旧文件名用于日志
+	// 4. 检查原文件和新文件名是否被 WebDAV 锁定
+	lockManager := lock.NewManager(f.factory)
+	var targets []string
+	if oldPath, err := lockManager.FilePath(ctx, userFile); err == nil {
+		targets = append(targets, path.Join(path.Dir(oldPath), req.NewFileName))
+	}
+	if resp := webDAVLockResponse(lockManager.CheckFileUnlocked(ctx, userFile, targets...)); resp != nil {
+		return resp, nil
+	}
+
+	// 5. 保存旧文件名用于日志
 	oldFileName := userFile.FileName
 
-	// 5. 更新文件名
+	// 6. 更新文件名
 	userFile.FileName = req.NewFileName
 	err = f.factory.UserFiles().Update(ctx, userFile)
 	if err != nil {
@@ -662,6 +728,7 @@ func (f *FileService) RenameFile(req *request.RenameFileRequest, userID string)
 		return nil, fmt.Errorf("重命名文件失败: %w", err)
 	}
 
+	search.Notify(userID, req.FileID)
 	logger.LOG.Info("文件重命名成功", "fileID", req.FileID, "oldFileName", oldFileName, "newFileName", req.NewFileName)
 	return models.NewJsonResponse(200, "文件重命名成功", map[string]interface{}{
 		"file_id":   req.FileID,
@@ -670,7 +737,10 @@ func (f *FileService) RenameFile(req *request.RenameFileRequest, userID string)
 }
 
 // RenameDir 重命名目录
-func (f *FileService) RenameDir(req *request.RenameDirRequest, userID string) (*models.JsonResponse, error) {
+func (f *FileService) RenameDir(req *request.RenameDirRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionDirRename, audit.TargetDir, fmt.Sprint(req.DirID), "新目录名: "+req.NewDirName, res, err)
+	}()
 	ctx := context.Background()
 
 	// 1. 获取目录信息
@@ -743,6 +813,14 @@ func (f *FileService) RenameDir(req *request.RenameDirRequest, userID string) (*
 		}
 	}
 
+	// 5.1 目录及其中的文件不能被 WebDAV 锁定
+	lockManager := lock.NewManager(f.factory)
+	if dirPath, err := lockManager.DirPath(ctx, req.DirID); err == nil {
+		if resp := webDAVLockResponse(lockManager.CheckUnlocked(ctx, userID, dirPath, path.Join(path.Dir(dirPath), newDirName))); resp != nil {
+			return resp, nil
+		}
+	}
+
 	// 6. 更新目录路径
 	oldPath := virtualPath.Path
 	virtualPath.Path = newPath
@@ -767,7 +845,10 @@ func (f *FileService) RenameDir(req *request.RenameDirRequest, userID string) (*
 }
 
 // SetFilePublic 设置文件公开状态
-func (f *FileService) SetFilePublic(req *request.SetFilePublicRequest, userID string) (*models.JsonResponse, error) {
+func (f *FileService) SetFilePublic(req *request.SetFilePublicRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFilePublic, audit.TargetFile, req.FileID, fmt.Sprintf("公开: %t", req.Public), res, err)
+	}()
 	ctx := context.Background()
 
 	// 1. 验证用户是否拥有该文件
@@ -813,8 +894,76 @@ func (f *FileService) SetFilePublic(req *request.SetFilePublicRequest, userID st
 	}), nil
 }
 
-// DeleteDir 删除目录（递归删除目录下的所有文件和子目录）
-func (f *FileService) DeleteDir(req *request.DeleteDirRequest, userID string) (*models.JsonResponse, error) {
+// ListEncryptPolicies 获取用户的写入加密策略
+func (f *FileService) ListEncryptPolicies(userID string) (*models.JsonResponse, error) {
+	policies, err := f.factory.EncryptPolicy().ListByUserID(context.Background(), userID)
+	if err != nil {
+		logger.LOG.Error("获取写入加密策略失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "获取成功", policies), nil
+}
+
+// SetEncryptPolicy 开启或关闭目录的写入加密策略
+// 开启后通过 WebDAV / SFTP 写入该目录（含子目录）的文件会自动加密存储
+func (f *FileService) SetEncryptPolicy(req *request.SetEncryptPolicyRequest, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+
+	// 1. 验证目录所有权（0 表示所有目录）
+	if req.DirID > 0 {
+		dir, err := f.factory.VirtualPath().GetByID(ctx, req.DirID)
+		if err != nil || dir.UserID != userID {
+			return models.NewJsonResponse(404, "目录不存在或无权访问", nil), nil
+		}
+	}
+
+	existing, err := f.factory.EncryptPolicy().GetByUserIDAndDirID(ctx, userID, req.DirID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.LOG.Error("获取写入加密策略失败", "error", err, "dirID", req.DirID)
+		return nil, err
+	}
+
+	// 2. 关闭策略
+	if !req.Enable {
+		if existing != nil {
+			if err := f.factory.EncryptPolicy().Delete(ctx, existing.ID); err != nil {
+				logger.LOG.Error("删除写入加密策略失败", "error", err, "dirID", req.DirID)
+				return nil, err
+			}
+		}
+		logger.LOG.Info("写入加密策略已关闭", "userID", userID, "dirID", req.DirID)
+		return models.NewJsonResponse(200, "写入加密已关闭", nil), nil
+	}
+
+	// 3. 开启策略：加密需要文件密码
+	user, err := f.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		logger.LOG.Error("获取用户信息失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	if user.FilePassword == "" {
+		return models.NewJsonResponse(400, "请先设置文件密码", nil), nil
+	}
+	if existing == nil {
+		existing = &models.EncryptPolicy{
+			UserID:    userID,
+			DirID:     req.DirID,
+			CreatedAt: custom_type.Now(),
+		}
+		if err := f.factory.EncryptPolicy().Create(ctx, existing); err != nil {
+			logger.LOG.Error("创建写入加密策略失败", "error", err, "dirID", req.DirID)
+			return nil, err
+		}
+	}
+	logger.LOG.Info("写入加密策略已开启", "userID", userID, "dirID", req.DirID)
+	return models.NewJsonResponse(200, "写入加密已开启", existing), nil
+}
+
+// DeleteDir 删除目录（目录连同其中的所有文件和子目录整体移入回收站）
+func (f *FileService) DeleteDir(req *request.DeleteDirRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionDirDelete, audit.TargetDir, fmt.Sprint(req.DirID), "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 1. 获取目录信息
@@ -844,150 +993,38 @@ func (f *FileService) DeleteDir(req *request.DeleteDirRequest, userID string) (*
 		return models.NewJsonResponse(400, "根目录不能删除", nil), nil
 	}
 
-	// 4. 递归获取目录下的所有文件和子目录
-	dirPathID := strconv.Itoa(req.DirID)
-
-	// 4.1 获取目录下的所有文件（直接查询该目录下的文件，避免获取所有文件）
-	// 注意：由于 UserFilesRepository 没有 ListByVirtualPath 方法，我们使用 ListByUserID 然后过滤
-	// 对于大多数用户，文件数量不会太多，这个实现是可以接受的
-	allFiles, err := f.factory.UserFiles().ListByUserID(ctx, userID, 0, 100000)
-	if err != nil {
-		logger.LOG.Error("获取文件列表失败", "error", err)
-		return nil, err
-	}
-
-	// 过滤出该目录下的文件（VirtualPath 存储的是路径ID的字符串形式）
-	var filesToDelete []string
-	for _, file := range allFiles {
-		// 检查文件是否在该目录下（VirtualPath 存储的是目录ID的字符串形式）
-		if file.VirtualPath == dirPathID {
-			filesToDelete = append(filesToDelete, file.UfID)
-		}
-	}
-
-	// 4.2 递归获取所有子目录
-	// 注意：需要处理根目录的子目录（ParentLevel 可能为空）的情况
-	var dirsToDelete []int
-	err = f.collectSubDirs(ctx, userID, req.DirID, &dirsToDelete, rootPath.ID)
-	if err != nil {
-		logger.LOG.Error("收集子目录失败", "error", err)
-		return nil, err
-	}
-
-	// 5. 删除所有文件（移动到回收站）
-	fileSuccessCount := 0
-	fileFailedCount := 0
-	if len(filesToDelete) > 0 {
-		deleteFileReq := &request.DeleteFileRequest{
-			FileIDs: filesToDelete,
-		}
-		result, err := f.DeleteFiles(deleteFileReq, userID)
-		if err != nil {
-			logger.LOG.Error("删除目录下文件失败", "error", err)
-			return nil, err
-		}
-		// 解析删除结果
-		if result.Data != nil {
-			if data, ok := result.Data.(map[string]interface{}); ok {
-				if success, ok := data["success"].(float64); ok {
-					fileSuccessCount = int(success)
-				}
-				if failed, ok := data["failed"].(float64); ok {
-					fileFailedCount = int(failed)
-				}
-			}
-		}
-	}
-
-	// 6. 递归删除所有子目录（从最深层开始）
-	// 注意：如果子目录删除失败，我们仍然会尝试删除父目录
-	// 这是合理的，因为用户已经确认要删除整个目录，且返回了详细的失败信息
-	dirSuccessCount := 0
-	dirFailedCount := 0
-	// 反转数组，从最深层开始删除（确保先删除子目录，再删除父目录）
-	for i := len(dirsToDelete) - 1; i >= 0; i-- {
-		dirID := dirsToDelete[i]
-		err := f.factory.VirtualPath().Delete(ctx, dirID)
-		if err != nil {
-			logger.LOG.Error("删除子目录失败", "error", err, "dirID", dirID)
-			dirFailedCount++
-			// 继续删除其他目录，不中断流程
-		} else {
-			dirSuccessCount++
+	// 3.1 目录及其中的文件不能被 WebDAV 锁定
+	lockManager := lock.NewManager(f.factory)
+	if dirPath, err := lockManager.DirPath(ctx, req.DirID); err == nil {
+		if resp := webDAVLockResponse(lockManager.CheckUnlocked(ctx, userID, dirPath)); resp != nil {
+			return resp, nil
 		}
 	}
 
-	// 7. 删除目录本身
-	// 即使部分子目录删除失败，仍然删除父目录（用户已确认删除）
-	err = f.factory.VirtualPath().Delete(ctx, req.DirID)
+	// 4. 目录连同其中的子目录与文件整体移入回收站，可在回收站中按原有层级还原
+	entry, summary, err := recycle.NewBin(f.factory).RecycleDir(ctx, userID, virtualPath)
 	if err != nil {
-		logger.LOG.Error("删除目录失败", "error", err, "dirID", req.DirID)
+		logger.LOG.Error("目录移入回收站失败", "error", err, "dirID", req.DirID)
 		return nil, fmt.Errorf("删除目录失败: %w", err)
 	}
 
-	logger.LOG.Info("目录删除成功", "dirID", req.DirID,
-		"filesDeleted", fileSuccessCount, "filesFailed", fileFailedCount,
-		"dirsDeleted", dirSuccessCount, "dirsFailed", dirFailedCount)
-
-	message := fmt.Sprintf("目录删除成功，已删除 %d 个文件", fileSuccessCount)
-	if fileFailedCount > 0 {
-		message = fmt.Sprintf("%s，%d 个文件删除失败", message, fileFailedCount)
-	}
-	if dirSuccessCount > 0 {
-		message = fmt.Sprintf("%s，已删除 %d 个子目录", message, dirSuccessCount)
+	message := fmt.Sprintf("目录已移入回收站，包含 %d 个文件", summary.Files)
+	if summary.Dirs > 0 {
+		message = fmt.Sprintf("%s、%d 个子目录", message, summary.Dirs)
 	}
-	if dirFailedCount > 0 {
-		message = fmt.Sprintf("%s，%d 个子目录删除失败", message, dirFailedCount)
-	}
-
 	return models.NewJsonResponse(200, message, map[string]interface{}{
 		"dir_id":        req.DirID,
-		"files_deleted": fileSuccessCount,
-		"files_failed":  fileFailedCount,
-		"dirs_deleted":  dirSuccessCount,
-		"dirs_failed":   dirFailedCount,
+		"recycled_id":   entry.ID,
+		"files_deleted": summary.Files,
+		"dirs_deleted":  summary.Dirs,
 	}), nil
 }
 
-// collectSubDirs 递归收集目录下的所有子目录
-func (f *FileService) collectSubDirs(ctx context.Context, userID string, parentDirID int, result *[]int, rootDirID int) error {
-	// 获取直接子目录
-	// 注意：ListSubFoldersByParentID 使用整数 parentID 查询 TEXT 类型的 parent_level 字段
-	// GORM 会自动进行类型转换，这在其他代码（如 RenameDir）中已经验证可行
-	subDirs, err := f.factory.VirtualPath().ListSubFoldersByParentID(ctx, userID, parentDirID, 0, 10000)
-	if err != nil {
-		return err
-	}
-
-	// 验证 parent_level 是否匹配（作为额外的安全检查）
-	parentLevelStr := strconv.Itoa(parentDirID)
-	for _, subDir := range subDirs {
-		// 验证确实是子目录
-		// 情况1：ParentLevel 等于父目录ID的字符串形式（正常情况）
-		// 情况2：ParentLevel 为空且父目录是根目录（根目录的直接子目录）
-		isValidChild := false
-		if subDir.ParentLevel == parentLevelStr {
-			// 正常情况：ParentLevel 匹配
-			isValidChild = true
-		} else if subDir.ParentLevel == "" && parentDirID == rootDirID {
-			// 特殊情况：根目录的直接子目录，ParentLevel 可能为空
-			isValidChild = true
-		}
-
-		if isValidChild && subDir.IsDir {
-			*result = append(*result, subDir.ID)
-			// 递归收集子目录的子目录
-			if err := f.collectSubDirs(ctx, userID, subDir.ID, result, rootDirID); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
 // DeleteFiles 删除文件（移动到回收站）
-func (f *FileService) DeleteFiles(req *request.DeleteFileRequest, userID string) (*models.JsonResponse, error) {
+func (f *FileService) DeleteFiles(req *request.DeleteFileRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFileDelete, audit.TargetFile, strings.Join(req.FileIDs, ","), "", res, err)
+	}()
 	ctx := context.Background()
 
 	successCount := 0
@@ -1013,31 +1050,16 @@ func (f *FileService) DeleteFiles(req *request.DeleteFileRequest, userID string)
 			continue
 		}
 
-		// 在事务中执行：1. 软删除 user_files、 2. 创建回收站记录
-		err = f.factory.DB().Transaction(func(tx *gorm.DB) error {
-			txFactory := f.factory.WithTx(tx)
-
-			// 软删除 user_files 记录
-			if err := tx.Where("user_id = ? AND uf_id = ?", userID, fileID).Delete(&models.UserFiles{}).Error; err != nil {
-				return fmt.Errorf("软删除用户文件失败: %w", err)
-			}
-
-			// 创建回收站记录
-			recycled := &models.Recycled{
-				ID:        uuid.Must(uuid.NewV7()).String(),
-				FileID:    fileID,
-				UserID:    userID,
-				CreatedAt: custom_type.Now(),
-			}
-
-			if err := txFactory.Recycled().Create(ctx, recycled); err != nil {
-				return fmt.Errorf("创建回收站记录失败: %w", err)
-			}
-
-			return nil
-		})
+		// 检查是否被 WebDAV 锁定
+		if err := lock.NewManager(f.factory).CheckFileUnlocked(ctx, userFile); err != nil {
+			logger.LOG.Warn("文件已被 WebDAV 锁定", "fileID", fileID, "error", err)
+			errors = append(errors, fmt.Sprintf("文件 %s 已被 WebDAV 锁定", userFile.FileName))
+			failedCount++
+			continue
+		}
 
-		if err != nil {
+		// 软删除 user_files 并创建回收站记录（与删除目录、WebDAV 删除共用回收站逻辑）
+		if _, err := recycle.NewBin(f.factory).RecycleFile(ctx, userID, userFile); err != nil {
 			logger.LOG.Error("删除文件失败", "error", err, "fileID", fileID, "userID", userID)
 			errors = append(errors, fmt.Sprintf("删除文件 %s 失败: %v", fileID, err))
 			failedCount++
@@ -1448,32 +1470,26 @@ func (f *FileService) handleSingleUpload(ctx context.Context, req *request.FileU
 // PublicFileList 获取公开文件列表
 func (f *FileService) PublicFileList(req *request.PublicFileListRequest) (*models.JsonResponse, error) {
 	ctx := context.Background()
+	query, err := parseFileQuery(&req.FileFilterRequest, req.SortBy, req.Cursor)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	public := true
+	query.filter.Public = &public
 
 	// 默认分页参数
 	page := req.Page
 	if page < 1 {
 		page = 1
 	}
-	pageSize := req.PageSize
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
-	offset := (page - 1) * pageSize
 
-	// 获取所有公开文件
-	userFiles, err := f.factory.UserFiles().ListPublicFiles(ctx, offset, pageSize)
+	// 获取满足条件的公开文件
+	userFiles, total, next, err := f.listFilteredFiles(ctx, query, page, req.PageSize)
 	if err != nil {
 		logger.LOG.Error("获取公开文件列表失败", "error", err)
 		return nil, err
 	}
 
-	// 统计公开文件数量
-	total, err := f.factory.UserFiles().CountPublicFiles(ctx)
-	if err != nil {
-		logger.LOG.Error("统计公开文件数量失败", "error", err)
-		return nil, err
-	}
-
 	// 构建响应数据
 	fileList := make([]response.PublicFileItem, 0, len(userFiles))
 	for _, uf := range userFiles {
@@ -1491,68 +1507,6 @@ func (f *FileService) PublicFileList(req *request.PublicFileListRequest) (*model
 			continue
 		}
 
-		// 根据文件类型过滤
-		if req.Type != "" && req.Type != "all" {
-			// 获取文件主类型（如 image、video、audio 等）
-			mainType := ""
-			if len(fileInfo.Mime) > 0 {
-				parts := strings.Split(fileInfo.Mime, "/")
-				if len(parts) > 0 {
-					mainType = parts[0]
-				}
-			}
-
-			// 特殊处理压缩文件
-			if req.Type == "archive" {
-				if !strings.Contains(fileInfo.Mime, "zip") && !strings.Contains(fileInfo.Mime, "rar") &&
-					!strings.Contains(fileInfo.Mime, "7z") && !strings.Contains(fileInfo.Mime, "tar") &&
-					!strings.Contains(fileInfo.Mime, "gzip") {
-					continue
-				}
-			} else if req.Type == "doc" {
-				// 文档类型：pdf、word、excel、ppt等
-				// 检查 PDF
-				isPDF := strings.Contains(fileInfo.Mime, "pdf")
-				// 检查 Word 文档（包括旧格式 .doc 和新格式 .docx）
-				isWord := strings.Contains(fileInfo.Mime, "word") || strings.Contains(fileInfo.Mime, "wordprocessingml")
-				// 检查 Excel 表格（包括旧格式 .xls 和新格式 .xlsx）
-				isExcel := strings.Contains(fileInfo.Mime, "excel") || strings.Contains(fileInfo.Mime, "spreadsheetml")
-				// 检查 PowerPoint 演示（包括旧格式 .ppt 和新格式 .pptx）
-				isPPT := strings.Contains(fileInfo.Mime, "powerpoint") || strings.Contains(fileInfo.Mime, "presentationml")
-				// 检查通用文档类型
-				isDocument := strings.Contains(fileInfo.Mime, "document") || strings.Contains(fileInfo.Mime, "presentation")
-
-				if !isPDF && !isWord && !isDocument && !isExcel && !isPPT {
-					continue
-				}
-			} else if req.Type == "other" {
-				// 其他类型：匹配所有不属于 image、video、audio、doc、archive 的文件
-				if mainType == "image" || mainType == "video" || mainType == "audio" {
-					continue
-				}
-				// 检查是否是文档类型
-				isPDF := strings.Contains(fileInfo.Mime, "pdf")
-				isWord := strings.Contains(fileInfo.Mime, "word") || strings.Contains(fileInfo.Mime, "wordprocessingml")
-				isExcel := strings.Contains(fileInfo.Mime, "excel") || strings.Contains(fileInfo.Mime, "spreadsheetml")
-				isPPT := strings.Contains(fileInfo.Mime, "powerpoint") || strings.Contains(fileInfo.Mime, "presentationml")
-				isDocument := strings.Contains(fileInfo.Mime, "document") || strings.Contains(fileInfo.Mime, "presentation")
-
-				if isPDF || isWord || isDocument || isExcel || isPPT {
-					continue
-				}
-				// 检查是否是压缩文件
-				if strings.Contains(fileInfo.Mime, "zip") || strings.Contains(fileInfo.Mime, "rar") ||
-					strings.Contains(fileInfo.Mime, "7z") || strings.Contains(fileInfo.Mime, "tar") ||
-					strings.Contains(fileInfo.Mime, "gzip") {
-					continue
-				}
-				// 其他所有类型都匹配（包括 text、application 等）
-			} else if mainType != req.Type {
-				// 其他类型直接匹配主类型（image、video、audio）
-				continue
-			}
-		}
-
 		fileList = append(fileList, response.PublicFileItem{
 			UfID:         uf.UfID,
 			FileName:     uf.FileName,
@@ -1564,29 +1518,12 @@ func (f *FileService) PublicFileList(req *request.PublicFileListRequest) (*model
 		})
 	}
 
-	// 排序
-	if req.SortBy != "" {
-		switch req.SortBy {
-		case "name":
-			sort.Slice(fileList, func(i, j int) bool {
-				return fileList[i].FileName < fileList[j].FileName
-			})
-		case "size":
-			sort.Slice(fileList, func(i, j int) bool {
-				return fileList[i].FileSize > fileList[j].FileSize
-			})
-		case "time":
-			sort.Slice(fileList, func(i, j int) bool {
-				return fileList[i].CreatedAt.After(fileList[j].CreatedAt)
-			})
-		}
-	}
-
 	resp := response.PublicFileListResponse{
-		Files:    fileList,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
+		Files:      fileList,
+		Total:      total,
+		Page:       page,
+		PageSize:   req.PageSize,
+		NextCursor: next,
 	}
 
 	return models.NewJsonResponse(200, "获取成功", resp), nil
@@ -2017,3 +1954,15 @@ func (f *FileService) GetUploadTaskList(req *request.UploadTaskListRequest, user
 
 	return models.NewJsonResponse(200, "获取上传任务列表成功", responseData), nil
 }
+
+// webDAVLockResponse 将 WebDAV 锁检查结果转换为接口响应，未被锁定时返回 nil
+func webDAVLockResponse(err error) *models.JsonResponse {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, lock.ErrLocked) {
+		return models.NewJsonResponse(423, err.Error(), nil)
+	}
+	logger.LOG.Error("检查 WebDAV 锁失败", "error", err)
+	return models.NewJsonResponse(500, "检查文件锁失败", nil)
+}
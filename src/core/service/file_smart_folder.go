@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// maxSmartFolders 每个用户最多保存的智能文件夹数量
+const maxSmartFolders = 100
+
+// smartFolderFilter 智能文件夹保存的过滤与排序条件
+type smartFolderFilter struct {
+	Keyword string `json:"keyword"`
+	Path    string `json:"path"`
+	request.FileFilterRequest
+	SortBy string `json:"sort_by"`
+}
+
+// ListSmartFolders 获取用户的智能文件夹
+func (f *FileService) ListSmartFolders(userID string) (*models.JsonResponse, error) {
+	folders, err := f.factory.SmartFolder().ListByUserID(context.Background(), userID)
+	if err != nil {
+		logger.LOG.Error("查询智能文件夹失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	items := make([]*response.SmartFolderItem, 0, len(folders))
+	for _, folder := range folders {
+		items = append(items, smartFolderItem(folder))
+	}
+	return models.NewJsonResponse(200, "获取成功", items), nil
+}
+
+// SaveSmartFolder 创建（ID 为 0 时）或修改智能文件夹
+func (f *FileService) SaveSmartFolder(req *request.SmartFolderRequest, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return models.NewJsonResponse(400, "名称不能为空", nil), nil
+	}
+	saved := smartFolderFilter{
+		Keyword:           strings.TrimSpace(req.Keyword),
+		Path:              strings.TrimSpace(req.Path),
+		FileFilterRequest: req.FileFilterRequest,
+		SortBy:            req.SortBy,
+	}
+	if _, err := parseFileQuery(&saved.FileFilterRequest, saved.SortBy, ""); err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	if saved.Path != "" {
+		dirIDs, err := f.searchPathDirs(ctx, userID, saved.Path)
+		if err != nil {
+			logger.LOG.Error("查询目录失败", "error", err, "userID", userID, "path", saved.Path)
+			return nil, err
+		}
+		if len(dirIDs) == 0 {
+			return models.NewJsonResponse(404, "目录不存在", nil), nil
+		}
+	}
+
+	folders, err := f.factory.SmartFolder().ListByUserID(ctx, userID)
+	if err != nil {
+		logger.LOG.Error("查询智能文件夹失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	var folder *models.SmartFolder
+	for _, existing := range folders {
+		if existing.ID == req.ID {
+			folder = existing
+		} else if existing.Name == name {
+			return models.NewJsonResponse(409, "已存在同名的智能文件夹", nil), nil
+		}
+	}
+	if req.ID != 0 && folder == nil {
+		return models.NewJsonResponse(404, "智能文件夹不存在", nil), nil
+	}
+	if folder == nil && len(folders) >= maxSmartFolders {
+		return models.NewJsonResponse(400, "智能文件夹数量已达上限", nil), nil
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return nil, err
+	}
+	if folder == nil {
+		folder = &models.SmartFolder{UserID: userID, CreatedAt: custom_type.Now()}
+	}
+	folder.Name = name
+	folder.Filter = string(data)
+	folder.UpdatedAt = custom_type.Now()
+	if folder.ID == 0 {
+		err = f.factory.SmartFolder().Create(ctx, folder)
+	} else {
+		err = f.factory.SmartFolder().Update(ctx, folder)
+	}
+	if err != nil {
+		logger.LOG.Error("保存智能文件夹失败", "error", err, "userID", userID, "name", name)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "保存成功", smartFolderItem(folder)), nil
+}
+
+// DeleteSmartFolder 删除智能文件夹（不影响其中的文件）
+func (f *FileService) DeleteSmartFolder(id int, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	if _, err := f.factory.SmartFolder().GetByID(ctx, userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.NewJsonResponse(404, "智能文件夹不存在", nil), nil
+		}
+		return nil, err
+	}
+	if err := f.factory.SmartFolder().Delete(ctx, userID, id); err != nil {
+		logger.LOG.Error("删除智能文件夹失败", "error", err, "userID", userID, "id", id)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "删除成功", nil), nil
+}
+
+// ListSmartFolderFiles 按智能文件夹保存的条件查询用户的文件
+func (f *FileService) ListSmartFolderFiles(req *request.SmartFolderFilesRequest, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	folder, err := f.factory.SmartFolder().GetByID(ctx, userID, req.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.NewJsonResponse(404, "智能文件夹不存在", nil), nil
+		}
+		return nil, err
+	}
+	var saved smartFolderFilter
+	if err := json.Unmarshal([]byte(folder.Filter), &saved); err != nil {
+		logger.LOG.Error("解析智能文件夹条件失败", "error", err, "id", folder.ID)
+		return nil, err
+	}
+	query, err := parseFileQuery(&saved.FileFilterRequest, saved.SortBy, req.Cursor)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	query.filter.UserID = userID
+	query.filter.Keyword = saved.Keyword
+	if saved.Path != "" {
+		dirIDs, err := f.searchPathDirs(ctx, userID, saved.Path)
+		if err != nil {
+			logger.LOG.Error("查询目录失败", "error", err, "userID", userID, "path", saved.Path)
+			return nil, err
+		}
+		if len(dirIDs) == 0 {
+			return models.NewJsonResponse(404, "目录不存在", nil), nil
+		}
+		query.filter.VirtualPaths = dirIDs
+	}
+
+	userFiles, total, next, err := f.listFilteredFiles(ctx, query, 1, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询智能文件夹文件失败", "error", err, "userID", userID, "id", folder.ID)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "获取成功", &response.SmartFolderFilesResponse{
+		Folder:     smartFolderItem(folder),
+		Files:      f.fileItems(ctx, userFiles),
+		Total:      total,
+		NextCursor: next,
+	}), nil
+}
+
+func smartFolderItem(folder *models.SmartFolder) *response.SmartFolderItem {
+	return &response.SmartFolderItem{
+		ID:        folder.ID,
+		Name:      folder.Name,
+		Filter:    json.RawMessage(folder.Filter),
+		CreatedAt: folder.CreatedAt,
+		UpdatedAt: folder.UpdatedAt,
+	}
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/search"
+	"myobj/src/pkg/upload"
+	"myobj/src/pkg/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errTextModified  = errors.New("文件已被修改，请重新读取后再保存")
+	errTextNoStorage = errors.New("剩余空间不足")
+)
+
+// ReadTextFile 读取文本文件内容，未指定编码时自动检测；加密文件需要提供文件密码
+func (f *FileService) ReadTextFile(req *request.TextFileReadRequest, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	userFile, fileInfo, resp, err := f.textFile(ctx, req.FileID, userID)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	if resp, err := f.checkFilePassword(ctx, fileInfo, userID, req.FilePassword); resp != nil || err != nil {
+		return resp, err
+	}
+	var encryptionKey string
+	if fileInfo.IsEnc {
+		encryptionKey = util.DeriveEncryptionKey(req.FilePassword, userID)
+	}
+
+	data, err := f.readTextData(ctx, fileInfo, encryptionKey)
+	if err != nil {
+		logger.LOG.Error("读取文本文件失败", "error", err, "fileID", req.FileID)
+		return nil, err
+	}
+
+	charset := strings.ToLower(req.Charset)
+	if charset == "" {
+		charset, err = util.DetectCharset(data)
+		if err != nil {
+			return models.NewJsonResponse(400, "该文件不是文本文件，无法在线编辑", nil), nil
+		}
+	} else if !util.IsCharset(charset) {
+		return models.NewJsonResponse(400, "不支持的文本编码: "+req.Charset, nil), nil
+	}
+	content, err := util.DecodeText(data, charset)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+
+	return models.NewJsonResponse(200, "获取成功", &response.TextFileResponse{
+		FileID:    userFile.UfID,
+		FileName:  userFile.FileName,
+		Content:   content,
+		Charset:   charset,
+		ETag:      fileInfo.FileHash,
+		Size:      fileInfo.Size,
+		Mime:      fileInfo.Mime,
+		UpdatedAt: fileInfo.UpdatedAt,
+	}), nil
+}
+
+// SaveTextFile 保存文本文件内容，etag 与当前文件内容不一致时拒绝保存
+// 文件内容被其他用户文件共享（秒传、复制）或文件已公开时，写入新的文件信息并只让当前用户文件指向它；
+// 否则原地替换文件内容
+func (f *FileService) SaveTextFile(req *request.TextFileSaveRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	var copied bool
+	defer func() {
+		recordAudit(f.factory, actor, audit.ActionFileEdit, audit.TargetFile, req.FileID, fmt.Sprintf("独立副本: %t", copied), res, err)
+	}()
+	ctx := context.Background()
+
+	userFile, fileInfo, resp, err := f.textFile(ctx, req.FileID, userID)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	if req.ETag != fileInfo.FileHash {
+		return models.NewJsonResponse(409, errTextModified.Error(), nil), nil
+	}
+	charset := strings.ToLower(req.Charset)
+	if charset == "" {
+		charset = util.CharsetUTF8
+	}
+	if !util.IsCharset(charset) {
+		return models.NewJsonResponse(400, "不支持的文本编码: "+req.Charset, nil), nil
+	}
+	data, err := util.EncodeText(req.Content, charset)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	if maxSize := config.CONFIG.File.TextEditMaxSize * 1024 * 1024; int64(len(data)) > maxSize {
+		return models.NewJsonResponse(400, fmt.Sprintf("文本内容超过在线编辑的大小上限 %d MB", config.CONFIG.File.TextEditMaxSize), nil), nil
+	}
+	if resp, err := f.checkFilePassword(ctx, fileInfo, userID, req.FilePassword); resp != nil || err != nil {
+		return resp, err
+	}
+	if resp := webDAVLockResponse(lock.NewManager(f.factory).CheckFileUnlocked(ctx, userFile)); resp != nil {
+		return resp, nil
+	}
+
+	// 1. 存储新的文件内容（与上传相同：计算hash、按需加密与分片）
+	tempFilePath, err := f.writeTextTempFile(ctx, userFile.FileName, data)
+	if err != nil {
+		logger.LOG.Error("写入临时文件失败", "error", err, "fileID", req.FileID)
+		return nil, err
+	}
+	newInfo, newChunks, err := upload.StoreFile(&upload.FileUploadData{
+		TempFilePath: tempFilePath,
+		FileName:     userFile.FileName,
+		FileSize:     int64(len(data)),
+		IsEnc:        fileInfo.IsEnc,
+		UserID:       userID,
+		FilePassword: req.FilePassword,
+	}, f.factory)
+	if err != nil {
+		logger.LOG.Error("存储文本文件失败", "error", err, "fileID", req.FileID)
+		return nil, err
+	}
+
+	// 2. 在事务中更新文件信息、分片记录与用户剩余空间
+	// 先锁定旧的文件信息再统计引用，避免统计之后新增的引用（秒传、复制）随原地替换一起被改写
+	var (
+		removeOld bool
+		oldChunks []*models.FileChunk
+	)
+	err = f.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := f.factory.WithTx(tx)
+
+		if _, err := txFactory.FileInfo().GetByIDForUpdate(ctx, fileInfo.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errTextModified
+			}
+			return fmt.Errorf("锁定文件信息失败: %w", err)
+		}
+		// 文件内容被其他用户文件共享或已公开时写时复制
+		sharedRefs, err := txFactory.Recycled().CountSharedReferences(ctx, fileInfo.ID, userFile.UfID)
+		if err != nil {
+			return fmt.Errorf("统计文件引用失败: %w", err)
+		}
+		copied = sharedRefs > 0 || userFile.IsPublic
+		// 没有其他引用时，旧的文件内容在保存后删除
+		removeOld = sharedRefs == 0
+		if removeOld && fileInfo.IsChunk {
+			if oldChunks, err = txFactory.FileChunk().GetByFileID(ctx, fileInfo.ID); err != nil {
+				return fmt.Errorf("查询分片记录失败: %w", err)
+			}
+		}
+		if !copied {
+			newInfo.ID = fileInfo.ID
+			newInfo.CreatedAt = fileInfo.CreatedAt
+			for _, chunk := range newChunks {
+				chunk.FileID = fileInfo.ID
+			}
+		}
+
+		if copied {
+			if err := txFactory.FileInfo().Create(ctx, newInfo); err != nil {
+				return fmt.Errorf("创建文件信息失败: %w", err)
+			}
+			if len(newChunks) > 0 {
+				if err := txFactory.FileChunk().BatchCreate(ctx, newChunks); err != nil {
+					return fmt.Errorf("创建分片记录失败: %w", err)
+				}
+			}
+			rows, err := txFactory.UserFiles().RepointFile(ctx, userID, userFile.UfID, fileInfo.ID, newInfo.ID)
+			if err != nil {
+				return fmt.Errorf("更新用户文件失败: %w", err)
+			}
+			if rows == 0 {
+				return errTextModified
+			}
+			// 分享按用户与文件信息定位用户文件，用户不再持有旧内容时分享随之指向新内容
+			if _, err := txFactory.UserFiles().GetByUserIDAndFileID(ctx, userID, fileInfo.ID); errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := txFactory.Share().UpdateFileID(ctx, userID, fileInfo.ID, newInfo.ID); err != nil {
+					return fmt.Errorf("更新分享失败: %w", err)
+				}
+			} else if err != nil {
+				return fmt.Errorf("查询用户文件失败: %w", err)
+			}
+			if removeOld {
+				if err := txFactory.FileChunk().DeleteByFileID(ctx, fileInfo.ID); err != nil {
+					return fmt.Errorf("删除分片记录失败: %w", err)
+				}
+				if err := txFactory.FileInfo().Delete(ctx, fileInfo.ID); err != nil {
+					return fmt.Errorf("删除文件信息失败: %w", err)
+				}
+			}
+		} else {
+			rows, err := txFactory.FileInfo().ReplaceContent(ctx, newInfo, req.ETag)
+			if err != nil {
+				return fmt.Errorf("更新文件信息失败: %w", err)
+			}
+			if rows == 0 {
+				return errTextModified
+			}
+			if err := txFactory.FileChunk().DeleteByFileID(ctx, fileInfo.ID); err != nil {
+				return fmt.Errorf("删除分片记录失败: %w", err)
+			}
+			if len(newChunks) > 0 {
+				if err := txFactory.FileChunk().BatchCreate(ctx, newChunks); err != nil {
+					return fmt.Errorf("创建分片记录失败: %w", err)
+				}
+			}
+		}
+
+		user, err := txFactory.User().GetByID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("查询用户信息失败: %w", err)
+		}
+		if user.Space > 0 { // 如果不是无限空间
+			delta := int64(newInfo.Size) - int64(fileInfo.Size)
+			if delta > user.FreeSpace {
+				return errTextNoStorage
+			}
+			user.FreeSpace -= delta
+			if err := txFactory.User().Update(ctx, user); err != nil {
+				return fmt.Errorf("更新用户剩余空间失败: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		upload.RemoveStoredFile(newInfo, newChunks)
+		switch {
+		case errors.Is(err, errTextModified):
+			return models.NewJsonResponse(409, err.Error(), nil), nil
+		case errors.Is(err, errTextNoStorage):
+			return models.NewJsonResponse(400, err.Error(), nil), nil
+		}
+		logger.LOG.Error("保存文本文件失败", "error", err, "fileID", req.FileID)
+		return nil, err
+	}
+
+	// 3. 清理旧的文件内容并重建索引
+	if removeOld {
+		upload.RemoveStoredFile(fileInfo, oldChunks)
+	}
+	if !copied {
+		if err := f.factory.SearchIndex().DeleteContent(ctx, fileInfo.ID); err != nil {
+			logger.LOG.Warn("删除文件内容的提取结果失败", "error", err, "fileID", fileInfo.ID)
+		}
+	}
+	if err := f.factory.SearchIndex().DeleteDocs(ctx, []string{userFile.UfID}); err != nil {
+		logger.LOG.Warn("删除索引文档失败", "error", err, "ufID", userFile.UfID)
+	}
+	search.Notify(userID, userFile.UfID)
+
+	logger.LOG.Info("文本文件已保存", "ufID", userFile.UfID, "oldFileID", fileInfo.ID, "newFileID", newInfo.ID, "copied", copied)
+	return models.NewJsonResponse(200, "保存成功", &response.TextFileSaveResponse{
+		FileID:  userFile.UfID,
+		ETag:    newInfo.FileHash,
+		Size:    newInfo.Size,
+		Charset: charset,
+		Copied:  copied,
+	}), nil
+}
+
+// textFile 获取要在线编辑的用户文件与文件信息，超过大小上限的文件不支持在线编辑
+func (f *FileService) textFile(ctx context.Context, ufID, userID string) (*models.UserFiles, *models.FileInfo, *models.JsonResponse, error) {
+	userFile, err := f.factory.UserFiles().GetByUserIDAndUfID(ctx, userID, ufID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, models.NewJsonResponse(404, "文件不存在或无权访问", nil), nil
+		}
+		logger.LOG.Error("获取文件失败", "error", err, "fileID", ufID)
+		return nil, nil, nil, err
+	}
+	fileInfo, err := f.factory.FileInfo().GetByID(ctx, userFile.FileID)
+	if err != nil {
+		logger.LOG.Error("获取文件信息失败", "error", err, "fileID", userFile.FileID)
+		return nil, nil, nil, err
+	}
+	if int64(fileInfo.Size) > config.CONFIG.File.TextEditMaxSize*1024*1024 {
+		return nil, nil, models.NewJsonResponse(400, fmt.Sprintf("文件超过在线编辑的大小上限 %d MB", config.CONFIG.File.TextEditMaxSize), nil), nil
+	}
+	return userFile, fileInfo, nil, nil
+}
+
+// checkFilePassword 加密文件校验文件密码
+func (f *FileService) checkFilePassword(ctx context.Context, fileInfo *models.FileInfo, userID, password string) (*models.JsonResponse, error) {
+	if !fileInfo.IsEnc {
+		return nil, nil
+	}
+	if password == "" {
+		return models.NewJsonResponse(400, "加密文件需要提供文件密码", nil), nil
+	}
+	user, err := f.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		logger.LOG.Error("查询用户信息失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	if user.FilePassword == "" || !util.CheckPassword(user.FilePassword, password) {
+		logger.LOG.Warn("文件密码错误", "userID", userID, "fileID", fileInfo.ID)
+		return models.NewJsonResponse(403, "文件密码错误", nil), nil
+	}
+	return nil, nil
+}
+
+// readTextData 读取文件的全部内容，分片文件按顺序拼接，加密文件解密
+func (f *FileService) readTextData(ctx context.Context, fileInfo *models.FileInfo, encryptionKey string) ([]byte, error) {
+	var blob io.ReadSeekCloser
+	if fileInfo.IsChunk {
+		chunks, err := f.factory.FileChunk().GetByFileID(ctx, fileInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) == 0 {
+			return nil, fmt.Errorf("未找到分片文件")
+		}
+		sort.Slice(chunks, func(i, j int) bool {
+			return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+		})
+		paths := make([]string, len(chunks))
+		sizes := make([]int64, len(chunks))
+		for i, c := range chunks {
+			paths[i], sizes[i] = c.ChunkPath, int64(c.ChunkSize)
+		}
+		blob = util.NewChunkedReader(paths, sizes)
+	} else {
+		file, err := os.Open(fileInfo.Path)
+		if err != nil {
+			return nil, err
+		}
+		blob = file
+	}
+	defer blob.Close()
+
+	if !fileInfo.IsEnc {
+		return io.ReadAll(blob)
+	}
+	reader, err := util.NewDecryptReader(blob, int64(fileInfo.Size), encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// writeTextTempFile 将文本内容写入磁盘临时目录：{DataPath}/temp/{文件名不带后缀}_{sessionID}/upload.tmp
+// 临时目录在存储完成后由 upload.StoreFile 删除
+func (f *FileService) writeTextTempFile(ctx context.Context, fileName string, data []byte) (string, error) {
+	disk, err := f.factory.Disk().GetBigDisk(ctx)
+	if err != nil {
+		return "", fmt.Errorf("获取磁盘失败: %w", err)
+	}
+	sessionID := uuid.Must(uuid.NewV7()).String()[:8]
+	fileNameWithoutExt := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	tempDir := filepath.Join(disk.DataPath, "temp", fmt.Sprintf("%s_%s", fileNameWithoutExt, sessionID))
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	tempFilePath := filepath.Join(tempDir, "upload.tmp")
+	if err := os.WriteFile(tempFilePath, data, 0644); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	return tempFilePath, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
+	"myobj/src/pkg/models"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// timelineDatePattern 时间线日期：yyyy、yyyy-mm 或 yyyy-mm-dd
+var timelineDatePattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+// timelineGroupLengths 时间线分组方式对应的日期前缀长度
+var timelineGroupLengths = map[string]int{"year": 4, "month": 7, "day": 10}
+
+// GetTimeline 按年、月或日分组统计当前用户的照片数
+// 照片按 EXIF 拍摄日期归入时间线，没有拍摄时间的按上传日期
+func (f *FileService) GetTimeline(req *request.TimelineRequest, userID string) (*models.JsonResponse, error) {
+	group := req.Group
+	if group == "" {
+		group = "month"
+	}
+	groups, err := f.factory.FileMetadata().TimelineGroups(context.Background(), userID, timelineGroupLengths[group])
+	if err != nil {
+		logger.LOG.Error("统计照片时间线失败", "error", err, "userID", userID)
+		return nil, err
+	}
+	resp := &response.TimelineResponse{Group: group, Groups: make([]*response.TimelineGroupItem, 0, len(groups))}
+	for _, g := range groups {
+		resp.Groups = append(resp.Groups, &response.TimelineGroupItem{Date: g.Date, Count: g.Count})
+		resp.Total += g.Count
+	}
+	return models.NewJsonResponse(200, "获取成功", resp), nil
+}
+
+// ListTimelineFiles 分页查询当前用户时间线中某一年、月或日的照片，按拍摄时间倒序
+func (f *FileService) ListTimelineFiles(req *request.TimelineFilesRequest, userID string) (*models.JsonResponse, error) {
+	if !timelineDatePattern.MatchString(req.Date) {
+		return models.NewJsonResponse(400, "日期格式错误，应为 yyyy、yyyy-mm 或 yyyy-mm-dd", nil), nil
+	}
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	ctx := context.Background()
+	userFiles, total, err := f.factory.FileMetadata().ListTimelineFiles(ctx, userID, req.Date, (page-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		logger.LOG.Error("查询时间线照片失败", "error", err, "userID", userID, "date", req.Date)
+		return nil, err
+	}
+	fileIDs := make(map[string]string, len(userFiles))
+	for _, uf := range userFiles {
+		fileIDs[uf.UfID] = uf.FileID
+	}
+	items := f.fileItems(ctx, userFiles)
+	files := make([]*response.TimelineFileItem, 0, len(items))
+	for _, item := range items {
+		file := &response.TimelineFileItem{FileItem: item}
+		if m, err := f.factory.FileMetadata().Get(ctx, fileIDs[item.FileID]); err == nil {
+			file.TakenAt, file.TakenDate = m.TakenAt, m.TakenDate
+			file.Width, file.Height = m.Width, m.Height
+			file.CameraMake, file.CameraModel = m.CameraMake, m.CameraModel
+		}
+		files = append(files, file)
+	}
+	return models.NewJsonResponse(200, "获取成功", &response.TimelineFilesResponse{
+		Date:     req.Date,
+		Files:    files,
+		Total:    total,
+		Page:     page,
+		PageSize: req.PageSize,
+	}), nil
+}
+
+// GetFileMetadata 获取文件的媒体元数据（自己的文件或公开文件）
+// 尚未提取时加入提取队列；非所有者访问公开文件且启用 strip_gps 时不返回经纬度
+func (f *FileService) GetFileMetadata(ufID, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	userFile, err := f.factory.UserFiles().GetByUfID(ctx, ufID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.NewJsonResponse(404, "文件不存在", nil), nil
+		}
+		return nil, err
+	}
+	if !userFile.IsPublic && userFile.UserID != userID {
+		return models.NewJsonResponse(403, "无权访问此文件", nil), nil
+	}
+	m, err := f.factory.FileMetadata().Get(ctx, userFile.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metadata.Notify(userFile.FileID)
+			return models.NewJsonResponse(404, "元数据尚未提取，请稍后再试", nil), nil
+		}
+		logger.LOG.Error("获取媒体元数据失败", "error", err, "fileID", userFile.FileID)
+		return nil, fmt.Errorf("获取媒体元数据失败: %w", err)
+	}
+	if userFile.UserID != userID && config.CONFIG.File.StripGPS {
+		m.Latitude, m.Longitude = nil, nil
+	}
+	return models.NewJsonResponse(200, "获取成功", &response.FileMetadataResponse{
+		FileID:   userFile.UfID,
+		FileName: userFile.FileName,
+		Metadata: m,
+	}), nil
+}
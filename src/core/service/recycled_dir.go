@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/dirtree"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
+)
+
+// dirItem 构造目录条目的列表项
+func (r *RecycledService) dirItem(ctx context.Context, recycled *models.Recycled) (*response.RecycledItem, error) {
+	summary, size, err := recycle.NewBin(r.factory).Stat(ctx, recycled)
+	if err != nil {
+		return nil, err
+	}
+	return &response.RecycledItem{
+		RecycledID:   recycled.ID,
+		FileName:     dirtree.DirName(recycled.OriginalPath),
+		FileSize:     size,
+		DeletedAt:    recycled.CreatedAt,
+		IsDir:        true,
+		DirID:        recycled.DirID,
+		OriginalPath: recycled.OriginalPath,
+		FileCount:    summary.Files,
+		DirCount:     summary.Dirs,
+	}, nil
+}
+
+// restoreDir 还原目录条目
+// 原位置已存在同名目录且未指定处理方式时返回 409，由用户选择重命名、合并或覆盖后重新提交
+func (r *RecycledService) restoreDir(ctx context.Context, recycled *models.Recycled, conflict string) (*models.JsonResponse, error) {
+	// 覆盖时原位置的目录会被移入回收站，不能被 WebDAV 锁定
+	if conflict == recycle.ConflictOverwrite {
+		lockManager := lock.NewManager(r.factory)
+		if resp := webDAVLockResponse(lockManager.CheckUnlocked(ctx, recycled.UserID, recycled.OriginalPath)); resp != nil {
+			return resp, nil
+		}
+	}
+
+	result, err := recycle.NewBin(r.factory).Restore(ctx, recycled, conflict)
+	if err != nil {
+		if errors.Is(err, recycle.ErrConflict) {
+			return models.NewJsonResponse(409, "原位置已存在同名目录，请选择重命名、合并或覆盖", map[string]interface{}{
+				"original_path": recycled.OriginalPath,
+				"conflicts":     []string{recycle.ConflictRename, recycle.ConflictMerge, recycle.ConflictOverwrite},
+			}), nil
+		}
+		logger.LOG.Error("还原目录失败", "error", err, "recycledID", recycled.ID)
+		return nil, fmt.Errorf("还原目录失败: %w", err)
+	}
+
+	message := "目录已还原"
+	switch {
+	case result.Merged:
+		message = "目录已合并到同名目录"
+	case result.Renamed > 0:
+		message = fmt.Sprintf("目录已还原为 %s", result.Name)
+	}
+	if result.ToRoot {
+		message += "（原父目录已删除，已还原到根目录）"
+	}
+	logger.LOG.Info("目录已还原", "recycledID", recycled.ID, "userID", recycled.UserID,
+		"originalPath", recycled.OriginalPath, "dirID", result.DirID, "conflict", conflict)
+	return models.NewJsonResponse(200, message, result), nil
+}
+
+// deleteDir 永久删除目录条目，返回删除失败的文件数量
+func (r *RecycledService) deleteDir(ctx context.Context, recycled *models.Recycled) (int, error) {
+	return recycle.NewBin(r.factory).Purge(ctx, recycled, func(member *models.Recycled) error {
+		return r.deleteSingleFile(ctx, member)
+	})
+}
+
+// restoreDetail 还原操作的审计详情
+func restoreDetail(req *request.RestoreFileRequest) string {
+	if req.Conflict == "" {
+		return ""
+	}
+	return "冲突处理: " + req.Conflict
+}
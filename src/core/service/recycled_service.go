@@ -7,10 +7,13 @@ import (
 	"myobj/src/core/domain/request"
 	"myobj/src/core/domain/response"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/filecopy"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/search"
 	"os"
 	"path/filepath"
 	"strings"
@@ -59,6 +62,16 @@ func (r *RecycledService) GetRecycledList(req *request.RecycledListRequest, user
 	// 构造响应数据
 	items := make([]*response.RecycledItem, 0, len(recycleds))
 	for _, recycled := range recycleds {
+		// 目录条目显示目录名、删除前路径及其中的文件数
+		if recycled.IsDir() {
+			item, err := r.dirItem(ctx, recycled)
+			if err != nil {
+				logger.LOG.Warn("统计回收站目录失败", "error", err, "userID", userID, "recycledID", recycled.ID)
+				continue
+			}
+			items = append(items, item)
+			continue
+		}
 		// 获取用户文件关联，以获取文件名（使用 Unscoped 查询软删除的记录）
 		var userFile models.UserFiles
 		err = r.factory.DB().Unscoped().Where("user_id = ? AND uf_id = ?", userID, recycled.FileID).First(&userFile).Error
@@ -94,7 +107,10 @@ func (r *RecycledService) GetRecycledList(req *request.RecycledListRequest, user
 }
 
 // RestoreFile 还原文件
-func (r *RecycledService) RestoreFile(req *request.RestoreFileRequest, userID string) (*models.JsonResponse, error) {
+func (r *RecycledService) RestoreFile(req *request.RestoreFileRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(r.factory, actor, audit.ActionRecycledRestore, audit.TargetRecycled, req.RecycledID, restoreDetail(req), res, err)
+	}()
 	ctx := context.Background()
 
 	// 验证回收站记录是否存在且属于该用户
@@ -112,6 +128,11 @@ func (r *RecycledService) RestoreFile(req *request.RestoreFileRequest, userID st
 		return nil, fmt.Errorf("无权操作此文件")
 	}
 
+	// 目录条目按原有层级还原整棵目录树
+	if recycled.IsDir() {
+		return r.restoreDir(ctx, recycled, req.Conflict)
+	}
+
 	// 获取要还原的文件记录（使用 Unscoped 查询软删除的记录）
 	var userFile models.UserFiles
 	err = r.factory.DB().Unscoped().Where("user_id = ? AND uf_id = ?", userID, recycled.FileID).First(&userFile).Error
@@ -198,6 +219,7 @@ func (r *RecycledService) RestoreFile(req *request.RestoreFileRequest, userID st
 		return nil, fmt.Errorf("还原文件失败: %w", err)
 	}
 
+	search.Notify(userID, recycled.FileID)
 	message := "文件已还原"
 	if !parentDirExists {
 		message = "文件已还原到根目录（原父目录已删除）"
@@ -213,7 +235,10 @@ func (r *RecycledService) RestoreFile(req *request.RestoreFileRequest, userID st
 }
 
 // DeletePermanently 永久删除文件
-func (r *RecycledService) DeletePermanently(req *request.DeleteRecycledRequest, userID string) (*models.JsonResponse, error) {
+func (r *RecycledService) DeletePermanently(req *request.DeleteRecycledRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(r.factory, actor, audit.ActionRecycledDelete, audit.TargetRecycled, req.RecycledID, "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 验证回收站记录
@@ -231,6 +256,20 @@ func (r *RecycledService) DeletePermanently(req *request.DeleteRecycledRequest,
 		return nil, fmt.Errorf("无权操作此文件")
 	}
 
+	// 目录条目连同其中的文件一起删除
+	if recycled.IsDir() {
+		failed, err := r.deleteDir(ctx, recycled)
+		if err != nil {
+			logger.LOG.Error("永久删除目录失败", "error", err, "recycledID", req.RecycledID)
+			return nil, fmt.Errorf("永久删除目录失败: %w", err)
+		}
+		if failed > 0 {
+			return models.NewJsonResponse(500, fmt.Sprintf("目录中有 %d 个文件删除失败，请稍后重试", failed), nil), nil
+		}
+		logger.LOG.Info("目录已永久删除", "recycledID", req.RecycledID, "userID", userID, "path", recycled.OriginalPath)
+		return models.NewJsonResponse(200, "目录已永久删除", nil), nil
+	}
+
 	// 执行永久删除
 	if err := r.deleteSingleFile(ctx, recycled); err != nil {
 		logger.LOG.Error("永久删除文件失败", "error", err, "recycledID", req.RecycledID)
@@ -242,7 +281,10 @@ func (r *RecycledService) DeletePermanently(req *request.DeleteRecycledRequest,
 }
 
 // EmptyRecycled 清空回收站
-func (r *RecycledService) EmptyRecycled(userID string) (*models.JsonResponse, error) {
+func (r *RecycledService) EmptyRecycled(userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(r.factory, actor, audit.ActionRecycledEmpty, audit.TargetRecycled, userID, "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 获取该用户的所有回收站记录
@@ -257,6 +299,17 @@ func (r *RecycledService) EmptyRecycled(userID string) (*models.JsonResponse, er
 
 	// 逐个删除
 	for _, recycled := range recycleds {
+		// 目录条目作为整体删除，其中有文件删除失败时计为失败
+		if recycled.IsDir() {
+			failed, err := r.deleteDir(ctx, recycled)
+			if err != nil || failed > 0 {
+				logger.LOG.Error("删除目录失败", "error", err, "recycledID", recycled.ID, "failedFiles", failed)
+				failedCount++
+			} else {
+				deletedCount++
+			}
+			continue
+		}
 		if err := r.deleteSingleFile(ctx, recycled); err != nil {
 			logger.LOG.Error("删除文件失败", "error", err, "recycledID", recycled.ID)
 			failedCount++
@@ -270,7 +323,7 @@ func (r *RecycledService) EmptyRecycled(userID string) (*models.JsonResponse, er
 		"deleted", deletedCount,
 		"failed", failedCount)
 
-	message := fmt.Sprintf("已清空回收站，成功删除 %d 个文件", deletedCount)
+	message := fmt.Sprintf("已清空回收站，成功删除 %d 项", deletedCount)
 	if failedCount > 0 {
 		message = fmt.Sprintf("%s，失败 %d 个", message, failedCount)
 	}
@@ -315,17 +368,17 @@ func (r *RecycledService) deleteSingleFile(ctx context.Context, recycled *models
 		logger.LOG.Error("获取用户文件记录失败", "error", err, "file_id", recycled.FileID)
 		return err
 	}
-	refCount, err := r.factory.Recycled().CountFileReferences(ctx, userFile.FileID)
+	refCount, err := r.factory.Recycled().CountSharedReferences(ctx, userFile.FileID, userFile.UfID)
 	if err != nil {
 		return fmt.Errorf("统计文件引用失败: %w", err)
 	}
 
-	// 2. 如果引用数 > 1，说明其他用户也持有该文件，仅删除回收站记录
-	if refCount > 1 {
-		logger.LOG.Debug("文件被多个用户持有，仅删除回收站记录",
+	// 2. 如果还有其他用户文件引用该文件（其他用户持有或复制产生），仅删除回收站记录
+	if refCount > 0 {
+		logger.LOG.Debug("文件仍被其他用户文件引用，仅删除回收站记录",
 			"file_id", recycled.FileID,
 			"ref_count", refCount)
-		return r.factory.Recycled().Delete(ctx, recycled.ID)
+		return r.releaseSharedFile(ctx, recycled, userFile)
 	}
 
 	// 3. 获取文件信息
@@ -398,6 +451,38 @@ func (r *RecycledService) deleteSingleFile(ctx context.Context, recycled *models
 	})
 }
 
+// releaseSharedFile 删除仍被其他用户文件引用的文件：保留物理文件，只删除回收站记录
+// 复制按文件大小扣除配额时（copy_quota = "full"），每个引用都占用了配额，需要归还
+func (r *RecycledService) releaseSharedFile(ctx context.Context, recycled *models.Recycled, userFile *models.UserFiles) error {
+	if !filecopy.ChargeQuota() {
+		return r.factory.Recycled().Delete(ctx, recycled.ID)
+	}
+	fileInfo, err := r.factory.FileInfo().GetByID(ctx, userFile.FileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return r.factory.Recycled().Delete(ctx, recycled.ID)
+		}
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	user, err := r.factory.User().GetByID(ctx, recycled.UserID)
+	if err != nil {
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	return r.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := r.factory.WithTx(tx)
+		if err := txFactory.Recycled().Delete(ctx, recycled.ID); err != nil {
+			return fmt.Errorf("删除回收站记录失败: %w", err)
+		}
+		if user.Space > 0 {
+			user.FreeSpace += int64(fileInfo.Size)
+			if err := txFactory.User().Update(ctx, user); err != nil {
+				return fmt.Errorf("更新用户空间失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
 // deletePhysicalFile 删除物理文件
 func (r *RecycledService) deletePhysicalFile(fileInfo *models.FileInfo) error {
 	// 如果有加密文件，优先删除加密文件
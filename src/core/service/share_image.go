@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"slices"
+)
+
+// GetShareImage 获取分享图片指定尺寸的衍生图（用于分享页预览，不计入下载次数）
+// 加密文件的衍生图需要文件密码，只能在登录会话中查看，分享页不提供
+func (s *SharesService) GetShareImage(req *request.ShareImageRequest, ip, accept string) (*ImageDerivative, *models.JsonResponse, error) {
+	ctx := context.Background()
+	byToken, err := s.factory.Share().GetByToken(ctx, req.Token)
+	if err != nil || byToken == nil {
+		return nil, models.NewJsonResponse(404, "分享不存在", nil), nil
+	}
+	if byToken.ExpiresAt.Before(custom_type.Now()) {
+		return nil, models.NewJsonResponse(400, "分享已过期", nil), nil
+	}
+	if byToken.PasswordHash != "" {
+		limiter := s.newLoginLimiter()
+		if err := limiter.CheckShare(ctx, req.Token, ip); err != nil {
+			return nil, nil, err
+		}
+		if !util.CheckPassword(byToken.PasswordHash, req.Password) {
+			limiter.ShareFailed(ctx, req.Token, ip)
+			return nil, models.NewJsonResponse(403, "密码错误", nil), nil
+		}
+	}
+
+	fileInfo, err := s.factory.FileInfo().GetByID(ctx, byToken.FileID)
+	if err != nil {
+		logger.LOG.Error("获取文件信息失败", "error", err, "fileID", byToken.FileID)
+		return nil, nil, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	if fileInfo.IsEnc {
+		return nil, models.NewJsonResponse(403, "加密文件不支持预览", nil), nil
+	}
+	base, _, _ := mime.ParseMediaType(fileInfo.Mime)
+	if !slices.Contains(derivativeSources, base) {
+		return nil, models.NewJsonResponse(400, "不支持该文件类型", fileInfo.Mime), nil
+	}
+	opts, resp := imageDerivativeOptions(&req.ImageDerivativeRequest, accept, fileInfo.Mime)
+	if resp != nil {
+		return nil, resp, nil
+	}
+	derivative, err := buildImageDerivative(ctx, s.factory, fileInfo, byToken.UserID, "", opts)
+	if err != nil {
+		logger.LOG.Error("生成分享图片衍生图失败", "error", err, "shareID", byToken.ID, "params", opts.Key())
+		return nil, nil, fmt.Errorf("生成图片失败: %w", err)
+	}
+	return derivative, nil, nil
+}
@@ -2,18 +2,23 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"myobj/src/config"
 	"myobj/src/core/domain/request"
 	"myobj/src/core/domain/response"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/auth"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/download"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/util"
 	"path"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -34,8 +39,16 @@ func (s *SharesService) GetRepository() *impl.RepositoryFactory {
 	return s.factory
 }
 
+// newLoginLimiter 创建密码错误次数限制器（与登录共用 IP 计数）
+func (s *SharesService) newLoginLimiter() *auth.LoginLimiter {
+	return auth.NewLoginLimiter(s.cacheLocal, s.factory.SecurityEvent(), config.CONFIG.Auth.LoginLimit)
+}
+
 // CreateShare 创建分享
-func (s *SharesService) CreateShare(req *request.CreateShareRequest, userID string) (*models.JsonResponse, error) {
+func (s *SharesService) CreateShare(req *request.CreateShareRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(s.factory, actor, audit.ActionShareCreate, audit.TargetFile, req.FileID, fmt.Sprintf("过期时间: %s, 密码保护: %t", req.Expire.Format("2006-01-02 15:04:05"), req.Password != ""), res, err)
+	}()
 	uid := fmt.Sprintf("%s-%v", uuid.New().String(), util.TimeUtil{}.GetTimestamp())
 	
 	// 如果密码为空，不生成哈希，直接设置为空字符串
@@ -73,7 +86,8 @@ func (s *SharesService) CreateShare(req *request.CreateShareRequest, userID stri
 
 // GetShareInfo 获取分享信息（不触发下载）
 // password: 分享密码（如果有密码则必需）
-func (s *SharesService) GetShareInfo(token string, password string) (*response.ShareInfoResponse, error) {
+// ip: 客户端 IP，用于限制密码错误次数
+func (s *SharesService) GetShareInfo(token string, password string, ip string) (*response.ShareInfoResponse, error) {
 	ctx := context.Background()
 	byToken, err := s.factory.Share().GetByToken(ctx, token)
 	if err != nil {
@@ -99,7 +113,13 @@ func (s *SharesService) GetShareInfo(token string, password string) (*response.S
 				IsExpired:   false,
 			}, nil
 		}
+		// 分享或 IP 密码错误次数过多时不再校验密码
+		limiter := s.newLoginLimiter()
+		if err := limiter.CheckShare(ctx, token, ip); err != nil {
+			return nil, err
+		}
 		if !util.CheckPassword(byToken.PasswordHash, password) {
+			limiter.ShareFailed(ctx, token, ip)
 			return nil, fmt.Errorf("密码错误")
 		}
 	}
@@ -130,10 +150,20 @@ func (s *SharesService) GetShareInfo(token string, password string) (*response.S
 	}, nil
 }
 
-// DownloadShare 下载分享文件
-func (s *SharesService) DownloadShare(token, psw string) *response.SharesDownloadResponse {
+// DownloadShare 下载分享文件，ip 用于限制密码错误次数
+func (s *SharesService) DownloadShare(token, psw, ip string) *response.SharesDownloadResponse {
 	ctx := context.Background()
 	sdr := &response.SharesDownloadResponse{}
+	// 匿名下载只记录 IP，对象为分享ID（不记录 token，避免导出的日志泄露分享链接）
+	var shareID string
+	defer func() {
+		var err error
+		if sdr.Err != "" {
+			err = errors.New(sdr.Err)
+		}
+		actor := audit.Actor{IP: ip, Source: audit.SourceShare}
+		recordAudit(s.factory, actor, audit.ActionShareDownload, audit.TargetShare, shareID, sdr.FileName, nil, err)
+	}()
 	byToken, err := s.factory.Share().GetByToken(ctx, token)
 	if err != nil {
 		logger.LOG.Error("获取分享失败", "error", err)
@@ -144,9 +174,20 @@ func (s *SharesService) DownloadShare(token, psw string) *response.SharesDownloa
 		sdr.Err = "获取分享失败"
 		return sdr
 	}
+	shareID = fmt.Sprint(byToken.ID)
 	// 验证密码
 	if byToken.PasswordHash != "" {
+		limiter := s.newLoginLimiter()
+		if err := limiter.CheckShare(ctx, token, ip); err != nil {
+			var limitErr *auth.LimitError
+			if errors.As(err, &limitErr) {
+				sdr.RetryAfter = int((limitErr.RetryAfter + time.Second - 1) / time.Second)
+			}
+			sdr.Err = err.Error()
+			return sdr
+		}
 		if !util.CheckPassword(byToken.PasswordHash, psw) {
+			limiter.ShareFailed(ctx, token, ip)
 			sdr.Err = "密码错误"
 			return sdr
 		}
@@ -186,6 +227,18 @@ func (s *SharesService) DownloadShare(token, psw string) *response.SharesDownloa
 		sdr.Err = "获取文件失败"
 		return sdr
 	}
+	// 按配置去除照片中的位置信息，副本写入临时目录，下载结束后随临时目录删除
+	fileInfo, err := s.factory.FileInfo().GetByID(ctx, byToken.FileID)
+	if err != nil {
+		logger.LOG.Error("获取文件信息失败", "error", err)
+		sdr.Err = "获取文件失败"
+		return sdr
+	}
+	if forDownload, err = metadata.PublicCopy(forDownload, fileInfo.Mime, tmpDir); err != nil {
+		logger.LOG.Error("去除位置信息失败", "error", err, "fileID", byToken.FileID)
+		sdr.Err = "准备文件下载失败"
+		return sdr
+	}
 	byToken.DownloadCount += 1
 	err = s.factory.Share().Update(ctx, byToken)
 	if err != nil {
@@ -236,7 +289,10 @@ func (s *SharesService) GetShareList(userID string) (*models.JsonResponse, error
 }
 
 // DeleteShare 删除分享
-func (s *SharesService) DeleteShare(shareID int, userID string) (*models.JsonResponse, error) {
+func (s *SharesService) DeleteShare(shareID int, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(s.factory, actor, audit.ActionShareDelete, audit.TargetShare, fmt.Sprint(shareID), "", res, err)
+	}()
 	ctx := context.Background()
 	// 验证分享是否属于该用户
 	share, err := s.factory.Share().GetByID(ctx, shareID)
@@ -256,7 +312,10 @@ func (s *SharesService) DeleteShare(shareID int, userID string) (*models.JsonRes
 }
 
 // UpdateSharePassword 修改分享密码
-func (s *SharesService) UpdateSharePassword(shareID int, password string, userID string) (*models.JsonResponse, error) {
+func (s *SharesService) UpdateSharePassword(shareID int, password string, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(s.factory, actor, audit.ActionSharePassword, audit.TargetShare, fmt.Sprint(shareID), fmt.Sprintf("密码保护: %t", password != ""), res, err)
+	}()
 	ctx := context.Background()
 	// 验证分享是否属于该用户
 	share, err := s.factory.Share().GetByID(ctx, shareID)
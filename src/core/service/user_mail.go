@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/mail"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// mailRatePrefix 邮件发送频率限制的缓存前缀
+	mailRatePrefix = "mail_rate:"
+	// mailRateTTL 同一账户两次发送的最小间隔（秒）
+	mailRateTTL = 60
+)
+
+var (
+	errMailDisabled    = errors.New("邮件功能未启用，请联系管理员")
+	errMailLinkInvalid = errors.New("链接无效或已被使用")
+	errMailLinkExpired = errors.New("链接已过期，请重新获取")
+)
+
+// newMailer 按当前配置创建邮件发送器
+func newMailer() *mail.Mailer {
+	return mail.NewMailer(config.CONFIG.Mail)
+}
+
+// newMailToken 生成邮件链接令牌（256 位随机数）及其哈希，数据库只保存哈希
+func newMailToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashMailToken(token), nil
+}
+
+// hashMailToken 计算邮件链接令牌哈希
+func hashMailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendInBackground 在后台发送邮件，失败只记录日志（不阻塞请求，也不向请求方暴露账户是否存在）
+func sendInBackground(kind, userID string, send func() error) {
+	go func() {
+		if err := send(); err != nil {
+			logger.LOG.Error("发送邮件失败", "kind", kind, "user_id", userID, "error", err)
+		}
+	}()
+}
+
+// mailRateLimited 检查并登记发送频率，key 在 mailRateTTL 秒内重复发送时返回 true
+func (u *UserService) mailRateLimited(kind, key string) bool {
+	cacheKey := mailRatePrefix + kind + ":" + strings.ToLower(key)
+	if _, err := u.cacheLocal.Get(cacheKey); err == nil {
+		return true
+	}
+	_ = u.cacheLocal.Set(cacheKey, "1", mailRateTTL)
+	return false
+}
+
+// issueUserToken 为用户签发一次性令牌，同类型的旧令牌同时作废
+func (u *UserService) issueUserToken(ctx context.Context, user *models.UserInfo, kind string, ttl time.Duration) (string, error) {
+	if err := u.factory.UserToken().DeleteByUserID(ctx, user.ID, kind); err != nil {
+		logger.LOG.Warn("删除旧令牌失败", "user_id", user.ID, "kind", kind, "error", err)
+	}
+	token, hash, err := newMailToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := u.factory.UserToken().Create(ctx, &models.UserToken{
+		UserID:    user.ID,
+		Kind:      kind,
+		TokenHash: hash,
+		Email:     user.Email,
+		CreatedAt: custom_type.JsonTime(now),
+		ExpiresAt: custom_type.JsonTime(now.Add(ttl)),
+	}); err != nil {
+		logger.LOG.Error("保存令牌失败", "user_id", user.ID, "kind", kind, "error", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// lookupUserToken 查询未过期的令牌（不删除）
+func (u *UserService) lookupUserToken(ctx context.Context, token, kind string) (*models.UserToken, error) {
+	if token == "" {
+		return nil, errMailLinkInvalid
+	}
+	record, err := u.factory.UserToken().GetByTokenHash(ctx, hashMailToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errMailLinkInvalid
+		}
+		logger.LOG.Error("查询令牌失败", "error", err)
+		return nil, err
+	}
+	if record.Kind != kind {
+		return nil, errMailLinkInvalid
+	}
+	if time.Now().After(time.Time(record.ExpiresAt)) {
+		_, _ = u.factory.UserToken().Delete(ctx, record.ID)
+		return nil, errMailLinkExpired
+	}
+	return record, nil
+}
+
+// consumeUserToken 删除令牌，并发使用同一令牌时只有一个请求成功
+func (u *UserService) consumeUserToken(ctx context.Context, record *models.UserToken) error {
+	deleted, err := u.factory.UserToken().Delete(ctx, record.ID)
+	if err != nil {
+		logger.LOG.Error("删除令牌失败", "id", record.ID, "error", err)
+		return err
+	}
+	if !deleted {
+		return errMailLinkInvalid
+	}
+	return nil
+}
+
+// sendVerifyEmail 签发邮箱验证令牌并发送验证邮件
+func (u *UserService) sendVerifyEmail(ctx context.Context, user *models.UserInfo) error {
+	cfg := config.CONFIG.Mail
+	token, err := u.issueUserToken(ctx, user, models.UserTokenVerifyEmail, time.Duration(cfg.VerifyExpire)*time.Hour)
+	if err != nil {
+		return err
+	}
+	mailer := newMailer()
+	return mailer.Send(user.Email, mail.TemplateVerifyEmail, map[string]any{
+		"UserName":    displayName(user),
+		"Link":        mailer.Link("/api/user/email/verify", url.Values{"token": {token}}),
+		"ExpireHours": cfg.VerifyExpire,
+	})
+}
+
+// sendResetPasswordEmail 签发重置密码令牌并发送重置邮件
+func (u *UserService) sendResetPasswordEmail(ctx context.Context, user *models.UserInfo) error {
+	cfg := config.CONFIG.Mail
+	token, err := u.issueUserToken(ctx, user, models.UserTokenResetPassword, time.Duration(cfg.ResetExpire)*time.Minute)
+	if err != nil {
+		return err
+	}
+	mailer := newMailer()
+	return mailer.Send(user.Email, mail.TemplateResetPassword, map[string]any{
+		"UserName":      displayName(user),
+		"Link":          mailer.Link("/", url.Values{"reset_token": {token}}),
+		"ExpireMinutes": cfg.ResetExpire,
+	})
+}
+
+// validEmail 是否为有效的邮箱地址（不含显示名称）
+func validEmail(email string) bool {
+	addr, err := netmail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// displayName 邮件中的称呼：优先使用昵称
+func displayName(user *models.UserInfo) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.UserName
+}
+
+// VerifyEmail 校验邮件中的验证链接，返回跳转回前端的地址（附带 email_verified 或 email_verify_error 参数）
+func (u *UserService) VerifyEmail(token string) string {
+	mailer := newMailer()
+	if err := u.verifyEmail(token); err != nil {
+		return mailer.Link("/", url.Values{"email_verify_error": {err.Error()}})
+	}
+	return mailer.Link("/", url.Values{"email_verified": {"1"}})
+}
+
+func (u *UserService) verifyEmail(token string) (err error) {
+	ctx := context.Background()
+	record, err := u.lookupUserToken(ctx, token, models.UserTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		actor := audit.Actor{UserID: record.UserID, Source: audit.SourceWeb}
+		recordAudit(u.factory, actor, audit.ActionUserVerifyEmail, audit.TargetUser, record.UserID, record.Email, nil, err)
+	}()
+	if err := u.consumeUserToken(ctx, record); err != nil {
+		return err
+	}
+	user, err := u.factory.User().GetByID(ctx, record.UserID)
+	if err != nil {
+		return errMailLinkInvalid
+	}
+	// 发送后修改过邮箱，旧链接不能验证新邮箱
+	if !strings.EqualFold(user.Email, record.Email) {
+		return fmt.Errorf("邮箱已变更，请重新发送验证邮件")
+	}
+	user.EmailVerifiedAt = custom_type.Now()
+	if user.State == models.UserStatePendingVerify {
+		user.State = models.UserStateNormal
+	}
+	if err := u.factory.User().Update(ctx, user); err != nil {
+		logger.LOG.Error("更新用户失败", "error", err)
+		return err
+	}
+	logger.LOG.Info("邮箱验证成功", "user_id", user.ID, "email", user.Email)
+	return nil
+}
+
+// SendVerifyEmail 登录用户发送邮箱验证邮件
+func (u *UserService) SendVerifyEmail(userID string) (*models.JsonResponse, error) {
+	if !config.CONFIG.Mail.Enable {
+		return nil, errMailDisabled
+	}
+	ctx := context.Background()
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if user.Email == "" {
+		return nil, fmt.Errorf("请先设置邮箱")
+	}
+	if !user.EmailVerifiedAt.IsZero() {
+		return nil, fmt.Errorf("邮箱已验证")
+	}
+	if u.mailRateLimited(models.UserTokenVerifyEmail, user.ID) {
+		return nil, fmt.Errorf("发送过于频繁，请稍后再试")
+	}
+	if err := u.sendVerifyEmail(ctx, user); err != nil {
+		logger.LOG.Error("发送验证邮件失败", "user_id", user.ID, "error", err)
+		return nil, fmt.Errorf("发送验证邮件失败")
+	}
+	return models.NewJsonResponse(200, "验证邮件已发送", nil), nil
+}
+
+// ResendVerifyEmail 未登录时重发验证邮件（注册后邮箱待验证的账户）
+// 无论账户是否存在都返回相同结果，避免被用来探测账户
+func (u *UserService) ResendVerifyEmail(req *request.MailAccountRequest) (*models.JsonResponse, error) {
+	if !config.CONFIG.Mail.Enable {
+		return nil, errMailDisabled
+	}
+	res := models.NewJsonResponse(200, "如果账户存在且邮箱待验证，验证邮件已重新发送", nil)
+	if u.mailRateLimited(models.UserTokenVerifyEmail, req.Account) {
+		return res, nil
+	}
+	ctx := context.Background()
+	for _, user := range u.findMailAccounts(ctx, req.Account) {
+		if user.State != models.UserStatePendingVerify {
+			continue
+		}
+		user := user
+		sendInBackground(models.UserTokenVerifyEmail, user.ID, func() error {
+			return u.sendVerifyEmail(context.Background(), user)
+		})
+	}
+	return res, nil
+}
+
+// findMailAccounts 按用户名或邮箱查找有邮箱的本地账户
+func (u *UserService) findMailAccounts(ctx context.Context, account string) []*models.UserInfo {
+	account = strings.TrimSpace(account)
+	if account == "" {
+		return nil
+	}
+	var users []*models.UserInfo
+	if user, err := u.factory.User().GetByUserName(ctx, account); err == nil && user != nil {
+		users = append(users, user)
+	} else if strings.Contains(account, "@") {
+		list, err := u.factory.User().ListByEmail(ctx, account)
+		if err != nil {
+			logger.LOG.Error("查询用户失败", "error", err)
+			return nil
+		}
+		users = list
+	}
+	result := make([]*models.UserInfo, 0, len(users))
+	for _, user := range users {
+		if user.AuthSource == models.AuthSourceLocal && user.Email != "" {
+			result = append(result, user)
+		}
+	}
+	return result
+}
+
+// ForgotPassword 找回密码：向账户绑定的邮箱发送重置链接
+// account 可以是用户名或邮箱（同一邮箱绑定多个账户时分别发送），无论账户是否存在都返回相同结果
+func (u *UserService) ForgotPassword(req *request.MailAccountRequest, ip string) (*models.JsonResponse, error) {
+	if !config.CONFIG.Mail.Enable {
+		return nil, errMailDisabled
+	}
+	res := models.NewJsonResponse(200, "如果账户存在且绑定了邮箱，重置密码邮件已发送", nil)
+	if u.mailRateLimited(models.UserTokenResetPassword, req.Account) || u.mailRateLimited(models.UserTokenResetPassword, "ip:"+ip) {
+		return res, nil
+	}
+	ctx := context.Background()
+	for _, user := range u.findMailAccounts(ctx, req.Account) {
+		if user.State == models.UserStateDisabled {
+			continue
+		}
+		user := user
+		sendInBackground(models.UserTokenResetPassword, user.ID, func() error {
+			return u.sendResetPasswordEmail(context.Background(), user)
+		})
+	}
+	logger.LOG.Info("找回密码请求", "account", req.Account, "ip", ip)
+	return res, nil
+}
+
+// ResetPassword 通过邮件链接重置登录密码
+// 重置成功后注销该用户的全部会话并解除登录锁定；链接发送到的邮箱与当前邮箱一致时，同时视为完成邮箱验证
+func (u *UserService) ResetPassword(req *request.ResetPasswordRequest, ip string) (res *models.JsonResponse, err error) {
+	var userID string
+	defer func() {
+		// 链接无效时不知道对应的用户，不记录
+		if userID == "" {
+			return
+		}
+		actor := audit.Actor{UserID: userID, IP: ip, Source: audit.SourceWeb}
+		recordAudit(u.factory, actor, audit.ActionUserPassword, audit.TargetUser, userID, "找回密码", res, err)
+	}()
+	ctx := context.Background()
+	psw, err := u.decryptChallenge(req.Challenge, req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("验证已过期")
+	}
+	if psw == "" {
+		return nil, fmt.Errorf("密码不能为空")
+	}
+	// 先校验令牌与密码策略，策略不通过时链接仍可继续使用
+	record, err := u.lookupUserToken(ctx, req.Token, models.UserTokenResetPassword)
+	if err != nil {
+		return nil, err
+	}
+	userID = record.UserID
+	user, err := u.factory.User().GetByID(ctx, record.UserID)
+	if err != nil {
+		return nil, errMailLinkInvalid
+	}
+	if user.AuthSource != models.AuthSourceLocal {
+		return nil, fmt.Errorf("该账户没有本地密码")
+	}
+	if user.State == models.UserStateDisabled {
+		return nil, fmt.Errorf("用户已被禁用")
+	}
+	if err := u.newPasswordPolicy().Check(ctx, models.PasswordKindLogin, user, psw); err != nil {
+		return nil, err
+	}
+	if err := u.consumeUserToken(ctx, record); err != nil {
+		return nil, err
+	}
+	password, err := util.GeneratePassword(psw)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = password
+	user.PasswordChangedAt = custom_type.Now()
+	if strings.EqualFold(user.Email, record.Email) {
+		if user.EmailVerifiedAt.IsZero() {
+			user.EmailVerifiedAt = custom_type.Now()
+		}
+		if user.State == models.UserStatePendingVerify {
+			user.State = models.UserStateNormal
+		}
+	}
+	if err := u.factory.User().Update(ctx, user); err != nil {
+		logger.LOG.Error("更新用户失败", "error", err)
+		return nil, err
+	}
+	u.recordPasswordHistory(ctx, models.PasswordKindLogin, user.ID, password)
+	if _, err := u.newSessionManager().RevokeAll(ctx, user.ID, ""); err != nil {
+		logger.LOG.Warn("注销用户会话失败", "user_id", user.ID, "error", err)
+	}
+	_ = u.newLoginLimiter().Unlock(ctx, user.UserName, auth.LimitChannelWeb, "找回密码")
+	_ = u.cacheLocal.Delete(req.Challenge)
+
+	return models.NewJsonResponse(200, "密码已重置，请使用新密码登录", nil), nil
+}
+
+// loadInvitation 根据邀请令牌查询可用的邀请
+func (u *UserService) loadInvitation(ctx context.Context, token string) (*models.Invitation, error) {
+	invitation, err := u.factory.Invitation().GetByTokenHash(ctx, hashMailToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("邀请链接无效")
+		}
+		logger.LOG.Error("查询邀请失败", "error", err)
+		return nil, err
+	}
+	if !invitation.UsedAt.IsZero() {
+		return nil, fmt.Errorf("邀请链接已被使用")
+	}
+	if time.Now().After(time.Time(invitation.ExpiresAt)) {
+		return nil, fmt.Errorf("邀请链接已过期")
+	}
+	return invitation, nil
+}
+
+// GetInvitation 查询邀请信息，注册页据此预填邮箱
+func (u *UserService) GetInvitation(token string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	invitation, err := u.loadInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	info := response.InvitationInfoResponse{
+		Email:     invitation.Email,
+		ExpiresAt: invitation.ExpiresAt,
+	}
+	if group, err := u.factory.Group().GetByID(ctx, invitation.GroupID); err == nil {
+		info.GroupName = group.Name
+	}
+	return models.NewJsonResponse(200, "ok", info), nil
+}
@@ -4,18 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"myobj/src/config"
 	"myobj/src/core/domain/request"
 	"myobj/src/core/domain/response"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
 	"myobj/src/pkg/auth"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/lock"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/util"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/oauth2"
 	"gorm.io/gorm"
 )
 
@@ -34,74 +41,391 @@ func (u *UserService) GetRepository() *impl.RepositoryFactory {
 	return u.factory
 }
 
-// Login 用户登录
-func (u *UserService) Login(username, password, challenge string) (*models.JsonResponse, error) {
+// Login 用户登录，ip 与 userAgent 用于登记会话，remember 为"记住我"（延长会话绝对有效期）
+func (u *UserService) Login(username, password, challenge string, remember bool, ip, userAgent string) (res *models.JsonResponse, err error) {
+	// 登录成功在 issueLogin 中记录（开启两步验证时在第二步完成后记录）
+	defer func() {
+		if err != nil {
+			actor := audit.Actor{UserName: username, IP: ip, Source: audit.SourceWeb}
+			recordAudit(u.factory, actor, audit.ActionUserLogin, audit.TargetUser, username, "密码登录", nil, err)
+		}
+	}()
+	ctx := context.Background()
+	psw, err := u.decryptChallenge(challenge, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证用户名和密码
+	if username == "" || psw == "" {
+		return nil, fmt.Errorf("用户名或密码不能为空")
+	}
+	// 账户或 IP 失败次数过多时拒绝登录，不再校验密码
+	limiter := u.newLoginLimiter()
+	if err := limiter.CheckLogin(ctx, username, ip); err != nil {
+		logger.LOG.Warn("登录失败次数过多,拒绝登录", "username", username, "ip", ip)
+		return nil, err
+	}
+	user, err := u.factory.User().GetByUserName(ctx, username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.LOG.Error("查询用户失败", "error", err)
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = nil
+	}
+	// 目录账户（或本地不存在、已启用 LDAP 时）由目录校验密码，同名的本地账户优先
+	ldapManager := u.newLDAPManager()
+	if ldapManager.Handles(user) {
+		user, err = ldapManager.Login(ctx, username, psw)
+		if err != nil {
+			if errors.Is(err, auth.ErrLDAPInvalidCredentials) || errors.Is(err, auth.ErrLDAPUserNotFound) {
+				limiter.LoginFailed(ctx, auth.LimitChannelWeb, username, ip, "目录认证失败: "+err.Error())
+			}
+			return nil, err
+		}
+	} else {
+		if user == nil {
+			limiter.LoginFailed(ctx, auth.LimitChannelWeb, username, ip, "用户不存在")
+			return nil, fmt.Errorf("用户不存在")
+		}
+		if user.State == 1 {
+			return nil, fmt.Errorf("用户已被禁用")
+		}
+		if !util.CheckPassword(user.Password, psw) {
+			logger.LOG.Error("密码错误", "error", err)
+			limiter.LoginFailed(ctx, auth.LimitChannelWeb, username, ip, "密码错误")
+			return nil, fmt.Errorf("密码错误")
+		}
+		// 密码正确后才提示待验证，避免泄露账户状态
+		if user.State == models.UserStatePendingVerify {
+			return nil, fmt.Errorf("邮箱未验证，请查收验证邮件完成激活")
+		}
+		disabled, err := auth.LocalLoginDisabled(ctx, u.factory.Group(), user.GroupID)
+		if err != nil {
+			logger.LOG.Error("查询用户组失败", "error", err)
+			return nil, err
+		}
+		if disabled {
+			return nil, fmt.Errorf("所在用户组已禁用密码登录,请使用单点登录")
+		}
+	}
+
+	// 删除已使用的挑战
+	_ = u.cacheLocal.Delete(challenge)
+
+	return u.completeLogin(ctx, user, remember, ip, userAgent)
+}
+
+// completeLogin 身份校验通过后完成登录
+// 开启两步验证（或所在组要求开启）时不直接下发令牌，返回票据进入第二步验证
+func (u *UserService) completeLogin(ctx context.Context, user *models.UserInfo, remember bool, ip, userAgent string) (*models.JsonResponse, error) {
+	twoFactor := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group())
+	enabled, err := twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		logger.LOG.Error("查询两步验证状态失败", "error", err)
+		return nil, err
+	}
+	required := false
+	if !enabled {
+		if required, err = twoFactor.Required(ctx, user.GroupID); err != nil {
+			logger.LOG.Error("查询用户组失败", "error", err)
+			return nil, err
+		}
+	}
+	if enabled || required {
+		ticket := uuid.NewString()
+		if err := u.cacheLocal.Set(twoFactorTicketPrefix+ticket, encodeTwoFactorTicket(user.ID, 0, remember), twoFactorTicketTTL); err != nil {
+			logger.LOG.Error("缓存登录票据失败", "error", err)
+			return nil, err
+		}
+		return models.NewJsonResponse(202, "需要两步验证", response.TwoFactorLoginResponse{
+			Ticket:        ticket,
+			SetupRequired: !enabled,
+		}), nil
+	}
+
+	return u.finishLogin(ctx, user, remember, ip, userAgent, nil)
+}
+
+// LoginTwoFactor 两步验证登录（第二步）：校验票据与验证码（或恢复码）后下发登录令牌
+// 验证码与密码一样使用挑战公钥加密传输；所在组要求开启但尚未绑定的用户，在此步骤完成绑定
+func (u *UserService) LoginTwoFactor(req *request.TwoFactorLoginRequest, ip, userAgent string) (res *models.JsonResponse, err error) {
+	actor := audit.Actor{IP: ip, Source: audit.SourceWeb}
+	defer func() {
+		if err != nil {
+			recordAudit(u.factory, actor, audit.ActionUserLogin, audit.TargetUser, actor.UserName, "两步验证", nil, err)
+		}
+	}()
+	ctx := context.Background()
+	userID, attempts, remember, err := u.getTwoFactorTicket(req.Ticket)
+	if err != nil {
+		return nil, err
+	}
+	actor.UserID = userID
+	code, err := u.decryptChallenge(req.Challenge, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	_ = u.cacheLocal.Delete(req.Challenge)
+
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	actor.UserName = user.UserName
+	if user.State == 1 {
+		return nil, fmt.Errorf("用户已被禁用")
+	}
+	// 验证码错误同样计入账户失败次数，避免重复输入密码获取新票据来绕过单个票据的次数限制
+	limiter := u.newLoginLimiter()
+	if err := limiter.CheckLogin(ctx, user.UserName, ip); err != nil {
+		return nil, err
+	}
+
+	twoFactor := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group())
+	enabled, err := twoFactor.Enabled(ctx, userID)
+	if err != nil {
+		logger.LOG.Error("查询两步验证状态失败", "error", err)
+		return nil, err
+	}
+	var recoveryCodes []string
+	if enabled {
+		var usedRecovery bool
+		if usedRecovery, err = twoFactor.Verify(ctx, userID, code); err == nil && usedRecovery {
+			logger.LOG.Warn("用户使用恢复码登录", "user_id", userID, "ip", ip)
+		}
+	} else {
+		recoveryCodes, err = twoFactor.Enable(ctx, userID, code)
+		if err == nil {
+			logger.LOG.Info("用户已在登录时绑定两步验证", "user_id", userID)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, auth.ErrTwoFactorCodeInvalid) {
+			limiter.LoginFailed(ctx, auth.LimitChannelWeb, user.UserName, ip, "两步验证码错误")
+			// 限制单个票据的尝试次数，超过后需重新输入密码
+			attempts++
+			if attempts >= twoFactorMaxAttempts {
+				_ = u.cacheLocal.Delete(twoFactorTicketPrefix + req.Ticket)
+				logger.LOG.Warn("两步验证失败次数过多", "user_id", userID, "ip", ip)
+				return nil, fmt.Errorf("验证失败次数过多,请重新登录")
+			}
+			_ = u.cacheLocal.Set(twoFactorTicketPrefix+req.Ticket, encodeTwoFactorTicket(userID, attempts, remember), twoFactorTicketTTL)
+		}
+		return nil, err
+	}
+
+	_ = u.cacheLocal.Delete(twoFactorTicketPrefix + req.Ticket)
+	return u.finishLogin(ctx, user, remember, ip, userAgent, recoveryCodes)
+}
+
+// LoginTwoFactorSetup 所在组要求开启两步验证但尚未绑定时，通过登录票据获取 TOTP 密钥
+func (u *UserService) LoginTwoFactorSetup(req *request.TwoFactorTicketRequest) (*models.JsonResponse, error) {
 	ctx := context.Background()
+	userID, _, _, err := u.getTwoFactorTicket(req.Ticket)
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	twoFactor := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group())
+	required, err := twoFactor.Required(ctx, user.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return nil, fmt.Errorf("无需绑定两步验证")
+	}
+	secret, uri, err := twoFactor.Setup(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "ok", map[string]string{
+		"secret": secret,
+		"uri":    uri,
+	}), nil
+}
+
+// decryptChallenge 使用挑战私钥解密客户端提交的密文（密码、验证码等）
+func (u *UserService) decryptChallenge(challenge, ciphertext string) (string, error) {
 	get, err := u.cacheLocal.Get(challenge)
 	if err != nil {
 		logger.LOG.Error("获取缓存失败", "error", err)
-		return nil, err
+		return "", err
 	}
 	challengeId := get.(string)
 	if challengeId == "" {
-		return nil, fmt.Errorf("验证已过期")
+		return "", fmt.Errorf("验证已过期")
 	}
-	decrypt, err := util.Decrypt(challengeId, password)
+	decrypt, err := util.Decrypt(challengeId, ciphertext)
 	if err != nil {
 		logger.LOG.Error("密码挑战验证失败", "error", err)
+		return "", err
+	}
+	return string(decrypt), nil
+}
+
+// newLDAPManager 创建目录账户管理器
+func (u *UserService) newLDAPManager() *auth.LDAPManager {
+	return auth.NewLDAPManager(config.CONFIG.LDAP, u.factory.User(), u.factory.Group(), u.factory.VirtualPath(), u.factory.UserSession())
+}
+
+// newLoginLimiter 创建登录失败限制器
+func (u *UserService) newLoginLimiter() *auth.LoginLimiter {
+	return auth.NewLoginLimiter(u.cacheLocal, u.factory.SecurityEvent(), config.CONFIG.Auth.LoginLimit)
+}
+
+// newPasswordPolicy 创建密码策略管理器
+func (u *UserService) newPasswordPolicy() *auth.PasswordPolicyManager {
+	return auth.NewPasswordPolicyManager(u.factory.SysConfig(), u.factory.PasswordHistory())
+}
+
+// recordPasswordHistory 记录历史密码，失败只记录日志，不影响密码修改结果
+func (u *UserService) recordPasswordHistory(ctx context.Context, kind, userID, hash string) {
+	if err := u.newPasswordPolicy().Record(ctx, kind, userID, hash); err != nil {
+		logger.LOG.Warn("记录历史密码失败", "user_id", userID, "kind", kind, "error", err)
+	}
+}
+
+// finishLogin 全部身份校验（含两步验证）通过后，登录密码已过期的本地账户必须先修改密码才能下发令牌
+// 过期检查放在两步验证之后，仅持有密码无法借改密流程接管账户
+func (u *UserService) finishLogin(ctx context.Context, user *models.UserInfo, remember bool, ip, userAgent string, recoveryCodes []string) (*models.JsonResponse, error) {
+	expired, err := u.newPasswordPolicy().Expired(ctx, models.PasswordKindLogin, user)
+	if err != nil {
+		logger.LOG.Error("查询密码策略失败", "error", err)
 		return nil, err
 	}
-	psw := string(decrypt)
+	if !expired {
+		return u.issueLogin(ctx, user, remember, ip, userAgent, recoveryCodes)
+	}
+	ticket := uuid.NewString()
+	if err := u.cacheLocal.Set(passwordChangeTicketPrefix+ticket, encodePasswordChangeTicket(user.ID, remember), passwordChangeTicketTTL); err != nil {
+		logger.LOG.Error("缓存改密票据失败", "error", err)
+		return nil, err
+	}
+	logger.LOG.Info("登录密码已过期,要求修改密码", "user_id", user.ID)
+	return models.NewJsonResponse(203, "密码已过期,请修改密码", response.PasswordExpiredResponse{
+		Ticket:        ticket,
+		RecoveryCodes: recoveryCodes,
+	}), nil
+}
 
-	// 验证用户名和密码
-	if username == "" || psw == "" {
-		return nil, fmt.Errorf("用户名或密码不能为空")
+// LoginChangePassword 登录密码过期时，使用登录返回的票据修改密码并完成登录
+func (u *UserService) LoginChangePassword(req *request.ExpiredPasswordChangeRequest, ip, userAgent string) (res *models.JsonResponse, err error) {
+	actor := audit.Actor{IP: ip, Source: audit.SourceWeb}
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionUserPassword, audit.TargetUser, actor.UserID, "密码过期修改", res, err)
+	}()
+	ctx := context.Background()
+	get, err := u.cacheLocal.Get(passwordChangeTicketPrefix + req.Ticket)
+	if err != nil {
+		return nil, fmt.Errorf("登录已过期,请重新登录")
 	}
-	user, err := u.factory.User().GetByUserName(ctx, username)
+	value, _ := get.(string)
+	userID, remember, ok := splitRememberFlag(value)
+	if !ok {
+		return nil, fmt.Errorf("登录已过期,请重新登录")
+	}
+	actor.UserID = userID
+	newPsw, err := u.decryptChallenge(req.Challenge, req.NewPasswd)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) || user == nil {
-			return nil, fmt.Errorf("用户不存在")
-		}
-		logger.LOG.Error("查询用户失败", "error", err)
 		return nil, err
 	}
+	_ = u.cacheLocal.Delete(req.Challenge)
+
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	actor.UserName = user.UserName
 	if user.State == 1 {
 		return nil, fmt.Errorf("用户已被禁用")
 	}
-	if !util.CheckPassword(user.Password, psw) {
-		logger.LOG.Error("密码错误", "error", err)
-		return nil, fmt.Errorf("密码错误")
+	policy := u.newPasswordPolicy()
+	if err := policy.Check(ctx, models.PasswordKindLogin, user, newPsw); err != nil {
+		return nil, err
 	}
-	powers, err := u.factory.Power().GetByGroupID(ctx, user.GroupID)
+	password, err := util.GeneratePassword(newPsw)
 	if err != nil {
-		logger.LOG.Error("查询用户权限失败", "error", err)
 		return nil, err
 	}
-	user.Password = ""
-	user.FilePassword = ""
-	res := response.UserLoginResponse{
-		Token: "",
-		User:  user,
-		Power: powers,
+	user.Password = password
+	user.PasswordChangedAt = custom_type.Now()
+	if err := u.factory.User().Update(ctx, user); err != nil {
+		return nil, err
+	}
+	u.recordPasswordHistory(ctx, models.PasswordKindLogin, user.ID, password)
+	_ = u.cacheLocal.Delete(passwordChangeTicketPrefix + req.Ticket)
+	return u.issueLogin(ctx, user, remember, ip, userAgent, nil)
+}
+
+// newSessionManager 创建登录会话管理器
+func (u *UserService) newSessionManager() *auth.SessionManager {
+	return auth.NewSessionManager(u.factory.UserSession(), u.factory.RefreshToken())
+}
+
+// newTokenIssuer 创建登录令牌签发器
+func (u *UserService) newTokenIssuer() *auth.TokenIssuer {
+	return auth.NewTokenIssuer(u.cacheLocal, u.factory.UserSession(), u.factory.RefreshToken(), u.factory.User(), u.factory.Power())
+}
+
+// issueLogin 登记会话并生成访问令牌与刷新令牌
+func (u *UserService) issueLogin(ctx context.Context, user *models.UserInfo, remember bool, ip, userAgent string, recoveryCodes []string) (*models.JsonResponse, error) {
+	// 文件密码过期只做提醒，不阻止登录与访问已加密的文件
+	filePasswordExpired, err := u.newPasswordPolicy().Expired(ctx, models.PasswordKindFile, user)
+	if err != nil {
+		logger.LOG.Warn("查询文件密码策略失败", "error", err)
 	}
-	uid := uuid.New().String()
-	jwt, err := auth.GenerateJWT(user.ID, uid, res)
+	// 登记会话（按用户索引，用于会话列表与定向注销）
+	res, err := u.newTokenIssuer().Login(ctx, user, remember, ip, userAgent)
 	if err != nil {
-		logger.LOG.Error("生成JWT失败", "error", err)
 		return nil, err
 	}
-	_ = u.cacheLocal.Set(uid, jwt, 7300)
-	res.Token = uid
+	// 完成登录（包括两步验证）后清除账户的失败记录
+	u.newLoginLimiter().LoginSucceeded(user.UserName)
+	actor := audit.Actor{UserID: user.ID, UserName: user.UserName, IP: ip, Source: audit.SourceWeb}
+	recordAudit(u.factory, actor, audit.ActionUserLogin, audit.TargetUser, user.UserName, "", nil, nil)
 	res.Power = nil
+	res.RecoveryCodes = recoveryCodes
+	res.FilePasswordExpired = filePasswordExpired
 
-	// 删除已使用的挑战
-	_ = u.cacheLocal.Delete(challenge)
+	return models.NewJsonResponse(200, "登录成功", *res), nil
+}
 
-	return models.NewJsonResponse(200, "登录成功", res), nil
+// RefreshToken 使用刷新令牌换取新的访问令牌与刷新令牌（刷新令牌随之轮换）
+func (u *UserService) RefreshToken(refreshToken, ip string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	res, session, err := u.newTokenIssuer().Refresh(ctx, refreshToken, ip)
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			actor := audit.Actor{UserID: session.UserID, IP: ip, Source: audit.SourceWeb}
+			recordAudit(u.factory, actor, audit.ActionSessionTokenReuse, audit.TargetSession, session.ID, "刷新令牌重复使用", nil, err)
+		}
+		return nil, err
+	}
+	res.Power = nil
+	return models.NewJsonResponse(200, "ok", *res), nil
+}
+
+// Logout 退出登录，注销当前会话
+func (u *UserService) Logout(userID, sessionID string) (*models.JsonResponse, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("当前登录方式不支持退出登录")
+	}
+	if err := u.newSessionManager().Revoke(context.Background(), userID, sessionID); err != nil {
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "已退出登录", nil), nil
 }
 
 // Register 用户注册
-func (u *UserService) Register(req *request.UserRegisterRequest) (*models.JsonResponse, error) {
+func (u *UserService) Register(req *request.UserRegisterRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionUserRegister, audit.TargetUser, req.Username, "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 检查系统是否允许注册（第一个用户注册除外，用于系统初始化）
@@ -111,8 +435,16 @@ func (u *UserService) Register(req *request.UserRegisterRequest) (*models.JsonRe
 		return nil, fmt.Errorf("系统错误")
 	}
 
+	// 通过邀请注册时使用邀请预设的用户组、存储空间与邮箱，不受注册开关限制
+	var invitation *models.Invitation
+	if req.InviteToken != "" {
+		if invitation, err = u.loadInvitation(ctx, req.InviteToken); err != nil {
+			return nil, err
+		}
+	}
+
 	// 如果不是第一个用户，需要检查注册配置
-	if userCount > 0 {
+	if userCount > 0 && invitation == nil {
 		allowRegister, err := u.factory.SysConfig().GetByKey(ctx, "allow_register")
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.LOG.Error("查询注册配置失败", "error", err)
@@ -144,6 +476,18 @@ func (u *UserService) Register(req *request.UserRegisterRequest) (*models.JsonRe
 	if req.Username == "" || psw == "" {
 		return nil, fmt.Errorf("用户名或密码不能为空")
 	}
+	if err := u.newPasswordPolicy().Check(ctx, models.PasswordKindLogin, nil, psw); err != nil {
+		return nil, err
+	}
+	email := strings.TrimSpace(req.Email)
+	if invitation != nil {
+		email = invitation.Email
+	}
+	// 要求验证邮箱时，新账户需验证邮箱后才能登录（首个用户与受邀用户除外）
+	requireVerify := userCount > 0 && invitation == nil && config.CONFIG.Mail.Enable && config.CONFIG.Mail.RequireVerification
+	if requireVerify && !validEmail(email) {
+		return nil, fmt.Errorf("请填写有效的邮箱地址")
+	}
 	user, err := u.factory.User().GetByUserName(ctx, req.Username)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		logger.LOG.Error("查询用户失败", "error", err)
@@ -170,6 +514,8 @@ func (u *UserService) Register(req *request.UserRegisterRequest) (*models.JsonRe
 	if isFirstUse {
 		// 首次使用，强制设置为管理员组（ID=1）
 		groupID = 1
+	} else if invitation != nil {
+		groupID = invitation.GroupID
 	} else {
 		// 非首次使用，获取默认组
 		group, err := u.factory.Group().GetDefaultGroup(ctx)
@@ -215,25 +561,52 @@ func (u *UserService) Register(req *request.UserRegisterRequest) (*models.JsonRe
 		}
 	}
 
+	space := group.Space
+	if invitation != nil && invitation.Space > 0 {
+		space = invitation.Space
+	}
+	state := models.UserStateNormal
+	if requireVerify {
+		state = models.UserStatePendingVerify
+	}
 	user = &models.UserInfo{
 		ID:           v7.String(),
 		Name:         req.Nickname,
 		UserName:     req.Username,
 		Password:     password,
-		Email:        req.Email,
+		Email:        email,
 		Phone:        req.Phone,
 		GroupID:      groupID,
 		CreatedAt:    custom_type.Now(),
-		Space:        group.Space,
+		Space:        space,
 		FilePassword: "",
-		FreeSpace:    group.Space,
-		State:        0,
+		FreeSpace:    space,
+		State:        state,
+
+		PasswordChangedAt: custom_type.Now(),
+	}
+	// 邀请邮件发送到受邀邮箱，通过邀请链接注册即视为邮箱已验证
+	if invitation != nil {
+		user.EmailVerifiedAt = custom_type.Now()
 	}
 	err = u.factory.User().Create(ctx, user)
 	if err != nil {
 		logger.LOG.Error("创建用户失败", "error", err)
 		return nil, err
 	}
+	if invitation != nil {
+		// 同一邀请被并发使用时只保留先完成的注册
+		used, err := u.factory.Invitation().MarkUsed(ctx, invitation.ID, user.ID)
+		if err != nil || !used {
+			_ = u.factory.User().Delete(ctx, user.ID)
+			if err != nil {
+				logger.LOG.Error("标记邀请已使用失败", "error", err)
+				return nil, err
+			}
+			return nil, fmt.Errorf("邀请链接已被使用")
+		}
+	}
+	u.recordPasswordHistory(ctx, models.PasswordKindLogin, user.ID, password)
 	virtualPath := &models.VirtualPath{
 		UserID:      user.ID,
 		Path:        "home",
@@ -249,6 +622,18 @@ func (u *UserService) Register(req *request.UserRegisterRequest) (*models.JsonRe
 	// 删除已使用的挑战
 	_ = u.cacheLocal.Delete(req.Challenge)
 
+	if requireVerify {
+		sendInBackground(models.UserTokenVerifyEmail, user.ID, func() error {
+			return u.sendVerifyEmail(context.Background(), user)
+		})
+		return models.NewJsonResponse(200, "注册成功，请查收验证邮件完成激活", user), nil
+	}
+	// 未强制验证时也发送验证邮件，用户可稍后验证
+	if config.CONFIG.Mail.Enable && invitation == nil && validEmail(user.Email) {
+		sendInBackground(models.UserTokenVerifyEmail, user.ID, func() error {
+			return u.sendVerifyEmail(context.Background(), user)
+		})
+	}
 	return models.NewJsonResponse(200, "注册成功", user), nil
 }
 
@@ -301,6 +686,9 @@ func (u *UserService) initDefaultPowersForAdminGroup(ctx context.Context) error
 		
 		// WebDAV访问权限
 		{"WebDAV访问", "允许通过WebDAV协议访问文件系统", "webdav:access"},
+
+		// SFTP访问权限
+		{"SFTP访问", "允许通过SFTP协议访问文件系统", "sftp:access"},
 	}
 	
 	// 获取所有现有权限
@@ -426,6 +814,9 @@ func (u *UserService) SysInit() (*models.JsonResponse, error) {
 	result := map[string]interface{}{
 		"is_first_use":   isFirstUse,
 		"allow_register": allowRegister,
+		// 是否启用邮件（前端据此显示找回密码入口）以及注册是否要求验证邮箱
+		"mail_enabled":         config.CONFIG.Mail.Enable,
+		"require_verification": config.CONFIG.Mail.Enable && config.CONFIG.Mail.RequireVerification,
 	}
 	return models.NewJsonResponse(200, "ok", result), nil
 }
@@ -443,8 +834,10 @@ func (u *UserService) UpdateUser(req *request.UserUpdateRequest) (*models.JsonRe
 	if req.Nickname != "" {
 		user.Name = req.Nickname
 	}
-	if req.Email != "" {
+	if req.Email != "" && req.Email != user.Email {
+		// 邮箱变更后需要重新验证
 		user.Email = req.Email
+		user.EmailVerifiedAt = custom_type.JsonTime{}
 	}
 	if req.Phone != "" {
 		user.Phone = req.Phone
@@ -456,7 +849,10 @@ func (u *UserService) UpdateUser(req *request.UserUpdateRequest) (*models.JsonRe
 }
 
 // UpdatePassword 修改用户密码
-func (u *UserService) UpdatePassword(req *request.UserUpdatePasswordRequest) (*models.JsonResponse, error) {
+func (u *UserService) UpdatePassword(req *request.UserUpdatePasswordRequest, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionUserPassword, audit.TargetUser, req.ID, "", res, err)
+	}()
 	// 验证挑战是否有效
 	get, err := u.cacheLocal.Get(req.Challenge)
 	if err != nil {
@@ -489,17 +885,28 @@ func (u *UserService) UpdatePassword(req *request.UserUpdatePasswordRequest) (*m
 	if err != nil {
 		return nil, err
 	}
+	if user.AuthSource == models.AuthSourceLDAP {
+		return nil, fmt.Errorf("目录账户请在 LDAP 中修改密码")
+	}
+	if user.AuthSource == models.AuthSourceOIDC {
+		return nil, fmt.Errorf("单点登录账户没有本地密码")
+	}
 	if !util.CheckPassword(user.Password, oldPsw) {
 		return nil, fmt.Errorf("密码错误")
 	}
+	if err := u.newPasswordPolicy().Check(ctx, models.PasswordKindLogin, user, newPsw); err != nil {
+		return nil, err
+	}
 	password, err := util.GeneratePassword(newPsw)
 	if err != nil {
 		return nil, err
 	}
 	user.Password = password
+	user.PasswordChangedAt = custom_type.Now()
 	if err := u.factory.User().Update(ctx, user); err != nil {
 		return nil, err
 	}
+	u.recordPasswordHistory(ctx, models.PasswordKindLogin, user.ID, password)
 
 	// 删除已使用的挑战
 	_ = u.cacheLocal.Delete(req.Challenge)
@@ -536,13 +943,18 @@ func (u *UserService) SetFilePassword(req *request.UserSetFilePasswordRequest) (
 	if psw == "" {
 		return nil, fmt.Errorf("密码不能为空")
 	}
+	if err := u.newPasswordPolicy().Check(ctx, models.PasswordKindFile, user, psw); err != nil {
+		return nil, err
+	}
 	user.FilePassword, err = util.GeneratePassword(psw)
 	if err != nil {
 		return nil, err
 	}
+	user.FilePasswordChangedAt = custom_type.Now()
 	if err := u.factory.User().Update(ctx, user); err != nil {
 		return nil, err
 	}
+	u.recordPasswordHistory(ctx, models.PasswordKindFile, user.ID, user.FilePassword)
 
 	// 删除已使用的挑战
 	_ = u.cacheLocal.Delete(req.Challenge)
@@ -587,14 +999,19 @@ func (u *UserService) UpdateFilePassword(req *request.UserUpdatePasswordRequest)
 	if !util.CheckPassword(user.FilePassword, oldPsw) {
 		return nil, fmt.Errorf("密码错误")
 	}
+	if err := u.newPasswordPolicy().Check(ctx, models.PasswordKindFile, user, newPsw); err != nil {
+		return nil, err
+	}
 	password, err := util.GeneratePassword(newPsw)
 	if err != nil {
 		return nil, err
 	}
 	user.FilePassword = password
+	user.FilePasswordChangedAt = custom_type.Now()
 	if err := u.factory.User().Update(ctx, user); err != nil {
 		return nil, err
 	}
+	u.recordPasswordHistory(ctx, models.PasswordKindFile, user.ID, password)
 
 	// 删除已使用的挑战
 	_ = u.cacheLocal.Delete(req.Challenge)
@@ -603,9 +1020,33 @@ func (u *UserService) UpdateFilePassword(req *request.UserUpdatePasswordRequest)
 }
 
 // GenerateApiKey 生成API Key
-func (u *UserService) GenerateApiKey(req *request.GenerateApiKeyRequest, userID string) (*models.JsonResponse, error) {
+func (u *UserService) GenerateApiKey(req *request.GenerateApiKeyRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionAPIKeyCreate, audit.TargetAPIKey, req.Name, fmt.Sprintf("权限: %v, 目录: %d, IP: %v, 有效天数: %d", req.Scopes, req.RootDirID, req.AllowedIPs, req.ExpiresDays), res, err)
+	}()
 	ctx := context.Background()
 
+	// 校验权限范围、访问目录与 IP 白名单
+	scopes, err := u.validateApiKeyScopes(ctx, userID, req.Scopes)
+	if err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+	rootPath := "/"
+	if req.RootDirID != 0 {
+		dir, err := u.factory.VirtualPath().GetByID(ctx, req.RootDirID)
+		if err != nil || dir.UserID != userID || !dir.IsDir {
+			return models.NewJsonResponse(400, "目录不存在", nil), nil
+		}
+		if rootPath, err = lock.NewManager(u.factory).DirPath(ctx, req.RootDirID); err != nil {
+			logger.LOG.Error("解析目录路径失败", "error", err, "dirID", req.RootDirID)
+			return nil, fmt.Errorf("解析目录路径失败: %w", err)
+		}
+	}
+	allowedIPs := auth.SplitList(strings.Join(req.AllowedIPs, ","))
+	if err := auth.ValidateIPAllowlist(allowedIPs); err != nil {
+		return models.NewJsonResponse(400, err.Error(), nil), nil
+	}
+
 	// 生成唯一的 API Key（使用 UUID）
 	apiKeyStr := uuid.Must(uuid.NewV7()).String()
 
@@ -634,6 +1075,10 @@ func (u *UserService) GenerateApiKey(req *request.GenerateApiKeyRequest, userID
 		PrivateKey: keyPair.PrivateKey,
 		ExpiresAt:  expiresAt,
 		CreatedAt:  custom_type.Now(),
+		Name:       req.Name,
+		Scopes:     strings.Join(scopes, ","),
+		RootDirID:  req.RootDirID,
+		AllowedIPs: strings.Join(allowedIPs, ","),
 	}
 
 	// 保存到数据库
@@ -642,7 +1087,7 @@ func (u *UserService) GenerateApiKey(req *request.GenerateApiKeyRequest, userID
 		return nil, fmt.Errorf("保存API Key失败: %w", err)
 	}
 
-	logger.LOG.Info("API Key已生成", "userID", userID, "apiKeyID", apiKey.ID)
+	logger.LOG.Info("API Key已生成", "userID", userID, "apiKeyID", apiKey.ID, "scopes", apiKey.Scopes, "rootDirID", apiKey.RootDirID)
 
 	// 处理过期时间：如果为零值，返回 null
 	var expiresAtResp interface{} = nil
@@ -654,12 +1099,44 @@ func (u *UserService) GenerateApiKey(req *request.GenerateApiKeyRequest, userID
 	return models.NewJsonResponse(200, "API Key生成成功", map[string]interface{}{
 		"id":         apiKey.ID,
 		"key":        apiKeyStr,
-		"public_key": keyPair.PublicKey, // 返回公钥，用于客户端签名
-		"expires_at": expiresAtResp,
-		"created_at": apiKey.CreatedAt,
+		"public_key":  keyPair.PublicKey, // 返回公钥，用于客户端签名
+		"name":        apiKey.Name,
+		"scopes":      scopes,
+		"root_dir_id": apiKey.RootDirID,
+		"root_path":   rootPath,
+		"allowed_ips": allowedIPs,
+		"expires_at":  expiresAtResp,
+		"created_at":  apiKey.CreatedAt,
 	}), nil
 }
 
+// validateApiKeyScopes 校验 API Key 的权限范围，只能从用户所在组已有的权限中选择
+func (u *UserService) validateApiKeyScopes(ctx context.Context, userID string, scopes []string) ([]string, error) {
+	scopes = auth.SplitList(strings.Join(scopes, ","))
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	powers, err := u.factory.Power().GetByGroupID(ctx, user.GroupID)
+	if err != nil {
+		logger.LOG.Error("查询用户组权限失败", "error", err, "groupID", user.GroupID)
+		return nil, fmt.Errorf("查询用户组权限失败")
+	}
+	owned := make(map[string]bool, len(powers))
+	for _, p := range powers {
+		owned[p.Characteristic] = true
+	}
+	for _, scope := range scopes {
+		if !owned[scope] {
+			return nil, fmt.Errorf("所在用户组没有权限: %s", scope)
+		}
+	}
+	return scopes, nil
+}
+
 // ListApiKeys 获取用户的API Key列表
 func (u *UserService) ListApiKeys(userID string) (*models.JsonResponse, error) {
 	ctx := context.Background()
@@ -672,6 +1149,7 @@ func (u *UserService) ListApiKeys(userID string) (*models.JsonResponse, error) {
 	}
 
 	// 构造响应数据（不返回完整的 Key 和 PrivateKey，只返回部分信息）
+	lockManager := lock.NewManager(u.factory)
 	items := make([]map[string]interface{}, 0, len(apiKeys))
 	for _, key := range apiKeys {
 		// 只显示 Key 的前8位和后4位，中间用*代替
@@ -683,12 +1161,35 @@ func (u *UserService) ListApiKeys(userID string) (*models.JsonResponse, error) {
 			expiresAt = key.ExpiresAt
 		}
 
+		// 访问目录已被删除时 root_path 返回 null，该 API Key 将无法访问任何文件
+		var rootPath interface{} = "/"
+		if key.RootDirID != 0 {
+			rootPath = nil
+			if dirPath, err := lockManager.DirPath(ctx, key.RootDirID); err == nil {
+				rootPath = dirPath
+			}
+		}
+
+		// 从未使用时返回 null
+		var lastUsedAt interface{} = nil
+		if !key.LastUsedAt.IsZero() {
+			lastUsedAt = key.LastUsedAt
+		}
+
 		item := map[string]interface{}{
-			"id":         key.ID,
-			"key":        maskedKey,
-			"expires_at": expiresAt,
-			"created_at": key.CreatedAt,
-			"is_expired": false,
+			"id":           key.ID,
+			"key":          maskedKey,
+			"name":         key.Name,
+			"scopes":       auth.SplitList(key.Scopes),
+			"root_dir_id":  key.RootDirID,
+			"root_path":    rootPath,
+			"allowed_ips":  auth.SplitList(key.AllowedIPs),
+			"last_used_at": lastUsedAt,
+			"last_used_ip": key.LastUsedIP,
+			"usage_count":  key.UsageCount,
+			"expires_at":   expiresAt,
+			"created_at":   key.CreatedAt,
+			"is_expired":   false,
 		}
 
 		// 检查是否过期
@@ -709,7 +1210,10 @@ func (u *UserService) ListApiKeys(userID string) (*models.JsonResponse, error) {
 }
 
 // DeleteApiKey 删除API Key
-func (u *UserService) DeleteApiKey(req *request.DeleteApiKeyRequest, userID string) (*models.JsonResponse, error) {
+func (u *UserService) DeleteApiKey(req *request.DeleteApiKeyRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionAPIKeyDelete, audit.TargetAPIKey, fmt.Sprint(req.ApiKeyID), "", res, err)
+	}()
 	ctx := context.Background()
 
 	// 验证API Key是否存在且属于该用户
@@ -738,6 +1242,328 @@ func (u *UserService) DeleteApiKey(req *request.DeleteApiKeyRequest, userID stri
 	return models.NewJsonResponse(200, "API Key已删除", nil), nil
 }
 
+// ListSessions 获取用户的登录会话列表，currentSessionID 为当前请求所属的会话
+func (u *UserService) ListSessions(userID, currentSessionID string) (*models.JsonResponse, error) {
+	sessions, err := u.newSessionManager().List(context.Background(), userID)
+	if err != nil {
+		logger.LOG.Error("查询会话列表失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("查询会话列表失败: %w", err)
+	}
+	items := make([]*response.UserSessionInfo, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, &response.UserSessionInfo{
+			UserSession: *s,
+			Current:     s.ID == currentSessionID,
+		})
+	}
+	return models.NewJsonResponse(200, "获取成功", items), nil
+}
+
+// RevokeSession 注销用户的指定会话
+func (u *UserService) RevokeSession(req *request.RevokeSessionRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionSessionRevoke, audit.TargetSession, req.SessionID, "", res, err)
+	}()
+	if err := u.newSessionManager().Revoke(context.Background(), userID, req.SessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return models.NewJsonResponse(404, err.Error(), nil), nil
+		}
+		logger.LOG.Error("注销会话失败", "error", err, "sessionID", req.SessionID)
+		return nil, fmt.Errorf("注销会话失败: %w", err)
+	}
+	return models.NewJsonResponse(200, "会话已注销", nil), nil
+}
+
+// RevokeOtherSessions 注销用户除当前会话外的所有会话
+func (u *UserService) RevokeOtherSessions(userID, currentSessionID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionSessionRevoke, audit.TargetSession, userID, "注销其他会话", res, err)
+	}()
+	count, err := u.newSessionManager().RevokeAll(context.Background(), userID, currentSessionID)
+	if err != nil {
+		logger.LOG.Error("注销其他会话失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("注销其他会话失败: %w", err)
+	}
+	return models.NewJsonResponse(200, "其他会话已注销", map[string]interface{}{"count": count}), nil
+}
+
+// CreateAppPassword 创建应用专用密码（供 WebDAV、SFTP 等同步协议使用，不能用于网页登录）
+func (u *UserService) CreateAppPassword(req *request.CreateAppPasswordRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionAppPasswordCreate, audit.TargetAppPassword, req.Name, fmt.Sprintf("只读: %t, 目录: %d", req.ReadOnly, req.RootDirID), res, err)
+	}()
+	ctx := context.Background()
+
+	// 校验访问目录是否属于该用户
+	rootPath := "/"
+	if req.RootDirID != 0 {
+		dir, err := u.factory.VirtualPath().GetByID(ctx, req.RootDirID)
+		if err != nil || dir.UserID != userID || !dir.IsDir {
+			return models.NewJsonResponse(400, "目录不存在", nil), nil
+		}
+		if rootPath, err = lock.NewManager(u.factory).DirPath(ctx, req.RootDirID); err != nil {
+			logger.LOG.Error("解析目录路径失败", "error", err, "dirID", req.RootDirID)
+			return nil, fmt.Errorf("解析目录路径失败: %w", err)
+		}
+	}
+
+	password, err := util.GenerateAppPassword()
+	if err != nil {
+		logger.LOG.Error("生成应用专用密码失败", "error", err)
+		return nil, fmt.Errorf("生成应用专用密码失败: %w", err)
+	}
+
+	appPassword := &models.AppPassword{
+		UserID:       userID,
+		Name:         req.Name,
+		PasswordHash: util.HashAppPassword(password),
+		Prefix:       password[:4],
+		ReadOnly:     req.ReadOnly,
+		RootDirID:    req.RootDirID,
+		CreatedAt:    custom_type.Now(),
+	}
+	if err := u.factory.AppPassword().Create(ctx, appPassword); err != nil {
+		logger.LOG.Error("保存应用专用密码失败", "error", err)
+		return nil, fmt.Errorf("保存应用专用密码失败: %w", err)
+	}
+
+	logger.LOG.Info("应用专用密码已创建", "userID", userID, "appPasswordID", appPassword.ID, "readOnly", req.ReadOnly, "rootDirID", req.RootDirID)
+
+	// 返回明文密码（注意：只返回一次，后续无法再获取）
+	return models.NewJsonResponse(200, "应用专用密码创建成功", map[string]interface{}{
+		"id":          appPassword.ID,
+		"name":        appPassword.Name,
+		"password":    password,
+		"read_only":   appPassword.ReadOnly,
+		"root_dir_id": appPassword.RootDirID,
+		"root_path":   rootPath,
+		"created_at":  appPassword.CreatedAt,
+	}), nil
+}
+
+// ListAppPasswords 获取用户的应用专用密码列表
+func (u *UserService) ListAppPasswords(userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+
+	list, err := u.factory.AppPassword().ListByUserID(ctx, userID)
+	if err != nil {
+		logger.LOG.Error("查询应用专用密码列表失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("查询应用专用密码列表失败: %w", err)
+	}
+
+	lockManager := lock.NewManager(u.factory)
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, p := range list {
+		// 访问目录已被删除时 root_path 返回 null，该密码将无法继续使用
+		var rootPath interface{} = "/"
+		if p.RootDirID != 0 {
+			rootPath = nil
+			if dirPath, err := lockManager.DirPath(ctx, p.RootDirID); err == nil {
+				rootPath = dirPath
+			}
+		}
+
+		// 从未使用时返回 null
+		var lastUsedAt interface{} = nil
+		if !p.LastUsedAt.IsZero() {
+			lastUsedAt = p.LastUsedAt
+		}
+
+		items = append(items, map[string]interface{}{
+			"id":           p.ID,
+			"name":         p.Name,
+			"password":     p.Prefix + "****",
+			"read_only":    p.ReadOnly,
+			"root_dir_id":  p.RootDirID,
+			"root_path":    rootPath,
+			"last_used_at": lastUsedAt,
+			"last_used_ip": p.LastUsedIP,
+			"created_at":   p.CreatedAt,
+		})
+	}
+
+	return models.NewJsonResponse(200, "获取成功", items), nil
+}
+
+// DeleteAppPassword 删除（吊销）应用专用密码
+func (u *UserService) DeleteAppPassword(req *request.DeleteAppPasswordRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionAppPasswordDelete, audit.TargetAppPassword, fmt.Sprint(req.ID), "", res, err)
+	}()
+	ctx := context.Background()
+
+	appPassword, err := u.factory.AppPassword().GetByID(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.NewJsonResponse(404, "应用专用密码不存在", nil), nil
+		}
+		logger.LOG.Error("获取应用专用密码失败", "error", err, "appPasswordID", req.ID)
+		return nil, fmt.Errorf("获取应用专用密码失败: %w", err)
+	}
+
+	if appPassword.UserID != userID {
+		logger.LOG.Warn("用户尝试删除他人的应用专用密码", "userID", userID, "appPasswordID", req.ID)
+		return models.NewJsonResponse(403, "无权操作此应用专用密码", nil), nil
+	}
+
+	if err := u.factory.AppPassword().Delete(ctx, req.ID); err != nil {
+		logger.LOG.Error("删除应用专用密码失败", "error", err, "appPasswordID", req.ID)
+		return nil, fmt.Errorf("删除应用专用密码失败: %w", err)
+	}
+
+	logger.LOG.Info("应用专用密码已删除", "userID", userID, "appPasswordID", req.ID)
+	return models.NewJsonResponse(200, "应用专用密码已删除", nil), nil
+}
+
+// 两步验证登录票据（第一步登录成功后发放，第二步提交验证码时使用）
+const (
+	twoFactorTicketPrefix = "2fa_ticket:"
+	twoFactorTicketTTL    = 300
+	// twoFactorMaxAttempts 单个票据允许的验证码错误次数
+	twoFactorMaxAttempts = 5
+	// 登录密码过期时修改密码的票据
+	passwordChangeTicketPrefix = "pwd_change_ticket:"
+	passwordChangeTicketTTL    = 600
+)
+
+// encodeTwoFactorTicket 编码票据缓存值（用户ID|已失败次数|记住我），本地缓存与 Redis 均以字符串保存
+func encodeTwoFactorTicket(userID string, attempts int, remember bool) string {
+	return fmt.Sprintf("%s|%d|%t", userID, attempts, remember)
+}
+
+// getTwoFactorTicket 读取登录票据，返回用户ID、已失败次数与是否记住登录
+func (u *UserService) getTwoFactorTicket(ticket string) (string, int, bool, error) {
+	expired := fmt.Errorf("登录已过期,请重新登录")
+	get, err := u.cacheLocal.Get(twoFactorTicketPrefix + ticket)
+	if err != nil {
+		return "", 0, false, expired
+	}
+	value, _ := get.(string)
+	value, remember, ok := splitRememberFlag(value)
+	if !ok {
+		return "", 0, false, expired
+	}
+	idx := strings.LastIndex(value, "|")
+	if idx <= 0 {
+		return "", 0, false, expired
+	}
+	attempts, err := strconv.Atoi(value[idx+1:])
+	if err != nil {
+		return "", 0, false, expired
+	}
+	return value[:idx], attempts, remember, nil
+}
+
+// encodePasswordChangeTicket 编码改密票据缓存值（用户ID|记住我）
+func encodePasswordChangeTicket(userID string, remember bool) string {
+	return fmt.Sprintf("%s|%t", userID, remember)
+}
+
+// splitRememberFlag 拆分票据缓存值末尾的 "|记住我" 标记
+func splitRememberFlag(value string) (string, bool, bool) {
+	idx := strings.LastIndex(value, "|")
+	if idx <= 0 {
+		return "", false, false
+	}
+	remember, err := strconv.ParseBool(value[idx+1:])
+	if err != nil {
+		return "", false, false
+	}
+	return value[:idx], remember, true
+}
+
+// GetTwoFactorStatus 获取两步验证状态
+func (u *UserService) GetTwoFactorStatus(userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	twoFactor := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group())
+	enabled, enabledAt, remaining, err := twoFactor.Status(ctx, userID)
+	if err != nil {
+		logger.LOG.Error("查询两步验证状态失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("查询两步验证状态失败: %w", err)
+	}
+	required, err := twoFactor.Required(ctx, user.GroupID)
+	if err != nil {
+		logger.LOG.Error("查询用户组失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("查询两步验证状态失败: %w", err)
+	}
+	return models.NewJsonResponse(200, "获取成功", map[string]interface{}{
+		"enabled":             enabled,
+		"required":            required,
+		"enabled_at":          enabledAt,
+		"recovery_codes_left": remaining,
+	}), nil
+}
+
+// SetupTwoFactor 生成 TOTP 密钥，返回密钥与 otpauth:// 配置地址（前端渲染为二维码）
+// 需调用 EnableTwoFactor 校验验证码后才正式启用
+func (u *UserService) SetupTwoFactor(userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	secret, uri, err := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group()).Setup(ctx, user)
+	if err != nil {
+		if errors.Is(err, auth.ErrTwoFactorAlreadyEnabled) {
+			return nil, err
+		}
+		logger.LOG.Error("生成两步验证密钥失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("生成两步验证密钥失败: %w", err)
+	}
+	return models.NewJsonResponse(200, "ok", map[string]string{
+		"secret": secret,
+		"uri":    uri,
+	}), nil
+}
+
+// EnableTwoFactor 校验验证码后启用两步验证，返回恢复码（只返回一次）
+func (u *UserService) EnableTwoFactor(req *request.TwoFactorCodeRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionTwoFactorEnable, audit.TargetUser, userID, "", res, err)
+	}()
+	codes, err := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group()).Enable(context.Background(), userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	logger.LOG.Info("两步验证已启用", "userID", userID)
+	return models.NewJsonResponse(200, "两步验证已启用", map[string]interface{}{
+		"recovery_codes": codes,
+	}), nil
+}
+
+// DisableTwoFactor 校验验证码（或恢复码）后关闭两步验证
+func (u *UserService) DisableTwoFactor(req *request.TwoFactorCodeRequest, userID string, actor audit.Actor) (res *models.JsonResponse, err error) {
+	defer func() {
+		recordAudit(u.factory, actor, audit.ActionTwoFactorDisable, audit.TargetUser, userID, "", res, err)
+	}()
+	ctx := context.Background()
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if err := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group()).Disable(ctx, user, req.Code); err != nil {
+		return nil, err
+	}
+	logger.LOG.Info("两步验证已关闭", "userID", userID)
+	return models.NewJsonResponse(200, "两步验证已关闭", nil), nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，原有恢复码全部作废
+func (u *UserService) RegenerateRecoveryCodes(req *request.TwoFactorCodeRequest, userID string) (*models.JsonResponse, error) {
+	codes, err := auth.NewTwoFactorManager(u.factory.TwoFactor(), u.factory.Group()).RegenerateRecoveryCodes(context.Background(), userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	logger.LOG.Info("恢复码已重新生成", "userID", userID)
+	return models.NewJsonResponse(200, "恢复码已重新生成", map[string]interface{}{
+		"recovery_codes": codes,
+	}), nil
+}
+
 // maskApiKey 掩码API Key（只显示前8位和后4位）
 func maskApiKey(key string) string {
 	if len(key) <= 12 {
@@ -760,5 +1586,182 @@ func (u *UserService) GetUserInfo(userID string) (*models.JsonResponse, error) {
 		Space:     id.Space,
 		FreeSpace: id.FreeSpace,
 		UserName:  id.UserName,
+
+		EmailVerified: !id.EmailVerifiedAt.IsZero(),
 	}), nil
 }
+
+// OIDC 单点登录状态（跳转到身份提供方前生成，回调时校验并删除）
+const (
+	oidcStatePrefix = "oidc_state:"
+	oidcStateTTL    = 600
+	// oidcCodePrefix 回调完成后发给前端的一次性登录码，用于换取登录令牌（令牌不出现在地址栏中）
+	oidcCodePrefix = "oidc_code:"
+	oidcCodeTTL    = 60
+)
+
+// newOIDCManager 创建单点登录账户管理器
+func (u *UserService) newOIDCManager() *auth.OIDCManager {
+	return auth.NewOIDCManager(config.CONFIG.OIDC, u.factory.User(), u.factory.Group(), u.factory.VirtualPath(), u.factory.UserIdentity())
+}
+
+// OIDCConfig 获取单点登录配置（登录页用于显示单点登录按钮）
+func (u *UserService) OIDCConfig() (*models.JsonResponse, error) {
+	return models.NewJsonResponse(200, "ok", map[string]interface{}{
+		"enable":       config.CONFIG.OIDC.Enable,
+		"display_name": config.CONFIG.OIDC.DisplayName,
+	}), nil
+}
+
+// OIDCAuthorize 生成跳转到身份提供方的授权地址
+// linkUserID 不为空时表示已登录用户绑定单点登录身份，否则为单点登录
+func (u *UserService) OIDCAuthorize(linkUserID string) (*models.JsonResponse, error) {
+	manager := u.newOIDCManager()
+	if !manager.Provider().Enabled() {
+		return nil, fmt.Errorf("未启用单点登录")
+	}
+	state := uuid.NewString()
+	nonce := uuid.NewString()
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := manager.Provider().AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+	// 缓存值：PKCE 校验码|nonce|绑定的用户ID（本地缓存与 Redis 均以字符串保存）
+	if err := u.cacheLocal.Set(oidcStatePrefix+state, verifier+"|"+nonce+"|"+linkUserID, oidcStateTTL); err != nil {
+		logger.LOG.Error("缓存单点登录状态失败", "error", err)
+		return nil, err
+	}
+	return models.NewJsonResponse(200, "ok", map[string]string{"url": authURL}), nil
+}
+
+// OIDCCallback 处理身份提供方回调，返回跳转回前端的地址
+// 登录成功时附带一次性登录码 oidc_code，绑定成功时附带 oidc_linked=1，失败时附带 oidc_error
+func (u *UserService) OIDCCallback(code, state, providerError string) string {
+	ctx := context.Background()
+	fail := func(msg string) string {
+		return oidcFrontendURL(url.Values{"oidc_error": {msg}})
+	}
+	get, err := u.cacheLocal.Get(oidcStatePrefix + state)
+	if state == "" || err != nil {
+		return fail("登录已过期,请重新登录")
+	}
+	_ = u.cacheLocal.Delete(oidcStatePrefix + state)
+	if providerError != "" {
+		logger.LOG.Warn("身份提供方返回错误", "error", providerError)
+		return fail("单点登录已取消")
+	}
+	parts := strings.SplitN(fmt.Sprint(get), "|", 3)
+	if len(parts) != 3 || code == "" {
+		return fail("登录已过期,请重新登录")
+	}
+	verifier, nonce, linkUserID := parts[0], parts[1], parts[2]
+
+	manager := u.newOIDCManager()
+	claims, err := manager.Provider().Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	if linkUserID != "" {
+		if err := manager.Link(ctx, linkUserID, claims); err != nil {
+			return fail(err.Error())
+		}
+		logger.LOG.Info("用户已绑定单点登录身份", "user_id", linkUserID, "subject", claims.Subject)
+		return oidcFrontendURL(url.Values{"oidc_linked": {"1"}})
+	}
+
+	user, err := manager.Login(ctx, claims)
+	if err != nil {
+		return fail(err.Error())
+	}
+	loginCode := uuid.NewString()
+	if err := u.cacheLocal.Set(oidcCodePrefix+loginCode, user.ID, oidcCodeTTL); err != nil {
+		logger.LOG.Error("缓存单点登录码失败", "error", err)
+		return fail("登录失败,请重试")
+	}
+	return oidcFrontendURL(url.Values{"oidc_code": {loginCode}})
+}
+
+// OIDCExchange 使用一次性登录码完成登录（与密码登录一样，开启两步验证时需进行第二步验证）
+func (u *UserService) OIDCExchange(req *request.OIDCExchangeRequest, ip, userAgent string) (res *models.JsonResponse, err error) {
+	actor := audit.Actor{IP: ip, Source: audit.SourceWeb}
+	defer func() {
+		if err != nil {
+			recordAudit(u.factory, actor, audit.ActionUserLogin, audit.TargetUser, actor.UserName, "单点登录", nil, err)
+		}
+	}()
+	ctx := context.Background()
+	get, err := u.cacheLocal.Get(oidcCodePrefix + req.Code)
+	if err != nil {
+		return nil, fmt.Errorf("登录已过期,请重新登录")
+	}
+	_ = u.cacheLocal.Delete(oidcCodePrefix + req.Code)
+	userID, _ := get.(string)
+	actor.UserID = userID
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	actor.UserName = user.UserName
+	if user.State == 1 {
+		return nil, fmt.Errorf("用户已被禁用")
+	}
+	return u.completeLogin(ctx, user, req.RememberMe, ip, userAgent)
+}
+
+// ListOIDCIdentities 获取用户绑定的单点登录身份
+func (u *UserService) ListOIDCIdentities(userID string) (*models.JsonResponse, error) {
+	list, err := u.factory.UserIdentity().ListByUserID(context.Background(), userID)
+	if err != nil {
+		logger.LOG.Error("获取单点登录绑定失败", "error", err, "userID", userID)
+		return nil, fmt.Errorf("获取单点登录绑定失败: %w", err)
+	}
+	return models.NewJsonResponse(200, "获取成功", list), nil
+}
+
+// UnlinkOIDCIdentity 解除单点登录绑定（单点登录创建的账户没有本地密码，不能解除最后一个绑定）
+func (u *UserService) UnlinkOIDCIdentity(req *request.OIDCUnlinkRequest, userID string) (*models.JsonResponse, error) {
+	ctx := context.Background()
+	user, err := u.factory.User().GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if user.AuthSource == models.AuthSourceOIDC {
+		list, err := u.factory.UserIdentity().ListByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) <= 1 {
+			return nil, fmt.Errorf("单点登录账户不能解除最后一个绑定")
+		}
+	}
+	count, err := u.factory.UserIdentity().Delete(ctx, req.ID, userID)
+	if err != nil {
+		logger.LOG.Error("解除单点登录绑定失败", "error", err, "identityID", req.ID)
+		return nil, fmt.Errorf("解除单点登录绑定失败: %w", err)
+	}
+	if count == 0 {
+		return models.NewJsonResponse(404, "绑定不存在", nil), nil
+	}
+	logger.LOG.Info("单点登录绑定已解除", "userID", userID, "identityID", req.ID)
+	return models.NewJsonResponse(200, "已解除绑定", nil), nil
+}
+
+// oidcFrontendURL 拼接跳转回前端的地址
+func oidcFrontendURL(params url.Values) string {
+	target := config.CONFIG.OIDC.FrontendURL
+	if target == "" {
+		target = "/"
+	}
+	sep := "?"
+	if strings.Contains(target, "?") {
+		sep = "&"
+	}
+	return target + sep + params.Encode()
+}
+
+// externalLoginEnabled 是否启用了本地密码以外的登录方式（OIDC 单点登录或 LDAP）
+func externalLoginEnabled() bool {
+	return config.CONFIG.OIDC.Enable || config.CONFIG.LDAP.Enable
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"fmt"
 	"myobj/src/core/domain/request"
 	"myobj/src/core/service"
 	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/audit"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/util"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,7 +32,9 @@ func (a *AdminHandler) Router(c *gin.RouterGroup) {
 		a.service.GetRepository().ApiKey(),
 		a.service.GetRepository().User(),
 		a.service.GetRepository().GroupPower(),
-		a.service.GetRepository().Power())
+		a.service.GetRepository().Power(),
+		a.service.GetRepository().UserSession(),
+		a.service.GetRepository().RefreshToken())
 
 	admin := c.Group("/admin")
 	admin.Use(verify.Verify())
@@ -41,6 +46,23 @@ func (a *AdminHandler) Router(c *gin.RouterGroup) {
 		admin.POST("/user/update", a.UpdateUser)
 		admin.POST("/user/delete", a.DeleteUser)
 		admin.POST("/user/toggle-state", a.ToggleUserState)
+		admin.GET("/user/session/list", a.UserSessionList)
+		admin.POST("/user/session/revoke", a.RevokeUserSession)
+		admin.POST("/user/reset-2fa", a.ResetUserTwoFactor)
+		admin.POST("/user/unlock", a.UnlockUser)
+
+		// 注册邀请
+		admin.GET("/invitation/list", a.InvitationList)
+		admin.POST("/invitation/create", a.CreateInvitation)
+		admin.POST("/invitation/delete", a.DeleteInvitation)
+
+		// 安全事件日志
+		admin.GET("/security/event/list", a.SecurityEventList)
+
+		// 审计日志
+		admin.GET("/audit/list", a.AuditLogList)
+		admin.GET("/audit/export", a.ExportAuditLogs)
+		admin.GET("/audit/verify", a.VerifyAuditLogs)
 
 		// 组管理
 		admin.GET("/group/list", a.GroupList)
@@ -67,6 +89,12 @@ func (a *AdminHandler) Router(c *gin.RouterGroup) {
 		// 系统配置
 		admin.GET("/system/config", a.GetSystemConfig)
 		admin.POST("/system/update-config", a.UpdateSystemConfig)
+		admin.GET("/system/password-policy", a.GetPasswordPolicy)
+		admin.POST("/system/update-password-policy", a.UpdatePasswordPolicy)
+
+		// WebDAV 锁管理
+		admin.GET("/webdav/lock/list", a.WebDAVLockList)
+		admin.POST("/webdav/lock/break", a.BreakWebDAVLock)
 	}
 
 	logger.LOG.Info("[路由] 管理路由注册完成✔️")
@@ -96,7 +124,7 @@ func (a *AdminHandler) CreateUser(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminCreateUser(req)
+	res, err := a.service.AdminCreateUser(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -111,7 +139,7 @@ func (a *AdminHandler) UpdateUser(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminUpdateUser(req)
+	res, err := a.service.AdminUpdateUser(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -126,7 +154,7 @@ func (a *AdminHandler) DeleteUser(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminDeleteUser(req)
+	res, err := a.service.AdminDeleteUser(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -141,7 +169,7 @@ func (a *AdminHandler) ToggleUserState(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminToggleUserState(req)
+	res, err := a.service.AdminToggleUserState(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -168,7 +196,7 @@ func (a *AdminHandler) CreateGroup(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminCreateGroup(req)
+	res, err := a.service.AdminCreateGroup(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -183,7 +211,7 @@ func (a *AdminHandler) UpdateGroup(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminUpdateGroup(req)
+	res, err := a.service.AdminUpdateGroup(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -198,7 +226,7 @@ func (a *AdminHandler) DeleteGroup(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminDeleteGroup(req)
+	res, err := a.service.AdminDeleteGroup(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -230,7 +258,7 @@ func (a *AdminHandler) AssignPower(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminAssignPower(req)
+	res, err := a.service.AdminAssignPower(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -260,7 +288,7 @@ func (a *AdminHandler) CreatePower(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminCreatePower(req)
+	res, err := a.service.AdminCreatePower(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -275,7 +303,7 @@ func (a *AdminHandler) UpdatePower(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminUpdatePower(req)
+	res, err := a.service.AdminUpdatePower(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -290,7 +318,7 @@ func (a *AdminHandler) DeletePower(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminDeletePower(req)
+	res, err := a.service.AdminDeletePower(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -305,7 +333,7 @@ func (a *AdminHandler) BatchDeletePower(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminBatchDeletePower(req)
+	res, err := a.service.AdminBatchDeletePower(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -332,7 +360,7 @@ func (a *AdminHandler) CreateDisk(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminCreateDisk(req)
+	res, err := a.service.AdminCreateDisk(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -347,7 +375,7 @@ func (a *AdminHandler) UpdateDisk(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminUpdateDisk(req)
+	res, err := a.service.AdminUpdateDisk(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -362,7 +390,7 @@ func (a *AdminHandler) DeleteDisk(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminDeleteDisk(req)
+	res, err := a.service.AdminDeleteDisk(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -400,7 +428,239 @@ func (a *AdminHandler) UpdateSystemConfig(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	res, err := a.service.AdminUpdateSystemConfig(req)
+	res, err := a.service.AdminUpdateSystemConfig(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// GetPasswordPolicy 获取密码策略
+func (a *AdminHandler) GetPasswordPolicy(c *gin.Context) {
+	res, err := a.service.AdminGetPasswordPolicy()
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// UpdatePasswordPolicy 更新密码策略
+func (a *AdminHandler) UpdatePasswordPolicy(c *gin.Context) {
+	req := new(request.AdminUpdatePasswordPolicyRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminUpdatePasswordPolicy(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// ========== WebDAV 锁管理 ==========
+
+// UserSessionList 获取用户登录会话列表
+func (a *AdminHandler) UserSessionList(c *gin.Context) {
+	req := new(request.AdminUserSessionListRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminListUserSessions(req)
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// RevokeUserSession 注销用户登录会话（不指定会话时注销该用户的所有会话）
+func (a *AdminHandler) RevokeUserSession(c *gin.Context) {
+	req := new(request.AdminRevokeUserSessionRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminRevokeUserSession(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// ResetUserTwoFactor 重置用户两步验证
+func (a *AdminHandler) ResetUserTwoFactor(c *gin.Context) {
+	req := new(request.AdminResetTwoFactorRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminResetTwoFactor(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// UnlockUser 解除用户登录锁定
+func (a *AdminHandler) UnlockUser(c *gin.Context) {
+	req := new(request.AdminUnlockUserRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminUnlockUser(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// InvitationList 获取注册邀请列表
+func (a *AdminHandler) InvitationList(c *gin.Context) {
+	req := new(request.AdminInvitationListRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminInvitationList(req)
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// CreateInvitation 创建注册邀请（预设用户组与存储空间）并发送邀请邮件
+func (a *AdminHandler) CreateInvitation(c *gin.Context) {
+	req := new(request.AdminCreateInvitationRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminCreateInvitation(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// DeleteInvitation 删除注册邀请
+func (a *AdminHandler) DeleteInvitation(c *gin.Context) {
+	req := new(request.AdminDeleteInvitationRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminDeleteInvitation(req, middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// SecurityEventList 获取安全事件日志列表
+func (a *AdminHandler) SecurityEventList(c *gin.Context) {
+	req := new(request.AdminSecurityEventListRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminSecurityEventList(req)
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// AuditLogList 获取审计日志列表
+func (a *AdminHandler) AuditLogList(c *gin.Context) {
+	req := new(request.AdminAuditLogListRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminAuditLogList(req)
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// ExportAuditLogs 导出审计日志（CSV 或 JSON 文件）
+func (a *AdminHandler) ExportAuditLogs(c *gin.Context) {
+	req := new(request.AdminAuditLogExportRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	filter, err := a.service.AdminAuditLogFilter(&req.AdminAuditLogFilter)
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	format := req.Format
+	if format == "" {
+		format = audit.FormatCSV
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == audit.FormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	fileName := fmt.Sprintf("audit_log_%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Status(200)
+	// 已开始写出文件，出错时只能记录日志
+	if err := a.service.AdminExportAuditLogs(filter, format, c.Writer, middleware.AuditActor(c)); err != nil {
+		logger.LOG.Error("导出审计日志失败", "error", err)
+	}
+}
+
+// VerifyAuditLogs 校验审计日志哈希链
+func (a *AdminHandler) VerifyAuditLogs(c *gin.Context) {
+	res, err := a.service.AdminAuditLogVerify()
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// WebDAVLockList 获取 WebDAV 锁列表
+func (a *AdminHandler) WebDAVLockList(c *gin.Context) {
+	req := new(request.AdminWebDAVLockListRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminWebDAVLockList(req)
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, res)
+}
+
+// BreakWebDAVLock 强制解除 WebDAV 锁
+func (a *AdminHandler) BreakWebDAVLock(c *gin.Context) {
+	req := new(request.AdminBreakWebDAVLockRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	res, err := a.service.AdminBreakWebDAVLock(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -2,15 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"myobj/src/core/domain/request"
 	_ "myobj/src/core/domain/response" // 导入用于Swagger文档生成
 	"myobj/src/core/service"
 	"myobj/src/internal/api/middleware"
 	"myobj/src/pkg/cache"
+	"myobj/src/pkg/convert"
 	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/download"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
 	"myobj/src/pkg/models"
 	"os"
 	"path/filepath"
@@ -18,6 +22,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type DownloadHandler struct {
@@ -37,7 +42,9 @@ func (h *DownloadHandler) Router(c *gin.RouterGroup) {
 		h.service.GetRepository().ApiKey(),
 		h.service.GetRepository().User(),
 		h.service.GetRepository().GroupPower(),
-		h.service.GetRepository().Power())
+		h.service.GetRepository().Power(),
+		h.service.GetRepository().UserSession(),
+		h.service.GetRepository().RefreshToken())
 
 	downloadGroup := c.Group("/download")
 	{
@@ -58,6 +65,8 @@ func (h *DownloadHandler) Router(c *gin.RouterGroup) {
 		downloadGroup.POST("/local/create", middleware.PowerVerify("file:download"), h.CreateLocalFileDownload)
 		// 下载网盘文件
 		downloadGroup.GET("/local/file/:taskID", middleware.PowerVerify("file:download"), h.DownloadLocalFile)
+		// 查询文件下载时可转换的格式
+		downloadGroup.GET("/convert/options", middleware.PowerVerify("file:download"), h.GetConvertOptions)
 		// 解析种子/磁力链
 		downloadGroup.POST("/torrent/parse", middleware.PowerVerify("file:offLine"), h.ParseTorrent)
 		// 开始种子/磁力链下载
@@ -207,6 +216,10 @@ func (h *DownloadHandler) CreateLocalFileDownload(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
+	repo := h.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), req.FileID) {
+		return
+	}
 
 	userID := c.GetString("userID")
 	result, err := h.service.CreateLocalFileDownload(req, userID)
@@ -220,11 +233,12 @@ func (h *DownloadHandler) CreateLocalFileDownload(c *gin.Context) {
 
 // DownloadLocalFile 下载网盘文件
 // @Summary 下载网盘文件
-// @Description 下载已准备完成的网盘文件，支持HTTP Range断点续传
+// @Description 下载已准备完成的网盘文件，支持HTTP Range断点续传；指定 format 时下载转换为该格式的文件（可选格式见 /download/convert/options）
 // @Tags 下载管理
 // @Produce octet-stream
 // @Security BearerAuth
 // @Param taskID path string true "任务ID"
+// @Param format query string false "转换的目标格式（如 png、jpeg、utf8、xlsx）"
 // @Param Range header string false "Range请求头（例：bytes=0-1023）"
 // @Success 200 {file} binary "文件流"
 // @Success 206 {file} binary "部分文件流（Range请求）"
@@ -261,10 +275,47 @@ func (h *DownloadHandler) DownloadLocalFile(c *gin.Context) {
 		return
 	}
 
+	// 指定了目标格式时，下载转换后的文件
+	servePath, fileName := tempFilePath, task.FileName
+	if format := c.Query("format"); format != "" {
+		converted, err := h.service.ConvertLocalFile(c.Request.Context(), task, format)
+		if err != nil {
+			switch {
+			case errors.Is(err, convert.ErrUnsupported):
+				c.JSON(200, models.NewJsonResponse(400, "不支持转换为该格式", format))
+			case errors.Is(err, convert.ErrTooLarge):
+				c.JSON(200, models.NewJsonResponse(400, "文件超过格式转换的大小上限", nil))
+			default:
+				logger.LOG.Error("格式转换失败", "error", err, "taskID", taskID, "format", format)
+				c.JSON(200, models.NewJsonResponse(500, "格式转换失败", err.Error()))
+			}
+			return
+		}
+		if converted.Temp {
+			// 未缓存的转换结果（加密文件）传输结束即删除，Range 请求会重新转换
+			defer os.Remove(converted.Path)
+		}
+		servePath, fileName = converted.Path, converted.FileName
+	} else if userFile, err := h.service.GetRepository().UserFiles().GetByUfID(c.Request.Context(), task.URL); err == nil && userFile.UserID != userID {
+		// 下载其他用户的公开文件时，按配置去除照片中的位置信息
+		fileInfo, err := h.service.GetRepository().FileInfo().GetByID(c.Request.Context(), userFile.FileID)
+		if err != nil {
+			c.JSON(404, models.NewJsonResponse(404, "文件不存在", nil))
+			return
+		}
+		publicDir := filepath.Join(download.TempDir(fileInfo.Path), fmt.Sprintf("public_%s", uuid.New().String()[:8]))
+		defer os.RemoveAll(publicDir)
+		if servePath, err = metadata.PublicCopy(servePath, fileInfo.Mime, publicDir); err != nil {
+			logger.LOG.Error("去除位置信息失败", "error", err, "taskID", taskID)
+			c.JSON(200, models.NewJsonResponse(500, "准备文件失败", nil))
+			return
+		}
+	}
+
 	// 5. 打开文件
-	file, err := os.Open(tempFilePath)
+	file, err := os.Open(servePath)
 	if err != nil {
-		logger.LOG.Error("打开文件失败", "error", err, "path", tempFilePath)
+		logger.LOG.Error("打开文件失败", "error", err, "path", servePath)
 		c.JSON(404, models.NewJsonResponse(404, "文件不存在", nil))
 		return
 	}
@@ -283,8 +334,8 @@ func (h *DownloadHandler) DownloadLocalFile(c *gin.Context) {
 	// 7. 传输文件（使用公共函数）
 	serveFileWithOptions(c, file, fileSize, &serveFileOptions{
 		ContentType:        "application/octet-stream",
-		ContentDisposition: "attachment; filename=\"" + task.FileName + "\"",
-		FileName:           task.FileName,
+		ContentDisposition: "attachment; filename=\"" + fileName + "\"",
+		FileName:           fileName,
 		LogContext:         map[string]interface{}{"taskID": taskID},
 		OnComplete: func() {
 			// 完整文件下载后清理临时文件
@@ -460,7 +511,7 @@ func (h *DownloadHandler) PreviewFile(c *gin.Context) {
 	}
 
 	// 获取临时目录（使用文件所在磁盘的temp目录）
-	tempDir := filepath.Join(filepath.Dir(filepath.Dir(fileInfo.Path)), "temp")
+	tempDir := download.TempDir(fileInfo.Path)
 	result, err := download.PrepareLocalFileDownload(
 		ctx,
 		userFile.FileID,
@@ -475,10 +526,22 @@ func (h *DownloadHandler) PreviewFile(c *gin.Context) {
 		return
 	}
 
+	// 非所有者访问公开文件时，按配置去除照片中的位置信息
+	servePath := result.TempFilePath
+	if userFile.UserID != userID {
+		publicDir := filepath.Join(tempDir, fmt.Sprintf("public_%s", uuid.New().String()[:8]))
+		defer os.RemoveAll(publicDir)
+		if servePath, err = metadata.PublicCopy(servePath, fileInfo.Mime, publicDir); err != nil {
+			logger.LOG.Error("去除位置信息失败", "error", err, "fileID", userFile.FileID)
+			c.JSON(200, models.NewJsonResponse(500, "准备文件失败", nil))
+			return
+		}
+	}
+
 	// 5. 打开文件
-	file, err := os.Open(result.TempFilePath)
+	file, err := os.Open(servePath)
 	if err != nil {
-		logger.LOG.Error("打开文件失败", "error", err, "path", result.TempFilePath)
+		logger.LOG.Error("打开文件失败", "error", err, "path", servePath)
 		c.JSON(200, models.NewJsonResponse(500, "文件不存在", nil))
 		return
 	}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetConvertOptions 查询文件下载时可转换的格式
+// @Summary 查询可转换格式
+// @Description 按文件类型列出下载时可转换的目标格式（图片格式、文本编码、CSV/XLSX，配置 ffmpeg 后包括音视频），下载网盘文件时通过 format 参数指定
+// @Tags 下载管理
+// @Produce json
+// @Security BearerAuth
+// @Param file_id query string true "文件ID"
+// @Success 200 {object} models.JsonResponse{data=response.ConvertOptionsResponse} "查询成功"
+// @Failure 400 {object} models.JsonResponse "参数错误"
+// @Failure 500 {object} models.JsonResponse "查询失败"
+// @Router /download/convert/options [get]
+func (h *DownloadHandler) GetConvertOptions(c *gin.Context) {
+	req := new(request.ConvertOptionsRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	repo := h.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), req.FileID) {
+		return
+	}
+
+	result, err := h.service.GetConvertOptions(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "查询失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
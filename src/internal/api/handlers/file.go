@@ -10,6 +10,7 @@ import (
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
 	"path/filepath"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -32,7 +33,9 @@ func (f *FileHandler) Router(c *gin.RouterGroup) {
 		f.service.GetRepository().ApiKey(),
 		f.service.GetRepository().User(),
 		f.service.GetRepository().GroupPower(),
-		f.service.GetRepository().Power())
+		f.service.GetRepository().Power(),
+		f.service.GetRepository().UserSession(),
+		f.service.GetRepository().RefreshToken())
 
 	// 公开路由（不需要验证）
 	publicGroup := c.Group("/file")
@@ -67,24 +70,49 @@ func (f *FileHandler) Router(c *gin.RouterGroup) {
 		fileGroup.GET("/list", middleware.PowerVerify("file:preview"), f.GetFileList)
 		// 获取缩略图
 		fileGroup.GET("/thumbnail/:fileId", middleware.PowerVerify("file:preview"), f.GetThumbnail)
+		// 获取图片衍生图（按尺寸缩放或裁剪）
+		fileGroup.GET("/image/:fileId", middleware.PowerVerify("file:preview"), f.GetImageDerivative)
 		// 搜索当前用户文件
 		fileGroup.GET("/search/user", middleware.PowerVerify("file:preview"), f.SearchUserFiles)
 		// 搜索公开文件
 		fileGroup.GET("/search/public", middleware.PowerVerify("file:preview"), f.SearchPublicFiles)
+		// 智能文件夹（保存的文件过滤条件）
+		fileGroup.GET("/smart/list", middleware.PowerVerify("file:preview"), f.ListSmartFolders)
+		fileGroup.POST("/smart/save", middleware.PowerVerify("file:preview"), f.SaveSmartFolder)
+		fileGroup.POST("/smart/delete", middleware.PowerVerify("file:preview"), f.DeleteSmartFolder)
+		fileGroup.GET("/smart/files", middleware.PowerVerify("file:preview"), f.ListSmartFolderFiles)
+		// 文件分类视图（全部目录中按分类查看文件）
+		fileGroup.GET("/category/stats", middleware.PowerVerify("file:preview"), f.GetCategoryStats)
+		fileGroup.GET("/category/:name", middleware.PowerVerify("file:preview"), f.ListCategoryFiles)
+		// 照片时间线（按拍摄日期分组）与媒体元数据
+		fileGroup.GET("/timeline", middleware.PowerVerify("file:preview"), f.GetTimeline)
+		fileGroup.GET("/timeline/files", middleware.PowerVerify("file:preview"), f.ListTimelineFiles)
+		fileGroup.GET("/metadata/:fileId", middleware.PowerVerify("file:preview"), f.GetFileMetadata)
+		// 在线预览与编辑文本文件（保存会写入新的文件内容，与上传使用相同权限）
+		fileGroup.POST("/text/read", middleware.PowerVerify("file:preview"), f.ReadTextFile)
+		fileGroup.POST("/text/save", middleware.PowerVerify("file:upload"), f.SaveTextFile)
 		// 创建目录
 		fileGroup.POST("/makeDir", middleware.PowerVerify("dir:create"), f.MakeDir)
 		// 移动文件
 		fileGroup.POST("/move", middleware.PowerVerify("file:move"), f.MoveFile)
+		// 批量移动文件与目录
+		fileGroup.POST("/move/batch", middleware.PowerVerify("file:move"), f.MoveBatch)
+		// 复制文件与目录（复制会占用空间，与上传使用相同权限）
+		fileGroup.POST("/copy", middleware.PowerVerify("file:upload"), f.CopyFiles)
+		fileGroup.GET("/copy/progress", middleware.PowerVerify("file:upload"), f.GetCopyProgress)
 		// 删除文件
 		fileGroup.POST("/delete", middleware.PowerVerify("file:delete"), f.DeleteFile)
 		// 重命名文件（业务逻辑已验证文件所有权，无需额外权限验证）
-		fileGroup.POST("/rename", f.RenameFile)
+		fileGroup.POST("/rename", middleware.ScopeVerify("file:rechristen"), f.RenameFile)
 		// 重命名目录（业务逻辑已验证目录所有权，无需额外权限验证）
-		fileGroup.POST("/renameDir", f.RenameDir)
+		fileGroup.POST("/renameDir", middleware.ScopeVerify("file:rechristen"), f.RenameDir)
 		// 删除目录（业务逻辑已验证目录所有权，无需额外权限验证）
-		fileGroup.POST("/deleteDir", f.DeleteDir)
+		fileGroup.POST("/deleteDir", middleware.ScopeVerify("dir:delete"), f.DeleteDir)
 		// 设置文件公开状态（业务逻辑已验证文件所有权和加密状态，无需额外权限验证）
-		fileGroup.POST("/setPublic", f.SetFilePublic)
+		fileGroup.POST("/setPublic", middleware.ScopeVerify("file:share"), f.SetFilePublic)
+		// 写入加密策略（WebDAV / SFTP 写入时自动加密）
+		fileGroup.GET("/encrypt-policy/list", middleware.PowerVerify("file:upload"), f.ListEncryptPolicies)
+		fileGroup.POST("/encrypt-policy/set", middleware.PowerVerify("file:upload"), f.SetEncryptPolicy)
 		// 获取虚拟路径
 		fileGroup.GET("/virtualPath", middleware.PowerVerify("file:preview"), f.GetVirtualPath)
 		// 打包下载
@@ -114,6 +142,9 @@ func (f *FileHandler) Precheck(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
+	if !middleware.DirScopeAllowed(c, f.service.GetRepository().VirtualPath(), req.PathID) {
+		return
+	}
 	req.UserID = c.GetString("userID")
 	precheck, err := f.service.Precheck(req, f.cache)
 	if err != nil {
@@ -125,14 +156,29 @@ func (f *FileHandler) Precheck(c *gin.Context) {
 
 // SearchUserFiles godoc
 // @Summary 搜索当前用户文件
-// @Description 根据关键词搜索当前用户的文件
+// @Description 根据关键词搜索当前用户的文件。启用全文索引时同时检索文件内容（文本、Markdown、源代码、PDF、docx/xlsx/pptx），
+// @Description 按相关度排序并返回高亮摘要（name_highlight、highlight），支持类型、大小、创建时间与路径过滤；未启用时只按文件名匹配
 // @Tags 文件管理
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param keyword query string true "搜索关键词"
+// @Param type query string false "文件类型，多个用逗号分隔：image、video、audio、document、archive、code、other"
+// @Param ext query string false "扩展名，多个用逗号分隔"
+// @Param minSize query int false "最小文件大小（字节）"
+// @Param maxSize query int false "最大文件大小（字节）"
+// @Param start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param end query string false "创建时间止（只有日期时包含当天）"
+// @Param updatedStart query string false "修改时间起"
+// @Param updatedEnd query string false "修改时间止"
+// @Param encrypted query bool false "是否加密"
+// @Param public query bool false "是否公开"
+// @Param shared query bool false "是否有未过期的分享链接"
+// @Param path query string false "路径前缀，只搜索该目录及其子目录"
+// @Param sortBy query string false "排序（未启用全文索引时有效），见 /file/list"
 // @Param page query int false "页码" default(1)
 // @Param pageSize query int false "每页数量" default(20)
+// @Param cursor query string false "上一页返回的 next_cursor（未启用全文索引时有效）"
 // @Success 200 {object} models.JsonResponse{data=object} "搜索结果"
 // @Failure 500 {object} models.JsonResponse "搜索失败"
 // @Router /file/search/user [get]
@@ -152,6 +198,7 @@ func (f *FileHandler) SearchUserFiles(c *gin.Context) {
 }
 
 // SearchPublicFiles 搜索公开文件（广场）
+// 参数与 SearchUserFiles 相同（不支持路径过滤）
 func (f *FileHandler) SearchPublicFiles(c *gin.Context) {
 	req := new(request.FileSearchRequest)
 	if err := c.ShouldBindQuery(req); err != nil {
@@ -168,14 +215,20 @@ func (f *FileHandler) SearchPublicFiles(c *gin.Context) {
 
 // GetFileList godoc
 // @Summary 获取文件列表
-// @Description 获取当前用户指定目录下的文件列表
+// @Description 获取当前用户指定目录下的文件列表，先返回目录再返回文件。过滤条件只作用于文件，参数与 /file/search/user 相同；
+// @Description sortBy 支持 name、size、created、updated、type（time 同 created），多个用逗号分隔，字段后可加 ":asc" 或 ":desc"；
+// @Description 本页已列完目录且还有更多文件时返回 next_cursor，传入 cursor 后只返回文件
 // @Tags 文件管理
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param virtualPath query string false "虚拟路径"
-// @Param page query int true "页码" minimum(1)
+// @Param type query string false "文件类型，多个用逗号分隔"
+// @Param ext query string false "扩展名，多个用逗号分隔"
+// @Param sortBy query string false "排序，如 type,size:desc"
+// @Param page query int false "页码" minimum(1) default(1)
 // @Param pageSize query int true "每页数量" minimum(1) maximum(100)
+// @Param cursor query string false "上一页返回的 next_cursor"
 // @Success 200 {object} models.JsonResponse{data=object} "文件列表"
 // @Failure 500 {object} models.JsonResponse "获取失败"
 // @Router /file/list [get]
@@ -185,6 +238,13 @@ func (f *FileHandler) GetFileList(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
+	// 限制了访问目录的 API Key 未指定目录时列出其访问目录
+	if rootDirID := middleware.APIKeyRootDir(c); rootDirID != 0 && (req.VirtualPath == "" || req.VirtualPath == "0") {
+		req.VirtualPath = strconv.Itoa(rootDirID)
+	}
+	if !middleware.DirScopeAllowed(c, f.service.GetRepository().VirtualPath(), req.VirtualPath) {
+		return
+	}
 	userID := c.GetString("userID")
 	result, err := f.service.GetFileList(req, userID)
 	if err != nil {
@@ -225,6 +285,9 @@ func (f *FileHandler) GetThumbnail(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "文件ID不能为空", nil))
 		return
 	}
+	if !middleware.FileScopeAllowed(c, f.service.GetRepository().UserFiles(), f.service.GetRepository().VirtualPath(), fileID) {
+		return
+	}
 
 	userID := c.GetString("userID")
 	ctx := c.Request.Context()
@@ -268,6 +331,9 @@ func (f *FileHandler) MakeDir(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
+	if !middleware.DirScopeAllowed(c, f.service.GetRepository().VirtualPath(), req.ParentLevel) {
+		return
+	}
 	userID := c.GetString("userID")
 	makeDir, err := f.service.MakeDir(req, userID)
 	if err != nil {
@@ -284,7 +350,12 @@ func (f *FileHandler) MoveFile(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
-	moveFile, err := f.service.MoveFile(req, c.GetString("userID"))
+	repo := f.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), req.FileID) ||
+		!middleware.DirScopeAllowed(c, repo.VirtualPath(), req.TargetPath) {
+		return
+	}
+	moveFile, err := f.service.MoveFile(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "移动文件失败", err.Error()))
 		return
@@ -320,7 +391,13 @@ func (f *FileHandler) DeleteFile(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
-	result, err := f.service.DeleteFiles(req, c.GetString("userID"))
+	repo := f.service.GetRepository()
+	for _, fileID := range req.FileIDs {
+		if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), fileID) {
+			return
+		}
+	}
+	result, err := f.service.DeleteFiles(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "删除文件失败", err.Error()))
 		return
@@ -346,7 +423,10 @@ func (f *FileHandler) RenameFile(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
-	result, err := f.service.RenameFile(req, c.GetString("userID"))
+	if !middleware.FileScopeAllowed(c, f.service.GetRepository().UserFiles(), f.service.GetRepository().VirtualPath(), req.FileID) {
+		return
+	}
+	result, err := f.service.RenameFile(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "重命名文件失败", err.Error()))
 		return
@@ -373,7 +453,10 @@ func (f *FileHandler) RenameDir(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
-	result, err := f.service.RenameDir(req, c.GetString("userID"))
+	if !f.subDirScopeAllowed(c, req.DirID) {
+		return
+	}
+	result, err := f.service.RenameDir(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "重命名目录失败", err.Error()))
 		return
@@ -383,7 +466,7 @@ func (f *FileHandler) RenameDir(c *gin.Context) {
 
 // DeleteDir godoc
 // @Summary 删除目录
-// @Description 删除目录及其下的所有文件和子目录（文件会移动到回收站）
+// @Description 删除目录，目录连同其下的所有文件和子目录作为一个整体移入回收站，可按原有层级还原
 // @Tags 文件管理
 // @Accept json
 // @Produce json
@@ -400,7 +483,10 @@ func (f *FileHandler) DeleteDir(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
-	result, err := f.service.DeleteDir(req, c.GetString("userID"))
+	if !f.subDirScopeAllowed(c, req.DirID) {
+		return
+	}
+	result, err := f.service.DeleteDir(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "删除目录失败", err.Error()))
 		return
@@ -427,7 +513,7 @@ func (f *FileHandler) SetFilePublic(c *gin.Context) {
 		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
 		return
 	}
-	result, err := f.service.SetFilePublic(req, c.GetString("userID"))
+	result, err := f.service.SetFilePublic(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		logger.LOG.Error("设置文件公开状态失败", "err", err)
 		c.JSON(200, models.NewJsonResponse(500, "设置文件公开状态失败", err.Error()))
@@ -436,6 +522,32 @@ func (f *FileHandler) SetFilePublic(c *gin.Context) {
 	c.JSON(200, result)
 }
 
+// ListEncryptPolicies 获取写入加密策略列表
+func (f *FileHandler) ListEncryptPolicies(c *gin.Context) {
+	result, err := f.service.ListEncryptPolicies(c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取写入加密策略失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// SetEncryptPolicy 开启或关闭目录的写入加密策略
+func (f *FileHandler) SetEncryptPolicy(c *gin.Context) {
+	req := new(request.SetEncryptPolicyRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.SetEncryptPolicy(req, c.GetString("userID"))
+	if err != nil {
+		logger.LOG.Error("设置写入加密策略失败", "err", err)
+		c.JSON(200, models.NewJsonResponse(500, "设置写入加密策略失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
 // UploadFile godoc
 // @Summary 文件上传
 // @Description 支持小文件直传和大文件分片上传
@@ -542,7 +654,7 @@ func (f *FileHandler) GetUploadTaskList(c *gin.Context) {
 
 // PublicFileList 广场公开文件列表
 // @Summary 获取广场公开文件列表
-// @Description 获取广场公开文件列表
+// @Description 获取广场公开文件列表，过滤、排序与游标参数与 /file/list 相同
 // @Tags 文件管理
 // @Accept json
 // @Produce json
@@ -689,3 +801,12 @@ func (f *FileHandler) RenewExpiredTask(c *gin.Context) {
 	}
 	c.JSON(200, result)
 }
+
+// subDirScopeAllowed 检查要修改的目录是否位于 API Key 可访问的目录内（不能修改访问目录本身）
+func (f *FileHandler) subDirScopeAllowed(c *gin.Context, dirID int) bool {
+	if rootDirID := middleware.APIKeyRootDir(c); rootDirID != 0 && dirID == rootDirID {
+		c.JSON(403, models.NewJsonResponse(403, "不能修改API Key的访问目录本身", nil))
+		return false
+	}
+	return middleware.DirScopeAllowed(c, f.service.GetRepository().VirtualPath(), strconv.Itoa(dirID))
+}
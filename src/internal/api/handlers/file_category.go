@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategoryStats godoc
+// @Summary 获取文件分类统计
+// @Description 按分类（image、video、audio、document、archive、code、other）统计当前用户全部目录中的文件数量与占用空间
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=response.CategoryStatsResponse} "分类统计"
+// @Router /file/category/stats [get]
+func (f *FileHandler) GetCategoryStats(c *gin.Context) {
+	result, err := f.service.GetCategoryStats(c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取分类统计失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListCategoryFiles godoc
+// @Summary 获取分类中的文件
+// @Description 分页获取当前用户全部目录中属于该分类的文件，文件分类在上传时按内容识别的 MIME 类型与文件名划分
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "文件分类：image、video、audio、document、archive、code、other"
+// @Param sortBy query string false "排序，如 size:desc，默认按创建时间倒序"
+// @Param page query int false "页码" minimum(1)
+// @Param pageSize query int true "每页数量" minimum(1) maximum(100)
+// @Param cursor query string false "上一页返回的 next_cursor，指定时忽略页码"
+// @Success 200 {object} models.JsonResponse{data=response.CategoryFilesResponse} "文件列表"
+// @Failure 404 {object} models.JsonResponse "不支持的文件分类"
+// @Router /file/category/{name} [get]
+func (f *FileHandler) ListCategoryFiles(c *gin.Context) {
+	req := new(request.CategoryFilesRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.ListCategoryFiles(c.Param("name"), req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取文件列表失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
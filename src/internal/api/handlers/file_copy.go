@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CopyFiles godoc
+// @Summary 复制文件与目录
+// @Description 复制文件、批量文件或整个目录树到目标目录。复制只新建文件记录，不复制物理文件，配额按 copy_quota 策略扣除；
+// @Description 同名处理方式 conflict：rename（默认，重命名为 "名称 (1)"）、skip（跳过）、overwrite（同名文件移入回收站，同名目录合并）。
+// @Description 文件与目录总数超过 copy_async_threshold 时转为后台任务，返回 task_id，通过 /file/copy/progress 查询进度
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.CopyFileRequest true "复制请求"
+// @Success 200 {object} models.JsonResponse{data=object} "复制完成"
+// @Success 200 {object} models.JsonResponse{data=response.CopyTaskResponse} "已创建后台复制任务"
+// @Failure 400 {object} models.JsonResponse "参数错误、空间不足或复制到自身"
+// @Failure 404 {object} models.JsonResponse "文件或目录不存在"
+// @Failure 423 {object} models.JsonResponse "目标位置已被 WebDAV 锁定"
+// @Router /file/copy [post]
+func (f *FileHandler) CopyFiles(c *gin.Context) {
+	req := new(request.CopyFileRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	repo := f.service.GetRepository()
+	if !middleware.DirScopeAllowed(c, repo.VirtualPath(), strconv.Itoa(req.TargetDirID)) {
+		return
+	}
+	for _, fileID := range req.FileIDs {
+		if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), fileID) {
+			return
+		}
+	}
+	for _, dirID := range req.DirIDs {
+		if !middleware.DirScopeAllowed(c, repo.VirtualPath(), strconv.Itoa(dirID)) {
+			return
+		}
+	}
+	result, err := f.service.CopyFiles(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "复制文件失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// GetCopyProgress godoc
+// @Summary 获取复制任务进度
+// @Description 查询后台复制任务的进度与结果，任务完成一小时后过期
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param task_id query string true "复制任务ID"
+// @Success 200 {object} models.JsonResponse{data=response.CopyTaskResponse} "任务进度"
+// @Failure 404 {object} models.JsonResponse "任务不存在或已过期"
+// @Router /file/copy/progress [get]
+func (f *FileHandler) GetCopyProgress(c *gin.Context) {
+	req := new(request.CopyProgressRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.GetCopyProgress(req.TaskID, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取复制进度失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
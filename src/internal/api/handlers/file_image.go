@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/core/service"
+	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetImageDerivative godoc
+// @Summary 获取图片衍生图
+// @Description 按指定尺寸缩放（contain）或裁剪（cover）图片并按 EXIF 方向校正，宽高只能取 image_sizes 中的值且不会放大原图；未指定格式时按 Accept 请求头选择（配置 ffmpeg 时支持 WebP）。衍生图按文件内容缓存；加密文件只能由所有者在登录会话中提供文件密码查看，结果不缓存
+// @Tags 文件管理
+// @Produce image/jpeg,image/png,image/webp
+// @Security BearerAuth
+// @Param fileId path string true "文件ID（uf_id）"
+// @Param w query int false "目标宽度"
+// @Param h query int false "目标高度"
+// @Param fit query string false "缩放方式（contain、cover），默认 contain"
+// @Param q query int false "编码质量（1-100），默认 image_quality"
+// @Param format query string false "输出格式（jpeg、png、webp）"
+// @Param file_password query string false "文件密码（加密文件必需）"
+// @Success 200 {file} binary "衍生图"
+// @Failure 400 {object} models.JsonResponse "不支持的尺寸、格式或文件类型"
+// @Failure 403 {object} models.JsonResponse "无权访问或文件密码错误"
+// @Router /file/image/{fileId} [get]
+func (f *FileHandler) GetImageDerivative(c *gin.Context) {
+	fileID := c.Param("fileId")
+	req := new(request.ImageDerivativeRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	repo := f.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), fileID) {
+		return
+	}
+	// 只有登录会话带有 sessionID，API Key 不能查看加密文件的衍生图
+	session := c.GetString("sessionID") != ""
+	derivative, resp, err := f.service.GetImageDerivative(c.Request.Context(), fileID, c.GetString("userID"), session, req, c.GetHeader("Accept"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "生成图片失败", err.Error()))
+		return
+	}
+	if resp != nil {
+		c.JSON(200, resp)
+		return
+	}
+	sendImageDerivative(c, derivative)
+}
+
+// ShareImage godoc
+// @Summary 获取分享图片的衍生图
+// @Description 分享页预览图片时使用，参数与 /file/image/{fileId} 相同，不计入下载次数；加密文件不支持
+// @Tags 分享管理
+// @Produce image/jpeg,image/png,image/webp
+// @Param token query string true "分享token"
+// @Param password query string false "分享密码"
+// @Param w query int false "目标宽度"
+// @Param h query int false "目标高度"
+// @Param fit query string false "缩放方式（contain、cover），默认 contain"
+// @Param q query int false "编码质量（1-100）"
+// @Param format query string false "输出格式（jpeg、png、webp）"
+// @Success 200 {file} binary "衍生图"
+// @Failure 400 {object} models.JsonResponse "不支持的尺寸、格式或文件类型"
+// @Failure 429 {object} models.JsonResponse "密码错误次数过多"
+// @Router /share/image [get]
+func (s *SharesHandler) ShareImage(c *gin.Context) {
+	req := new(request.ShareImageRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	derivative, resp, err := s.service.GetShareImage(req, c.ClientIP(), c.GetHeader("Accept"))
+	if err != nil {
+		if respondLimited(c, err) {
+			return
+		}
+		c.JSON(200, models.NewJsonResponse(500, "生成图片失败", err.Error()))
+		return
+	}
+	if resp != nil {
+		c.JSON(200, resp)
+		return
+	}
+	sendImageDerivative(c, derivative)
+}
+
+// sendImageDerivative 发送衍生图，未缓存的衍生图（加密文件等）发送后删除临时目录
+func sendImageDerivative(c *gin.Context, derivative *service.ImageDerivative) {
+	if derivative.TempDir != "" {
+		defer func() {
+			if err := os.RemoveAll(derivative.TempDir); err != nil {
+				logger.LOG.Warn("删除衍生图临时目录失败", "dir", derivative.TempDir, "error", err)
+			}
+		}()
+	}
+	c.Header("Vary", "Accept")
+	// 衍生图需要鉴权或分享密码，只允许浏览器缓存，不允许代理缓存
+	if derivative.Private {
+		c.Header("Cache-Control", "private, no-store")
+	} else {
+		c.Header("Cache-Control", "private, max-age=86400")
+	}
+	c.Header("Content-Type", derivative.ContentType)
+	c.File(derivative.Path)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MoveBatch godoc
+// @Summary 批量移动文件与目录
+// @Description 将多个文件与目录移动到同一个目标目录，目录连同其中的子目录与文件整体移动，整批移动在一个事务中完成。
+// @Description 同名处理方式 conflict：skip（跳过）、rename（重命名为 "名称 (1)"）、overwrite（同名文件移入回收站，同名目录合并）；
+// @Description 未指定且目标目录已存在同名项时返回 409 及冲突的名称
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.BatchMoveRequest true "批量移动请求"
+// @Success 200 {object} models.JsonResponse{data=object} "移动完成"
+// @Failure 400 {object} models.JsonResponse "参数错误、移动根目录或移动到自身"
+// @Failure 404 {object} models.JsonResponse "文件或目录不存在"
+// @Failure 409 {object} models.JsonResponse "目标目录已存在同名文件或目录"
+// @Failure 423 {object} models.JsonResponse "文件或目标位置已被 WebDAV 锁定"
+// @Router /file/move/batch [post]
+func (f *FileHandler) MoveBatch(c *gin.Context) {
+	req := new(request.BatchMoveRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	repo := f.service.GetRepository()
+	if !middleware.DirScopeAllowed(c, repo.VirtualPath(), strconv.Itoa(req.TargetDirID)) {
+		return
+	}
+	for _, fileID := range req.FileIDs {
+		if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), fileID) {
+			return
+		}
+	}
+	for _, dirID := range req.DirIDs {
+		if !middleware.DirScopeAllowed(c, repo.VirtualPath(), strconv.Itoa(dirID)) {
+			return
+		}
+	}
+	result, err := f.service.MoveBatch(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "移动文件失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
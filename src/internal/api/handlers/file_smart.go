@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSmartFolders godoc
+// @Summary 获取智能文件夹列表
+// @Description 获取当前用户保存的智能文件夹（命名的文件过滤条件）
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=[]response.SmartFolderItem} "智能文件夹列表"
+// @Router /file/smart/list [get]
+func (f *FileHandler) ListSmartFolders(c *gin.Context) {
+	result, err := f.service.ListSmartFolders(c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取智能文件夹失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// SaveSmartFolder godoc
+// @Summary 保存智能文件夹
+// @Description 创建（id 为空）或修改智能文件夹。过滤条件与文件列表相同：type、ext 可用逗号分隔多个值，
+// @Description encrypted、public、shared 为空时不过滤；keyword 匹配文件名，path 只包含该目录及其子目录中的文件
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.SmartFolderRequest true "智能文件夹"
+// @Success 200 {object} models.JsonResponse{data=response.SmartFolderItem} "保存成功"
+// @Failure 400 {object} models.JsonResponse "参数错误或数量已达上限"
+// @Failure 404 {object} models.JsonResponse "智能文件夹或目录不存在"
+// @Failure 409 {object} models.JsonResponse "已存在同名的智能文件夹"
+// @Router /file/smart/save [post]
+func (f *FileHandler) SaveSmartFolder(c *gin.Context) {
+	req := new(request.SmartFolderRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.SaveSmartFolder(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "保存智能文件夹失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// DeleteSmartFolder godoc
+// @Summary 删除智能文件夹
+// @Description 只删除保存的过滤条件，不影响其中的文件
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.SmartFolderIDRequest true "智能文件夹ID"
+// @Success 200 {object} models.JsonResponse "删除成功"
+// @Failure 404 {object} models.JsonResponse "智能文件夹不存在"
+// @Router /file/smart/delete [post]
+func (f *FileHandler) DeleteSmartFolder(c *gin.Context) {
+	req := new(request.SmartFolderIDRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.DeleteSmartFolder(req.ID, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "删除智能文件夹失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListSmartFolderFiles godoc
+// @Summary 获取智能文件夹中的文件
+// @Description 按智能文件夹保存的条件查询当前用户的全部文件，使用游标分页
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "智能文件夹ID"
+// @Param pageSize query int true "每页数量" minimum(1) maximum(100)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} models.JsonResponse{data=response.SmartFolderFilesResponse} "文件列表"
+// @Failure 404 {object} models.JsonResponse "智能文件夹不存在"
+// @Router /file/smart/files [get]
+func (f *FileHandler) ListSmartFolderFiles(c *gin.Context) {
+	req := new(request.SmartFolderFilesRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.ListSmartFolderFiles(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取文件列表失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
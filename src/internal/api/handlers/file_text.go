@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadTextFile godoc
+// @Summary 读取文本文件
+// @Description 读取文本文件内容用于在线预览与编辑，未指定编码时自动检测（UTF-8、UTF-16、GBK/GB18030），超过 text_edit_max_size 的文件不支持；加密文件需要提供文件密码
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.TextFileReadRequest true "读取文本文件请求"
+// @Success 200 {object} models.JsonResponse{data=response.TextFileResponse} "文本内容，etag 用于保存时检测并发修改"
+// @Failure 400 {object} models.JsonResponse "不是文本文件或超过大小上限"
+// @Failure 403 {object} models.JsonResponse "文件密码错误"
+// @Router /file/text/read [post]
+func (f *FileHandler) ReadTextFile(c *gin.Context) {
+	req := new(request.TextFileReadRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	repo := f.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), req.FileID) {
+		return
+	}
+	result, err := f.service.ReadTextFile(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "读取文件失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// SaveTextFile godoc
+// @Summary 保存文本文件
+// @Description 按指定编码（默认 UTF-8）保存文本内容，etag 与当前文件内容不一致时返回 409；文件内容与其他用户文件共享（秒传、复制）或已公开时保存为当前用户文件的独立副本，不影响其他文件
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.TextFileSaveRequest true "保存文本文件请求"
+// @Success 200 {object} models.JsonResponse{data=response.TextFileSaveResponse} "保存结果与新的 etag"
+// @Failure 400 {object} models.JsonResponse "编码无法表示、超过大小上限或剩余空间不足"
+// @Failure 409 {object} models.JsonResponse "文件已被修改"
+// @Failure 423 {object} models.JsonResponse "文件已被 WebDAV 锁定"
+// @Router /file/text/save [post]
+func (f *FileHandler) SaveTextFile(c *gin.Context) {
+	req := new(request.TextFileSaveRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	repo := f.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), req.FileID) {
+		return
+	}
+	result, err := f.service.SaveTextFile(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "保存文件失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/internal/api/middleware"
+	"myobj/src/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTimeline godoc
+// @Summary 获取照片时间线
+// @Description 按年、月或日分组统计当前用户的照片数，日期倒序；照片按 EXIF 拍摄日期归入时间线，没有拍摄时间的按上传日期
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param group query string false "分组方式：year、month（默认）、day"
+// @Success 200 {object} models.JsonResponse{data=response.TimelineResponse} "时间线分组"
+// @Router /file/timeline [get]
+func (f *FileHandler) GetTimeline(c *gin.Context) {
+	req := new(request.TimelineRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.GetTimeline(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取时间线失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListTimelineFiles godoc
+// @Summary 获取时间线中的照片
+// @Description 分页获取时间线中某一年、月或日的照片，按拍摄时间倒序，返回拍摄时间、尺寸与相机信息
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param date query string true "日期：yyyy、yyyy-mm 或 yyyy-mm-dd"
+// @Param page query int false "页码" minimum(1)
+// @Param pageSize query int true "每页数量" minimum(1) maximum(100)
+// @Success 200 {object} models.JsonResponse{data=response.TimelineFilesResponse} "照片列表"
+// @Failure 400 {object} models.JsonResponse "日期格式错误"
+// @Router /file/timeline/files [get]
+func (f *FileHandler) ListTimelineFiles(c *gin.Context) {
+	req := new(request.TimelineFilesRequest)
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(200, models.NewJsonResponse(400, "参数错误", err.Error()))
+		return
+	}
+	result, err := f.service.ListTimelineFiles(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取照片列表失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// GetFileMetadata godoc
+// @Summary 获取文件的媒体元数据
+// @Description 获取图片的拍摄时间、相机、尺寸与位置，音频的标签与时长，视频的时长与分辨率；元数据在上传后异步提取，加密文件不提取。非所有者访问公开文件且启用 strip_gps 时不返回经纬度
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param fileId path string true "文件ID（uf_id）"
+// @Success 200 {object} models.JsonResponse{data=response.FileMetadataResponse} "媒体元数据"
+// @Failure 403 {object} models.JsonResponse "无权访问此文件"
+// @Failure 404 {object} models.JsonResponse "文件不存在或元数据尚未提取"
+// @Router /file/metadata/{fileId} [get]
+func (f *FileHandler) GetFileMetadata(c *gin.Context) {
+	fileID := c.Param("fileId")
+	repo := f.service.GetRepository()
+	if !middleware.FileScopeAllowed(c, repo.UserFiles(), repo.VirtualPath(), fileID) {
+		return
+	}
+	result, err := f.service.GetFileMetadata(fileID, c.GetString("userID"))
+	if err != nil {
+		c.JSON(200, models.NewJsonResponse(500, "获取媒体元数据失败", err.Error()))
+		return
+	}
+	c.JSON(200, result)
+}
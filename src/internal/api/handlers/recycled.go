@@ -28,7 +28,9 @@ func (h *RecycledHandler) Router(c *gin.RouterGroup) {
 		h.service.GetRepository().ApiKey(),
 		h.service.GetRepository().User(),
 		h.service.GetRepository().GroupPower(),
-		h.service.GetRepository().Power())
+		h.service.GetRepository().Power(),
+		h.service.GetRepository().UserSession(),
+		h.service.GetRepository().RefreshToken())
 
 	recycled := c.Group("/recycled")
 	recycled.Use(verify.Verify())
@@ -48,7 +50,7 @@ func (h *RecycledHandler) Router(c *gin.RouterGroup) {
 
 // GetRecycledList godoc
 // @Summary 获取回收站列表
-// @Description 获取当前用户回收站中的文件列表
+// @Description 获取当前用户回收站中的文件列表，删除的目录作为一个条目显示（is_dir 为 true）
 // @Tags 回收站
 // @Accept json
 // @Produce json
@@ -77,7 +79,8 @@ func (h *RecycledHandler) GetRecycledList(c *gin.Context) {
 
 // RestoreFile godoc
 // @Summary 还原文件
-// @Description 从回收站还原文件到原位置
+// @Description 从回收站还原文件到原位置；目录条目按原有层级还原整棵目录树，原位置已存在同名目录时返回 409，
+// @Description 需指定 conflict 重新提交：rename 重命名、merge 合并（同名文件重命名）、overwrite 覆盖（原目录移入回收站）
 // @Tags 回收站
 // @Accept json
 // @Produce json
@@ -94,7 +97,7 @@ func (h *RecycledHandler) RestoreFile(c *gin.Context) {
 	}
 
 	userID := c.GetString("userID")
-	result, err := h.service.RestoreFile(req, userID)
+	result, err := h.service.RestoreFile(req, userID, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "还原文件失败", err.Error()))
 		return
@@ -122,7 +125,7 @@ func (h *RecycledHandler) DeletePermanently(c *gin.Context) {
 	}
 
 	userID := c.GetString("userID")
-	result, err := h.service.DeletePermanently(req, userID)
+	result, err := h.service.DeletePermanently(req, userID, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "永久删除失败", err.Error()))
 		return
@@ -142,7 +145,7 @@ func (h *RecycledHandler) DeletePermanently(c *gin.Context) {
 // @Router /recycled/empty [post]
 func (h *RecycledHandler) EmptyRecycled(c *gin.Context) {
 	userID := c.GetString("userID")
-	result, err := h.service.EmptyRecycled(userID)
+	result, err := h.service.EmptyRecycled(userID, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(200, models.NewJsonResponse(500, "清空回收站失败", err.Error()))
 		return
@@ -11,6 +11,7 @@ import (
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
 	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -35,11 +36,14 @@ func (s *SharesHandler) Router(c *gin.RouterGroup) {
 		s.service.GetRepository().ApiKey(),
 		s.service.GetRepository().User(),
 		s.service.GetRepository().GroupPower(),
-		s.service.GetRepository().Power())
+		s.service.GetRepository().Power(),
+		s.service.GetRepository().UserSession(),
+		s.service.GetRepository().RefreshToken())
 	share := c.Group("/share")
 	{
 		share.GET("/info", s.GetShareInfo)      // 获取分享信息（不触发下载）
 		share.GET("/download", s.DownloadShare) // 下载分享文件（GET请求，直接触发下载）
+		share.GET("/image", s.ShareImage)       // 获取分享图片的衍生图（预览）
 	}
 	ver := c.Group("/share")
 	ver.Use(verify.Verify())
@@ -62,7 +66,7 @@ func (s *SharesHandler) CreateShare(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
 	}
-	createShare, err := s.service.CreateShare(req, c.GetString("userID"))
+	createShare, err := s.service.CreateShare(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -80,8 +84,11 @@ func (s *SharesHandler) GetShareInfo(c *gin.Context) {
 		return
 	}
 
-	shareInfo, err := s.service.GetShareInfo(token, password)
+	shareInfo, err := s.service.GetShareInfo(token, password, c.ClientIP())
 	if err != nil {
+		if respondLimited(c, err) {
+			return
+		}
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
 	}
@@ -100,7 +107,12 @@ func (s *SharesHandler) DownloadShare(c *gin.Context) {
 	}
 
 	// 调用服务下载分享文件
-	share := s.service.DownloadShare(token, password)
+	share := s.service.DownloadShare(token, password, c.ClientIP())
+	if share.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(share.RetryAfter))
+		c.JSON(429, models.NewJsonResponse(429, share.Err, nil))
+		return
+	}
 	if share.Err != "" {
 		c.JSON(400, models.NewJsonResponse(400, share.Err, nil))
 		return
@@ -162,7 +174,7 @@ func (s *SharesHandler) DeleteShare(c *gin.Context) {
 		return
 	}
 	userID := c.GetString("userID")
-	deleteShare, err := s.service.DeleteShare(req.ID, userID)
+	deleteShare, err := s.service.DeleteShare(req.ID, userID, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -178,7 +190,7 @@ func (s *SharesHandler) UpdateSharePassword(c *gin.Context) {
 		return
 	}
 	userID := c.GetString("userID")
-	updatePassword, err := s.service.UpdateSharePassword(req.ID, req.Password, userID)
+	updatePassword, err := s.service.UpdateSharePassword(req.ID, req.Password, userID, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
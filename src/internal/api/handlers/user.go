@@ -10,6 +10,8 @@ import (
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -29,15 +31,32 @@ func NewUserHandler(service *service.UserService, cacheLocal cache.Cache) *UserH
 
 func (u *UserHandler) Router(c *gin.RouterGroup) {
 	c.POST("/user/login", u.Login)
+	c.POST("/user/login/twoFactor", u.LoginTwoFactor)
+	c.POST("/user/login/twoFactor/setup", u.LoginTwoFactorSetup)
+	c.POST("/user/login/changePassword", u.LoginChangePassword)
+	c.POST("/user/token/refresh", u.RefreshToken)
 	c.POST("/user/register", u.Register)
 	c.GET("/user/sysInfo", u.SysInit)
 	c.GET("/user/challenge", u.Challenge)
+	// OIDC 单点登录相关路由
+	c.GET("/user/oidc/config", u.OIDCConfig)
+	c.GET("/user/oidc/authorize", u.OIDCAuthorize)
+	c.GET("/user/oidc/callback", u.OIDCCallback)
+	c.POST("/user/oidc/exchange", u.OIDCExchange)
+	// 邮箱验证、找回密码与注册邀请相关路由
+	c.GET("/user/email/verify", u.VerifyEmail)
+	c.POST("/user/email/resend", u.ResendVerifyEmail)
+	c.POST("/user/password/forgot", u.ForgotPassword)
+	c.POST("/user/password/reset", u.ResetPassword)
+	c.GET("/user/invitation", u.GetInvitation)
 
 	verify := middleware.NewAuthMiddleware(u.cache,
 		u.service.GetRepository().ApiKey(),
 		u.service.GetRepository().User(),
 		u.service.GetRepository().GroupPower(),
-		u.service.GetRepository().Power())
+		u.service.GetRepository().Power(),
+		u.service.GetRepository().UserSession(),
+		u.service.GetRepository().RefreshToken())
 
 	r := c.Group("/user")
 	r.Use(verify.Verify())
@@ -47,11 +66,31 @@ func (u *UserHandler) Router(c *gin.RouterGroup) {
 		r.POST("/updatePassword", middleware.PowerVerify("user:update:password"), u.UpdatePassword)
 		r.POST("/setFilePassword", middleware.PowerVerify("file:update:filePassword"), u.SetFilePassword)
 		r.POST("/updateFilePassword", middleware.PowerVerify("file:update:filePassword"), u.UserUpdateFilePassword)
-		r.GET("/info", u.GetUserInfo)
+		r.GET("/info", middleware.ScopeVerify("user:get"), u.GetUserInfo)
+		r.POST("/email/sendVerify", middleware.ScopeVerify("user:update"), u.SendVerifyEmail)
+		// 登录会话相关路由
+		r.POST("/logout", u.Logout)
+		r.GET("/session/list", middleware.ScopeVerify("user:update"), u.ListSessions)
+		r.POST("/session/revoke", middleware.ScopeVerify("user:update"), u.RevokeSession)
+		r.POST("/session/revokeOthers", middleware.ScopeVerify("user:update"), u.RevokeOtherSessions)
+		// 两步验证相关路由
+		r.GET("/twoFactor/status", middleware.ScopeVerify("user:update"), u.TwoFactorStatus)
+		r.POST("/twoFactor/setup", middleware.ScopeVerify("user:update"), u.SetupTwoFactor)
+		r.POST("/twoFactor/enable", middleware.ScopeVerify("user:update"), u.EnableTwoFactor)
+		r.POST("/twoFactor/disable", middleware.ScopeVerify("user:update"), u.DisableTwoFactor)
+		r.POST("/twoFactor/recoveryCodes", middleware.ScopeVerify("user:update"), u.RegenerateRecoveryCodes)
+		// 单点登录身份绑定相关路由
+		r.POST("/oidc/link", middleware.ScopeVerify("user:update"), u.OIDCLink)
+		r.GET("/oidc/identities", middleware.ScopeVerify("user:update"), u.ListOIDCIdentities)
+		r.POST("/oidc/unlink", middleware.ScopeVerify("user:update"), u.UnlinkOIDCIdentity)
 		// API Key 相关路由
-		r.POST("/apiKey/generate", middleware.PowerVerify("user:update"), u.GenerateApiKey)
+		r.POST("/apiKey/generate", middleware.PowerVerify("user:update"), middleware.DenyRestrictedAPIKey(), u.GenerateApiKey)
 		r.GET("/apiKey/list", middleware.PowerVerify("user:update"), u.ListApiKeys)
 		r.POST("/apiKey/delete", middleware.PowerVerify("user:update"), u.DeleteApiKey)
+		// 应用专用密码相关路由（WebDAV、SFTP 等同步协议使用）
+		r.POST("/appPassword/create", middleware.PowerVerify("user:update"), middleware.DenyRestrictedAPIKey(), u.CreateAppPassword)
+		r.GET("/appPassword/list", middleware.PowerVerify("user:update"), u.ListAppPasswords)
+		r.POST("/appPassword/delete", middleware.PowerVerify("user:update"), u.DeleteAppPassword)
 	}
 	logger.LOG.Info("[路由] 用户路由注册完成✔️")
 }
@@ -63,8 +102,11 @@ func (u *UserHandler) Router(c *gin.RouterGroup) {
 // @Accept json
 // @Produce json
 // @Param request body request.UserLoginRequest true "登录请求"
-// @Success 200 {object} models.JsonResponse{data=string} "token"
+// @Success 200 {object} models.JsonResponse{data=response.UserLoginResponse} "登录成功"
+// @Success 202 {object} models.JsonResponse{data=response.TwoFactorLoginResponse} "需要两步验证，使用票据调用 /user/login/twoFactor"
+// @Success 203 {object} models.JsonResponse{data=response.PasswordExpiredResponse} "密码已过期，使用票据调用 /user/login/changePassword"
 // @Failure 400 {object} models.JsonResponse "参数错误或登录失败"
+// @Failure 429 {object} models.JsonResponse "失败次数过多，账户或 IP 已临时锁定（Retry-After 头为需要等待的秒数）"
 // @Router /user/login [post]
 func (u *UserHandler) Login(c *gin.Context) {
 	req := new(request.UserLoginRequest)
@@ -72,8 +114,11 @@ func (u *UserHandler) Login(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	login, err := u.service.Login(req.Username, req.Password, req.Challenge)
+	login, err := u.service.Login(req.Username, req.Password, req.Challenge, req.RememberMe, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
+		if respondLimited(c, err) {
+			return
+		}
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(400, models.NewJsonResponse(400, "用户不存在", nil))
 			return
@@ -81,11 +126,157 @@ func (u *UserHandler) Login(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
 	}
+	// 需要两步验证时只返回票据，不设置登录 Cookie
+	if data, ok := login.Data.(response.UserLoginResponse); ok {
+		middleware.SetLoginCookies(c, &data)
+	}
+	c.JSON(200, login)
+}
+
+// LoginTwoFactor godoc
+// @Summary 两步验证登录
+// @Description 登录第二步：提交第一步返回的票据与验证码（或恢复码），验证码需使用新的挑战公钥加密；所在组要求开启但尚未绑定时，此步骤同时完成绑定并返回恢复码
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.TwoFactorLoginRequest true "两步验证登录请求"
+// @Success 200 {object} models.JsonResponse{data=response.UserLoginResponse} "登录成功"
+// @Success 203 {object} models.JsonResponse{data=response.PasswordExpiredResponse} "密码已过期，使用票据调用 /user/login/changePassword"
+// @Failure 400 {object} models.JsonResponse "参数错误、验证码错误或票据已过期"
+// @Failure 429 {object} models.JsonResponse "失败次数过多，账户已临时锁定"
+// @Router /user/login/twoFactor [post]
+func (u *UserHandler) LoginTwoFactor(c *gin.Context) {
+	req := new(request.TwoFactorLoginRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	login, err := u.service.LoginTwoFactor(req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if respondLimited(c, err) {
+			return
+		}
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	// 密码已过期时只返回改密票据，不设置登录 Cookie
+	if data, ok := login.Data.(response.UserLoginResponse); ok {
+		middleware.SetLoginCookies(c, &data)
+	}
+	c.JSON(200, login)
+}
+
+// LoginChangePassword godoc
+// @Summary 修改过期密码并登录
+// @Description 登录密码超过有效期时，使用登录返回的改密票据提交新密码（使用新的挑战公钥加密），新密码需符合密码策略，修改成功后直接完成登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.ExpiredPasswordChangeRequest true "改密请求"
+// @Success 200 {object} models.JsonResponse{data=response.UserLoginResponse} "登录成功"
+// @Failure 400 {object} models.JsonResponse "参数错误、密码不符合策略或票据已过期"
+// @Router /user/login/changePassword [post]
+func (u *UserHandler) LoginChangePassword(c *gin.Context) {
+	req := new(request.ExpiredPasswordChangeRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	login, err := u.service.LoginChangePassword(req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
 	data := login.Data.(response.UserLoginResponse)
-	c.SetCookie("Authorization", data.Token, 7*24*3600, "/", auth.GetCookieDomain(c.Request.Host), false, true)
+	middleware.SetLoginCookies(c, &data)
 	c.JSON(200, login)
 }
 
+// RefreshToken godoc
+// @Summary 刷新访问令牌
+// @Description 访问令牌过期后使用刷新令牌换取新的访问令牌与刷新令牌，旧刷新令牌随即失效；已轮换的刷新令牌再次使用时整个会话被注销。请求体为空时使用 RefreshToken Cookie
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.RefreshTokenRequest false "刷新令牌"
+// @Success 200 {object} models.JsonResponse{data=response.UserLoginResponse} "刷新成功"
+// @Failure 400 {object} models.JsonResponse "参数错误"
+// @Failure 401 {object} models.JsonResponse "刷新令牌无效、已使用或会话已过期，需要重新登录"
+// @Router /user/token/refresh [post]
+func (u *UserHandler) RefreshToken(c *gin.Context) {
+	req := new(request.RefreshTokenRequest)
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(req); err != nil {
+			c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		if cookie, err := c.Request.Cookie(middleware.RefreshTokenCookie); err == nil {
+			req.RefreshToken = cookie.Value
+		}
+	}
+	if req.RefreshToken == "" {
+		c.JSON(200, models.NewJsonResponse(401, "未授权:缺少刷新令牌", nil))
+		return
+	}
+	result, err := u.service.RefreshToken(req.RefreshToken, c.ClientIP())
+	if err != nil {
+		// 并发刷新时先完成的请求已写入新 Cookie，不能清除
+		if !errors.Is(err, auth.ErrRefreshTokenRotated) {
+			middleware.ClearLoginCookies(c)
+		}
+		c.JSON(200, models.NewJsonResponse(401, "未授权:"+err.Error(), nil))
+		return
+	}
+	data := result.Data.(response.UserLoginResponse)
+	middleware.SetLoginCookies(c, &data)
+	c.JSON(200, result)
+}
+
+// Logout godoc
+// @Summary 退出登录
+// @Description 注销当前登录会话（访问令牌与刷新令牌同时失效）并清除登录 Cookie
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse "已退出登录"
+// @Failure 400 {object} models.JsonResponse "退出失败"
+// @Router /user/logout [post]
+func (u *UserHandler) Logout(c *gin.Context) {
+	result, err := u.service.Logout(c.GetString("userID"), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	middleware.ClearLoginCookies(c)
+	c.JSON(200, result)
+}
+
+// LoginTwoFactorSetup godoc
+// @Summary 登录时绑定两步验证
+// @Description 所在组要求开启两步验证但尚未绑定时，使用第一步登录返回的票据获取 TOTP 密钥与 otpauth:// 配置地址
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.TwoFactorTicketRequest true "票据"
+// @Success 200 {object} models.JsonResponse{data=object} "密钥与配置地址"
+// @Failure 400 {object} models.JsonResponse "参数错误或票据已过期"
+// @Router /user/login/twoFactor/setup [post]
+func (u *UserHandler) LoginTwoFactorSetup(c *gin.Context) {
+	req := new(request.TwoFactorTicketRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.LoginTwoFactorSetup(req)
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
 // Register godoc
 // @Summary 用户注册
 // @Description 注册新用户账号
@@ -102,7 +293,7 @@ func (u *UserHandler) Register(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	register, err := u.service.Register(req)
+	register, err := u.service.Register(req, middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -191,13 +382,14 @@ func (u *UserHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 	req.ID = c.GetString("userID")
-	update, err := u.service.UpdatePassword(req)
+	update, err := u.service.UpdatePassword(req, middleware.AuditActor(c))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(400, models.NewJsonResponse(400, "用户不存在", nil))
 			return
 		}
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
 	}
 	c.JSON(200, update)
 }
@@ -209,23 +401,25 @@ func (u *UserHandler) UpdatePassword(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param request body request.UserUpdatePasswordRequest true "设置文件密码请求"
+// @Param request body request.UserSetFilePasswordRequest true "设置文件密码请求"
 // @Success 200 {object} models.JsonResponse "设置成功"
 // @Failure 400 {object} models.JsonResponse "参数错误或设置失败"
 // @Router /user/setFilePassword [post]
 func (u *UserHandler) SetFilePassword(c *gin.Context) {
-	req := new(request.UserUpdatePasswordRequest)
+	req := new(request.UserSetFilePasswordRequest)
 	if err := c.ShouldBindJSON(req); err != nil {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
 	req.ID = c.GetString("userID")
-	update, err := u.service.UpdatePassword(req)
+	update, err := u.service.SetFilePassword(req)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(400, models.NewJsonResponse(400, "用户不存在", nil))
+			return
 		}
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
 	}
 	c.JSON(200, update)
 }
@@ -252,15 +446,17 @@ func (u *UserHandler) UserUpdateFilePassword(c *gin.Context) {
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(400, models.NewJsonResponse(400, "用户不存在", nil))
+			return
 		}
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
 	}
 	c.JSON(200, update)
 }
 
 // GenerateApiKey godoc
 // @Summary 生成API Key
-// @Description 为用户生成新的API Key，用于API调用认证
+// @Description 为用户生成新的API Key，用于API调用认证，可限制权限范围、访问目录与来源IP
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -275,7 +471,7 @@ func (u *UserHandler) GenerateApiKey(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	result, err := u.service.GenerateApiKey(req, c.GetString("userID"))
+	result, err := u.service.GenerateApiKey(req, c.GetString("userID"), middleware.AuditActor(c))
 	if err != nil {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -320,7 +516,387 @@ func (u *UserHandler) DeleteApiKey(c *gin.Context) {
 		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
 		return
 	}
-	result, err := u.service.DeleteApiKey(req, c.GetString("userID"))
+	result, err := u.service.DeleteApiKey(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListSessions godoc
+// @Summary 获取登录会话列表
+// @Description 获取当前用户所有未过期的登录会话（设备、IP、User-Agent、登录时间、最近访问时间），current 标记当前会话
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=[]response.UserSessionInfo} "获取成功"
+// @Failure 400 {object} models.JsonResponse "获取失败"
+// @Router /user/session/list [get]
+func (u *UserHandler) ListSessions(c *gin.Context) {
+	result, err := u.service.ListSessions(c.GetString("userID"), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// RevokeSession godoc
+// @Summary 注销登录会话
+// @Description 注销当前用户的指定登录会话，该会话下一次请求将要求重新登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.RevokeSessionRequest true "注销会话请求"
+// @Success 200 {object} models.JsonResponse "注销成功"
+// @Failure 400 {object} models.JsonResponse "参数错误或注销失败"
+// @Failure 404 {object} models.JsonResponse "会话不存在"
+// @Router /user/session/revoke [post]
+func (u *UserHandler) RevokeSession(c *gin.Context) {
+	req := new(request.RevokeSessionRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.RevokeSession(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// RevokeOtherSessions godoc
+// @Summary 注销其他登录会话
+// @Description 注销当前用户除当前会话外的所有登录会话（使用 API Key 调用时注销全部会话）
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=object} "注销成功，返回注销数量"
+// @Failure 400 {object} models.JsonResponse "注销失败"
+// @Router /user/session/revokeOthers [post]
+func (u *UserHandler) RevokeOtherSessions(c *gin.Context) {
+	result, err := u.service.RevokeOtherSessions(c.GetString("userID"), c.GetString("sessionID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// TwoFactorStatus godoc
+// @Summary 获取两步验证状态
+// @Description 获取当前用户是否开启两步验证、所在组是否要求开启以及剩余恢复码数量
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=object} "获取成功"
+// @Failure 400 {object} models.JsonResponse "获取失败"
+// @Router /user/twoFactor/status [get]
+func (u *UserHandler) TwoFactorStatus(c *gin.Context) {
+	result, err := u.service.GetTwoFactorStatus(c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// SetupTwoFactor godoc
+// @Summary 获取两步验证密钥
+// @Description 生成 TOTP 密钥，返回密钥与 otpauth:// 配置地址（可渲染为二维码供验证器 App 扫描），需调用启用接口确认后生效
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=object} "密钥与配置地址"
+// @Failure 400 {object} models.JsonResponse "已开启两步验证或生成失败"
+// @Router /user/twoFactor/setup [post]
+func (u *UserHandler) SetupTwoFactor(c *gin.Context) {
+	result, err := u.service.SetupTwoFactor(c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// EnableTwoFactor godoc
+// @Summary 启用两步验证
+// @Description 校验验证器 App 中的验证码后启用两步验证，返回一次性恢复码（仅返回一次）
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} models.JsonResponse{data=object} "启用成功，返回恢复码"
+// @Failure 400 {object} models.JsonResponse "参数错误或验证码错误"
+// @Router /user/twoFactor/enable [post]
+func (u *UserHandler) EnableTwoFactor(c *gin.Context) {
+	req := new(request.TwoFactorCodeRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.EnableTwoFactor(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// DisableTwoFactor godoc
+// @Summary 关闭两步验证
+// @Description 校验验证码或恢复码后关闭两步验证，所在组要求开启时不允许关闭
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} models.JsonResponse "关闭成功"
+// @Failure 400 {object} models.JsonResponse "参数错误、验证码错误或不允许关闭"
+// @Router /user/twoFactor/disable [post]
+func (u *UserHandler) DisableTwoFactor(c *gin.Context) {
+	req := new(request.TwoFactorCodeRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.DisableTwoFactor(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Description 校验验证码后重新生成一次性恢复码，原有恢复码全部作废
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} models.JsonResponse{data=object} "生成成功，返回恢复码"
+// @Failure 400 {object} models.JsonResponse "参数错误或验证码错误"
+// @Router /user/twoFactor/recoveryCodes [post]
+func (u *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	req := new(request.TwoFactorCodeRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.RegenerateRecoveryCodes(req, c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// CreateAppPassword godoc
+// @Summary 创建应用专用密码
+// @Description 为 WebDAV、SFTP 等同步客户端创建独立密码，可限制为只读或指定目录，不能用于网页登录
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.CreateAppPasswordRequest true "创建应用专用密码请求"
+// @Success 200 {object} models.JsonResponse{data=object} "创建成功，返回密码明文（仅返回一次）"
+// @Failure 400 {object} models.JsonResponse "参数错误或创建失败"
+// @Router /user/appPassword/create [post]
+func (u *UserHandler) CreateAppPassword(c *gin.Context) {
+	req := new(request.CreateAppPasswordRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.CreateAppPassword(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListAppPasswords godoc
+// @Summary 获取应用专用密码列表
+// @Description 获取当前用户的应用专用密码列表（密码已掩码），包含最近使用时间和 IP
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=[]object} "获取成功"
+// @Failure 400 {object} models.JsonResponse "获取失败"
+// @Router /user/appPassword/list [get]
+func (u *UserHandler) ListAppPasswords(c *gin.Context) {
+	result, err := u.service.ListAppPasswords(c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// DeleteAppPassword godoc
+// @Summary 删除应用专用密码
+// @Description 吊销指定的应用专用密码，使用该密码的客户端将无法继续访问
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.DeleteAppPasswordRequest true "删除应用专用密码请求"
+// @Success 200 {object} models.JsonResponse "删除成功"
+// @Failure 400 {object} models.JsonResponse "参数错误或删除失败"
+// @Failure 403 {object} models.JsonResponse "无权操作"
+// @Failure 404 {object} models.JsonResponse "应用专用密码不存在"
+// @Router /user/appPassword/delete [post]
+func (u *UserHandler) DeleteAppPassword(c *gin.Context) {
+	req := new(request.DeleteAppPasswordRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.DeleteAppPassword(req, c.GetString("userID"), middleware.AuditActor(c))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// OIDCConfig godoc
+// @Summary 获取单点登录配置
+// @Description 获取是否启用 OIDC 单点登录及按钮显示名称，登录页据此显示单点登录入口
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} models.JsonResponse{data=object} "获取成功"
+// @Router /user/oidc/config [get]
+func (u *UserHandler) OIDCConfig(c *gin.Context) {
+	result, err := u.service.OIDCConfig()
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// OIDCAuthorize godoc
+// @Summary 发起单点登录
+// @Description 生成跳转到身份提供方的授权地址（授权码模式 + PKCE），前端跳转到返回的 url
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} models.JsonResponse{data=object} "授权地址"
+// @Failure 400 {object} models.JsonResponse "未启用单点登录或身份提供方不可用"
+// @Router /user/oidc/authorize [get]
+func (u *UserHandler) OIDCAuthorize(c *gin.Context) {
+	result, err := u.service.OIDCAuthorize("")
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// OIDCCallback godoc
+// @Summary 单点登录回调
+// @Description 身份提供方授权后回调此地址，校验 ID Token 后跳转回前端：登录成功附带一次性登录码 oidc_code，绑定成功附带 oidc_linked=1，失败附带 oidc_error
+// @Tags 用户管理
+// @Param code query string false "授权码"
+// @Param state query string true "状态"
+// @Param error query string false "身份提供方返回的错误"
+// @Success 302 "跳转回前端"
+// @Router /user/oidc/callback [get]
+func (u *UserHandler) OIDCCallback(c *gin.Context) {
+	target := u.service.OIDCCallback(c.Query("code"), c.Query("state"), c.Query("error"))
+	c.Redirect(302, target)
+}
+
+// OIDCExchange godoc
+// @Summary 单点登录换取令牌
+// @Description 使用回调跳转时附带的一次性登录码换取登录令牌，登录码 60 秒内有效且只能使用一次；开启两步验证时返回票据，与密码登录一致
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.OIDCExchangeRequest true "一次性登录码"
+// @Success 200 {object} models.JsonResponse{data=response.UserLoginResponse} "登录成功"
+// @Success 202 {object} models.JsonResponse{data=response.TwoFactorLoginResponse} "需要两步验证，使用票据调用 /user/login/twoFactor"
+// @Failure 400 {object} models.JsonResponse "参数错误或登录码已过期"
+// @Router /user/oidc/exchange [post]
+func (u *UserHandler) OIDCExchange(c *gin.Context) {
+	req := new(request.OIDCExchangeRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	login, err := u.service.OIDCExchange(req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	if data, ok := login.Data.(response.UserLoginResponse); ok {
+		middleware.SetLoginCookies(c, &data)
+	}
+	c.JSON(200, login)
+}
+
+// OIDCLink godoc
+// @Summary 绑定单点登录身份
+// @Description 生成跳转到身份提供方的授权地址，授权完成后将该身份绑定到当前账户
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=object} "授权地址"
+// @Failure 400 {object} models.JsonResponse "未启用单点登录或身份提供方不可用"
+// @Router /user/oidc/link [post]
+func (u *UserHandler) OIDCLink(c *gin.Context) {
+	result, err := u.service.OIDCAuthorize(c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListOIDCIdentities godoc
+// @Summary 获取单点登录绑定列表
+// @Description 获取当前账户绑定的单点登录身份
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse{data=[]models.UserIdentity} "获取成功"
+// @Failure 400 {object} models.JsonResponse "获取失败"
+// @Router /user/oidc/identities [get]
+func (u *UserHandler) ListOIDCIdentities(c *gin.Context) {
+	result, err := u.service.ListOIDCIdentities(c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// UnlinkOIDCIdentity godoc
+// @Summary 解除单点登录绑定
+// @Description 解除当前账户绑定的单点登录身份，单点登录创建的账户不能解除最后一个绑定
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body request.OIDCUnlinkRequest true "解除绑定请求"
+// @Success 200 {object} models.JsonResponse "解除成功"
+// @Failure 400 {object} models.JsonResponse "参数错误或解除失败"
+// @Failure 404 {object} models.JsonResponse "绑定不存在"
+// @Router /user/oidc/unlink [post]
+func (u *UserHandler) UnlinkOIDCIdentity(c *gin.Context) {
+	req := new(request.OIDCUnlinkRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.UnlinkOIDCIdentity(req, c.GetString("userID"))
 	if err != nil {
 		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
 		return
@@ -348,3 +924,14 @@ func (u *UserHandler) GetUserInfo(c *gin.Context) {
 	c.JSON(200, result)
 	return
 }
+
+// respondLimited 失败次数过多时返回 429 与 Retry-After 头，返回是否已响应
+func respondLimited(c *gin.Context, err error) bool {
+	var limitErr *auth.LimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int((limitErr.RetryAfter+time.Second-1)/time.Second)))
+	c.JSON(429, models.NewJsonResponse(429, limitErr.Error(), nil))
+	return true
+}
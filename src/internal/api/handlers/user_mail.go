@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"myobj/src/core/domain/request"
+	"myobj/src/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmail godoc
+// @Summary 验证邮箱
+// @Description 验证邮件中的链接，校验后跳转回站点首页：成功附带 email_verified=1，失败附带 email_verify_error。注册时要求验证邮箱的账户验证后即可登录
+// @Tags 用户管理
+// @Param token query string true "邮件中的验证令牌"
+// @Success 302 "跳转回站点首页"
+// @Router /user/email/verify [get]
+func (u *UserHandler) VerifyEmail(c *gin.Context) {
+	c.Redirect(302, u.service.VerifyEmail(c.Query("token")))
+}
+
+// ResendVerifyEmail godoc
+// @Summary 重发验证邮件
+// @Description 注册后邮箱待验证的账户重新获取验证邮件（无需登录），同一账户 60 秒内只发送一次；无论账户是否存在都返回相同结果
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.MailAccountRequest true "用户名或邮箱"
+// @Success 200 {object} models.JsonResponse "已受理"
+// @Failure 400 {object} models.JsonResponse "参数错误或邮件功能未启用"
+// @Router /user/email/resend [post]
+func (u *UserHandler) ResendVerifyEmail(c *gin.Context) {
+	req := new(request.MailAccountRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.ResendVerifyEmail(req)
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// SendVerifyEmail godoc
+// @Summary 发送邮箱验证邮件
+// @Description 向当前用户的邮箱发送验证邮件，60 秒内只能发送一次
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.JsonResponse "验证邮件已发送"
+// @Failure 400 {object} models.JsonResponse "未设置邮箱、邮箱已验证或发送失败"
+// @Router /user/email/sendVerify [post]
+func (u *UserHandler) SendVerifyEmail(c *gin.Context) {
+	result, err := u.service.SendVerifyEmail(c.GetString("userID"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ForgotPassword godoc
+// @Summary 找回密码
+// @Description 向账户绑定的邮箱发送重置密码链接（仅本地账户），account 为用户名或邮箱；无论账户是否存在都返回相同结果
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.MailAccountRequest true "用户名或邮箱"
+// @Success 200 {object} models.JsonResponse "已受理"
+// @Failure 400 {object} models.JsonResponse "参数错误或邮件功能未启用"
+// @Router /user/password/forgot [post]
+func (u *UserHandler) ForgotPassword(c *gin.Context) {
+	req := new(request.MailAccountRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.ForgotPassword(req, c.ClientIP())
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ResetPassword godoc
+// @Summary 重置密码
+// @Description 使用重置邮件中的 reset_token 设置新密码（新密码使用挑战加密），链接只能使用一次；成功后该用户的全部登录会话失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body request.ResetPasswordRequest true "重置令牌与新密码"
+// @Success 200 {object} models.JsonResponse "密码已重置"
+// @Failure 400 {object} models.JsonResponse "链接无效、已过期或密码不符合策略"
+// @Router /user/password/reset [post]
+func (u *UserHandler) ResetPassword(c *gin.Context) {
+	req := new(request.ResetPasswordRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(400, models.NewJsonResponse(400, "参数错误", nil))
+		return
+	}
+	result, err := u.service.ResetPassword(req, c.ClientIP())
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// GetInvitation godoc
+// @Summary 查询注册邀请
+// @Description 注册页根据邀请链接中的 invite_token 获取受邀邮箱与用户组，注册时提交 invite_token 即可不受注册开关限制
+// @Tags 用户管理
+// @Produce json
+// @Param token query string true "邀请令牌"
+// @Success 200 {object} models.JsonResponse{data=response.InvitationInfoResponse} "邀请信息"
+// @Failure 400 {object} models.JsonResponse "邀请无效、已使用或已过期"
+// @Router /user/invitation [get]
+func (u *UserHandler) GetInvitation(c *gin.Context) {
+	result, err := u.service.GetInvitation(c.Query("token"))
+	if err != nil {
+		c.JSON(400, models.NewJsonResponse(400, err.Error(), nil))
+		return
+	}
+	c.JSON(200, result)
+}
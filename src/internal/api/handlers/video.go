@@ -35,7 +35,9 @@ func (v *VideoHandler) Router(c *gin.RouterGroup) {
 		v.fileService.GetRepository().ApiKey(),
 		v.fileService.GetRepository().User(),
 		v.fileService.GetRepository().GroupPower(),
-		v.fileService.GetRepository().Power())
+		v.fileService.GetRepository().Power(),
+		v.fileService.GetRepository().UserSession(),
+		v.fileService.GetRepository().RefreshToken())
 
 	videoGroup := c.Group("/video")
 	{
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"myobj/src/core/domain/response"
+	"myobj/src/pkg/auth"
 	"myobj/src/pkg/models"
 
 	"github.com/gin-gonic/gin"
@@ -25,6 +26,13 @@ func AdminVerify() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+
+		// 限制了权限或目录的 API Key 不能访问管理接口
+		if scope, ok := c.Get("apiKeyScope"); ok && scope.(*auth.APIKeyScope).Restricted() {
+			c.JSON(403, models.NewJsonResponse(403, "受限的API Key不能访问管理接口", nil))
+			c.Abort()
+			return
+		}
 		
 		c.Next()
 	}
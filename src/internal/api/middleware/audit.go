@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditActor 从请求上下文构造审计日志的操作人（未登录时只包含 IP）
+func AuditActor(c *gin.Context) audit.Actor {
+	actor := audit.Actor{
+		UserID: c.GetString("userID"),
+		IP:     c.ClientIP(),
+		Source: audit.SourceWeb,
+	}
+	if value, ok := c.Get("userLogin"); ok {
+		if userLogin, ok := value.(response.UserLoginResponse); ok && userLogin.User != nil {
+			actor.UserName = userLogin.User.UserName
+		}
+	}
+	if value, ok := c.Get("apiKeyScope"); ok {
+		if scope, ok := value.(*auth.APIKeyScope); ok && scope != nil {
+			actor.APIKeyID = scope.KeyID
+			actor.Source = audit.SourceAPIKey
+		}
+	}
+	return actor
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"myobj/src/config"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AccessTokenCookie 访问令牌 Cookie
+	AccessTokenCookie = "Authorization"
+	// RefreshTokenCookie 刷新令牌 Cookie
+	RefreshTokenCookie = "RefreshToken"
+)
+
+// SetLoginCookies 写入登录 Cookie（访问令牌与刷新令牌）
+// "记住我"的会话使用持久 Cookie，有效期与会话一致；否则为浏览器会话 Cookie，关闭浏览器即失效
+func SetLoginCookies(c *gin.Context, login *response.UserLoginResponse) {
+	maxAge := 0
+	if login.RememberMe {
+		maxAge = login.SessionExpiresIn
+	}
+	setLoginCookie(c, AccessTokenCookie, login.Token, maxAge)
+	if login.RefreshToken != "" {
+		setLoginCookie(c, RefreshTokenCookie, login.RefreshToken, maxAge)
+	}
+}
+
+// ClearLoginCookies 清除登录 Cookie
+func ClearLoginCookies(c *gin.Context) {
+	setLoginCookie(c, AccessTokenCookie, "", -1)
+	setLoginCookie(c, RefreshTokenCookie, "", -1)
+}
+
+func setLoginCookie(c *gin.Context, name, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, "/", auth.GetCookieDomain(c.Request.Host), config.CONFIG.Auth.CookieSecure, true)
+}
@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"myobj/src/core/domain/response"
+	"myobj/src/pkg/auth"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +18,12 @@ func PowerVerify(power string) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// 使用 API Key 认证时，还需要 API Key 授予了该权限
+		if scope, ok := c.Get("apiKeyScope"); ok && !scope.(*auth.APIKeyScope).Allows(power) {
+			c.JSON(403, models.NewJsonResponse(403, "API Key无此权限", nil))
+			c.Abort()
+			return
+		}
 		loginInfo := userLogin.(response.UserLoginResponse)
 		for _, p := range loginInfo.Power {
 			if p.Characteristic == power {
@@ -28,3 +36,64 @@ func PowerVerify(power string) gin.HandlerFunc {
 		return
 	}
 }
+
+// ScopeVerify API Key 权限验证中间件
+// 用于业务逻辑已校验所有权、不要求用户组权限的接口：JWT 登录直接通过，API Key 需要授予了该权限
+func ScopeVerify(power string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scope, ok := c.Get("apiKeyScope"); ok && !scope.(*auth.APIKeyScope).Allows(power) {
+			c.JSON(403, models.NewJsonResponse(403, "API Key无此权限", nil))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// DenyRestrictedAPIKey 禁止受限的 API Key 调用（如创建新的 API Key、应用专用密码，防止借此扩大权限）
+func DenyRestrictedAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scope, ok := c.Get("apiKeyScope"); ok && scope.(*auth.APIKeyScope).Restricted() {
+			c.JSON(403, models.NewJsonResponse(403, "受限的API Key不能调用此接口", nil))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKeyRootDir 使用 API Key 认证时返回其可访问的目录ID（0 表示不限制）
+func APIKeyRootDir(c *gin.Context) int {
+	if scope, ok := c.Get("apiKeyScope"); ok {
+		return scope.(*auth.APIKeyScope).RootDirID
+	}
+	return 0
+}
+
+// DirScopeAllowed 检查目录（以字符串保存的目录ID）是否位于 API Key 可访问的目录内
+// JWT 登录或未限制目录时直接通过；不允许时写入 403 响应并返回 false
+func DirScopeAllowed(c *gin.Context, vpRepo repository.VirtualPathRepository, dirID string) bool {
+	scope, ok := c.Get("apiKeyScope")
+	if !ok {
+		return true
+	}
+	if err := scope.(*auth.APIKeyScope).CheckDirPath(c.Request.Context(), vpRepo, dirID); err != nil {
+		c.JSON(403, models.NewJsonResponse(403, err.Error(), nil))
+		return false
+	}
+	return true
+}
+
+// FileScopeAllowed 检查用户文件是否位于 API Key 可访问的目录内
+// 限制了目录的 API Key 只能访问自己的文件（不包括他人的公开文件）；不允许时写入 403 响应并返回 false
+func FileScopeAllowed(c *gin.Context, ufRepo repository.UserFilesRepository, vpRepo repository.VirtualPathRepository, ufID string) bool {
+	if APIKeyRootDir(c) == 0 {
+		return true
+	}
+	userFile, err := ufRepo.GetByUfID(c.Request.Context(), ufID)
+	if err != nil || userFile.UserID != c.GetString("userID") {
+		c.JSON(403, models.NewJsonResponse(403, auth.ErrAPIKeyOutOfScope.Error(), nil))
+		return false
+	}
+	return DirScopeAllowed(c, vpRepo, userFile.VirtualPath)
+}
@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"math"
 	"myobj/src/config"
 	"myobj/src/core/domain/response"
 	"myobj/src/pkg/auth"
 	"myobj/src/pkg/cache"
+	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/repository"
 	"myobj/src/pkg/util"
@@ -27,6 +29,8 @@ type AuthMiddleware struct {
 	userRepo       repository.UserRepository
 	groupPowerRepo repository.GroupPowerRepository
 	powerRepo      repository.PowerRepository
+	sessions       *auth.SessionManager
+	tokens         *auth.TokenIssuer
 }
 
 // NewAuthMiddleware 创建认证中间件
@@ -36,6 +40,8 @@ func NewAuthMiddleware(
 	userRepo repository.UserRepository,
 	groupPowerRepo repository.GroupPowerRepository,
 	powerRepo repository.PowerRepository,
+	sessionRepo repository.UserSessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
 ) *AuthMiddleware {
 	return &AuthMiddleware{
 		cache:          cache,
@@ -43,29 +49,58 @@ func NewAuthMiddleware(
 		userRepo:       userRepo,
 		groupPowerRepo: groupPowerRepo,
 		powerRepo:      powerRepo,
+		sessions:       auth.NewSessionManager(sessionRepo, refreshRepo),
+		tokens:         auth.NewTokenIssuer(cache, sessionRepo, refreshRepo, userRepo, powerRepo),
 	}
 }
 
+// 登录凭据来源
+const (
+	credentialNone   = ""
+	credentialHeader = "header"
+	credentialCookie = "cookie"
+)
+
+// authenticate 依次尝试 Authorization 请求头、访问令牌 Cookie、刷新令牌 Cookie 进行认证
+// 请求头令牌失效时仍会尝试 Cookie：浏览器端访问令牌过期后由刷新令牌 Cookie 透明续期并写入新的 Cookie；
+// 返回最后尝试的凭据来源，未携带任何登录凭据时返回 credentialNone
+func (m *AuthMiddleware) authenticate(c *gin.Context) (string, error) {
+	source := credentialNone
+	var err error
+	// 1. 尝试从Authorization头获取Token
+	if authorization := c.Request.Header.Get("Authorization"); authorization != "" {
+		source = credentialHeader
+		if err = m.handleJWTAuth(c, authorization); err == nil {
+			return source, nil
+		}
+	}
+	// 2. 尝试从Cookie中获取访问令牌
+	if cookie, cerr := c.Request.Cookie(AccessTokenCookie); cerr == nil && cookie.Value != "" {
+		source = credentialCookie
+		if err = m.handleJWTAuth(c, "Bearer "+cookie.Value); err == nil {
+			return source, nil
+		}
+	}
+	// 3. 访问令牌过期时使用刷新令牌续期
+	if cookie, cerr := c.Request.Cookie(RefreshTokenCookie); cerr == nil && cookie.Value != "" {
+		source = credentialCookie
+		if err = m.handleRefreshAuth(c, cookie.Value); err == nil {
+			return source, nil
+		}
+	}
+	if source == credentialCookie {
+		ClearLoginCookies(c)
+	}
+	return source, err
+}
+
 // Verify 认证验证中间件
 func (m *AuthMiddleware) Verify() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. 尝试从Authorization头获取JWT Token
-		authorization := c.Request.Header.Get("Authorization")
-		if authorization != "" {
-			// JWT认证流程
-			if err := m.handleJWTAuth(c, authorization); err != nil {
-				c.JSON(200, models.NewJsonResponse(401, err.Error(), nil))
-				c.Abort()
-				return
-			}
-			c.Next()
-			return
-		}
-		// 2. 尝试从Cookie中获取JWT
-		cookie, err := c.Request.Cookie("Authorization")
-		if err == nil && cookie.Value != "" {
-			// JWT认证流程
-			if err := m.handleJWTAuth(c, "Bearer "+cookie.Value); err != nil {
+		// 1-2. 登录令牌认证（请求头、Cookie）
+		source, err := m.authenticate(c)
+		if source != credentialNone {
+			if err != nil {
 				c.JSON(200, models.NewJsonResponse(401, err.Error(), nil))
 				c.Abort()
 				return
@@ -95,28 +130,17 @@ func (m *AuthMiddleware) Verify() gin.HandlerFunc {
 // VerifyOptional 可选认证验证中间件（允许未登录用户访问，但会尝试认证）
 func (m *AuthMiddleware) VerifyOptional() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. 尝试从Authorization头获取JWT Token
-		authorization := c.Request.Header.Get("Authorization")
-
-		if authorization != "" {
-			// JWT认证流程（如果失败，不阻止请求，只是不设置用户信息）
-			if err := m.handleJWTAuth(c, authorization); err == nil {
-				c.Next()
-				return
-			}
-		}
-		// 2. 尝试从Cookie中获取JWT
-		cookie, err := c.Request.Cookie("Authorization")
-		if err == nil && cookie.Value != "" {
-			// JWT认证流程
-			if err := m.handleJWTAuth(c, "Bearer "+cookie.Value); err != nil {
-				c.JSON(200, models.NewJsonResponse(401, err.Error(), nil))
-				c.Abort()
-				return
-			}
+		// 1-2. 登录令牌认证（请求头令牌失败时不阻止请求，Cookie 失效时已清除 Cookie 并返回 401）
+		source, err := m.authenticate(c)
+		if err == nil && source != credentialNone {
 			c.Next()
 			return
 		}
+		if source == credentialCookie {
+			c.JSON(200, models.NewJsonResponse(401, err.Error(), nil))
+			c.Abort()
+			return
+		}
 
 		// 3. 如果没有JWT,检查是否启用了API Key
 		if config.CONFIG.Auth.ApiKey {
@@ -160,19 +184,14 @@ func (m *AuthMiddleware) handleJWTAuth(c *gin.Context, authorization string) err
 		return fmt.Errorf("未授权:Token已过期,请重新登录")
 	}
 
-	// 检查JWT剩余时间,如果不足5分钟则刷新
-	if claims.ExpiresAt != nil {
-		timeRemaining := time.Until(claims.ExpiresAt.Time)
-		if timeRemaining > 0 && timeRemaining < 5*time.Minute {
-			// 重新生成JWT
-			newToken, err := auth.GenerateJWT(claims.UserID, claims.SessionID, claims.UserLogin)
-			if err == nil {
-				// 更新缓存中的token
-				_ = m.cache.Set(token, newToken, 2*60*60)
-				c.SetCookie("Authorization", newToken, 2*60*60, "/", auth.GetCookieDomain(c.Request.Host), false, true)
-			}
-		}
+	// 检查会话是否已被注销（会话登记在数据库中，其他进程注销后这里同样生效）
+	// 刷新令牌后会话只认新的访问令牌，旧令牌随之失效
+	session, err := m.sessions.Validate(context.Background(), token, c.ClientIP())
+	if err != nil {
+		_ = m.cache.Delete(token)
+		return fmt.Errorf("未授权:%v", err)
 	}
+
 	id, err := m.powerRepo.GetByGroupID(context.Background(), claims.UserLogin.User.GroupID)
 	if err != nil {
 		return err
@@ -181,6 +200,23 @@ func (m *AuthMiddleware) handleJWTAuth(c *gin.Context, authorization string) err
 	// 将用户信息放入gin context
 	c.Set("userLogin", claims.UserLogin)
 	c.Set("userID", claims.UserID)
+	c.Set("sessionID", session.ID)
+	return nil
+}
+
+// handleRefreshAuth 使用刷新令牌 Cookie 续期并认证
+// 刷新令牌刚被同一页面的并发请求轮换时，放行本次请求但不写入新 Cookie（新 Cookie 已由先完成的请求下发）
+func (m *AuthMiddleware) handleRefreshAuth(c *gin.Context, refreshToken string) error {
+	res, session, err := m.tokens.Refresh(context.Background(), refreshToken, c.ClientIP())
+	if err != nil && !errors.Is(err, auth.ErrRefreshTokenRotated) {
+		return fmt.Errorf("未授权:%v", err)
+	}
+	if err == nil {
+		SetLoginCookies(c, res)
+	}
+	c.Set("userLogin", *res)
+	c.Set("userID", session.UserID)
+	c.Set("sessionID", session.ID)
 	return nil
 }
 
@@ -227,6 +263,17 @@ func (m *AuthMiddleware) handleAPIKeyAuth(c *gin.Context) error {
 		return fmt.Errorf("未授权:API Key已过期")
 	}
 
+	// 检查IP白名单
+	if !auth.IPAllowed(apiKeyRecord.AllowedIPs, c.ClientIP()) {
+		return fmt.Errorf("未授权:当前IP不允许使用该API Key")
+	}
+
+	// 限制了访问目录的API Key只能调用会校验目录的接口
+	scope := auth.NewAPIKeyScope(apiKeyRecord)
+	if scope.RootDirID != 0 && !dirScopedRoutes[c.FullPath()] {
+		return fmt.Errorf("未授权:该API Key限制了访问目录,不能调用此接口")
+	}
+
 	// 使用私钥解密签名
 	decryptedData, err := util.DecryptToString(apiKeyRecord.PrivateKey, signature)
 	if err != nil {
@@ -278,16 +325,45 @@ func (m *AuthMiddleware) handleAPIKeyAuth(c *gin.Context) error {
 		}
 	}
 
-	// 构造UserLoginResponse
+	// 构造UserLoginResponse（权限为用户组权限与API Key权限的交集）
 	userLoginResp := response.UserLoginResponse{
 		Token: "", // API Key认证不使用JWT Token
 		User:  user,
-		Power: powers,
+		Power: scope.FilterPowers(powers),
+	}
+
+	// 记录调用次数与最近使用信息
+	if err := m.apiKeyRepo.RecordUsage(ctx, apiKeyRecord.ID, c.ClientIP()); err != nil {
+		logger.LOG.Warn("更新API Key使用记录失败", "error", err, "apiKeyID", apiKeyRecord.ID)
 	}
 
 	// 将用户信息放入gin context
 	c.Set("userLogin", userLoginResp)
 	c.Set("userID", user.ID)
+	c.Set("apiKeyScope", scope)
 
 	return nil
 }
+
+// dirScopedRoutes 会校验 API Key 访问目录的接口
+// 限制了访问目录的 API Key 只能调用这些接口，其余接口（搜索、分享等）可能返回目录外的文件
+var dirScopedRoutes = map[string]bool{
+	"/api/file/upload/precheck":        true,
+	"/api/file/upload":                 true,
+	"/api/file/upload/progress":        true,
+	"/api/file/list":                   true,
+	"/api/file/thumbnail/:fileId":      true,
+	"/api/file/image/:fileId":          true,
+	"/api/file/metadata/:fileId":       true,
+	"/api/file/makeDir":                true,
+	"/api/file/move":                   true,
+	"/api/file/delete":                 true,
+	"/api/file/rename":                 true,
+	"/api/file/renameDir":              true,
+	"/api/file/deleteDir":              true,
+	"/api/file/text/read":              true,
+	"/api/file/text/save":              true,
+	"/api/download/local/create":       true,
+	"/api/download/local/file/:taskID": true,
+	"/api/download/convert/options":    true,
+}
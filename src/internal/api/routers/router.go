@@ -10,6 +10,8 @@ import (
 	"myobj/src/internal/repository/impl"
 	"myobj/src/pkg/cache"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
+	"myobj/src/pkg/search"
 	"myobj/src/pkg/task"
 	"os"
 	"time"
@@ -52,7 +54,7 @@ func initRouter(factory *service.ServerFactory, cache cache.Cache) *gin.Engine {
 	logger.LOG.Info("[路由] 正在注册API路由...")
 	// 尝试加载 HTML 模板（如果存在）
 	if _, err := os.Stat("templates"); err == nil {
-		r.LoadHTMLGlob("templates/*")
+		r.LoadHTMLGlob("templates/*.html") // 邮件模板在 templates/mail 下单独加载
 		logger.LOG.Info("[路由] HTML模板已加载")
 	}
 
@@ -115,6 +117,41 @@ func Execute(cacheLocal cache.Cache) {
 	// 启动上传任务定时清理任务（每天清理一次过期任务）
 	uploadTask := task.NewUploadTask(factory)
 	uploadTask.StartScheduledCleanup(24 * time.Hour)
+	// 启动 WebDAV 锁定时清理任务（过期锁不再生效，定期删除残留记录）
+	webdavLockTask := task.NewWebDAVLockTask(factory)
+	webdavLockTask.StartScheduledCleanup(time.Hour)
+	// 启动会话定时清理任务（过期会话不再生效，定期删除残留记录）
+	userSessionTask := task.NewUserSessionTask(factory)
+	userSessionTask.StartScheduledCleanup(time.Hour)
+	// 启动安全事件日志定时清理任务（删除超过保留天数的记录）
+	securityEventTask := task.NewSecurityEventTask(factory)
+	securityEventTask.StartScheduledCleanup(config.CONFIG.Auth.LoginLimit.EventRetentionDays, 24*time.Hour)
+	// 启动审计日志定时清理任务（保留天数为 0 时永久保留）
+	if config.CONFIG.Audit.RetentionDays > 0 {
+		auditLogTask := task.NewAuditLogTask(factory)
+		auditLogTask.StartScheduledCleanup(config.CONFIG.Audit.RetentionDays, 24*time.Hour)
+	}
+	// 启动目录账户定时同步任务（目录中删除或禁用的账户在本地禁用并注销会话）
+	if config.CONFIG.LDAP.Enable && config.CONFIG.LDAP.SyncInterval > 0 {
+		ldapSyncTask := task.NewLDAPSyncTask(factory)
+		ldapSyncTask.StartScheduledSync(time.Duration(config.CONFIG.LDAP.SyncInterval) * time.Minute)
+	}
+	// 启动全文索引：上传、改名、删除等操作通过队列增量更新，定时任务补建缺失的索引
+	if config.CONFIG.Search.Enable {
+		search.Start(factory)
+		searchIndexTask := task.NewSearchIndexTask(factory)
+		searchIndexTask.StartScheduledSync(time.Duration(config.CONFIG.Search.SyncInterval) * time.Minute)
+	}
+	// 启动媒体元数据提取：上传后通过队列提取，定时任务补充提取已有文件
+	metadata.Start(factory)
+	metadataTask := task.NewMetadataTask(factory)
+	metadataTask.StartScheduledSync(time.Hour)
+	// 为升级前上传的文件补齐分类
+	fileCategoryTask := task.NewFileCategoryTask(factory)
+	fileCategoryTask.StartBackfill()
+	// 启动格式转换缓存定时清理任务（删除超过保留天数未使用的转换结果）
+	convertCacheTask := task.NewConvertCacheTask(serverFactory.DownloadService().ConvertCache())
+	convertCacheTask.StartScheduledCleanup(config.CONFIG.Convert.CacheDays, 24*time.Hour)
 	// 初始化路由
 	router := initRouter(serverFactory, cacheLocal)
 
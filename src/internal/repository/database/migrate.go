@@ -0,0 +1,172 @@
+package database
+
+import (
+	"fmt"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// migrateModels 需要自动建表/补齐字段的模型
+// 初始表结构由 sql 目录下的脚本创建，此处只登记后续版本新增的表
+var migrateModels = []interface{}{
+	&models.WebDAVLock{},
+	&models.EncryptPolicy{},
+	&models.WebDAVProperty{},
+	&models.AppPassword{},
+	&models.UserSession{},
+	&models.UserTwoFactor{},
+	&models.UserIdentity{},
+	&models.SecurityEvent{},
+	&models.AuditLog{},
+	&models.PasswordHistory{},
+	&models.RefreshToken{},
+	&models.UserToken{},
+	&models.Invitation{},
+	&models.SearchContent{},
+	&models.SearchDoc{},
+	&models.SearchPosting{},
+	&models.SmartFolder{},
+	&models.FileMetadata{},
+}
+
+// migrateColumn 已有数据表中后续版本新增的字段
+type migrateColumn struct {
+	Model interface{}
+	Field string
+}
+
+// migrateColumns 已有数据表新增的字段列表
+// 已有表由 sql 脚本创建，不使用 AutoMigrate 以免改动原有字段定义，只补齐缺失的字段
+var migrateColumns = []migrateColumn{
+	{&models.Group{}, "Require2FA"},
+	{&models.UserInfo{}, "AuthSource"},
+	{&models.Group{}, "DisableLocalLogin"},
+	{&models.ApiKey{}, "Name"},
+	{&models.ApiKey{}, "Scopes"},
+	{&models.ApiKey{}, "RootDirID"},
+	{&models.ApiKey{}, "AllowedIPs"},
+	{&models.ApiKey{}, "LastUsedAt"},
+	{&models.ApiKey{}, "LastUsedIP"},
+	{&models.ApiKey{}, "UsageCount"},
+	{&models.UserInfo{}, "PasswordChangedAt"},
+	{&models.UserInfo{}, "FilePasswordChangedAt"},
+	{&models.UserSession{}, "AbsoluteExpiresAt"},
+	{&models.UserSession{}, "RememberMe"},
+	{&models.UserInfo{}, "EmailVerifiedAt"},
+	{&models.VirtualPath{}, "DeletedAt"},
+	{&models.Recycled{}, "DirID"},
+	{&models.Recycled{}, "ParentID"},
+	{&models.Recycled{}, "OriginalPath"},
+	{&models.FileInfo{}, "Category"},
+}
+
+// migrateIndex 已有数据表中后续版本新增的索引
+type migrateIndex struct {
+	Model interface{}
+	Name  string
+}
+
+// migrateIndexes 已有数据表新增的索引列表（补齐字段时不会创建索引）
+var migrateIndexes = []migrateIndex{
+	{&models.FileInfo{}, "file_info_category_index"},
+}
+
+// seedPower 后续版本新增的权限
+type seedPower struct {
+	Name           string
+	Description    string
+	Characteristic string
+}
+
+// seedPowers 后续版本新增的权限列表（按 characteristic 幂等写入，并默认授予管理员组）
+var seedPowers = []seedPower{
+	{"SFTP访问", "允许通过SFTP协议访问文件系统", "sftp:access"},
+}
+
+// Migrate 执行数据库迁移
+// 1. 自动创建新增的数据表
+// 2. 补齐已有数据表新增的字段与索引
+// 3. 补齐新增的权限并授予管理员组（group_id=1）
+func Migrate() error {
+	db := GetDB()
+	if db == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	if len(migrateModels) > 0 {
+		if err := db.AutoMigrate(migrateModels...); err != nil {
+			logger.LOG.Error("[数据库] 自动迁移数据表失败", "error", err)
+			return err
+		}
+	}
+
+	for _, c := range migrateColumns {
+		if db.Migrator().HasColumn(c.Model, c.Field) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(c.Model, c.Field); err != nil {
+			logger.LOG.Error("[数据库] 补齐数据表字段失败", "field", c.Field, "error", err)
+			return err
+		}
+		logger.LOG.Info("[数据库] 新增字段", "field", c.Field)
+	}
+
+	for _, idx := range migrateIndexes {
+		if db.Migrator().HasIndex(idx.Model, idx.Name) {
+			continue
+		}
+		if err := db.Migrator().CreateIndex(idx.Model, idx.Name); err != nil {
+			logger.LOG.Error("[数据库] 补齐数据表索引失败", "index", idx.Name, "error", err)
+			return err
+		}
+		logger.LOG.Info("[数据库] 新增索引", "index", idx.Name)
+	}
+
+	if err := seedNewPowers(db); err != nil {
+		logger.LOG.Error("[数据库] 补齐权限数据失败", "error", err)
+		return err
+	}
+
+	logger.LOG.Info("[数据库] 数据库迁移完成 ✓")
+	return nil
+}
+
+// seedNewPowers 写入缺失的权限并授予管理员组
+func seedNewPowers(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var maxID int
+		if err := tx.Model(&models.Power{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+			return err
+		}
+
+		for _, sp := range seedPowers {
+			var count int64
+			if err := tx.Model(&models.Power{}).Where("characteristic = ?", sp.Characteristic).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			maxID++
+			power := &models.Power{
+				ID:             maxID,
+				Name:           sp.Name,
+				Description:    sp.Description,
+				Characteristic: sp.Characteristic,
+				CreatedAt:      custom_type.Now(),
+			}
+			if err := tx.Create(power).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.GroupPower{GroupID: 1, PowerID: power.ID}).Error; err != nil {
+				return err
+			}
+			logger.LOG.Info("[数据库] 新增权限", "name", sp.Name, "characteristic", sp.Characteristic, "id", power.ID)
+		}
+		return nil
+	})
+}
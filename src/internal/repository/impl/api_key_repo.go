@@ -2,6 +2,7 @@ package impl
 
 import (
 	"context"
+	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/repository"
 
@@ -66,3 +67,13 @@ func (r *apiKeyRepository) Count(ctx context.Context, userID string) (int64, err
 	err := query.Count(&count).Error
 	return count, err
 }
+
+// RecordUsage 累加调用次数并更新最近使用时间和 IP
+func (r *apiKeyRepository) RecordUsage(ctx context.Context, id int, ip string) error {
+	return r.db.WithContext(ctx).Model(&models.ApiKey{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"usage_count":  gorm.Expr("usage_count + ?", 1),
+			"last_used_at": custom_type.Now(),
+			"last_used_ip": ip,
+		}).Error
+}
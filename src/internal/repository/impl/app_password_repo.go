@@ -0,0 +1,65 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type appPasswordRepository struct {
+	db *gorm.DB
+}
+
+// NewAppPasswordRepository 创建应用专用密码仓储实例
+func NewAppPasswordRepository(db *gorm.DB) repository.AppPasswordRepository {
+	return &appPasswordRepository{db: db}
+}
+
+// Create 创建应用专用密码
+func (r *appPasswordRepository) Create(ctx context.Context, appPassword *models.AppPassword) error {
+	return r.db.WithContext(ctx).Create(appPassword).Error
+}
+
+// GetByID 通过ID查询
+func (r *appPasswordRepository) GetByID(ctx context.Context, id int) (*models.AppPassword, error) {
+	var appPassword models.AppPassword
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&appPassword).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appPassword, nil
+}
+
+// GetByHash 通过密码哈希查询
+func (r *appPasswordRepository) GetByHash(ctx context.Context, passwordHash string) (*models.AppPassword, error) {
+	var appPassword models.AppPassword
+	err := r.db.WithContext(ctx).Where("password_hash = ?", passwordHash).First(&appPassword).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appPassword, nil
+}
+
+// ListByUserID 获取用户的所有应用专用密码
+func (r *appPasswordRepository) ListByUserID(ctx context.Context, userID string) ([]*models.AppPassword, error) {
+	var list []*models.AppPassword
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// UpdateLastUsed 更新最近使用时间和 IP
+func (r *appPasswordRepository) UpdateLastUsed(ctx context.Context, id int, ip string) error {
+	return r.db.WithContext(ctx).Model(&models.AppPassword{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": custom_type.Now(),
+			"last_used_ip": ip,
+		}).Error
+}
+
+// Delete 删除应用专用密码
+func (r *appPasswordRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.AppPassword{}).Error
+}
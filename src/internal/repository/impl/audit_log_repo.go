@@ -0,0 +1,111 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 创建审计日志仓储实例
+func NewAuditLogRepository(db *gorm.DB) repository.AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create 追加审计日志
+func (r *auditLogRepository) Create(ctx context.Context, log *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// GetLast 获取最新的一条记录
+func (r *auditLogRepository) GetLast(ctx context.Context) (*models.AuditLog, error) {
+	var log models.AuditLog
+	err := r.db.WithContext(ctx).Order("id DESC").First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// List 分页查询（最新的在前）
+func (r *auditLogRepository) List(ctx context.Context, filter repository.AuditLogFilter, offset, limit int) ([]*models.AuditLog, error) {
+	var logs []*models.AuditLog
+	err := r.filter(ctx, filter).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// Count 统计记录数量
+func (r *auditLogRepository) Count(ctx context.Context, filter repository.AuditLogFilter) (int64, error) {
+	var count int64
+	err := r.filter(ctx, filter).Count(&count).Error
+	return count, err
+}
+
+// ListAfter 按ID升序查询指定ID之后的记录
+func (r *auditLogRepository) ListAfter(ctx context.Context, filter repository.AuditLogFilter, afterID, limit int) ([]*models.AuditLog, error) {
+	var logs []*models.AuditLog
+	err := r.filter(ctx, filter).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// DeleteBefore 删除指定时间之前的记录
+func (r *auditLogRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *auditLogRepository) filter(ctx context.Context, filter repository.AuditLogFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ActorName != "" {
+		query = query.Where("actor_name = ?", filter.ActorName)
+	}
+	if filter.Action != "" {
+		// 以 . 结尾时按前缀匹配，如 share. 匹配所有分享操作
+		if filter.Action[len(filter.Action)-1] == '.' {
+			query = query.Where("action LIKE ?", filter.Action+"%")
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Result != "" {
+		query = query.Where("result = ?", filter.Result)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Start != nil {
+		query = query.Where("created_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("created_at <= ?", *filter.End)
+	}
+	return query
+}
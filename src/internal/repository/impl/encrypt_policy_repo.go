@@ -0,0 +1,45 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type encryptPolicyRepository struct {
+	db *gorm.DB
+}
+
+// NewEncryptPolicyRepository 创建写入加密策略仓储实例
+func NewEncryptPolicyRepository(db *gorm.DB) repository.EncryptPolicyRepository {
+	return &encryptPolicyRepository{db: db}
+}
+
+// Create 创建策略
+func (r *encryptPolicyRepository) Create(ctx context.Context, policy *models.EncryptPolicy) error {
+	return r.db.WithContext(ctx).Create(policy).Error
+}
+
+// GetByUserIDAndDirID 获取用户指定目录的策略
+func (r *encryptPolicyRepository) GetByUserIDAndDirID(ctx context.Context, userID string, dirID int) (*models.EncryptPolicy, error) {
+	var policy models.EncryptPolicy
+	err := r.db.WithContext(ctx).Where("user_id = ? AND dir_id = ?", userID, dirID).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ListByUserID 获取用户的所有策略
+func (r *encryptPolicyRepository) ListByUserID(ctx context.Context, userID string) ([]*models.EncryptPolicy, error) {
+	var policies []*models.EncryptPolicy
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&policies).Error
+	return policies, err
+}
+
+// Delete 删除策略
+func (r *encryptPolicyRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.EncryptPolicy{}).Error
+}
@@ -10,22 +10,38 @@ import (
 type RepositoryFactory struct {
 	db *gorm.DB
 
-	userRepo         repository.UserRepository
-	fileInfoRepo     repository.FileInfoRepository
-	groupRepo        repository.GroupRepository
-	shareRepo        repository.ShareRepository
-	diskRepo         repository.DiskRepository
-	apiKeyRepo       repository.ApiKeyRepository
-	fileChunkRepo    repository.FileChunkRepository
-	powerRepo        repository.PowerRepository
-	groupPowerRepo   repository.GroupPowerRepository
-	userFilesRepo    repository.UserFilesRepository
-	virtualPathRepo  repository.VirtualPathRepository
-	recycledRepo     repository.RecycledRepository
-	downloadTaskRepo repository.DownloadTaskRepository
-	sysConfigRepo    repository.SysConfigRepository
-	uploadChunkRepo  repository.UploadChunkRepository
-	uploadTaskRepo   repository.UploadTaskRepository
+	userRepo           repository.UserRepository
+	fileInfoRepo       repository.FileInfoRepository
+	groupRepo          repository.GroupRepository
+	shareRepo          repository.ShareRepository
+	diskRepo           repository.DiskRepository
+	apiKeyRepo         repository.ApiKeyRepository
+	fileChunkRepo      repository.FileChunkRepository
+	powerRepo          repository.PowerRepository
+	groupPowerRepo     repository.GroupPowerRepository
+	userFilesRepo      repository.UserFilesRepository
+	virtualPathRepo    repository.VirtualPathRepository
+	recycledRepo       repository.RecycledRepository
+	downloadTaskRepo   repository.DownloadTaskRepository
+	sysConfigRepo      repository.SysConfigRepository
+	uploadChunkRepo    repository.UploadChunkRepository
+	uploadTaskRepo     repository.UploadTaskRepository
+	webdavLockRepo     repository.WebDAVLockRepository
+	encryptPolicyRepo  repository.EncryptPolicyRepository
+	webdavPropertyRepo repository.WebDAVPropertyRepository
+	appPasswordRepo    repository.AppPasswordRepository
+	userSessionRepo    repository.UserSessionRepository
+	twoFactorRepo      repository.TwoFactorRepository
+	userIdentityRepo   repository.UserIdentityRepository
+	securityEventRepo  repository.SecurityEventRepository
+	auditLogRepo       repository.AuditLogRepository
+	passwordHistRepo   repository.PasswordHistoryRepository
+	refreshTokenRepo   repository.RefreshTokenRepository
+	userTokenRepo      repository.UserTokenRepository
+	invitationRepo     repository.InvitationRepository
+	searchIndexRepo    repository.SearchIndexRepository
+	smartFolderRepo    repository.SmartFolderRepository
+	fileMetadataRepo   repository.FileMetadataRepository
 }
 
 // NewRepositoryFactory 创建仓储工厂实例
@@ -163,6 +179,134 @@ func (f *RepositoryFactory) UploadTask() repository.UploadTaskRepository {
 	return f.uploadTaskRepo
 }
 
+// WebDAVLock 获取 WebDAV 锁仓储
+func (f *RepositoryFactory) WebDAVLock() repository.WebDAVLockRepository {
+	if f.webdavLockRepo == nil {
+		f.webdavLockRepo = NewWebDAVLockRepository(f.db)
+	}
+	return f.webdavLockRepo
+}
+
+// EncryptPolicy 获取写入加密策略仓储
+func (f *RepositoryFactory) EncryptPolicy() repository.EncryptPolicyRepository {
+	if f.encryptPolicyRepo == nil {
+		f.encryptPolicyRepo = NewEncryptPolicyRepository(f.db)
+	}
+	return f.encryptPolicyRepo
+}
+
+// WebDAVProperty 获取 WebDAV 自定义属性仓储
+func (f *RepositoryFactory) WebDAVProperty() repository.WebDAVPropertyRepository {
+	if f.webdavPropertyRepo == nil {
+		f.webdavPropertyRepo = NewWebDAVPropertyRepository(f.db)
+	}
+	return f.webdavPropertyRepo
+}
+
+// AppPassword 获取应用专用密码仓储
+func (f *RepositoryFactory) AppPassword() repository.AppPasswordRepository {
+	if f.appPasswordRepo == nil {
+		f.appPasswordRepo = NewAppPasswordRepository(f.db)
+	}
+	return f.appPasswordRepo
+}
+
+// UserSession 获取用户会话仓储
+func (f *RepositoryFactory) UserSession() repository.UserSessionRepository {
+	if f.userSessionRepo == nil {
+		f.userSessionRepo = NewUserSessionRepository(f.db)
+	}
+	return f.userSessionRepo
+}
+
+// TwoFactor 获取两步验证仓储
+func (f *RepositoryFactory) TwoFactor() repository.TwoFactorRepository {
+	if f.twoFactorRepo == nil {
+		f.twoFactorRepo = NewTwoFactorRepository(f.db)
+	}
+	return f.twoFactorRepo
+}
+
+// UserIdentity 获取外部身份绑定仓储
+func (f *RepositoryFactory) UserIdentity() repository.UserIdentityRepository {
+	if f.userIdentityRepo == nil {
+		f.userIdentityRepo = NewUserIdentityRepository(f.db)
+	}
+	return f.userIdentityRepo
+}
+
+// SecurityEvent 获取安全事件日志仓储
+func (f *RepositoryFactory) SecurityEvent() repository.SecurityEventRepository {
+	if f.securityEventRepo == nil {
+		f.securityEventRepo = NewSecurityEventRepository(f.db)
+	}
+	return f.securityEventRepo
+}
+
+// AuditLog 获取审计日志仓储
+func (f *RepositoryFactory) AuditLog() repository.AuditLogRepository {
+	if f.auditLogRepo == nil {
+		f.auditLogRepo = NewAuditLogRepository(f.db)
+	}
+	return f.auditLogRepo
+}
+
+// PasswordHistory 获取历史密码仓储
+func (f *RepositoryFactory) PasswordHistory() repository.PasswordHistoryRepository {
+	if f.passwordHistRepo == nil {
+		f.passwordHistRepo = NewPasswordHistoryRepository(f.db)
+	}
+	return f.passwordHistRepo
+}
+
+// RefreshToken 获取刷新令牌仓储
+func (f *RepositoryFactory) RefreshToken() repository.RefreshTokenRepository {
+	if f.refreshTokenRepo == nil {
+		f.refreshTokenRepo = NewRefreshTokenRepository(f.db)
+	}
+	return f.refreshTokenRepo
+}
+
+// UserToken 获取邮件一次性令牌仓储
+func (f *RepositoryFactory) UserToken() repository.UserTokenRepository {
+	if f.userTokenRepo == nil {
+		f.userTokenRepo = NewUserTokenRepository(f.db)
+	}
+	return f.userTokenRepo
+}
+
+// Invitation 获取注册邀请仓储
+func (f *RepositoryFactory) Invitation() repository.InvitationRepository {
+	if f.invitationRepo == nil {
+		f.invitationRepo = NewInvitationRepository(f.db)
+	}
+	return f.invitationRepo
+}
+
+// SearchIndex 获取全文索引仓储
+func (f *RepositoryFactory) SearchIndex() repository.SearchIndexRepository {
+	if f.searchIndexRepo == nil {
+		f.searchIndexRepo = NewSearchIndexRepository(f.db)
+	}
+	return f.searchIndexRepo
+}
+
+// SmartFolder 获取智能文件夹仓储
+func (f *RepositoryFactory) SmartFolder() repository.SmartFolderRepository {
+	if f.smartFolderRepo == nil {
+		f.smartFolderRepo = NewSmartFolderRepository(f.db)
+	}
+	return f.smartFolderRepo
+}
+
+// FileMetadata 获取媒体元数据仓储
+func (f *RepositoryFactory) FileMetadata() repository.FileMetadataRepository {
+	if f.fileMetadataRepo == nil {
+		f.fileMetadataRepo = NewFileMetadataRepository(f.db)
+	}
+	return f.fileMetadataRepo
+}
+
 // DB 获取数据库实例（用于事务操作）
 func (f *RepositoryFactory) DB() *gorm.DB {
 	return f.db
@@ -0,0 +1,175 @@
+package impl
+
+import (
+	"fmt"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fileInfoJoin 关联用户文件的文件信息
+const fileInfoJoin = "JOIN file_info ON file_info.id = user_files.file_id"
+
+// fileUpdatedAt 文件修改时间（没有修改时间时按创建时间，保证排序与游标比较时不为 NULL）
+const fileUpdatedAt = "COALESCE(file_info.updated_at, user_files.created_at)"
+
+// fileCategory 文件分类（升级前上传、尚未划分分类的文件按 other 处理）
+const fileCategory = "COALESCE(NULLIF(file_info.category, ''), 'other')"
+
+// fileSortColumns 排序字段对应的列
+var fileSortColumns = map[string]string{
+	repository.FileSortName:    "user_files.file_name",
+	repository.FileSortSize:    "file_info.size",
+	repository.FileSortCreated: "user_files.created_at",
+	repository.FileSortUpdated: fileUpdatedAt,
+	repository.FileSortType:    "file_info.mime",
+}
+
+// applyFileFilter 按结构化条件过滤用户文件，query 需要已关联 user_files 与 file_info
+func applyFileFilter(query *gorm.DB, filter repository.FileFilter) *gorm.DB {
+	if filter.UserID != "" {
+		query = query.Where("user_files.user_id = ?", filter.UserID)
+	}
+	if len(filter.VirtualPaths) > 0 {
+		query = query.Where("user_files.virtual_path IN ?", filter.VirtualPaths)
+	}
+	if filter.Keyword != "" {
+		query = query.Where("user_files.file_name LIKE ? ESCAPE '!'", "%"+escapeLike(filter.Keyword)+"%")
+	}
+	if len(filter.Categories) > 0 {
+		// 直接比较分类列以便使用索引，尚未划分分类的文件只在筛选 other 时包含
+		cond := "file_info.category IN ?"
+		for _, category := range filter.Categories {
+			if category == util.CategoryOther {
+				cond = "(file_info.category IN ? OR file_info.category IS NULL OR file_info.category = '')"
+				break
+			}
+		}
+		query = query.Where(cond, filter.Categories)
+	}
+	if len(filter.Exts) > 0 {
+		// 扩展名按文件名后缀匹配，不区分大小写
+		conds := make([]string, len(filter.Exts))
+		args := make([]interface{}, len(filter.Exts))
+		for i, ext := range filter.Exts {
+			conds[i] = "LOWER(user_files.file_name) LIKE ? ESCAPE '!'"
+			args[i] = "%." + escapeLike(ext)
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	if filter.MinSize > 0 {
+		query = query.Where("file_info.size >= ?", filter.MinSize)
+	}
+	if filter.MaxSize > 0 {
+		query = query.Where("file_info.size <= ?", filter.MaxSize)
+	}
+	if filter.CreatedStart != nil {
+		query = query.Where("user_files.created_at >= ?", *filter.CreatedStart)
+	}
+	if filter.CreatedEnd != nil {
+		query = query.Where("user_files.created_at <= ?", *filter.CreatedEnd)
+	}
+	if filter.UpdatedStart != nil {
+		query = query.Where(fileUpdatedAt+" >= ?", *filter.UpdatedStart)
+	}
+	if filter.UpdatedEnd != nil {
+		query = query.Where(fileUpdatedAt+" <= ?", *filter.UpdatedEnd)
+	}
+	if filter.Encrypted != nil {
+		if *filter.Encrypted {
+			query = query.Where("file_info.is_enc = ?", true)
+		} else {
+			query = query.Where("(file_info.is_enc = ? OR file_info.is_enc IS NULL)", false)
+		}
+	}
+	if filter.Public != nil {
+		query = query.Where("user_files.public = ?", *filter.Public)
+	}
+	if filter.Shared != nil {
+		// 分享按文件信息ID记录，同一用户秒传或复制出的文件共享分享状态
+		shared := "EXISTS (SELECT 1 FROM shares WHERE shares.user_id = user_files.user_id " +
+			"AND shares.file_id = user_files.file_id AND shares.expires_at > ?)"
+		if !*filter.Shared {
+			shared = "NOT " + shared
+		}
+		query = query.Where(shared, time.Now())
+	}
+	return query
+}
+
+// orderFiles 按排序条件排序，最后按 uf_id 排序保证顺序稳定
+func orderFiles(query *gorm.DB, sorts []repository.FileSort) *gorm.DB {
+	for _, s := range sorts {
+		direction := " ASC"
+		if s.Desc {
+			direction = " DESC"
+		}
+		query = query.Order(fileSortColumns[s.Field] + direction)
+	}
+	return query.Order("user_files.uf_id ASC")
+}
+
+// cursorCondition 游标位置之后的条件：(k1, k2, ..., uf_id) 按各自的排序方向大于游标的值
+func cursorCondition(sorts []repository.FileSort, after *repository.FileCursor) (string, []interface{}, error) {
+	if len(after.Values) != len(sorts) {
+		return "", nil, fmt.Errorf("游标与排序条件不一致")
+	}
+	columns := make([]string, 0, len(sorts)+1)
+	ops := make([]string, 0, len(sorts)+1)
+	values := make([]interface{}, 0, len(sorts)+1)
+	for i, s := range sorts {
+		value, err := cursorValue(s.Field, after.Values[i])
+		if err != nil {
+			return "", nil, err
+		}
+		op := ">"
+		if s.Desc {
+			op = "<"
+		}
+		columns = append(columns, fileSortColumns[s.Field])
+		ops = append(ops, op)
+		values = append(values, value)
+	}
+	columns = append(columns, "user_files.uf_id")
+	ops = append(ops, ">")
+	values = append(values, after.UfID)
+
+	ors := make([]string, len(columns))
+	var args []interface{}
+	for i := range columns {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+		ands = append(ands, columns[i]+" "+ops[i]+" ?")
+		args = append(args, values[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args, nil
+}
+
+// cursorValue 将游标中的字符串还原为排序字段的类型
+func cursorValue(field, value string) (interface{}, error) {
+	switch field {
+	case repository.FileSortSize:
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的游标: %w", err)
+		}
+		return size, nil
+	case repository.FileSortCreated, repository.FileSortUpdated:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("无效的游标: %w", err)
+		}
+		return t, nil
+	case repository.FileSortName, repository.FileSortType:
+		return value, nil
+	}
+	return nil, fmt.Errorf("不支持的排序字段: %s", field)
+}
@@ -6,6 +6,7 @@ import (
 	"myobj/src/pkg/repository"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type fileInfoRepository struct {
@@ -30,6 +31,16 @@ func (r *fileInfoRepository) GetByID(ctx context.Context, id string) (*models.Fi
 	return &file, nil
 }
 
+// GetByIDForUpdate 在事务中获取文件信息并加行锁（SELECT ... FOR UPDATE）；SQLite 不支持行锁，并发写入由数据库锁串行化
+func (r *fileInfoRepository) GetByIDForUpdate(ctx context.Context, id string) (*models.FileInfo, error) {
+	var file models.FileInfo
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
 func (r *fileInfoRepository) GetByHash(ctx context.Context, hash string) (*models.FileInfo, error) {
 	var file models.FileInfo
 	err := r.db.WithContext(ctx).Where("file_hash = ?", hash).First(&file).Error
@@ -117,3 +128,47 @@ func (r *fileInfoRepository) CountByVirtualPath(ctx context.Context, userID, vir
 		Count(&count).Error
 	return count, err
 }
+
+// ListUncategorized 查询尚未划分分类的文件（升级前上传的文件）
+func (r *fileInfoRepository) ListUncategorized(ctx context.Context, limit int) ([]*models.FileInfo, error) {
+	var files []*models.FileInfo
+	err := r.db.WithContext(ctx).
+		Where("category IS NULL OR category = ?", "").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+// UpdateCategory 更新文件分类
+func (r *fileInfoRepository) UpdateCategory(ctx context.Context, id, category string) error {
+	return r.db.WithContext(ctx).Model(&models.FileInfo{}).
+		Where("id = ?", id).
+		Update("category", category).Error
+}
+
+// ReplaceContent 文件哈希仍为 expectedHash 时替换文件内容相关字段，返回更新的行数（0 表示内容已被修改）
+func (r *fileInfoRepository) ReplaceContent(ctx context.Context, file *models.FileInfo, expectedHash string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.FileInfo{}).
+		Where("id = ? AND file_hash = ?", file.ID, expectedHash).
+		Select("*").Omit("id", "created_at").
+		Updates(file)
+	return result.RowsAffected, result.Error
+}
+
+// ListImagesAfter 按ID顺序查询 afterID 之后未加密、未分片存储的图片文件（用于补建缩略图）
+func (r *fileInfoRepository) ListImagesAfter(ctx context.Context, afterID string, limit int) ([]*models.FileInfo, error) {
+	var files []*models.FileInfo
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND mime LIKE ? AND (is_enc = ? OR is_enc IS NULL) AND is_chunk = ?", afterID, "image/%", false, false).
+		Order("id").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+// UpdateThumbnail 更新文件缩略图路径
+func (r *fileInfoRepository) UpdateThumbnail(ctx context.Context, id, thumbnailPath string) error {
+	return r.db.WithContext(ctx).Model(&models.FileInfo{}).
+		Where("id = ?", id).
+		Update("thumbnail_img", thumbnailPath).Error
+}
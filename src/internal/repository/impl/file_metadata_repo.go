@@ -0,0 +1,90 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+// timelinePhotos 关联用户文件的照片元数据（时间线只包含已提取到日期的图片）
+const timelinePhotos = "JOIN file_metadata ON file_metadata.file_id = user_files.file_id AND file_metadata.kind = 'image' AND file_metadata.taken_date <> ''"
+
+type fileMetadataRepository struct {
+	db *gorm.DB
+}
+
+// NewFileMetadataRepository 创建媒体元数据仓储实例
+func NewFileMetadataRepository(db *gorm.DB) repository.FileMetadataRepository {
+	return &fileMetadataRepository{db: db}
+}
+
+// Get 获取文件的元数据
+func (r *fileMetadataRepository) Get(ctx context.Context, fileID string) (*models.FileMetadata, error) {
+	var metadata models.FileMetadata
+	err := r.db.WithContext(ctx).Where("file_id = ?", fileID).First(&metadata).Error
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// Save 保存文件的元数据
+func (r *fileMetadataRepository) Save(ctx context.Context, metadata *models.FileMetadata) error {
+	return r.db.WithContext(ctx).Save(metadata).Error
+}
+
+// ListUnextracted 尚未提取元数据的图片、音频与视频文件
+func (r *fileMetadataRepository) ListUnextracted(ctx context.Context, limit int) ([]*models.FileInfo, error) {
+	var files []*models.FileInfo
+	err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN file_metadata ON file_metadata.file_id = file_info.id").
+		Where("file_metadata.file_id IS NULL").
+		Where("(file_info.mime LIKE ? OR file_info.mime LIKE ? OR file_info.mime LIKE ?)", "image/%", "audio/%", "video/%").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+// DeleteOrphans 删除文件信息已删除的元数据
+func (r *fileMetadataRepository) DeleteOrphans(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("file_id NOT IN (?)", r.db.Model(&models.FileInfo{}).Select("id")).
+		Delete(&models.FileMetadata{})
+	return result.RowsAffected, result.Error
+}
+
+// TimelineGroups 按时间线日期的前 length 个字符分组统计用户的照片数，日期倒序
+func (r *fileMetadataRepository) TimelineGroups(ctx context.Context, userID string, length int) ([]*repository.TimelineGroup, error) {
+	// SQLite 与 MySQL 都支持 SUBSTR，日期按 yyyy-mm-dd 保存，截取前缀即可按年、月、日分组
+	period := fmt.Sprintf("SUBSTR(file_metadata.taken_date, 1, %d)", length)
+	var groups []*repository.TimelineGroup
+	err := r.db.WithContext(ctx).Model(&models.UserFiles{}).
+		Select(period+" AS date, COUNT(*) AS count").
+		Joins(timelinePhotos).
+		Where("user_files.user_id = ?", userID).
+		Group(period).
+		Order("date DESC").
+		Scan(&groups).Error
+	return groups, err
+}
+
+// ListTimelineFiles 分页查询用户时间线日期以 datePrefix 开头的照片，按拍摄时间倒序（没有拍摄时间的按上传时间）
+func (r *fileMetadataRepository) ListTimelineFiles(ctx context.Context, userID, datePrefix string, offset, limit int) ([]*models.UserFiles, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.UserFiles{}).
+		Joins(timelinePhotos).
+		Where("user_files.user_id = ? AND file_metadata.taken_date LIKE ?", userID, datePrefix+"%")
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var userFiles []*models.UserFiles
+	err := query.
+		Order("file_metadata.taken_date DESC, file_metadata.taken_at DESC, user_files.created_at DESC, user_files.uf_id").
+		Offset(offset).
+		Limit(limit).
+		Find(&userFiles).Error
+	return userFiles, total, err
+}
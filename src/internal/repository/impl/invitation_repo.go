@@ -0,0 +1,71 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository 创建注册邀请仓储实例
+func NewInvitationRepository(db *gorm.DB) repository.InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+// Create 创建邀请
+func (r *invitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+// GetByID 根据ID获取邀请
+func (r *invitationRepository) GetByID(ctx context.Context, id int) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// GetByTokenHash 根据令牌哈希获取邀请
+func (r *invitationRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// List 分页获取邀请（按创建时间倒序）
+func (r *invitationRepository) List(ctx context.Context, offset, limit int) ([]*models.Invitation, error) {
+	var invitations []*models.Invitation
+	err := r.db.WithContext(ctx).Order("id DESC").Offset(offset).Limit(limit).Find(&invitations).Error
+	return invitations, err
+}
+
+// Count 统计邀请数量
+func (r *invitationRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Invitation{}).Count(&count).Error
+	return count, err
+}
+
+// MarkUsed 标记邀请已使用
+func (r *invitationRepository) MarkUsed(ctx context.Context, id int, userID string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Updates(map[string]interface{}{"used_at": custom_type.Now(), "used_by": userID})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete 删除邀请
+func (r *invitationRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Invitation{}).Error
+}
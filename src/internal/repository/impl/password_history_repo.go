@@ -0,0 +1,52 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository 创建历史密码仓储实例
+func NewPasswordHistoryRepository(db *gorm.DB) repository.PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+// Create 记录历史密码
+func (r *passwordHistoryRepository) Create(ctx context.Context, history *models.PasswordHistory) error {
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+// ListRecent 获取用户最近的历史密码（最新的在前）
+func (r *passwordHistoryRepository) ListRecent(ctx context.Context, userID, kind string, limit int) ([]*models.PasswordHistory, error) {
+	var list []*models.PasswordHistory
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND kind = ?", userID, kind).
+		Order("id DESC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// DeleteExceptRecent 只保留最近 keep 条历史密码
+func (r *passwordHistoryRepository) DeleteExceptRecent(ctx context.Context, userID, kind string, keep int) error {
+	recent, err := r.ListRecent(ctx, userID, kind, keep)
+	if err != nil {
+		return err
+	}
+	query := r.db.WithContext(ctx).Where("user_id = ? AND kind = ?", userID, kind)
+	if len(recent) > 0 {
+		query = query.Where("id < ?", recent[len(recent)-1].ID)
+	}
+	return query.Delete(&models.PasswordHistory{}).Error
+}
+
+// DeleteByUserID 删除用户的全部历史密码
+func (r *passwordHistoryRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PasswordHistory{}).Error
+}
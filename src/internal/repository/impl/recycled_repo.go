@@ -9,6 +9,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// topLevel 顶层条目条件（随目录一起删除的记录 parent_id 指向目录条目）
+const topLevel = "(parent_id IS NULL OR parent_id = '')"
+
 type recycledRepository struct {
 	db *gorm.DB
 }
@@ -46,7 +49,7 @@ func (r *recycledRepository) Delete(ctx context.Context, id string) error {
 
 func (r *recycledRepository) ListByUserID(ctx context.Context, userID string, offset, limit int) ([]*models.Recycled, error) {
 	var recycleds []*models.Recycled
-	err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Where(topLevel).
 		Offset(offset).Limit(limit).Find(&recycleds).Error
 	return recycleds, err
 }
@@ -54,7 +57,7 @@ func (r *recycledRepository) ListByUserID(ctx context.Context, userID string, of
 func (r *recycledRepository) Count(ctx context.Context, userID string) (int64, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&models.Recycled{}).
-		Where("user_id = ?", userID).Count(&count).Error
+		Where("user_id = ?", userID).Where(topLevel).Count(&count).Error
 	return count, err
 }
 
@@ -63,7 +66,7 @@ func (r *recycledRepository) GetExpiredRecords(ctx context.Context, days int) ([
 	var recycleds []*models.Recycled
 	expireTime := time.Now().AddDate(0, 0, -days)
 	err := r.db.WithContext(ctx).
-		Where("created_at < ?", expireTime).
+		Where("created_at < ?", expireTime).Where(topLevel).
 		Find(&recycleds).Error
 	return recycleds, err
 }
@@ -75,3 +78,38 @@ func (r *recycledRepository) CountFileReferences(ctx context.Context, fileID str
 		Where("file_id = ?", fileID).Count(&count).Error
 	return count, err
 }
+
+// CountSharedReferences 统计除指定用户文件外仍引用同一文件的用户文件数
+// 复制与秒传产生的用户文件共享同一个 file_info，回收站中的用户文件可能被还原，同样计入
+func (r *recycledRepository) CountSharedReferences(ctx context.Context, fileID, exceptUfID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.UserFiles{}).
+		Where("file_id = ? AND uf_id <> ?", fileID, exceptUfID).
+		Where("deleted_at IS NULL OR uf_id IN (?)", r.db.Model(&models.Recycled{}).Select("file_id")).
+		Count(&count).Error
+	return count, err
+}
+
+// ListByParentID 查询随目录条目一起删除的文件和子目录记录
+func (r *recycledRepository) ListByParentID(ctx context.Context, parentID string) ([]*models.Recycled, error) {
+	var recycleds []*models.Recycled
+	err := r.db.WithContext(ctx).Where("parent_id = ?", parentID).Find(&recycleds).Error
+	return recycleds, err
+}
+
+// DeleteByParentID 删除目录条目下的全部记录
+func (r *recycledRepository) DeleteByParentID(ctx context.Context, parentID string) error {
+	return r.db.WithContext(ctx).Where("parent_id = ?", parentID).Delete(&models.Recycled{}).Error
+}
+
+// SumFileSizeByParentID 统计目录条目下文件的总大小
+func (r *recycledRepository) SumFileSizeByParentID(ctx context.Context, parentID string) (int64, error) {
+	var size int64
+	err := r.db.WithContext(ctx).Model(&models.Recycled{}).
+		Select("COALESCE(SUM(file_info.size), 0)").
+		Joins("JOIN user_files ON user_files.uf_id = recycled.file_id AND user_files.user_id = recycled.user_id").
+		Joins("JOIN file_info ON file_info.id = user_files.file_id").
+		Where("recycled.parent_id = ?", parentID).
+		Scan(&size).Error
+	return size, err
+}
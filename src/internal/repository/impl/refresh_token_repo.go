@@ -0,0 +1,55 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository 创建刷新令牌仓储实例
+func NewRefreshTokenRepository(db *gorm.DB) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+// Create 创建刷新令牌
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetByTokenHash 根据令牌哈希获取刷新令牌（包括已轮换的令牌）
+func (r *refreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRotated 标记令牌已轮换，仅当令牌尚未轮换时更新
+func (r *refreshTokenRepository) MarkRotated(ctx context.Context, id int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL", id).
+		Update("rotated_at", custom_type.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteBySessionID 删除会话的所有刷新令牌
+func (r *refreshTokenRepository) DeleteBySessionID(ctx context.Context, sessionID string) error {
+	return r.db.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&models.RefreshToken{}).Error
+}
+
+// DeleteOrphaned 删除所属会话已不存在的刷新令牌
+func (r *refreshTokenRepository) DeleteOrphaned(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("session_id NOT IN (?)", r.db.Model(&models.UserSession{}).Select("id")).
+		Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
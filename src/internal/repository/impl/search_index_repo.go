@@ -0,0 +1,200 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// liveUserFiles 关联未删除的用户文件
+const liveUserFiles = "JOIN user_files ON user_files.uf_id = search_doc.uf_id AND user_files.deleted_at IS NULL"
+
+type searchIndexRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchIndexRepository 创建全文索引仓储实例
+func NewSearchIndexRepository(db *gorm.DB) repository.SearchIndexRepository {
+	return &searchIndexRepository{db: db}
+}
+
+// GetContent 获取文件内容的提取结果
+func (r *searchIndexRepository) GetContent(ctx context.Context, fileID string) (*models.SearchContent, error) {
+	var content models.SearchContent
+	err := r.db.WithContext(ctx).Where("file_id = ?", fileID).First(&content).Error
+	if err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// SaveContent 保存文件内容的提取结果
+func (r *searchIndexRepository) SaveContent(ctx context.Context, content *models.SearchContent) error {
+	return r.db.WithContext(ctx).Save(content).Error
+}
+
+// DeleteContent 删除文件内容的提取结果
+func (r *searchIndexRepository) DeleteContent(ctx context.Context, fileID string) error {
+	return r.db.WithContext(ctx).Where("file_id = ?", fileID).Delete(&models.SearchContent{}).Error
+}
+
+// DeleteOrphanContents 删除已没有文档引用的提取结果
+func (r *searchIndexRepository) DeleteOrphanContents(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("file_id NOT IN (?)", r.db.Model(&models.SearchDoc{}).Select("file_id")).
+		Delete(&models.SearchContent{})
+	return result.RowsAffected, result.Error
+}
+
+// GetDoc 获取文档
+func (r *searchIndexRepository) GetDoc(ctx context.Context, ufID string) (*models.SearchDoc, error) {
+	var doc models.SearchDoc
+	err := r.db.WithContext(ctx).Where("uf_id = ?", ufID).First(&doc).Error
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ReplaceDoc 在一个事务中替换文档及其倒排记录
+func (r *searchIndexRepository) ReplaceDoc(ctx context.Context, doc *models.SearchDoc, postings []*models.SearchPosting) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uf_id = ?", doc.UfID).Delete(&models.SearchPosting{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(doc).Error; err != nil {
+			return err
+		}
+		if len(postings) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(postings, 500).Error
+	})
+}
+
+// DeleteDocs 删除文档及其倒排记录
+func (r *searchIndexRepository) DeleteDocs(ctx context.Context, ufIDs []string) error {
+	if len(ufIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uf_id IN ?", ufIDs).Delete(&models.SearchPosting{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("uf_id IN ?", ufIDs).Delete(&models.SearchDoc{}).Error
+	})
+}
+
+// ListUnindexed 未建立索引的用户文件
+func (r *searchIndexRepository) ListUnindexed(ctx context.Context, limit int) ([]*models.UserFiles, error) {
+	var userFiles []*models.UserFiles
+	err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN search_doc ON search_doc.uf_id = user_files.uf_id").
+		Where("search_doc.uf_id IS NULL").
+		Limit(limit).
+		Find(&userFiles).Error
+	return userFiles, err
+}
+
+// ListRenamed 建立索引后改过名的用户文件
+func (r *searchIndexRepository) ListRenamed(ctx context.Context, limit int) ([]*models.UserFiles, error) {
+	var userFiles []*models.UserFiles
+	err := r.db.WithContext(ctx).
+		Joins("JOIN search_doc ON search_doc.uf_id = user_files.uf_id").
+		Where("search_doc.name <> user_files.file_name").
+		Limit(limit).
+		Find(&userFiles).Error
+	return userFiles, err
+}
+
+// ListOrphanDocs 对应的用户文件已删除的文档
+func (r *searchIndexRepository) ListOrphanDocs(ctx context.Context, limit int) ([]string, error) {
+	var ufIDs []string
+	err := r.db.WithContext(ctx).Model(&models.SearchDoc{}).
+		Joins("LEFT JOIN user_files ON user_files.uf_id = search_doc.uf_id AND user_files.deleted_at IS NULL").
+		Where("user_files.uf_id IS NULL").
+		Limit(limit).
+		Pluck("search_doc.uf_id", &ufIDs).Error
+	return ufIDs, err
+}
+
+// Stats 范围内的文档数与平均文档长度
+func (r *searchIndexRepository) Stats(ctx context.Context, filter repository.FileFilter) (int64, float64, error) {
+	var stats struct {
+		Count  int64
+		Length float64
+	}
+	err := r.filter(r.db.WithContext(ctx).Model(&models.SearchDoc{}), filter).
+		Select("COUNT(*) AS count, COALESCE(AVG(search_doc.length), 0) AS length").
+		Scan(&stats).Error
+	return stats.Count, stats.Length, err
+}
+
+// DocFreq 范围内包含各词条的文档数
+func (r *searchIndexRepository) DocFreq(ctx context.Context, filter repository.FileFilter, terms []string) (map[string]int64, error) {
+	var rows []struct {
+		Term  string
+		Count int64
+	}
+	err := r.postings(ctx, filter, terms).
+		Select("search_posting.term AS term, COUNT(*) AS count").
+		Group("search_posting.term").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	freq := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		freq[row.Term] = row.Count
+	}
+	return freq, nil
+}
+
+// Postings 范围内包含任一词条的倒排记录
+func (r *searchIndexRepository) Postings(ctx context.Context, filter repository.FileFilter, terms []string, limit int) ([]*repository.SearchHit, error) {
+	var hits []*repository.SearchHit
+	err := r.postings(ctx, filter, terms).
+		Select("search_posting.uf_id AS uf_id, search_posting.term AS term, search_posting.name_tf AS name_tf, " +
+			"search_posting.content_tf AS content_tf, search_doc.length AS length").
+		Limit(limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// MatchNames 范围内文件名包含关键词的用户文件ID
+func (r *searchIndexRepository) MatchNames(ctx context.Context, filter repository.FileFilter, keyword string, limit int) ([]string, error) {
+	var ufIDs []string
+	err := r.filter(r.db.WithContext(ctx).Model(&models.SearchDoc{}), filter).
+		Where("user_files.file_name LIKE ? ESCAPE '!'", "%"+escapeLike(keyword)+"%").
+		Limit(limit).
+		Pluck("search_doc.uf_id", &ufIDs).Error
+	return ufIDs, err
+}
+
+// ListDocs 批量获取文档
+func (r *searchIndexRepository) ListDocs(ctx context.Context, ufIDs []string) ([]*models.SearchDoc, error) {
+	var docs []*models.SearchDoc
+	err := r.db.WithContext(ctx).Where("uf_id IN ?", ufIDs).Find(&docs).Error
+	return docs, err
+}
+
+// postings 范围内指定词条的倒排记录查询
+func (r *searchIndexRepository) postings(ctx context.Context, filter repository.FileFilter, terms []string) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.SearchPosting{}).
+		Joins("JOIN search_doc ON search_doc.uf_id = search_posting.uf_id").
+		Where("search_posting.term IN ?", terms)
+	return r.filter(query, filter)
+}
+
+// filter 关联未删除的用户文件并按条件过滤
+func (r *searchIndexRepository) filter(query *gorm.DB, filter repository.FileFilter) *gorm.DB {
+	return applyFileFilter(query.Joins(liveUserFiles).Joins(fileInfoJoin), filter)
+}
+
+// escapeLike 转义 LIKE 模式中的通配符（转义符使用 "!"，与 SQLite、MySQL 的字符串转义规则均无冲突）
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,74 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityEventRepository 创建安全事件日志仓储实例
+func NewSecurityEventRepository(db *gorm.DB) repository.SecurityEventRepository {
+	return &securityEventRepository{db: db}
+}
+
+// Create 记录安全事件
+func (r *securityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// GetLatest 获取指定类型与对象的最近一条事件
+func (r *securityEventRepository) GetLatest(ctx context.Context, eventType, target string) (*models.SecurityEvent, error) {
+	var event models.SecurityEvent
+	err := r.db.WithContext(ctx).
+		Where("type = ? AND target = ?", eventType, target).
+		Order("id DESC").
+		First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List 分页查询事件（最新的在前），eventType、target 为空时不过滤
+func (r *securityEventRepository) List(ctx context.Context, eventType, target string, offset, limit int) ([]*models.SecurityEvent, error) {
+	var events []*models.SecurityEvent
+	err := r.filter(ctx, eventType, target).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// Count 统计事件数量
+func (r *securityEventRepository) Count(ctx context.Context, eventType, target string) (int64, error) {
+	var count int64
+	err := r.filter(ctx, eventType, target).Count(&count).Error
+	return count, err
+}
+
+// DeleteBefore 删除指定时间之前的事件
+func (r *securityEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&models.SecurityEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *securityEventRepository) filter(ctx context.Context, eventType, target string) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.SecurityEvent{})
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+	if target != "" {
+		query = query.Where("target = ?", target)
+	}
+	return query
+}
@@ -72,3 +72,10 @@ func (r *shareRepository) IncrementDownloadCount(ctx context.Context, id int) er
 		Where("id = ?", id).
 		UpdateColumn("download_count", gorm.Expr("download_count + ?", 1)).Error
 }
+
+// UpdateFileID 将用户指向旧文件的分享改为指向新文件
+func (r *shareRepository) UpdateFileID(ctx context.Context, userID, oldFileID, newFileID string) error {
+	return r.db.WithContext(ctx).Model(&models.Share{}).
+		Where("user_id = ? AND file_id = ?", userID, oldFileID).
+		Update("file_id", newFileID).Error
+}
@@ -0,0 +1,57 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type smartFolderRepository struct {
+	db *gorm.DB
+}
+
+// NewSmartFolderRepository 创建智能文件夹仓储实例
+func NewSmartFolderRepository(db *gorm.DB) repository.SmartFolderRepository {
+	return &smartFolderRepository{db: db}
+}
+
+// Create 创建智能文件夹
+func (r *smartFolderRepository) Create(ctx context.Context, folder *models.SmartFolder) error {
+	return r.db.WithContext(ctx).Create(folder).Error
+}
+
+// GetByID 获取用户的智能文件夹
+func (r *smartFolderRepository) GetByID(ctx context.Context, userID string, id int) (*models.SmartFolder, error) {
+	var folder models.SmartFolder
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// ListByUserID 获取用户的全部智能文件夹（按名称排序）
+func (r *smartFolderRepository) ListByUserID(ctx context.Context, userID string) ([]*models.SmartFolder, error) {
+	var folders []*models.SmartFolder
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name, id").Find(&folders).Error
+	return folders, err
+}
+
+// Update 更新智能文件夹
+func (r *smartFolderRepository) Update(ctx context.Context, folder *models.SmartFolder) error {
+	return r.db.WithContext(ctx).Where("id = ? AND user_id = ?", folder.ID, folder.UserID).Save(folder).Error
+}
+
+// Delete 删除智能文件夹
+func (r *smartFolderRepository) Delete(ctx context.Context, userID string, id int) error {
+	return r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.SmartFolder{}).Error
+}
+
+// CountByUserID 统计用户的智能文件夹数量
+func (r *smartFolderRepository) CountByUserID(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.SmartFolder{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,54 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorRepository 创建两步验证仓储实例
+func NewTwoFactorRepository(db *gorm.DB) repository.TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// GetByUserID 获取用户的两步验证配置
+func (r *twoFactorRepository) GetByUserID(ctx context.Context, userID string) (*models.UserTwoFactor, error) {
+	var twoFactor models.UserTwoFactor
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&twoFactor).Error
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+// Save 创建或更新两步验证配置
+func (r *twoFactorRepository) Save(ctx context.Context, twoFactor *models.UserTwoFactor) error {
+	return r.db.WithContext(ctx).Save(twoFactor).Error
+}
+
+// UpdateLastStep 更新最近验证通过的时间步（条件更新，防止并发请求重复使用同一验证码）
+func (r *twoFactorRepository) UpdateLastStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateRecoveryCodes 更新恢复码（条件更新，防止并发请求重复使用同一恢复码）
+func (r *twoFactorRepository) UpdateRecoveryCodes(ctx context.Context, userID, oldCodes, newCodes string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND recovery_codes = ?", userID, oldCodes).
+		Update("recovery_codes", newCodes)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete 删除用户的两步验证配置
+func (r *twoFactorRepository) Delete(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/repository"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -31,7 +33,8 @@ func (r *userFilesRepository) GetByUserIDAndFileID(ctx context.Context, userID,
 }
 
 func (r *userFilesRepository) Update(ctx context.Context, userFile *models.UserFiles) error {
-	return r.db.WithContext(ctx).Where("user_id = ? and file_id = ?", userFile.UserID, userFile.FileID).Save(userFile).Error
+	// 复制与秒传的用户文件共享 file_id，按 uf_id 定位，避免同时更新其他副本
+	return r.db.WithContext(ctx).Where("user_id = ? and uf_id = ?", userFile.UserID, userFile.UfID).Save(userFile).Error
 }
 
 func (r *userFilesRepository) Delete(ctx context.Context, userID, fileID string) error {
@@ -53,6 +56,17 @@ func (r *userFilesRepository) Count(ctx context.Context, userID string) (int64,
 	return count, err
 }
 
+// SumSizeByUserID 统计用户文件占用的总大小（字节）
+func (r *userFilesRepository) SumSizeByUserID(ctx context.Context, userID string) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.UserFiles{}).
+		Select("COALESCE(SUM(file_info.size), 0)").
+		Joins("JOIN file_info ON file_info.id = user_files.file_id").
+		Where("user_files.user_id = ?", userID).
+		Scan(&total).Error
+	return total, err
+}
+
 // ListPublicFiles 获取所有公开文件
 func (r *userFilesRepository) ListPublicFiles(ctx context.Context, offset, limit int) ([]*models.UserFiles, error) {
 	var userFiles []*models.UserFiles
@@ -61,56 +75,6 @@ func (r *userFilesRepository) ListPublicFiles(ctx context.Context, offset, limit
 	return userFiles, err
 }
 
-// CountPublicFiles 统计公开文件数量
-func (r *userFilesRepository) CountPublicFiles(ctx context.Context) (int64, error) {
-	var count int64
-	err := r.db.WithContext(ctx).Model(&models.UserFiles{}).
-		Where("public = ?", true).Count(&count).Error
-	return count, err
-}
-
-// SearchPublicFiles 搜索公开文件（根据文件名）
-func (r *userFilesRepository) SearchPublicFiles(ctx context.Context, keyword string, offset, limit int) ([]*models.UserFiles, error) {
-	var userFiles []*models.UserFiles
-	err := r.db.WithContext(ctx).
-		Joins("JOIN file_info ON user_files.file_id = file_info.id").
-		Where("user_files.public = ? AND file_info.name LIKE ?", true, "%"+keyword+"%").
-		Offset(offset).Limit(limit).
-		Find(&userFiles).Error
-	return userFiles, err
-}
-
-// CountPublicFilesByKeyword 统计匹配关键词的公开文件数量
-func (r *userFilesRepository) CountPublicFilesByKeyword(ctx context.Context, keyword string) (int64, error) {
-	var count int64
-	err := r.db.WithContext(ctx).Model(&models.UserFiles{}).
-		Joins("JOIN file_info ON user_files.file_id = file_info.id").
-		Where("user_files.public = ? AND file_info.name LIKE ?", true, "%"+keyword+"%").
-		Count(&count).Error
-	return count, err
-}
-
-// SearchUserFiles 搜索用户文件（根据文件名）
-func (r *userFilesRepository) SearchUserFiles(ctx context.Context, userID, keyword string, offset, limit int) ([]*models.UserFiles, error) {
-	var userFiles []*models.UserFiles
-	err := r.db.WithContext(ctx).
-		Joins("JOIN file_info ON user_files.file_id = file_info.id").
-		Where("user_files.user_id = ? AND user_files.file_name LIKE ?", userID, "%"+keyword+"%").
-		Offset(offset).Limit(limit).
-		Find(&userFiles).Error
-	return userFiles, err
-}
-
-// CountUserFilesByKeyword 统计用户匹配关键词的文件数量
-func (r *userFilesRepository) CountUserFilesByKeyword(ctx context.Context, userID, keyword string) (int64, error) {
-	var count int64
-	err := r.db.WithContext(ctx).Model(&models.UserFiles{}).
-		Joins("JOIN file_info ON user_files.file_id = file_info.id").
-		Where("user_files.user_id = ? AND file_info.name LIKE ?", userID, "%"+keyword+"%").
-		Count(&count).Error
-	return count, err
-}
-
 // GetByUserIDAndUfID 获取用户文件关联
 func (r *userFilesRepository) GetByUserIDAndUfID(ctx context.Context, userID, ufID string) (*models.UserFiles, error) {
 	var userFile models.UserFiles
@@ -137,8 +101,91 @@ func (r *userFilesRepository) ListByVirtualPath(ctx context.Context, userID, vir
 	var userFiles []*models.UserFiles
 	err := r.db.WithContext(ctx).
 		Where("user_id = ? AND virtual_path = ?", userID, virtualPath).
-		Order("created_at DESC").
+		Order("created_at DESC, uf_id ASC"). // 按 uf_id 确定同一时间文件的顺序，保证分页查询不遗漏
 		Offset(offset).Limit(limit).
 		Find(&userFiles).Error
 	return userFiles, err
 }
+
+// ListByFilter 按条件查询文件，依次按 sorts 与 uf_id 排序；after 不为空时从该位置之后开始
+// 还有更多文件时返回指向本页最后一个文件的游标，否则返回 nil
+func (r *userFilesRepository) ListByFilter(ctx context.Context, filter repository.FileFilter, sorts []repository.FileSort, after *repository.FileCursor, offset, limit int) ([]*models.UserFiles, *repository.FileCursor, error) {
+	query := applyFileFilter(r.db.WithContext(ctx).Model(&models.UserFiles{}).Joins(fileInfoJoin), filter)
+	if after != nil && after.UfID != "" {
+		cond, args, err := cursorCondition(sorts, after)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Where(cond, args...)
+	}
+	var userFiles []*models.UserFiles
+	// 多查一条判断是否还有更多文件
+	err := orderFiles(query, sorts).Select("user_files.*").
+		Offset(offset).Limit(limit + 1).
+		Find(&userFiles).Error
+	if err != nil || len(userFiles) <= limit {
+		return userFiles, nil, err
+	}
+	userFiles = userFiles[:limit]
+	next, err := r.cursorOf(ctx, sorts, userFiles[limit-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return userFiles, next, nil
+}
+
+// CountByFilter 统计满足条件的文件数量
+func (r *userFilesRepository) CountByFilter(ctx context.Context, filter repository.FileFilter) (int64, error) {
+	var count int64
+	err := applyFileFilter(r.db.WithContext(ctx).Model(&models.UserFiles{}).Joins(fileInfoJoin), filter).
+		Count(&count).Error
+	return count, err
+}
+
+// StatByCategory 按分类统计用户文件的数量与占用空间，未划分分类的文件计入 other
+func (r *userFilesRepository) StatByCategory(ctx context.Context, userID string) ([]*repository.CategoryStat, error) {
+	var stats []*repository.CategoryStat
+	err := r.db.WithContext(ctx).Model(&models.UserFiles{}).
+		Select(fileCategory+" AS category, COUNT(*) AS count, COALESCE(SUM(file_info.size), 0) AS size").
+		Joins(fileInfoJoin).
+		Where("user_files.user_id = ?", userID).
+		Group(fileCategory).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// RepointFile 用户文件仍指向 oldFileID 时改为指向 newFileID，返回更新的行数
+func (r *userFilesRepository) RepointFile(ctx context.Context, userID, ufID, oldFileID, newFileID string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserFiles{}).
+		Where("user_id = ? AND uf_id = ? AND file_id = ?", userID, ufID, oldFileID).
+		Update("file_id", newFileID)
+	return result.RowsAffected, result.Error
+}
+
+// cursorOf 生成指向该文件的游标
+func (r *userFilesRepository) cursorOf(ctx context.Context, sorts []repository.FileSort, userFile *models.UserFiles) (*repository.FileCursor, error) {
+	var fileInfo models.FileInfo
+	if err := r.db.WithContext(ctx).Where("id = ?", userFile.FileID).First(&fileInfo).Error; err != nil {
+		return nil, err
+	}
+	values := make([]string, len(sorts))
+	for i, s := range sorts {
+		switch s.Field {
+		case repository.FileSortName:
+			values[i] = userFile.FileName
+		case repository.FileSortSize:
+			values[i] = strconv.Itoa(fileInfo.Size)
+		case repository.FileSortCreated:
+			values[i] = time.Time(userFile.CreatedAt).Format(time.RFC3339Nano)
+		case repository.FileSortUpdated:
+			updated := fileInfo.UpdatedAt
+			if updated.IsZero() {
+				updated = userFile.CreatedAt
+			}
+			values[i] = time.Time(updated).Format(time.RFC3339Nano)
+		case repository.FileSortType:
+			values[i] = fileInfo.Mime
+		}
+	}
+	return &repository.FileCursor{Values: values, UfID: userFile.UfID}, nil
+}
@@ -0,0 +1,53 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"gorm.io/gorm"
+)
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository 创建外部身份仓储实例
+func NewUserIdentityRepository(db *gorm.DB) repository.UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+// Create 创建外部身份绑定
+func (r *userIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// GetBySubject 通过身份提供方与主体查询
+func (r *userIdentityRepository) GetBySubject(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListByUserID 获取用户绑定的所有外部身份
+func (r *userIdentityRepository) ListByUserID(ctx context.Context, userID string) ([]*models.UserIdentity, error) {
+	var list []*models.UserIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+// UpdateLastLogin 更新最近登录时间
+func (r *userIdentityRepository) UpdateLastLogin(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("id = ?", id).
+		Update("last_login_at", custom_type.Now()).Error
+}
+
+// Delete 解除用户的外部身份绑定，返回删除的数量
+func (r *userIdentityRepository) Delete(ctx context.Context, id int, userID string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserIdentity{})
+	return result.RowsAffected, result.Error
+}
@@ -6,6 +6,7 @@ import (
 	"myobj/src/pkg/repository"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -30,6 +31,16 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*models.UserIn
 	return &user, nil
 }
 
+// GetByIDForUpdate 在事务中获取用户并加行锁（SELECT ... FOR UPDATE）；SQLite 不支持行锁，并发写入由数据库锁串行化
+func (r *userRepository) GetByIDForUpdate(ctx context.Context, id string) (*models.UserInfo, error) {
+	var user models.UserInfo
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) GetByUserName(ctx context.Context, userName string) (*models.UserInfo, error) {
 	var user models.UserInfo
 	err := r.db.WithContext(ctx).Where("user_name = ?", userName).First(&user).Error
@@ -58,3 +69,15 @@ func (r *userRepository) Count(ctx context.Context) (int64, error) {
 	err := r.db.WithContext(ctx).Model(&models.UserInfo{}).Count(&count).Error
 	return count, err
 }
+
+func (r *userRepository) ListByAuthSource(ctx context.Context, source string) ([]*models.UserInfo, error) {
+	var users []*models.UserInfo
+	err := r.db.WithContext(ctx).Where("auth_source = ?", source).Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) ListByEmail(ctx context.Context, email string) ([]*models.UserInfo, error) {
+	var users []*models.UserInfo
+	err := r.db.WithContext(ctx).Where("email = ?", email).Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,98 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewUserSessionRepository 创建用户会话仓储实例
+func NewUserSessionRepository(db *gorm.DB) repository.UserSessionRepository {
+	return &userSessionRepository{db: db}
+}
+
+// Create 创建会话
+func (r *userSessionRepository) Create(ctx context.Context, session *models.UserSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// GetByID 根据会话ID获取会话
+func (r *userSessionRepository) GetByID(ctx context.Context, id string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByTokenHash 根据令牌哈希获取未过期的会话
+func (r *userSessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveByUserID 获取用户所有未过期的会话（最近访问的在前）
+func (r *userSessionRepository) ListActiveByUserID(ctx context.Context, userID string) ([]*models.UserSession, error) {
+	var sessions []*models.UserSession
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch 更新最近访问时间和 IP
+func (r *userSessionRepository) Touch(ctx context.Context, id, ip string) error {
+	return r.db.WithContext(ctx).Model(&models.UserSession{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_seen_at": custom_type.Now(),
+			"ip":           ip,
+		}).Error
+}
+
+// UpdateToken 刷新令牌后更新访问令牌哈希与过期时间
+func (r *userSessionRepository) UpdateToken(ctx context.Context, id, tokenHash string, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserSession{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"token_hash": tokenHash,
+			"expires_at": custom_type.JsonTime(expiresAt),
+		}).Error
+}
+
+// Delete 删除会话
+func (r *userSessionRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.UserSession{}).Error
+}
+
+// DeleteByUserID 删除用户的所有会话，exceptID 不为空时保留该会话
+func (r *userSessionRepository) DeleteByUserID(ctx context.Context, userID, exceptID string) (int64, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+	result := query.Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpired 删除已过期的会话
+func (r *userSessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewUserTokenRepository 创建邮件一次性令牌仓储实例
+func NewUserTokenRepository(db *gorm.DB) repository.UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+// Create 创建令牌
+func (r *userTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetByTokenHash 根据令牌哈希获取令牌（包括已过期的令牌）
+func (r *userTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Delete 删除令牌
+func (r *userTokenRepository) Delete(ctx context.Context, id int) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.UserToken{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByUserID 删除用户指定类型的所有令牌
+func (r *userTokenRepository) DeleteByUserID(ctx context.Context, userID, kind string) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND kind = ?", userID, kind).Delete(&models.UserToken{}).Error
+}
+
+// DeleteExpired 删除已过期的令牌
+func (r *userTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.UserToken{})
+	return result.RowsAffected, result.Error
+}
@@ -66,7 +66,7 @@ func (r *virtualPathRepository) ListSubFoldersByParentID(ctx context.Context, us
 	var vpaths []*models.VirtualPath
 	err := r.db.WithContext(ctx).
 		Where("user_id = ? AND parent_level = ? AND is_dir = ?", userID, parentID, true).
-		Order("path ASC").
+		Order("path ASC, id ASC").
 		Offset(offset).Limit(limit).
 		Find(&vpaths).Error
 	return vpaths, err
@@ -99,3 +99,31 @@ func (r *virtualPathRepository) GetPathByUser(ctx context.Context, userID string
 	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&vpaths).Error
 	return vpaths, err
 }
+
+// GetByIDUnscoped 通过ID查询目录（包含已移入回收站的目录）
+func (r *virtualPathRepository) GetByIDUnscoped(ctx context.Context, id int) (*models.VirtualPath, error) {
+	var vpath models.VirtualPath
+	err := r.db.WithContext(ctx).Unscoped().Where("id = ?", id).First(&vpath).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vpath, nil
+}
+
+// ListByIDsUnscoped 批量查询目录（包含已移入回收站的目录）
+func (r *virtualPathRepository) ListByIDsUnscoped(ctx context.Context, ids []int) ([]*models.VirtualPath, error) {
+	var vpaths []*models.VirtualPath
+	if len(ids) == 0 {
+		return vpaths, nil
+	}
+	err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&vpaths).Error
+	return vpaths, err
+}
+
+// Purge 彻底删除目录记录（包含已移入回收站的目录）
+func (r *virtualPathRepository) Purge(ctx context.Context, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Delete(&models.VirtualPath{}).Error
+}
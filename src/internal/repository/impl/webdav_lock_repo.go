@@ -0,0 +1,83 @@
+package impl
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webdavLockRepository struct {
+	db *gorm.DB
+}
+
+// NewWebDAVLockRepository 创建 WebDAV 锁仓储实例
+func NewWebDAVLockRepository(db *gorm.DB) repository.WebDAVLockRepository {
+	return &webdavLockRepository{db: db}
+}
+
+// Create 创建锁记录
+func (r *webdavLockRepository) Create(ctx context.Context, lock *models.WebDAVLock) error {
+	return r.db.WithContext(ctx).Create(lock).Error
+}
+
+// GetByToken 根据令牌获取锁
+func (r *webdavLockRepository) GetByToken(ctx context.Context, token string) (*models.WebDAVLock, error) {
+	var lock models.WebDAVLock
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&lock).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// Update 更新锁记录
+func (r *webdavLockRepository) Update(ctx context.Context, lock *models.WebDAVLock) error {
+	return r.db.WithContext(ctx).Save(lock).Error
+}
+
+// Delete 删除锁记录
+func (r *webdavLockRepository) Delete(ctx context.Context, token string) error {
+	return r.db.WithContext(ctx).Where("token = ?", token).Delete(&models.WebDAVLock{}).Error
+}
+
+// ListActiveByUserID 获取用户所有未过期的锁
+func (r *webdavLockRepository) ListActiveByUserID(ctx context.Context, userID string) ([]*models.WebDAVLock, error) {
+	var locks []*models.WebDAVLock
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Find(&locks).Error
+	return locks, err
+}
+
+// List 分页获取未过期的锁
+func (r *webdavLockRepository) List(ctx context.Context, userID string, offset, limit int) ([]*models.WebDAVLock, error) {
+	var locks []*models.WebDAVLock
+	query := r.db.WithContext(ctx).Where("expires_at > ?", time.Now())
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&locks).Error
+	return locks, err
+}
+
+// Count 统计未过期的锁数量
+func (r *webdavLockRepository) Count(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&models.WebDAVLock{}).Where("expires_at > ?", time.Now())
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// DeleteExpired 删除已过期的锁
+func (r *webdavLockRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&models.WebDAVLock{})
+	return result.RowsAffected, result.Error
+}
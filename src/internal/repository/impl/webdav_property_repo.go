@@ -0,0 +1,94 @@
+package impl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type webdavPropertyRepository struct {
+	db *gorm.DB
+}
+
+// NewWebDAVPropertyRepository 创建 WebDAV 自定义属性仓储实例
+func NewWebDAVPropertyRepository(db *gorm.DB) repository.WebDAVPropertyRepository {
+	return &webdavPropertyRepository{db: db}
+}
+
+// propertyPathHash 资源路径的 SHA-256（十六进制），对应 path_hash 字段
+func propertyPathHash(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListByPath 获取资源的所有属性
+func (r *webdavPropertyRepository) ListByPath(ctx context.Context, userID, path string) ([]*models.WebDAVProperty, error) {
+	var props []*models.WebDAVProperty
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND path_hash = ? AND path = ?", userID, propertyPathHash(path), path).
+		Order("id ASC").
+		Find(&props).Error
+	return props, err
+}
+
+// Save 写入属性（同名属性存在时覆盖）
+func (r *webdavPropertyRepository) Save(ctx context.Context, prop *models.WebDAVProperty) error {
+	prop.UpdatedAt = custom_type.Now()
+	prop.PathHash = propertyPathHash(prop.Path)
+	var existing models.WebDAVProperty
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND path_hash = ? AND path = ? AND namespace = ? AND name = ?", prop.UserID, prop.PathHash, prop.Path, prop.Namespace, prop.Name).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.db.WithContext(ctx).Create(prop).Error
+	}
+	if err != nil {
+		return err
+	}
+	prop.ID = existing.ID
+	return r.db.WithContext(ctx).Save(prop).Error
+}
+
+// Delete 删除资源的指定属性
+func (r *webdavPropertyRepository) Delete(ctx context.Context, userID, path, namespace, name string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND path_hash = ? AND path = ? AND namespace = ? AND name = ?", userID, propertyPathHash(path), path, namespace, name).
+		Delete(&models.WebDAVProperty{}).Error
+}
+
+// DeleteByPath 删除资源及其所有子资源的属性
+func (r *webdavPropertyRepository) DeleteByPath(ctx context.Context, userID, path string) error {
+	// 子资源路径以 "path/" 开头，按字典序落在 ["path/", "path0") 区间内，避免 LIKE 通配符转义问题
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND (path = ? OR (path >= ? AND path < ?))", userID, path, path+"/", path+"0").
+		Delete(&models.WebDAVProperty{}).Error
+}
+
+// MovePath 资源移动后更新其及子资源属性的路径
+func (r *webdavPropertyRepository) MovePath(ctx context.Context, userID, oldPath, newPath string) error {
+	var props []*models.WebDAVProperty
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND (path = ? OR (path >= ? AND path < ?))", userID, oldPath, oldPath+"/", oldPath+"0").
+		Find(&props).Error
+	if err != nil {
+		return err
+	}
+	for _, prop := range props {
+		if prop.Path != oldPath && !strings.HasPrefix(prop.Path, oldPath+"/") {
+			continue
+		}
+		p := newPath + strings.TrimPrefix(prop.Path, oldPath)
+		if err := r.db.WithContext(ctx).Model(&models.WebDAVProperty{}).
+			Where("id = ?", prop.ID).Updates(map[string]interface{}{"path": p, "path_hash": propertyPathHash(p)}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
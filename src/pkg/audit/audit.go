@@ -0,0 +1,264 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"myobj/src/config"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 操作来源
+const (
+	SourceWeb    = "web"
+	SourceAPIKey = "api_key"
+	SourceWebDAV = "webdav"
+	SourceShare  = "share"
+)
+
+// 操作对象类型
+const (
+	TargetUser        = "user"
+	TargetGroup       = "group"
+	TargetPower       = "power"
+	TargetDisk        = "disk"
+	TargetConfig      = "config"
+	TargetSession     = "session"
+	TargetAPIKey      = "api_key"
+	TargetAppPassword = "app_password"
+	TargetShare       = "share"
+	TargetFile        = "file"
+	TargetDir         = "dir"
+	TargetRecycled    = "recycled"
+	TargetWebDAVLock  = "webdav_lock"
+	TargetPath        = "path"
+	TargetAuditLog    = "audit_log"
+	TargetInvitation  = "invitation"
+)
+
+// 操作（按 对象.动作 命名，查询时可用 "对象." 前缀匹配一类操作）
+const (
+	ActionUserLogin          = "user.login"
+	ActionUserRegister       = "user.register"
+	ActionUserPassword       = "user.password"
+	ActionUserCreate         = "user.create"
+	ActionUserUpdate         = "user.update"
+	ActionUserDelete         = "user.delete"
+	ActionUserState          = "user.state"
+	ActionUserUnlock         = "user.unlock"
+	ActionUserVerifyEmail    = "user.email_verify"
+	ActionUserResetTwoFactor = "user.2fa_reset"
+	ActionTwoFactorEnable    = "2fa.enable"
+	ActionTwoFactorDisable   = "2fa.disable"
+	ActionSessionRevoke      = "session.revoke"
+	ActionSessionTokenReuse  = "session.token_reuse"
+	ActionAPIKeyCreate       = "apikey.create"
+	ActionAPIKeyDelete       = "apikey.delete"
+	ActionAppPasswordCreate  = "app_password.create"
+	ActionAppPasswordDelete  = "app_password.delete"
+	ActionInvitationCreate   = "invitation.create"
+	ActionInvitationDelete   = "invitation.delete"
+	ActionGroupCreate        = "group.create"
+	ActionGroupUpdate        = "group.update"
+	ActionGroupDelete        = "group.delete"
+	ActionPowerCreate        = "power.create"
+	ActionPowerUpdate        = "power.update"
+	ActionPowerDelete        = "power.delete"
+	ActionPowerAssign        = "power.assign"
+	ActionDiskCreate         = "disk.create"
+	ActionDiskUpdate         = "disk.update"
+	ActionDiskDelete         = "disk.delete"
+	ActionConfigUpdate       = "config.update"
+	ActionWebDAVLockBreak    = "webdav.lock_break"
+	ActionShareCreate        = "share.create"
+	ActionShareDelete        = "share.delete"
+	ActionSharePassword      = "share.password"
+	ActionShareDownload      = "share.download"
+	ActionFileDelete         = "file.delete"
+	ActionFileMove           = "file.move"
+	ActionFileMoveBatch      = "file.move_batch"
+	ActionFileCopy           = "file.copy"
+	ActionFileRename         = "file.rename"
+	ActionFilePublic         = "file.public"
+	ActionFileEdit           = "file.edit"
+	ActionDirDelete          = "dir.delete"
+	ActionDirRename          = "dir.rename"
+	ActionRecycledRestore    = "recycled.restore"
+	ActionRecycledDelete     = "recycled.delete"
+	ActionRecycledEmpty      = "recycled.empty"
+	ActionWebDAVPut          = "webdav.put"
+	ActionWebDAVDelete       = "webdav.delete"
+	ActionWebDAVMkcol        = "webdav.mkcol"
+	ActionWebDAVMove         = "webdav.move"
+	ActionWebDAVCopy         = "webdav.copy"
+	ActionWebDAVProppatch    = "webdav.proppatch"
+	ActionAuditExport        = "audit.export"
+)
+
+const (
+	maxTargetLen = 512
+	maxDetailLen = 1024
+)
+
+// chainMu 串行化审计日志写入，保证哈希链中上一条记录与写入顺序一致
+var chainMu sync.Mutex
+
+// Actor 操作人
+type Actor struct {
+	UserID   string
+	UserName string
+	// APIKeyID 使用 API Key 认证时的 Key ID
+	APIKeyID int
+	IP       string
+	Source   string
+}
+
+// Recorder 审计日志记录器
+type Recorder struct {
+	repo repository.AuditLogRepository
+	cfg  config.Audit
+}
+
+// NewRecorder 创建审计日志记录器
+func NewRecorder(repo repository.AuditLogRepository, cfg config.Audit) *Recorder {
+	return &Recorder{repo: repo, cfg: cfg}
+}
+
+// Record 记录一次操作，err 不为空时结果记为失败并附带错误信息
+// 写入失败只记录日志，不影响业务流程
+func (r *Recorder) Record(actor Actor, action, targetType, targetID, detail string, err error) {
+	if r == nil || !r.cfg.Enable {
+		return
+	}
+	result := models.AuditResultSuccess
+	if err != nil {
+		result = models.AuditResultFailure
+		if detail != "" {
+			detail += "; "
+		}
+		detail += "错误: " + err.Error()
+	}
+	entry := &models.AuditLog{
+		ActorID:    actor.UserID,
+		ActorName:  actor.UserName,
+		APIKeyID:   actor.APIKeyID,
+		Source:     actor.Source,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   truncate(targetID, maxTargetLen),
+		Detail:     truncate(detail, maxDetailLen),
+		IP:         actor.IP,
+		Result:     result,
+		// 数据库时间可能只精确到秒，截断后写入，保证校验哈希时与写入时一致
+		CreatedAt: custom_type.JsonTime(time.Unix(time.Now().Unix(), 0)),
+	}
+
+	ctx := context.Background()
+	chainMu.Lock()
+	defer chainMu.Unlock()
+	if r.cfg.HashChain {
+		last, lastErr := r.repo.GetLast(ctx)
+		if lastErr != nil && !errors.Is(lastErr, gorm.ErrRecordNotFound) {
+			logger.LOG.Error("读取上一条审计日志失败", "action", action, "error", lastErr)
+			return
+		}
+		if last != nil {
+			entry.PrevHash = last.Hash
+		}
+		entry.Hash = ComputeHash(entry)
+	}
+	if createErr := r.repo.Create(ctx, entry); createErr != nil {
+		logger.LOG.Error("写入审计日志失败", "action", action, "actor", actor.UserName, "target", targetID, "error", createErr)
+	}
+}
+
+// ComputeHash 计算记录的哈希：sha256(上一条哈希 + 各字段)
+func ComputeHash(entry *models.AuditLog) string {
+	fields := []string{
+		entry.PrevHash,
+		entry.ActorID,
+		entry.ActorName,
+		strconv.Itoa(entry.APIKeyID),
+		entry.Source,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.Detail,
+		entry.IP,
+		entry.Result,
+		strconv.FormatInt(entry.CreatedAt.Unix(), 10),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyResult 哈希链校验结果
+type VerifyResult struct {
+	// Checked 检查的记录数
+	Checked int
+	// Chained 带哈希的记录数
+	Chained int
+	// BrokenID 第一条校验失败的记录ID（0 表示校验通过）
+	BrokenID int
+	// Reason 校验失败原因
+	Reason string
+}
+
+// Verify 按ID顺序校验哈希链
+// 未启用哈希链时写入的记录不参与校验；第一条记录的上一条可能已按保留期清理，不检查其 PrevHash
+func Verify(ctx context.Context, repo repository.AuditLogRepository) (*VerifyResult, error) {
+	const batch = 500
+	result := &VerifyResult{}
+	var prev *models.AuditLog
+	afterID := 0
+	for {
+		logs, err := repo.ListAfter(ctx, repository.AuditLogFilter{}, afterID, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range logs {
+			result.Checked++
+			if entry.Hash != "" {
+				result.Chained++
+				if ComputeHash(entry) != entry.Hash {
+					result.BrokenID = entry.ID
+					result.Reason = "记录内容与哈希不一致,可能已被修改"
+					return result, nil
+				}
+				if prev != nil && entry.PrevHash != prev.Hash {
+					result.BrokenID = entry.ID
+					result.Reason = "与上一条记录的哈希不衔接,中间记录可能已被删除或修改"
+					return result, nil
+				}
+			}
+			prev = entry
+			afterID = entry.ID
+		}
+		if len(logs) < batch {
+			return result, nil
+		}
+	}
+}
+
+// truncate 按字节截断字符串，不截断多字节字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"myobj/src/pkg/models"
+	"strconv"
+	"strings"
+)
+
+// 导出格式
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var csvHeader = []string{
+	"id", "created_at", "actor_id", "actor_name", "api_key_id", "source", "action",
+	"target_type", "target_id", "result", "ip", "detail", "prev_hash", "hash",
+}
+
+// Exporter 分批写出审计日志（CSV 或 JSON 数组）
+type Exporter struct {
+	w      io.Writer
+	format string
+	csv    *csv.Writer
+	count  int
+}
+
+// NewExporter 创建导出器，format 为 csv 或 json
+func NewExporter(w io.Writer, format string) (*Exporter, error) {
+	e := &Exporter{w: w, format: format}
+	switch format {
+	case FormatCSV:
+		e.csv = csv.NewWriter(w)
+		if err := e.csv.Write(csvHeader); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	return e, nil
+}
+
+// Write 写出一批记录
+func (e *Exporter) Write(logs []*models.AuditLog) error {
+	for _, entry := range logs {
+		if e.csv != nil {
+			record := []string{
+				strconv.Itoa(entry.ID),
+				entry.CreatedAt.Format("2006-01-02 15:04:05"),
+				entry.ActorID,
+				entry.ActorName,
+				strconv.Itoa(entry.APIKeyID),
+				entry.Source,
+				entry.Action,
+				entry.TargetType,
+				entry.TargetID,
+				entry.Result,
+				entry.IP,
+				entry.Detail,
+				entry.PrevHash,
+				entry.Hash,
+			}
+			for i := range record {
+				record[i] = csvSafe(record[i])
+			}
+			if err := e.csv.Write(record); err != nil {
+				return err
+			}
+		} else {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if e.count > 0 {
+				if _, err := io.WriteString(e.w, ","); err != nil {
+					return err
+				}
+			}
+			if _, err := e.w.Write(data); err != nil {
+				return err
+			}
+		}
+		e.count++
+	}
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+// Close 结束导出
+func (e *Exporter) Close() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	_, err := io.WriteString(e.w, "]")
+	return err
+}
+
+// csvSafe 避免文件名等内容在表格软件中被当作公式执行
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -1,3 +1,152 @@
 package auth
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"net"
+	"strconv"
+	"strings"
+)
+
 // ApiKeyHandler API密钥处理
+
+// ErrAPIKeyOutOfScope 请求的目录或文件不在 API Key 可访问的目录内
+var ErrAPIKeyOutOfScope = errors.New("API Key 无权访问该目录")
+
+// APIKeyScope API Key 的访问范围
+// 认证中间件在使用 API Key 认证时保存到 gin context 的 "apiKeyScope" 中，JWT 登录时不存在
+type APIKeyScope struct {
+	KeyID int
+	// 允许的权限标识，为空表示继承用户组全部权限
+	Scopes []string
+	// 可访问的目录ID，0 表示整个空间
+	RootDirID int
+}
+
+// NewAPIKeyScope 根据 API Key 记录创建访问范围
+func NewAPIKeyScope(key *models.ApiKey) *APIKeyScope {
+	return &APIKeyScope{
+		KeyID:     key.ID,
+		Scopes:    SplitList(key.Scopes),
+		RootDirID: key.RootDirID,
+	}
+}
+
+// Restricted 是否限制了权限或目录（受限的 API Key 不能用于 WebDAV / SFTP）
+func (s *APIKeyScope) Restricted() bool {
+	return len(s.Scopes) > 0 || s.RootDirID != 0
+}
+
+// Allows 是否允许使用指定权限
+func (s *APIKeyScope) Allows(characteristic string) bool {
+	if len(s.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range s.Scopes {
+		if scope == characteristic {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterPowers 用户组权限与 API Key 权限取交集
+func (s *APIKeyScope) FilterPowers(powers []*models.Power) []*models.Power {
+	if len(s.Scopes) == 0 {
+		return powers
+	}
+	filtered := make([]*models.Power, 0, len(s.Scopes))
+	for _, p := range powers {
+		if s.Allows(p.Characteristic) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// CheckDir 检查目录是否位于可访问的目录内（包含该目录本身）
+func (s *APIKeyScope) CheckDir(ctx context.Context, vpRepo repository.VirtualPathRepository, dirID int) error {
+	if s.RootDirID == 0 {
+		return nil
+	}
+	// 限制层级深度，防止异常数据导致死循环
+	for depth := 0; depth < 256; depth++ {
+		if dirID == s.RootDirID {
+			return nil
+		}
+		dir, err := vpRepo.GetByID(ctx, dirID)
+		if err != nil || dir.ParentLevel == "" {
+			return ErrAPIKeyOutOfScope
+		}
+		if dirID, err = strconv.Atoi(dir.ParentLevel); err != nil {
+			return ErrAPIKeyOutOfScope
+		}
+	}
+	return ErrAPIKeyOutOfScope
+}
+
+// CheckDirPath 检查以字符串保存的目录ID（如 UserFiles.VirtualPath、请求参数）是否位于可访问的目录内
+func (s *APIKeyScope) CheckDirPath(ctx context.Context, vpRepo repository.VirtualPathRepository, dirID string) error {
+	if s.RootDirID == 0 {
+		return nil
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(dirID))
+	if err != nil {
+		return ErrAPIKeyOutOfScope
+	}
+	return s.CheckDir(ctx, vpRepo, id)
+}
+
+// SplitList 拆分逗号分隔的列表，去除空白与重复项
+func SplitList(s string) []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		list = append(list, item)
+	}
+	return list
+}
+
+// ValidateIPAllowlist 校验 IP 白名单，每项为 IP 地址或 CIDR 网段
+func ValidateIPAllowlist(list []string) error {
+	for _, item := range list {
+		if strings.Contains(item, "/") {
+			if _, _, err := net.ParseCIDR(item); err != nil {
+				return fmt.Errorf("无效的网段: %s", item)
+			}
+		} else if net.ParseIP(item) == nil {
+			return fmt.Errorf("无效的 IP 地址: %s", item)
+		}
+	}
+	return nil
+}
+
+// IPAllowed 检查 IP 是否在白名单内（白名单为空表示不限制）
+func IPAllowed(allowlist, ip string) bool {
+	list := SplitList(allowlist)
+	if len(list) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, item := range list {
+		if strings.Contains(item, "/") {
+			if _, network, err := net.ParseCIDR(item); err == nil && network.Contains(addr) {
+				return true
+			}
+		} else if allowed := net.ParseIP(item); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+// createExternalUser 为外部账户（LDAP 目录、OIDC 单点登录）创建本地账户与根目录
+// user 需填写用户名、资料、用户组与账户来源，ID、创建时间与存储空间（取自用户组）在此生成
+func createExternalUser(
+	ctx context.Context,
+	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	virtualPathRepo repository.VirtualPathRepository,
+	user *models.UserInfo,
+) error {
+	group, err := groupRepo.GetByID(ctx, user.GroupID)
+	if err != nil {
+		logger.LOG.Error("查询用户组失败", "group_id", user.GroupID, "error", err)
+		return fmt.Errorf("用户组不存在")
+	}
+	if user.Name == "" {
+		user.Name = user.UserName
+	}
+	user.ID = uuid.Must(uuid.NewV7()).String()
+	user.Password = ""
+	user.CreatedAt = custom_type.Now()
+	user.Space = group.Space
+	user.FreeSpace = group.Space
+	user.State = 0
+	if err := userRepo.Create(ctx, user); err != nil {
+		logger.LOG.Error("创建外部账户失败", "username", user.UserName, "auth_source", user.AuthSource, "error", err)
+		return err
+	}
+	if err := virtualPathRepo.Create(ctx, &models.VirtualPath{
+		UserID:      user.ID,
+		Path:        "home",
+		ParentLevel: "",
+		CreatedTime: custom_type.Now(),
+		UpdateTime:  custom_type.Now(),
+	}); err != nil {
+		logger.LOG.Error("创建目录失败", "username", user.UserName, "error", err)
+		return err
+	}
+	return nil
+}
+
+// externalDefaultGroup 外部账户未命中组映射时使用的用户组：配置的 default_group_id，否则为系统默认组
+func externalDefaultGroup(ctx context.Context, groupRepo repository.GroupRepository, defaultGroupID int) (int, error) {
+	if defaultGroupID > 0 {
+		return defaultGroupID, nil
+	}
+	group, err := groupRepo.GetDefaultGroup(ctx)
+	if err != nil {
+		logger.LOG.Error("查询默认分组失败", "error", err)
+		return 0, fmt.Errorf("未配置默认用户组")
+	}
+	// 与注册一致：未显式映射时不允许进入管理员组
+	if group.ID == 1 {
+		return 0, fmt.Errorf("系统配置错误：默认组不能是管理员组，请联系管理员")
+	}
+	return group.ID, nil
+}
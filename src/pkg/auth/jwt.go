@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"myobj/src/config"
 	"myobj/src/core/domain/response"
+	"net"
 	"strings"
 	"time"
 
@@ -25,9 +26,9 @@ func GenerateJWT(userID string, sessionID string, userLogin response.UserLoginRe
 		sessionID,
 		userLogin,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(config.CONFIG.Auth.JwtExpire) * time.Hour)), // 过期时间
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                                                              // 签发时间
-			Issuer:    "wind",                                                                                      // 签发者
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(config.CONFIG.Auth.AccessTokenExpire) * time.Minute)), // 过期时间（与访问令牌一致）
+			IssuedAt:  jwt.NewNumericDate(time.Now()),                                                                        // 签发时间
+			Issuer:    "wind",                                                                                                // 签发者
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -54,8 +55,12 @@ func transition(token *jwt.Token) *CustomClaims {
 
 // GetCookieDomain 获取 Cookie 的 domain
 func GetCookieDomain(host string) string {
-	// 如果是本地开发环境
-	if strings.Contains(host, "localhost") || strings.Contains(host, "127.0.0.1") {
+	// 去掉端口
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	// 如果是本地开发环境或使用 IP 访问
+	if strings.Contains(host, "localhost") || net.ParseIP(host) != nil {
 		return "" // 不设置 domain
 	}
 
@@ -0,0 +1,475 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLDAPInvalidCredentials 目录账户密码错误
+	ErrLDAPInvalidCredentials = errors.New("密码错误")
+	// ErrLDAPUserNotFound 目录中不存在该用户
+	ErrLDAPUserNotFound = errors.New("用户不存在")
+	// ErrLDAPAccountDisabled 目录账户已被禁用
+	ErrLDAPAccountDisabled = errors.New("用户已被禁用")
+	// ErrLDAPUnavailable 目录服务连接失败
+	ErrLDAPUnavailable = errors.New("目录服务不可用,请稍后重试")
+)
+
+// adAccountDisabled AD userAccountControl 中的 ACCOUNTDISABLE 标志位
+const adAccountDisabled = 0x2
+
+// LDAPEntry 目录中的用户信息
+type LDAPEntry struct {
+	DN       string
+	Username string
+	Name     string
+	Email    string
+	Phone    string
+	// Groups 所属组的 DN
+	Groups []string
+	// Disabled 账户是否已在目录中禁用
+	Disabled bool
+}
+
+// LDAPProvider LDAP / Active Directory 目录访问
+// 认证流程：服务账号绑定 → 按过滤器搜索用户 DN → 使用用户 DN 与密码绑定校验
+type LDAPProvider struct {
+	cfg       config.LDAP
+	tlsConfig *tls.Config
+}
+
+// NewLDAPProvider 创建目录访问实例
+func NewLDAPProvider(cfg config.LDAP) *LDAPProvider {
+	p := &LDAPProvider{cfg: cfg}
+	p.tlsConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if u, err := url.Parse(cfg.URL); err == nil {
+		p.tlsConfig.ServerName = u.Hostname()
+	}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			logger.LOG.Error("读取 LDAP CA 证书失败", "path", cfg.CACert, "error", err)
+		} else {
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(pem)
+			p.tlsConfig.RootCAs = pool
+		}
+	}
+	return p
+}
+
+// Enabled 是否启用 LDAP 认证
+func (p *LDAPProvider) Enabled() bool {
+	return p.cfg.Enable
+}
+
+// Authenticate 校验目录账户密码，返回目录中的用户信息
+// 账户在目录中被禁用时返回 ErrLDAPAccountDisabled 与用户信息（用于同步本地状态）
+func (p *LDAPProvider) Authenticate(username, password string) (*LDAPEntry, error) {
+	// 空密码会被服务器当作匿名绑定而返回成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	e, err := p.searchUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := p.toEntry(conn, e, username)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Disabled {
+		return entry, ErrLDAPAccountDisabled
+	}
+
+	if err := conn.Bind(e.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		logger.LOG.Error("LDAP 用户绑定失败", "dn", e.DN, "error", err)
+		return nil, ErrLDAPUnavailable
+	}
+	return entry, nil
+}
+
+// Lookup 使用服务账号查询目录用户（不校验密码，用于定时同步）
+func (p *LDAPProvider) Lookup(username string) (*LDAPEntry, error) {
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	e, err := p.searchUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	return p.toEntry(conn, e, username)
+}
+
+// MapGroup 按配置顺序匹配 LDAP 组，返回第一个命中的 MyObj 用户组ID
+func (p *LDAPProvider) MapGroup(groups []string) (int, bool) {
+	for _, m := range p.cfg.GroupMapping {
+		for _, g := range groups {
+			if strings.EqualFold(g, m.LDAPGroup) || strings.EqualFold(groupCN(g), m.LDAPGroup) {
+				return m.GroupID, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// connect 建立连接（按配置启用 StartTLS）并使用服务账号绑定
+func (p *LDAPProvider) connect() (*ldap.Conn, error) {
+	timeout := time.Duration(p.cfg.Timeout) * time.Second
+	conn, err := ldap.DialURL(p.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(p.tlsConfig),
+	)
+	if err != nil {
+		logger.LOG.Error("连接 LDAP 服务器失败", "url", p.cfg.URL, "error", err)
+		return nil, ErrLDAPUnavailable
+	}
+	conn.SetTimeout(timeout)
+
+	if p.cfg.StartTLS && strings.HasPrefix(strings.ToLower(p.cfg.URL), "ldap://") {
+		if err := conn.StartTLS(p.tlsConfig); err != nil {
+			conn.Close()
+			logger.LOG.Error("LDAP StartTLS 失败", "url", p.cfg.URL, "error", err)
+			return nil, ErrLDAPUnavailable
+		}
+	}
+	if err := p.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bindService 使用服务账号绑定，未配置服务账号时保持匿名
+func (p *LDAPProvider) bindService(conn *ldap.Conn) error {
+	if p.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+		logger.LOG.Error("LDAP 服务账号绑定失败", "bind_dn", p.cfg.BindDN, "error", err)
+		return ErrLDAPUnavailable
+	}
+	return nil
+}
+
+// searchUser 按用户过滤器搜索用户条目，结果不唯一时视为不存在
+func (p *LDAPProvider) searchUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(p.cfg.UserFilter, "{username}", ldap.EscapeFilter(username))
+	attrs := []string{p.cfg.UsernameAttr, p.cfg.NameAttr, p.cfg.EmailAttr, "userAccountControl", "pwdAccountLockedTime"}
+	if p.cfg.PhoneAttr != "" {
+		attrs = append(attrs, p.cfg.PhoneAttr)
+	}
+	if p.cfg.MemberOfAttr != "" {
+		attrs = append(attrs, p.cfg.MemberOfAttr)
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, p.cfg.Timeout, false,
+		filter, attrs, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrLDAPUserNotFound
+		}
+		logger.LOG.Error("LDAP 搜索用户失败", "filter", filter, "error", err)
+		return nil, ErrLDAPUnavailable
+	}
+	if res == nil || len(res.Entries) != 1 {
+		if res != nil && len(res.Entries) > 1 {
+			logger.LOG.Warn("LDAP 搜索到多个同名用户，拒绝登录", "username", username)
+		}
+		return nil, ErrLDAPUserNotFound
+	}
+	return res.Entries[0], nil
+}
+
+// toEntry 读取用户属性、所属组与禁用状态，目录未返回用户名属性时使用登录名
+func (p *LDAPProvider) toEntry(conn *ldap.Conn, e *ldap.Entry, username string) (*LDAPEntry, error) {
+	entry := &LDAPEntry{
+		DN:       e.DN,
+		Username: e.GetAttributeValue(p.cfg.UsernameAttr),
+		Name:     e.GetAttributeValue(p.cfg.NameAttr),
+		Email:    e.GetAttributeValue(p.cfg.EmailAttr),
+	}
+	if p.cfg.PhoneAttr != "" {
+		entry.Phone = e.GetAttributeValue(p.cfg.PhoneAttr)
+	}
+	if entry.Username == "" {
+		entry.Username = username
+	}
+	if p.cfg.MemberOfAttr != "" {
+		entry.Groups = append(entry.Groups, e.GetAttributeValues(p.cfg.MemberOfAttr)...)
+	}
+
+	if p.cfg.GroupBaseDN != "" && p.cfg.GroupFilter != "" {
+		filter := strings.NewReplacer(
+			"{dn}", ldap.EscapeFilter(e.DN),
+			"{username}", ldap.EscapeFilter(entry.Username),
+		).Replace(p.cfg.GroupFilter)
+		res, err := conn.Search(ldap.NewSearchRequest(
+			p.cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, p.cfg.Timeout, false,
+			filter, []string{"cn"}, nil,
+		))
+		if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			logger.LOG.Error("LDAP 搜索用户组失败", "filter", filter, "error", err)
+			return nil, ErrLDAPUnavailable
+		}
+		if res != nil {
+			for _, g := range res.Entries {
+				entry.Groups = append(entry.Groups, g.DN)
+			}
+		}
+	}
+
+	disabled, err := p.isDisabled(conn, e)
+	if err != nil {
+		return nil, err
+	}
+	entry.Disabled = disabled
+	return entry, nil
+}
+
+// isDisabled 判断目录账户是否已禁用
+// AD: userAccountControl 含 ACCOUNTDISABLE 位；OpenLDAP ppolicy: 存在 pwdAccountLockedTime；
+// 其他目录通过 disabled_filter 配置（在用户条目上求值）
+func (p *LDAPProvider) isDisabled(conn *ldap.Conn, e *ldap.Entry) (bool, error) {
+	if uac, err := strconv.ParseInt(e.GetAttributeValue("userAccountControl"), 10, 64); err == nil && uac&adAccountDisabled != 0 {
+		return true, nil
+	}
+	if e.GetAttributeValue("pwdAccountLockedTime") != "" {
+		return true, nil
+	}
+	if p.cfg.DisabledFilter == "" {
+		return false, nil
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		e.DN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, p.cfg.Timeout, false,
+		p.cfg.DisabledFilter, []string{"1.1"}, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return false, nil
+		}
+		logger.LOG.Error("LDAP 查询账户禁用状态失败", "dn", e.DN, "error", err)
+		return false, ErrLDAPUnavailable
+	}
+	return len(res.Entries) > 0, nil
+}
+
+// groupCN 提取组 DN 的第一个 RDN 值（如 cn=admins,ou=groups,... → admins），不是 DN 时原样返回
+func groupCN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+// LDAPManager 目录账户管理：登录时自动创建本地账户，并同步资料、用户组与禁用状态
+// 目录账户在本地不保存密码（UserInfo.AuthSource = ldap），同名的本地账户优先，不会被目录接管
+type LDAPManager struct {
+	provider        *LDAPProvider
+	userRepo        repository.UserRepository
+	groupRepo       repository.GroupRepository
+	virtualPathRepo repository.VirtualPathRepository
+	sessionRepo     repository.UserSessionRepository
+}
+
+// NewLDAPManager 创建目录账户管理器
+func NewLDAPManager(
+	cfg config.LDAP,
+	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	virtualPathRepo repository.VirtualPathRepository,
+	sessionRepo repository.UserSessionRepository,
+) *LDAPManager {
+	return &LDAPManager{
+		provider:        NewLDAPProvider(cfg),
+		userRepo:        userRepo,
+		groupRepo:       groupRepo,
+		virtualPathRepo: virtualPathRepo,
+		sessionRepo:     sessionRepo,
+	}
+}
+
+// Handles 是否由目录校验该账户的密码（user 为 nil 表示本地不存在该用户）
+func (m *LDAPManager) Handles(user *models.UserInfo) bool {
+	return m.provider.Enabled() && (user == nil || user.AuthSource == models.AuthSourceLDAP)
+}
+
+// Login 校验目录账户密码，首次登录自动创建本地账户，每次登录同步资料与用户组
+func (m *LDAPManager) Login(ctx context.Context, username, password string) (*models.UserInfo, error) {
+	user, err := m.userRepo.GetByUserName(ctx, username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user != nil && user.AuthSource != models.AuthSourceLDAP {
+		return nil, fmt.Errorf("本地账户不能使用目录密码登录")
+	}
+
+	entry, err := m.provider.Authenticate(username, password)
+	if err != nil {
+		if user != nil && (errors.Is(err, ErrLDAPAccountDisabled) || errors.Is(err, ErrLDAPUserNotFound)) {
+			m.disable(ctx, user, err.Error())
+		}
+		return nil, err
+	}
+
+	if user == nil {
+		return m.provision(ctx, entry)
+	}
+	if user.State == 1 {
+		return nil, ErrLDAPAccountDisabled
+	}
+	if err := m.apply(ctx, user, entry); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Sync 同步单个目录账户：目录中已删除或禁用的账户在本地禁用并注销会话，其余同步资料与用户组
+func (m *LDAPManager) Sync(ctx context.Context, user *models.UserInfo) error {
+	entry, err := m.provider.Lookup(user.UserName)
+	if err != nil {
+		if errors.Is(err, ErrLDAPUserNotFound) {
+			m.disable(ctx, user, err.Error())
+			return nil
+		}
+		return err
+	}
+	if entry.Disabled {
+		m.disable(ctx, user, ErrLDAPAccountDisabled.Error())
+		return nil
+	}
+	return m.apply(ctx, user, entry)
+}
+
+// SyncAll 同步所有目录账户，返回同步成功的数量
+func (m *LDAPManager) SyncAll(ctx context.Context) (int, error) {
+	if !m.provider.Enabled() {
+		return 0, nil
+	}
+	users, err := m.userRepo.ListByAuthSource(ctx, models.AuthSourceLDAP)
+	if err != nil {
+		return 0, err
+	}
+	synced := 0
+	for _, user := range users {
+		if err := m.Sync(ctx, user); err != nil {
+			// 目录服务不可用时中止，避免把所有账户误判为异常
+			if errors.Is(err, ErrLDAPUnavailable) {
+				return synced, err
+			}
+			logger.LOG.Warn("同步目录账户失败", "username", user.UserName, "error", err)
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// provision 首次登录时创建本地账户（不保存密码）与根目录
+func (m *LDAPManager) provision(ctx context.Context, entry *LDAPEntry) (*models.UserInfo, error) {
+	groupID, err := m.resolveGroup(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.UserInfo{
+		Name:       entry.Name,
+		UserName:   entry.Username,
+		Email:      entry.Email,
+		Phone:      entry.Phone,
+		GroupID:    groupID,
+		AuthSource: models.AuthSourceLDAP,
+	}
+	if err := createExternalUser(ctx, m.userRepo, m.groupRepo, m.virtualPathRepo, user); err != nil {
+		return nil, err
+	}
+	logger.LOG.Info("目录账户首次登录，已创建本地账户", "username", entry.Username, "dn", entry.DN, "group_id", groupID)
+	return user, nil
+}
+
+// apply 同步目录中的资料与用户组
+// 配置了组映射时以目录为准（未命中映射的用户回到默认组），未配置时不修改用户组
+func (m *LDAPManager) apply(ctx context.Context, user *models.UserInfo, entry *LDAPEntry) error {
+	changed := false
+	if entry.Name != "" && entry.Name != user.Name {
+		user.Name, changed = entry.Name, true
+	}
+	if entry.Email != user.Email {
+		user.Email, changed = entry.Email, true
+	}
+	if entry.Phone != user.Phone {
+		user.Phone, changed = entry.Phone, true
+	}
+	if len(m.provider.cfg.GroupMapping) > 0 {
+		groupID, err := m.resolveGroup(ctx, entry)
+		if err != nil {
+			return err
+		}
+		if groupID != user.GroupID {
+			logger.LOG.Info("目录账户用户组已变更", "username", user.UserName, "from", user.GroupID, "to", groupID)
+			user.GroupID, changed = groupID, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := m.userRepo.Update(ctx, user); err != nil {
+		logger.LOG.Error("同步目录账户失败", "username", user.UserName, "error", err)
+		return err
+	}
+	return nil
+}
+
+// resolveGroup 根据组映射确定用户组，未命中时使用 default_group_id 或系统默认组
+func (m *LDAPManager) resolveGroup(ctx context.Context, entry *LDAPEntry) (int, error) {
+	if groupID, ok := m.provider.MapGroup(entry.Groups); ok {
+		return groupID, nil
+	}
+	return externalDefaultGroup(ctx, m.groupRepo, m.provider.cfg.DefaultGroupID)
+}
+
+// disable 在本地禁用目录账户并注销其所有会话（目录恢复后需管理员手动解封）
+func (m *LDAPManager) disable(ctx context.Context, user *models.UserInfo, reason string) {
+	if user.State == 1 {
+		return
+	}
+	user.State = 1
+	if err := m.userRepo.Update(ctx, user); err != nil {
+		logger.LOG.Error("禁用目录账户失败", "username", user.UserName, "error", err)
+		return
+	}
+	if _, err := m.sessionRepo.DeleteByUserID(ctx, user.ID, ""); err != nil {
+		logger.LOG.Warn("注销目录账户会话失败", "username", user.UserName, "error", err)
+	}
+	logger.LOG.Warn("目录账户已在本地禁用", "username", user.UserName, "reason", reason)
+}
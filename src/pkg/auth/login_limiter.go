@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 登录限制来源渠道
+const (
+	LimitChannelWeb    = "web"
+	LimitChannelWebDAV = "webdav"
+	LimitChannelSFTP   = "sftp"
+	LimitChannelShare  = "share"
+	LimitChannelAdmin  = "admin"
+	LimitChannelCLI    = "cli"
+)
+
+const (
+	loginLimitPrefix = "login_limit:"
+	// maxBackoff 连续失败时单次等待时间上限
+	maxBackoff = 30 * time.Second
+)
+
+// LimitError 失败次数过多，需要等待后重试
+type LimitError struct {
+	// RetryAfter 需要等待的时间
+	RetryAfter time.Duration
+	// Locked 是否处于锁定状态（否则为失败后的退避等待）
+	Locked bool
+}
+
+func (e *LimitError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("失败次数过多,已临时锁定,请%d分钟后重试", int((e.RetryAfter+time.Minute-1)/time.Minute))
+	}
+	return fmt.Sprintf("操作过于频繁,请%d秒后重试", int((e.RetryAfter+time.Second-1)/time.Second))
+}
+
+// limitState 单个计数对象的状态，以 "失败次数|首次失败|最近失败|锁定截止|锁定时间|锁定次数"（时间为 Unix 毫秒）保存到缓存
+type limitState struct {
+	failures    int
+	firstAt     int64
+	lastAt      int64
+	lockedUntil int64
+	lockedAt    int64
+	locks       int
+}
+
+func (s *limitState) encode() string {
+	return fmt.Sprintf("%d|%d|%d|%d|%d|%d", s.failures, s.firstAt, s.lastAt, s.lockedUntil, s.lockedAt, s.locks)
+}
+
+func decodeLimitState(value string) (*limitState, bool) {
+	parts := strings.Split(value, "|")
+	if len(parts) != 6 {
+		return nil, false
+	}
+	nums := make([]int64, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return &limitState{
+		failures:    int(nums[0]),
+		firstAt:     nums[1],
+		lastAt:      nums[2],
+		lockedUntil: nums[3],
+		lockedAt:    nums[4],
+		locks:       int(nums[5]),
+	}, true
+}
+
+// LoginLimiter 登录失败限制器（网页登录、WebDAV、SFTP 与分享密码共用）
+// 按用户名、IP 与分享分别计数，状态保存在缓存中；连续失败时按 1、2、4… 秒退避，
+// 统计窗口内达到上限后临时锁定，再次锁定时锁定时长加倍。
+// 缓存接口不提供原子递增，并发失败时计数可能略少，不影响限制效果
+type LoginLimiter struct {
+	cache     cache.Cache
+	eventRepo repository.SecurityEventRepository
+	cfg       config.LoginLimit
+}
+
+// NewLoginLimiter 创建登录失败限制器
+func NewLoginLimiter(cacheStore cache.Cache, eventRepo repository.SecurityEventRepository, cfg config.LoginLimit) *LoginLimiter {
+	return &LoginLimiter{
+		cache:     cacheStore,
+		eventRepo: eventRepo,
+		cfg:       cfg,
+	}
+}
+
+func userLimitKey(username string) string {
+	return loginLimitPrefix + "user:" + strings.ToLower(username)
+}
+
+func ipLimitKey(ip string) string {
+	return loginLimitPrefix + "ip:" + ip
+}
+
+func shareLimitKey(token string) string {
+	return loginLimitPrefix + "share:" + token
+}
+
+// CheckLogin 登录前检查账户与 IP 是否处于锁定或退避等待中
+func (l *LoginLimiter) CheckLogin(ctx context.Context, username, ip string) error {
+	if !l.cfg.Enable {
+		return nil
+	}
+	if err := l.check(ctx, ipLimitKey(ip), ""); err != nil {
+		return err
+	}
+	return l.check(ctx, userLimitKey(username), username)
+}
+
+// LoginFailed 记录登录失败（用户不存在时同样计数，避免通过锁定行为判断账户是否存在）
+func (l *LoginLimiter) LoginFailed(ctx context.Context, channel, username, ip, detail string) {
+	if !l.cfg.Enable {
+		return
+	}
+	l.recordEvent(ctx, models.SecurityEventLoginFailed, channel, username, ip, detail)
+	if lock := l.fail(ipLimitKey(ip), l.cfg.IPMaxAttempts); lock > 0 {
+		l.recordEvent(ctx, models.SecurityEventLocked, channel, "", ip, fmt.Sprintf("IP 登录失败次数过多,锁定%d分钟", int(lock/time.Minute)))
+	}
+	if lock := l.fail(userLimitKey(username), l.cfg.MaxAttempts); lock > 0 {
+		l.recordEvent(ctx, models.SecurityEventLocked, channel, username, ip, fmt.Sprintf("账户登录失败次数过多,锁定%d分钟", int(lock/time.Minute)))
+	}
+}
+
+// LoginSucceeded 登录成功后清除账户的失败记录
+// IP 的失败记录不清除，避免攻击者用自己的账户登录来重置计数
+func (l *LoginLimiter) LoginSucceeded(username string) {
+	if !l.cfg.Enable {
+		return
+	}
+	_ = l.cache.Delete(userLimitKey(username))
+}
+
+// CheckShare 校验分享密码前检查分享与 IP 是否处于锁定或退避等待中
+func (l *LoginLimiter) CheckShare(ctx context.Context, token, ip string) error {
+	if !l.cfg.Enable {
+		return nil
+	}
+	if err := l.check(ctx, ipLimitKey(ip), ""); err != nil {
+		return err
+	}
+	return l.check(ctx, shareLimitKey(token), "")
+}
+
+// ShareFailed 记录分享密码错误
+func (l *LoginLimiter) ShareFailed(ctx context.Context, token, ip string) {
+	if !l.cfg.Enable {
+		return
+	}
+	l.recordEvent(ctx, models.SecurityEventSharePasswordFailed, LimitChannelShare, token, ip, "分享密码错误")
+	if lock := l.fail(ipLimitKey(ip), l.cfg.IPMaxAttempts); lock > 0 {
+		l.recordEvent(ctx, models.SecurityEventLocked, LimitChannelShare, "", ip, fmt.Sprintf("IP 分享密码错误次数过多,锁定%d分钟", int(lock/time.Minute)))
+	}
+	if lock := l.fail(shareLimitKey(token), l.cfg.ShareMaxAttempts); lock > 0 {
+		l.recordEvent(ctx, models.SecurityEventLocked, LimitChannelShare, token, ip, fmt.Sprintf("分享密码错误次数过多,锁定%d分钟", int(lock/time.Minute)))
+	}
+}
+
+// Unlock 解除账户锁定并记录解锁事件
+// 解锁事件保存在数据库中（对象为小写用户名）：CLI 等独立进程无法清除服务端的本地缓存，服务端检查锁定时发现更新的解锁事件即解除锁定
+func (l *LoginLimiter) Unlock(ctx context.Context, username, channel, operator string) error {
+	_ = l.cache.Delete(userLimitKey(username))
+	err := l.eventRepo.Create(ctx, &models.SecurityEvent{
+		Type:      models.SecurityEventUnlocked,
+		Channel:   channel,
+		Target:    strings.ToLower(username),
+		Detail:    "操作人: " + operator,
+		CreatedAt: custom_type.Now(),
+	})
+	if err != nil {
+		logger.LOG.Error("记录解锁事件失败", "username", username, "error", err)
+		return err
+	}
+	logger.LOG.Info("账户已解除锁定", "username", username, "operator", operator, "channel", channel)
+	return nil
+}
+
+// check 检查计数对象是否处于锁定或退避等待中，username 不为空时检查是否已被管理员解锁
+func (l *LoginLimiter) check(ctx context.Context, key, username string) error {
+	state := l.load(key)
+	if state == nil {
+		return nil
+	}
+	now := time.Now()
+	if state.lockedUntil > now.UnixMilli() {
+		if username != "" && l.unlockedSince(ctx, username, state.lockedAt) {
+			_ = l.cache.Delete(key)
+			return nil
+		}
+		return &LimitError{RetryAfter: time.UnixMilli(state.lockedUntil).Sub(now), Locked: true}
+	}
+	if state.failures > 0 && now.UnixMilli()-state.firstAt < l.window().Milliseconds() {
+		retryAt := time.UnixMilli(state.lastAt).Add(backoff(state.failures))
+		if wait := retryAt.Sub(now); wait > 0 {
+			return &LimitError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// fail 失败次数加一，达到上限时锁定并返回锁定时长，否则返回 0
+func (l *LoginLimiter) fail(key string, maxAttempts int) time.Duration {
+	now := time.Now().UnixMilli()
+	state := l.load(key)
+	if state == nil {
+		state = &limitState{}
+	}
+	// 超过统计窗口或上次锁定已结束时重新计数，锁定次数保留用于加倍锁定时长
+	if state.failures == 0 || now-state.firstAt >= l.window().Milliseconds() {
+		state.failures = 0
+		state.firstAt = now
+	}
+	state.failures++
+	state.lastAt = now
+
+	var lock time.Duration
+	if state.failures >= maxAttempts {
+		state.locks++
+		lock = l.lockDuration(state.locks)
+		state.failures = 0
+		state.lockedAt = now
+		state.lockedUntil = now + lock.Milliseconds()
+	}
+	// 保留到最长锁定结束之后，用于连续锁定时加倍锁定时长
+	ttl := (l.cfg.Window + l.cfg.MaxLockMinutes) * 60
+	if err := l.cache.Set(key, state.encode(), ttl); err != nil {
+		logger.LOG.Error("保存登录失败记录失败", "key", key, "error", err)
+	}
+	return lock
+}
+
+// window 统计窗口
+func (l *LoginLimiter) window() time.Duration {
+	return time.Duration(l.cfg.Window) * time.Minute
+}
+
+// lockDuration 第 n 次锁定的时长（首次为 LockMinutes，之后逐次加倍，不超过 MaxLockMinutes）
+func (l *LoginLimiter) lockDuration(locks int) time.Duration {
+	minutes := l.cfg.LockMinutes
+	for i := 1; i < locks && minutes < l.cfg.MaxLockMinutes; i++ {
+		minutes *= 2
+	}
+	if minutes > l.cfg.MaxLockMinutes {
+		minutes = l.cfg.MaxLockMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// backoff 第 n 次失败后的等待时间（1、2、4… 秒，不超过 maxBackoff）
+func backoff(failures int) time.Duration {
+	wait := time.Second
+	for i := 1; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+func (l *LoginLimiter) load(key string) *limitState {
+	value, err := l.cache.Get(key)
+	if err != nil {
+		return nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	state, ok := decodeLimitState(str)
+	if !ok {
+		return nil
+	}
+	return state
+}
+
+// unlockedSince 账户在锁定之后是否被管理员解锁（包括 CLI 等其他进程）
+func (l *LoginLimiter) unlockedSince(ctx context.Context, username string, lockedAt int64) bool {
+	event, err := l.eventRepo.GetLatest(ctx, models.SecurityEventUnlocked, strings.ToLower(username))
+	if err != nil {
+		return false
+	}
+	// 数据库时间可能只精确到秒，同一秒内的解锁视为在锁定之后
+	return event.CreatedAt.Unix() >= lockedAt/1000
+}
+
+// recordEvent 记录安全事件，写入失败只记录日志，不影响认证流程
+func (l *LoginLimiter) recordEvent(ctx context.Context, eventType, channel, target, ip, detail string) {
+	if len(detail) > 500 {
+		detail = detail[:500]
+	}
+	err := l.eventRepo.Create(ctx, &models.SecurityEvent{
+		Type:      eventType,
+		Channel:   channel,
+		Target:    target,
+		IP:        ip,
+		Detail:    detail,
+		CreatedAt: custom_type.Now(),
+	})
+	if err != nil {
+		logger.LOG.Error("记录安全事件失败", "type", eventType, "target", target, "error", err)
+	}
+	if eventType == models.SecurityEventLocked {
+		logger.LOG.Warn("失败次数过多,已临时锁定", "channel", channel, "target", target, "ip", ip, "detail", detail)
+	}
+}
@@ -0,0 +1,465 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOIDCUnavailable 身份提供方不可用（发现文档或令牌端点访问失败）
+	ErrOIDCUnavailable = errors.New("单点登录服务不可用,请稍后重试")
+	// ErrOIDCInvalidToken ID Token 校验失败
+	ErrOIDCInvalidToken = errors.New("单点登录验证失败")
+	// ErrOIDCNotLinked 身份未绑定账户且未开启自动创建
+	ErrOIDCNotLinked = errors.New("该身份未绑定账户,请先使用密码登录后绑定")
+	// ErrOIDCUsernameTaken 自动创建账户时用户名已被占用
+	ErrOIDCUsernameTaken = errors.New("用户名已被占用,请使用密码登录后绑定,或联系管理员")
+	// ErrOIDCLinkedToOther 身份已绑定其他账户
+	ErrOIDCLinkedToOther = errors.New("该身份已绑定其他账户")
+)
+
+// oidcHTTPTimeout 访问身份提供方的超时时间
+const oidcHTTPTimeout = 10 * time.Second
+
+// oidcProviders 已完成发现的身份提供方（按 issuer 缓存，发现失败时下次请求重试）
+var oidcProviders = struct {
+	sync.Mutex
+	m map[string]*oidc.Provider
+}{m: make(map[string]*oidc.Provider)}
+
+// OIDCClaims 从 ID Token（及 UserInfo 端点）中读取的用户信息
+type OIDCClaims struct {
+	Issuer        string
+	Subject       string
+	Username      string
+	Name          string
+	Email         string
+	EmailVerified bool
+	Phone         string
+	Groups        []string
+}
+
+// OIDCProvider OpenID Connect 身份提供方访问
+// 使用授权码模式 + PKCE（S256），通过发现文档获取端点与签名公钥，校验 ID Token 的签名、issuer、audience、有效期与 nonce
+type OIDCProvider struct {
+	cfg    config.OIDC
+	client *http.Client
+}
+
+// NewOIDCProvider 创建身份提供方访问实例
+func NewOIDCProvider(cfg config.OIDC) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// Enabled 是否启用 OIDC 单点登录
+func (p *OIDCProvider) Enabled() bool {
+	return p.cfg.Enable
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址
+// state 防止 CSRF，nonce 绑定 ID Token，verifier 为 PKCE 校验码（只发送其 S256 摘要）
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauthConfig, _, err := p.oauthConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 使用授权码换取令牌并校验 ID Token，返回用户信息
+// ID Token 中缺少用户名、邮箱或组声明时，从 UserInfo 端点补充
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*OIDCClaims, error) {
+	oauthConfig, provider, err := p.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = oidc.ClientContext(ctx, p.client)
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		logger.LOG.Warn("OIDC 授权码换取令牌失败", "error", err)
+		return nil, ErrOIDCInvalidToken
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		logger.LOG.Warn("OIDC 令牌响应中缺少 id_token")
+		return nil, ErrOIDCInvalidToken
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		logger.LOG.Warn("OIDC ID Token 校验失败", "error", err)
+		return nil, ErrOIDCInvalidToken
+	}
+	if idToken.Nonce != nonce {
+		logger.LOG.Warn("OIDC ID Token nonce 不匹配", "subject", idToken.Subject)
+		return nil, ErrOIDCInvalidToken
+	}
+
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		logger.LOG.Warn("解析 OIDC 声明失败", "error", err)
+		return nil, ErrOIDCInvalidToken
+	}
+	if p.missingClaims(claims) && provider.UserInfoEndpoint() != "" {
+		p.mergeUserInfo(ctx, provider, token, idToken.Subject, claims)
+	}
+
+	result := &OIDCClaims{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Username:      claimString(claims, p.cfg.UsernameClaim),
+		Name:          claimString(claims, p.cfg.NameClaim),
+		Email:         claimString(claims, p.cfg.EmailClaim),
+		EmailVerified: claimBool(claims, "email_verified"),
+		Phone:         claimString(claims, p.cfg.PhoneClaim),
+		Groups:        claimStrings(claims, p.cfg.GroupsClaim),
+	}
+	if result.Username == "" {
+		result.Username = result.Subject
+	}
+	return result, nil
+}
+
+// MapGroup 按配置顺序匹配组声明，返回第一个命中的 MyObj 用户组ID
+// Keycloak 的组声明带有路径前缀（如 /admins），比较时忽略开头的 /
+func (p *OIDCProvider) MapGroup(groups []string) (int, bool) {
+	for _, m := range p.cfg.GroupMapping {
+		for _, g := range groups {
+			if strings.EqualFold(strings.TrimPrefix(g, "/"), strings.TrimPrefix(m.Group, "/")) {
+				return m.GroupID, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// oauthConfig 获取（必要时发现）身份提供方并生成 OAuth2 客户端配置
+func (p *OIDCProvider) oauthConfig(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	scopes := []string{oidc.ScopeOpenID}
+	for _, s := range p.cfg.Scopes {
+		if s != oidc.ScopeOpenID {
+			scopes = append(scopes, s)
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       scopes,
+	}, provider, nil
+}
+
+// discover 读取发现文档，成功后按 issuer 缓存
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	oidcProviders.Lock()
+	defer oidcProviders.Unlock()
+	if provider, ok := oidcProviders.m[p.cfg.Issuer]; ok {
+		return provider, nil
+	}
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.cfg.Issuer)
+	if err != nil {
+		logger.LOG.Error("OIDC 发现文档读取失败", "issuer", p.cfg.Issuer, "error", err)
+		return nil, ErrOIDCUnavailable
+	}
+	oidcProviders.m[p.cfg.Issuer] = provider
+	return provider, nil
+}
+
+// missingClaims ID Token 中是否缺少配置的用户名、邮箱或组声明
+func (p *OIDCProvider) missingClaims(claims map[string]any) bool {
+	for _, key := range []string{p.cfg.UsernameClaim, p.cfg.EmailClaim, p.cfg.GroupsClaim} {
+		if key == "" {
+			continue
+		}
+		if _, ok := claims[key]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUserInfo 从 UserInfo 端点补充 ID Token 中缺少的声明（sub 不一致时忽略）
+func (p *OIDCProvider) mergeUserInfo(ctx context.Context, provider *oidc.Provider, token *oauth2.Token, subject string, claims map[string]any) {
+	info, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err != nil {
+		logger.LOG.Warn("OIDC UserInfo 请求失败", "error", err)
+		return
+	}
+	if info.Subject != subject {
+		logger.LOG.Warn("OIDC UserInfo sub 与 ID Token 不一致", "subject", subject, "userinfo_subject", info.Subject)
+		return
+	}
+	extra := map[string]any{}
+	if err := info.Claims(&extra); err != nil {
+		return
+	}
+	for k, v := range extra {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+}
+
+// claimString 读取字符串声明
+func claimString(claims map[string]any, key string) string {
+	if key == "" {
+		return ""
+	}
+	switch v := claims[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// claimBool 读取布尔声明（部分身份提供方以字符串返回）
+func claimBool(claims map[string]any, key string) bool {
+	switch v := claims[key].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// claimStrings 读取字符串数组声明（单个字符串视为只有一个元素）
+func claimStrings(claims map[string]any, key string) []string {
+	if key == "" {
+		return nil
+	}
+	switch v := claims[key].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// OIDCManager 单点登录账户管理：按 issuer + subject 查找绑定的账户，
+// 首次登录时按已验证邮箱绑定已有账户或自动创建账户，每次登录同步单点登录账户的资料与用户组
+type OIDCManager struct {
+	provider        *OIDCProvider
+	userRepo        repository.UserRepository
+	groupRepo       repository.GroupRepository
+	virtualPathRepo repository.VirtualPathRepository
+	identityRepo    repository.UserIdentityRepository
+}
+
+// NewOIDCManager 创建单点登录账户管理器
+func NewOIDCManager(
+	cfg config.OIDC,
+	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	virtualPathRepo repository.VirtualPathRepository,
+	identityRepo repository.UserIdentityRepository,
+) *OIDCManager {
+	return &OIDCManager{
+		provider:        NewOIDCProvider(cfg),
+		userRepo:        userRepo,
+		groupRepo:       groupRepo,
+		virtualPathRepo: virtualPathRepo,
+		identityRepo:    identityRepo,
+	}
+}
+
+// Provider 获取身份提供方访问实例
+func (m *OIDCManager) Provider() *OIDCProvider {
+	return m.provider
+}
+
+// Login 根据单点登录身份获取（绑定或创建）本地账户
+func (m *OIDCManager) Login(ctx context.Context, claims *OIDCClaims) (*models.UserInfo, error) {
+	identity, err := m.identityRepo.GetBySubject(ctx, claims.Issuer, claims.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var user *models.UserInfo
+	if identity != nil {
+		if user, err = m.userRepo.GetByID(ctx, identity.UserID); err != nil {
+			logger.LOG.Error("单点登录绑定的账户不存在", "user_id", identity.UserID, "error", err)
+			return nil, fmt.Errorf("用户不存在")
+		}
+		if err := m.identityRepo.UpdateLastLogin(ctx, identity.ID); err != nil {
+			logger.LOG.Warn("更新单点登录时间失败", "identity_id", identity.ID, "error", err)
+		}
+		if user.AuthSource == models.AuthSourceOIDC {
+			if err := m.apply(ctx, user, claims); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if user, err = m.firstLogin(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.State == 1 {
+		return nil, fmt.Errorf("用户已被禁用")
+	}
+	return user, nil
+}
+
+// Link 将单点登录身份绑定到已登录的账户
+func (m *OIDCManager) Link(ctx context.Context, userID string, claims *OIDCClaims) error {
+	identity, err := m.identityRepo.GetBySubject(ctx, claims.Issuer, claims.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if identity != nil {
+		if identity.UserID != userID {
+			return ErrOIDCLinkedToOther
+		}
+		return nil
+	}
+	return m.link(ctx, userID, claims)
+}
+
+// firstLogin 身份首次登录：按已验证邮箱绑定已有账户，否则自动创建账户
+func (m *OIDCManager) firstLogin(ctx context.Context, claims *OIDCClaims) (*models.UserInfo, error) {
+	cfg := m.provider.cfg
+	if cfg.LinkByEmail && claims.EmailVerified && claims.Email != "" {
+		users, err := m.userRepo.ListByEmail(ctx, claims.Email)
+		if err != nil {
+			return nil, err
+		}
+		// 同一邮箱对应多个账户时无法确定绑定对象，不自动绑定
+		if len(users) == 1 {
+			if err := m.link(ctx, users[0].ID, claims); err != nil {
+				return nil, err
+			}
+			logger.LOG.Info("单点登录身份已按邮箱绑定账户", "user_id", users[0].ID, "subject", claims.Subject)
+			return users[0], nil
+		}
+	}
+	if !cfg.AutoCreate {
+		return nil, ErrOIDCNotLinked
+	}
+
+	if _, err := m.userRepo.GetByUserName(ctx, claims.Username); err == nil {
+		return nil, ErrOIDCUsernameTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	groupID, err := m.resolveGroup(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.UserInfo{
+		Name:       claims.Name,
+		UserName:   claims.Username,
+		Email:      claims.Email,
+		Phone:      claims.Phone,
+		GroupID:    groupID,
+		AuthSource: models.AuthSourceOIDC,
+	}
+	if err := createExternalUser(ctx, m.userRepo, m.groupRepo, m.virtualPathRepo, user); err != nil {
+		return nil, err
+	}
+	if err := m.link(ctx, user.ID, claims); err != nil {
+		return nil, err
+	}
+	logger.LOG.Info("单点登录首次登录，已创建本地账户", "username", user.UserName, "subject", claims.Subject, "group_id", groupID)
+	return user, nil
+}
+
+// link 创建身份绑定记录
+func (m *OIDCManager) link(ctx context.Context, userID string, claims *OIDCClaims) error {
+	if err := m.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:      userID,
+		Issuer:      claims.Issuer,
+		Subject:     claims.Subject,
+		Username:    claims.Username,
+		Email:       claims.Email,
+		CreatedAt:   custom_type.Now(),
+		LastLoginAt: custom_type.Now(),
+	}); err != nil {
+		logger.LOG.Error("创建单点登录绑定失败", "user_id", userID, "subject", claims.Subject, "error", err)
+		return err
+	}
+	return nil
+}
+
+// apply 同步单点登录账户的资料与用户组
+// 配置了组映射时以组声明为准（未命中映射的用户回到默认组），未配置时不修改用户组；绑定的本地账户不做同步
+func (m *OIDCManager) apply(ctx context.Context, user *models.UserInfo, claims *OIDCClaims) error {
+	changed := false
+	if claims.Name != "" && claims.Name != user.Name {
+		user.Name, changed = claims.Name, true
+	}
+	if claims.Email != "" && claims.Email != user.Email {
+		user.Email, changed = claims.Email, true
+	}
+	if claims.Phone != "" && claims.Phone != user.Phone {
+		user.Phone, changed = claims.Phone, true
+	}
+	if len(m.provider.cfg.GroupMapping) > 0 {
+		groupID, err := m.resolveGroup(ctx, claims)
+		if err != nil {
+			return err
+		}
+		if groupID != user.GroupID {
+			logger.LOG.Info("单点登录账户用户组已变更", "username", user.UserName, "from", user.GroupID, "to", groupID)
+			user.GroupID, changed = groupID, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := m.userRepo.Update(ctx, user); err != nil {
+		logger.LOG.Error("同步单点登录账户失败", "username", user.UserName, "error", err)
+		return err
+	}
+	return nil
+}
+
+// resolveGroup 根据组映射确定用户组，未命中时使用 default_group_id 或系统默认组
+func (m *OIDCManager) resolveGroup(ctx context.Context, claims *OIDCClaims) (int, error) {
+	if groupID, ok := m.provider.MapGroup(claims.Groups); ok {
+		return groupID, nil
+	}
+	return externalDefaultGroup(ctx, m.groupRepo, m.provider.cfg.DefaultGroupID)
+}
+
+// LocalLoginDisabled 用户所在组是否禁止本地密码登录（只影响本地账户的登录密码，不影响应用专用密码与 API Key）
+func LocalLoginDisabled(ctx context.Context, groupRepo repository.GroupRepository, groupID int) (bool, error) {
+	group, err := groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return group.DisableLocalLogin, nil
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// PasswordPolicyKey 登录密码策略在 sys_config 中的键
+	PasswordPolicyKey = "password_policy"
+	// FilePasswordPolicyKey 文件密码策略在 sys_config 中的键
+	FilePasswordPolicyKey = "file_password_policy"
+
+	// 策略取值范围
+	maxPasswordLength   = 128
+	maxPasswordHistory  = 24
+	maxPasswordAgeDays  = 3650
+	defaultPasswordSize = 6
+)
+
+var (
+	// ErrPasswordReused 新密码与最近使用过的密码相同
+	ErrPasswordReused = errors.New("不能使用最近使用过的密码")
+	// ErrPasswordCommon 密码在常见弱密码列表中
+	ErrPasswordCommon = errors.New("密码过于常见,请更换")
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// isCommonPassword 是否为常见弱密码（不区分大小写）
+func isCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			commonPasswords[strings.ToLower(line)] = struct{}{}
+		}
+	})
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+// PasswordPolicy 密码策略
+// 登录密码与文件密码各自独立配置，以 JSON 形式保存在 sys_config 中
+type PasswordPolicy struct {
+	// 最小长度
+	MinLength int `json:"min_length"`
+	// 必须包含大写字母
+	RequireUpper bool `json:"require_upper"`
+	// 必须包含小写字母
+	RequireLower bool `json:"require_lower"`
+	// 必须包含数字
+	RequireDigit bool `json:"require_digit"`
+	// 必须包含特殊字符
+	RequireSymbol bool `json:"require_symbol"`
+	// 禁止使用常见弱密码
+	DisallowCommon bool `json:"disallow_common"`
+	// 禁止重复使用最近 N 次的密码，0 表示不限制
+	HistoryCount int `json:"history_count"`
+	// 密码最长有效天数，0 表示永不过期
+	MaxAgeDays int `json:"max_age_days"`
+}
+
+// DefaultPasswordPolicy 默认密码策略（与引入策略前的最小长度保持一致）
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: defaultPasswordSize}
+}
+
+// Validate 校验策略本身的取值是否合法
+func (p *PasswordPolicy) Validate() error {
+	if p.MinLength < 1 || p.MinLength > maxPasswordLength {
+		return fmt.Errorf("最小长度需在1-%d之间", maxPasswordLength)
+	}
+	if p.HistoryCount < 0 || p.HistoryCount > maxPasswordHistory {
+		return fmt.Errorf("历史密码数量需在0-%d之间", maxPasswordHistory)
+	}
+	if p.MaxAgeDays < 0 || p.MaxAgeDays > maxPasswordAgeDays {
+		return fmt.Errorf("密码有效期需在0-%d天之间", maxPasswordAgeDays)
+	}
+	return nil
+}
+
+// CheckStrength 校验密码是否满足长度、字符类型及弱密码要求（不含历史密码校验）
+func (p *PasswordPolicy) CheckStrength(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", p.MinLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("密码长度不能超过%d位", maxPasswordLength)
+	}
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' ':
+			hasSymbol = true
+		}
+	}
+	missing := make([]string, 0, 4)
+	if p.RequireUpper && !hasUpper {
+		missing = append(missing, "大写字母")
+	}
+	if p.RequireLower && !hasLower {
+		missing = append(missing, "小写字母")
+	}
+	if p.RequireDigit && !hasDigit {
+		missing = append(missing, "数字")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("密码必须包含%s", strings.Join(missing, "、"))
+	}
+	if p.DisallowCommon && isCommonPassword(password) {
+		return ErrPasswordCommon
+	}
+	return nil
+}
+
+// PasswordPolicyManager 密码策略管理
+// 负责读取/保存策略、校验新密码（含历史密码）、记录历史密码以及判断密码是否过期
+type PasswordPolicyManager struct {
+	sysConfigRepo repository.SysConfigRepository
+	historyRepo   repository.PasswordHistoryRepository
+}
+
+// NewPasswordPolicyManager 创建密码策略管理器
+func NewPasswordPolicyManager(sysConfigRepo repository.SysConfigRepository, historyRepo repository.PasswordHistoryRepository) *PasswordPolicyManager {
+	return &PasswordPolicyManager{sysConfigRepo: sysConfigRepo, historyRepo: historyRepo}
+}
+
+// policyKey 密码类型对应的配置键
+func policyKey(kind string) string {
+	if kind == models.PasswordKindFile {
+		return FilePasswordPolicyKey
+	}
+	return PasswordPolicyKey
+}
+
+// Policy 获取指定类型的密码策略，未配置或配置损坏时返回默认策略
+func (m *PasswordPolicyManager) Policy(ctx context.Context, kind string) (*PasswordPolicy, error) {
+	cfg, err := m.sysConfigRepo.GetByKey(ctx, policyKey(kind))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DefaultPasswordPolicy(), nil
+		}
+		return nil, err
+	}
+	policy := DefaultPasswordPolicy()
+	if cfg.Value == "" {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(cfg.Value), policy); err != nil || policy.Validate() != nil {
+		return DefaultPasswordPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SavePolicy 保存指定类型的密码策略
+func (m *PasswordPolicyManager) SavePolicy(ctx context.Context, kind string, policy *PasswordPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	key := policyKey(kind)
+	cfg, err := m.sysConfigRepo.GetByKey(ctx, key)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return m.sysConfigRepo.Create(ctx, &models.SysConfig{Key: key, Value: string(data)})
+	}
+	cfg.Value = string(data)
+	return m.sysConfigRepo.Update(ctx, cfg)
+}
+
+// Check 校验新密码是否符合策略
+// user 为空表示新建账户（不做历史密码校验）；否则同时校验当前密码及最近 HistoryCount 次历史密码
+func (m *PasswordPolicyManager) Check(ctx context.Context, kind string, user *models.UserInfo, password string) error {
+	policy, err := m.Policy(ctx, kind)
+	if err != nil {
+		return err
+	}
+	if err := policy.CheckStrength(password); err != nil {
+		return err
+	}
+	if user == nil || policy.HistoryCount == 0 {
+		return nil
+	}
+	current := user.Password
+	if kind == models.PasswordKindFile {
+		current = user.FilePassword
+	}
+	if current != "" && util.CheckPassword(current, password) {
+		return ErrPasswordReused
+	}
+	history, err := m.historyRepo.ListRecent(ctx, user.ID, kind, policy.HistoryCount)
+	if err != nil {
+		return err
+	}
+	for _, h := range history {
+		if util.CheckPassword(h.PasswordHash, password) {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// Record 记录新设置的密码哈希，并只保留策略要求数量的历史密码
+func (m *PasswordPolicyManager) Record(ctx context.Context, kind, userID, hash string) error {
+	policy, err := m.Policy(ctx, kind)
+	if err != nil {
+		return err
+	}
+	if policy.HistoryCount > 0 {
+		if err := m.historyRepo.Create(ctx, &models.PasswordHistory{
+			UserID:       userID,
+			Kind:         kind,
+			PasswordHash: hash,
+			CreatedAt:    custom_type.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+	return m.historyRepo.DeleteExceptRecent(ctx, userID, kind, policy.HistoryCount)
+}
+
+// Expired 判断用户的密码是否已超过有效期
+// 修改时间为空时按账户创建时间计算；非本地账户的登录密码不由本系统管理，永不过期
+func (m *PasswordPolicyManager) Expired(ctx context.Context, kind string, user *models.UserInfo) (bool, error) {
+	changedAt := user.PasswordChangedAt
+	if kind == models.PasswordKindFile {
+		if user.FilePassword == "" {
+			return false, nil
+		}
+		changedAt = user.FilePasswordChangedAt
+	} else if user.AuthSource != models.AuthSourceLocal {
+		return false, nil
+	}
+	policy, err := m.Policy(ctx, kind)
+	if err != nil {
+		return false, err
+	}
+	if policy.MaxAgeDays == 0 {
+		return false, nil
+	}
+	if changedAt.IsZero() {
+		changedAt = user.CreatedAt
+	}
+	if changedAt.IsZero() {
+		return false, nil
+	}
+	return time.Since(changedAt.ToTime()) > time.Duration(policy.MaxAgeDays)*24*time.Hour, nil
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"myobj/src/config"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// sessionTouchInterval 会话最近访问信息的更新间隔，同一 IP 在间隔内不重复写库
+	sessionTouchInterval = time.Minute
+	// refreshReuseGrace 刷新令牌轮换后的宽限期，页面并发请求携带同一个旧令牌时不视为泄露
+	refreshReuseGrace = 10 * time.Second
+)
+
+var (
+	// ErrSessionRevoked 会话不存在、已过期或已被注销
+	ErrSessionRevoked = errors.New("会话已失效,请重新登录")
+	// ErrSessionNotFound 要注销的会话不存在或不属于该用户
+	ErrSessionNotFound = errors.New("会话不存在")
+	// ErrRefreshTokenInvalid 刷新令牌不存在
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效,请重新登录")
+	// ErrRefreshTokenRotated 刷新令牌刚被轮换（并发刷新），应使用新令牌
+	ErrRefreshTokenRotated = errors.New("刷新令牌已更新,请使用新的令牌")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用，会话已注销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用过,会话已注销,请重新登录")
+)
+
+// SessionManager 用户登录会话管理
+// 会话登记在 user_session 表中（只保存令牌哈希），按用户索引；
+// 认证中间件每次请求校验会话是否存在，因此注销只需删除记录，与本地缓存 / Redis 缓存无关，
+// CLI 等独立进程同样可以注销指定用户的会话。
+// 访问令牌短期有效，过期后使用刷新令牌（refresh_token 表）换取新令牌，每次刷新都轮换刷新令牌并滑动延长会话，
+// 但不超过绝对过期时间（勾选"记住我"时为 remember_me_days 天，否则为 session_max_age 小时）
+type SessionManager struct {
+	repo        repository.UserSessionRepository
+	refreshRepo repository.RefreshTokenRepository
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager(repo repository.UserSessionRepository, refreshRepo repository.RefreshTokenRepository) *SessionManager {
+	return &SessionManager{repo: repo, refreshRepo: refreshRepo}
+}
+
+// sessionExpiry 计算会话本次刷新后的过期时间
+// 勾选"记住我"的会话不受空闲超时限制，直接使用绝对过期时间；否则按空闲超时滑动延长
+func sessionExpiry(now, absolute time.Time, remember bool) time.Time {
+	if remember {
+		return absolute
+	}
+	expires := now.Add(time.Duration(config.CONFIG.Auth.JwtExpire) * time.Hour)
+	if expires.After(absolute) {
+		return absolute
+	}
+	return expires
+}
+
+// Create 登记新会话并签发刷新令牌，token 为下发给客户端的访问令牌
+func (m *SessionManager) Create(ctx context.Context, userID, token, ip, userAgent string, remember bool) (*models.UserSession, string, error) {
+	now := time.Now()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	absolute := now.Add(time.Duration(config.CONFIG.Auth.SessionMaxAge) * time.Hour)
+	if remember {
+		absolute = now.AddDate(0, 0, config.CONFIG.Auth.RememberMeDays)
+	}
+	session := &models.UserSession{
+		ID:                uuid.Must(uuid.NewV7()).String(),
+		UserID:            userID,
+		TokenHash:         hashToken(token),
+		Device:            ParseDevice(userAgent),
+		IP:                ip,
+		UserAgent:         userAgent,
+		CreatedAt:         custom_type.JsonTime(now),
+		LastSeenAt:        custom_type.JsonTime(now),
+		ExpiresAt:         custom_type.JsonTime(sessionExpiry(now, absolute, remember)),
+		AbsoluteExpiresAt: custom_type.JsonTime(absolute),
+		RememberMe:        remember,
+	}
+	if err := m.repo.Create(ctx, session); err != nil {
+		logger.LOG.Error("登记会话失败", "user_id", userID, "error", err)
+		return nil, "", err
+	}
+	refreshToken, err := m.issueRefreshToken(ctx, session.ID)
+	if err != nil {
+		_ = m.repo.Delete(ctx, session.ID)
+		return nil, "", err
+	}
+	return session, refreshToken, nil
+}
+
+// issueRefreshToken 为会话签发新的刷新令牌（256 位随机数）
+func (m *SessionManager) issueRefreshToken(ctx context.Context, sessionID string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	if err := m.refreshRepo.Create(ctx, &models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashToken(token),
+		CreatedAt: custom_type.Now(),
+	}); err != nil {
+		logger.LOG.Error("签发刷新令牌失败", "session_id", sessionID, "error", err)
+		return "", err
+	}
+	return token, nil
+}
+
+// Refresh 使用刷新令牌续期会话：轮换刷新令牌，会话改为使用新的访问令牌 newToken，并滑动延长过期时间
+// 令牌在宽限期内刚被轮换（同一页面的并发请求）时返回会话与 ErrRefreshTokenRotated，调用方可以放行本次请求但不签发新令牌；
+// 超过宽限期再次使用已轮换的令牌说明令牌可能已泄露，注销整个会话并返回 ErrRefreshTokenReused
+func (m *SessionManager) Refresh(ctx context.Context, refreshToken, newToken, ip string) (*models.UserSession, string, error) {
+	record, err := m.refreshRepo.GetByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrRefreshTokenInvalid
+		}
+		return nil, "", err
+	}
+	session, err := m.repo.GetByID(ctx, record.SessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrSessionRevoked
+		}
+		return nil, "", err
+	}
+	now := time.Now()
+	if !now.Before(time.Time(session.ExpiresAt)) {
+		return nil, "", ErrSessionRevoked
+	}
+	if !record.RotatedAt.IsZero() {
+		if now.Sub(time.Time(record.RotatedAt)) <= refreshReuseGrace {
+			return session, "", ErrRefreshTokenRotated
+		}
+		m.revokeSession(ctx, session.ID)
+		logger.LOG.Warn("刷新令牌被重复使用,已注销会话", "user_id", session.UserID, "session_id", session.ID, "ip", ip)
+		return session, "", ErrRefreshTokenReused
+	}
+	// 并发刷新时只有一个请求能完成轮换
+	rotated, err := m.refreshRepo.MarkRotated(ctx, record.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !rotated {
+		return session, "", ErrRefreshTokenRotated
+	}
+	newRefreshToken, err := m.issueRefreshToken(ctx, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	absolute := time.Time(session.AbsoluteExpiresAt)
+	if absolute.IsZero() {
+		absolute = time.Time(session.ExpiresAt)
+	}
+	expires := sessionExpiry(now, absolute, session.RememberMe)
+	if err := m.repo.UpdateToken(ctx, session.ID, hashToken(newToken), expires); err != nil {
+		return nil, "", err
+	}
+	session.TokenHash = hashToken(newToken)
+	session.ExpiresAt = custom_type.JsonTime(expires)
+	if session.IP != ip || now.Sub(time.Time(session.LastSeenAt)) > sessionTouchInterval {
+		if err := m.repo.Touch(ctx, session.ID, ip); err != nil {
+			logger.LOG.Warn("更新会话访问记录失败", "session_id", session.ID, "error", err)
+		}
+	}
+	return session, newRefreshToken, nil
+}
+
+// revokeSession 删除会话及其刷新令牌
+func (m *SessionManager) revokeSession(ctx context.Context, sessionID string) {
+	if err := m.repo.Delete(ctx, sessionID); err != nil {
+		logger.LOG.Error("注销会话失败", "session_id", sessionID, "error", err)
+	}
+	if err := m.refreshRepo.DeleteBySessionID(ctx, sessionID); err != nil {
+		logger.LOG.Warn("删除刷新令牌失败", "session_id", sessionID, "error", err)
+	}
+}
+
+// Validate 校验登录令牌对应的会话是否有效，并更新最近访问信息
+func (m *SessionManager) Validate(ctx context.Context, token, ip string) (*models.UserSession, error) {
+	session, err := m.repo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+	if session.IP != ip || time.Since(time.Time(session.LastSeenAt)) > sessionTouchInterval {
+		if err := m.repo.Touch(ctx, session.ID, ip); err != nil {
+			logger.LOG.Warn("更新会话访问记录失败", "session_id", session.ID, "error", err)
+		}
+	}
+	return session, nil
+}
+
+// List 获取用户所有未过期的会话
+func (m *SessionManager) List(ctx context.Context, userID string) ([]*models.UserSession, error) {
+	return m.repo.ListActiveByUserID(ctx, userID)
+}
+
+// Revoke 注销用户的指定会话
+func (m *SessionManager) Revoke(ctx context.Context, userID, sessionID string) error {
+	session, err := m.repo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	if err := m.repo.Delete(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := m.refreshRepo.DeleteBySessionID(ctx, sessionID); err != nil {
+		logger.LOG.Warn("删除刷新令牌失败", "session_id", sessionID, "error", err)
+	}
+	logger.LOG.Info("会话已注销", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// RevokeAll 注销用户的所有会话，exceptID 不为空时保留该会话（如当前会话），返回注销数量
+func (m *SessionManager) RevokeAll(ctx context.Context, userID, exceptID string) (int64, error) {
+	count, err := m.repo.DeleteByUserID(ctx, userID, exceptID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := m.refreshRepo.DeleteOrphaned(ctx); err != nil {
+		logger.LOG.Warn("删除刷新令牌失败", "user_id", userID, "error", err)
+	}
+	logger.LOG.Info("用户会话已批量注销", "user_id", userID, "except", exceptID, "count", count)
+	return count, nil
+}
+
+// hashToken 计算登录令牌哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseDevice 根据 User-Agent 识别设备（浏览器 / 操作系统），无法识别时返回 "未知设备"
+func ParseDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	// 注意匹配顺序：Edge、Opera 的 UA 中包含 Chrome，Chrome 的 UA 中包含 Safari
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case ua != "":
+		// 非浏览器客户端（如 curl、SDK）取产品名
+		browser = strings.SplitN(strings.SplitN(userAgent, " ", 2)[0], "/", 2)[0]
+	}
+
+	// iPhone / Android 的 UA 中分别包含 "Mac OS X" / "Linux"，需先匹配
+	system := ""
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		system = "iOS"
+	case strings.Contains(ua, "android"):
+		system = "Android"
+	case strings.Contains(ua, "windows"):
+		system = "Windows"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		system = "macOS"
+	case strings.Contains(ua, "linux"):
+		system = "Linux"
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " / " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "未知设备"
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"myobj/src/config"
+	"myobj/src/core/domain/response"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenIssuer 签发登录令牌（登录、刷新令牌接口与认证中间件共用）
+// 访问令牌为随机串，对应的 JWT（含用户信息）保存在缓存中，有效期 access_token_expire 分钟；
+// 刷新令牌登记在数据库中，每次使用后轮换，由 SessionManager 负责校验与重用检测
+type TokenIssuer struct {
+	cache     cache.Cache
+	sessions  *SessionManager
+	userRepo  repository.UserRepository
+	powerRepo repository.PowerRepository
+}
+
+// NewTokenIssuer 创建令牌签发器
+func NewTokenIssuer(
+	cache cache.Cache,
+	sessionRepo repository.UserSessionRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	userRepo repository.UserRepository,
+	powerRepo repository.PowerRepository,
+) *TokenIssuer {
+	return &TokenIssuer{
+		cache:     cache,
+		sessions:  NewSessionManager(sessionRepo, refreshRepo),
+		userRepo:  userRepo,
+		powerRepo: powerRepo,
+	}
+}
+
+// AccessTokenTTL 访问令牌有效期（秒）
+func AccessTokenTTL() int {
+	return config.CONFIG.Auth.AccessTokenExpire * 60
+}
+
+// Login 登记新会话并签发访问令牌与刷新令牌
+// 返回的 Power 为用户组权限，写入令牌后由调用方决定是否下发给客户端
+func (t *TokenIssuer) Login(ctx context.Context, user *models.UserInfo, remember bool, ip, userAgent string) (*response.UserLoginResponse, error) {
+	powers, err := t.powerRepo.GetByGroupID(ctx, user.GroupID)
+	if err != nil {
+		logger.LOG.Error("查询用户权限失败", "error", err)
+		return nil, err
+	}
+	user.Password = ""
+	user.FilePassword = ""
+	token := uuid.New().String()
+	session, refreshToken, err := t.sessions.Create(ctx, user.ID, token, ip, userAgent, remember)
+	if err != nil {
+		return nil, errors.New("登记会话失败")
+	}
+	res, err := t.issueAccessToken(token, session, user, powers)
+	if err != nil {
+		_ = t.sessions.Revoke(ctx, user.ID, session.ID)
+		return nil, err
+	}
+	res.RefreshToken = refreshToken
+	return res, nil
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌与刷新令牌
+// 刷新时重新读取用户信息与权限，用户资料、用户组的变更以及禁用在刷新后生效；
+// 刷新令牌刚被并发请求轮换时返回会话对应的用户信息与 ErrRefreshTokenRotated（不含新令牌）
+func (t *TokenIssuer) Refresh(ctx context.Context, refreshToken, ip string) (*response.UserLoginResponse, *models.UserSession, error) {
+	token := uuid.New().String()
+	session, newRefreshToken, err := t.sessions.Refresh(ctx, refreshToken, token, ip)
+	if err != nil && !errors.Is(err, ErrRefreshTokenRotated) {
+		return nil, session, err
+	}
+	user, uerr := t.userRepo.GetByID(ctx, session.UserID)
+	if uerr != nil || user.State == 1 {
+		_ = t.sessions.Revoke(ctx, session.UserID, session.ID)
+		return nil, session, ErrSessionRevoked
+	}
+	powers, perr := t.powerRepo.GetByGroupID(ctx, user.GroupID)
+	if perr != nil {
+		logger.LOG.Error("查询用户权限失败", "error", perr)
+		return nil, session, perr
+	}
+	user.Password = ""
+	user.FilePassword = ""
+	if err != nil {
+		return &response.UserLoginResponse{User: user, Power: powers, RememberMe: session.RememberMe}, session, err
+	}
+	res, err := t.issueAccessToken(token, session, user, powers)
+	if err != nil {
+		return nil, session, err
+	}
+	res.RefreshToken = newRefreshToken
+	return res, session, nil
+}
+
+// issueAccessToken 生成 JWT 并以访问令牌为键写入缓存
+func (t *TokenIssuer) issueAccessToken(token string, session *models.UserSession, user *models.UserInfo, powers []*models.Power) (*response.UserLoginResponse, error) {
+	res := response.UserLoginResponse{
+		User:  user,
+		Power: powers,
+	}
+	jwt, err := GenerateJWT(user.ID, session.ID, res)
+	if err != nil {
+		logger.LOG.Error("生成JWT失败", "error", err)
+		return nil, err
+	}
+	ttl := AccessTokenTTL()
+	if err := t.cache.Set(token, jwt, ttl); err != nil {
+		logger.LOG.Error("缓存访问令牌失败", "error", err)
+		return nil, err
+	}
+	res.Token = token
+	res.ExpiresIn = ttl
+	res.RememberMe = session.RememberMe
+	res.SessionExpiresIn = int(time.Until(time.Time(session.ExpiresAt)).Seconds())
+	return &res, nil
+}
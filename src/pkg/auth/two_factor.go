@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// TwoFactorIssuer 验证器 App 中显示的服务名称
+	TwoFactorIssuer = "MyObj"
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrTwoFactorCodeInvalid 验证码或恢复码错误（含已使用过的验证码）
+	ErrTwoFactorCodeInvalid = errors.New("验证码错误")
+	// ErrTwoFactorNotEnabled 用户未开启两步验证
+	ErrTwoFactorNotEnabled = errors.New("未开启两步验证")
+	// ErrTwoFactorAlreadyEnabled 用户已开启两步验证
+	ErrTwoFactorAlreadyEnabled = errors.New("已开启两步验证")
+	// ErrTwoFactorNotSetup 尚未生成密钥（需先调用 Setup）
+	ErrTwoFactorNotSetup = errors.New("请先获取两步验证密钥")
+	// ErrTwoFactorRequired 所在用户组要求开启两步验证，不能关闭
+	ErrTwoFactorRequired = errors.New("所在用户组要求开启两步验证,无法关闭")
+)
+
+// TwoFactorManager 两步验证（RFC 6238 TOTP + 一次性恢复码）管理
+// 启用流程：Setup 生成密钥 → 用户在验证器 App 中添加 → Enable 校验验证码后正式启用并下发恢复码；
+// 开启后网页登录需要二次验证，WebDAV / SFTP 只接受应用专用密码（见 RequireAppPassword）
+type TwoFactorManager struct {
+	repo      repository.TwoFactorRepository
+	groupRepo repository.GroupRepository
+}
+
+// NewTwoFactorManager 创建两步验证管理器
+func NewTwoFactorManager(repo repository.TwoFactorRepository, groupRepo repository.GroupRepository) *TwoFactorManager {
+	return &TwoFactorManager{repo: repo, groupRepo: groupRepo}
+}
+
+// get 获取用户的两步验证配置，不存在时返回 nil
+func (m *TwoFactorManager) get(ctx context.Context, userID string) (*models.UserTwoFactor, error) {
+	tf, err := m.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tf, nil
+}
+
+// Enabled 用户是否已开启两步验证
+func (m *TwoFactorManager) Enabled(ctx context.Context, userID string) (bool, error) {
+	tf, err := m.get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.Enabled, nil
+}
+
+// Required 用户所在组是否要求开启两步验证
+func (m *TwoFactorManager) Required(ctx context.Context, groupID int) (bool, error) {
+	group, err := m.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return group.Require2FA, nil
+}
+
+// RequireAppPassword 同步协议（WebDAV / SFTP）是否只允许使用应用专用密码
+// 开启两步验证（或所在组要求开启）的用户，登录密码和 API Key 无法提供第二因素，
+// 同步客户端必须使用在已通过两步验证的会话中创建的应用专用密码
+func (m *TwoFactorManager) RequireAppPassword(ctx context.Context, user *models.UserInfo) (bool, error) {
+	enabled, err := m.Enabled(ctx, user.ID)
+	if err != nil || enabled {
+		return enabled, err
+	}
+	return m.Required(ctx, user.GroupID)
+}
+
+// Status 获取两步验证状态，返回是否开启、启用时间与剩余恢复码数量
+func (m *TwoFactorManager) Status(ctx context.Context, userID string) (bool, *custom_type.JsonTime, int, error) {
+	tf, err := m.get(ctx, userID)
+	if err != nil || tf == nil || !tf.Enabled {
+		return false, nil, 0, err
+	}
+	return true, &tf.EnabledAt, len(decodeRecoveryCodes(tf.RecoveryCodes)), nil
+}
+
+// Setup 生成新的 TOTP 密钥（尚未启用），返回密钥与 otpauth:// 配置地址
+// 重复调用会覆盖之前未确认的密钥
+func (m *TwoFactorManager) Setup(ctx context.Context, user *models.UserInfo) (string, string, error) {
+	tf, err := m.get(ctx, user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if tf != nil && tf.Enabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := m.repo.Save(ctx, &models.UserTwoFactor{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: custom_type.Now(),
+	}); err != nil {
+		logger.LOG.Error("保存两步验证密钥失败", "user_id", user.ID, "error", err)
+		return "", "", err
+	}
+	return secret, util.TOTPProvisioningURI(TwoFactorIssuer, user.UserName, secret), nil
+}
+
+// Enable 校验验证码并正式启用两步验证，返回恢复码明文（只返回一次）
+func (m *TwoFactorManager) Enable(ctx context.Context, userID, code string) ([]string, error) {
+	tf, err := m.get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotSetup
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	step, ok := util.VerifyTOTP(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tf.Enabled = true
+	tf.LastStep = step
+	tf.RecoveryCodes = hashes
+	tf.EnabledAt = custom_type.Now()
+	if err := m.repo.Save(ctx, tf); err != nil {
+		logger.LOG.Error("启用两步验证失败", "user_id", userID, "error", err)
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify 校验验证码或恢复码，恢复码使用后立即作废，返回是否使用了恢复码
+func (m *TwoFactorManager) Verify(ctx context.Context, userID, code string) (bool, error) {
+	tf, err := m.get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if tf == nil || !tf.Enabled {
+		return false, ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := util.VerifyTOTP(tf.Secret, code, time.Now()); ok {
+		// 同一时间步的验证码只能使用一次（条件更新，并发请求只有一个能成功）
+		updated, err := m.repo.UpdateLastStep(ctx, userID, step)
+		if err != nil {
+			return false, err
+		}
+		if !updated {
+			return false, ErrTwoFactorCodeInvalid
+		}
+		return false, nil
+	}
+
+	hash := util.HashRecoveryCode(code)
+	hashes := decodeRecoveryCodes(tf.RecoveryCodes)
+	for i, h := range hashes {
+		if h != hash {
+			continue
+		}
+		remain, _ := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+		updated, err := m.repo.UpdateRecoveryCodes(ctx, userID, tf.RecoveryCodes, string(remain))
+		if err != nil {
+			return false, err
+		}
+		if !updated {
+			return false, ErrTwoFactorCodeInvalid
+		}
+		logger.LOG.Info("恢复码已使用", "user_id", userID, "remaining", len(hashes)-1)
+		return true, nil
+	}
+	return false, ErrTwoFactorCodeInvalid
+}
+
+// Disable 校验验证码后关闭两步验证，所在组要求开启时不允许关闭
+func (m *TwoFactorManager) Disable(ctx context.Context, user *models.UserInfo, code string) error {
+	required, err := m.Required(ctx, user.GroupID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if _, err := m.Verify(ctx, user.ID, code); err != nil {
+		return err
+	}
+	return m.repo.Delete(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，原有恢复码全部作废
+func (m *TwoFactorManager) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	if _, err := m.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	tf, err := m.get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	updated, err := m.repo.UpdateRecoveryCodes(ctx, userID, tf.RecoveryCodes, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+	return codes, nil
+}
+
+// Reset 重置（删除）用户的两步验证配置，供管理员在用户丢失验证器时使用
+func (m *TwoFactorManager) Reset(ctx context.Context, userID string) error {
+	return m.repo.Delete(ctx, userID)
+}
+
+// newRecoveryCodes 生成恢复码，返回明文列表与哈希 JSON
+func newRecoveryCodes() ([]string, string, error) {
+	codes, err := util.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, "", err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, util.HashRecoveryCode(c))
+	}
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, "", err
+	}
+	return codes, string(data), nil
+}
+
+// decodeRecoveryCodes 解析恢复码哈希列表
+func decodeRecoveryCodes(data string) []string {
+	var hashes []string
+	if data != "" {
+		_ = json.Unmarshal([]byte(data), &hashes)
+	}
+	return hashes
+}
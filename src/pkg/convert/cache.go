@@ -0,0 +1,106 @@
+package convert
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache 转换结果缓存，按 (文件hash, 目标格式) 存放，同一内容的多个文件共用缓存
+//
+// 目录结构: {dir}/{hash前两位}/{hash}_{format}{ext}
+type Cache struct {
+	dir   string
+	group singleflight.Group
+}
+
+// NewCache 创建转换结果缓存
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir 缓存目录
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get 获取转换结果，缓存不存在时转换并写入缓存，同一结果的并发请求只转换一次；
+// 命中时更新修改时间，清理按最后一次使用时间计算
+func (c *Cache) Get(ctx context.Context, fileHash, mime, format, src string) (string, Target, error) {
+	converter, target, err := Lookup(mime, format)
+	if err != nil {
+		return "", target, err
+	}
+	prefix := fileHash
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	cachePath := filepath.Join(c.dir, prefix, fileHash+"_"+target.Format+target.Ext)
+	if _, err := os.Stat(cachePath); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(cachePath, now, now)
+		return cachePath, target, nil
+	}
+
+	_, err, _ = c.group.Do(cachePath, func() (any, error) {
+		if _, err := os.Stat(cachePath); err == nil {
+			return nil, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+			return nil, err
+		}
+		// 先写入临时文件再重命名，避免读到转换了一半的结果
+		tmp, err := os.CreateTemp(filepath.Dir(cachePath), ".tmp-*")
+		if err != nil {
+			return nil, err
+		}
+		tmpPath := tmp.Name()
+		tmp.Close()
+		if err := converter.Convert(ctx, src, tmpPath, mime, target); err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		if err := os.Rename(tmpPath, cachePath); err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return "", target, err
+	}
+	return cachePath, target, nil
+}
+
+// Cleanup 删除超过 maxAge 未使用的转换结果，返回删除的文件数
+func (c *Cache) Cleanup(maxAge time.Duration) (int, error) {
+	deadline := time.Now().Add(-maxAge)
+	removed := 0
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(deadline) {
+			if err := os.Remove(path); err == nil && !strings.HasPrefix(d.Name(), ".tmp-") {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,99 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	// ErrUnsupported 源文件类型不支持转换为该格式
+	ErrUnsupported = errors.New("不支持转换为该格式")
+	// ErrTooLarge 源文件超过转换的大小上限
+	ErrTooLarge = errors.New("文件超过格式转换的大小上限")
+)
+
+// Target 转换目标格式
+type Target struct {
+	// Format 格式标识（下载时的 format 参数）
+	Format string
+	// Name 格式名称
+	Name string
+	// Mime 转换结果的 MIME 类型
+	Mime string
+	// Ext 转换结果的扩展名，为空时沿用原文件名（如文本编码转换）
+	Ext string
+}
+
+// Converter 格式转换器
+type Converter interface {
+	// Targets 该类型的源文件可转换的目标格式，mime 可带参数（如 charset）
+	Targets(mime string) []Target
+	// Convert 将类型为 mime 的 src 转换为目标格式写入 dst
+	Convert(ctx context.Context, src, dst, mime string, target Target) error
+}
+
+// registry 按源文件 MIME 类型注册的转换器
+var registry = make(map[string][]Converter)
+
+// Register 为源文件 MIME 类型注册转换器，同一类型可注册多个转换器
+func Register(c Converter, sourceMimes ...string) {
+	for _, m := range sourceMimes {
+		key := baseMime(m)
+		registry[key] = append(registry[key], c)
+	}
+}
+
+// Targets 源文件可转换的全部目标格式
+func Targets(mime string) []Target {
+	var targets []Target
+	for _, c := range registry[baseMime(mime)] {
+		targets = append(targets, c.Targets(mime)...)
+	}
+	return targets
+}
+
+// Lookup 查找源文件可转换的目标格式及其转换器
+func Lookup(mime, format string) (Converter, Target, error) {
+	for _, c := range registry[baseMime(mime)] {
+		for _, target := range c.Targets(mime) {
+			if target.Format == format {
+				return c, target, nil
+			}
+		}
+	}
+	return nil, Target{}, ErrUnsupported
+}
+
+// Convert 将 src 转换为 format 格式写入 dst
+func Convert(ctx context.Context, mime, format, src, dst string) (Target, error) {
+	c, target, err := Lookup(mime, format)
+	if err != nil {
+		return target, err
+	}
+	return target, c.Convert(ctx, src, dst, mime, target)
+}
+
+// baseMime 去掉参数并统一为 mimetype 的标准名称（别名如 image/x-ms-bmp 归为 image/bmp）
+func baseMime(m string) string {
+	base, _, err := mime.ParseMediaType(m)
+	if err != nil {
+		base = strings.ToLower(strings.TrimSpace(strings.Split(m, ";")[0]))
+	}
+	if known := mimetype.Lookup(base); known != nil {
+		return known.String()
+	}
+	return base
+}
+
+// mimeCharset MIME 类型中的 charset 参数（小写）
+func mimeCharset(m string) string {
+	_, params, err := mime.ParseMediaType(m)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(params["charset"])
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"myobj/src/config"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var (
+	audioTargets = []Target{
+		{Format: "mp3", Name: "MP3 音频", Mime: "audio/mpeg", Ext: ".mp3"},
+		{Format: "wav", Name: "WAV 音频", Mime: "audio/wav", Ext: ".wav"},
+		{Format: "flac", Name: "FLAC 音频", Mime: "audio/flac", Ext: ".flac"},
+		{Format: "ogg", Name: "Ogg 音频", Mime: "audio/ogg", Ext: ".ogg"},
+	}
+	videoTargets = []Target{
+		{Format: "mp4", Name: "MP4 视频", Mime: "video/mp4", Ext: ".mp4"},
+		{Format: "webm", Name: "WebM 视频", Mime: "video/webm", Ext: ".webm"},
+		{Format: "mp3", Name: "MP3 音频（提取音轨）", Mime: "audio/mpeg", Ext: ".mp3"},
+	}
+	// ffmpegArgs 各目标格式的输出参数，编码器使用 ffmpeg 对该封装格式的默认选择
+	ffmpegArgs = map[string][]string{
+		"mp3":  {"-vn", "-f", "mp3"},
+		"wav":  {"-vn", "-f", "wav"},
+		"flac": {"-vn", "-f", "flac"},
+		"ogg":  {"-vn", "-f", "ogg"},
+		"mp4":  {"-movflags", "+faststart", "-f", "mp4"},
+		"webm": {"-f", "webm"},
+	}
+)
+
+// ffmpegConverter 调用 ffmpeg 的音视频格式转换，未配置 ffmpeg_path 或找不到 ffmpeg 时不提供目标格式
+type ffmpegConverter struct {
+	targets []Target
+}
+
+func init() {
+	Register(ffmpegConverter{targets: audioTargets},
+		"audio/mpeg", "audio/wav", "audio/flac", "audio/ogg", "audio/aac", "audio/x-m4a", "audio/mp4")
+	Register(ffmpegConverter{targets: videoTargets},
+		"video/mp4", "video/quicktime", "video/x-matroska", "video/webm", "video/x-msvideo", "video/x-flv", "video/mpeg")
+}
+
+// FFmpegPath 已配置且可执行的 ffmpeg 路径
+func FFmpegPath() (string, bool) {
+	if config.CONFIG == nil || config.CONFIG.Convert.FFmpegPath == "" {
+		return "", false
+	}
+	path, err := exec.LookPath(config.CONFIG.Convert.FFmpegPath)
+	return path, err == nil
+}
+
+// Targets 排除与源文件相同的格式
+func (c ffmpegConverter) Targets(mime string) []Target {
+	if _, ok := FFmpegPath(); !ok {
+		return nil
+	}
+	source := baseMime(mime)
+	targets := make([]Target, 0, len(c.targets))
+	for _, target := range c.targets {
+		if target.Mime != source {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Convert 调用 ffmpeg 转换，超过 ffmpeg_timeout 时终止进程
+func (c ffmpegConverter) Convert(ctx context.Context, src, dst, mime string, target Target) error {
+	path, ok := FFmpegPath()
+	args, known := ffmpegArgs[target.Format]
+	if !ok || !known {
+		return ErrUnsupported
+	}
+	timeout := time.Duration(config.CONFIG.Convert.FFmpegTimeout) * time.Minute
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmdArgs := append([]string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y", "-i", src}, args...)
+	cmd := exec.CommandContext(ctx, path, append(cmdArgs, dst)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("ffmpeg 转换超时或已取消: %w", ctx.Err())
+		}
+		return fmt.Errorf("ffmpeg 转换失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
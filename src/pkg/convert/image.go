@@ -0,0 +1,104 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+
+	_ "golang.org/x/image/bmp" // BMP格式支持
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff" // TIFF格式支持
+	_ "golang.org/x/image/webp" // WebP格式支持
+)
+
+// maxImagePixels 参与转换的图片最大像素数，避免超大图片解码时占用过多内存
+const maxImagePixels = 100 * 1000 * 1000
+
+// jpegQuality 转换为 JPEG 时的质量
+const jpegQuality = 90
+
+// imageTargets 图片可转换的目标格式
+var imageTargets = []Target{
+	{Format: "png", Name: "PNG", Mime: "image/png", Ext: ".png"},
+	{Format: "jpeg", Name: "JPEG", Mime: "image/jpeg", Ext: ".jpg"},
+	{Format: "gif", Name: "GIF", Mime: "image/gif", Ext: ".gif"},
+}
+
+// imageConverter 纯 Go 实现的图片格式转换（PNG/JPEG/GIF/BMP/TIFF/WebP 转 PNG/JPEG/GIF）
+type imageConverter struct{}
+
+func init() {
+	Register(imageConverter{}, "image/png", "image/jpeg", "image/gif", "image/bmp", "image/tiff", "image/webp")
+}
+
+// Targets 排除与源文件相同的格式
+func (imageConverter) Targets(mime string) []Target {
+	source := baseMime(mime)
+	targets := make([]Target, 0, len(imageTargets))
+	for _, target := range imageTargets {
+		if target.Mime != source {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Convert 解码源图片并按目标格式编码，动图只保留第一帧
+func (imageConverter) Convert(ctx context.Context, src, dst, mime string, target Target) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	cfg, _, err := image.DecodeConfig(in)
+	if err != nil {
+		return fmt.Errorf("读取图片信息失败: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return fmt.Errorf("%w: 图片尺寸 %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	if _, err := in.Seek(0, 0); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(in)
+	if err != nil {
+		return fmt.Errorf("解码图片失败: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	switch target.Format {
+	case "png":
+		err = png.Encode(out, img)
+	case "jpeg":
+		err = jpeg.Encode(out, flatten(img), &jpeg.Options{Quality: jpegQuality})
+	case "gif":
+		err = gif.Encode(out, img, nil)
+	default:
+		err = ErrUnsupported
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// flatten 将带透明通道的图片合成到白色背景上（JPEG 不支持透明）
+func flatten(img image.Image) image.Image {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(canvas, bounds, img, bounds.Min, draw.Over)
+	return canvas
+}
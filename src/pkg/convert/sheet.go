@@ -0,0 +1,454 @@
+package convert
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"myobj/src/pkg/util"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const xlsxMime = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Excel 工作表的行列上限
+const (
+	maxSheetRows = 1048576
+	maxSheetCols = 16384
+)
+
+// maxSharedStringsSize 共享字符串表解压后的大小上限，共享字符串需要全部读入内存，避免压缩炸弹耗尽内存
+const maxSharedStringsSize = 64 << 20
+
+var (
+	xlsxTarget = Target{Format: "xlsx", Name: "Excel 工作簿", Mime: xlsxMime, Ext: ".xlsx"}
+	csvTarget  = Target{Format: "csv", Name: "CSV", Mime: "text/csv; charset=utf-8", Ext: ".csv"}
+)
+
+// sheetConverter CSV/TSV 与 XLSX 互转（只处理第一个工作表，不保留样式与公式）
+type sheetConverter struct{}
+
+func init() {
+	Register(sheetConverter{}, "text/csv", "text/tab-separated-values", xlsxMime)
+}
+
+func (sheetConverter) Targets(mime string) []Target {
+	if baseMime(mime) == xlsxMime {
+		return []Target{csvTarget}
+	}
+	return []Target{xlsxTarget}
+}
+
+func (sheetConverter) Convert(ctx context.Context, src, dst, mime string, target Target) error {
+	switch target.Format {
+	case "xlsx":
+		comma := ','
+		if baseMime(mime) == "text/tab-separated-values" {
+			comma = '\t'
+		}
+		return csvToXlsx(ctx, src, dst, comma)
+	case "csv":
+		return xlsxToCsv(ctx, src, dst)
+	}
+	return ErrUnsupported
+}
+
+// csvToXlsx 将 CSV/TSV 写为只含一个工作表的 XLSX，能无损往返的数字写为数值，其余写为文本
+func csvToXlsx(ctx context.Context, src, dst string, comma rune) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	charset, err := util.DetectCharset(data)
+	if err != nil {
+		return err
+	}
+	text, err := util.DecodeText(data, charset)
+	if err != nil {
+		return fmt.Errorf("按 %s 解码失败: %w", charset, err)
+	}
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(out)
+	err = writeXlsx(ctx, zw, reader)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// xlsx 固定的包结构文件
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func writeXlsx(ctx context.Context, zw *zip.Writer, reader *csv.Reader) error {
+	for _, part := range xlsxParts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+	w, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("解析 CSV 失败: %w", err)
+		}
+		if row > maxSheetRows || len(record) > maxSheetCols {
+			return fmt.Errorf("%w: 超过工作表的行列上限", ErrTooLarge)
+		}
+		if row%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(bw, `<row r="%d">`, row)
+		for col, value := range record {
+			if value == "" {
+				continue
+			}
+			ref := columnName(col) + strconv.Itoa(row)
+			if isNumber(value) {
+				fmt.Fprintf(bw, `<c r="%s"><v>%s</v></c>`, ref, value)
+				continue
+			}
+			fmt.Fprintf(bw, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(bw, []byte(value))
+			bw.WriteString(`</t></is></c>`)
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+// isNumber 是否为转换成数值后能原样还原的数字（如 007、1e3 仍按文本处理）
+func isNumber(value string) bool {
+	f, err := strconv.ParseFloat(value, 64)
+	return err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value
+}
+
+// columnName 列序号（从 0 开始）转为 A、B…Z、AA 形式的列名
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// columnIndex 从单元格引用（如 AB12）解析列序号（从 0 开始），无效时返回 -1
+func columnIndex(ref string) int {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+	}
+	return col - 1
+}
+
+// xlsxToCsv 将 XLSX 的第一个工作表写为带 BOM 的 UTF-8 CSV（便于 Excel 直接打开）
+func xlsxToCsv(ctx context.Context, src, dst string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("打开 XLSX 失败: %w", err)
+	}
+	defer zr.Close()
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return err
+	}
+	var sharedStrings []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if sharedStrings, err = readSharedStrings(f); err != nil {
+			return err
+		}
+	}
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return fmt.Errorf("XLSX 缺少工作表 %s", sheetPath)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(out)
+	bw.Write([]byte{0xEF, 0xBB, 0xBF})
+	err = writeSheetCsv(ctx, sheet, sharedStrings, csv.NewWriter(bw))
+	if err == nil {
+		err = bw.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// firstSheetPath 按 workbook.xml 及其关系文件找到第一个工作表在包内的路径
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXMLPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("XLSX 不包含工作表")
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXMLPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].ID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", errors.New("XLSX 找不到第一个工作表")
+}
+
+func decodeXMLPart(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("XLSX 缺少 %s", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := xml.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// readSharedStrings 读取共享字符串表，富文本取各段文字拼接，忽略注音（rPh）
+func readSharedStrings(f *zip.File) ([]string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	limited := &io.LimitedReader{R: r, N: maxSharedStringsSize + 1}
+	var (
+		result        []string
+		current       strings.Builder
+		inText, inRPh bool
+	)
+	decoder := xml.NewDecoder(limited)
+	for {
+		token, err := decoder.Token()
+		if limited.N <= 0 {
+			return nil, fmt.Errorf("共享字符串表超过 %d MB", maxSharedStringsSize>>20)
+		}
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析共享字符串失败: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inRPh = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				result = append(result, current.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inRPh = false
+			}
+		case xml.CharData:
+			if inText && !inRPh {
+				current.Write(t)
+			}
+		}
+	}
+}
+
+// writeSheetCsv 流式解析工作表，按单元格引用补齐空行与空列后写入 CSV
+func writeSheetCsv(ctx context.Context, sheet *zip.File, sharedStrings []string, writer *csv.Writer) error {
+	r, err := sheet.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var (
+		record    []string
+		lastRow   int
+		cellType  string
+		cellCol   int
+		value     bytes.Buffer
+		inValue   bool
+		inlineStr bool
+	)
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("解析工作表失败: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row := lastRow + 1
+				if n, err := strconv.Atoi(attr(t, "r")); err == nil && n > row {
+					row = n
+				}
+				if row > maxSheetRows {
+					return fmt.Errorf("%w: 超过工作表的行数上限", ErrTooLarge)
+				}
+				for ; lastRow+1 < row; lastRow++ {
+					if err := writer.Write(nil); err != nil {
+						return err
+					}
+				}
+				lastRow = row
+				record = record[:0]
+				if row%1000 == 0 {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+				}
+			case "c":
+				cellType = attr(t, "t")
+				cellCol = columnIndex(attr(t, "r"))
+				if cellCol < 0 {
+					cellCol = len(record)
+				}
+				if cellCol >= maxSheetCols {
+					return fmt.Errorf("%w: 超过工作表的列数上限", ErrTooLarge)
+				}
+				value.Reset()
+			case "v":
+				inValue = true
+			case "is":
+				inlineStr = true
+			case "t":
+				inValue = inlineStr
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "row":
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+			case "c":
+				for len(record) < cellCol {
+					record = append(record, "")
+				}
+				record = append(record, cellValue(cellType, value.String(), sharedStrings))
+			case "v", "t":
+				inValue = false
+			case "is":
+				inlineStr = false
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// cellValue 按单元格类型取显示值：共享字符串按序号查表，布尔值转为 TRUE/FALSE，其余原样输出
+func cellValue(cellType, raw string, sharedStrings []string) string {
+	switch cellType {
+	case "s":
+		if i, err := strconv.Atoi(raw); err == nil && i >= 0 && i < len(sharedStrings) {
+			return sharedStrings[i]
+		}
+		return ""
+	case "b":
+		if raw == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return raw
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"myobj/src/pkg/util"
+	"os"
+)
+
+// textConverter 文本编码转换（GBK 与 UTF-8 互转），转换结果沿用原文件名
+type textConverter struct{}
+
+func init() {
+	Register(textConverter{}, "text/plain", "text/csv", "text/tab-separated-values", "text/html")
+}
+
+// Targets 排除与源文件 charset 相同的编码
+func (textConverter) Targets(mime string) []Target {
+	base := baseMime(mime)
+	charset := mimeCharset(mime)
+	var targets []Target
+	if charset != util.CharsetUTF8 {
+		targets = append(targets, Target{Format: "utf8", Name: "UTF-8 编码", Mime: base + "; charset=utf-8"})
+	}
+	if charset != util.CharsetGBK && charset != util.CharsetGB18030 && charset != "gb2312" {
+		targets = append(targets, Target{Format: "gbk", Name: "GBK 编码", Mime: base + "; charset=gbk"})
+	}
+	return targets
+}
+
+// Convert 检测源文件编码并转换为目标编码，GBK 无法表示的字符返回错误
+func (textConverter) Convert(ctx context.Context, src, dst, mime string, target Target) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	charset, err := util.DetectCharset(data)
+	if err != nil {
+		return err
+	}
+	text, err := util.DecodeText(data, charset)
+	if err != nil {
+		return fmt.Errorf("按 %s 解码失败: %w", charset, err)
+	}
+	var out []byte
+	switch target.Format {
+	case "utf8":
+		out, err = util.EncodeText(text, util.CharsetUTF8)
+	case "gbk":
+		out, err = util.EncodeText(text, util.CharsetGBK)
+	default:
+		return ErrUnsupported
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, out, 0644)
+}
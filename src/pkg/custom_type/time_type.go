@@ -21,7 +21,8 @@ func (t JsonTime) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON 实现 UnmarshalJSON 接口
 func (t *JsonTime) UnmarshalJSON(data []byte) error {
-	if string(data) == "null" {
+	// 零值序列化为空字符串，反序列化时同样视为零值
+	if string(data) == "null" || string(data) == `""` {
 		return nil
 	}
 	parsed, err := time.Parse(`"2006-01-02 15:04:05"`, string(data))
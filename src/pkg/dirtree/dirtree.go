@@ -0,0 +1,166 @@
+// Package dirtree 提供虚拟目录树的公共操作：查询目录内容、查找上级目录、按名称索引目录中的文件与子目录，
+// 供回收站、复制与移动共用
+package dirtree
+
+import (
+	"context"
+	"fmt"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxDepth 目录树的最大层级，防止异常数据导致死循环
+	MaxDepth = 256
+	// pageSize 分页查询目录内容时每页的数量
+	pageSize = 1000
+)
+
+// DirName 获取虚拟目录的显示名称（Path 存储为 "/目录名"）
+func DirName(p string) string {
+	return path.Base("/" + strings.TrimPrefix(p, "/"))
+}
+
+// ListFiles 分页查询目录下的全部文件，直到取完为止
+func ListFiles(ctx context.Context, factory *impl.RepositoryFactory, userID string, dirID int) ([]*models.UserFiles, error) {
+	var files []*models.UserFiles
+	for offset := 0; ; offset += pageSize {
+		page, err := factory.UserFiles().ListByVirtualPath(ctx, userID, strconv.Itoa(dirID), offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, page...)
+		if len(page) < pageSize {
+			return files, nil
+		}
+	}
+}
+
+// ListSubDirs 分页查询目录下的全部子目录，直到取完为止
+func ListSubDirs(ctx context.Context, factory *impl.RepositoryFactory, userID string, parentID int) ([]*models.VirtualPath, error) {
+	var dirs []*models.VirtualPath
+	for offset := 0; ; offset += pageSize {
+		page, err := factory.VirtualPath().ListSubFoldersByParentID(ctx, userID, parentID, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, page...)
+		if len(page) < pageSize {
+			return dirs, nil
+		}
+	}
+}
+
+// Ancestors 目录自身及其全部上级目录的ID，用于判断目录是否被移动或复制到自身的子目录中
+func Ancestors(ctx context.Context, factory *impl.RepositoryFactory, dir *models.VirtualPath) (map[int]bool, error) {
+	ids := map[int]bool{dir.ID: true}
+	for depth := 0; dir.ParentLevel != ""; depth++ {
+		if depth >= MaxDepth {
+			return nil, fmt.Errorf("目录层级过深")
+		}
+		parentID, err := strconv.Atoi(dir.ParentLevel)
+		if err != nil {
+			return nil, fmt.Errorf("目录层级信息错误: %w", err)
+		}
+		if dir, err = factory.VirtualPath().GetByID(ctx, parentID); err != nil {
+			return nil, fmt.Errorf("获取上级目录失败: %w", err)
+		}
+		ids[dir.ID] = true
+	}
+	return ids, nil
+}
+
+// Entries 目录下已有的文件与子目录（按名称索引）
+type Entries struct {
+	Files map[string]*models.UserFiles
+	Dirs  map[string]*models.VirtualPath
+}
+
+// NewEntries 创建空的目录内容（如新建的目录）
+func NewEntries() *Entries {
+	return &Entries{
+		Files: make(map[string]*models.UserFiles),
+		Dirs:  make(map[string]*models.VirtualPath),
+	}
+}
+
+// LoadEntries 查询目录下已有的文件与子目录
+func LoadEntries(ctx context.Context, factory *impl.RepositoryFactory, userID string, dirID int) (*Entries, error) {
+	entries := NewEntries()
+	files, err := ListFiles(ctx, factory, userID, dirID)
+	if err != nil {
+		return nil, fmt.Errorf("获取目录文件失败: %w", err)
+	}
+	for _, file := range files {
+		entries.Files[file.FileName] = file
+	}
+	dirs, err := ListSubDirs(ctx, factory, userID, dirID)
+	if err != nil {
+		return nil, fmt.Errorf("获取子目录失败: %w", err)
+	}
+	for _, dir := range dirs {
+		entries.Dirs[DirName(dir.Path)] = dir
+	}
+	return entries, nil
+}
+
+// Names 已占用的文件名或目录名集合
+func (e *Entries) Names(isFile bool) map[string]bool {
+	names := make(map[string]bool)
+	if isFile {
+		for name := range e.Files {
+			names[name] = true
+		}
+	} else {
+		for name := range e.Dirs {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// UniqueName 与已有文件或目录重名时生成 "名称 (1)" 形式的新名称
+func (e *Entries) UniqueName(name string, isFile bool) string {
+	return util.UniqueName(name, e.Names(isFile), isFile)
+}
+
+// Cache 按目录ID缓存目录内容，用于一次批量操作中多次写入同一目标目录
+type Cache struct {
+	ctx     context.Context
+	factory *impl.RepositoryFactory
+	userID  string
+	entries map[int]*Entries
+}
+
+// NewCache 创建目录内容缓存
+func NewCache(ctx context.Context, factory *impl.RepositoryFactory, userID string) *Cache {
+	return &Cache{ctx: ctx, factory: factory, userID: userID, entries: make(map[int]*Entries)}
+}
+
+// Get 获取目录内容，未缓存时查询
+func (c *Cache) Get(dirID int) (*Entries, error) {
+	if entries, ok := c.entries[dirID]; ok {
+		return entries, nil
+	}
+	entries, err := LoadEntries(c.ctx, c.factory, c.userID, dirID)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[dirID] = entries
+	return entries, nil
+}
+
+// Cached 获取已缓存的目录内容，未缓存时返回 false
+func (c *Cache) Cached(dirID int) (*Entries, bool) {
+	entries, ok := c.entries[dirID]
+	return entries, ok
+}
+
+// Put 缓存目录内容（如新建的空目录，无需再查询）
+func (c *Cache) Put(dirID int, entries *Entries) {
+	c.entries[dirID] = entries
+}
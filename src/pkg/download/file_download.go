@@ -267,6 +267,15 @@ func extractDiskPathFromFilePath(filePath string) string {
 	return filePath[:dataIndex]
 }
 
+// TempDir 文件所在磁盘的临时目录：{DiskPath}/temp，无法识别磁盘路径时使用系统临时目录
+func TempDir(filePath string) string {
+	diskPath := extractDiskPathFromFilePath(filePath)
+	if diskPath == "" {
+		return os.TempDir()
+	}
+	return filepath.Join(diskPath, "temp")
+}
+
 // IsTempPath 判断路径是否为临时路径（导出函数）
 // 临时路径格式: {DiskPath}/temp/...
 func IsTempPath(path string) bool {
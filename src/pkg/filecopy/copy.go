@@ -0,0 +1,469 @@
+package filecopy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/dirtree"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
+	"myobj/src/pkg/search"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 文件与目录复制：复制只新建 user_files 记录并指向同一个 file_info（与秒传一致，不复制物理文件），
+// 目录按原有层级新建 virtual_path 记录；配额按 [file] copy_quota 策略扣除
+
+// 目标位置已存在同名文件或目录时的处理方式
+const (
+	// ConflictRename 重命名为 "名称 (1)" 后复制（默认）
+	ConflictRename = "rename"
+	// ConflictSkip 跳过同名文件或目录
+	ConflictSkip = "skip"
+	// ConflictOverwrite 同名文件移入回收站后复制，同名目录合并（其中的同名文件同样覆盖）
+	ConflictOverwrite = "overwrite"
+)
+
+// 复制的配额策略
+const (
+	// QuotaFull 按文件大小扣除配额
+	QuotaFull = "full"
+	// QuotaNone 复制不占用配额
+	QuotaNone = "none"
+)
+
+var (
+	// ErrInsufficientSpace 用户可用空间不足
+	ErrInsufficientSpace = errors.New("用户可用空间不足")
+	// ErrIntoSelf 目录不能复制到自身或其子目录中
+	ErrIntoSelf = errors.New("不能将目录复制到自身或其子目录中")
+	// ErrNotFound 源文件、源目录或目标目录不存在
+	ErrNotFound = errors.New("文件或目录不存在")
+)
+
+// ChargeQuota 当前配置下复制是否扣除配额
+// 扣除配额时，删除仍被其他用户文件引用的文件同样归还配额
+func ChargeQuota() bool {
+	return config.CONFIG == nil || config.CONFIG.File.CopyQuota != QuotaNone
+}
+
+// Source 复制源：FileID 与 DirID 二选一
+type Source struct {
+	// FileID 用户文件ID（uf_id）
+	FileID string
+	// DirID 目录ID
+	DirID int
+	// Name 复制后的名称，为空时沿用原名称
+	Name string
+	// Shallow 只复制目录本身，不复制其中的内容（WebDAV Depth: 0）
+	Shallow bool
+}
+
+// Plan 复制计划：校验后的复制源与统计
+type Plan struct {
+	// Files 要复制的文件数
+	Files int `json:"files"`
+	// Dirs 要复制的目录数
+	Dirs int `json:"dirs"`
+	// Size 要复制的文件总大小
+	Size int64 `json:"size"`
+
+	targetID int
+	nodes    []*node
+}
+
+// Total 要复制的文件与目录总数
+func (p *Plan) Total() int {
+	return p.Files + p.Dirs
+}
+
+// Names 顶层文件与目录复制后的名称（未处理重名），用于检查目标路径是否被锁定
+func (p *Plan) Names() []string {
+	names := make([]string, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		names = append(names, n.name)
+	}
+	return names
+}
+
+// Result 复制结果
+type Result struct {
+	Files       int   `json:"files"`
+	Dirs        int   `json:"dirs"`
+	Skipped     int   `json:"skipped"`
+	Renamed     int   `json:"renamed"`
+	Overwritten int   `json:"overwritten"`
+	Size        int64 `json:"size"`
+	// Items 顶层复制结果
+	Items []*Item `json:"items"`
+}
+
+// Item 顶层文件或目录的复制结果
+type Item struct {
+	FileID string `json:"file_id,omitempty"`
+	DirID  int    `json:"dir_id,omitempty"`
+	Name   string `json:"name"`
+}
+
+// node 复制源中的文件或目录
+type node struct {
+	name string
+	file *models.UserFiles
+	size int64
+	dir  *models.VirtualPath
+	// children 目录下的子目录与文件
+	children []*node
+}
+
+// count 节点（含子节点）中的文件与目录数
+func (n *node) count() int {
+	total := 1
+	for _, child := range n.children {
+		total += child.count()
+	}
+	return total
+}
+
+// Copier 文件复制器
+type Copier struct {
+	factory *impl.RepositoryFactory
+	userID  string
+	// Progress 每处理完一个文件或目录后回调，参数为已处理数量
+	Progress func(done int)
+
+	done int
+	// created 新建的用户文件ID，提交后通知全文索引
+	created []string
+}
+
+// NewCopier 创建文件复制器
+func NewCopier(factory *impl.RepositoryFactory, userID string) *Copier {
+	return &Copier{factory: factory, userID: userID}
+}
+
+// Plan 校验复制源与目标目录并统计要复制的文件数、目录数与总大小
+func (c *Copier) Plan(ctx context.Context, sources []Source, targetID int) (*Plan, error) {
+	target, err := c.factory.VirtualPath().GetByID(ctx, targetID)
+	if err != nil || target.UserID != c.userID {
+		return nil, fmt.Errorf("目标目录%w", ErrNotFound)
+	}
+	ancestors, err := dirtree.Ancestors(ctx, c.factory, target)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{targetID: targetID}
+	for _, source := range sources {
+		var n *node
+		if source.FileID != "" {
+			n, err = c.loadFile(ctx, source.FileID)
+		} else {
+			if ancestors[source.DirID] {
+				return nil, ErrIntoSelf
+			}
+			n, err = c.loadDir(ctx, source.DirID, source.Shallow)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if source.Name != "" {
+			n.name = source.Name
+		}
+		plan.add(n)
+		plan.nodes = append(plan.nodes, n)
+	}
+	return plan, nil
+}
+
+// add 累加节点统计
+func (p *Plan) add(n *node) {
+	if n.file != nil {
+		p.Files++
+		p.Size += n.size
+		return
+	}
+	p.Dirs++
+	for _, child := range n.children {
+		p.add(child)
+	}
+}
+
+// Copy 按计划复制到目标目录，整个复制在一个事务中完成
+func (c *Copier) Copy(ctx context.Context, plan *Plan, conflict string) (*Result, error) {
+	if conflict == "" {
+		conflict = ConflictRename
+	}
+	if err := c.CheckQuota(ctx, plan); err != nil {
+		return nil, err
+	}
+	charge := ChargeQuota()
+
+	result := &Result{}
+	c.done, c.created = 0, nil
+	err := c.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := c.factory.WithTx(tx)
+		w := &writer{
+			Copier:   c,
+			ctx:      ctx,
+			factory:  txFactory,
+			conflict: conflict,
+			result:   result,
+			entries:  dirtree.NewCache(ctx, txFactory, c.userID),
+		}
+		for _, n := range plan.nodes {
+			item, err := w.copyNode(n, plan.targetID)
+			if err != nil {
+				return err
+			}
+			if item != nil {
+				result.Items = append(result.Items, item)
+			}
+		}
+
+		// 复制完成后按实际复制的大小扣除配额（跳过的文件不计）
+		if !charge || result.Size == 0 {
+			return nil
+		}
+		user, err := w.factory.User().GetByID(ctx, c.userID)
+		if err != nil {
+			return fmt.Errorf("获取用户信息失败: %w", err)
+		}
+		if user.Space <= 0 {
+			return nil
+		}
+		if user.FreeSpace < result.Size {
+			return ErrInsufficientSpace
+		}
+		user.FreeSpace -= result.Size
+		if err := w.factory.User().Update(ctx, user); err != nil {
+			return fmt.Errorf("更新用户空间失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	search.Notify(c.userID, c.created...)
+	logger.LOG.Info("文件复制完成", "userID", c.userID, "targetID", plan.targetID,
+		"files", result.Files, "dirs", result.Dirs, "skipped", result.Skipped, "size", result.Size)
+	return result, nil
+}
+
+// CheckQuota 检查用户可用空间能否容纳要复制的文件（不扣除配额时直接通过）
+func (c *Copier) CheckQuota(ctx context.Context, plan *Plan) error {
+	if !ChargeQuota() {
+		return nil
+	}
+	user, err := c.factory.User().GetByID(ctx, c.userID)
+	if err != nil {
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.Space > 0 && user.FreeSpace < plan.Size {
+		return ErrInsufficientSpace
+	}
+	return nil
+}
+
+// loadFile 加载要复制的文件
+func (c *Copier) loadFile(ctx context.Context, ufID string) (*node, error) {
+	file, err := c.factory.UserFiles().GetByUserIDAndUfID(ctx, c.userID, ufID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("源文件%w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("获取文件失败: %w", err)
+	}
+	return c.fileNode(ctx, file)
+}
+
+// fileNode 构造文件节点
+func (c *Copier) fileNode(ctx context.Context, file *models.UserFiles) (*node, error) {
+	info, err := c.factory.FileInfo().GetByID(ctx, file.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	return &node{name: file.FileName, file: file, size: int64(info.Size)}, nil
+}
+
+// loadDir 加载要复制的目录树，shallow 为 true 时只加载目录本身
+func (c *Copier) loadDir(ctx context.Context, dirID int, shallow bool) (*node, error) {
+	dir, err := c.factory.VirtualPath().GetByID(ctx, dirID)
+	if err != nil || dir.UserID != c.userID {
+		return nil, fmt.Errorf("源目录%w", ErrNotFound)
+	}
+	if dir.ParentLevel == "" {
+		return nil, fmt.Errorf("不能复制根目录")
+	}
+	n := &node{name: dirtree.DirName(dir.Path), dir: dir}
+	if shallow {
+		return n, nil
+	}
+	if err := c.loadChildren(ctx, n, 0); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// loadChildren 递归加载目录下的子目录与文件
+func (c *Copier) loadChildren(ctx context.Context, n *node, depth int) error {
+	if depth >= dirtree.MaxDepth {
+		return fmt.Errorf("目录层级过深")
+	}
+	files, err := dirtree.ListFiles(ctx, c.factory, c.userID, n.dir.ID)
+	if err != nil {
+		return fmt.Errorf("获取目录文件失败: %w", err)
+	}
+	for _, file := range files {
+		child, err := c.fileNode(ctx, file)
+		if err != nil {
+			return err
+		}
+		n.children = append(n.children, child)
+	}
+	subDirs, err := dirtree.ListSubDirs(ctx, c.factory, c.userID, n.dir.ID)
+	if err != nil {
+		return fmt.Errorf("获取子目录失败: %w", err)
+	}
+	for _, sub := range subDirs {
+		child := &node{name: dirtree.DirName(sub.Path), dir: sub}
+		if err := c.loadChildren(ctx, child, depth+1); err != nil {
+			return err
+		}
+		n.children = append(n.children, child)
+	}
+	return nil
+}
+
+// writer 在事务中执行复制
+type writer struct {
+	*Copier
+	ctx      context.Context
+	factory  *impl.RepositoryFactory
+	conflict string
+	result   *Result
+	// entries 目标目录中已有的文件与子目录
+	entries *dirtree.Cache
+}
+
+// copyNode 将文件或目录复制到目标目录下，跳过时返回 nil
+func (w *writer) copyNode(n *node, targetID int) (*Item, error) {
+	if n.file != nil {
+		return w.copyFile(n, targetID)
+	}
+	return w.copyDir(n, targetID)
+}
+
+// copyFile 复制单个文件：新建 user_files 记录指向同一个 file_info
+func (w *writer) copyFile(n *node, targetID int) (*Item, error) {
+	entries, err := w.entries.Get(targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer w.advance(1)
+
+	name := n.name
+	if existing := entries.Files[name]; existing != nil {
+		switch {
+		case w.conflict == ConflictSkip:
+			w.result.Skipped++
+			return nil, nil
+		// 同名文件就是源文件本身时不能覆盖，改为重命名
+		case w.conflict == ConflictOverwrite && existing.UfID != n.file.UfID:
+			if _, err := recycle.NewBin(w.factory).RecycleFile(w.ctx, w.userID, existing); err != nil {
+				return nil, fmt.Errorf("同名文件移入回收站失败: %w", err)
+			}
+			w.result.Overwritten++
+		default:
+			name = entries.UniqueName(name, true)
+			w.result.Renamed++
+		}
+	}
+
+	userFile := &models.UserFiles{
+		UserID:      w.userID,
+		FileID:      n.file.FileID,
+		FileName:    name,
+		VirtualPath: strconv.Itoa(targetID),
+		IsPublic:    false,
+		CreatedAt:   custom_type.Now(),
+		UfID:        uuid.NewString(),
+	}
+	if err := w.factory.UserFiles().Create(w.ctx, userFile); err != nil {
+		return nil, fmt.Errorf("创建用户文件失败: %w", err)
+	}
+	entries.Files[name] = userFile
+	w.created = append(w.created, userFile.UfID)
+	w.result.Files++
+	w.result.Size += n.size
+	return &Item{FileID: userFile.UfID, Name: name}, nil
+}
+
+// copyDir 复制目录：新建目录后递归复制其中的子目录与文件
+// 覆盖模式下同名目录直接合并，其中的同名文件同样覆盖
+func (w *writer) copyDir(n *node, targetID int) (*Item, error) {
+	entries, err := w.entries.Get(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := n.name
+	existing := entries.Dirs[name]
+	var dirID int
+	switch {
+	case existing == nil:
+	case w.conflict == ConflictSkip:
+		w.result.Skipped++
+		w.advance(n.count())
+		return nil, nil
+	// 同名目录就是源目录本身时不能合并，改为重命名
+	case w.conflict == ConflictOverwrite && existing.ID != n.dir.ID:
+		dirID = existing.ID
+	default:
+		name = entries.UniqueName(name, false)
+		w.result.Renamed++
+	}
+
+	if dirID == 0 {
+		now := custom_type.Now()
+		dir := &models.VirtualPath{
+			UserID:      w.userID,
+			Path:        "/" + name,
+			IsDir:       true,
+			ParentLevel: strconv.Itoa(targetID),
+			CreatedTime: now,
+			UpdateTime:  now,
+		}
+		if err := w.factory.VirtualPath().Create(w.ctx, dir); err != nil {
+			return nil, fmt.Errorf("创建目录失败: %w", err)
+		}
+		entries.Dirs[name] = dir
+		// 新建的目录为空，无需再查询其中的内容
+		w.entries.Put(dir.ID, dirtree.NewEntries())
+		dirID = dir.ID
+		w.result.Dirs++
+	}
+	w.advance(1)
+
+	for _, child := range n.children {
+		if _, err := w.copyNode(child, dirID); err != nil {
+			return nil, err
+		}
+	}
+	return &Item{DirID: dirID, Name: name}, nil
+}
+
+// advance 累加已处理数量并回调进度
+func (w *writer) advance(n int) {
+	w.done += n
+	if w.Progress != nil {
+		w.Progress(w.done)
+	}
+}
@@ -0,0 +1,379 @@
+package filemove
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/dirtree"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
+	"myobj/src/pkg/search"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 文件与目录批量移动：文件修改 user_files 的 virtual_path，目录只修改顶层目录的 parent_level，
+// 子目录与文件随之移动；一批移动在一个事务中完成，任一项失败时整批回滚
+
+// 目标目录已存在同名文件或目录时的处理方式，为空时返回 ErrConflict
+const (
+	// ConflictSkip 跳过同名文件或目录
+	ConflictSkip = "skip"
+	// ConflictRename 重命名为 "名称 (1)" 后移动
+	ConflictRename = "rename"
+	// ConflictOverwrite 同名文件移入回收站后移动，同名目录合并（其中的同名文件同样覆盖）
+	ConflictOverwrite = "overwrite"
+)
+
+var (
+	// ErrConflict 目标目录已存在同名文件或目录且未指定处理方式
+	ErrConflict = errors.New("目标目录已存在同名文件或目录")
+	// ErrIntoSelf 目录不能移动到自身或其子目录中
+	ErrIntoSelf = errors.New("不能将目录移动到自身或其子目录中")
+	// ErrRoot 根目录不能移动
+	ErrRoot = errors.New("不能移动根目录")
+	// ErrNotFound 源文件、源目录或目标目录不存在
+	ErrNotFound = errors.New("文件或目录不存在")
+)
+
+// Source 移动源：FileID 与 DirID 二选一
+type Source struct {
+	// FileID 用户文件ID（uf_id）
+	FileID string
+	// DirID 目录ID
+	DirID int
+	// Name 移动后的名称，为空时沿用原名称
+	Name string
+}
+
+// Plan 移动计划：校验后的移动源
+type Plan struct {
+	// Files 要移动的文件数
+	Files int `json:"files"`
+	// Dirs 要移动的目录数（不含子目录）
+	Dirs int `json:"dirs"`
+	// Conflicts 目标目录中已存在的同名文件与目录
+	Conflicts []string `json:"conflicts"`
+
+	targetID int
+	nodes    []*node
+}
+
+// Names 顶层文件与目录移动后的名称（未处理重名），用于检查目标路径是否被锁定
+func (p *Plan) Names() []string {
+	names := make([]string, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		names = append(names, n.name)
+	}
+	return names
+}
+
+// Result 移动结果
+type Result struct {
+	Files       int `json:"files"`
+	Dirs        int `json:"dirs"`
+	Skipped     int `json:"skipped"`
+	Renamed     int `json:"renamed"`
+	Overwritten int `json:"overwritten"`
+	// Merged 合并到已存在同名目录的目录数
+	Merged int `json:"merged"`
+	// Items 顶层移动结果
+	Items []*Item `json:"items"`
+}
+
+// Item 顶层文件或目录的移动结果
+type Item struct {
+	FileID string `json:"file_id,omitempty"`
+	DirID  int    `json:"dir_id,omitempty"`
+	Name   string `json:"name"`
+}
+
+// node 移动源中的文件或目录
+type node struct {
+	name string
+	file *models.UserFiles
+	dir  *models.VirtualPath
+}
+
+// Mover 文件移动器
+type Mover struct {
+	factory *impl.RepositoryFactory
+	userID  string
+	// renamed 移动时改名的用户文件ID，提交后通知全文索引（只移动位置不需要重建索引）
+	renamed []string
+}
+
+// NewMover 创建文件移动器
+func NewMover(factory *impl.RepositoryFactory, userID string) *Mover {
+	return &Mover{factory: factory, userID: userID}
+}
+
+// Plan 校验移动源与目标目录：目录不能移动到自身或其子目录中，并找出目标目录中的同名文件与目录
+func (m *Mover) Plan(ctx context.Context, sources []Source, targetID int) (*Plan, error) {
+	target, err := m.factory.VirtualPath().GetByID(ctx, targetID)
+	if err != nil || target.UserID != m.userID {
+		return nil, fmt.Errorf("目标目录%w", ErrNotFound)
+	}
+	ancestors, err := dirtree.Ancestors(ctx, m.factory, target)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := dirtree.LoadEntries(ctx, m.factory, m.userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{targetID: targetID}
+	for _, source := range sources {
+		n := &node{}
+		if source.FileID != "" {
+			file, err := m.factory.UserFiles().GetByUserIDAndUfID(ctx, m.userID, source.FileID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, fmt.Errorf("源文件%w", ErrNotFound)
+				}
+				return nil, fmt.Errorf("获取文件失败: %w", err)
+			}
+			n.name, n.file = file.FileName, file
+			plan.Files++
+		} else {
+			dir, err := m.factory.VirtualPath().GetByID(ctx, source.DirID)
+			if err != nil || dir.UserID != m.userID {
+				return nil, fmt.Errorf("源目录%w", ErrNotFound)
+			}
+			if dir.ParentLevel == "" {
+				return nil, ErrRoot
+			}
+			if ancestors[dir.ID] {
+				return nil, ErrIntoSelf
+			}
+			n.name, n.dir = dirtree.DirName(dir.Path), dir
+			plan.Dirs++
+		}
+		if source.Name != "" {
+			n.name = source.Name
+		}
+		if conflicts(entries, n) {
+			plan.Conflicts = append(plan.Conflicts, n.name)
+		}
+		plan.nodes = append(plan.nodes, n)
+	}
+	return plan, nil
+}
+
+// Move 按计划移动到目标目录，整批移动在一个事务中完成
+// conflict 为空且存在同名文件或目录时返回 ErrConflict，不做任何修改
+func (m *Mover) Move(ctx context.Context, plan *Plan, conflict string) (*Result, error) {
+	if conflict == "" && len(plan.Conflicts) > 0 {
+		return nil, ErrConflict
+	}
+
+	result := &Result{}
+	m.renamed = nil
+	err := m.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := m.factory.WithTx(tx)
+		w := &writer{
+			Mover:    m,
+			ctx:      ctx,
+			factory:  txFactory,
+			conflict: conflict,
+			result:   result,
+			entries:  dirtree.NewCache(ctx, txFactory, m.userID),
+			merging:  make(map[int]bool),
+		}
+		for _, n := range plan.nodes {
+			item, err := w.moveNode(n, plan.targetID)
+			if err != nil {
+				return err
+			}
+			if item != nil {
+				result.Items = append(result.Items, item)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	search.Notify(m.userID, m.renamed...)
+	logger.LOG.Info("文件移动完成", "userID", m.userID, "targetID", plan.targetID,
+		"files", result.Files, "dirs", result.Dirs, "skipped", result.Skipped, "merged", result.Merged)
+	return result, nil
+}
+
+// conflicts 节点是否与已有的其他文件或目录重名（与自身同名不算冲突）
+func conflicts(e *dirtree.Entries, n *node) bool {
+	if n.file != nil {
+		existing := e.Files[n.name]
+		return existing != nil && existing.UfID != n.file.UfID
+	}
+	existing := e.Dirs[n.name]
+	return existing != nil && existing.ID != n.dir.ID
+}
+
+// writer 在事务中执行移动
+type writer struct {
+	*Mover
+	ctx      context.Context
+	factory  *impl.RepositoryFactory
+	conflict string
+	result   *Result
+	// entries 目标目录中已有的文件与子目录
+	entries *dirtree.Cache
+	// merging 正在合并的源目录，不能作为合并目标
+	merging map[int]bool
+}
+
+// moveNode 将文件或目录移动到目标目录下，跳过时返回 nil
+func (w *writer) moveNode(n *node, targetID int) (*Item, error) {
+	if n.file != nil {
+		return w.moveFile(n, targetID)
+	}
+	return w.moveDir(n, targetID)
+}
+
+// moveFile 移动单个文件
+func (w *writer) moveFile(n *node, targetID int) (*Item, error) {
+	entries, err := w.entries.Get(targetID)
+	if err != nil {
+		return nil, err
+	}
+	// 已在目标位置时无需移动
+	target := strconv.Itoa(targetID)
+	if n.file.VirtualPath == target && n.file.FileName == n.name {
+		w.result.Skipped++
+		return &Item{FileID: n.file.UfID, Name: n.name}, nil
+	}
+
+	name := n.name
+	if conflicts(entries, n) {
+		switch w.conflict {
+		case ConflictSkip:
+			w.result.Skipped++
+			return nil, nil
+		case ConflictOverwrite:
+			if _, err := recycle.NewBin(w.factory).RecycleFile(w.ctx, w.userID, entries.Files[name]); err != nil {
+				return nil, fmt.Errorf("同名文件移入回收站失败: %w", err)
+			}
+			w.result.Overwritten++
+		case ConflictRename:
+			name = entries.UniqueName(name, true)
+			w.result.Renamed++
+		default:
+			return nil, ErrConflict
+		}
+	}
+
+	w.forgetFile(n.file)
+	if n.file.FileName != name {
+		w.renamed = append(w.renamed, n.file.UfID)
+	}
+	n.file.VirtualPath = target
+	n.file.FileName = name
+	if err := w.factory.UserFiles().Update(w.ctx, n.file); err != nil {
+		return nil, fmt.Errorf("移动文件失败: %w", err)
+	}
+	entries.Files[name] = n.file
+	w.result.Files++
+	return &Item{FileID: n.file.UfID, Name: name}, nil
+}
+
+// moveDir 移动目录：只修改目录本身的上级目录，子目录与文件随之移动
+// 覆盖模式下同名目录直接合并，其中的同名文件同样覆盖
+func (w *writer) moveDir(n *node, targetID int) (*Item, error) {
+	entries, err := w.entries.Get(targetID)
+	if err != nil {
+		return nil, err
+	}
+	target := strconv.Itoa(targetID)
+	if n.dir.ParentLevel == target && dirtree.DirName(n.dir.Path) == n.name {
+		w.result.Skipped++
+		return &Item{DirID: n.dir.ID, Name: n.name}, nil
+	}
+
+	name := n.name
+	if conflicts(entries, n) {
+		existing := entries.Dirs[name]
+		switch {
+		case w.conflict == ConflictSkip:
+			w.result.Skipped++
+			return nil, nil
+		// 合并目标是正在合并的源目录时不能合并，改为重命名
+		case w.conflict == ConflictOverwrite && !w.merging[existing.ID]:
+			if err := w.mergeDir(n.dir, existing); err != nil {
+				return nil, err
+			}
+			w.result.Merged++
+			return &Item{DirID: existing.ID, Name: name}, nil
+		case w.conflict == ConflictRename, w.conflict == ConflictOverwrite:
+			name = entries.UniqueName(name, false)
+			w.result.Renamed++
+		default:
+			return nil, ErrConflict
+		}
+	}
+
+	w.forgetDir(n.dir)
+	n.dir.ParentLevel = target
+	n.dir.Path = "/" + name
+	n.dir.UpdateTime = custom_type.Now()
+	if err := w.factory.VirtualPath().Update(w.ctx, n.dir); err != nil {
+		return nil, fmt.Errorf("移动目录失败: %w", err)
+	}
+	entries.Dirs[name] = n.dir
+	w.result.Dirs++
+	return &Item{DirID: n.dir.ID, Name: name}, nil
+}
+
+// mergeDir 将目录中的子目录与文件逐个移动到已存在的同名目录，完成后删除已清空的源目录
+func (w *writer) mergeDir(dir, existing *models.VirtualPath) error {
+	w.merging[dir.ID] = true
+	defer delete(w.merging, dir.ID)
+
+	children, err := dirtree.LoadEntries(w.ctx, w.factory, w.userID, dir.ID)
+	if err != nil {
+		return err
+	}
+	for name, file := range children.Files {
+		if _, err := w.moveFile(&node{name: name, file: file}, existing.ID); err != nil {
+			return err
+		}
+	}
+	for name, sub := range children.Dirs {
+		if _, err := w.moveDir(&node{name: name, dir: sub}, existing.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := w.factory.VirtualPath().Purge(w.ctx, []int{dir.ID}); err != nil {
+		return fmt.Errorf("删除已合并目录失败: %w", err)
+	}
+	w.forgetDir(dir)
+	return nil
+}
+
+// forgetFile 文件移出原目录后，从原目录的缓存中移除
+func (w *writer) forgetFile(file *models.UserFiles) {
+	dirID, _ := strconv.Atoi(file.VirtualPath)
+	if entries, ok := w.entries.Cached(dirID); ok {
+		if cached := entries.Files[file.FileName]; cached != nil && cached.UfID == file.UfID {
+			delete(entries.Files, file.FileName)
+		}
+	}
+}
+
+// forgetDir 目录移出原上级目录后，从原上级目录的缓存中移除
+func (w *writer) forgetDir(dir *models.VirtualPath) {
+	parentID, _ := strconv.Atoi(dir.ParentLevel)
+	if entries, ok := w.entries.Cached(parentID); ok {
+		name := dirtree.DirName(dir.Path)
+		if cached := entries.Dirs[name]; cached != nil && cached.ID == dir.ID {
+			delete(entries.Dirs, name)
+		}
+	}
+}
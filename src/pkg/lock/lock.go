@@ -0,0 +1,314 @@
+// Package lock 提供基于数据库的 WebDAV 锁管理，供 WebDAV、SFTP 与 REST 接口共用
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTimeout 客户端未指定或指定 Infinite 时使用的锁超时时间
+	DefaultTimeout = time.Hour
+	// MaxTimeout 锁的最大超时时间，避免客户端崩溃后锁长期残留
+	MaxTimeout = 24 * time.Hour
+
+	// TokenPrefix 锁令牌的前缀
+	TokenPrefix = "opaquelocktoken:"
+)
+
+var (
+	// ErrLocked 资源已被 WebDAV 锁定
+	ErrLocked = errors.New("文件已被 WebDAV 锁定，请稍后再试")
+	// ErrNoSuchLock 锁不存在或已过期
+	ErrNoSuchLock = errors.New("锁不存在或已过期")
+)
+
+// Manager 基于数据库的 WebDAV 锁管理器
+// 锁持久化在 webdav_lock 表中，服务重启或多实例部署时依然有效；
+// 锁只作用于持有者自己的命名空间，路径格式为 "/目录/文件名"
+type Manager struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewManager 创建锁管理器
+func NewManager(factory *impl.RepositoryFactory) *Manager {
+	return &Manager{factory: factory}
+}
+
+// Request 创建锁的参数
+type Request struct {
+	Root      string
+	Shared    bool
+	ZeroDepth bool
+	OwnerXML  string
+	Duration  time.Duration
+}
+
+// Create 创建锁，与已有锁冲突时返回 ErrLocked
+// 冲突规则：独占锁与任何重叠的锁冲突，共享锁只与重叠的独占锁冲突
+func (m *Manager) Create(ctx context.Context, userID string, req Request) (*models.WebDAVLock, error) {
+	root := Path(req.Root)
+	duration := normalizeTimeout(req.Duration)
+	now := time.Now()
+
+	lock := &models.WebDAVLock{
+		Token:     TokenPrefix + uuid.Must(uuid.NewRandom()).String(),
+		UserID:    userID,
+		Root:      root,
+		Shared:    req.Shared,
+		ZeroDepth: req.ZeroDepth,
+		OwnerXML:  req.OwnerXML,
+		Timeout:   int64(duration / time.Second),
+		ExpiresAt: custom_type.JsonTime(now.Add(duration)),
+		CreatedAt: custom_type.JsonTime(now),
+	}
+
+	// 锁定用户记录后再检查冲突并写入，同一用户的并发 LOCK 依次执行，避免同时创建重叠的独占锁
+	err := m.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := m.factory.WithTx(tx)
+		if _, err := txFactory.User().GetByIDForUpdate(ctx, userID); err != nil {
+			return err
+		}
+		repo := txFactory.WebDAVLock()
+		locks, err := repo.ListActiveByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, l := range locks {
+			if !locksOverlap(l.Root, l.ZeroDepth, root, req.ZeroDepth) {
+				continue
+			}
+			if l.Shared && req.Shared {
+				continue
+			}
+			return ErrLocked
+		}
+		return repo.Create(ctx, lock)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrLocked) {
+			logger.LOG.Error("创建 WebDAV 锁失败", "user_id", userID, "root", root, "error", err)
+		}
+		return nil, err
+	}
+
+	logger.LOG.Info("WebDAV 锁已创建",
+		"user_id", userID,
+		"root", root,
+		"shared", req.Shared,
+		"zero_depth", req.ZeroDepth,
+		"timeout", lock.Timeout,
+	)
+	return lock, nil
+}
+
+// Refresh 刷新锁的超时时间
+func (m *Manager) Refresh(ctx context.Context, userID, token string, duration time.Duration) (*models.WebDAVLock, error) {
+	lock, err := m.getActiveLock(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+	duration = normalizeTimeout(duration)
+	lock.Timeout = int64(duration / time.Second)
+	lock.ExpiresAt = custom_type.JsonTime(time.Now().Add(duration))
+	if err := m.factory.WebDAVLock().Update(ctx, lock); err != nil {
+		logger.LOG.Error("刷新 WebDAV 锁失败", "token", token, "error", err)
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Unlock 释放锁（只能释放自己的锁）
+func (m *Manager) Unlock(ctx context.Context, userID, token string) error {
+	if _, err := m.getActiveLock(ctx, userID, token); err != nil {
+		return err
+	}
+	if err := m.factory.WebDAVLock().Delete(ctx, token); err != nil {
+		logger.LOG.Error("释放 WebDAV 锁失败", "token", token, "error", err)
+		return err
+	}
+	logger.LOG.Info("WebDAV 锁已释放", "user_id", userID, "token", token)
+	return nil
+}
+
+// Break 强制解除锁（管理员操作，不校验持有者）
+func (m *Manager) Break(ctx context.Context, token string) (*models.WebDAVLock, error) {
+	lock, err := m.factory.WebDAVLock().GetByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoSuchLock
+		}
+		return nil, err
+	}
+	if err := m.factory.WebDAVLock().Delete(ctx, token); err != nil {
+		return nil, err
+	}
+	logger.LOG.Warn("WebDAV 锁已被强制解除", "user_id", lock.UserID, "root", lock.Root, "token", token)
+	return lock, nil
+}
+
+// Confirm 校验请求携带的锁令牌能否操作指定资源
+// names 中每个路径（及其子树）上的锁都必须被满足：独占锁需提供其令牌，共享锁需提供其中任一令牌；
+// 请求中携带的令牌也必须是当前用户有效的锁
+func (m *Manager) Confirm(ctx context.Context, userID string, names []string, tokens []string) error {
+	locks, err := m.factory.WebDAVLock().ListActiveByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	held := make(map[string]bool, len(tokens))
+	for _, t := range tokens {
+		held[t] = true
+	}
+	valid := 0
+	for _, l := range locks {
+		if held[l.Token] {
+			valid++
+		}
+	}
+	if valid < len(held) {
+		return ErrNoSuchLock
+	}
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		root := Path(name)
+		sharedLocked, sharedHeld := false, false
+		for _, l := range locks {
+			if !locksOverlap(l.Root, l.ZeroDepth, root, false) {
+				continue
+			}
+			if l.Shared {
+				sharedLocked = true
+				sharedHeld = sharedHeld || held[l.Token]
+				continue
+			}
+			if !held[l.Token] {
+				return ErrLocked
+			}
+		}
+		if sharedLocked && !sharedHeld {
+			return ErrLocked
+		}
+	}
+	return nil
+}
+
+// CheckUnlocked 检查资源及其子树是否未被锁定，被锁定时返回 ErrLocked
+func (m *Manager) CheckUnlocked(ctx context.Context, userID string, names ...string) error {
+	return m.Confirm(ctx, userID, names, nil)
+}
+
+// FilePath 获取用户文件在 WebDAV 命名空间中的路径
+func (m *Manager) FilePath(ctx context.Context, userFile *models.UserFiles) (string, error) {
+	dirID, err := strconv.Atoi(userFile.VirtualPath)
+	if err != nil {
+		return "", fmt.Errorf("无效的虚拟路径: %s", userFile.VirtualPath)
+	}
+	dir, err := m.DirPath(ctx, dirID)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, userFile.FileName), nil
+}
+
+// DirPath 获取虚拟目录在 WebDAV 命名空间中的路径（根目录为 "/"）
+func (m *Manager) DirPath(ctx context.Context, dirID int) (string, error) {
+	var parts []string
+	// 限制层级深度，防止异常数据导致死循环
+	for depth := 0; depth < 256; depth++ {
+		dir, err := m.factory.VirtualPath().GetByID(ctx, dirID)
+		if err != nil {
+			return "", err
+		}
+		if dir.ParentLevel == "" {
+			return "/" + strings.Join(parts, "/"), nil
+		}
+		parts = append([]string{path.Base("/" + strings.TrimPrefix(dir.Path, "/"))}, parts...)
+		if dirID, err = strconv.Atoi(dir.ParentLevel); err != nil {
+			return "", fmt.Errorf("无效的父目录: %s", dir.ParentLevel)
+		}
+	}
+	return "", fmt.Errorf("目录层级过深")
+}
+
+// CheckFileUnlocked 检查用户文件是否未被 WebDAV 锁定（供 REST 接口使用）
+// extra 为操作的目标路径（如移动、重命名后的新路径），同样需要未被锁定
+func (m *Manager) CheckFileUnlocked(ctx context.Context, userFile *models.UserFiles, extra ...string) error {
+	p, err := m.FilePath(ctx, userFile)
+	if err != nil {
+		// 路径无法解析时不阻塞业务操作
+		logger.LOG.Warn("解析文件路径失败，跳过锁检查", "uf_id", userFile.UfID, "error", err)
+		return nil
+	}
+	return m.CheckUnlocked(ctx, userFile.UserID, append([]string{p}, extra...)...)
+}
+
+// CleanupExpired 清理已过期的锁
+func (m *Manager) CleanupExpired(ctx context.Context) (int64, error) {
+	return m.factory.WebDAVLock().DeleteExpired(ctx)
+}
+
+// getActiveLock 获取用户未过期的锁
+func (m *Manager) getActiveLock(ctx context.Context, userID, token string) (*models.WebDAVLock, error) {
+	lock, err := m.factory.WebDAVLock().GetByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoSuchLock
+		}
+		return nil, err
+	}
+	if lock.UserID != userID || !time.Time(lock.ExpiresAt).After(time.Now()) {
+		return nil, ErrNoSuchLock
+	}
+	return lock, nil
+}
+
+// Path 规范化锁路径
+func Path(name string) string {
+	return path.Clean("/" + name)
+}
+
+// IsDescendant 判断 p 是否位于 root 之下（不含 root 本身）
+func IsDescendant(p, root string) bool {
+	if root == "/" {
+		return p != "/"
+	}
+	return strings.HasPrefix(p, root+"/")
+}
+
+// locksOverlap 判断两个锁定范围是否重叠
+func locksOverlap(rootA string, zeroDepthA bool, rootB string, zeroDepthB bool) bool {
+	if rootA == rootB {
+		return true
+	}
+	if !zeroDepthA && IsDescendant(rootB, rootA) {
+		return true
+	}
+	return !zeroDepthB && IsDescendant(rootA, rootB)
+}
+
+// normalizeTimeout 规范化超时时间
+func normalizeTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultTimeout
+	}
+	if d > MaxTimeout {
+		return MaxTimeout
+	}
+	return d
+}
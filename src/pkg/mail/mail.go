@@ -0,0 +1,183 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"myobj/src/config"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message 邮件
+type Message struct {
+	// To 收件人地址
+	To string
+	// Subject 标题
+	Subject string
+	// HTML HTML 正文
+	HTML string
+}
+
+// Sender 邮件发送方式
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// NewSender 根据配置创建发送方式（smtp 或 file）
+func NewSender(cfg config.Mail) Sender {
+	if cfg.Transport == "file" {
+		return &fileSender{cfg: cfg}
+	}
+	return &smtpSender{cfg: cfg}
+}
+
+// smtpSender 通过 SMTP 服务器发送
+type smtpSender struct {
+	cfg config.Mail
+}
+
+// Send 发送邮件，支持 STARTTLS 与隐式 TLS（ssl）
+func (s *smtpSender) Send(msg *Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %w", err)
+	}
+	data, err := buildMessage(s.cfg, to.Address, msg, true)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	var conn net.Conn
+	if s.cfg.Encryption == "ssl" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if s.cfg.Encryption == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS 失败: %w", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// fileMu 串行化 file 方式的写入（每次发送都会创建新的 Sender）
+var fileMu sync.Mutex
+
+// fileSender 将邮件追加写入本地文件（不实际发送，用于测试或没有 SMTP 服务器的环境）
+type fileSender struct {
+	cfg config.Mail
+}
+
+// Send 追加写入邮件，正文不编码以便直接阅读
+func (f *fileSender) Send(msg *Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %w", err)
+	}
+	data, err := buildMessage(f.cfg, to.Address, msg, false)
+	if err != nil {
+		return err
+	}
+	fileMu.Lock()
+	defer fileMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(f.cfg.FilePath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.cfg.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, []byte("\r\n\r\n")...))
+	return err
+}
+
+// buildMessage 生成 MIME 邮件内容
+// base64Body 为 false 时正文以 8bit 原样写入
+func buildMessage(cfg config.Mail, to string, msg *Message, base64Body bool) ([]byte, error) {
+	from := mail.Address{Name: cfg.FromName, Address: cfg.From}
+	idBuf := make([]byte, 16)
+	if _, err := rand.Read(idBuf); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(cfg.From, "@"); at >= 0 {
+		domain = cfg.From[at+1:]
+	}
+	encoding := "8bit"
+	if base64Body {
+		encoding = "base64"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + to + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("Message-ID: <" + hex.EncodeToString(idBuf) + "@" + domain + ">\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: " + encoding + "\r\n\r\n")
+	if !base64Body {
+		buf.WriteString(msg.HTML)
+		return buf.Bytes(), nil
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.HTML))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"myobj/src/config"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// 邮件模板（templates/mail 下的 <名称>.html）
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+	TemplateInvitation    = "invitation"
+)
+
+// Mailer 邮件发送器：渲染 templates/mail 下的模板并通过配置的方式发送
+// 模板使用 html/template 语法，需定义名为 subject 的子模板作为邮件标题，
+// 可使用的变量除调用方传入的数据外，还有 SiteName（发件人名称）与 SiteURL
+type Mailer struct {
+	cfg    config.Mail
+	sender Sender
+}
+
+// NewMailer 创建邮件发送器
+func NewMailer(cfg config.Mail) *Mailer {
+	return &Mailer{cfg: cfg, sender: NewSender(cfg)}
+}
+
+// Enabled 是否启用邮件
+func (m *Mailer) Enabled() bool {
+	return m.cfg.Enable
+}
+
+// Link 生成邮件中的站点链接，path 为站点内路径
+func (m *Mailer) Link(path string, query url.Values) string {
+	link := strings.TrimRight(m.cfg.SiteURL, "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// Send 渲染模板并发送邮件
+func (m *Mailer) Send(to, name string, data map[string]any) error {
+	if !m.cfg.Enable {
+		return fmt.Errorf("邮件功能未启用")
+	}
+	msg, err := m.Render(to, name, data)
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(msg)
+}
+
+// Render 渲染模板生成邮件
+func (m *Mailer) Render(to, name string, data map[string]any) (*Message, error) {
+	tmpl, err := template.ParseFiles(filepath.Join(m.cfg.TemplateDir, name+".html"))
+	if err != nil {
+		return nil, fmt.Errorf("加载邮件模板失败: %w", err)
+	}
+	if tmpl.Lookup("subject") == nil {
+		return nil, fmt.Errorf("邮件模板 %s 缺少 subject 定义", name)
+	}
+	values := map[string]any{
+		"SiteName": m.cfg.FromName,
+		"SiteURL":  strings.TrimRight(m.cfg.SiteURL, "/"),
+	}
+	for k, v := range data {
+		values[k] = v
+	}
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", values); err != nil {
+		return nil, fmt.Errorf("渲染邮件标题失败: %w", err)
+	}
+	if err := tmpl.Execute(&body, values); err != nil {
+		return nil, fmt.Errorf("渲染邮件内容失败: %w", err)
+	}
+	return &Message{
+		To: to,
+		// 标题为纯文本，还原 html/template 的转义
+		Subject: strings.TrimSpace(html.UnescapeString(subject.String())),
+		HTML:    body.String(),
+	}, nil
+}
@@ -0,0 +1,474 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxTextFrameSize 读取的 ID3 文本帧与 Vorbis 注释的最大字节数（跳过封面图片等大帧）
+const maxTextFrameSize = 64 * 1024
+
+// id3Genres ID3v1 的流派编号（ID3v2 的 TCON 也可能以 "(编号)" 表示）
+var id3Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz", "Metal",
+	"New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno", "Industrial",
+	"Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk",
+	"Fusion", "Trance", "Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic",
+	"Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta",
+	"Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes",
+	"Trailer", "Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+}
+
+// genreName 流派名称，"(17)"、"17"、"(17)Rock" 等形式转为名称
+func genreName(genre string) string {
+	genre = strings.TrimSpace(genre)
+	if strings.HasPrefix(genre, "(") {
+		if end := strings.IndexByte(genre, ')'); end > 0 {
+			if rest := strings.TrimSpace(genre[end+1:]); rest != "" {
+				return rest
+			}
+			genre = genre[1:end]
+		}
+	}
+	if n, err := strconv.Atoi(genre); err == nil {
+		if n >= 0 && n < len(id3Genres) {
+			return id3Genres[n]
+		}
+		return ""
+	}
+	return genre
+}
+
+// leadingInt 字符串开头的整数（"2004-05-01" 取 2004，"3/12" 取 3）
+func leadingInt(s string) int {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// setTag 按标签名写入元数据，已有值的字段不覆盖
+func setTag(m *models.FileMetadata, key, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	switch strings.ToUpper(key) {
+	case "TITLE":
+		if m.Title == "" {
+			m.Title = value
+		}
+	case "ARTIST":
+		if m.Artist == "" {
+			m.Artist = value
+		}
+	case "ALBUM":
+		if m.Album == "" {
+			m.Album = value
+		}
+	case "GENRE":
+		if m.Genre == "" {
+			m.Genre = genreName(value)
+		}
+	case "DATE", "YEAR":
+		if m.Year == 0 {
+			m.Year = leadingInt(value)
+		}
+	case "TRACKNUMBER", "TRACK":
+		if m.Track == 0 {
+			m.Track = leadingInt(value)
+		}
+	}
+}
+
+// decodeLegacyText 解码未标明编码的文本（ID3 的 ISO-8859-1 文本中常见 GBK 编码的中文）
+// 依次按 UTF-8、GB18030 解码，都不合法时按 ISO-8859-1 处理
+func decodeLegacyText(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	if text, err := util.DecodeText(data, util.CharsetGB18030); err == nil && !strings.ContainsRune(text, utf8.RuneError) {
+		return text
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// decodeUTF16 解码 UTF-16 文本，有 BOM 时按 BOM 判断字节序
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFF && data[1] == 0xFE:
+			order, data = binary.LittleEndian, data[2:]
+		case data[0] == 0xFE && data[1] == 0xFF:
+			order, data = binary.BigEndian, data[2:]
+		}
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// id3Text 解码 ID3v2 文本帧，多个值时只取第一个
+func id3Text(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	var text string
+	switch data[0] {
+	case 1:
+		text = decodeUTF16(data[1:], binary.LittleEndian)
+	case 2:
+		text = decodeUTF16(data[1:], binary.BigEndian)
+	case 3:
+		text = string(data[1:])
+	default:
+		if end := bytes.IndexByte(data[1:], 0); end >= 0 {
+			data = data[:end+1]
+		}
+		text = decodeLegacyText(data[1:])
+	}
+	if end := strings.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+	return text
+}
+
+// id3Frames ID3v2 文本帧对应的标签（v2.2 使用三个字符的帧ID）
+var id3Frames = map[string]string{
+	"TIT2": "TITLE", "TT2": "TITLE",
+	"TPE1": "ARTIST", "TP1": "ARTIST",
+	"TALB": "ALBUM", "TAL": "ALBUM",
+	"TCON": "GENRE", "TCO": "GENRE",
+	"TYER": "YEAR", "TYE": "YEAR", "TDRC": "YEAR",
+	"TRCK": "TRACK", "TRK": "TRACK",
+}
+
+// syncsafe 解析 ID3v2 的 syncsafe 整数（每字节只用低 7 位）
+func syncsafe(b []byte) int64 {
+	return int64(b[0]&0x7F)<<21 | int64(b[1]&0x7F)<<14 | int64(b[2]&0x7F)<<7 | int64(b[3]&0x7F)
+}
+
+// parseID3v2 解析文件开头的 ID3v2 标签，返回标签的总长度（没有标签时为 0）
+func parseID3v2(r io.ReaderAt, size int64, m *models.FileMetadata) int64 {
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return 0
+	}
+	version, flags := header[3], header[5]
+	tagSize := 10 + syncsafe(header[6:10])
+	if flags&0x10 != 0 { // 标签尾
+		tagSize += 10
+	}
+	end := min(tagSize, size)
+	pos := int64(10)
+	if flags&0x40 != 0 { // 扩展头
+		ext := make([]byte, 4)
+		if _, err := r.ReadAt(ext, pos); err != nil {
+			return tagSize
+		}
+		if version == 4 {
+			pos += syncsafe(ext)
+		} else {
+			pos += 4 + int64(binary.BigEndian.Uint32(ext))
+		}
+	}
+
+	idLen, headerLen := 4, int64(10)
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+	frame := make([]byte, headerLen)
+	for pos+headerLen <= end {
+		if _, err := r.ReadAt(frame, pos); err != nil {
+			break
+		}
+		if frame[0] == 0 { // 填充
+			break
+		}
+		id := string(frame[:idLen])
+		var frameSize int64
+		var frameFlags uint16
+		switch version {
+		case 2:
+			frameSize = int64(frame[3])<<16 | int64(frame[4])<<8 | int64(frame[5])
+		case 4:
+			frameSize = syncsafe(frame[4:8])
+			frameFlags = binary.BigEndian.Uint16(frame[8:])
+		default:
+			frameSize = int64(binary.BigEndian.Uint32(frame[4:8]))
+			frameFlags = binary.BigEndian.Uint16(frame[8:])
+		}
+		pos += headerLen
+		if frameSize <= 0 || pos+frameSize > end {
+			break
+		}
+		if key, ok := id3Frames[id]; ok && frameSize <= maxTextFrameSize {
+			data := make([]byte, frameSize)
+			if _, err := r.ReadAt(data, pos); err == nil {
+				if version == 4 && frameFlags&0x0001 != 0 && len(data) > 4 { // 数据长度指示
+					data = data[4:]
+				}
+				setTag(m, key, id3Text(data))
+			}
+		}
+		pos += frameSize
+	}
+	return tagSize
+}
+
+// parseID3v1 解析文件末尾的 ID3v1 标签（只补充 ID3v2 中没有的字段），返回是否存在
+func parseID3v1(r io.ReaderAt, size int64, m *models.FileMetadata) bool {
+	if size < 128 {
+		return false
+	}
+	tag := make([]byte, 128)
+	if _, err := r.ReadAt(tag, size-128); err != nil || string(tag[:3]) != "TAG" {
+		return false
+	}
+	field := func(b []byte) string {
+		if end := bytes.IndexByte(b, 0); end >= 0 {
+			b = b[:end]
+		}
+		return decodeLegacyText(bytes.TrimRight(b, " "))
+	}
+	setTag(m, "TITLE", field(tag[3:33]))
+	setTag(m, "ARTIST", field(tag[33:63]))
+	setTag(m, "ALBUM", field(tag[63:93]))
+	setTag(m, "YEAR", field(tag[93:97]))
+	if tag[125] == 0 && tag[126] != 0 { // ID3v1.1 音轨号
+		setTag(m, "TRACK", strconv.Itoa(int(tag[126])))
+	}
+	if int(tag[127]) < len(id3Genres) {
+		setTag(m, "GENRE", id3Genres[tag[127]])
+	}
+	return true
+}
+
+// MPEG 音频帧的码率（kbps），按 [MPEG1 层I、层II、层III，MPEG2/2.5 层I、层II/III] 排列
+var mpegBitrates = [5][16]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// mpegFrame MPEG 音频帧头
+type mpegFrame struct {
+	mpeg1      bool
+	layer      int
+	bitrate    int // kbps
+	sampleRate int
+	mono       bool
+}
+
+// parseMPEGFrame 解析 MPEG 音频帧头，不是合法帧头时返回 false
+func parseMPEGFrame(h []byte) (mpegFrame, bool) {
+	if h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mpegFrame{}, false
+	}
+	version, layerBits := (h[1]>>3)&3, (h[1]>>1)&3
+	bitrateIndex, rateIndex := h[2]>>4, (h[2]>>2)&3
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mpegFrame{}, false
+	}
+	f := mpegFrame{mpeg1: version == 3, layer: 4 - int(layerBits), mono: h[3]>>6 == 3}
+	table := 3
+	if f.layer != 1 {
+		table = 4
+	}
+	if f.mpeg1 {
+		table = f.layer - 1
+	}
+	f.bitrate = mpegBitrates[table][bitrateIndex]
+	f.sampleRate = []int{44100, 48000, 32000}[rateIndex]
+	switch version {
+	case 2: // MPEG2
+		f.sampleRate /= 2
+	case 0: // MPEG2.5
+		f.sampleRate /= 4
+	}
+	return f, true
+}
+
+// samplesPerFrame 每帧的采样数
+func (f mpegFrame) samplesPerFrame() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && !f.mpeg1:
+		return 576
+	}
+	return 1152
+}
+
+// parseMP3 解析 MP3 的 ID3 标签与时长
+// 时长优先按 Xing/Info、VBRI 头中的总帧数计算，没有时按固定码率估算
+func parseMP3(r io.ReaderAt, size int64, m *models.FileMetadata) error {
+	start := parseID3v2(r, size, m)
+	end := size
+	if parseID3v1(r, size, m) {
+		end -= 128
+	}
+
+	buf := make([]byte, 64*1024)
+	n, _ := r.ReadAt(buf, start)
+	buf = buf[:n]
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMPEGFrame(buf[i : i+4])
+		if !ok {
+			continue
+		}
+		// Xing/Info 头位于第一帧的边信息之后
+		if frames := vbrFrames(buf[i:], 4+frame.sideInfoSize()); frames > 0 {
+			m.Duration = float64(frames) * float64(frame.samplesPerFrame()) / float64(frame.sampleRate)
+		} else if frame.bitrate > 0 {
+			m.Duration = float64(end-start-int64(i)) * 8 / float64(frame.bitrate*1000)
+		}
+		return nil
+	}
+	return nil
+}
+
+// sideInfoSize 帧头之后边信息的字节数
+func (f mpegFrame) sideInfoSize() int {
+	switch {
+	case f.mpeg1 && f.mono:
+		return 17
+	case f.mpeg1:
+		return 32
+	case f.mono:
+		return 9
+	}
+	return 17
+}
+
+// vbrFrames 读取 Xing/Info 或 VBRI 头中的总帧数，没有时返回 0
+func vbrFrames(frame []byte, xingOffset int) uint32 {
+	if len(frame) >= xingOffset+12 {
+		tag := string(frame[xingOffset : xingOffset+4])
+		if tag == "Xing" || tag == "Info" {
+			if flags := binary.BigEndian.Uint32(frame[xingOffset+4:]); flags&1 != 0 {
+				return binary.BigEndian.Uint32(frame[xingOffset+8:])
+			}
+			return 0
+		}
+	}
+	if len(frame) >= 36+18 && string(frame[36:40]) == "VBRI" {
+		return binary.BigEndian.Uint32(frame[36+14:])
+	}
+	return 0
+}
+
+// parseFLAC 解析 FLAC 的 STREAMINFO（时长）与 Vorbis 注释（标签）
+func parseFLAC(r io.ReaderAt, size int64, m *models.FileMetadata) error {
+	pos := parseID3v2(r, size, m)
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, pos); err != nil || string(magic) != "fLaC" {
+		return ErrUnsupported
+	}
+	pos += 4
+	header := make([]byte, 4)
+	for pos+4 <= size {
+		if _, err := r.ReadAt(header, pos); err != nil {
+			return err
+		}
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7F
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		pos += 4
+		switch {
+		case blockType == 0 && length >= 34: // STREAMINFO
+			info := make([]byte, 34)
+			if _, err := r.ReadAt(info, pos); err != nil {
+				return err
+			}
+			sampleRate := int64(info[10])<<12 | int64(info[11])<<4 | int64(info[12])>>4
+			samples := int64(info[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(info[14:18]))
+			if sampleRate > 0 {
+				m.Duration = float64(samples) / float64(sampleRate)
+			}
+		case blockType == 4 && length <= maxTextFrameSize: // VORBIS_COMMENT
+			data := make([]byte, length)
+			if _, err := r.ReadAt(data, pos); err != nil {
+				return err
+			}
+			parseVorbisComment(data, m)
+		}
+		pos += length
+		if last {
+			break
+		}
+	}
+	return nil
+}
+
+// parseVorbisComment 解析 Vorbis 注释（小端长度前缀的 "KEY=value" 列表）
+func parseVorbisComment(data []byte, m *models.FileMetadata) {
+	if len(data) < 8 {
+		return
+	}
+	pos := 4 + int(binary.LittleEndian.Uint32(data)) // 跳过编码器名称
+	if pos+4 > len(data) || pos < 4 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(data[pos:]))
+	pos += 4
+	for i := 0; i < count && pos+4 <= len(data); i++ {
+		length := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if length < 0 || pos+length > len(data) {
+			return
+		}
+		if key, value, ok := strings.Cut(string(data[pos:pos+length]), "="); ok {
+			setTag(m, key, value)
+		}
+		pos += length
+	}
+}
+
+// parseWAV 解析 WAV 的时长（数据块大小 / 每秒字节数）
+func parseWAV(r io.ReaderAt, size int64, m *models.FileMetadata) error {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return ErrUnsupported
+	}
+	var byteRate, dataSize int64
+	chunk := make([]byte, 20)
+	for pos := int64(12); pos+8 <= size; {
+		n, _ := r.ReadAt(chunk, pos)
+		if n < 8 {
+			break
+		}
+		length := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if n >= 20 {
+				byteRate = int64(binary.LittleEndian.Uint32(chunk[16:20]))
+			}
+		case "data":
+			dataSize = min(length, size-pos-8)
+		}
+		pos += 8 + length + length%2
+	}
+	if byteRate > 0 && dataSize > 0 {
+		m.Duration = float64(dataSize) / float64(byteRate)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+// ErrNoEXIF 图片中没有 EXIF 信息
+var ErrNoEXIF = errors.New("没有 EXIF 信息")
+
+// EXIF 标签
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+
+	gpsLatitudeRef  = 0x0001
+	gpsLatitude     = 0x0002
+	gpsLongitudeRef = 0x0003
+	gpsLongitude    = 0x0004
+)
+
+// maxIFDEntries 单个 IFD 的最大条目数，超过时视为数据损坏
+const maxIFDEntries = 1000
+
+// maxTagValueSize 读取的标签值最大字节数
+const maxTagValueSize = 64 * 1024
+
+// EXIF 照片 EXIF 中的常用信息
+type EXIF struct {
+	Make  string
+	Model string
+	// DateTime 拍摄时间（DateTimeOriginal，没有时为 DateTime），格式 2006:01:02 15:04:05，没有时区
+	DateTime string
+	// Orientation 方向（1-8），没有时为 1
+	Orientation int
+	// Latitude 纬度（十进制度数），没有坐标时为 nil
+	Latitude *float64
+	// Longitude 经度
+	Longitude *float64
+	// HasGPS 是否包含 GPS 信息（可能只有海拔、时间等，没有坐标）
+	HasGPS bool
+}
+
+// tiffTypeSizes TIFF 字段类型对应的字节数
+var tiffTypeSizes = map[uint16]int64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// tiffReader EXIF 数据块或 TIFF 文件
+type tiffReader struct {
+	r     io.ReaderAt
+	size  int64
+	order binary.ByteOrder
+}
+
+// ifdEntry IFD 中的一个标签
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	// pos 标签条目的位置
+	pos int64
+	// offset 标签值的位置（不超过 4 字节时在条目内）
+	offset int64
+	// size 标签值的字节数
+	size int64
+}
+
+// newTIFFReader 解析 TIFF 头，返回 IFD0 的位置
+func newTIFFReader(r io.ReaderAt, size int64) (*tiffReader, int64, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, 0, ErrNoEXIF
+	}
+	t := &tiffReader{r: r, size: size}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, ErrNoEXIF
+	}
+	if t.order.Uint16(header[2:]) != 42 {
+		return nil, 0, ErrNoEXIF
+	}
+	return t, int64(t.order.Uint32(header[4:])), nil
+}
+
+// readIFD 读取 IFD 的全部条目
+func (t *tiffReader) readIFD(offset int64) ([]ifdEntry, error) {
+	if offset < 8 || offset+2 > t.size {
+		return nil, ErrNoEXIF
+	}
+	buf := make([]byte, 2)
+	if _, err := t.r.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+	count := int64(t.order.Uint16(buf))
+	if count > maxIFDEntries || offset+2+count*12 > t.size {
+		return nil, ErrNoEXIF
+	}
+	data := make([]byte, count*12)
+	if _, err := t.r.ReadAt(data, offset+2); err != nil {
+		return nil, err
+	}
+	entries := make([]ifdEntry, 0, count)
+	for i := int64(0); i < count; i++ {
+		raw := data[i*12 : i*12+12]
+		e := ifdEntry{tag: t.order.Uint16(raw), typ: t.order.Uint16(raw[2:]), count: t.order.Uint32(raw[4:]), pos: offset + 2 + i*12}
+		typeSize, ok := tiffTypeSizes[e.typ]
+		if !ok {
+			continue
+		}
+		e.size = typeSize * int64(e.count)
+		if e.size <= 4 {
+			e.offset = e.pos + 8
+		} else {
+			e.offset = int64(t.order.Uint32(raw[8:]))
+		}
+		if e.offset+e.size > t.size {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// value 读取标签值
+func (t *tiffReader) value(e ifdEntry) []byte {
+	if e.size > maxTagValueSize {
+		return nil
+	}
+	buf := make([]byte, e.size)
+	if _, err := t.r.ReadAt(buf, e.offset); err != nil {
+		return nil
+	}
+	return buf
+}
+
+// str 读取 ASCII 标签
+func (t *tiffReader) str(e ifdEntry) string {
+	return strings.TrimSpace(strings.TrimRight(string(t.value(e)), "\x00"))
+}
+
+// uint 读取 SHORT/LONG 标签的第一个值
+func (t *tiffReader) uint(e ifdEntry) uint32 {
+	buf := t.value(e)
+	switch {
+	case e.typ == 3 && len(buf) >= 2:
+		return uint32(t.order.Uint16(buf))
+	case e.typ == 4 && len(buf) >= 4:
+		return t.order.Uint32(buf)
+	}
+	return 0
+}
+
+// rationals 读取 RATIONAL 标签
+func (t *tiffReader) rationals(e ifdEntry) []float64 {
+	if e.typ != 5 {
+		return nil
+	}
+	buf := t.value(e)
+	values := make([]float64, 0, len(buf)/8)
+	for i := 0; i+8 <= len(buf); i += 8 {
+		num, den := t.order.Uint32(buf[i:]), t.order.Uint32(buf[i+4:])
+		if den == 0 {
+			return nil
+		}
+		values = append(values, float64(num)/float64(den))
+	}
+	return values
+}
+
+// ParseTIFF 从 TIFF 结构（JPEG 的 EXIF 数据块或 TIFF 文件）中解析 EXIF 信息
+func ParseTIFF(r io.ReaderAt, size int64) (*EXIF, error) {
+	t, ifd0, err := newTIFFReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := t.readIFD(ifd0)
+	if err != nil {
+		return nil, ErrNoEXIF
+	}
+	info := &EXIF{Orientation: 1}
+	var exifIFD, gpsIFD int64
+	for _, e := range entries {
+		switch e.tag {
+		case tagMake:
+			info.Make = t.str(e)
+		case tagModel:
+			info.Model = t.str(e)
+		case tagDateTime:
+			info.DateTime = t.str(e)
+		case tagOrientation:
+			if o := int(t.uint(e)); o >= 1 && o <= 8 {
+				info.Orientation = o
+			}
+		case tagExifIFD:
+			exifIFD = int64(t.uint(e))
+		case tagGPSIFD:
+			gpsIFD = int64(t.uint(e))
+		}
+	}
+	if exifIFD > 0 {
+		if entries, err := t.readIFD(exifIFD); err == nil {
+			for _, e := range entries {
+				if e.tag == tagDateTimeOriginal {
+					if dt := t.str(e); dt != "" {
+						info.DateTime = dt
+					}
+				}
+			}
+		}
+	}
+	if gpsIFD > 0 {
+		if entries, err := t.readIFD(gpsIFD); err == nil {
+			info.HasGPS = true
+			info.Latitude, info.Longitude = t.gpsCoordinates(entries)
+		}
+	}
+	return info, nil
+}
+
+// gpsCoordinates 从 GPS IFD 中读取经纬度
+func (t *tiffReader) gpsCoordinates(entries []ifdEntry) (*float64, *float64) {
+	var latRef, lonRef string
+	var lat, lon []float64
+	for _, e := range entries {
+		switch e.tag {
+		case gpsLatitudeRef:
+			latRef = t.str(e)
+		case gpsLatitude:
+			lat = t.rationals(e)
+		case gpsLongitudeRef:
+			lonRef = t.str(e)
+		case gpsLongitude:
+			lon = t.rationals(e)
+		}
+	}
+	if len(lat) != 3 || len(lon) != 3 {
+		return nil, nil
+	}
+	latitude := lat[0] + lat[1]/60 + lat[2]/3600
+	longitude := lon[0] + lon[1]/60 + lon[2]/3600
+	if latRef == "S" {
+		latitude = -latitude
+	}
+	if lonRef == "W" {
+		longitude = -longitude
+	}
+	// 没有定位时部分设备写入 0,0
+	if math.Abs(latitude) > 90 || math.Abs(longitude) > 180 || math.Abs(latitude)+math.Abs(longitude) == 0 {
+		return nil, nil
+	}
+	return &latitude, &longitude
+}
+
+// jpegSegment JPEG 图像数据之前的段
+type jpegSegment struct {
+	marker byte
+	// data 段内容（不含长度），没有内容的标记为 nil
+	data []byte
+}
+
+// exifPrefix JPEG APP1 段中 EXIF 数据的前缀
+var exifPrefix = []byte("Exif\x00\x00")
+
+// isEXIF 是否为 EXIF 段
+func (s jpegSegment) isEXIF() bool {
+	return s.marker == 0xE1 && bytes.HasPrefix(s.data, exifPrefix)
+}
+
+// readJPEGSegments 依次读取 JPEG 图像数据（SOS）之前的段，fn 返回 false 时停止
+// 读到 SOS 标记时返回 true，r 停在 SOS 段的长度处
+func readJPEGSegments(r *bufio.Reader, fn func(seg jpegSegment) bool) (bool, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return false, ErrNoEXIF
+	}
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false, err
+		}
+		if b != 0xFF {
+			return false, ErrNoEXIF
+		}
+		marker, err := r.ReadByte()
+		for err == nil && marker == 0xFF { // 填充字节
+			marker, err = r.ReadByte()
+		}
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case marker == 0xDA:
+			return true, nil
+		case marker == 0xD9:
+			return false, nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			if !fn(jpegSegment{marker: marker}) {
+				return false, nil
+			}
+			continue
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return false, err
+		}
+		size := int(binary.BigEndian.Uint16(length[:])) - 2
+		if size < 0 {
+			return false, ErrNoEXIF
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return false, err
+		}
+		if !fn(jpegSegment{marker: marker, data: data}) {
+			return false, nil
+		}
+	}
+}
+
+// ReadJPEGEXIF 读取 JPEG 图片的 EXIF 信息，没有 EXIF 时返回 ErrNoEXIF
+func ReadJPEGEXIF(r io.Reader) (*EXIF, error) {
+	var exif []byte
+	_, err := readJPEGSegments(bufio.NewReader(r), func(seg jpegSegment) bool {
+		if seg.isEXIF() {
+			exif = seg.data[len(exifPrefix):]
+			return false
+		}
+		return true
+	})
+	if exif == nil {
+		if err == nil || err == io.EOF {
+			err = ErrNoEXIF
+		}
+		return nil, err
+	}
+	return ParseTIFF(bytes.NewReader(exif), int64(len(exif)))
+}
@@ -0,0 +1,236 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // GIF格式支持
+	_ "image/jpeg" // JPEG格式支持
+	_ "image/png"  // PNG格式支持
+	"io"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "golang.org/x/image/bmp"  // BMP格式支持
+	_ "golang.org/x/image/tiff" // TIFF格式支持
+	_ "golang.org/x/image/webp" // WebP格式支持
+)
+
+// ErrUnsupported 不支持解析的格式
+var ErrUnsupported = errors.New("不支持解析的格式")
+
+// exifTimeLayout EXIF 中的时间格式
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// source 文件的存储数据
+type source struct {
+	io.ReaderAt
+	io.Closer
+	size int64
+	// path 未分片文件的路径（分片文件为空）
+	path string
+}
+
+// seekReaderAt 以 Seek 加 Read 实现 ReaderAt（分片数据流只在单个协程中读取）
+type seekReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(s.rs, p)
+}
+
+// openSource 打开文件的存储数据，分片文件组合为连续数据
+func openSource(ctx context.Context, factory *impl.RepositoryFactory, fileInfo *models.FileInfo) (*source, error) {
+	if !fileInfo.IsChunk {
+		file, err := os.Open(fileInfo.Path)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &source{ReaderAt: file, Closer: file, size: stat.Size(), path: fileInfo.Path}, nil
+	}
+	chunks, err := factory.FileChunk().GetByFileID(ctx, fileInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("未找到分片文件")
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+	})
+	paths := make([]string, len(chunks))
+	sizes := make([]int64, len(chunks))
+	for i, c := range chunks {
+		paths[i], sizes[i] = c.ChunkPath, int64(c.ChunkSize)
+	}
+	reader := util.NewChunkedReader(paths, sizes)
+	return &source{ReaderAt: &seekReaderAt{rs: reader}, Closer: reader, size: reader.Size()}, nil
+}
+
+// mediaKind 按 MIME 类型判断媒体类型，不是图片、音频或视频时返回空字符串
+func mediaKind(mime string) string {
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return models.FileMetadataImage
+	case strings.HasPrefix(mime, "audio/"):
+		return models.FileMetadataAudio
+	case strings.HasPrefix(mime, "video/"):
+		return models.FileMetadataVideo
+	}
+	return ""
+}
+
+// baseMime 去掉 MIME 类型中的参数
+func baseMime(mime string) string {
+	mime, _, _ = strings.Cut(mime, ";")
+	return strings.ToLower(strings.TrimSpace(mime))
+}
+
+// Extract 提取文件的媒体元数据
+// 加密文件不读取内容，只按上传日期归入时间线；提取失败时同样保留上传日期，并返回错误供记录日志
+func Extract(ctx context.Context, factory *impl.RepositoryFactory, fileInfo *models.FileInfo) (*models.FileMetadata, error) {
+	mime := baseMime(fileInfo.Mime)
+	m := &models.FileMetadata{
+		FileID:      fileInfo.ID,
+		Kind:        mediaKind(mime),
+		ExtractedAt: custom_type.Now(),
+	}
+	if m.Kind == "" {
+		m.Status = models.FileMetadataUnsupported
+		return m, nil
+	}
+	if m.Kind == models.FileMetadataImage && !time.Time(fileInfo.CreatedAt).IsZero() {
+		m.TakenDate = time.Time(fileInfo.CreatedAt).In(time.Local).Format(time.DateOnly)
+	}
+	if fileInfo.IsEnc {
+		m.Status = models.FileMetadataEncrypted
+		return m, nil
+	}
+
+	src, err := openSource(ctx, factory, fileInfo)
+	if err != nil {
+		m.Status = models.FileMetadataFailed
+		return m, fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer src.Close()
+
+	var takenAt time.Time
+	switch m.Kind {
+	case models.FileMetadataImage:
+		takenAt, err = extractImage(src, mime, m)
+	default:
+		takenAt, err = extractMedia(ctx, src, mime, m)
+	}
+	switch {
+	case errors.Is(err, ErrUnsupported):
+		m.Status = models.FileMetadataUnsupported
+	case err != nil:
+		m.Status = models.FileMetadataFailed
+		return m, err
+	default:
+		m.Status = models.FileMetadataOK
+	}
+	if !takenAt.IsZero() {
+		m.TakenAt = custom_type.JsonTime(takenAt)
+		if m.Kind == models.FileMetadataImage {
+			m.TakenDate = takenAt.Format(time.DateOnly)
+		}
+	}
+	return m, nil
+}
+
+// extractImage 读取图片的尺寸与 EXIF（JPEG、TIFF），返回拍摄时间
+func extractImage(src *source, mime string, m *models.FileMetadata) (time.Time, error) {
+	var exif *EXIF
+	var err error
+	switch mime {
+	case "image/jpeg", "image/pjpeg":
+		exif, err = ReadJPEGEXIF(io.NewSectionReader(src, 0, src.size))
+	case "image/tiff":
+		exif, err = ParseTIFF(src, src.size)
+	}
+	if err != nil && !errors.Is(err, ErrNoEXIF) {
+		return time.Time{}, fmt.Errorf("读取 EXIF 失败: %w", err)
+	}
+
+	config, _, configErr := image.DecodeConfig(io.NewSectionReader(src, 0, src.size))
+	if configErr == nil {
+		m.Width, m.Height = config.Width, config.Height
+	}
+	if exif == nil {
+		if configErr != nil {
+			return time.Time{}, ErrUnsupported
+		}
+		return time.Time{}, nil
+	}
+
+	m.CameraMake, m.CameraModel = exif.Make, exif.Model
+	m.Orientation = exif.Orientation
+	m.Latitude, m.Longitude = exif.Latitude, exif.Longitude
+	if exif.Orientation >= 5 { // 方向 5-8 包含 90 度旋转，按显示方向记录宽高
+		m.Width, m.Height = m.Height, m.Width
+	}
+	// EXIF 时间没有时区，按拍摄地的本地时间记录
+	takenAt, err := time.ParseInLocation(exifTimeLayout, exif.DateTime, time.Local)
+	if err != nil || takenAt.Year() < 1900 {
+		return time.Time{}, nil
+	}
+	return takenAt, nil
+}
+
+// extractMedia 按格式读取音频与视频的标签、时长与画面尺寸，返回拍摄时间
+// 没有内置解析的格式调用 ffmpeg（只支持未分片的文件）
+func extractMedia(ctx context.Context, src *source, mime string, m *models.FileMetadata) (time.Time, error) {
+	switch mime {
+	case "audio/mpeg", "audio/mp3":
+		return time.Time{}, parseMP3(src, src.size, m)
+	case "audio/flac", "audio/x-flac":
+		return time.Time{}, parseFLAC(src, src.size, m)
+	case "audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave":
+		return time.Time{}, parseWAV(src, src.size, m)
+	case "video/mp4", "video/quicktime", "video/3gpp", "video/3gpp2", "video/x-m4v", "audio/mp4", "audio/x-m4a":
+		takenAt, err := parseMP4(src, src.size, m)
+		if !errors.Is(err, ErrUnsupported) {
+			return takenAt, err
+		}
+	}
+	if src.path == "" {
+		return time.Time{}, ErrUnsupported
+	}
+	_, err := probeFFmpeg(ctx, src.path, m)
+	return time.Time{}, err
+}
+
+// Update 提取文件的媒体元数据并保存
+func Update(ctx context.Context, factory *impl.RepositoryFactory, fileInfo *models.FileInfo) error {
+	m, err := Extract(ctx, factory, fileInfo)
+	if err != nil {
+		// 提取失败时同样保存状态，避免反复提取
+		logger.LOG.Warn("提取媒体元数据失败", "fileID", fileInfo.ID, "mime", fileInfo.Mime, "error", err)
+	}
+	if err := factory.FileMetadata().Save(ctx, m); err != nil {
+		return fmt.Errorf("保存媒体元数据失败: %w", err)
+	}
+	return nil
+}
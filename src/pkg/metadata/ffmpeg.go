@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"myobj/src/pkg/convert"
+	"myobj/src/pkg/models"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// probeTimeout ffmpeg 读取媒体信息的超时时间
+const probeTimeout = 30 * time.Second
+
+var (
+	// ffmpegDuration ffmpeg 输出中的时长（Duration: 00:03:25.47）
+	ffmpegDuration = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+	// ffmpegVideo ffmpeg 输出中视频流的画面尺寸
+	ffmpegVideo = regexp.MustCompile(`Stream #.*: Video: .*?, (\d{2,5})x(\d{2,5})`)
+	// ffmpegRotate ffmpeg 输出中视频流的旋转角度
+	ffmpegRotate = regexp.MustCompile(`(?:rotate\s*:\s*|rotation of )(-?\d+(?:\.\d+)?)`)
+)
+
+// probeFFmpeg 调用 ffmpeg 读取其他格式音视频的时长与画面尺寸（未配置 ffmpeg 时返回 ErrUnsupported）
+// 只传入输入文件时 ffmpeg 输出媒体信息后以非零状态退出，这里只解析输出
+func probeFFmpeg(ctx context.Context, path string, m *models.FileMetadata) (bool, error) {
+	ffmpeg, ok := convert.FFmpegPath()
+	if !ok {
+		return false, ErrUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, ffmpeg, "-hide_banner", "-nostdin", "-i", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	_ = cmd.Run()
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("ffmpeg 读取媒体信息超时或已取消: %w", ctx.Err())
+	}
+
+	output := stderr.Bytes()
+	if match := ffmpegDuration.FindSubmatch(output); match != nil {
+		hours, _ := strconv.Atoi(string(match[1]))
+		minutes, _ := strconv.Atoi(string(match[2]))
+		seconds, _ := strconv.ParseFloat(string(match[3]), 64)
+		m.Duration = float64(hours*3600+minutes*60) + seconds
+	}
+	match := ffmpegVideo.FindSubmatch(output)
+	if match == nil {
+		return false, nil
+	}
+	m.Width, _ = strconv.Atoi(string(match[1]))
+	m.Height, _ = strconv.Atoi(string(match[2]))
+	if rotate := ffmpegRotate.FindSubmatch(output); rotate != nil {
+		if degrees, err := strconv.ParseFloat(string(rotate[1]), 64); err == nil && int(degrees)%180 != 0 {
+			m.Width, m.Height = m.Height, m.Width
+		}
+	}
+	return true, nil
+}
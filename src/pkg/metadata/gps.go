@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"os"
+	"path/filepath"
+)
+
+// xmpPrefixes JPEG APP1 段中 XMP 数据（含扩展 XMP）的前缀
+var xmpPrefixes = [][]byte{[]byte("http://ns.adobe.com/xap/1.0/\x00"), []byte("http://ns.adobe.com/xmp/extension/\x00")}
+
+// isXMPWithGPS 是否为包含 GPS 坐标的 XMP 段
+func (s jpegSegment) isXMPWithGPS() bool {
+	if s.marker != 0xE1 {
+		return false
+	}
+	for _, prefix := range xmpPrefixes {
+		if bytes.HasPrefix(s.data, prefix) {
+			return bytes.Contains(s.data, []byte("GPSLatitude")) || bytes.Contains(s.data, []byte("GPSLongitude"))
+		}
+	}
+	return false
+}
+
+// StripGPS 去除 JPEG 照片中的位置信息（EXIF 中的 GPS IFD，以及包含 GPS 坐标的 XMP 段），结果写入 dst
+// 图像数据原样复制；不是 JPEG 或没有位置信息时不创建 dst，返回 false
+func StripGPS(src, dst string) (bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	r := bufio.NewReader(in)
+	var segments []jpegSegment
+	changed := false
+	sos, err := readJPEGSegments(r, func(seg jpegSegment) bool {
+		switch {
+		case seg.isEXIF():
+			if stripEXIFGPS(seg.data[len(exifPrefix):]) {
+				changed = true
+			}
+		case seg.isXMPWithGPS():
+			changed = true
+			return true
+		}
+		segments = append(segments, seg)
+		return true
+	})
+	if errors.Is(err, ErrNoEXIF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("读取 JPEG 失败: %w", err)
+	}
+	if !sos || !changed {
+		return false, nil
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return false, err
+	}
+	w := bufio.NewWriter(out)
+	w.Write([]byte{0xFF, 0xD8})
+	for _, seg := range segments {
+		w.Write([]byte{0xFF, seg.marker})
+		if seg.data != nil {
+			var length [2]byte
+			binary.BigEndian.PutUint16(length[:], uint16(len(seg.data)+2))
+			w.Write(length[:])
+			w.Write(seg.data)
+		}
+	}
+	w.Write([]byte{0xFF, 0xDA})
+	_, err = w.ReadFrom(r)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return false, fmt.Errorf("写入文件失败: %w", err)
+	}
+	return true, nil
+}
+
+// PublicCopy 按 strip_gps 配置准备公开文件与分享下载使用的文件
+// JPEG 照片包含位置信息时在 dir 中生成去除位置信息的副本（dir 由调用方清理），返回用于下载的路径；不需要处理时返回 src
+func PublicCopy(src, mime, dir string) (string, error) {
+	if config.CONFIG == nil || !config.CONFIG.File.StripGPS || baseMime(mime) != "image/jpeg" {
+		return src, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	dst := filepath.Join(dir, "nogps_"+filepath.Base(src))
+	stripped, err := StripGPS(src, dst)
+	if err != nil {
+		return "", err
+	}
+	if !stripped {
+		return src, nil
+	}
+	return dst, nil
+}
+
+// stripEXIFGPS 在 EXIF 数据块中就地去除 GPS 信息：清零 GPS IFD 及其标签值，并从 IFD0 中删除指向它的标签
+// 数据块长度不变，其他标签的偏移量不受影响
+func stripEXIFGPS(data []byte) bool {
+	t, ifd0, err := newTIFFReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil || ifd0+2 > int64(len(data)) {
+		return false
+	}
+	count := int64(t.order.Uint16(data[ifd0:]))
+	end := ifd0 + 2 + count*12 + 4 // 条目之后是下一个 IFD 的偏移量
+	if count == 0 || end > int64(len(data)) {
+		return false
+	}
+	pos := int64(-1)
+	for i := int64(0); i < count; i++ {
+		if t.order.Uint16(data[ifd0+2+i*12:]) == tagGPSIFD {
+			pos = ifd0 + 2 + i*12
+			break
+		}
+	}
+	if pos < 0 {
+		return false
+	}
+
+	gpsIFD := int64(t.order.Uint32(data[pos+8:]))
+	if entries, err := t.readIFD(gpsIFD); err == nil {
+		for _, e := range entries {
+			if e.size > 4 {
+				clear(data[e.offset : e.offset+e.size])
+			}
+		}
+		clear(data[gpsIFD:min(gpsIFD+2+int64(len(entries))*12+4, int64(len(data)))])
+	}
+
+	// 后面的条目与下一个 IFD 的偏移量前移一个条目
+	copy(data[pos:], data[pos+12:end])
+	clear(data[end-12 : end])
+	t.order.PutUint16(data[ifd0:], uint16(count-1))
+	return true
+}
@@ -0,0 +1,297 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"io"
+	"myobj/src/pkg/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBoxDepth 解析 MP4 box 的最大嵌套层数
+const maxBoxDepth = 8
+
+// mp4Epoch MP4 时间字段的起点（1904-01-01 UTC）
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// iso6709Pattern ISO 6709 位置字符串（如 "+39.9042+116.4074+043.000/"）中的经纬度
+var iso6709Pattern = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)`)
+
+// mp4Box MP4（ISO BMFF）box
+type mp4Box struct {
+	typ string
+	// start box 内容的位置
+	start int64
+	// end box 结束的位置
+	end int64
+}
+
+// mp4Parser MP4、MOV、M4A 等文件的元数据解析
+type mp4Parser struct {
+	r io.ReaderAt
+	m *models.FileMetadata
+	// takenAt 拍摄时间（优先取 QuickTime 的 creationdate，其次为 mvhd 的创建时间）
+	takenAt time.Time
+	// created mvhd 中的创建时间
+	created time.Time
+	// video 是否已读取到画面尺寸（只取第一个视频轨道）
+	video bool
+}
+
+// boxes 依次读取 [start, end) 范围内的 box
+func (p *mp4Parser) boxes(start, end int64, fn func(box mp4Box) error) error {
+	header := make([]byte, 16)
+	for pos := start; pos+8 <= end; {
+		if _, err := p.r.ReadAt(header[:8], pos); err != nil {
+			return err
+		}
+		size, headerLen := int64(binary.BigEndian.Uint32(header)), int64(8)
+		switch size {
+		case 0: // 延续到文件末尾
+			size = end - pos
+		case 1: // 64 位长度
+			if _, err := p.r.ReadAt(header[8:16], pos+8); err != nil {
+				return err
+			}
+			size, headerLen = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+		if size < headerLen || pos+size > end {
+			return nil
+		}
+		if err := fn(mp4Box{typ: string(header[4:8]), start: pos + headerLen, end: pos + size}); err != nil {
+			return err
+		}
+		pos += size
+	}
+	return nil
+}
+
+// read 读取 box 的内容，超过 limit 时返回 nil
+func (p *mp4Parser) read(box mp4Box, limit int64) []byte {
+	size := box.end - box.start
+	if size <= 0 || size > limit {
+		return nil
+	}
+	data := make([]byte, size)
+	if _, err := p.r.ReadAt(data, box.start); err != nil {
+		return nil
+	}
+	return data
+}
+
+// walk 遍历容器 box，读取其中的 mvhd、tkhd 与 meta/udta 标签
+func (p *mp4Parser) walk(start, end int64, depth int) error {
+	if depth > maxBoxDepth {
+		return nil
+	}
+	return p.boxes(start, end, func(box mp4Box) error {
+		switch box.typ {
+		case "moov", "trak", "udta":
+			return p.walk(box.start, box.end, depth+1)
+		case "meta":
+			return p.meta(box, depth)
+		case "mvhd":
+			p.mvhd(p.read(box, 256))
+		case "tkhd":
+			p.tkhd(p.read(box, 256))
+		case "\xa9xyz":
+			if data := p.read(box, maxTextFrameSize); len(data) > 4 {
+				p.location(string(data[4:])) // 2 字节长度与 2 字节语言之后为文本
+			}
+		}
+		return nil
+	})
+}
+
+// mvhd 读取影片的时长与创建时间
+func (p *mp4Parser) mvhd(data []byte) {
+	var created uint64
+	var timescale uint32
+	var duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		created = binary.BigEndian.Uint64(data[4:])
+		timescale = binary.BigEndian.Uint32(data[20:])
+		duration = binary.BigEndian.Uint64(data[24:])
+	case len(data) >= 20 && data[0] == 0:
+		created = uint64(binary.BigEndian.Uint32(data[4:]))
+		timescale = binary.BigEndian.Uint32(data[12:])
+		duration = uint64(binary.BigEndian.Uint32(data[16:]))
+	default:
+		return
+	}
+	if timescale > 0 {
+		p.m.Duration = float64(duration) / float64(timescale)
+	}
+	if created > 0 {
+		p.created = mp4Epoch.Add(time.Duration(created) * time.Second)
+	}
+}
+
+// tkhd 读取轨道的画面尺寸（音频轨道为 0），按变换矩阵判断是否旋转 90 度
+func (p *mp4Parser) tkhd(data []byte) {
+	matrix, size := 40, 76
+	if len(data) > 0 && data[0] == 1 {
+		matrix, size = 52, 88
+	}
+	if len(data) < size+8 {
+		return
+	}
+	width := int(binary.BigEndian.Uint32(data[size:]) >> 16)
+	height := int(binary.BigEndian.Uint32(data[size+4:]) >> 16)
+	if width == 0 || height == 0 || p.video {
+		return
+	}
+	a, d := binary.BigEndian.Uint32(data[matrix:]), binary.BigEndian.Uint32(data[matrix+16:])
+	if a == 0 && d == 0 {
+		width, height = height, width
+	}
+	p.video = true
+	p.m.Width, p.m.Height = width, height
+}
+
+// meta 读取 meta box 中的 iTunes 标签（ilst）与 QuickTime 键值（keys）
+// ISO 格式的 meta 带 4 字节的版本与标志，QuickTime 格式的直接是子 box
+func (p *mp4Parser) meta(box mp4Box, depth int) error {
+	header := make([]byte, 12)
+	if _, err := p.r.ReadAt(header, box.start); err != nil {
+		return nil
+	}
+	start := box.start
+	if string(header[4:8]) != "hdlr" {
+		start += 4
+	}
+	var keys []string
+	return p.boxes(start, box.end, func(child mp4Box) error {
+		switch child.typ {
+		case "keys":
+			keys = mp4Keys(p.read(child, maxTextFrameSize))
+		case "ilst":
+			return p.ilst(child, keys)
+		case "udta", "moov":
+			return p.walk(child.start, child.end, depth+1)
+		}
+		return nil
+	})
+}
+
+// mp4Keys 解析 keys box 中的键名，下标从 1 开始对应 ilst 中的条目
+func mp4Keys(data []byte) []string {
+	if len(data) < 8 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	keys := make([]string, 0, min(count, 256))
+	for pos := 8; len(keys) < count && pos+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		if size < 8 || pos+size > len(data) {
+			break
+		}
+		keys = append(keys, string(data[pos+8:pos+size]))
+		pos += size
+	}
+	return keys
+}
+
+// ilst 读取 iTunes 标签列表，条目类型为键名下标时按 keys 查找
+func (p *mp4Parser) ilst(box mp4Box, keys []string) error {
+	return p.boxes(box.start, box.end, func(item mp4Box) error {
+		name := item.typ
+		if index := int(binary.BigEndian.Uint32([]byte(item.typ))); index >= 1 && index <= len(keys) {
+			name = keys[index-1]
+		}
+		return p.boxes(item.start, item.end, func(data mp4Box) error {
+			if data.typ == "data" {
+				if value := p.read(data, maxTextFrameSize); len(value) >= 8 {
+					p.item(name, value[8:]) // 4 字节类型与 4 字节语言之后为值
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// item 按标签名写入元数据
+func (p *mp4Parser) item(name string, value []byte) {
+	switch name {
+	case "\xa9nam":
+		setTag(p.m, "TITLE", string(value))
+	case "\xa9ART", "aART":
+		setTag(p.m, "ARTIST", string(value))
+	case "\xa9alb":
+		setTag(p.m, "ALBUM", string(value))
+	case "\xa9gen":
+		setTag(p.m, "GENRE", string(value))
+	case "gnre": // ID3v1 流派编号加 1
+		if len(value) >= 2 {
+			if n := int(binary.BigEndian.Uint16(value)); n >= 1 {
+				setTag(p.m, "GENRE", strconv.Itoa(n-1))
+			}
+		}
+	case "\xa9day":
+		setTag(p.m, "YEAR", string(value))
+	case "trkn":
+		if len(value) >= 4 {
+			setTag(p.m, "TRACK", strconv.Itoa(int(binary.BigEndian.Uint16(value[2:]))))
+		}
+	case "com.apple.quicktime.make":
+		if p.m.CameraMake == "" {
+			p.m.CameraMake = strings.TrimSpace(string(value))
+		}
+	case "com.apple.quicktime.model":
+		if p.m.CameraModel == "" {
+			p.m.CameraModel = strings.TrimSpace(string(value))
+		}
+	case "com.apple.quicktime.location.ISO6709":
+		p.location(string(value))
+	case "com.apple.quicktime.creationdate":
+		p.creationDate(strings.TrimSpace(string(value)))
+	}
+}
+
+// location 解析 ISO 6709 格式的位置
+func (p *mp4Parser) location(value string) {
+	if p.m.Latitude != nil {
+		return
+	}
+	match := iso6709Pattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return
+	}
+	lat, err1 := strconv.ParseFloat(match[1], 64)
+	lon, err2 := strconv.ParseFloat(match[2], 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 || lat == 0 && lon == 0 {
+		return
+	}
+	p.m.Latitude, p.m.Longitude = &lat, &lon
+}
+
+// creationDate 解析 QuickTime 的拍摄时间（如 "2024-05-01T18:30:00+0800"），保留拍摄地的本地时间
+func (p *mp4Parser) creationDate(value string) {
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			p.takenAt = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+			return
+		}
+	}
+}
+
+// parseMP4 解析 MP4、MOV、M4A 等 ISO BMFF 格式文件的时长、画面尺寸、标签、拍摄时间与位置
+// 返回拍摄时间（没有时为零值）
+func parseMP4(r io.ReaderAt, size int64, m *models.FileMetadata) (time.Time, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[4:8]) != "ftyp" {
+		return time.Time{}, ErrUnsupported
+	}
+	p := &mp4Parser{r: r, m: m}
+	if err := p.walk(0, size, 0); err != nil {
+		return time.Time{}, err
+	}
+	takenAt := p.takenAt
+	if takenAt.IsZero() && !p.created.IsZero() {
+		takenAt = p.created.In(time.Local)
+	}
+	return takenAt, nil
+}
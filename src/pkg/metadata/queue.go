@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/logger"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// syncBatchSize 补充提取时每批处理的文件数
+const syncBatchSize = 100
+
+var (
+	startOnce sync.Once
+	queue     chan string
+)
+
+// Start 启动元数据提取队列
+func Start(factory *impl.RepositoryFactory) {
+	startOnce.Do(func() {
+		queue = make(chan string, 1024)
+		go func() {
+			for fileID := range queue {
+				apply(context.Background(), factory, fileID)
+			}
+		}()
+	})
+}
+
+// Notify 通知文件已上传，由队列异步提取元数据
+// 队列未启动时忽略；队列已满时丢弃，由定时任务补充提取
+func Notify(fileIDs ...string) {
+	if queue == nil {
+		return
+	}
+	for _, fileID := range fileIDs {
+		select {
+		case queue <- fileID:
+		default:
+			logger.LOG.Warn("元数据提取队列已满，等待定时补充", "fileID", fileID)
+			return
+		}
+	}
+}
+
+// apply 提取文件的元数据，已提取过（秒传的文件共享文件信息）时跳过
+func apply(ctx context.Context, factory *impl.RepositoryFactory, fileID string) {
+	if _, err := factory.FileMetadata().Get(ctx, fileID); err == nil {
+		return
+	}
+	fileInfo, err := factory.FileInfo().GetByID(ctx, fileID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.LOG.Warn("获取文件信息失败", "fileID", fileID, "error", err)
+		}
+		return
+	}
+	if mediaKind(baseMime(fileInfo.Mime)) == "" {
+		return
+	}
+	if err := Update(ctx, factory, fileInfo); err != nil {
+		logger.LOG.Warn("更新媒体元数据失败", "fileID", fileID, "error", err)
+	}
+}
+
+// Sync 为尚未提取的图片、音频与视频补充提取元数据，删除文件信息已删除的元数据
+// 用于已有文件、队列溢出或服务重启时丢失的任务
+func Sync(ctx context.Context, factory *impl.RepositoryFactory) (extracted int, removed int64, err error) {
+	for {
+		files, err := factory.FileMetadata().ListUnextracted(ctx, syncBatchSize)
+		if err != nil {
+			return extracted, 0, fmt.Errorf("查询未提取元数据的文件失败: %w", err)
+		}
+		for _, fileInfo := range files {
+			if err := Update(ctx, factory, fileInfo); err != nil {
+				return extracted, 0, err
+			}
+			extracted++
+		}
+		if len(files) < syncBatchSize {
+			break
+		}
+	}
+	removed, err = factory.FileMetadata().DeleteOrphans(ctx)
+	if err != nil {
+		return extracted, 0, fmt.Errorf("清理元数据失败: %w", err)
+	}
+	return extracted, removed, nil
+}
@@ -12,6 +12,20 @@ type ApiKey struct {
 	ExpiresAt  custom_type.JsonTime `gorm:"type:DATETIME" json:"expires_at"`                   // 过期时间
 	CreatedAt  custom_type.JsonTime `gorm:"type:DATETIME;not null" json:"created_at"`          // 创建时间
 	PrivateKey string               `gorm:"type:text;not null" json:"private_key"`             // 私钥
+	// 名称（用途说明，如备份脚本）
+	Name string `gorm:"column:name;type:varchar(255)" json:"name"`
+	// 允许的权限标识（Power.Characteristic，逗号分隔），为空表示继承用户组全部权限
+	Scopes string `gorm:"column:scopes;type:text" json:"scopes"`
+	// 可访问的目录ID（0 表示整个空间，否则只能访问该目录及其子目录）
+	RootDirID int `gorm:"column:root_dir_id;type:integer;not null;default:0" json:"root_dir_id"`
+	// 允许使用的 IP 或网段（CIDR，逗号分隔），为空表示不限制
+	AllowedIPs string `gorm:"column:allowed_ips;type:text" json:"allowed_ips"`
+	// 最近使用时间
+	LastUsedAt custom_type.JsonTime `gorm:"column:last_used_at;type:datetime" json:"last_used_at"`
+	// 最近使用 IP
+	LastUsedIP string `gorm:"column:last_used_ip;type:varchar(64)" json:"last_used_ip"`
+	// 累计调用次数
+	UsageCount int64 `gorm:"column:usage_count;type:integer;not null;default:0" json:"usage_count"`
 }
 
 func (ApiKey) TableName() string {
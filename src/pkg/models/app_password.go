@@ -0,0 +1,32 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// AppPassword 应用专用密码（供 WebDAV、SFTP 等同步协议使用，不能用于网页登录）
+type AppPassword struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 名称（设备或客户端名称）
+	Name string `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	// 密码哈希（SHA-256，密码只在创建时返回一次）
+	PasswordHash string `gorm:"column:password_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
+	// 密码前缀（用于列表展示）
+	Prefix string `gorm:"column:prefix;type:varchar(16)" json:"prefix"`
+	// 是否只读
+	ReadOnly bool `gorm:"column:read_only;type:boolean;default:false" json:"read_only"`
+	// 可访问的目录ID（0 表示整个空间，否则只能访问该目录及其子目录）
+	RootDirID int `gorm:"column:root_dir_id;type:integer;not null;default:0" json:"root_dir_id"`
+	// 最近使用时间
+	LastUsedAt custom_type.JsonTime `gorm:"column:last_used_at;type:datetime" json:"last_used_at"`
+	// 最近使用 IP
+	LastUsedIP string `gorm:"column:last_used_ip;type:varchar(64)" json:"last_used_ip"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+}
+
+func (AppPassword) TableName() string {
+	return "app_password"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// 审计结果
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditLog 审计日志（只追加，除按保留期清理外不修改、不删除）
+// 启用哈希链时 Hash = sha256(PrevHash + 记录内容)，删除或修改任意一条记录都会导致后续校验失败
+type AuditLog struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 操作人用户ID（匿名操作为空）
+	ActorID string `gorm:"column:actor_id;type:varchar(64);index" json:"actor_id"`
+	// 操作人用户名
+	ActorName string `gorm:"column:actor_name;type:varchar(255)" json:"actor_name"`
+	// 使用 API Key 认证时的 Key ID
+	APIKeyID int `gorm:"column:api_key_id;type:integer" json:"api_key_id"`
+	// 来源（web、api_key、webdav、share）
+	Source string `gorm:"column:source;type:varchar(16)" json:"source"`
+	// 操作，如 user.login、share.create、recycled.delete
+	Action string `gorm:"column:action;type:varchar(64);index;not null" json:"action"`
+	// 操作对象类型，如 user、group、file、share
+	TargetType string `gorm:"column:target_type;type:varchar(32);index" json:"target_type"`
+	// 操作对象ID或路径
+	TargetID string `gorm:"column:target_id;type:varchar(512)" json:"target_id"`
+	// 详细说明
+	Detail string `gorm:"column:detail;type:varchar(1024)" json:"detail"`
+	// 客户端 IP
+	IP string `gorm:"column:ip;type:varchar(64)" json:"ip"`
+	// 结果（success、failure）
+	Result string `gorm:"column:result;type:varchar(16);index" json:"result"`
+	// 操作时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime;index" json:"created_at"`
+	// 上一条记录的哈希（未启用哈希链时为空）
+	PrevHash string `gorm:"column:prev_hash;type:varchar(64)" json:"prev_hash"`
+	// 本条记录的哈希（未启用哈希链时为空）
+	Hash string `gorm:"column:hash;type:varchar(64)" json:"hash"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
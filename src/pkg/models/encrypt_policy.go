@@ -0,0 +1,21 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// EncryptPolicy 写入加密策略
+// 通过 WebDAV / SFTP 等协议写入命中策略的目录时，文件会自动加密存储
+type EncryptPolicy struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 目录ID（0 表示对用户所有目录生效，否则对该目录及其子目录生效）
+	DirID int `gorm:"column:dir_id;type:integer;not null;default:0" json:"dir_id"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+}
+
+func (EncryptPolicy) TableName() string {
+	return "encrypt_policy"
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// 媒体元数据提取状态
+const (
+	FileMetadataOK          = "ok"          // 已提取
+	FileMetadataUnsupported = "unsupported" // 不支持解析的格式
+	FileMetadataEncrypted   = "encrypted"   // 加密文件（不提取，避免在数据库中保存明文的位置等信息）
+	FileMetadataFailed      = "failed"      // 提取失败
+)
+
+// 媒体元数据的类型
+const (
+	FileMetadataImage = "image"
+	FileMetadataAudio = "audio"
+	FileMetadataVideo = "video"
+)
+
+// FileMetadata 图片、音频与视频的元数据
+// 按 file_info 保存，秒传与复制出的文件共享同一份元数据，只提取一次
+type FileMetadata struct {
+	// 文件信息ID
+	FileID string `gorm:"column:file_id;type:varchar(64);primaryKey" json:"file_id"`
+	// 提取状态
+	Status string `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	// 类型（image、audio、video）
+	Kind string `gorm:"column:kind;type:varchar(16);index" json:"kind"`
+	// 宽度（图片按 EXIF 方向校正后的显示宽度，视频为画面宽度）
+	Width int `gorm:"column:width;type:int" json:"width,omitempty"`
+	// 高度
+	Height int `gorm:"column:height;type:int" json:"height,omitempty"`
+	// 时长（秒）
+	Duration float64 `gorm:"column:duration;type:double" json:"duration,omitempty"`
+	// 拍摄时间（EXIF，按拍摄地的本地时间）
+	TakenAt custom_type.JsonTime `gorm:"column:taken_at;type:datetime" json:"taken_at"`
+	// 时间线日期（yyyy-mm-dd），有拍摄时间时取拍摄日期，否则取上传日期
+	TakenDate string `gorm:"column:taken_date;type:varchar(10);index" json:"taken_date"`
+	// 相机厂商
+	CameraMake string `gorm:"column:camera_make;type:varchar(64)" json:"camera_make,omitempty"`
+	// 相机型号
+	CameraModel string `gorm:"column:camera_model;type:varchar(128)" json:"camera_model,omitempty"`
+	// EXIF 方向（1-8）
+	Orientation int `gorm:"column:orientation;type:int" json:"orientation,omitempty"`
+	// 纬度（没有 GPS 信息时为空）
+	Latitude *float64 `gorm:"column:latitude;type:double" json:"latitude,omitempty"`
+	// 经度
+	Longitude *float64 `gorm:"column:longitude;type:double" json:"longitude,omitempty"`
+	// 标题（音频标签）
+	Title string `gorm:"column:title;type:varchar(255)" json:"title,omitempty"`
+	// 艺术家
+	Artist string `gorm:"column:artist;type:varchar(255)" json:"artist,omitempty"`
+	// 专辑
+	Album string `gorm:"column:album;type:varchar(255)" json:"album,omitempty"`
+	// 流派
+	Genre string `gorm:"column:genre;type:varchar(64)" json:"genre,omitempty"`
+	// 年份
+	Year int `gorm:"column:year;type:int" json:"year,omitempty"`
+	// 音轨号
+	Track int `gorm:"column:track;type:int" json:"track,omitempty"`
+	// 提取时间
+	ExtractedAt custom_type.JsonTime `gorm:"column:extracted_at;type:datetime" json:"extracted_at"`
+}
+
+func (FileMetadata) TableName() string {
+	return "file_metadata"
+}
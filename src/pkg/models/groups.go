@@ -6,11 +6,14 @@ import (
 
 // Group 组
 type Group struct {
-	ID           int                  `gorm:"type:INTEGER;not null;primaryKey;unique" json:"id"` // 组ID，主键且唯一
-	Name         string               `gorm:"type:VARCHAR;not null" json:"name"`                 // 组名称
-	GroupDefault int                  `gorm:"type:INTEGER;not null" json:"group_default"`        // 是否为默认组 0-否 1-是
-	CreatedAt    custom_type.JsonTime `gorm:"type:DATETIME;not null" json:"created_at"`          // 创建时间
-	Space        int64                `gorm:"type:INTEGER" json:"space"`                         // 组默认可用存储空间
+	ID           int                  `gorm:"type:INTEGER;not null;primaryKey;unique" json:"id"`                // 组ID，主键且唯一
+	Name         string               `gorm:"type:VARCHAR;not null" json:"name"`                                // 组名称
+	GroupDefault int                  `gorm:"type:INTEGER;not null" json:"group_default"`                       // 是否为默认组 0-否 1-是
+	CreatedAt    custom_type.JsonTime `gorm:"type:DATETIME;not null" json:"created_at"`                         // 创建时间
+	Space        int64                `gorm:"type:INTEGER" json:"space"`                                        // 组默认可用存储空间
+	Require2FA   bool                 `gorm:"column:require_2fa;type:BOOLEAN;default:false" json:"require_2fa"` // 是否要求组内用户开启两步验证
+	// 是否禁止组内用户使用本地密码登录（只能通过单点登录或目录账户登录）
+	DisableLocalLogin bool `gorm:"column:disable_local_login;type:BOOLEAN;default:false" json:"disable_local_login"`
 }
 
 func (Group) TableName() string {
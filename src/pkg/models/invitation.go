@@ -0,0 +1,33 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// Invitation 管理员发出的注册邀请
+// 通过邀请链接注册不受 allow_register 限制，账户使用邀请预设的用户组与存储空间，邮箱视为已验证
+type Invitation struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 受邀邮箱
+	Email string `gorm:"column:email;type:varchar(255);not null" json:"email"`
+	// 预设用户组ID
+	GroupID int `gorm:"column:group_id;type:integer;not null" json:"group_id"`
+	// 预设存储空间（字节，0 表示使用用户组的空间）
+	Space int64 `gorm:"column:space;type:bigint;default:0" json:"space"`
+	// 令牌哈希（SHA-256，不保存令牌明文）
+	TokenHash string `gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
+	// 邀请人用户ID
+	CreatedBy string `gorm:"column:created_by;type:varchar(64)" json:"created_by"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 过期时间
+	ExpiresAt custom_type.JsonTime `gorm:"column:expires_at;type:datetime" json:"expires_at"`
+	// 使用时间（为空表示未使用）
+	UsedAt custom_type.JsonTime `gorm:"column:used_at;type:datetime" json:"used_at"`
+	// 通过邀请注册的用户ID
+	UsedBy string `gorm:"column:used_by;type:varchar(64)" json:"used_by"`
+}
+
+func (Invitation) TableName() string {
+	return "invitation"
+}
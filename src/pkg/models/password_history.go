@@ -0,0 +1,28 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// 密码类型（登录密码与文件密码分别适用各自的密码策略）
+const (
+	PasswordKindLogin = "login"
+	PasswordKindFile  = "file"
+)
+
+// PasswordHistory 历史密码（只保存哈希，用于禁止重复使用最近的密码）
+type PasswordHistory struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 密码类型（login、file）
+	Kind string `gorm:"column:kind;type:varchar(16);not null" json:"kind"`
+	// 密码哈希
+	PasswordHash string `gorm:"column:password_hash;type:text;not null" json:"-"`
+	// 设置时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
@@ -12,6 +12,17 @@ type Recycled struct {
 	UserID string `gorm:"type:VARCHAR;not null" json:"user_id"`
 	// 删除时间
 	CreatedAt custom_type.JsonTime `gorm:"type:DATETIME;not null" json:"created_at"`
+	// 目录ID（目录条目对应已软删除的 virtual_path，文件条目为 0）
+	DirID int `gorm:"type:INTEGER;default:0" json:"dir_id"`
+	// 所属目录条目ID（随目录一起删除的文件和子目录指向顶层目录条目，顶层条目为空）
+	ParentID string `gorm:"type:VARCHAR;default:''" json:"parent_id"`
+	// 删除前的完整路径（目录条目）
+	OriginalPath string `gorm:"type:TEXT" json:"original_path"`
+}
+
+// IsDir 是否为目录条目
+func (r *Recycled) IsDir() bool {
+	return r.DirID > 0
 }
 
 func (Recycled) TableName() string {
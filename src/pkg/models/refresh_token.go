@@ -0,0 +1,23 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// RefreshToken 刷新令牌表
+// 每次刷新都签发新的刷新令牌并标记旧令牌已轮换；已轮换的令牌再次使用视为泄露，注销整个会话
+type RefreshToken struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 所属会话ID
+	SessionID string `gorm:"column:session_id;type:varchar(64);index;not null" json:"session_id"`
+	// 令牌哈希（SHA-256，不保存令牌明文）
+	TokenHash string `gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
+	// 签发时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 轮换时间（为空表示当前有效的令牌）
+	RotatedAt custom_type.JsonTime `gorm:"column:rotated_at;type:datetime" json:"rotated_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// 文件内容提取状态
+const (
+	SearchContentOK          = "ok"          // 已提取文本
+	SearchContentEmpty       = "empty"       // 没有可索引的文本
+	SearchContentUnsupported = "unsupported" // 不支持提取的文件类型
+	SearchContentEncrypted   = "encrypted"   // 加密文件（没有文件密码，只索引文件名）
+	SearchContentTooLarge    = "too_large"   // 超过提取的文件大小上限
+	SearchContentFailed      = "failed"      // 提取失败
+)
+
+// SearchContent 文件内容的提取结果
+// 按 file_info 保存，秒传与复制出的文件共享同一份文本，只提取一次
+type SearchContent struct {
+	// 文件信息ID
+	FileID string `gorm:"column:file_id;type:varchar(64);primaryKey" json:"file_id"`
+	// 提取状态
+	Status string `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	// 提取出的文本（超过 max_text_size 时截断）
+	Content string `gorm:"column:content;type:mediumtext" json:"-"`
+	// 提取时间
+	UpdatedAt custom_type.JsonTime `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+}
+
+func (SearchContent) TableName() string {
+	return "search_content"
+}
+
+// SearchDoc 全文索引中的文档（一个用户文件）
+// 文件名、所在目录与公开状态以查询时的 user_files 为准，此处的文件名只用于判断是否需要重建索引
+type SearchDoc struct {
+	// 用户文件ID
+	UfID string `gorm:"column:uf_id;type:varchar(64);primaryKey" json:"uf_id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 文件信息ID
+	FileID string `gorm:"column:file_id;type:varchar(64);index;not null" json:"file_id"`
+	// 建立索引时的文件名
+	Name string `gorm:"column:name;type:varchar(255)" json:"name"`
+	// 文件分类（image、video、audio、document、archive、code、other）
+	Category string `gorm:"column:category;type:varchar(16);index" json:"category"`
+	// 文件大小
+	Size int64 `gorm:"column:size;type:bigint" json:"size"`
+	// 文档长度（文件名与内容的词条总数，用于 BM25 评分）
+	Length int `gorm:"column:length;type:int" json:"length"`
+	// 建立索引时间
+	IndexedAt custom_type.JsonTime `gorm:"column:indexed_at;type:datetime" json:"indexed_at"`
+}
+
+func (SearchDoc) TableName() string {
+	return "search_doc"
+}
+
+// SearchPosting 倒排记录：词条在文档中的出现次数
+type SearchPosting struct {
+	// 词条
+	Term string `gorm:"column:term;type:varchar(64);primaryKey" json:"term"`
+	// 用户文件ID
+	UfID string `gorm:"column:uf_id;type:varchar(64);primaryKey;index" json:"uf_id"`
+	// 在文件名中出现的次数
+	NameTF int `gorm:"column:name_tf;type:int" json:"name_tf"`
+	// 在内容中出现的次数
+	ContentTF int `gorm:"column:content_tf;type:int" json:"content_tf"`
+}
+
+func (SearchPosting) TableName() string {
+	return "search_posting"
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// 安全事件类型
+const (
+	// SecurityEventLoginFailed 登录失败（网页、WebDAV、SFTP）
+	SecurityEventLoginFailed = "login_failed"
+	// SecurityEventSharePasswordFailed 分享密码错误
+	SecurityEventSharePasswordFailed = "share_password_failed"
+	// SecurityEventLocked 失败次数过多，账户、IP 或分享被临时锁定
+	SecurityEventLocked = "locked"
+	// SecurityEventUnlocked 管理员解除账户锁定
+	SecurityEventUnlocked = "unlocked"
+)
+
+// SecurityEvent 安全事件日志（登录失败、锁定与解锁）
+// 解锁事件同时用于通知其他进程：CLI 无法访问服务端的本地缓存，服务端检查锁定时以最近的解锁事件为准
+type SecurityEvent struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 事件类型
+	Type string `gorm:"column:type;type:varchar(32);index;not null" json:"type"`
+	// 来源渠道（web、webdav、sftp、share、admin、cli）
+	Channel string `gorm:"column:channel;type:varchar(16)" json:"channel"`
+	// 事件对象：登录相关为用户名，分享相关为分享 token
+	Target string `gorm:"column:target;type:varchar(255);index" json:"target"`
+	// 客户端 IP
+	IP string `gorm:"column:ip;type:varchar(64)" json:"ip"`
+	// 详细说明
+	Detail string `gorm:"column:detail;type:varchar(512)" json:"detail"`
+	// 发生时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime;index" json:"created_at"`
+}
+
+func (SecurityEvent) TableName() string {
+	return "security_event"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// SmartFolder 智能文件夹：保存的文件过滤条件，打开时按条件查询用户的全部文件
+type SmartFolder struct {
+	ID     int    `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	UserID string `gorm:"column:user_id;type:varchar(64);not null;index" json:"user_id"`
+	// 名称（同一用户内唯一）
+	Name string `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	// 过滤与排序条件（JSON）
+	Filter string `gorm:"column:filter;type:text;not null" json:"-"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 更新时间
+	UpdatedAt custom_type.JsonTime `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+}
+
+func (SmartFolder) TableName() string {
+	return "smart_folder"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// UserIdentity 用户绑定的外部身份（OIDC 单点登录）
+// 同一身份提供方的同一主体（issuer + subject）只能绑定一个账户，一个账户可以绑定多个身份
+type UserIdentity struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 身份提供方（ID Token 中的 iss）
+	Issuer string `gorm:"column:issuer;type:varchar(255);uniqueIndex:idx_user_identity_subject;not null" json:"issuer"`
+	// 身份提供方中的用户标识（ID Token 中的 sub）
+	Subject string `gorm:"column:subject;type:varchar(255);uniqueIndex:idx_user_identity_subject;not null" json:"subject"`
+	// 绑定时的用户名（用于展示）
+	Username string `gorm:"column:username;type:varchar(255)" json:"username"`
+	// 绑定时的邮箱（用于展示）
+	Email string `gorm:"column:email;type:varchar(255)" json:"email"`
+	// 绑定时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 最近登录时间
+	LastLoginAt custom_type.JsonTime `gorm:"column:last_login_at;type:datetime" json:"last_login_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identity"
+}
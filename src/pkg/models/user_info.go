@@ -28,10 +28,32 @@ type UserInfo struct {
 	FilePassword string `gorm:"type:text" json:"file_password"`
 	//用户剩余存储空间
 	FreeSpace int64 `gorm:"type:free_space" json:"free_space"`
-	//用户状态 0正常 1禁用
+	//用户状态 0正常 1禁用 2待验证邮箱（开启注册邮箱验证时，验证前不能登录）
 	State int `gorm:"type:INTEGER;not null" json:"state"`
+	//账户来源 空-本地账户 ldap-LDAP 目录账户（密码由目录校验，本地不保存） oidc-单点登录创建的账户（本地不保存密码）
+	AuthSource string `gorm:"column:auth_source;type:VARCHAR(16);default:''" json:"auth_source"`
+	//登录密码修改时间（为空时按创建时间计算密码有效期）
+	PasswordChangedAt custom_type.JsonTime `gorm:"column:password_changed_at;type:DATETIME" json:"password_changed_at"`
+	//文件密码修改时间
+	FilePasswordChangedAt custom_type.JsonTime `gorm:"column:file_password_changed_at;type:DATETIME" json:"file_password_changed_at"`
+	//邮箱验证时间（为空表示未验证，修改邮箱后清空）
+	EmailVerifiedAt custom_type.JsonTime `gorm:"column:email_verified_at;type:DATETIME" json:"email_verified_at"`
 }
 
+// 用户状态
+const (
+	UserStateNormal        = 0
+	UserStateDisabled      = 1
+	UserStatePendingVerify = 2
+)
+
+// 账户来源
+const (
+	AuthSourceLocal = ""
+	AuthSourceLDAP  = "ldap"
+	AuthSourceOIDC  = "oidc"
+)
+
 func (UserInfo) TableName() string {
 	return "user_info"
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// UserSession 用户登录会话表（按用户索引，用于会话列表与定向注销）
+// 会话保存在数据库中，与缓存类型无关，CLI 等独立进程也能注销指定用户的会话
+type UserSession struct {
+	// 会话ID（对外展示与注销使用，与登录令牌不同）
+	ID string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 当前访问令牌哈希（SHA-256，不保存令牌明文，刷新令牌时更新）
+	TokenHash string `gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
+	// 设备（根据 User-Agent 识别，如 "Chrome / Windows"）
+	Device string `gorm:"column:device;type:varchar(128)" json:"device"`
+	// 最近访问 IP
+	IP string `gorm:"column:ip;type:varchar(64)" json:"ip"`
+	// User-Agent
+	UserAgent string `gorm:"column:user_agent;type:varchar(512)" json:"user_agent"`
+	// 创建（登录）时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 最近访问时间
+	LastSeenAt custom_type.JsonTime `gorm:"column:last_seen_at;type:datetime" json:"last_seen_at"`
+	// 过期时间（未勾选"记住我"时随刷新令牌滑动延长，不超过绝对过期时间）
+	ExpiresAt custom_type.JsonTime `gorm:"column:expires_at;type:datetime;index" json:"expires_at"`
+	// 绝对过期时间（到期后必须重新登录）
+	AbsoluteExpiresAt custom_type.JsonTime `gorm:"column:absolute_expires_at;type:datetime" json:"absolute_expires_at"`
+	// 登录时是否勾选"记住我"
+	RememberMe bool `gorm:"column:remember_me;default:false" json:"remember_me"`
+}
+
+func (UserSession) TableName() string {
+	return "user_session"
+}
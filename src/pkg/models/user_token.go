@@ -0,0 +1,32 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// 用户令牌类型
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+)
+
+// UserToken 邮件链接中的一次性令牌（邮箱验证、重置密码），使用后删除
+type UserToken struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 令牌类型（verify_email、reset_password）
+	Kind string `gorm:"column:kind;type:varchar(32);not null" json:"kind"`
+	// 令牌哈希（SHA-256，不保存令牌明文）
+	TokenHash string `gorm:"column:token_hash;type:varchar(64);uniqueIndex;not null" json:"-"`
+	// 邮件发送到的邮箱（验证邮箱时以此为准，期间修改过邮箱则令牌作废）
+	Email string `gorm:"column:email;type:varchar(255)" json:"email"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 过期时间
+	ExpiresAt custom_type.JsonTime `gorm:"column:expires_at;type:datetime;index" json:"expires_at"`
+}
+
+func (UserToken) TableName() string {
+	return "user_token"
+}
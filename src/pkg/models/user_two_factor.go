@@ -0,0 +1,28 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// UserTwoFactor 用户两步验证（TOTP）配置
+// 发起绑定时生成密钥（Enabled=false），用户输入验证码确认后才正式启用
+type UserTwoFactor struct {
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);primaryKey" json:"user_id"`
+	// TOTP 密钥（Base32）
+	Secret string `gorm:"column:secret;type:varchar(64);not null" json:"-"`
+	// 是否已启用
+	Enabled bool `gorm:"column:enabled;type:boolean;default:false" json:"enabled"`
+	// 未使用的恢复码哈希（JSON 数组，SHA-256）
+	RecoveryCodes string `gorm:"column:recovery_codes;type:text" json:"-"`
+	// 最近一次验证通过的时间步，同一验证码不能重复使用
+	LastStep int64 `gorm:"column:last_step;type:bigint;default:0" json:"-"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+	// 启用时间
+	EnabledAt custom_type.JsonTime `gorm:"column:enabled_at;type:datetime" json:"enabled_at"`
+}
+
+func (UserTwoFactor) TableName() string {
+	return "user_two_factor"
+}
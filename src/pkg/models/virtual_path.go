@@ -2,6 +2,8 @@ package models
 
 import (
 	"myobj/src/pkg/custom_type"
+
+	"gorm.io/gorm"
 )
 
 // VirtualPath 虚拟路径
@@ -14,6 +16,7 @@ type VirtualPath struct {
 	ParentLevel string               `gorm:"type:TEXT" json:"parent_level"`                      // 父级层级信息
 	CreatedTime custom_type.JsonTime `gorm:"type:DATETIME;not null" json:"created_time"`         // 创建时间
 	UpdateTime  custom_type.JsonTime `gorm:"type:DATETIME;not null" json:"update_time"`          // 更新时间
+	DeletedAt   gorm.DeletedAt       `gorm:"type:DATETIME;index" json:"-"`                       // 删除时间（移入回收站）
 }
 
 func (VirtualPath) TableName() string {
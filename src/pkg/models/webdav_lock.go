@@ -0,0 +1,31 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// WebDAVLock WebDAV 锁表（持久化锁，重启或多实例部署时仍然有效）
+type WebDAVLock struct {
+	// 锁令牌（opaquelocktoken:<uuid>）
+	Token string `gorm:"column:token;type:varchar(128);primaryKey" json:"token"`
+	// 用户ID（锁只作用于该用户自己的命名空间）
+	UserID string `gorm:"column:user_id;type:varchar(64);index;not null" json:"user_id"`
+	// 锁定的资源路径（以 / 开头，如 /docs/a.docx）
+	Root string `gorm:"column:root;type:varchar(1024);not null" json:"root"`
+	// 是否为共享锁（false 为独占锁）
+	Shared bool `gorm:"column:shared;type:boolean;default:false" json:"shared"`
+	// 是否只锁定资源本身（Depth: 0），否则锁定整个子树
+	ZeroDepth bool `gorm:"column:zero_depth;type:boolean;default:false" json:"zero_depth"`
+	// 锁持有者信息（客户端提交的 owner XML）
+	OwnerXML string `gorm:"column:owner_xml;type:text" json:"owner_xml"`
+	// 超时时长（秒）
+	Timeout int64 `gorm:"column:timeout;type:integer;not null" json:"timeout"`
+	// 过期时间
+	ExpiresAt custom_type.JsonTime `gorm:"column:expires_at;type:datetime;index" json:"expires_at"`
+	// 创建时间
+	CreatedAt custom_type.JsonTime `gorm:"column:created_at;type:datetime" json:"created_at"`
+}
+
+func (WebDAVLock) TableName() string {
+	return "webdav_lock"
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"myobj/src/pkg/custom_type"
+)
+
+// WebDAVProperty WebDAV 自定义属性表（PROPPATCH 写入的 dead property）
+// 以虚拟路径为键，Windows 资源管理器、macOS Finder 等客户端会写入时间戳、标签等属性
+type WebDAVProperty struct {
+	ID int `gorm:"column:id;type:integer;primaryKey;autoIncrement" json:"id"`
+	// 用户ID
+	UserID string `gorm:"column:user_id;type:varchar(64);index:webdav_property_user_path_index;not null" json:"user_id"`
+	// 资源路径（以 / 开头，如 /docs/a.docx）
+	Path string `gorm:"column:path;type:varchar(1024);not null" json:"path"`
+	// 资源路径的 SHA-256（十六进制），路径过长无法直接建索引，按路径查询时使用该字段
+	PathHash string `gorm:"column:path_hash;type:char(64);index:webdav_property_user_path_index;not null" json:"-"`
+	// 属性命名空间
+	Namespace string `gorm:"column:namespace;type:varchar(255);not null" json:"namespace"`
+	// 属性名
+	Name string `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	// xml:lang
+	Lang string `gorm:"column:lang;type:varchar(32)" json:"lang"`
+	// 属性值（原始 XML）
+	InnerXML string `gorm:"column:inner_xml;type:text" json:"inner_xml"`
+	// 更新时间
+	UpdatedAt custom_type.JsonTime `gorm:"column:updated_at;type:datetime" json:"updated_at"`
+}
+
+func (WebDAVProperty) TableName() string {
+	return "webdav_property"
+}
@@ -0,0 +1,147 @@
+package preview
+
+import (
+	"context"
+	"io/fs"
+	"myobj/src/pkg/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DerivativeCache 图片衍生图的磁盘缓存，按 (文件hash, 参数) 存放，同一内容的多个文件共用缓存
+// 总大小超过上限时按最后一次使用时间（命中时更新修改时间）删除最久未使用的衍生图
+//
+// 目录结构: {dir}/{hash前两位}/{hash}_{参数}{ext}
+type DerivativeCache struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	size    int64 // 当前缓存总大小，首次写入时扫描目录得到
+	scanned bool
+	group   singleflight.Group
+}
+
+// NewDerivativeCache 创建衍生图缓存，maxSize 为缓存总大小上限（字节）
+func NewDerivativeCache(dir string, maxSize int64) *DerivativeCache {
+	return &DerivativeCache{dir: dir, maxSize: maxSize}
+}
+
+// Dir 缓存目录
+func (c *DerivativeCache) Dir() string {
+	return c.dir
+}
+
+// Get 获取衍生图，缓存不存在时由 src 生成并写入缓存，同一衍生图的并发请求只生成一次
+func (c *DerivativeCache) Get(ctx context.Context, fileHash, src string, opts DerivativeOptions) (string, error) {
+	prefix := fileHash
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	cachePath := filepath.Join(c.dir, prefix, fileHash+"_"+opts.Key()+opts.Ext())
+	if _, err := os.Stat(cachePath); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(cachePath, now, now)
+		return cachePath, nil
+	}
+
+	_, err, _ := c.group.Do(cachePath, func() (any, error) {
+		if _, err := os.Stat(cachePath); err == nil {
+			return nil, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+			return nil, err
+		}
+		// 先写入临时文件再重命名，避免读到生成了一半的衍生图
+		tmp, err := os.CreateTemp(filepath.Dir(cachePath), ".tmp-*"+opts.Ext())
+		if err != nil {
+			return nil, err
+		}
+		tmpPath := tmp.Name()
+		tmp.Close()
+		if err := GenerateImageDerivative(ctx, src, tmpPath, opts); err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		info, err := os.Stat(tmpPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		if err := os.Rename(tmpPath, cachePath); err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		c.added(info.Size())
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+// added 记录新写入的衍生图大小，超过上限时淘汰
+func (c *DerivativeCache) added(size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.scanned {
+		// 首次写入时扫描目录得到已有缓存的大小（已包含本次写入的文件）
+		_, total := c.scan()
+		c.size, c.scanned = total, true
+	} else {
+		c.size += size
+	}
+	if c.maxSize > 0 && c.size > c.maxSize {
+		c.evict()
+	}
+}
+
+type derivativeEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// scan 列出缓存中的衍生图及总大小（不含生成中的临时文件）
+func (c *DerivativeCache) scan() ([]derivativeEntry, int64) {
+	var entries []derivativeEntry
+	var total int64
+	_ = filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Base(path)[0] == '.' {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, derivativeEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	return entries, total
+}
+
+// evict 按最后一次使用时间从旧到新删除衍生图，直到总大小降到上限的 90% 以下
+func (c *DerivativeCache) evict() {
+	entries, total := c.scan()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	target := c.maxSize / 10 * 9
+	removed := 0
+	for _, entry := range entries {
+		if total <= target {
+			break
+		}
+		if err := os.Remove(entry.path); err == nil {
+			total -= entry.size
+			removed++
+		}
+	}
+	c.size = total
+	logger.LOG.Info("衍生图缓存淘汰完成", "removed", removed, "size", total)
+}
@@ -0,0 +1,235 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"myobj/src/pkg/convert"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// 衍生图的缩放方式
+const (
+	// FitContain 完整显示在目标尺寸内，保持宽高比
+	FitContain = "contain"
+	// FitCover 铺满目标尺寸，居中裁剪超出部分
+	FitCover = "cover"
+)
+
+// 衍生图的输出格式
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	// FormatWebP 通过 ffmpeg 编码，需要配置 [convert] ffmpeg_path
+	FormatWebP = "webp"
+)
+
+// maxDerivativePixels 生成衍生图的原图最大像素数，避免超大图片解码时占用过多内存
+const maxDerivativePixels = 100 * 1000 * 1000
+
+// webpEncodeTimeout ffmpeg 编码 WebP 的超时时间
+const webpEncodeTimeout = time.Minute
+
+// DerivativeOptions 图片衍生图参数
+type DerivativeOptions struct {
+	// Width 目标宽度，0 表示按高度等比缩放
+	Width int
+	// Height 目标高度，0 表示按宽度等比缩放
+	Height int
+	// Fit 缩放方式（contain、cover），cover 需要同时指定宽高
+	Fit string
+	// Quality JPEG/WebP 的编码质量（1-100）
+	Quality int
+	// Format 输出格式（jpeg、png、webp）
+	Format string
+}
+
+// Key 衍生图参数的缓存键
+func (o DerivativeOptions) Key() string {
+	return fmt.Sprintf("w%d_h%d_%s_q%d", o.Width, o.Height, o.Fit, o.Quality)
+}
+
+// Ext 输出格式的扩展名
+func (o DerivativeOptions) Ext() string {
+	switch o.Format {
+	case FormatPNG:
+		return ".png"
+	case FormatWebP:
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// ContentType 输出格式的 MIME 类型
+func (o DerivativeOptions) ContentType() string {
+	switch o.Format {
+	case FormatPNG:
+		return "image/png"
+	case FormatWebP:
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// FormatAvailable 输出格式是否可用（WebP 需要配置 ffmpeg）
+func FormatAvailable(format string) bool {
+	switch format {
+	case FormatJPEG, FormatPNG:
+		return true
+	case FormatWebP:
+		_, ok := convert.FFmpegPath()
+		return ok
+	}
+	return false
+}
+
+// GenerateImageDerivative 生成图片衍生图
+// 按 EXIF 方向校正后缩放（cover 时居中裁剪），不会放大原图
+func GenerateImageDerivative(ctx context.Context, inputPath, outputPath string, opts DerivativeOptions) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("无法打开图片文件: %w", err)
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return fmt.Errorf("读取图片信息失败: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxDerivativePixels {
+		return fmt.Errorf("图片尺寸过大: %dx%d", cfg.Width, cfg.Height)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("图片解码失败: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	dst := resizeImage(img, ReadOrientation(inputPath), opts)
+	return encodeDerivative(ctx, outputPath, dst, opts)
+}
+
+// resizeImage 按目标尺寸缩放并校正方向
+// 先把原图缩放到校正方向后对应的尺寸，再旋转缩小后的图片，避免旋转整张原图
+func resizeImage(img image.Image, orientation int, opts DerivativeOptions) image.Image {
+	bounds := img.Bounds()
+	ow, oh := bounds.Dx(), bounds.Dy()
+	if swapsDimensions(orientation) {
+		ow, oh = oh, ow
+	}
+	cover := opts.Fit == FitCover && opts.Width > 0 && opts.Height > 0
+
+	scale := 1.0
+	if cover {
+		scale = math.Max(float64(opts.Width)/float64(ow), float64(opts.Height)/float64(oh))
+	} else {
+		if opts.Width > 0 {
+			scale = math.Min(scale, float64(opts.Width)/float64(ow))
+		}
+		if opts.Height > 0 {
+			scale = math.Min(scale, float64(opts.Height)/float64(oh))
+		}
+	}
+	scale = math.Min(scale, 1)
+	round := math.Round
+	if cover {
+		round = math.Ceil // 保证缩放后不小于裁剪尺寸
+	}
+	nw := max(1, int(round(float64(ow)*scale)))
+	nh := max(1, int(round(float64(oh)*scale)))
+
+	out := img
+	if nw != ow || nh != oh {
+		sw, sh := nw, nh
+		if swapsDimensions(orientation) {
+			sw, sh = nh, nw
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, sw, sh))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+		out = scaled
+	}
+	out = applyOrientation(out, orientation)
+
+	if cover {
+		cw, ch := min(opts.Width, nw), min(opts.Height, nh)
+		ob := out.Bounds()
+		crop := image.Rect(0, 0, cw, ch).Add(ob.Min).Add(image.Pt((nw-cw)/2, (nh-ch)/2))
+		cropped := image.NewRGBA(image.Rect(0, 0, cw, ch))
+		draw.Draw(cropped, cropped.Bounds(), out, crop.Min, draw.Src)
+		out = cropped
+	}
+	return out
+}
+
+// encodeDerivative 按输出格式编码，JPEG 不支持透明，透明区域合成为白色
+func encodeDerivative(ctx context.Context, outputPath string, img image.Image, opts DerivativeOptions) error {
+	switch opts.Format {
+	case FormatPNG:
+		return writeImageFile(outputPath, func(f *os.File) error { return png.Encode(f, img) })
+	case FormatWebP:
+		return encodeWebP(ctx, outputPath, img, opts.Quality)
+	}
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+	return writeImageFile(outputPath, func(f *os.File) error {
+		return jpeg.Encode(f, flat, &jpeg.Options{Quality: opts.Quality})
+	})
+}
+
+func writeImageFile(path string, encode func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("无法创建输出文件: %w", err)
+	}
+	err = encode(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("图片编码失败: %w", err)
+	}
+	return nil
+}
+
+// encodeWebP 先编码为无损 PNG，再调用 ffmpeg 转为 WebP
+func encodeWebP(ctx context.Context, outputPath string, img image.Image, quality int) error {
+	ffmpeg, ok := convert.FFmpegPath()
+	if !ok {
+		return fmt.Errorf("未配置 ffmpeg，无法生成 WebP")
+	}
+	pngPath := outputPath + ".src.png"
+	defer os.Remove(pngPath)
+	if err := writeImageFile(pngPath, func(f *os.File) error { return png.Encode(f, img) }); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webpEncodeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, ffmpeg, "-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-i", pngPath, "-q:v", strconv.Itoa(quality), "-f", "webp", filepath.Clean(outputPath))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg 编码 WebP 失败: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -55,6 +55,8 @@ func GenerateImageThumbnail(inputPath, outputPath string, maxDimension uint) err
 		return fmt.Errorf("图片解码失败: %w", err)
 	}
 	logger.LOG.Info("图片解码成功", "format", format)
+	// 按 EXIF 方向校正（手机拍摄的照片常以未旋转的方向存储）
+	img = applyOrientation(img, ReadOrientation(inputPath))
 	// 获取原图尺寸并计算缩略图尺寸
 	bounds := img.Bounds()
 	width := bounds.Dx()
@@ -0,0 +1,533 @@
+package recycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/dirtree"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/search"
+	"myobj/src/pkg/util"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 目录回收站：目录连同其中的子目录与文件作为一个整体移入回收站
+// 顶层条目记录目录ID与删除前路径，子目录与文件的记录通过 ParentID 指向顶层条目，
+// 目录本身只做软删除，还原时按原有层级重建；永久删除与过期清理同样按整棵目录树处理
+
+// 还原目录时原位置已存在同名目录的处理方式
+const (
+	// ConflictRename 重命名为 "目录名 (1)" 后还原
+	ConflictRename = "rename"
+	// ConflictMerge 合并到已存在的目录，同名子目录继续合并，同名文件重命名
+	ConflictMerge = "merge"
+	// ConflictOverwrite 将已存在的目录移入回收站后还原
+	ConflictOverwrite = "overwrite"
+)
+
+// ErrConflict 原位置已存在同名目录且未指定处理方式
+var ErrConflict = errors.New("原位置已存在同名目录")
+
+// Bin 目录回收站
+type Bin struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewBin 创建目录回收站
+func NewBin(factory *impl.RepositoryFactory) *Bin {
+	return &Bin{factory: factory}
+}
+
+// Summary 目录树统计
+type Summary struct {
+	Dirs  int `json:"dirs"`
+	Files int `json:"files"`
+}
+
+// RestoreResult 目录还原结果
+type RestoreResult struct {
+	// DirID 还原后的目录ID（合并时为已存在目录的ID）
+	DirID int `json:"dir_id"`
+	// Name 还原后的目录名
+	Name string `json:"name"`
+	// ToRoot 原父目录已不存在，还原到了根目录
+	ToRoot bool `json:"to_root"`
+	// Merged 合并到了已存在的同名目录
+	Merged bool `json:"merged"`
+	// Renamed 因重名而改名的目录与文件数量
+	Renamed int `json:"renamed"`
+}
+
+// RecycleFile 将单个用户文件移入回收站（软删除 user_files 并创建回收站记录）
+func (b *Bin) RecycleFile(ctx context.Context, userID string, file *models.UserFiles) (*models.Recycled, error) {
+	entry := &models.Recycled{
+		ID:        uuid.Must(uuid.NewV7()).String(),
+		FileID:    file.UfID,
+		UserID:    userID,
+		CreatedAt: custom_type.Now(),
+	}
+	err := b.factory.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND uf_id = ?", userID, file.UfID).Delete(&models.UserFiles{}).Error; err != nil {
+			return fmt.Errorf("软删除用户文件失败: %w", err)
+		}
+		return b.factory.WithTx(tx).Recycled().Create(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	search.Notify(userID, file.UfID)
+	return entry, nil
+}
+
+// RecycleDir 将目录连同其中的子目录与文件整体移入回收站
+func (b *Bin) RecycleDir(ctx context.Context, userID string, dir *models.VirtualPath) (*models.Recycled, *Summary, error) {
+	originalPath, err := b.dirPath(ctx, dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取目录路径失败: %w", err)
+	}
+
+	now := custom_type.Now()
+	entry := &models.Recycled{
+		ID:           uuid.Must(uuid.NewV7()).String(),
+		UserID:       userID,
+		DirID:        dir.ID,
+		OriginalPath: originalPath,
+		CreatedAt:    now,
+	}
+	// 在事务中收集目录树，保证移入回收站的文件与软删除的目录一致
+	dirIDs := []int{dir.ID}
+	var files []*models.UserFiles
+	err = b.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := b.factory.WithTx(tx)
+		if err := collectSubDirs(ctx, txFactory, userID, dir.ID, &dirIDs, 0); err != nil {
+			return fmt.Errorf("收集子目录失败: %w", err)
+		}
+		for _, id := range dirIDs {
+			list, err := dirtree.ListFiles(ctx, txFactory, userID, id)
+			if err != nil {
+				return fmt.Errorf("获取目录文件失败: %w", err)
+			}
+			files = append(files, list...)
+		}
+
+		if err := txFactory.Recycled().Create(ctx, entry); err != nil {
+			return fmt.Errorf("创建回收站记录失败: %w", err)
+		}
+		for _, id := range dirIDs[1:] {
+			member := &models.Recycled{
+				ID:        uuid.Must(uuid.NewV7()).String(),
+				UserID:    userID,
+				DirID:     id,
+				ParentID:  entry.ID,
+				CreatedAt: now,
+			}
+			if err := txFactory.Recycled().Create(ctx, member); err != nil {
+				return fmt.Errorf("创建回收站记录失败: %w", err)
+			}
+		}
+		for _, file := range files {
+			if err := tx.Where("user_id = ? AND uf_id = ?", userID, file.UfID).Delete(&models.UserFiles{}).Error; err != nil {
+				return fmt.Errorf("软删除用户文件失败: %w", err)
+			}
+			member := &models.Recycled{
+				ID:        uuid.Must(uuid.NewV7()).String(),
+				FileID:    file.UfID,
+				UserID:    userID,
+				ParentID:  entry.ID,
+				CreatedAt: now,
+			}
+			if err := txFactory.Recycled().Create(ctx, member); err != nil {
+				return fmt.Errorf("创建回收站记录失败: %w", err)
+			}
+		}
+		if err := tx.Where("id IN ?", dirIDs).Delete(&models.VirtualPath{}).Error; err != nil {
+			return fmt.Errorf("软删除目录失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	search.Notify(userID, ufIDs(files)...)
+	summary := &Summary{Dirs: len(dirIDs) - 1, Files: len(files)}
+	logger.LOG.Info("目录已移入回收站", "userID", userID, "dirID", dir.ID, "path", originalPath,
+		"dirs", summary.Dirs, "files", summary.Files)
+	return entry, summary, nil
+}
+
+// Restore 还原目录条目，按原有层级重建整棵目录树
+// 原父目录已不存在时还原到根目录；原位置已存在同名目录时按 conflict 处理，conflict 为空时返回 ErrConflict
+func (b *Bin) Restore(ctx context.Context, entry *models.Recycled, conflict string) (*RestoreResult, error) {
+	tree, err := b.loadTree(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Name: dirtree.DirName(tree.top.Path)}
+	parentID, err := strconv.Atoi(tree.top.ParentLevel)
+	if err == nil {
+		if parent, err := b.factory.VirtualPath().GetByID(ctx, parentID); err != nil || parent.UserID != entry.UserID {
+			parentID = 0
+		}
+	}
+	if parentID == 0 {
+		root, err := b.factory.VirtualPath().GetRootPath(ctx, entry.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("获取根目录失败: %w", err)
+		}
+		parentID = root.ID
+		result.ToRoot = true
+	}
+
+	err = b.factory.DB().Transaction(func(tx *gorm.DB) error {
+		r := &restorer{ctx: ctx, factory: b.factory.WithTx(tx), tx: tx, tree: tree, result: result}
+
+		existing, err := r.findLiveDir(parentID, result.Name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case existing == nil:
+			err = r.restoreDir(tree.top, parentID, tree.top.Path)
+		case conflict == ConflictRename:
+			names, nameErr := r.liveDirNames(parentID)
+			if nameErr != nil {
+				return nameErr
+			}
+			result.Name = util.UniqueName(result.Name, names, false)
+			result.Renamed++
+			err = r.restoreDir(tree.top, parentID, "/"+result.Name)
+		case conflict == ConflictMerge:
+			result.Merged = true
+			err = r.mergeDir(tree.top, existing.ID)
+		case conflict == ConflictOverwrite:
+			if _, _, err := NewBin(r.factory).RecycleDir(ctx, entry.UserID, existing); err != nil {
+				return fmt.Errorf("原位置目录移入回收站失败: %w", err)
+			}
+			err = r.restoreDir(tree.top, parentID, tree.top.Path)
+		default:
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := r.factory.Recycled().DeleteByParentID(ctx, entry.ID); err != nil {
+			return fmt.Errorf("删除回收站记录失败: %w", err)
+		}
+		if err := r.factory.Recycled().Delete(ctx, entry.ID); err != nil {
+			return fmt.Errorf("删除回收站记录失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.DirID == 0 {
+		result.DirID = tree.top.ID
+	}
+	for _, files := range tree.files {
+		search.Notify(entry.UserID, ufIDs(files)...)
+	}
+	return result, nil
+}
+
+// Purge 永久删除目录条目：purgeFile 逐个删除其中的文件（负责删除文件的回收站记录），
+// 全部文件删除成功后彻底删除目录记录与回收站记录，返回删除失败的文件数量
+func (b *Bin) Purge(ctx context.Context, entry *models.Recycled, purgeFile func(member *models.Recycled) error) (int, error) {
+	members, err := b.factory.Recycled().ListByParentID(ctx, entry.ID)
+	if err != nil {
+		return 0, fmt.Errorf("获取目录回收站记录失败: %w", err)
+	}
+
+	failed := 0
+	dirIDs := []int{entry.DirID}
+	for _, member := range members {
+		if member.IsDir() {
+			dirIDs = append(dirIDs, member.DirID)
+			continue
+		}
+		if err := purgeFile(member); err != nil {
+			logger.LOG.Error("删除目录中的文件失败", "error", err, "recycledID", member.ID, "fileID", member.FileID)
+			failed++
+		}
+	}
+	// 有文件删除失败时保留目录条目，便于之后重试
+	if failed > 0 {
+		return failed, nil
+	}
+
+	err = b.factory.DB().Transaction(func(tx *gorm.DB) error {
+		txFactory := b.factory.WithTx(tx)
+		if err := txFactory.VirtualPath().Purge(ctx, dirIDs); err != nil {
+			return fmt.Errorf("删除目录记录失败: %w", err)
+		}
+		if err := txFactory.Recycled().DeleteByParentID(ctx, entry.ID); err != nil {
+			return fmt.Errorf("删除回收站记录失败: %w", err)
+		}
+		return txFactory.Recycled().Delete(ctx, entry.ID)
+	})
+	return 0, err
+}
+
+// Stat 统计目录条目中的子目录数、文件数与文件总大小
+func (b *Bin) Stat(ctx context.Context, entry *models.Recycled) (*Summary, int64, error) {
+	members, err := b.factory.Recycled().ListByParentID(ctx, entry.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	summary := &Summary{}
+	for _, member := range members {
+		if member.IsDir() {
+			summary.Dirs++
+		} else {
+			summary.Files++
+		}
+	}
+	size, err := b.factory.Recycled().SumFileSizeByParentID(ctx, entry.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return summary, size, nil
+}
+
+// collectSubDirs 递归收集目录下的全部子目录
+func collectSubDirs(ctx context.Context, factory *impl.RepositoryFactory, userID string, parentID int, result *[]int, depth int) error {
+	if depth >= dirtree.MaxDepth {
+		return fmt.Errorf("目录层级过深")
+	}
+	subDirs, err := dirtree.ListSubDirs(ctx, factory, userID, parentID)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subDirs {
+		*result = append(*result, sub.ID)
+		if err := collectSubDirs(ctx, factory, userID, sub.ID, result, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dirPath 获取目录的完整路径（如 "/文档/报告"）
+func (b *Bin) dirPath(ctx context.Context, dir *models.VirtualPath) (string, error) {
+	var parts []string
+	for depth := 0; depth < dirtree.MaxDepth; depth++ {
+		if dir.ParentLevel == "" {
+			return "/" + strings.Join(parts, "/"), nil
+		}
+		parts = append([]string{dirtree.DirName(dir.Path)}, parts...)
+		parentID, err := strconv.Atoi(dir.ParentLevel)
+		if err != nil {
+			return "", err
+		}
+		if dir, err = b.factory.VirtualPath().GetByID(ctx, parentID); err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("目录层级过深")
+}
+
+// dirTree 回收站中的目录树
+type dirTree struct {
+	top *models.VirtualPath
+	// subDirs 父目录ID -> 子目录
+	subDirs map[int][]*models.VirtualPath
+	// files 目录ID -> 文件
+	files map[int][]*models.UserFiles
+}
+
+// loadTree 加载目录条目对应的目录树
+func (b *Bin) loadTree(ctx context.Context, entry *models.Recycled) (*dirTree, error) {
+	top, err := b.factory.VirtualPath().GetByIDUnscoped(ctx, entry.DirID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("目录记录不存在")
+		}
+		return nil, fmt.Errorf("获取目录记录失败: %w", err)
+	}
+	members, err := b.factory.Recycled().ListByParentID(ctx, entry.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取目录回收站记录失败: %w", err)
+	}
+	var dirIDs []int
+	var fileIDs []string
+	for _, member := range members {
+		if member.IsDir() {
+			dirIDs = append(dirIDs, member.DirID)
+		} else {
+			fileIDs = append(fileIDs, member.FileID)
+		}
+	}
+
+	tree := &dirTree{
+		top:     top,
+		subDirs: make(map[int][]*models.VirtualPath),
+		files:   make(map[int][]*models.UserFiles),
+	}
+	dirs, err := b.factory.VirtualPath().ListByIDsUnscoped(ctx, dirIDs)
+	if err != nil {
+		return nil, fmt.Errorf("获取子目录记录失败: %w", err)
+	}
+	for _, dir := range dirs {
+		parentID, _ := strconv.Atoi(dir.ParentLevel)
+		tree.subDirs[parentID] = append(tree.subDirs[parentID], dir)
+	}
+	if len(fileIDs) > 0 {
+		var files []*models.UserFiles
+		err := b.factory.DB().WithContext(ctx).Unscoped().
+			Where("user_id = ? AND uf_id IN ?", entry.UserID, fileIDs).Find(&files).Error
+		if err != nil {
+			return nil, fmt.Errorf("获取文件记录失败: %w", err)
+		}
+		for _, file := range files {
+			dirID, _ := strconv.Atoi(file.VirtualPath)
+			tree.files[dirID] = append(tree.files[dirID], file)
+		}
+	}
+	return tree, nil
+}
+
+// restorer 在事务中还原目录树
+type restorer struct {
+	ctx     context.Context
+	factory *impl.RepositoryFactory
+	tx      *gorm.DB
+	tree    *dirTree
+	result  *RestoreResult
+}
+
+// restoreDir 将目录还原到指定父目录下，其中的子目录与文件保持原有层级
+func (r *restorer) restoreDir(dir *models.VirtualPath, parentID int, dirPath string) error {
+	err := r.tx.Model(&models.VirtualPath{}).Unscoped().Where("id = ?", dir.ID).Updates(map[string]interface{}{
+		"deleted_at":   nil,
+		"parent_level": strconv.Itoa(parentID),
+		"path":         dirPath,
+		"update_time":  custom_type.Now(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("还原目录失败: %w", err)
+	}
+	if err := r.restoreFiles(dir.ID); err != nil {
+		return err
+	}
+	for _, sub := range r.tree.subDirs[dir.ID] {
+		if err := r.restoreDir(sub, dir.ID, sub.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreFiles 原样还原目录中的文件
+func (r *restorer) restoreFiles(dirID int) error {
+	for _, file := range r.tree.files[dirID] {
+		err := r.tx.Model(&models.UserFiles{}).Unscoped().
+			Where("user_id = ? AND uf_id = ?", file.UserID, file.UfID).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return fmt.Errorf("还原文件失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeDir 将回收站中的目录合并到已存在的目录：同名子目录继续合并，同名文件重命名后还原，
+// 合并完成后彻底删除回收站中的目录记录
+func (r *restorer) mergeDir(dir *models.VirtualPath, targetID int) error {
+	if r.result.DirID == 0 {
+		r.result.DirID = targetID
+	}
+	if files := r.tree.files[dir.ID]; len(files) > 0 {
+		live, err := dirtree.ListFiles(r.ctx, r.factory, dir.UserID, targetID)
+		if err != nil {
+			return fmt.Errorf("获取目录文件失败: %w", err)
+		}
+		names := make(map[string]bool, len(live))
+		for _, file := range live {
+			names[file.FileName] = true
+		}
+		for _, file := range files {
+			name := file.FileName
+			if names[name] {
+				name = util.UniqueName(name, names, true)
+				r.result.Renamed++
+			}
+			names[name] = true
+			err := r.tx.Model(&models.UserFiles{}).Unscoped().
+				Where("user_id = ? AND uf_id = ?", file.UserID, file.UfID).
+				Updates(map[string]interface{}{
+					"deleted_at":   nil,
+					"virtual_path": strconv.Itoa(targetID),
+					"file_name":    name,
+				}).Error
+			if err != nil {
+				return fmt.Errorf("还原文件失败: %w", err)
+			}
+		}
+	}
+
+	for _, sub := range r.tree.subDirs[dir.ID] {
+		existing, err := r.findLiveDir(targetID, dirtree.DirName(sub.Path))
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			err = r.mergeDir(sub, existing.ID)
+		} else {
+			err = r.restoreDir(sub, targetID, sub.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.factory.VirtualPath().Purge(r.ctx, []int{dir.ID}); err != nil {
+		return fmt.Errorf("删除已合并目录失败: %w", err)
+	}
+	return nil
+}
+
+// ufIDs 用户文件ID列表
+func ufIDs(files []*models.UserFiles) []string {
+	ids := make([]string, len(files))
+	for i, file := range files {
+		ids[i] = file.UfID
+	}
+	return ids
+}
+
+// findLiveDir 在父目录下按名称查找未删除的子目录
+func (r *restorer) findLiveDir(parentID int, name string) (*models.VirtualPath, error) {
+	dirs, err := dirtree.ListSubDirs(r.ctx, r.factory, r.tree.top.UserID, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询子目录失败: %w", err)
+	}
+	for _, dir := range dirs {
+		if dirtree.DirName(dir.Path) == name {
+			return dir, nil
+		}
+	}
+	return nil, nil
+}
+
+// liveDirNames 父目录下未删除的子目录名称
+func (r *restorer) liveDirNames(parentID int) (map[string]bool, error) {
+	dirs, err := dirtree.ListSubDirs(r.ctx, r.factory, r.tree.top.UserID, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询子目录失败: %w", err)
+	}
+	names := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		names[dirtree.DirName(dir.Path)] = true
+	}
+	return names, nil
+}
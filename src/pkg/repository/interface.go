@@ -3,23 +3,32 @@ package repository
 import (
 	"context"
 	"myobj/src/pkg/models"
+	"time"
 )
 
 // UserRepository 用户仓储接口
 type UserRepository interface {
 	Create(ctx context.Context, user *models.UserInfo) error
 	GetByID(ctx context.Context, id string) (*models.UserInfo, error)
+	// GetByIDForUpdate 在事务中获取用户并加行锁，用于串行化同一用户的并发操作
+	GetByIDForUpdate(ctx context.Context, id string) (*models.UserInfo, error)
 	GetByUserName(ctx context.Context, userName string) (*models.UserInfo, error)
 	Update(ctx context.Context, user *models.UserInfo) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, offset, limit int) ([]*models.UserInfo, error)
 	Count(ctx context.Context) (int64, error)
+	// ListByAuthSource 获取指定来源的所有账户（如 LDAP 目录账户）
+	ListByAuthSource(ctx context.Context, source string) ([]*models.UserInfo, error)
+	// ListByEmail 获取指定邮箱的所有账户
+	ListByEmail(ctx context.Context, email string) ([]*models.UserInfo, error)
 }
 
 // FileInfoRepository 文件信息仓储接口
 type FileInfoRepository interface {
 	Create(ctx context.Context, file *models.FileInfo) error
 	GetByID(ctx context.Context, id string) (*models.FileInfo, error)
+	// GetByIDForUpdate 在事务中获取文件信息并加行锁，用于串行化同一文件内容的修改
+	GetByIDForUpdate(ctx context.Context, id string) (*models.FileInfo, error)
 	GetByHash(ctx context.Context, hash string) (*models.FileInfo, error)
 	GetByChunkSignature(ctx context.Context, signature string, fileSize int64) (*models.FileInfo, error)
 	Update(ctx context.Context, file *models.FileInfo) error
@@ -33,6 +42,16 @@ type FileInfoRepository interface {
 	ListByVirtualPath(ctx context.Context, userID, virtualPath string, offset, limit int) ([]*models.FileInfo, error)
 	// CountByVirtualPath 统计指定虚拟路径下的文件数量
 	CountByVirtualPath(ctx context.Context, userID, virtualPath string) (int64, error)
+	// ListUncategorized 查询尚未划分分类的文件（升级前上传的文件）
+	ListUncategorized(ctx context.Context, limit int) ([]*models.FileInfo, error)
+	// UpdateCategory 更新文件分类
+	UpdateCategory(ctx context.Context, id, category string) error
+	// ReplaceContent 文件哈希仍为 expectedHash 时替换文件内容相关字段，返回更新的行数（0 表示内容已被修改）
+	ReplaceContent(ctx context.Context, file *models.FileInfo, expectedHash string) (int64, error)
+	// ListImagesAfter 按ID顺序查询 afterID 之后未加密、未分片存储的图片文件（用于补建缩略图）
+	ListImagesAfter(ctx context.Context, afterID string, limit int) ([]*models.FileInfo, error)
+	// UpdateThumbnail 更新文件缩略图路径
+	UpdateThumbnail(ctx context.Context, id, thumbnailPath string) error
 }
 
 // GroupRepository 组仓储接口
@@ -56,6 +75,8 @@ type ShareRepository interface {
 	List(ctx context.Context, userID string, offset, limit int) ([]*models.Share, error)
 	Count(ctx context.Context, userID string) (int64, error)
 	IncrementDownloadCount(ctx context.Context, id int) error
+	// UpdateFileID 将用户指向旧文件的分享改为指向新文件
+	UpdateFileID(ctx context.Context, userID, oldFileID, newFileID string) error
 }
 
 // DiskRepository 磁盘仓储接口
@@ -79,6 +100,8 @@ type ApiKeyRepository interface {
 	Delete(ctx context.Context, id int) error
 	List(ctx context.Context, userID string, offset, limit int) ([]*models.ApiKey, error)
 	Count(ctx context.Context, userID string) (int64, error)
+	// RecordUsage 累加调用次数并更新最近使用时间和 IP
+	RecordUsage(ctx context.Context, id int, ip string) error
 }
 
 // FileChunkRepository 文件分片仓储接口
@@ -113,6 +136,66 @@ type GroupPowerRepository interface {
 	BatchCreate(ctx context.Context, groupPowers []*models.GroupPower) error
 }
 
+// FileFilter 用户文件的结构化过滤条件，零值字段不过滤
+type FileFilter struct {
+	// UserID 只查询该用户的文件
+	UserID string
+	// VirtualPaths 只查询这些目录中的文件（目录ID）
+	VirtualPaths []string
+	// Keyword 文件名包含的关键词
+	Keyword string
+	// Categories 文件分类（image、video、audio、document、archive、code、other），满足任一即可
+	Categories []string
+	// Exts 扩展名（小写，不含 "."），满足任一即可
+	Exts []string
+	// MinSize、MaxSize 文件大小范围（字节）
+	MinSize int64
+	MaxSize int64
+	// CreatedStart、CreatedEnd 创建时间范围
+	CreatedStart *time.Time
+	CreatedEnd   *time.Time
+	// UpdatedStart、UpdatedEnd 修改时间范围（没有修改时间时按创建时间）
+	UpdatedStart *time.Time
+	UpdatedEnd   *time.Time
+	// Encrypted 是否加密
+	Encrypted *bool
+	// Public 是否公开
+	Public *bool
+	// Shared 是否有未过期的分享链接
+	Shared *bool
+}
+
+// 文件排序字段
+const (
+	FileSortName    = "name"
+	FileSortSize    = "size"
+	FileSortCreated = "created"
+	FileSortUpdated = "updated"
+	FileSortType    = "type"
+)
+
+// FileSort 文件排序条件
+type FileSort struct {
+	Field string
+	Desc  bool
+}
+
+// FileCursor 游标分页位置，记录上一页最后一个文件的排序字段值
+// UfID 为空表示从第一个文件开始
+type FileCursor struct {
+	// Values 与排序条件一一对应（时间为 RFC3339 格式）
+	Values []string `json:"v"`
+	UfID   string   `json:"id"`
+}
+
+// CategoryStat 单个文件分类的统计
+type CategoryStat struct {
+	Category string
+	Count    int64
+	// Size 占用空间（字节）
+	Size int64
+}
+
 // UserFilesRepository 用户文件关联仓储接口
 type UserFilesRepository interface {
 	Create(ctx context.Context, userFile *models.UserFiles) error
@@ -121,17 +204,22 @@ type UserFilesRepository interface {
 	Delete(ctx context.Context, userID, fileID string) error
 	ListByUserID(ctx context.Context, userID string, offset, limit int) ([]*models.UserFiles, error)
 	Count(ctx context.Context, userID string) (int64, error)
+	// SumSizeByUserID 统计用户文件占用的总大小（字节）
+	SumSizeByUserID(ctx context.Context, userID string) (int64, error)
 	ListPublicFiles(ctx context.Context, offset, limit int) ([]*models.UserFiles, error)
-	CountPublicFiles(ctx context.Context) (int64, error)
-	SearchPublicFiles(ctx context.Context, keyword string, offset, limit int) ([]*models.UserFiles, error)
-	CountPublicFilesByKeyword(ctx context.Context, keyword string) (int64, error)
-	SearchUserFiles(ctx context.Context, userID, keyword string, offset, limit int) ([]*models.UserFiles, error)
-	CountUserFilesByKeyword(ctx context.Context, userID, keyword string) (int64, error)
 	GetByUserIDAndUfID(ctx context.Context, userID, ufID string) (*models.UserFiles, error)
 	// GetByUfID 通过 uf_id 查询文件（用于公开文件访问，不要求 user_id）
 	GetByUfID(ctx context.Context, ufID string) (*models.UserFiles, error)
 	// ListByVirtualPath 查询指定虚拟路径下的user_files记录（避免file_id重复问题）
 	ListByVirtualPath(ctx context.Context, userID, virtualPath string, offset, limit int) ([]*models.UserFiles, error)
+	// ListByFilter 按条件查询文件，依次按 sorts 与 uf_id 排序；after 不为空时从该位置之后开始
+	// 还有更多文件时返回指向本页最后一个文件的游标，否则返回 nil
+	ListByFilter(ctx context.Context, filter FileFilter, sorts []FileSort, after *FileCursor, offset, limit int) ([]*models.UserFiles, *FileCursor, error)
+	CountByFilter(ctx context.Context, filter FileFilter) (int64, error)
+	// StatByCategory 按分类统计用户文件的数量与占用空间，未划分分类的文件计入 other
+	StatByCategory(ctx context.Context, userID string) ([]*CategoryStat, error)
+	// RepointFile 用户文件仍指向 oldFileID 时改为指向 newFileID，返回更新的行数
+	RepointFile(ctx context.Context, userID, ufID, oldFileID, newFileID string) (int64, error)
 }
 
 // VirtualPathRepository 虚拟路径仓储接口
@@ -151,6 +239,12 @@ type VirtualPathRepository interface {
 	GetRootPath(ctx context.Context, userID string) (*models.VirtualPath, error)
 	// GetPathByUser 获取用户所有路径
 	GetPathByUser(ctx context.Context, userID string) ([]*models.VirtualPath, error)
+	// GetByIDUnscoped 通过ID查询目录（包含已移入回收站的目录）
+	GetByIDUnscoped(ctx context.Context, id int) (*models.VirtualPath, error)
+	// ListByIDsUnscoped 批量查询目录（包含已移入回收站的目录）
+	ListByIDsUnscoped(ctx context.Context, ids []int) ([]*models.VirtualPath, error)
+	// Purge 彻底删除目录记录（包含已移入回收站的目录）
+	Purge(ctx context.Context, ids []int) error
 }
 
 // RecycledRepository 回收站仓储接口
@@ -159,12 +253,22 @@ type RecycledRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Recycled, error)
 	GetByUserIDAndFileID(ctx context.Context, userID, fileID string) (*models.Recycled, error)
 	Delete(ctx context.Context, id string) error
+	// ListByUserID 查询用户回收站的顶层条目（不含随目录一起删除的文件和子目录）
 	ListByUserID(ctx context.Context, userID string, offset, limit int) ([]*models.Recycled, error)
+	// Count 统计用户回收站的顶层条目数量
 	Count(ctx context.Context, userID string) (int64, error)
-	// GetExpiredRecords 获取超过指定天数的回收站记录
+	// GetExpiredRecords 获取超过指定天数的回收站顶层条目
 	GetExpiredRecords(ctx context.Context, days int) ([]*models.Recycled, error)
+	// ListByParentID 查询随目录条目一起删除的文件和子目录记录
+	ListByParentID(ctx context.Context, parentID string) ([]*models.Recycled, error)
+	// DeleteByParentID 删除目录条目下的全部记录
+	DeleteByParentID(ctx context.Context, parentID string) error
+	// SumFileSizeByParentID 统计目录条目下文件的总大小
+	SumFileSizeByParentID(ctx context.Context, parentID string) (int64, error)
 	// CountFileReferences 统计指定文件被多少个用户持有
 	CountFileReferences(ctx context.Context, fileID string) (int64, error)
+	// CountSharedReferences 统计除指定用户文件外仍引用同一文件的用户文件数（含回收站中可还原的）
+	CountSharedReferences(ctx context.Context, fileID, exceptUfID string) (int64, error)
 }
 
 // DownloadTaskRepository 下载任务仓储接口
@@ -234,3 +338,253 @@ type UploadChunkRepository interface {
 	// ListByPathID 根据路径ID获取分片列表
 	ListByPathID(ctx context.Context, pathID string, offset, limit int) ([]*models.UploadChunk, error)
 }
+
+// WebDAVLockRepository WebDAV 锁仓储接口
+type WebDAVLockRepository interface {
+	Create(ctx context.Context, lock *models.WebDAVLock) error
+	GetByToken(ctx context.Context, token string) (*models.WebDAVLock, error)
+	Update(ctx context.Context, lock *models.WebDAVLock) error
+	Delete(ctx context.Context, token string) error
+	// ListActiveByUserID 获取用户所有未过期的锁
+	ListActiveByUserID(ctx context.Context, userID string) ([]*models.WebDAVLock, error)
+	// List 分页获取所有未过期的锁（userID 为空时不过滤用户）
+	List(ctx context.Context, userID string, offset, limit int) ([]*models.WebDAVLock, error)
+	Count(ctx context.Context, userID string) (int64, error)
+	// DeleteExpired 删除已过期的锁
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// WebDAVPropertyRepository WebDAV 自定义属性仓储接口
+type WebDAVPropertyRepository interface {
+	// ListByPath 获取资源的所有属性
+	ListByPath(ctx context.Context, userID, path string) ([]*models.WebDAVProperty, error)
+	// Save 写入属性（同名属性存在时覆盖）
+	Save(ctx context.Context, prop *models.WebDAVProperty) error
+	// Delete 删除资源的指定属性
+	Delete(ctx context.Context, userID, path, namespace, name string) error
+	// DeleteByPath 删除资源及其所有子资源的属性
+	DeleteByPath(ctx context.Context, userID, path string) error
+	// MovePath 资源移动后更新其及子资源属性的路径
+	MovePath(ctx context.Context, userID, oldPath, newPath string) error
+}
+
+// UserSessionRepository 用户登录会话仓储接口
+type UserSessionRepository interface {
+	Create(ctx context.Context, session *models.UserSession) error
+	GetByID(ctx context.Context, id string) (*models.UserSession, error)
+	// GetByTokenHash 根据令牌哈希获取未过期的会话
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.UserSession, error)
+	// ListActiveByUserID 获取用户所有未过期的会话
+	ListActiveByUserID(ctx context.Context, userID string) ([]*models.UserSession, error)
+	// Touch 更新最近访问时间和 IP
+	Touch(ctx context.Context, id, ip string) error
+	// UpdateToken 刷新令牌后更新访问令牌哈希与过期时间
+	UpdateToken(ctx context.Context, id, tokenHash string, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	// DeleteByUserID 删除用户的所有会话，exceptID 不为空时保留该会话
+	DeleteByUserID(ctx context.Context, userID, exceptID string) (int64, error)
+	// DeleteExpired 删除已过期的会话
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// RefreshTokenRepository 刷新令牌仓储接口
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// MarkRotated 标记令牌已轮换，仅当令牌尚未轮换时更新，返回是否更新成功（用于并发刷新）
+	MarkRotated(ctx context.Context, id int) (bool, error)
+	// DeleteBySessionID 删除会话的所有刷新令牌
+	DeleteBySessionID(ctx context.Context, sessionID string) error
+	// DeleteOrphaned 删除所属会话已不存在（已注销或过期清理）的刷新令牌
+	DeleteOrphaned(ctx context.Context) (int64, error)
+}
+
+// UserTokenRepository 邮件一次性令牌仓储接口
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.UserToken, error)
+	// Delete 删除令牌，返回是否删除成功（并发使用同一令牌时只有一个请求成功）
+	Delete(ctx context.Context, id int) (bool, error)
+	// DeleteByUserID 删除用户指定类型的所有令牌
+	DeleteByUserID(ctx context.Context, userID, kind string) error
+	// DeleteExpired 删除已过期的令牌
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// InvitationRepository 注册邀请仓储接口
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	GetByID(ctx context.Context, id int) (*models.Invitation, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	List(ctx context.Context, offset, limit int) ([]*models.Invitation, error)
+	Count(ctx context.Context) (int64, error)
+	// MarkUsed 标记邀请已使用，仅当邀请尚未使用时更新，返回是否更新成功
+	MarkUsed(ctx context.Context, id int, userID string) (bool, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// TwoFactorRepository 用户两步验证仓储接口
+type TwoFactorRepository interface {
+	GetByUserID(ctx context.Context, userID string) (*models.UserTwoFactor, error)
+	// Save 创建或更新两步验证配置
+	Save(ctx context.Context, twoFactor *models.UserTwoFactor) error
+	// UpdateLastStep 更新最近验证通过的时间步，仅当新时间步更大时更新，返回是否更新成功
+	UpdateLastStep(ctx context.Context, userID string, step int64) (bool, error)
+	// UpdateRecoveryCodes 更新恢复码，仅当原值未被并发修改时更新，返回是否更新成功
+	UpdateRecoveryCodes(ctx context.Context, userID, oldCodes, newCodes string) (bool, error)
+	Delete(ctx context.Context, userID string) error
+}
+
+// AppPasswordRepository 应用专用密码仓储接口
+type AppPasswordRepository interface {
+	Create(ctx context.Context, appPassword *models.AppPassword) error
+	GetByID(ctx context.Context, id int) (*models.AppPassword, error)
+	// GetByHash 通过密码哈希查询
+	GetByHash(ctx context.Context, passwordHash string) (*models.AppPassword, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.AppPassword, error)
+	// UpdateLastUsed 更新最近使用时间和 IP
+	UpdateLastUsed(ctx context.Context, id int, ip string) error
+	Delete(ctx context.Context, id int) error
+}
+
+// UserIdentityRepository 外部身份（OIDC）绑定仓储接口
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	// GetBySubject 通过身份提供方与主体查询
+	GetBySubject(ctx context.Context, issuer, subject string) (*models.UserIdentity, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.UserIdentity, error)
+	// UpdateLastLogin 更新最近登录时间
+	UpdateLastLogin(ctx context.Context, id int) error
+	// Delete 解除用户的外部身份绑定，返回删除的数量（只删除属于该用户的记录）
+	Delete(ctx context.Context, id int, userID string) (int64, error)
+}
+
+// EncryptPolicyRepository 写入加密策略仓储接口
+type EncryptPolicyRepository interface {
+	Create(ctx context.Context, policy *models.EncryptPolicy) error
+	// GetByUserIDAndDirID 获取用户指定目录的策略
+	GetByUserIDAndDirID(ctx context.Context, userID string, dirID int) (*models.EncryptPolicy, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.EncryptPolicy, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// SecurityEventRepository 安全事件日志仓储接口
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *models.SecurityEvent) error
+	// GetLatest 获取指定类型与对象的最近一条事件
+	GetLatest(ctx context.Context, eventType, target string) (*models.SecurityEvent, error)
+	// List 分页查询事件（最新的在前），eventType、target 为空时不过滤
+	List(ctx context.Context, eventType, target string, offset, limit int) ([]*models.SecurityEvent, error)
+	Count(ctx context.Context, eventType, target string) (int64, error)
+	// DeleteBefore 删除指定时间之前的事件
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditLogFilter 审计日志查询条件，零值字段不过滤
+type AuditLogFilter struct {
+	ActorID    string
+	ActorName  string
+	Action     string
+	TargetType string
+	TargetID   string
+	Result     string
+	Source     string
+	IP         string
+	Start      *time.Time
+	End        *time.Time
+}
+
+// AuditLogRepository 审计日志仓储接口（只追加，不提供修改方法）
+type AuditLogRepository interface {
+	Create(ctx context.Context, log *models.AuditLog) error
+	// GetLast 获取最新的一条记录
+	GetLast(ctx context.Context) (*models.AuditLog, error)
+	// List 分页查询（最新的在前）
+	List(ctx context.Context, filter AuditLogFilter, offset, limit int) ([]*models.AuditLog, error)
+	Count(ctx context.Context, filter AuditLogFilter) (int64, error)
+	// ListAfter 按ID升序查询指定ID之后的记录，用于导出与哈希链校验
+	ListAfter(ctx context.Context, filter AuditLogFilter, afterID, limit int) ([]*models.AuditLog, error)
+	// DeleteBefore 删除指定时间之前的记录（仅用于保留期清理）
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PasswordHistoryRepository 历史密码仓储接口
+type PasswordHistoryRepository interface {
+	Create(ctx context.Context, history *models.PasswordHistory) error
+	// ListRecent 获取用户最近的历史密码（最新的在前）
+	ListRecent(ctx context.Context, userID, kind string, limit int) ([]*models.PasswordHistory, error)
+	// DeleteExceptRecent 只保留最近 keep 条历史密码
+	DeleteExceptRecent(ctx context.Context, userID, kind string, keep int) error
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// SearchHit 词条命中的文档
+type SearchHit struct {
+	UfID      string
+	Term      string
+	NameTF    int
+	ContentTF int
+	// Length 文档长度
+	Length int
+}
+
+// SearchIndexRepository 全文索引仓储接口
+// 文档只统计未删除的用户文件，删除的文件在查询时即被排除，索引记录由同步任务清理
+type SearchIndexRepository interface {
+	GetContent(ctx context.Context, fileID string) (*models.SearchContent, error)
+	SaveContent(ctx context.Context, content *models.SearchContent) error
+	// DeleteContent 删除文件内容的提取结果（文件内容被修改后重新提取）
+	DeleteContent(ctx context.Context, fileID string) error
+	// DeleteOrphanContents 删除已没有文档引用的提取结果
+	DeleteOrphanContents(ctx context.Context) (int64, error)
+	GetDoc(ctx context.Context, ufID string) (*models.SearchDoc, error)
+	// ReplaceDoc 在一个事务中替换文档及其倒排记录
+	ReplaceDoc(ctx context.Context, doc *models.SearchDoc, postings []*models.SearchPosting) error
+	DeleteDocs(ctx context.Context, ufIDs []string) error
+	// ListUnindexed 未建立索引的用户文件
+	ListUnindexed(ctx context.Context, limit int) ([]*models.UserFiles, error)
+	// ListRenamed 建立索引后改过名的用户文件
+	ListRenamed(ctx context.Context, limit int) ([]*models.UserFiles, error)
+	// ListOrphanDocs 对应的用户文件已删除的文档
+	ListOrphanDocs(ctx context.Context, limit int) ([]string, error)
+	// Stats 范围内的文档数与平均文档长度
+	Stats(ctx context.Context, filter FileFilter) (int64, float64, error)
+	// DocFreq 范围内包含各词条的文档数
+	DocFreq(ctx context.Context, filter FileFilter, terms []string) (map[string]int64, error)
+	// Postings 范围内包含任一词条的倒排记录
+	Postings(ctx context.Context, filter FileFilter, terms []string, limit int) ([]*SearchHit, error)
+	// MatchNames 范围内文件名包含关键词的用户文件ID
+	MatchNames(ctx context.Context, filter FileFilter, keyword string, limit int) ([]string, error)
+	ListDocs(ctx context.Context, ufIDs []string) ([]*models.SearchDoc, error)
+}
+
+// SmartFolderRepository 智能文件夹仓储接口
+type SmartFolderRepository interface {
+	Create(ctx context.Context, folder *models.SmartFolder) error
+	GetByID(ctx context.Context, userID string, id int) (*models.SmartFolder, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.SmartFolder, error)
+	Update(ctx context.Context, folder *models.SmartFolder) error
+	Delete(ctx context.Context, userID string, id int) error
+	CountByUserID(ctx context.Context, userID string) (int64, error)
+}
+
+// TimelineGroup 时间线中一个日期分组的照片数
+type TimelineGroup struct {
+	// Date 分组日期（yyyy、yyyy-mm 或 yyyy-mm-dd）
+	Date  string
+	Count int64
+}
+
+// FileMetadataRepository 媒体元数据仓储接口
+type FileMetadataRepository interface {
+	Get(ctx context.Context, fileID string) (*models.FileMetadata, error)
+	Save(ctx context.Context, metadata *models.FileMetadata) error
+	// ListUnextracted 尚未提取元数据的图片、音频与视频文件
+	ListUnextracted(ctx context.Context, limit int) ([]*models.FileInfo, error)
+	// DeleteOrphans 删除文件信息已删除的元数据
+	DeleteOrphans(ctx context.Context) (int64, error)
+	// TimelineGroups 按时间线日期的前 length 个字符（4 年、7 月、10 日）分组统计用户的照片数，日期倒序
+	TimelineGroups(ctx context.Context, userID string, length int) ([]*TimelineGroup, error)
+	// ListTimelineFiles 分页查询用户时间线日期以 datePrefix 开头的照片，按拍摄时间倒序
+	ListTimelineFiles(ctx context.Context, userID, datePrefix string, offset, limit int) ([]*models.UserFiles, int64, error)
+}
@@ -0,0 +1,302 @@
+package search
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 提取方式
+const (
+	kindText = "text"
+	kindPDF  = "pdf"
+	kindDocx = "docx"
+	kindXlsx = "xlsx"
+	kindPptx = "pptx"
+)
+
+// textExts 按纯文本提取的扩展名（文本、Markdown、配置与源代码）
+var textExts = map[string]bool{
+	".txt": true, ".text": true, ".md": true, ".markdown": true, ".rst": true, ".adoc": true, ".tex": true,
+	".csv": true, ".tsv": true, ".log": true, ".json": true, ".xml": true, ".yaml": true, ".yml": true,
+	".toml": true, ".ini": true, ".conf": true, ".cfg": true, ".properties": true, ".env": true, ".srt": true,
+	".html": true, ".htm": true, ".css": true, ".scss": true, ".less": true, ".sql": true,
+	".go": true, ".py": true, ".java": true, ".kt": true, ".scala": true, ".groovy": true, ".gradle": true,
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true, ".cs": true, ".rs": true, ".swift": true,
+	".m": true, ".js": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true, ".jsx": true, ".vue": true,
+	".rb": true, ".php": true, ".pl": true, ".lua": true, ".r": true, ".dart": true, ".proto": true,
+	".sh": true, ".bash": true, ".zsh": true, ".bat": true, ".ps1": true, ".dockerfile": true, ".makefile": true,
+}
+
+// extractKind 按文件名与 MIME 类型判断提取方式，不支持时返回空字符串
+func extractKind(name, mime string) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case ext == ".pdf" || mime == "application/pdf":
+		return kindPDF
+	case ext == ".docx" || strings.Contains(mime, "wordprocessingml"):
+		return kindDocx
+	case ext == ".xlsx" || strings.Contains(mime, "spreadsheetml"):
+		return kindXlsx
+	case ext == ".pptx" || strings.Contains(mime, "presentationml"):
+		return kindPptx
+	case textExts[ext] || strings.HasPrefix(mime, "text/"):
+		return kindText
+	}
+	return ""
+}
+
+// limits 内容提取的文件大小与文本长度上限（字节）
+func limits() (maxFileSize, maxTextSize int64) {
+	maxFileSize, maxTextSize = 20<<20, 1024<<10
+	if config.CONFIG != nil {
+		if config.CONFIG.Search.MaxFileSize > 0 {
+			maxFileSize = int64(config.CONFIG.Search.MaxFileSize) << 20
+		}
+		if config.CONFIG.Search.MaxTextSize > 0 {
+			maxTextSize = int64(config.CONFIG.Search.MaxTextSize) << 10
+		}
+	}
+	return
+}
+
+// inflateRatio 解压后的数据总量上限相对于文件大小上限的倍数
+const inflateRatio = 4
+
+// errTooLarge 解压后的数据超过上限（压缩炸弹）
+var errTooLarge = errors.New("解压后的数据过大")
+
+// budget 单个文件提取过程中的剩余额度
+type budget struct {
+	// text 剩余可输出的文本长度，用完后停止提取
+	text int64
+	// inflate 剩余可解压的字节数，超出时提取失败
+	inflate int64
+}
+
+// full 文本额度是否已用完
+func (bg *budget) full(b *strings.Builder) bool {
+	return int64(b.Len()) >= bg.text
+}
+
+// reader 包装解压数据流，读取量计入解压额度，超出时返回 errTooLarge
+func (bg *budget) reader(r io.Reader) io.Reader {
+	return &inflateReader{r: io.LimitReader(r, bg.inflate+1), bg: bg}
+}
+
+// inflateReader 计入解压额度的数据流
+type inflateReader struct {
+	r  io.Reader
+	bg *budget
+}
+
+func (r *inflateReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.bg.inflate -= int64(n)
+	if r.bg.inflate < 0 {
+		return n, errTooLarge
+	}
+	return n, err
+}
+
+// Extract 提取文件的文本内容，返回提取状态与文本
+// 加密文件没有文件密码无法读取，只索引文件名
+func Extract(ctx context.Context, factory *impl.RepositoryFactory, fileInfo *models.FileInfo, name string) (string, string, error) {
+	if fileInfo.IsEnc {
+		return models.SearchContentEncrypted, "", nil
+	}
+	kind := extractKind(name, fileInfo.Mime)
+	if kind == "" {
+		return models.SearchContentUnsupported, "", nil
+	}
+	maxFileSize, maxTextSize := limits()
+	// 纯文本只读取前 max_text_size 的内容，其他格式需要完整读取后解析
+	readSize := int64(fileInfo.Size)
+	if kind == kindText {
+		if readSize > maxTextSize+utf8.UTFMax {
+			readSize = maxTextSize + utf8.UTFMax
+		}
+	} else if readSize > maxFileSize {
+		return models.SearchContentTooLarge, "", nil
+	}
+
+	reader, err := openData(ctx, factory, fileInfo)
+	if err != nil {
+		return models.SearchContentFailed, "", err
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, readSize))
+	reader.Close()
+	if err != nil {
+		return models.SearchContentFailed, "", err
+	}
+
+	var text string
+	bg := &budget{text: maxTextSize, inflate: maxFileSize * inflateRatio}
+	switch kind {
+	case kindText:
+		text, err = decodeText(data)
+	case kindPDF:
+		text, err = extractPDF(data, bg)
+	default:
+		text, err = extractOOXML(data, kind, bg)
+	}
+	if errors.Is(err, errTooLarge) {
+		return models.SearchContentTooLarge, "", nil
+	}
+	if err != nil {
+		return models.SearchContentFailed, "", err
+	}
+	text = strings.TrimSpace(strings.ToValidUTF8(text, ""))
+	if int64(len(text)) > maxTextSize {
+		end := int(maxTextSize)
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text = text[:end]
+	}
+	if text == "" {
+		return models.SearchContentEmpty, "", nil
+	}
+	return models.SearchContentOK, text, nil
+}
+
+// openData 打开文件的存储数据，分片文件组合为连续数据流
+func openData(ctx context.Context, factory *impl.RepositoryFactory, fileInfo *models.FileInfo) (io.ReadCloser, error) {
+	if !fileInfo.IsChunk {
+		return os.Open(fileInfo.Path)
+	}
+	chunks, err := factory.FileChunk().GetByFileID(ctx, fileInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("未找到分片文件")
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+	})
+	paths := make([]string, len(chunks))
+	sizes := make([]int64, len(chunks))
+	for i, c := range chunks {
+		paths[i], sizes[i] = c.ChunkPath, int64(c.ChunkSize)
+	}
+	return util.NewChunkedReader(paths, sizes), nil
+}
+
+// decodeText 按检测出的编码解码纯文本，二进制文件返回空文本
+func decodeText(data []byte) (string, error) {
+	charset, err := util.DetectCharset(data)
+	if err != nil {
+		return "", nil
+	}
+	return util.DecodeText(data, charset)
+}
+
+// ooxmlParts 各格式中包含文本的 XML 部件
+func ooxmlParts(kind, name string) bool {
+	switch kind {
+	case kindDocx:
+		return name == "word/document.xml" || name == "word/footnotes.xml" || name == "word/endnotes.xml" ||
+			strings.HasPrefix(name, "word/header") || strings.HasPrefix(name, "word/footer")
+	case kindXlsx:
+		return name == "xl/sharedStrings.xml" || strings.HasPrefix(name, "xl/worksheets/sheet")
+	case kindPptx:
+		return strings.HasPrefix(name, "ppt/slides/slide") || strings.HasPrefix(name, "ppt/notesSlides/notesSlide")
+	}
+	return false
+}
+
+// extractOOXML 提取 docx、xlsx、pptx 的文本
+// 三种格式都是 zip 包中的 XML，文本位于 <w:t>、<a:t>、<t> 等元素中；段落、共享字符串与行结束时换行
+func extractOOXML(data []byte, kind string, bg *budget) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("解析压缩包失败: %w", err)
+	}
+	files := make([]*zip.File, 0)
+	for _, file := range archive.File {
+		if strings.HasSuffix(file.Name, ".xml") && ooxmlParts(kind, file.Name) {
+			files = append(files, file)
+		}
+	}
+	// 按部件名中的序号排序，使幻灯片与工作表按顺序输出
+	sort.Slice(files, func(i, j int) bool {
+		if len(files[i].Name) != len(files[j].Name) {
+			return len(files[i].Name) < len(files[j].Name)
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	var b strings.Builder
+	for _, file := range files {
+		if bg.full(&b) {
+			break
+		}
+		if err := extractXMLText(file, &b, bg); err != nil {
+			return "", fmt.Errorf("解析 %s 失败: %w", file.Name, err)
+		}
+	}
+	return b.String(), nil
+}
+
+// extractXMLText 读取 XML 部件中文本元素的内容，文本额度用完时停止
+func extractXMLText(file *zip.File, b *strings.Builder, bg *budget) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	decoder := xml.NewDecoder(bg.reader(reader))
+	inText := false
+	for !bg.full(b) {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p", "si", "row":
+				b.WriteByte('\n')
+			case "c":
+				b.WriteByte('\t')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	// 解码器可能在读取出错前先返回已读取的文本，额度用完退出循环时需要再检查解压量
+	if bg.inflate < 0 {
+		return errTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// syncBatchSize 补建与清理索引时每批处理的文件数
+const syncBatchSize = 100
+
+// writeMu 串行化索引的写入，避免队列与定时任务同时重建同一文档
+var writeMu sync.Mutex
+
+// Enabled 是否启用全文索引
+func Enabled() bool {
+	return config.CONFIG != nil && config.CONFIG.Search.Enable
+}
+
+// Index 全文索引
+// 文档以用户文件为单位，文件内容按文件信息提取一次后缓存，秒传与复制出的文件共享提取结果；
+// 文件所在目录、公开状态与是否已删除在查询时关联 user_files 判断，移动文件不需要重建索引
+type Index struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewIndex 创建全文索引
+func NewIndex(factory *impl.RepositoryFactory) *Index {
+	return &Index{factory: factory}
+}
+
+// IndexFile 为用户文件建立（或重建）索引
+func (x *Index) IndexFile(ctx context.Context, userFile *models.UserFiles) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	fileInfo, err := x.factory.FileInfo().GetByID(ctx, userFile.FileID)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	content, err := x.content(ctx, fileInfo, userFile.FileName)
+	if err != nil {
+		return err
+	}
+
+	nameFreq, nameLength := TermFreq(userFile.FileName)
+	contentFreq, contentLength := TermFreq(content.Content)
+	postings := make([]*models.SearchPosting, 0, len(nameFreq)+len(contentFreq))
+	for term, tf := range contentFreq {
+		postings = append(postings, &models.SearchPosting{Term: term, UfID: userFile.UfID, NameTF: nameFreq[term], ContentTF: tf})
+	}
+	for term, tf := range nameFreq {
+		if _, ok := contentFreq[term]; !ok {
+			postings = append(postings, &models.SearchPosting{Term: term, UfID: userFile.UfID, NameTF: tf})
+		}
+	}
+	category := fileInfo.Category
+	if category == "" {
+		category = util.FileCategory(userFile.FileName, fileInfo.Mime)
+	}
+	doc := &models.SearchDoc{
+		UfID:      userFile.UfID,
+		UserID:    userFile.UserID,
+		FileID:    userFile.FileID,
+		Name:      userFile.FileName,
+		Category:  category,
+		Size:      int64(fileInfo.Size),
+		Length:    nameLength + contentLength,
+		IndexedAt: custom_type.Now(),
+	}
+	if err := x.factory.SearchIndex().ReplaceDoc(ctx, doc, postings); err != nil {
+		return fmt.Errorf("保存索引失败: %w", err)
+	}
+	return nil
+}
+
+// content 获取文件内容的提取结果，没有时提取并保存
+func (x *Index) content(ctx context.Context, fileInfo *models.FileInfo, name string) (*models.SearchContent, error) {
+	content, err := x.factory.SearchIndex().GetContent(ctx, fileInfo.ID)
+	if err == nil {
+		return content, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("获取文件内容失败: %w", err)
+	}
+	status, text, err := Extract(ctx, x.factory, fileInfo, name)
+	if err != nil {
+		// 提取失败时只索引文件名，记录状态避免反复提取
+		logger.LOG.Warn("提取文件内容失败", "fileID", fileInfo.ID, "name", name, "error", err)
+	}
+	content = &models.SearchContent{FileID: fileInfo.ID, Status: status, Content: text, UpdatedAt: custom_type.Now()}
+	if err := x.factory.SearchIndex().SaveContent(ctx, content); err != nil {
+		return nil, fmt.Errorf("保存文件内容失败: %w", err)
+	}
+	return content, nil
+}
+
+// Remove 删除用户文件的索引
+func (x *Index) Remove(ctx context.Context, ufIDs ...string) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	return x.factory.SearchIndex().DeleteDocs(ctx, ufIDs)
+}
+
+// Sync 补建缺失与过期的索引，删除已删除文件的索引
+// 用于首次启用、增量队列溢出或服务重启时丢失的更新
+func (x *Index) Sync(ctx context.Context) (indexed, removed int, err error) {
+	repo := x.factory.SearchIndex()
+	for _, list := range []func(context.Context, int) ([]*models.UserFiles, error){repo.ListUnindexed, repo.ListRenamed} {
+		for {
+			userFiles, err := list(ctx, syncBatchSize)
+			if err != nil {
+				return indexed, removed, err
+			}
+			done := 0
+			for _, userFile := range userFiles {
+				if err := x.IndexFile(ctx, userFile); err != nil {
+					logger.LOG.Warn("建立索引失败", "ufID", userFile.UfID, "error", err)
+					continue
+				}
+				done++
+			}
+			indexed += done
+			// 整批都失败时停止，等待下次同步
+			if done == 0 {
+				break
+			}
+		}
+	}
+
+	for {
+		ufIDs, err := repo.ListOrphanDocs(ctx, syncBatchSize)
+		if err != nil {
+			return indexed, removed, err
+		}
+		if len(ufIDs) == 0 {
+			break
+		}
+		if err := x.Remove(ctx, ufIDs...); err != nil {
+			return indexed, removed, err
+		}
+		removed += len(ufIDs)
+	}
+	if _, err := repo.DeleteOrphanContents(ctx); err != nil {
+		return indexed, removed, err
+	}
+	return indexed, removed, nil
+}
+
+// job 增量索引任务
+type job struct {
+	userID string
+	ufIDs  []string
+}
+
+var (
+	startOnce sync.Once
+	queue     chan job
+)
+
+// Start 启动增量索引队列
+func Start(factory *impl.RepositoryFactory) {
+	startOnce.Do(func() {
+		queue = make(chan job, 1024)
+		index := NewIndex(factory)
+		go func() {
+			for j := range queue {
+				index.apply(context.Background(), j)
+			}
+		}()
+	})
+}
+
+// Notify 通知用户文件已新增、改名或删除，由队列异步更新索引
+// 队列未启动（未启用全文索引）时忽略；队列已满时丢弃，由定时同步补建
+func Notify(userID string, ufIDs ...string) {
+	if queue == nil || len(ufIDs) == 0 {
+		return
+	}
+	select {
+	case queue <- job{userID: userID, ufIDs: ufIDs}:
+	default:
+		logger.LOG.Warn("全文索引队列已满，等待定时同步", "userID", userID, "count", len(ufIDs))
+	}
+}
+
+// apply 按用户文件的当前状态更新索引：存在时重建，已删除时移除
+func (x *Index) apply(ctx context.Context, j job) {
+	for _, ufID := range j.ufIDs {
+		userFile, err := x.factory.UserFiles().GetByUserIDAndUfID(ctx, j.userID, ufID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = x.Remove(ctx, ufID)
+		} else if err == nil {
+			err = x.IndexFile(ctx, userFile)
+		}
+		if err != nil {
+			logger.LOG.Warn("更新全文索引失败", "ufID", ufID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,744 @@
+package search
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF 文本提取
+// 只解析提取文本所需的部分：间接对象（含对象流中的对象）、页面树、页面内容流中的文本操作符与字体的 ToUnicode 映射。
+// 只支持未压缩与 FlateDecode 压缩的流；加密的 PDF 与没有 ToUnicode 的复合字体无法得到文本，按没有文本处理
+
+var (
+	pdfObjPattern  = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfRootPattern = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+)
+
+// pdfDoc 已解析的 PDF 文档
+type pdfDoc struct {
+	objects map[int][]byte
+	fonts   map[string]*pdfFont
+	bg      *budget
+	// 解压流时遇到的错误（超过解压额度），出现后停止提取
+	err error
+}
+
+// pdfFont 字体的文本解码方式
+type pdfFont struct {
+	cmap map[string]string
+	// 是否为复合字体（没有 ToUnicode 时无法解码）
+	composite bool
+}
+
+// extractPDF 提取 PDF 的文本
+func extractPDF(data []byte, bg *budget) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \r\n\t"), []byte("%PDF")) {
+		return "", nil
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", nil
+	}
+	doc := &pdfDoc{objects: make(map[int][]byte), fonts: make(map[string]*pdfFont), bg: bg}
+	doc.parseObjects(data)
+
+	var b strings.Builder
+	for _, page := range doc.pages(data) {
+		if doc.err != nil || bg.full(&b) {
+			break
+		}
+		fonts := doc.pageFonts(page)
+		for _, content := range doc.pageContents(page) {
+			extractContentText(content, fonts, &b, bg)
+			b.WriteByte('\n')
+		}
+	}
+	if doc.err != nil {
+		return "", doc.err
+	}
+	return b.String(), nil
+}
+
+// parseObjects 读取所有间接对象，后出现的对象（增量更新）覆盖先出现的
+func (d *pdfDoc) parseObjects(data []byte) {
+	matches := pdfObjPattern.FindAllSubmatchIndex(data, -1)
+	for i, m := range matches {
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		body := data[m[1]:end]
+		if idx := bytes.Index(body, []byte("endobj")); idx >= 0 {
+			body = body[:idx]
+		}
+		d.objects[num] = bytes.TrimSpace(body)
+	}
+	// 对象流（PDF 1.5）中保存的对象
+	var objStreams [][]byte
+	for _, body := range d.objects {
+		if string(dictValue(pdfDict(body), "Type")) == "/ObjStm" {
+			objStreams = append(objStreams, body)
+		}
+	}
+	for _, body := range objStreams {
+		dict := pdfDict(body)
+		stream, ok := d.stream(body)
+		if !ok {
+			continue
+		}
+		count, _ := strconv.Atoi(string(dictValue(dict, "N")))
+		first, _ := strconv.Atoi(string(dictValue(dict, "First")))
+		if first <= 0 || first > len(stream) {
+			continue
+		}
+		header := strings.Fields(string(stream[:first]))
+		for i := 0; i+1 < len(header) && i/2 < count; i += 2 {
+			num, err1 := strconv.Atoi(header[i])
+			offset, err2 := strconv.Atoi(header[i+1])
+			if err1 != nil || err2 != nil || first+offset > len(stream) {
+				continue
+			}
+			end := len(stream)
+			if i+3 < len(header) {
+				if next, err := strconv.Atoi(header[i+3]); err == nil && first+next <= len(stream) && next >= offset {
+					end = first + next
+				}
+			}
+			if _, exists := d.objects[num]; !exists {
+				d.objects[num] = bytes.TrimSpace(stream[first+offset : end])
+			}
+		}
+	}
+}
+
+// resolve 解析间接引用，返回对象的内容
+func (d *pdfDoc) resolve(value []byte) []byte {
+	for i := 0; i < 8; i++ {
+		num, ok := pdfRef(value)
+		if !ok {
+			return value
+		}
+		value = d.objects[num]
+	}
+	return value
+}
+
+// pages 按页面树顺序返回页面字典，页面树损坏时按对象编号顺序查找页面
+func (d *pdfDoc) pages(data []byte) [][]byte {
+	var pages [][]byte
+	visited := make(map[int]bool)
+	var walk func(value []byte, depth int)
+	walk = func(value []byte, depth int) {
+		num, ok := pdfRef(value)
+		if !ok || visited[num] || depth > 64 {
+			return
+		}
+		visited[num] = true
+		dict := pdfDict(d.objects[num])
+		switch string(dictValue(dict, "Type")) {
+		case "/Pages":
+			for _, kid := range pdfArray(d.resolve(dictValue(dict, "Kids"))) {
+				walk(kid, depth+1)
+			}
+		case "/Page":
+			pages = append(pages, dict)
+		}
+	}
+	if roots := pdfRootPattern.FindAllSubmatch(data, -1); len(roots) > 0 {
+		root := roots[len(roots)-1]
+		catalog := pdfDict(d.objects[atoi(root[1])])
+		walk(dictValue(catalog, "Pages"), 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		dict := pdfDict(d.objects[num])
+		if string(dictValue(dict, "Type")) == "/Page" {
+			pages = append(pages, dict)
+		}
+	}
+	return pages
+}
+
+// pageContents 页面的内容流
+func (d *pdfDoc) pageContents(page []byte) [][]byte {
+	var contents [][]byte
+	value := dictValue(page, "Contents")
+	refs := [][]byte{value}
+	if _, ok := pdfRef(value); !ok {
+		refs = pdfArray(d.resolve(value))
+	}
+	for _, ref := range refs {
+		if stream, ok := d.stream(d.resolve(ref)); ok {
+			contents = append(contents, stream)
+		}
+	}
+	return contents
+}
+
+// pageFonts 页面资源中的字体，页面没有资源时继承上级页面树节点的资源
+func (d *pdfDoc) pageFonts(page []byte) map[string]*pdfFont {
+	resources := dictValue(page, "Resources")
+	node := page
+	for i := 0; len(resources) == 0 && i < 32; i++ {
+		node = pdfDict(d.resolve(dictValue(node, "Parent")))
+		if len(node) == 0 {
+			break
+		}
+		resources = dictValue(node, "Resources")
+	}
+	fonts := make(map[string]*pdfFont)
+	entries := dictEntries(d.resolve(dictValue(pdfDict(d.resolve(resources)), "Font")))
+	for name, value := range entries {
+		fonts[name] = d.font(value)
+	}
+	return fonts
+}
+
+// font 解析字体的 ToUnicode 映射
+func (d *pdfDoc) font(value []byte) *pdfFont {
+	key := string(value)
+	if font, ok := d.fonts[key]; ok {
+		return font
+	}
+	dict := pdfDict(d.resolve(value))
+	font := &pdfFont{composite: string(dictValue(dict, "Subtype")) == "/Type0"}
+	if stream, ok := d.stream(d.resolve(dictValue(dict, "ToUnicode"))); ok {
+		font.cmap = parseCMap(stream)
+	}
+	d.fonts[key] = font
+	return font
+}
+
+// decode 将字符串中的字符编码转为文本
+func (f *pdfFont) decode(s []byte) string {
+	if f != nil && len(f.cmap) > 0 {
+		var b strings.Builder
+		for i := 0; i < len(s); {
+			matched := false
+			for n := 4; n >= 1; n-- {
+				if i+n > len(s) {
+					continue
+				}
+				if text, ok := f.cmap[string(s[i:i+n])]; ok {
+					b.WriteString(text)
+					i += n
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				i++
+			}
+		}
+		return b.String()
+	}
+	if f != nil && f.composite {
+		return ""
+	}
+	if bytes.HasPrefix(s, []byte{0xFE, 0xFF}) {
+		return decodeUTF16BE(s[2:])
+	}
+	// 简单字体按 Latin-1 近似处理
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// parseCMap 解析 ToUnicode CMap 中的 bfchar 与 bfrange 映射
+func parseCMap(data []byte) map[string]string {
+	cmap := make(map[string]string)
+	lex := &pdfLexer{data: data}
+	var operands []pdfToken
+	for {
+		token, ok := lex.next()
+		if !ok {
+			break
+		}
+		if token.kind != 'o' {
+			operands = append(operands, token)
+			if token.kind == '[' {
+				// bfrange 的目标数组
+				for {
+					item, ok := lex.next()
+					if !ok || item.kind == ']' {
+						break
+					}
+					operands = append(operands, item)
+				}
+				operands = append(operands, pdfToken{kind: ']'})
+			}
+			continue
+		}
+		switch string(token.data) {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if operands[i].kind == 's' && operands[i+1].kind == 's' {
+					cmap[string(operands[i].data)] = decodeUTF16BE(operands[i+1].data)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); {
+				lo, hi, dst := operands[i], operands[i+1], operands[i+2]
+				if lo.kind != 's' || hi.kind != 's' || len(lo.data) != len(hi.data) {
+					break
+				}
+				i += 3
+				var targets [][]byte
+				if dst.kind == '[' {
+					for i < len(operands) && operands[i].kind != ']' {
+						targets = append(targets, operands[i].data)
+						i++
+					}
+					i++
+				}
+				start, end := codeValue(lo.data), codeValue(hi.data)
+				for code, n := start, 0; code <= end && n < 65536; code, n = code+1, n+1 {
+					key := codeBytes(code, len(lo.data))
+					switch {
+					case dst.kind == '[':
+						if n < len(targets) {
+							cmap[key] = decodeUTF16BE(targets[n])
+						}
+					case dst.kind == 's' && len(dst.data) >= 2:
+						target := append([]byte(nil), dst.data...)
+						last := int(target[len(target)-2])<<8 | int(target[len(target)-1])
+						last += n
+						target[len(target)-2], target[len(target)-1] = byte(last>>8), byte(last)
+						cmap[key] = decodeUTF16BE(target)
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return cmap
+}
+
+// extractContentText 解析内容流中的文本操作符，文本额度用完时停止
+func extractContentText(data []byte, fonts map[string]*pdfFont, b *strings.Builder, bg *budget) {
+	lex := &pdfLexer{data: data}
+	var operands []pdfToken
+	var font *pdfFont
+	for !bg.full(b) {
+		token, ok := lex.next()
+		if !ok {
+			return
+		}
+		if token.kind == '[' {
+			// TJ 的数组：字符串与字距调整，较大的字距视为空格
+			var b2 strings.Builder
+			for {
+				item, ok := lex.next()
+				if !ok || item.kind == ']' {
+					break
+				}
+				switch item.kind {
+				case 's':
+					b2.WriteString(font.decode(item.data))
+				case 'n':
+					if v, err := strconv.ParseFloat(string(item.data), 64); err == nil && v < -200 {
+						b2.WriteByte(' ')
+					}
+				}
+			}
+			operands = append(operands, pdfToken{kind: 'a', data: []byte(b2.String())})
+			continue
+		}
+		if token.kind != 'o' {
+			operands = append(operands, token)
+			continue
+		}
+		switch string(token.data) {
+		case "Tf":
+			if len(operands) >= 2 && operands[len(operands)-2].kind == '/' {
+				font = fonts[string(operands[len(operands)-2].data)]
+			}
+		case "Tj", "'", "\"":
+			if string(token.data) != "Tj" {
+				b.WriteByte('\n')
+			}
+			if len(operands) > 0 && operands[len(operands)-1].kind == 's' {
+				b.WriteString(font.decode(operands[len(operands)-1].data))
+			}
+		case "TJ":
+			if len(operands) > 0 && operands[len(operands)-1].kind == 'a' {
+				b.Write(operands[len(operands)-1].data)
+			}
+		case "Td", "TD":
+			// 同一行内的移动多为字距调整（空格通常以字符编码输出），只在换行时分隔
+			if ty, err := strconv.ParseFloat(string(lastData(operands)), 64); err == nil && ty != 0 {
+				b.WriteByte('\n')
+			}
+		case "T*", "ET", "Tm":
+			b.WriteByte('\n')
+		case "ID":
+			lex.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// pdfToken 词法单元：s 字符串、n 数字、/ 名称、o 操作符、[ ] 数组、< > 字典，a 为 TJ 数组解码后的文本
+type pdfToken struct {
+	kind byte
+	data []byte
+}
+
+// lastData 最后一个操作数的内容
+func lastData(operands []pdfToken) []byte {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[len(operands)-1].data
+}
+
+// pdfLexer PDF 词法分析器
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return isPDFSpace(c) || strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: 's', data: l.literal()}, true
+		case c == '<':
+			if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+				l.pos += 2
+				return pdfToken{kind: '<'}, true
+			}
+			return pdfToken{kind: 's', data: l.hex()}, true
+		case c == '>':
+			l.pos++
+			if l.pos < len(l.data) && l.data[l.pos] == '>' {
+				l.pos++
+			}
+			return pdfToken{kind: '>'}, true
+		case c == '[' || c == ']':
+			l.pos++
+			return pdfToken{kind: c}, true
+		case c == '{' || c == '}' || c == ')':
+			l.pos++
+		default:
+			start := l.pos
+			if c == '/' {
+				l.pos++
+			}
+			for l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			word := l.data[start:l.pos]
+			switch {
+			case c == '/':
+				return pdfToken{kind: '/', data: word}, true
+			case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+				return pdfToken{kind: 'n', data: word}, true
+			}
+			return pdfToken{kind: 'o', data: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// literal 读取 (...) 字符串，处理转义与嵌套括号
+func (l *pdfLexer) literal() []byte {
+	var out []byte
+	depth := 0
+	l.pos++
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return out
+			}
+			depth--
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hex 读取 <...> 十六进制字符串
+func (l *pdfLexer) hex() []byte {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; isHexDigit(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[i*2:i*2+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// skipInlineImage 跳过内嵌图像的二进制数据（ID 与 EI 之间）
+func (l *pdfLexer) skipInlineImage() {
+	for l.pos+2 < len(l.data) {
+		if l.data[l.pos] == 'E' && l.data[l.pos+1] == 'I' && isPDFSpace(l.data[l.pos-1]) &&
+			(l.pos+2 == len(l.data) || isPDFDelimiter(l.data[l.pos+2])) {
+			l.pos += 2
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
+
+// skipValue 跳过以 first 开头的值（字典、数组与间接引用需要读取多个词法单元）
+func (l *pdfLexer) skipValue(first pdfToken) {
+	switch first.kind {
+	case '<', '[':
+		depth := 1
+		for depth > 0 {
+			token, ok := l.next()
+			if !ok {
+				return
+			}
+			switch token.kind {
+			case '<', '[':
+				depth++
+			case '>', ']':
+				depth--
+			}
+		}
+	case 'n':
+		// 间接引用 "N G R"
+		save := l.pos
+		second, ok1 := l.next()
+		third, ok2 := l.next()
+		if !ok1 || !ok2 || second.kind != 'n' || third.kind != 'o' || string(third.data) != "R" {
+			l.pos = save
+		}
+	}
+}
+
+// dictEntries 解析字典的顶层键值，值保留原始文本
+func dictEntries(dict []byte) map[string][]byte {
+	entries := make(map[string][]byte)
+	lex := &pdfLexer{data: dict}
+	if token, ok := lex.next(); !ok || token.kind != '<' {
+		return entries
+	}
+	for {
+		key, ok := lex.next()
+		if !ok || key.kind == '>' {
+			return entries
+		}
+		if key.kind != '/' {
+			continue
+		}
+		start := lex.pos
+		value, ok := lex.next()
+		if !ok {
+			return entries
+		}
+		if value.kind == '>' {
+			return entries
+		}
+		lex.skipValue(value)
+		entries[string(key.data)] = bytes.TrimSpace(dict[start:lex.pos])
+	}
+}
+
+// dictValue 获取字典中的值
+func dictValue(dict []byte, key string) []byte {
+	return dictEntries(dict)["/"+key]
+}
+
+// pdfDict 对象内容中的字典部分
+func pdfDict(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	if !bytes.HasPrefix(body, []byte("<<")) {
+		return nil
+	}
+	lex := &pdfLexer{data: body}
+	first, _ := lex.next()
+	lex.skipValue(first)
+	return body[:lex.pos]
+}
+
+// pdfArray 数组中的元素
+func pdfArray(value []byte) [][]byte {
+	var items [][]byte
+	lex := &pdfLexer{data: value}
+	if token, ok := lex.next(); !ok || token.kind != '[' {
+		return nil
+	}
+	for {
+		start := lex.pos
+		token, ok := lex.next()
+		if !ok || token.kind == ']' {
+			return items
+		}
+		lex.skipValue(token)
+		items = append(items, bytes.TrimSpace(value[start:lex.pos]))
+	}
+}
+
+// pdfRef 解析间接引用 "N G R"
+func pdfRef(value []byte) (int, bool) {
+	fields := strings.Fields(string(value))
+	if len(fields) != 3 || fields[2] != "R" {
+		return 0, false
+	}
+	num, err := strconv.Atoi(fields[0])
+	return num, err == nil
+}
+
+// stream 读取并解码对象中的流，只支持 FlateDecode；解压的数据计入解压额度，超出时记录错误
+func (d *pdfDoc) stream(body []byte) ([]byte, bool) {
+	if d.err != nil {
+		return nil, false
+	}
+	dict := pdfDict(body)
+	if dict == nil {
+		return nil, false
+	}
+	rest := bytes.TrimLeft(body[len(dict):], " \r\n\t")
+	if !bytes.HasPrefix(rest, []byte("stream")) {
+		return nil, false
+	}
+	rest = rest[len("stream"):]
+	rest = bytes.TrimPrefix(rest, []byte("\r"))
+	rest = bytes.TrimPrefix(rest, []byte("\n"))
+	if end := bytes.LastIndex(rest, []byte("endstream")); end >= 0 {
+		rest = rest[:end]
+	}
+	filter := string(dictValue(dict, "Filter"))
+	switch strings.Trim(filter, "[] \r\n") {
+	case "":
+		return rest, true
+	case "/FlateDecode", "/Fl":
+		reader, err := zlib.NewReader(bytes.NewReader(rest))
+		if err != nil {
+			return nil, false
+		}
+		// 数据截断时保留已解压的部分
+		out, err := io.ReadAll(d.bg.reader(reader))
+		if errors.Is(err, errTooLarge) {
+			d.err = err
+			return nil, false
+		}
+		return out, len(out) > 0
+	}
+	return nil, false
+}
+
+func decodeUTF16BE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[i*2])<<8 | uint16(data[i*2+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+func codeValue(code []byte) int {
+	v := 0
+	for _, c := range code {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+func codeBytes(v, n int) string {
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return string(out)
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func atoi(b []byte) int {
+	v, _ := strconv.Atoi(string(b))
+	return v
+}
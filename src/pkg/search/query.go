@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"math"
+	"myobj/src/pkg/repository"
+	"sort"
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// nameWeight 文件名中的词条按内容中的若干次计算
+	nameWeight = 3
+	// nameMatchBonus 文件名包含完整关键词时的加分
+	nameMatchBonus = 2.0
+	// maxCandidates 参与评分的候选文档上限
+	maxCandidates = 10000
+	// snippetWidth 内容摘要的长度（字节）
+	snippetWidth = 160
+)
+
+// Query 检索条件
+type Query struct {
+	Keyword string
+	Filter  repository.FileFilter
+	Offset  int
+	Limit   int
+}
+
+// Hit 检索命中的用户文件
+type Hit struct {
+	UfID  string
+	Score float64
+	// Highlight 内容中命中位置附近的摘要（HTML，命中词条用 <mark> 标记）
+	Highlight string
+}
+
+// Result 检索结果
+type Result struct {
+	Total int64
+	Hits  []*Hit
+}
+
+// Search 按 BM25 对文件名与内容检索，所有查询词条都命中的文档才返回；文件名包含完整关键词的文档总是返回并加分
+// 文档总数、平均长度与文档频率按用户（或公开文件）范围统计，不受类型、大小等过滤条件影响
+func (x *Index) Search(ctx context.Context, q Query) (*Result, error) {
+	result := &Result{Hits: make([]*Hit, 0)}
+	terms := QueryTerms(q.Keyword)
+	if len(terms) == 0 {
+		return result, nil
+	}
+	repo := x.factory.SearchIndex()
+	scope := repository.FileFilter{UserID: q.Filter.UserID}
+	if scope.UserID == "" {
+		scope.Public = q.Filter.Public
+	}
+	count, avgLength, err := repo.Stats(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return result, nil
+	}
+	if avgLength <= 0 {
+		avgLength = 1
+	}
+	docFreq, err := repo.DocFreq(ctx, scope, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	postings, err := repo.Postings(ctx, q.Filter, terms, maxCandidates*len(terms))
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for _, posting := range postings {
+		df := float64(docFreq[posting.Term])
+		idf := math.Log(1 + (float64(count)-df+0.5)/(df+0.5))
+		tf := float64(posting.ContentTF + nameWeight*posting.NameTF)
+		norm := bm25K1 * (1 - bm25B + bm25B*float64(posting.Length)/avgLength)
+		scores[posting.UfID] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		matched[posting.UfID]++
+	}
+	hits := make(map[string]*Hit)
+	for ufID, score := range scores {
+		if matched[ufID] == len(terms) {
+			hits[ufID] = &Hit{UfID: ufID, Score: score}
+		}
+	}
+	names, err := repo.MatchNames(ctx, q.Filter, q.Keyword, maxCandidates)
+	if err != nil {
+		return nil, err
+	}
+	for _, ufID := range names {
+		if hit, ok := hits[ufID]; ok {
+			hit.Score += nameMatchBonus
+		} else {
+			hits[ufID] = &Hit{UfID: ufID, Score: scores[ufID] + nameMatchBonus}
+		}
+	}
+
+	ranked := make([]*Hit, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, hit)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].UfID < ranked[j].UfID
+	})
+	result.Total = int64(len(ranked))
+	if q.Offset >= len(ranked) {
+		return result, nil
+	}
+	end := len(ranked)
+	if q.Limit > 0 && q.Offset+q.Limit < end {
+		end = q.Offset + q.Limit
+	}
+	result.Hits = ranked[q.Offset:end]
+	x.highlight(ctx, q.Keyword, result.Hits)
+	return result, nil
+}
+
+// highlight 为当前页的结果生成内容摘要
+func (x *Index) highlight(ctx context.Context, keyword string, hits []*Hit) {
+	ufIDs := make([]string, len(hits))
+	for i, hit := range hits {
+		ufIDs[i] = hit.UfID
+	}
+	docs, err := x.factory.SearchIndex().ListDocs(ctx, ufIDs)
+	if err != nil {
+		return
+	}
+	fileIDs := make(map[string]string, len(docs))
+	for _, doc := range docs {
+		fileIDs[doc.UfID] = doc.FileID
+	}
+	for _, hit := range hits {
+		content, err := x.factory.SearchIndex().GetContent(ctx, fileIDs[hit.UfID])
+		if err != nil || content.Content == "" {
+			continue
+		}
+		hit.Highlight = Highlight(content.Content, keyword, snippetWidth)
+	}
+}
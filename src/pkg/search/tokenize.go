@@ -0,0 +1,223 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTermLength 词条最大字节数，超过的词条（如 base64、哈希值）不建立索引
+const maxTermLength = 64
+
+// Token 词条及其在原文中的字节位置
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// textRun 连续的同类字符
+type textRun struct {
+	start, end int
+	cjk        bool
+}
+
+// isCJK 是否为中日韩文字（汉字、平假名、片假名、谚文）
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// splitRuns 将文本切分为字母数字串与中日韩文字串，其余字符作为分隔符
+func splitRuns(text string) []textRun {
+	var runs []textRun
+	cur := textRun{start: -1}
+	flush := func(end int) {
+		if cur.start >= 0 {
+			cur.end = end
+			runs = append(runs, cur)
+		}
+		cur = textRun{start: -1}
+	}
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			if cur.start >= 0 && !cur.cjk {
+				flush(i)
+			}
+			if cur.start < 0 {
+				cur = textRun{start: i, cjk: true}
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if cur.start >= 0 && cur.cjk {
+				flush(i)
+			}
+			if cur.start < 0 {
+				cur = textRun{start: i}
+			}
+		default:
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return runs
+}
+
+// Tokenize 分词
+// 字母数字串转为小写后作为一个词条；中日韩文字没有空格分词，每个字与相邻两字都作为词条（单字 + 二元组），
+// 查询时用二元组匹配，既能检索任意长度的词语，也不依赖词典
+func Tokenize(text string) []Token {
+	var tokens []Token
+	for _, run := range splitRuns(text) {
+		if !run.cjk {
+			term := strings.ToLower(text[run.start:run.end])
+			if len(term) <= maxTermLength {
+				tokens = append(tokens, Token{Term: term, Start: run.start, End: run.end})
+			}
+			continue
+		}
+		prev := -1
+		for i := run.start; i < run.end; {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			tokens = append(tokens, Token{Term: text[i : i+size], Start: i, End: i + size})
+			if prev >= 0 {
+				tokens = append(tokens, Token{Term: text[prev : i+size], Start: prev, End: i + size})
+			}
+			prev = i
+			i += size
+		}
+	}
+	return tokens
+}
+
+// TermFreq 统计文本中各词条的出现次数
+func TermFreq(text string) (map[string]int, int) {
+	freq := make(map[string]int)
+	tokens := Tokenize(text)
+	for _, token := range tokens {
+		freq[token.Term]++
+	}
+	return freq, len(tokens)
+}
+
+// QueryTerms 将搜索关键词转为查询词条（已去重）
+// 中日韩文字串按相邻两字切分，单个字时使用单字
+func QueryTerms(keyword string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if term != "" && len(term) <= maxTermLength && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, run := range splitRuns(keyword) {
+		segment := keyword[run.start:run.end]
+		if !run.cjk {
+			add(strings.ToLower(segment))
+			continue
+		}
+		if utf8.RuneCountInString(segment) == 1 {
+			add(segment)
+			continue
+		}
+		prev := -1
+		for i := range segment {
+			if prev >= 0 {
+				_, size := utf8.DecodeRuneInString(segment[i:])
+				add(segment[prev : i+size])
+			}
+			prev = i
+		}
+	}
+	return terms
+}
+
+// Highlight 用 <mark> 标记文本中命中的词条，返回 HTML 转义后的片段
+// width 大于 0 时截取首个命中位置附近约 width 字节的片段；没有命中时返回空字符串
+func Highlight(text, keyword string, width int) string {
+	terms := make(map[string]bool)
+	for _, term := range QueryTerms(keyword) {
+		terms[term] = true
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	var spans [][2]int
+	for _, token := range Tokenize(text) {
+		if terms[token.Term] {
+			spans = append(spans, [2]int{token.Start, token.End})
+		}
+	}
+	if len(spans) == 0 {
+		return ""
+	}
+	// 合并重叠的命中区间（中文二元组相互重叠）
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span[0] <= last[1] {
+			if span[1] > last[1] {
+				last[1] = span[1]
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+
+	start, end := 0, len(text)
+	if width > 0 && len(text) > width {
+		start = merged[0][0] - width/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + width
+		if end > len(text) {
+			end = len(text)
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, span := range merged {
+		if span[1] <= start || span[0] >= end {
+			continue
+		}
+		s, e := span[0], span[1]
+		if s < start {
+			s = start
+		}
+		if e > end {
+			e = end
+		}
+		b.WriteString(escapeSnippet(text[pos:s]))
+		b.WriteString("<mark>")
+		b.WriteString(escapeSnippet(text[s:e]))
+		b.WriteString("</mark>")
+		pos = e
+	}
+	b.WriteString(escapeSnippet(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// snippetSpace 片段中的换行与制表符替换为空格
+var snippetSpace = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+func escapeSnippet(s string) string {
+	return html.EscapeString(snippetSpace.Replace(s))
+}
@@ -0,0 +1,279 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/webdav"
+	"os"
+	"sync"
+
+	"github.com/pkg/sftp"
+	xwebdav "golang.org/x/net/webdav"
+)
+
+// errEncryptedDir 写入开启了写入加密的目录（SFTP 无法提供文件密码）
+var errEncryptedDir = errors.New("目标目录已开启写入加密，SFTP 无法提供文件密码，请使用网页端或 WebDAV 上传")
+
+// handler 将 MyObj 虚拟文件系统适配为 SFTP 请求处理器
+// 目录树、上传入库（ProcessUploadedFile）、删除入回收站等逻辑与 WebDAV 完全复用
+type handler struct {
+	fs     *webdav.MyObjFileSystem
+	userID string
+	locks  *lock.Manager
+}
+
+// newHandlers 创建 SFTP 请求处理器
+func newHandlers(fs *webdav.MyObjFileSystem, userID string, locks *lock.Manager) sftp.Handlers {
+	h := &handler{fs: fs, userID: userID, locks: locks}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// Fileread 读取文件
+func (h *handler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	f, err := h.fs.OpenFile(r.Context(), r.Filepath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, sftp.ErrSSHFxFailure
+	}
+	return &readerAt{file: f}, nil
+}
+
+// Filewrite 写入文件：内容先写入临时文件，关闭时通过上传流程入库
+func (h *handler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	ctx := r.Context()
+	if r.Pflags().Append {
+		// 已入库的文件经过加密/分片处理，不支持追加写入
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+
+	if info, err := h.fs.Stat(ctx, r.Filepath); err == nil {
+		if info.IsDir() {
+			return nil, sftp.ErrSSHFxFailure
+		}
+		if r.Pflags().Excl {
+			return nil, os.ErrExist
+		}
+	}
+	if err := h.checkUnlocked(ctx, r.Filepath); err != nil {
+		return nil, err
+	}
+	// 写入加密目录需要文件密码，提前拒绝，避免上传完成后才以通用错误失败
+	isEnc, err := h.fs.RequiresEncryption(ctx, r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	if isEnc {
+		return nil, fmt.Errorf("%w: %w", errEncryptedDir, sftp.ErrSSHFxPermissionDenied)
+	}
+
+	// 覆盖写入时新内容上传成功后，旧文件才会移入回收站
+	f, err := h.fs.OpenFile(ctx, r.Filepath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &writerAt{file: f}, nil
+}
+
+// Filecmd 处理重命名、建目录、删除等命令
+func (h *handler) Filecmd(r *sftp.Request) error {
+	ctx := r.Context()
+	switch r.Method {
+	case "Setstat":
+		// 虚拟文件系统不维护权限与时间属性，直接忽略
+		return nil
+	case "Rename":
+		if _, err := h.fs.Stat(ctx, r.Target); err == nil {
+			return os.ErrExist
+		}
+		if err := h.checkUnlocked(ctx, r.Filepath, r.Target); err != nil {
+			return err
+		}
+		return h.fs.Rename(ctx, r.Filepath, r.Target)
+	case "Mkdir":
+		if err := h.checkUnlocked(ctx, r.Filepath); err != nil {
+			return err
+		}
+		return h.fs.Mkdir(ctx, r.Filepath, 0755)
+	case "Rmdir":
+		if err := h.checkUnlocked(ctx, r.Filepath); err != nil {
+			return err
+		}
+		return h.removeDir(ctx, r.Filepath)
+	case "Remove":
+		info, err := h.fs.Stat(ctx, r.Filepath)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return sftp.ErrSSHFxFailure
+		}
+		if err := h.checkUnlocked(ctx, r.Filepath); err != nil {
+			return err
+		}
+		return h.fs.RemoveAll(ctx, r.Filepath)
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename POSIX 重命名：目标文件存在时覆盖，旧文件在移动成功的同一事务中移入回收站
+func (h *handler) PosixRename(r *sftp.Request) error {
+	ctx := r.Context()
+	if err := h.checkUnlocked(ctx, r.Filepath, r.Target); err != nil {
+		return err
+	}
+	if info, err := h.fs.Stat(ctx, r.Target); err == nil && info.IsDir() {
+		return os.ErrExist
+	}
+	return h.fs.RenameOverwrite(ctx, r.Filepath, r.Target)
+}
+
+// Filelist 列出目录、获取文件信息
+func (h *handler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	ctx := r.Context()
+	switch r.Method {
+	case "List":
+		f, err := h.fs.OpenFile(ctx, r.Filepath, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		infos, err := f.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt(infos), nil
+	case "Stat":
+		info, err := h.fs.Stat(ctx, r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// checkUnlocked 检查路径及其子树未被锁定
+// SFTP 无法携带锁令牌，被 WebDAV 或网页端锁定的资源一律拒绝修改，返回权限不足
+func (h *handler) checkUnlocked(ctx context.Context, names ...string) error {
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = h.fs.LockPath(name)
+	}
+	if err := h.locks.CheckUnlocked(ctx, h.userID, paths...); err != nil {
+		if errors.Is(err, lock.ErrLocked) {
+			logger.LOG.Info("SFTP 操作被锁拒绝", "user_id", h.userID, "paths", paths)
+			return fmt.Errorf("%w: %w", err, sftp.ErrSSHFxPermissionDenied)
+		}
+		return err
+	}
+	return nil
+}
+
+// removeDir 删除空目录（与 rmdir 语义一致，非空目录拒绝删除）
+func (h *handler) removeDir(ctx context.Context, name string) error {
+	f, err := h.fs.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return sftp.ErrSSHFxFailure
+	}
+	children, err := f.Readdir(-1)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("目录不为空")
+	}
+	return h.fs.RemoveAll(ctx, name)
+}
+
+// listerAt 目录列表
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readerAt 基于 Seek + Read 的随机读
+type readerAt struct {
+	mu   sync.Mutex
+	file xwebdav.File
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.file, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *readerAt) Close() error {
+	return r.file.Close()
+}
+
+// writerAt 基于 Seek + Write 的随机写
+type writerAt struct {
+	mu      sync.Mutex
+	file    xwebdav.File
+	aborted bool
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return w.file.Write(p)
+}
+
+// TransferError 传输中断时标记上传失败，避免不完整的文件入库
+func (w *writerAt) TransferError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	logger.LOG.Warn("SFTP 上传传输中断", "error", err)
+	w.aborted = true
+}
+
+func (w *writerAt) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.aborted {
+		if a, ok := w.file.(interface{ Abort() error }); ok {
+			return a.Abort()
+		}
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,272 @@
+package sftp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/webdav"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxAcceptDelay 接受连接临时失败时的最长重试间隔
+const maxAcceptDelay = time.Second
+
+// Server SFTP 服务器
+type Server struct {
+	auth    *webdav.Authenticator
+	factory *impl.RepositoryFactory
+
+	mu sync.Mutex
+	// listener 正在监听的连接，Stop 时关闭
+	listener net.Listener
+}
+
+// NewServer 创建 SFTP 服务器实例
+// cacheStore 用于保存登录失败次数（与 HTTP 服务共用，同一账户在各协议中统一计数）
+func NewServer(factory *impl.RepositoryFactory, cacheStore cache.Cache) *Server {
+	authenticator := webdav.NewAuthenticator(
+		factory.ApiKey(),
+		factory.AppPassword(),
+		factory.User(),
+		factory.Power(),
+		factory.SysConfig(),
+		factory.TwoFactor(),
+		factory.Group(),
+		factory.VirtualPath(),
+		factory.UserSession(),
+		auth.NewLoginLimiter(cacheStore, factory.SecurityEvent(), config.CONFIG.Auth.LoginLimit),
+	)
+
+	return &Server{
+		auth:    authenticator,
+		factory: factory,
+	}
+}
+
+// Start 启动 SFTP 服务器
+func (s *Server) Start() error {
+	addr := fmt.Sprintf("%s:%d",
+		config.CONFIG.SFTP.Host,
+		config.CONFIG.SFTP.Port,
+	)
+
+	logger.LOG.Info("========== SFTP 服务器启动 ==========")
+	logger.LOG.Info("SFTP 服务器配置", "address", addr, "host_key", config.CONFIG.SFTP.HostKey)
+
+	hostKey, err := loadHostKey(config.CONFIG.SFTP.HostKey)
+	if err != nil {
+		logger.LOG.Error("SFTP 加载主机密钥失败", "error", err)
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.LOG.Error("SFTP 服务器启动失败", "error", err)
+		return err
+	}
+	logger.LOG.Info("SFTP 服务器正在监听...", "address", addr)
+	return s.Serve(listener, hostKey)
+}
+
+// Serve 在 listener 上接受 SSH 连接，直到调用 Stop 关闭监听
+// 接受连接临时失败（如文件描述符耗尽）时逐步延长间隔后重试，其他错误直接返回
+func (s *Server) Serve(listener net.Listener, hostKey ssh.Signer) error {
+	sshConfig := &ssh.ServerConfig{
+		PasswordCallback: s.passwordCallback,
+	}
+	sshConfig.AddHostKey(hostKey)
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	var delay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				logger.LOG.Info("SFTP 服务器已停止")
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				delay = min(max(delay*2, 5*time.Millisecond), maxAcceptDelay)
+				logger.LOG.Warn("SFTP 接受连接失败，稍后重试", "error", err, "retry_in", delay)
+				time.Sleep(delay)
+				continue
+			}
+			logger.LOG.Error("SFTP 接受连接失败", "error", err)
+			return err
+		}
+		delay = 0
+		go s.handleConn(conn, sshConfig)
+	}
+}
+
+// Stop 关闭监听，不再接受新连接（已建立的会话继续处理至客户端断开）
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// passwordCallback SSH 密码认证：支持应用专用密码、登录密码或 API Key，并校验 sftp:access 权限
+func (s *Server) passwordCallback(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	username := conn.User()
+	user, appPassword, err := s.auth.AuthenticatePassword(username, string(password), webdav.RemoteIP(conn.RemoteAddr().String()))
+	if err != nil {
+		logger.LOG.Warn("SFTP 认证失败",
+			"username", username,
+			"ip", conn.RemoteAddr().String(),
+			"error", err,
+		)
+		return nil, fmt.Errorf("认证失败")
+	}
+
+	hasPermission, err := s.auth.CheckPermission(user.ID, user.GroupID, "sftp:access")
+	if err != nil || !hasPermission {
+		logger.LOG.Warn("SFTP 权限不足",
+			"user_id", user.ID,
+			"username", username,
+			"group_id", user.GroupID,
+		)
+		return nil, fmt.Errorf("无权限访问 SFTP")
+	}
+
+	extensions := map[string]string{"user_id": user.ID}
+	if appPassword != nil {
+		// 使用应用专用密码登录时，会话的访问范围与只读限制由该密码决定
+		extensions["app_password_id"] = strconv.Itoa(appPassword.ID)
+	}
+	return &ssh.Permissions{
+		Extensions: extensions,
+	}, nil
+}
+
+// handleConn 处理单个 SSH 连接
+func (s *Server) handleConn(conn net.Conn, sshConfig *ssh.ServerConfig) {
+	defer conn.Close()
+
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, sshConfig)
+	if err != nil {
+		logger.LOG.Debug("SFTP 握手失败", "ip", conn.RemoteAddr().String(), "error", err)
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	ctx := context.Background()
+	user, err := s.factory.User().GetByID(ctx, sshConn.Permissions.Extensions["user_id"])
+	if err != nil {
+		logger.LOG.Error("SFTP 获取用户失败", "error", err)
+		return
+	}
+	var appPassword *models.AppPassword
+	if id, ok := sshConn.Permissions.Extensions["app_password_id"]; ok {
+		appPasswordID, _ := strconv.Atoi(id)
+		if appPassword, err = s.factory.AppPassword().GetByID(ctx, appPasswordID); err != nil {
+			logger.LOG.Error("SFTP 获取应用专用密码失败", "error", err)
+			return
+		}
+	}
+	fs, err := webdav.NewScopedFileSystem(ctx, user, s.factory, appPassword)
+	if err != nil {
+		logger.LOG.Warn("SFTP 创建文件系统失败", "user_id", user.ID, "error", err)
+		return
+	}
+	logger.LOG.Info("SFTP 用户已连接", "user_id", user.ID, "username", user.UserName, "ip", sshConn.RemoteAddr().String())
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			logger.LOG.Error("SFTP 接受通道失败", "error", err)
+			continue
+		}
+
+		go s.handleRequests(channel, requests, user, fs)
+	}
+}
+
+// handleRequests 处理会话通道上的请求：只接受一次 sftp 子系统请求，接受后才启动 SFTP 服务
+// 其他请求（shell、exec 等）一律拒绝；通道关闭前未请求 sftp 子系统时关闭通道
+func (s *Server) handleRequests(channel ssh.Channel, in <-chan *ssh.Request, user *models.UserInfo, fs *webdav.MyObjFileSystem) {
+	started := false
+	for req := range in {
+		ok := !started && req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+		req.Reply(ok, nil)
+		if ok {
+			started = true
+			go s.serveChannel(channel, user, fs)
+		}
+	}
+	if !started {
+		channel.Close()
+	}
+}
+
+// serveChannel 在会话通道上运行 SFTP 请求服务
+func (s *Server) serveChannel(channel ssh.Channel, user *models.UserInfo, fs *webdav.MyObjFileSystem) {
+	server := sftp.NewRequestServer(channel, newHandlers(fs, user.ID, lock.NewManager(s.factory)))
+	if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+		logger.LOG.Warn("SFTP 会话异常结束", "user_id", user.ID, "error", err)
+	}
+	server.Close()
+	logger.LOG.Info("SFTP 会话结束", "user_id", user.ID, "username", user.UserName)
+}
+
+// loadHostKey 加载主机私钥，文件不存在时自动生成 ed25519 密钥
+func loadHostKey(keyPath string) (ssh.Signer, error) {
+	if keyPath == "" {
+		keyPath = "./libs/sftp_host_key"
+	}
+
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		return ssh.ParsePrivateKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	logger.LOG.Info("SFTP 主机密钥不存在，正在生成", "path", keyPath)
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "myobj sftp host key")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(privateKey)
+}
@@ -3,9 +3,17 @@ package task
 import (
 	"context"
 	"fmt"
+	"myobj/src/config"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/convert"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/preview"
+	"myobj/src/pkg/recycle"
+	"myobj/src/pkg/search"
+	"myobj/src/pkg/util"
 	"os"
 	"path/filepath"
 	"strings"
@@ -52,6 +60,24 @@ func (t *RecycledTask) CleanupExpiredFiles(days int) error {
 	failCount := 0
 
 	for _, record := range expiredRecords {
+		// 目录条目按整棵目录树清理（保留期以目录删除时间为准）
+		if record.IsDir() {
+			failed, err := recycle.NewBin(t.factory).Purge(ctx, record, func(member *models.Recycled) error {
+				return t.processExpiredRecord(ctx, member)
+			})
+			if err != nil || failed > 0 {
+				logger.LOG.Error("清理过期目录失败",
+					"record_id", record.ID,
+					"path", record.OriginalPath,
+					"user_id", record.UserID,
+					"failed_files", failed,
+					"error", err)
+				failCount++
+			} else {
+				successCount++
+			}
+			continue
+		}
 		if err := t.processExpiredRecord(ctx, record); err != nil {
 			logger.LOG.Error("处理过期记录失败",
 				"record_id", record.ID,
@@ -377,4 +403,452 @@ func (t *UploadTask) StartScheduledCleanup(interval time.Duration) {
 			}
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// UserSessionTask 用户会话定时任务
+type UserSessionTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewUserSessionTask 创建用户会话定时任务
+func NewUserSessionTask(factory *impl.RepositoryFactory) *UserSessionTask {
+	return &UserSessionTask{
+		factory: factory,
+	}
+}
+
+// CleanupExpiredSessions 清理已过期的会话
+func (t *UserSessionTask) CleanupExpiredSessions() error {
+	count, err := t.factory.UserSession().DeleteExpired(context.Background())
+	if err != nil {
+		logger.LOG.Error("清理过期会话失败", "error", err)
+		return fmt.Errorf("清理过期会话失败: %w", err)
+	}
+	if count > 0 {
+		logger.LOG.Info("过期会话清理完成", "count", count)
+	}
+	// 会话删除后遗留的刷新令牌
+	tokens, err := t.factory.RefreshToken().DeleteOrphaned(context.Background())
+	if err != nil {
+		logger.LOG.Error("清理刷新令牌失败", "error", err)
+		return fmt.Errorf("清理刷新令牌失败: %w", err)
+	}
+	if tokens > 0 {
+		logger.LOG.Info("刷新令牌清理完成", "count", tokens)
+	}
+	// 过期的邮件链接令牌（邮箱验证、重置密码）
+	mailTokens, err := t.factory.UserToken().DeleteExpired(context.Background())
+	if err != nil {
+		logger.LOG.Error("清理邮件令牌失败", "error", err)
+		return fmt.Errorf("清理邮件令牌失败: %w", err)
+	}
+	if mailTokens > 0 {
+		logger.LOG.Info("邮件令牌清理完成", "count", mailTokens)
+	}
+	return nil
+}
+
+// StartScheduledCleanup 启动定时清理任务
+// interval: 执行间隔
+func (t *UserSessionTask) StartScheduledCleanup(interval time.Duration) {
+	logger.LOG.Info("启动会话定时清理任务", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := t.CleanupExpiredSessions(); err != nil {
+				logger.LOG.Error("定时清理任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// SecurityEventTask 安全事件日志定时任务
+type SecurityEventTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewSecurityEventTask 创建安全事件日志定时任务
+func NewSecurityEventTask(factory *impl.RepositoryFactory) *SecurityEventTask {
+	return &SecurityEventTask{
+		factory: factory,
+	}
+}
+
+// CleanupExpiredEvents 删除超过保留天数的安全事件
+func (t *SecurityEventTask) CleanupExpiredEvents(retentionDays int) error {
+	before := time.Now().AddDate(0, 0, -retentionDays)
+	count, err := t.factory.SecurityEvent().DeleteBefore(context.Background(), before)
+	if err != nil {
+		logger.LOG.Error("清理安全事件日志失败", "error", err)
+		return fmt.Errorf("清理安全事件日志失败: %w", err)
+	}
+	if count > 0 {
+		logger.LOG.Info("安全事件日志清理完成", "count", count)
+	}
+	return nil
+}
+
+// StartScheduledCleanup 启动定时清理任务
+// retentionDays: 保留天数
+// interval: 执行间隔
+func (t *SecurityEventTask) StartScheduledCleanup(retentionDays int, interval time.Duration) {
+	logger.LOG.Info("启动安全事件日志定时清理任务", "retention_days", retentionDays, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := t.CleanupExpiredEvents(retentionDays); err != nil {
+				logger.LOG.Error("定时清理任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// AuditLogTask 审计日志定时任务
+type AuditLogTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewAuditLogTask 创建审计日志定时任务
+func NewAuditLogTask(factory *impl.RepositoryFactory) *AuditLogTask {
+	return &AuditLogTask{
+		factory: factory,
+	}
+}
+
+// CleanupExpiredLogs 删除超过保留天数的审计日志
+// 启用哈希链时，清理后剩余的第一条记录的上一条哈希无法再校验，校验从该记录开始
+func (t *AuditLogTask) CleanupExpiredLogs(retentionDays int) error {
+	before := time.Now().AddDate(0, 0, -retentionDays)
+	count, err := t.factory.AuditLog().DeleteBefore(context.Background(), before)
+	if err != nil {
+		logger.LOG.Error("清理审计日志失败", "error", err)
+		return fmt.Errorf("清理审计日志失败: %w", err)
+	}
+	if count > 0 {
+		logger.LOG.Info("审计日志清理完成", "count", count)
+	}
+	return nil
+}
+
+// StartScheduledCleanup 启动定时清理任务
+// retentionDays: 保留天数
+// interval: 执行间隔
+func (t *AuditLogTask) StartScheduledCleanup(retentionDays int, interval time.Duration) {
+	logger.LOG.Info("启动审计日志定时清理任务", "retention_days", retentionDays, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := t.CleanupExpiredLogs(retentionDays); err != nil {
+				logger.LOG.Error("定时清理任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// WebDAVLockTask WebDAV 锁定时任务
+type WebDAVLockTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewWebDAVLockTask 创建 WebDAV 锁定时任务
+func NewWebDAVLockTask(factory *impl.RepositoryFactory) *WebDAVLockTask {
+	return &WebDAVLockTask{
+		factory: factory,
+	}
+}
+
+// CleanupExpiredLocks 清理已过期的 WebDAV 锁
+func (t *WebDAVLockTask) CleanupExpiredLocks() error {
+	count, err := t.factory.WebDAVLock().DeleteExpired(context.Background())
+	if err != nil {
+		logger.LOG.Error("清理过期 WebDAV 锁失败", "error", err)
+		return fmt.Errorf("清理过期 WebDAV 锁失败: %w", err)
+	}
+	if count > 0 {
+		logger.LOG.Info("过期 WebDAV 锁清理完成", "count", count)
+	}
+	return nil
+}
+
+// StartScheduledCleanup 启动定时清理任务
+// interval: 执行间隔
+func (t *WebDAVLockTask) StartScheduledCleanup(interval time.Duration) {
+	logger.LOG.Info("启动 WebDAV 锁定时清理任务", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := t.CleanupExpiredLocks(); err != nil {
+				logger.LOG.Error("定时清理任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// LDAPSyncTask 目录账户同步定时任务
+type LDAPSyncTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewLDAPSyncTask 创建目录账户同步定时任务
+func NewLDAPSyncTask(factory *impl.RepositoryFactory) *LDAPSyncTask {
+	return &LDAPSyncTask{
+		factory: factory,
+	}
+}
+
+// SyncUsers 同步所有目录账户（资料、用户组与禁用状态）
+func (t *LDAPSyncTask) SyncUsers() error {
+	manager := auth.NewLDAPManager(config.CONFIG.LDAP, t.factory.User(), t.factory.Group(), t.factory.VirtualPath(), t.factory.UserSession())
+	count, err := manager.SyncAll(context.Background())
+	if err != nil {
+		logger.LOG.Error("同步目录账户失败", "synced", count, "error", err)
+		return fmt.Errorf("同步目录账户失败: %w", err)
+	}
+	logger.LOG.Info("目录账户同步完成", "count", count)
+	return nil
+}
+
+// StartScheduledSync 启动定时同步任务
+// interval: 执行间隔
+func (t *LDAPSyncTask) StartScheduledSync(interval time.Duration) {
+	logger.LOG.Info("启动目录账户定时同步任务", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := t.SyncUsers(); err != nil {
+				logger.LOG.Error("定时同步任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// SearchIndexTask 全文索引定时任务
+type SearchIndexTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewSearchIndexTask 创建全文索引定时任务
+func NewSearchIndexTask(factory *impl.RepositoryFactory) *SearchIndexTask {
+	return &SearchIndexTask{
+		factory: factory,
+	}
+}
+
+// SyncIndex 补建缺失的索引并清理已删除文件的索引
+// 增量索引队列溢出或服务重启时丢失的更新由该任务补齐
+func (t *SearchIndexTask) SyncIndex() error {
+	indexed, removed, err := search.NewIndex(t.factory).Sync(context.Background())
+	if err != nil {
+		logger.LOG.Error("同步全文索引失败", "error", err)
+		return fmt.Errorf("同步全文索引失败: %w", err)
+	}
+	if indexed > 0 || removed > 0 {
+		logger.LOG.Info("全文索引同步完成", "indexed", indexed, "removed", removed)
+	}
+	return nil
+}
+
+// StartScheduledSync 启动定时同步任务，启动时先执行一次
+// interval: 执行间隔
+func (t *SearchIndexTask) StartScheduledSync(interval time.Duration) {
+	logger.LOG.Info("启动全文索引定时同步任务", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		if err := t.SyncIndex(); err != nil {
+			logger.LOG.Error("定时同步任务执行失败", "error", err)
+		}
+		for range ticker.C {
+			if err := t.SyncIndex(); err != nil {
+				logger.LOG.Error("定时同步任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// FileCategoryTask 文件分类补齐任务
+type FileCategoryTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewFileCategoryTask 创建文件分类补齐任务
+func NewFileCategoryTask(factory *impl.RepositoryFactory) *FileCategoryTask {
+	return &FileCategoryTask{
+		factory: factory,
+	}
+}
+
+// BackfillCategories 为升级前上传、尚未划分分类的文件补齐分类
+// 文件的 MIME 类型在上传时已按内容识别，此处结合文件名划分分类
+func (t *FileCategoryTask) BackfillCategories() error {
+	ctx := context.Background()
+	count := 0
+	for {
+		files, err := t.factory.FileInfo().ListUncategorized(ctx, 500)
+		if err != nil {
+			return fmt.Errorf("查询未分类文件失败: %w", err)
+		}
+		if len(files) == 0 {
+			break
+		}
+		for _, file := range files {
+			if err := t.factory.FileInfo().UpdateCategory(ctx, file.ID, util.FileCategory(file.Name, file.Mime)); err != nil {
+				return fmt.Errorf("更新文件分类失败: %w", err)
+			}
+		}
+		count += len(files)
+	}
+	if count > 0 {
+		logger.LOG.Info("文件分类补齐完成", "count", count)
+	}
+	return nil
+}
+
+// StartBackfill 在后台执行一次分类补齐（新上传的文件在上传时写入分类）
+func (t *FileCategoryTask) StartBackfill() {
+	go func() {
+		if err := t.BackfillCategories(); err != nil {
+			logger.LOG.Error("文件分类补齐失败", "error", err)
+		}
+	}()
+}
+
+// ConvertCacheTask 格式转换缓存定时任务
+type ConvertCacheTask struct {
+	cache *convert.Cache
+}
+
+// NewConvertCacheTask 创建格式转换缓存定时任务
+func NewConvertCacheTask(cache *convert.Cache) *ConvertCacheTask {
+	return &ConvertCacheTask{
+		cache: cache,
+	}
+}
+
+// CleanupUnusedResults 删除超过保留天数未使用的转换结果
+func (t *ConvertCacheTask) CleanupUnusedResults(cacheDays int) error {
+	count, err := t.cache.Cleanup(time.Duration(cacheDays) * 24 * time.Hour)
+	if err != nil {
+		logger.LOG.Error("清理格式转换缓存失败", "error", err, "dir", t.cache.Dir())
+		return fmt.Errorf("清理格式转换缓存失败: %w", err)
+	}
+	if count > 0 {
+		logger.LOG.Info("格式转换缓存清理完成", "count", count)
+	}
+	return nil
+}
+
+// StartScheduledCleanup 启动定时清理任务
+// cacheDays: 保留天数（按最后一次使用时间）
+// interval: 执行间隔
+func (t *ConvertCacheTask) StartScheduledCleanup(cacheDays int, interval time.Duration) {
+	logger.LOG.Info("启动格式转换缓存定时清理任务", "cache_days", cacheDays, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := t.CleanupUnusedResults(cacheDays); err != nil {
+				logger.LOG.Error("定时清理任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// MetadataTask 媒体元数据定时任务
+type MetadataTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewMetadataTask 创建媒体元数据定时任务
+func NewMetadataTask(factory *impl.RepositoryFactory) *MetadataTask {
+	return &MetadataTask{
+		factory: factory,
+	}
+}
+
+// SyncMetadata 为升级前上传或队列中丢失的图片、音频与视频补充提取元数据，并清理已删除文件的元数据
+func (t *MetadataTask) SyncMetadata() error {
+	extracted, removed, err := metadata.Sync(context.Background(), t.factory)
+	if err != nil {
+		logger.LOG.Error("同步媒体元数据失败", "error", err)
+		return fmt.Errorf("同步媒体元数据失败: %w", err)
+	}
+	if extracted > 0 || removed > 0 {
+		logger.LOG.Info("媒体元数据同步完成", "extracted", extracted, "removed", removed)
+	}
+	return nil
+}
+
+// StartScheduledSync 启动定时同步任务，启动时先执行一次
+// interval: 执行间隔
+func (t *MetadataTask) StartScheduledSync(interval time.Duration) {
+	logger.LOG.Info("启动媒体元数据定时同步任务", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		if err := t.SyncMetadata(); err != nil {
+			logger.LOG.Error("定时同步任务执行失败", "error", err)
+		}
+		for range ticker.C {
+			if err := t.SyncMetadata(); err != nil {
+				logger.LOG.Error("定时同步任务执行失败", "error", err)
+			}
+		}
+	}()
+}
+
+// ThumbnailTask 缩略图补齐任务
+type ThumbnailTask struct {
+	factory *impl.RepositoryFactory
+}
+
+// NewThumbnailTask 创建缩略图补齐任务
+func NewThumbnailTask(factory *impl.RepositoryFactory) *ThumbnailTask {
+	return &ThumbnailTask{
+		factory: factory,
+	}
+}
+
+// BackfillThumbnails 为缺少缩略图的图片生成缩略图，返回生成的数量
+// regenerate 为 true 时重新生成所有图片的缩略图（如升级后按 EXIF 方向校正旧缩略图）
+// 加密和分块存储的文件没有可直接读取的原图，不处理
+func (t *ThumbnailTask) BackfillThumbnails(regenerate bool) (int, error) {
+	ctx := context.Background()
+	count, failed := 0, 0
+	afterID := ""
+	for {
+		files, err := t.factory.FileInfo().ListImagesAfter(ctx, afterID, 200)
+		if err != nil {
+			return count, fmt.Errorf("查询图片文件失败: %w", err)
+		}
+		if len(files) == 0 {
+			break
+		}
+		afterID = files[len(files)-1].ID
+		for _, file := range files {
+			if !regenerate && file.ThumbnailImg != "" {
+				if _, err := os.Stat(file.ThumbnailImg); err == nil {
+					continue
+				}
+			}
+			// 与上传时相同，缩略图存放在原图所在目录: {RandomName}.jpg
+			thumbnailPath := filepath.Join(filepath.Dir(file.Path), file.RandomName+".jpg")
+			if err := preview.GenerateImageThumbnail(file.Path, thumbnailPath, 300); err != nil {
+				logger.LOG.Warn("生成缩略图失败", "fileID", file.ID, "mime", file.Mime, "error", err)
+				failed++
+				continue
+			}
+			if err := t.factory.FileInfo().UpdateThumbnail(ctx, file.ID, thumbnailPath); err != nil {
+				return count, fmt.Errorf("更新缩略图路径失败: %w", err)
+			}
+			count++
+		}
+	}
+	logger.LOG.Info("缩略图补齐完成", "count", count, "failed", failed)
+	return count, nil
+}
@@ -9,8 +9,10 @@ import (
 	"myobj/src/pkg/custom_type"
 	"myobj/src/pkg/hash"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/preview"
+	"myobj/src/pkg/search"
 	"myobj/src/pkg/util"
 	"os"
 	"path/filepath"
@@ -87,10 +89,108 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 		mergedFilePath = mergedPath
 	}
 
+	// 2-9. 存储文件数据
+	fileInfo, chunks, err := storeFile(ctx, data, mergedFilePath, repoFactory)
+	if err != nil {
+		return "", err
+	}
+	fileID = fileInfo.ID
+
+	// 将虚拟路径转换为路径ID
+	// 如果 VirtualPath 已经是路径ID（纯数字字符串），直接使用
+	// 如果是路径字符串（如 "/home/"），则调用 getVirtualPathID 获取或创建
+	var virtualPathID string
+	if data.VirtualPath == "" {
+		// 空路径，使用根目录
+		rootPath, err := repoFactory.VirtualPath().GetRootPath(ctx, data.UserID)
+		if err != nil {
+			return "", fmt.Errorf("获取根目录失败: %w", err)
+		}
+		virtualPathID = fmt.Sprintf("%d", rootPath.ID)
+	} else if matched, _ := regexp.MatchString(`^\d+$`, data.VirtualPath); matched {
+		// 纯数字字符串，说明已经是路径ID，直接使用
+		virtualPathID = data.VirtualPath
+	} else {
+		// 路径字符串，需要获取或创建路径
+		var err error
+		virtualPathID, err = getVirtualPathID(ctx, data.UserID, data.VirtualPath, repoFactory)
+		if err != nil {
+			return "", fmt.Errorf("获取虚拟路径ID失败: %w", err)
+		}
+	}
+
+	userFile := &models.UserFiles{
+		UserID:      data.UserID,
+		FileID:      fileID,
+		IsPublic:    false,         // 默认私有
+		VirtualPath: virtualPathID, // 存储路径ID而不是路径字符串
+		FileName:    data.FileName,
+		CreatedAt:   custom_type.Now(),
+		UfID:        uuid.NewString(),
+	}
+
+	// 开启数据库事务，确保所有数据库操作的原子性
+	err = repoFactory.DB().Transaction(func(tx *gorm.DB) error {
+		// 创建基于事务的仓储工厂
+		txFactory := repoFactory.WithTx(tx)
+
+		// 10.1 写入文件信息
+		if err := txFactory.FileInfo().Create(ctx, fileInfo); err != nil {
+			return fmt.Errorf("写入文件信息失败: %w", err)
+		}
+
+		// 10.2 写入分片信息（如果是分片存储）
+		if len(chunks) > 0 {
+			if err := txFactory.FileChunk().BatchCreate(ctx, chunks); err != nil {
+				return fmt.Errorf("写入分片信息失败: %w", err)
+			}
+		}
+
+		// 10.3 写入用户文件关联
+		if err := txFactory.UserFiles().Create(ctx, userFile); err != nil {
+			return fmt.Errorf("写入用户文件关联失败: %w", err)
+		}
+
+		// 10.4 更新用户剩余空间
+		user, err := txFactory.User().GetByID(ctx, data.UserID)
+		if err != nil {
+			return fmt.Errorf("查询用户信息失败: %w", err)
+		}
+		if user.Space > 0 { // 如果不是无限空间
+			user.FreeSpace -= int64(fileInfo.Size) // 使用实际文件大小
+			if err := txFactory.User().Update(ctx, user); err != nil {
+				return fmt.Errorf("更新用户剩余空间失败: %w", err)
+			}
+		}
+
+		return nil // 事务成功，自动提交
+	})
+
+	if err != nil {
+		// 事务回滚，需要清理已创建的文件
+		cleanupProcessedFiles(fileInfo.Path, fileInfo.ThumbnailImg, chunks)
+		return "", err
+	}
+
+	// 10.5 写入.info文件（保存hash信息）
+	if err := writeInfoFile(fileInfo.Path, fileInfo.FileHash, fileInfo.FileEncHash); err != nil {
+		logger.LOG.Warn("写入.info文件失败", "error", err)
+		// .info文件写入失败不影响主流程
+	}
+
+	search.Notify(data.UserID, userFile.UfID)
+	metadata.Notify(fileID)
+	logger.LOG.Info("文件处理完成", "fileID", fileID, "fileName", data.FileName, "size", fileInfo.Size)
+	return fileID, nil
+}
+
+// storeFile 存储文件数据：检测MIME类型、计算hash、生成缩略图、按需加密与分片存储
+// 返回的文件信息与分片记录尚未写入数据库，写入失败时由调用方清理已存储的文件
+func storeFile(ctx context.Context, data *FileUploadData, mergedFilePath string, repoFactory *impl.RepositoryFactory) (*models.FileInfo, []*models.FileChunk, error) {
 	// 2. 检测文件MIME类型
 	mimeType, err := detectMimeType(mergedFilePath)
 	if err != nil {
-		return "", fmt.Errorf("检测文件类型失败: %w", err)
+		return nil, nil, fmt.Errorf("检测文件类型失败: %w", err)
 	}
 
 	// 3. 并行计算全量hash和生成缩略图（如果需要）
@@ -135,7 +235,7 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 	var fullHash, tempThumbnailPath string
 	for result := range resultChan {
 		if result.err != nil {
-			return "", fmt.Errorf("异步处理失败: %w", result.err)
+			return nil, nil, fmt.Errorf("异步处理失败: %w", result.err)
 		}
 		if result.fullHash != "" {
 			fullHash = result.fullHash
@@ -148,18 +248,18 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 	// 4. 选择存储磁盘（按剩余空间最大原则）
 	disk, err := selectBestDisk(ctx, repoFactory, data.FileSize)
 	if err != nil {
-		return "", fmt.Errorf("选择存储磁盘失败: %w", err)
+		return nil, nil, fmt.Errorf("选择存储磁盘失败: %w", err)
 	}
 
 	// 5. 生成文件ID和存储路径
-	fileID = uuid.Must(uuid.NewV7()).String()
+	fileID := uuid.Must(uuid.NewV7()).String()
 	virtualFileName := util.GenerateUniqueFilename()
 	fileNameWithoutExt := strings.TrimSuffix(data.FileName, filepath.Ext(data.FileName))
 
 	// 存储目录: {DataPath}/data/{原文件名不带后缀}/
 	storageDir := filepath.Join(disk.DataPath, "data", fileNameWithoutExt)
 	if err := os.MkdirAll(storageDir, 0755); err != nil {
-		return "", fmt.Errorf("创建存储目录失败: %w", err)
+		return nil, nil, fmt.Errorf("创建存储目录失败: %w", err)
 	}
 
 	// 6. 判断是否需要分片存储（超大文件）
@@ -172,7 +272,7 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 	if data.IsEnc {
 		// 验证用户是否提供了加密密码
 		if data.FilePassword == "" {
-			return "", fmt.Errorf("加密文件必须提供密码")
+			return nil, nil, fmt.Errorf("加密文件必须提供密码")
 		}
 
 		// 使用PBKDF2从明文密码和用户ID派生加密密钥
@@ -184,14 +284,14 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 		encryptedPath := mergedFilePath + ".enc"
 		crypto := util.NewFileCrypto(encryptionKey)
 		if err := crypto.EncryptFile(mergedFilePath, encryptedPath); err != nil {
-			return "", fmt.Errorf("文件加密失败: %w", err)
+			return nil, nil, fmt.Errorf("文件加密失败: %w", err)
 		}
 
 		// 计算加密文件的hash
 		encHasher := hash.NewFastBlake3Hasher()
 		fileEncHash, _, err = encHasher.ComputeFileHash(encryptedPath)
 		if err != nil {
-			return "", fmt.Errorf("计算加密文件hash失败: %w", err)
+			return nil, nil, fmt.Errorf("计算加密文件hash失败: %w", err)
 		}
 		logger.LOG.Debug("加密文件hash计算完成", "fileEncHash", fileEncHash)
 
@@ -210,7 +310,7 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 		// 超大文件分片存储
 		chunks, mainFilePath, err = splitAndStoreFile(finalFilePath, storageDir, virtualFileName, fileID, config.CONFIG.File.BigChunkSize)
 		if err != nil {
-			return "", fmt.Errorf("分片存储失败: %w", err)
+			return nil, nil, fmt.Errorf("分片存储失败: %w", err)
 		}
 		// 计算实际文件大小（所有分片的总和）
 		for _, chunk := range chunks {
@@ -223,24 +323,24 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 		// 记录源文件大小用于调试
 		srcInfo, err := os.Stat(finalFilePath)
 		if err != nil {
-			return "", fmt.Errorf("获取源文件信息失败: %w", err)
+			return nil, nil, fmt.Errorf("获取源文件信息失败: %w", err)
 		}
 		actualFileSize = srcInfo.Size() // 使用实际文件大小
 		logger.LOG.Debug("准备复制文件", "源文件", finalFilePath, "目标文件", mainFilePath, "源文件大小", srcInfo.Size())
 
 		if err := copyFile(finalFilePath, mainFilePath); err != nil {
-			return "", fmt.Errorf("存储文件失败: %w", err)
+			return nil, nil, fmt.Errorf("存储文件失败: %w", err)
 		}
 
 		// 验证复制后的文件大小
 		dstInfo, err := os.Stat(mainFilePath)
 		if err != nil {
-			return "", fmt.Errorf("获取目标文件信息失败: %w", err)
+			return nil, nil, fmt.Errorf("获取目标文件信息失败: %w", err)
 		}
 		logger.LOG.Debug("文件复制完成", "目标文件大小", dstInfo.Size())
 
 		if dstInfo.Size() != srcInfo.Size() {
-			return "", fmt.Errorf("文件复制后大小不一致: 源文件=%d, 目标文件=%d", srcInfo.Size(), dstInfo.Size())
+			return nil, nil, fmt.Errorf("文件复制后大小不一致: 源文件=%d, 目标文件=%d", srcInfo.Size(), dstInfo.Size())
 		}
 	}
 
@@ -268,6 +368,7 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 		RandomName:      virtualFileName,
 		Size:            int(actualFileSize), // 使用实际计算的文件大小
 		Mime:            mimeType,
+		Category:        util.FileCategory(data.FileName, mimeType),
 		ThumbnailImg:    thumbnailPath,
 		Path:            mainFilePath,
 		FileHash:        fullHash,
@@ -284,91 +385,27 @@ func ProcessUploadedFile(data *FileUploadData, repoFactory *impl.RepositoryFacto
 		CreatedAt:       custom_type.Now(),
 		UpdatedAt:       custom_type.Now(),
 	}
+	return fileInfo, chunks, nil
+}
 
-	// 将虚拟路径转换为路径ID
-	// 如果 VirtualPath 已经是路径ID（纯数字字符串），直接使用
-	// 如果是路径字符串（如 "/home/"），则调用 getVirtualPathID 获取或创建
-	var virtualPathID string
-	if data.VirtualPath == "" {
-		// 空路径，使用根目录
-		rootPath, err := repoFactory.VirtualPath().GetRootPath(ctx, data.UserID)
-		if err != nil {
-			return "", fmt.Errorf("获取根目录失败: %w", err)
-		}
-		virtualPathID = fmt.Sprintf("%d", rootPath.ID)
-	} else if matched, _ := regexp.MatchString(`^\d+$`, data.VirtualPath); matched {
-		// 纯数字字符串，说明已经是路径ID，直接使用
-		virtualPathID = data.VirtualPath
-	} else {
-		// 路径字符串，需要获取或创建路径
-		var err error
-		virtualPathID, err = getVirtualPathID(ctx, data.UserID, data.VirtualPath, repoFactory)
-		if err != nil {
-			return "", fmt.Errorf("获取虚拟路径ID失败: %w", err)
-		}
-	}
-
-	userFile := &models.UserFiles{
-		UserID:      data.UserID,
-		FileID:      fileID,
-		IsPublic:    false,         // 默认私有
-		VirtualPath: virtualPathID, // 存储路径ID而不是路径字符串
-		FileName:    data.FileName,
-		CreatedAt:   custom_type.Now(),
-		UfID:        uuid.NewString(),
-	}
-
-	// 开启数据库事务，确保所有数据库操作的原子性
-	err = repoFactory.DB().Transaction(func(tx *gorm.DB) error {
-		// 创建基于事务的仓储工厂
-		txFactory := repoFactory.WithTx(tx)
-
-		// 10.1 写入文件信息
-		if err := txFactory.FileInfo().Create(ctx, fileInfo); err != nil {
-			return fmt.Errorf("写入文件信息失败: %w", err)
-		}
-
-		// 10.2 写入分片信息（如果是分片存储）
-		if len(chunks) > 0 {
-			if err := txFactory.FileChunk().BatchCreate(ctx, chunks); err != nil {
-				return fmt.Errorf("写入分片信息失败: %w", err)
-			}
-		}
-
-		// 10.3 写入用户文件关联
-		if err := txFactory.UserFiles().Create(ctx, userFile); err != nil {
-			return fmt.Errorf("写入用户文件关联失败: %w", err)
-		}
-
-		// 10.4 更新用户剩余空间
-		user, err := txFactory.User().GetByID(ctx, data.UserID)
-		if err != nil {
-			return fmt.Errorf("查询用户信息失败: %w", err)
-		}
-		if user.Space > 0 { // 如果不是无限空间
-			user.FreeSpace -= actualFileSize // 使用实际文件大小
-			if err := txFactory.User().Update(ctx, user); err != nil {
-				return fmt.Errorf("更新用户剩余空间失败: %w", err)
-			}
-		}
-
-		return nil // 事务成功，自动提交
-	})
+// StoreFile 将临时文件存储为新的文件数据，不创建用户文件关联（如在线编辑保存时生成新内容）
+// 调用方负责写入返回的文件信息与分片记录，写入失败时调用 RemoveStoredFile 清理；处理完成后删除临时文件所在目录
+func StoreFile(data *FileUploadData, repoFactory *impl.RepositoryFactory) (*models.FileInfo, []*models.FileChunk, error) {
+	defer cleanupTempFiles(data)
 
+	fileInfo, chunks, err := storeFile(context.Background(), data, data.TempFilePath, repoFactory)
 	if err != nil {
-		// 事务回滚，需要清理已创建的文件
-		cleanupProcessedFiles(mainFilePath, thumbnailPath, chunks)
-		return "", err
+		return nil, nil, err
 	}
-
-	// 10.5 写入.info文件（保存hash信息）
-	if err := writeInfoFile(mainFilePath, fullHash, fileEncHash); err != nil {
+	if err := writeInfoFile(fileInfo.Path, fileInfo.FileHash, fileInfo.FileEncHash); err != nil {
 		logger.LOG.Warn("写入.info文件失败", "error", err)
-		// .info文件写入失败不影响主流程
 	}
+	return fileInfo, chunks, nil
+}
 
-	logger.LOG.Info("文件处理完成", "fileID", fileID, "fileName", data.FileName, "size", actualFileSize)
-	return fileID, nil
+// RemoveStoredFile 删除文件的存储数据（主文件、.info 文件、缩略图与分片文件）
+func RemoveStoredFile(fileInfo *models.FileInfo, chunks []*models.FileChunk) {
+	cleanupProcessedFiles(fileInfo.Path, fileInfo.ThumbnailImg, chunks)
 }
 
 // mergeChunks 合并分片文件
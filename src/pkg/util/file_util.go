@@ -7,6 +7,7 @@ import (
 	"myobj/src/pkg/logger"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gabriel-vasile/mimetype"
@@ -18,6 +19,22 @@ func GenerateUniqueFilename() string {
 	return fmt.Sprintf("%d_%s", currentTime, generateCode())
 }
 
+// UniqueName 生成不与已有名称重复的名称，如 "报告 (1)"、"报告 (1).pdf"
+// isFile 为 true 时序号插在扩展名之前
+func UniqueName(name string, taken map[string]bool, isFile bool) string {
+	base, ext := name, ""
+	if isFile {
+		ext = filepath.Ext(name)
+		base = strings.TrimSuffix(name, ext)
+	}
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
 func generateCode() string {
 	var code string
 	for i := 0; i < 4; i++ {
@@ -0,0 +1,115 @@
+package util
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 文件分类，上传时写入文件信息，用于分类视图与文件列表、搜索的类型筛选
+const (
+	CategoryImage    = "image"
+	CategoryVideo    = "video"
+	CategoryAudio    = "audio"
+	CategoryDocument = "document"
+	CategoryArchive  = "archive"
+	CategoryCode     = "code"
+	CategoryOther    = "other"
+)
+
+// FileCategories 全部文件分类（按展示顺序）
+var FileCategories = []string{CategoryImage, CategoryVideo, CategoryAudio, CategoryDocument,
+	CategoryArchive, CategoryCode, CategoryOther}
+
+// categoryAliases 旧版本使用的分类名称
+var categoryAliases = map[string]string{"doc": CategoryDocument}
+
+// docMimeKeywords MIME 类型包含这些关键词的文件属于文档（pdf、Word、Excel、PowerPoint、OpenDocument 等）
+var docMimeKeywords = []string{"pdf", "word", "wordprocessingml", "excel", "spreadsheetml",
+	"powerpoint", "presentationml", "document", "presentation", "epub"}
+
+// archiveMimeKeywords MIME 类型包含这些关键词的文件属于压缩文件
+var archiveMimeKeywords = []string{"zip", "rar", "7z", "tar", "gzip", "bzip2", "xz", "zstd"}
+
+// codeMimeTypes 属于代码的 MIME 类型（mimetype 可按内容识别的脚本与标记语言）
+var codeMimeTypes = map[string]bool{
+	"text/html": true, "text/xml": true, "application/xml": true, "application/json": true,
+	"text/javascript": true, "application/javascript": true, "text/x-php": true, "text/x-python": true,
+	"text/x-perl": true, "text/x-ruby": true, "text/x-lua": true, "text/x-tcl": true,
+	"text/x-shellscript": true, "text/css": true,
+}
+
+// codeExts 内容识别为纯文本时，按扩展名判断为代码的文件
+var codeExts = map[string]bool{
+	".go": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true, ".cs": true,
+	".java": true, ".kt": true, ".scala": true, ".rs": true, ".swift": true, ".m": true,
+	".js": true, ".mjs": true, ".ts": true, ".tsx": true, ".jsx": true, ".vue": true,
+	".py": true, ".rb": true, ".php": true, ".pl": true, ".lua": true, ".sh": true, ".bat": true, ".ps1": true,
+	".sql": true, ".html": true, ".htm": true, ".css": true, ".scss": true, ".less": true,
+	".json": true, ".xml": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true,
+	".dart": true, ".r": true,
+}
+
+// NormalizeFileCategory 规范化分类名称（兼容旧名称），不支持的分类返回空字符串
+func NormalizeFileCategory(category string) string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if alias, ok := categoryAliases[category]; ok {
+		return alias
+	}
+	for _, c := range FileCategories {
+		if c == category {
+			return c
+		}
+	}
+	return ""
+}
+
+// FileCategory 按内容识别的 MIME 类型与文件名划分文件分类
+// 依次判断：图片、视频、音频（MIME 主类型） → 代码（MIME 类型，纯文本再看扩展名） → 文档 → 压缩文件（含以压缩格式为基础的类型，如 apk、jar）
+func FileCategory(name, mime string) string {
+	mime, _, _ = strings.Cut(strings.ToLower(mime), ";")
+	mime = strings.TrimSpace(mime)
+	mainType, _, _ := strings.Cut(mime, "/")
+	switch mainType {
+	case CategoryImage, CategoryVideo, CategoryAudio:
+		return mainType
+	}
+
+	// 沿 mimetype 的类型层级向上查找，如 text/x-python 的上级为 text/plain，jar 的上级为 zip
+	chain := []string{mime}
+	if m := mimetype.Lookup(mime); m != nil {
+		for p := m.Parent(); p != nil; p = p.Parent() {
+			chain = append(chain, p.String())
+		}
+	}
+	isText := mainType == "text"
+	for _, m := range chain {
+		if codeMimeTypes[m] {
+			return CategoryCode
+		}
+		if strings.HasPrefix(m, "text/") {
+			isText = true
+		}
+	}
+	// 纯文本或无法识别内容的文件按扩展名判断是否为代码
+	if (isText || mime == "" || mime == "application/octet-stream") && codeExts[strings.ToLower(filepath.Ext(name))] {
+		return CategoryCode
+	}
+	for _, keyword := range docMimeKeywords {
+		if strings.Contains(mime, keyword) {
+			return CategoryDocument
+		}
+	}
+	if isText {
+		return CategoryDocument
+	}
+	for _, m := range chain {
+		for _, keyword := range archiveMimeKeywords {
+			if strings.Contains(m, keyword) {
+				return CategoryArchive
+			}
+		}
+	}
+	return CategoryOther
+}
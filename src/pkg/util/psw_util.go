@@ -1,6 +1,14 @@
 package util
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // GeneratePassword 生成密码
 func GeneratePassword(psw string) (string, error) {
@@ -17,3 +25,24 @@ func GeneratePassword(psw string) (string, error) {
 func CheckPassword(hashedPassword, password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
 }
+
+// GenerateAppPassword 生成应用专用密码（120 位随机数，格式 xxxx-xxxx-xxxx-xxxx-xxxx-xxxx）
+func GenerateAppPassword() (string, error) {
+	buf := make([]byte, 15)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+	groups := make([]string, 0, 6)
+	for i := 0; i < len(s); i += 4 {
+		groups = append(groups, s[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// HashAppPassword 计算应用专用密码的哈希
+// 应用专用密码为高熵随机串，使用 SHA-256 即可，且每次请求认证都需要查询，不适合使用 bcrypt
+func HashAppPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,190 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// EncHeaderLength 加密文件头长度（盐 + IV + HMAC）
+const EncHeaderLength = SaltLength + IVLength + HMACLength
+
+var errNegativeOffset = errors.New("无效的偏移量")
+
+// ChunkedReader 将按顺序存储的多个分片文件组合为一个可随机读取的数据流
+// Seek 只记录偏移量，读取时直接打开偏移量所在的分片，不合并临时文件
+type ChunkedReader struct {
+	paths  []string
+	sizes  []int64
+	total  int64
+	offset int64
+	// 当前打开的分片
+	cur    *os.File
+	curIdx int
+}
+
+// NewChunkedReader 创建分片读取器，paths 与 sizes 需按分片索引排序
+func NewChunkedReader(paths []string, sizes []int64) *ChunkedReader {
+	r := &ChunkedReader{paths: paths, sizes: sizes, curIdx: -1}
+	for _, s := range sizes {
+		r.total += s
+	}
+	return r
+}
+
+// Size 数据总大小
+func (r *ChunkedReader) Size() int64 {
+	return r.total
+}
+
+func (r *ChunkedReader) Read(p []byte) (int, error) {
+	if r.offset >= r.total {
+		return 0, io.EOF
+	}
+	// 定位偏移量所在的分片
+	idx, start := 0, int64(0)
+	for idx < len(r.sizes) && start+r.sizes[idx] <= r.offset {
+		start += r.sizes[idx]
+		idx++
+	}
+	if idx != r.curIdx {
+		if r.cur != nil {
+			r.cur.Close()
+			r.cur = nil
+		}
+		f, err := os.Open(r.paths[idx])
+		if err != nil {
+			return 0, fmt.Errorf("打开分片文件失败 [索引=%d]: %w", idx, err)
+		}
+		r.cur, r.curIdx = f, idx
+	}
+	remain := start + r.sizes[idx] - r.offset
+	if int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := r.cur.ReadAt(p, r.offset-start)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *ChunkedReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.total
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *ChunkedReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+// DecryptReader 加密文件的随机读取解密器（AES-CTR）
+// 文件结构: [salt(32)][iv(16)][hmac(32)][密文...]，CTR 模式可根据偏移量直接计算计数器，
+// 因此 Seek 后无需从头解密；随机读取场景不校验整体 HMAC，调用方需事先验证文件密码
+type DecryptReader struct {
+	src    io.ReadSeeker
+	block  cipher.Block
+	iv     []byte
+	size   int64
+	offset int64
+	// 当前偏移量对应的密钥流，Seek 后重建
+	stream cipher.Stream
+}
+
+// NewDecryptReader 创建解密读取器
+// encSize 为加密数据总大小（含文件头），password 为派生后的加密密钥（见 DeriveEncryptionKey）
+func NewDecryptReader(src io.ReadSeeker, encSize int64, password string) (*DecryptReader, error) {
+	if encSize < EncHeaderLength {
+		return nil, fmt.Errorf("加密文件格式错误")
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("定位文件位置失败: %w", err)
+	}
+	header := make([]byte, SaltLength+IVLength)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	block, err := aes.NewCipher(deriveKeyFromPassword(password, header[:SaltLength]))
+	if err != nil {
+		return nil, fmt.Errorf("创建AES密码器失败: %w", err)
+	}
+	return &DecryptReader{
+		src:   src,
+		block: block,
+		iv:    header[SaltLength:],
+		size:  encSize - EncHeaderLength,
+	}, nil
+}
+
+// Size 明文大小
+func (r *DecryptReader) Size() int64 {
+	return r.size
+}
+
+func (r *DecryptReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.stream == nil {
+		if _, err := r.src.Seek(EncHeaderLength+r.offset, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("定位文件位置失败: %w", err)
+		}
+		r.stream = cipher.NewCTR(r.block, IncrementIV(r.iv, r.offset/aes.BlockSize))
+		// 跳过块内偏移对应的密钥流
+		if skip := r.offset % aes.BlockSize; skip > 0 {
+			discard := make([]byte, skip)
+			r.stream.XORKeyStream(discard, discard)
+		}
+	}
+	if remain := r.size - r.offset; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := r.src.Read(p)
+	r.stream.XORKeyStream(p[:n], p[:n])
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *DecryptReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	if offset != r.offset {
+		r.offset = offset
+		r.stream = nil
+	}
+	return offset, nil
+}
+
+// Close 关闭底层数据源
+func (r *DecryptReader) Close() error {
+	if c, ok := r.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
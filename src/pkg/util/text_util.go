@@ -0,0 +1,101 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 文本编码
+const (
+	CharsetUTF8    = "utf-8"
+	CharsetUTF8BOM = "utf-8-bom"
+	CharsetUTF16LE = "utf-16le"
+	CharsetUTF16BE = "utf-16be"
+	CharsetGBK     = "gbk"
+	CharsetGB18030 = "gb18030"
+)
+
+// ErrNotText 内容不是文本（含 NUL 字符且没有 UTF-16 BOM）
+var ErrNotText = errors.New("不是文本文件")
+
+// textEncodings 支持的文本编码（UTF-16 与带 BOM 的 UTF-8 解码时去掉 BOM，编码时写入 BOM）
+var textEncodings = map[string]encoding.Encoding{
+	CharsetUTF8:    unicode.UTF8,
+	CharsetUTF8BOM: unicode.UTF8BOM,
+	CharsetUTF16LE: unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM),
+	CharsetUTF16BE: unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM),
+	CharsetGBK:     simplifiedchinese.GBK,
+	CharsetGB18030: simplifiedchinese.GB18030,
+}
+
+// IsCharset 是否为支持的文本编码
+func IsCharset(charset string) bool {
+	_, ok := textEncodings[strings.ToLower(charset)]
+	return ok
+}
+
+// DetectCharset 检测文本编码：先按 BOM 判断 UTF-8 与 UTF-16，其次为不带 BOM 的 UTF-8，
+// 都不是时按 GB18030（兼容 GBK、GB2312）处理；含 NUL 字符且没有 UTF-16 BOM 的返回 ErrNotText
+func DetectCharset(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return CharsetUTF8BOM, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return CharsetUTF16LE, nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return CharsetUTF16BE, nil
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", ErrNotText
+	}
+	if utf8.Valid(trimPartialRune(data)) {
+		return CharsetUTF8, nil
+	}
+	return CharsetGB18030, nil
+}
+
+// trimPartialRune 去掉末尾不完整的 UTF-8 字符（只读取了文件开头时可能截断在字符中间）
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// DecodeText 按指定编码将内容解码为 UTF-8 文本
+func DecodeText(data []byte, charset string) (string, error) {
+	enc, ok := textEncodings[strings.ToLower(charset)]
+	if !ok {
+		return "", fmt.Errorf("不支持的文本编码: %s", charset)
+	}
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("按 %s 解码失败: %w", charset, err)
+	}
+	return string(text), nil
+}
+
+// EncodeText 按指定编码编码文本，文本中有该编码无法表示的字符时返回错误
+func EncodeText(text, charset string) ([]byte, error) {
+	enc, ok := textEncodings[strings.ToLower(charset)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的文本编码: %s", charset)
+	}
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("文本包含 %s 编码无法表示的字符", charset)
+	}
+	return data, nil
+}
@@ -2,93 +2,330 @@ package webdav
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"myobj/src/config"
+	"myobj/src/pkg/auth"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
 	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+	"net"
+	"sync"
 	"time"
 )
 
+// appPasswordTouchInterval 应用专用密码最近使用信息的更新间隔
+// WebDAV 客户端同步时每秒可能发起大量请求，同一 IP 在间隔内不重复写库
+const appPasswordTouchInterval = time.Minute
+
+// ldapCredentialTTL 目录密码认证成功后的缓存时间
+// 同步客户端每个请求都会携带密码，缓存期内不再访问目录服务
+const ldapCredentialTTL = 5 * time.Minute
+
+// ldapCredential 目录密码认证缓存（只保存哈希）
+type ldapCredential struct {
+	hash      [32]byte
+	expiresAt time.Time
+}
+
 // Authenticator WebDAV 认证器
 type Authenticator struct {
-	apiKeyRepo    repository.ApiKeyRepository
-	userRepo      repository.UserRepository
-	powerRepo     repository.PowerRepository
-	sysConfigRepo repository.SysConfigRepository
+	apiKeyRepo      repository.ApiKeyRepository
+	appPasswordRepo repository.AppPasswordRepository
+	userRepo        repository.UserRepository
+	powerRepo       repository.PowerRepository
+	sysConfigRepo   repository.SysConfigRepository
+	groupRepo       repository.GroupRepository
+	twoFactor       *auth.TwoFactorManager
+	ldap            *auth.LDAPManager
+	limiter         *auth.LoginLimiter
+	ldapCredentials sync.Map // username -> ldapCredential
 }
 
 // NewAuthenticator 创建 WebDAV 认证器
 func NewAuthenticator(
 	apiKeyRepo repository.ApiKeyRepository,
+	appPasswordRepo repository.AppPasswordRepository,
 	userRepo repository.UserRepository,
 	powerRepo repository.PowerRepository,
 	sysConfigRepo repository.SysConfigRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	groupRepo repository.GroupRepository,
+	virtualPathRepo repository.VirtualPathRepository,
+	sessionRepo repository.UserSessionRepository,
+	limiter *auth.LoginLimiter,
 ) *Authenticator {
 	return &Authenticator{
-		apiKeyRepo:    apiKeyRepo,
-		userRepo:      userRepo,
-		powerRepo:     powerRepo,
-		sysConfigRepo: sysConfigRepo,
+		apiKeyRepo:      apiKeyRepo,
+		appPasswordRepo: appPasswordRepo,
+		userRepo:        userRepo,
+		powerRepo:       powerRepo,
+		sysConfigRepo:   sysConfigRepo,
+		groupRepo:       groupRepo,
+		twoFactor:       auth.NewTwoFactorManager(twoFactorRepo, groupRepo),
+		ldap:            auth.NewLDAPManager(config.CONFIG.LDAP, userRepo, groupRepo, virtualPathRepo, sessionRepo),
+		limiter:         limiter,
 	}
 }
 
-// Authenticate WebDAV 认证（使用 API Key）
+// Authenticate WebDAV 认证（使用应用专用密码或 API Key，目录账户还可以使用目录密码）
 // username: 用户名
-// password: API Key（直接使用，无需签名）
-func (a *Authenticator) Authenticate(username, password string) (*models.UserInfo, error) {
+// password: 应用专用密码、API Key 或目录密码（直接使用，无需签名）
+// ip: 客户端 IP，用于记录应用专用密码的最近使用信息与限制失败次数
+// 使用应用专用密码认证时返回对应记录（用于限制访问范围），否则为 nil
+// 账户或 IP 失败次数过多时返回 *auth.LimitError
+func (a *Authenticator) Authenticate(username, password, ip string) (*models.UserInfo, *models.AppPassword, error) {
 	ctx := context.Background()
 
 	// 0. 检查 WebDAV 是否全局启用
 	webdavConfig, err := a.sysConfigRepo.GetByKey(ctx, "webdav_enabled")
 	if err == nil && webdavConfig.Value == "false" {
 		logger.LOG.Warn("WebDAV 认证失败：WebDAV 服务已全局禁用", "username", username)
-		return nil, fmt.Errorf("WebDAV 服务已禁用")
+		return nil, nil, fmt.Errorf("WebDAV 服务已禁用")
+	}
+	if err := a.limiter.CheckLogin(ctx, username, ip); err != nil {
+		logger.LOG.Warn("WebDAV 认证失败：失败次数过多", "username", username, "ip", ip)
+		return nil, nil, err
 	}
 
-	// 1. 查询用户
+	// 1. 查询用户（已启用 LDAP 时，本地不存在的用户可能是首次使用的目录账户）
 	user, err := a.userRepo.GetByUserName(ctx, username)
 	if err != nil {
-		logger.LOG.Warn("WebDAV 认证失败：用户不存在", "username", username)
-		return nil, fmt.Errorf("用户不存在")
+		if !a.ldap.Handles(nil) {
+			logger.LOG.Warn("WebDAV 认证失败：用户不存在", "username", username)
+			a.limiter.LoginFailed(ctx, auth.LimitChannelWebDAV, username, ip, "用户不存在")
+			return nil, nil, fmt.Errorf("用户不存在")
+		}
+		user = nil
 	}
 
-	// 2. 验证 API Key
-	apiKeyRecord, err := a.apiKeyRepo.GetByKey(ctx, password)
+	// 2. 优先验证应用专用密码，其次验证 API Key，目录账户最后验证目录密码
+	var appPassword *models.AppPassword
+	if user != nil {
+		appPassword = a.verifyAppPassword(ctx, user, password, ip)
+	}
+	if appPassword == nil {
+		keyErr := fmt.Errorf("用户不存在")
+		if user != nil {
+			keyErr = a.verifyApiKey(ctx, user, password, ip)
+		}
+		if keyErr != nil {
+			if !a.ldap.Handles(user) {
+				logger.LOG.Warn("WebDAV 认证失败", "username", username, "error", keyErr)
+				a.limiter.LoginFailed(ctx, auth.LimitChannelWebDAV, username, ip, keyErr.Error())
+				return nil, nil, keyErr
+			}
+			if user, err = a.verifyLDAPPassword(ctx, username, password); err != nil {
+				logger.LOG.Warn("WebDAV 认证失败：目录认证失败", "username", username, "error", err)
+				if !errors.Is(err, auth.ErrLDAPUnavailable) {
+					a.limiter.LoginFailed(ctx, auth.LimitChannelWebDAV, username, ip, "目录认证失败")
+				}
+				return nil, nil, err
+			}
+		}
+		if err := a.checkTwoFactorPolicy(ctx, user); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 3. 检查用户状态
+	if user.State == 1 {
+		logger.LOG.Warn("WebDAV 认证失败：用户已被禁用", "username", username, "user_id", user.ID)
+		return nil, nil, fmt.Errorf("用户已被禁用")
+	}
+	if user.State == models.UserStatePendingVerify {
+		logger.LOG.Warn("WebDAV 认证失败：邮箱未验证", "username", username, "user_id", user.ID)
+		return nil, nil, fmt.Errorf("邮箱未验证")
+	}
+
+	a.limiter.LoginSucceeded(username)
+	logger.LOG.Info("WebDAV 认证成功", "username", username, "user_id", user.ID)
+	return user, appPassword, nil
+}
+
+// AuthenticatePassword 使用用户名 + 应用专用密码、API Key 或登录密码认证（供 SFTP 等协议使用）
+// 依次校验应用专用密码、API Key、账户登录密码（目录账户为目录密码）；使用应用专用密码认证时返回对应记录，否则为 nil
+func (a *Authenticator) AuthenticatePassword(username, password, ip string) (*models.UserInfo, *models.AppPassword, error) {
+	ctx := context.Background()
+	if err := a.limiter.CheckLogin(ctx, username, ip); err != nil {
+		logger.LOG.Warn("认证失败：失败次数过多", "username", username, "ip", ip)
+		return nil, nil, err
+	}
+
+	user, err := a.userRepo.GetByUserName(ctx, username)
 	if err != nil {
-		logger.LOG.Warn("WebDAV 认证失败：API Key 无效", "username", username)
-		return nil, fmt.Errorf("API Key 无效")
+		if !a.ldap.Handles(nil) {
+			logger.LOG.Warn("认证失败：用户不存在", "username", username)
+			a.limiter.LoginFailed(ctx, auth.LimitChannelSFTP, username, ip, "用户不存在")
+			return nil, nil, fmt.Errorf("用户不存在")
+		}
+		user = nil
+	}
+
+	var appPassword *models.AppPassword
+	if user != nil {
+		appPassword = a.verifyAppPassword(ctx, user, password, ip)
+	}
+	if appPassword == nil {
+		if user == nil || a.verifyApiKey(ctx, user, password, ip) != nil {
+			if a.ldap.Handles(user) {
+				if user, err = a.verifyLDAPPassword(ctx, username, password); err != nil {
+					logger.LOG.Warn("认证失败：目录认证失败", "username", username, "error", err)
+					if !errors.Is(err, auth.ErrLDAPUnavailable) {
+						a.limiter.LoginFailed(ctx, auth.LimitChannelSFTP, username, ip, "目录认证失败")
+					}
+					return nil, nil, err
+				}
+			} else if !util.CheckPassword(user.Password, password) {
+				logger.LOG.Warn("认证失败：密码或 API Key 错误", "username", username)
+				a.limiter.LoginFailed(ctx, auth.LimitChannelSFTP, username, ip, "密码或 API Key 错误")
+				return nil, nil, fmt.Errorf("用户名或密码错误")
+			} else if disabled, err := auth.LocalLoginDisabled(ctx, a.groupRepo, user.GroupID); err != nil || disabled {
+				logger.LOG.Warn("认证失败：所在用户组已禁用密码登录", "username", username)
+				return nil, nil, fmt.Errorf("所在用户组已禁用密码登录,请使用应用专用密码")
+			}
+		}
+		if err := a.checkTwoFactorPolicy(ctx, user); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// 3. 验证 API Key 是否属于该用户
+	if user.State == 1 {
+		logger.LOG.Warn("认证失败：用户已被禁用", "username", username, "user_id", user.ID)
+		return nil, nil, fmt.Errorf("用户已被禁用")
+	}
+	if user.State == models.UserStatePendingVerify {
+		logger.LOG.Warn("认证失败：邮箱未验证", "username", username, "user_id", user.ID)
+		return nil, nil, fmt.Errorf("邮箱未验证")
+	}
+
+	a.limiter.LoginSucceeded(username)
+	logger.LOG.Info("认证成功", "username", username, "user_id", user.ID)
+	return user, appPassword, nil
+}
+
+// verifyAppPassword 校验应用专用密码，通过时更新最近使用信息并返回对应记录，否则返回 nil
+func (a *Authenticator) verifyAppPassword(ctx context.Context, user *models.UserInfo, password, ip string) *models.AppPassword {
+	appPassword, err := a.appPasswordRepo.GetByHash(ctx, util.HashAppPassword(password))
+	if err != nil {
+		return nil
+	}
+	if appPassword.UserID != user.ID {
+		logger.LOG.Warn("认证失败：应用专用密码与用户不匹配",
+			"username", user.UserName,
+			"app_password_id", appPassword.ID,
+		)
+		return nil
+	}
+
+	if appPassword.LastUsedIP != ip || time.Since(time.Time(appPassword.LastUsedAt)) > appPasswordTouchInterval {
+		if err := a.appPasswordRepo.UpdateLastUsed(ctx, appPassword.ID, ip); err != nil {
+			logger.LOG.Warn("更新应用专用密码使用记录失败", "app_password_id", appPassword.ID, "error", err)
+		}
+	}
+	return appPassword
+}
+
+// verifyLDAPPassword 使用目录密码认证目录账户（首次使用时自动创建本地账户）
+// 认证成功后在内存中缓存密码哈希，缓存期内同一密码直接通过，本地账户状态仍由调用方检查
+func (a *Authenticator) verifyLDAPPassword(ctx context.Context, username, password string) (*models.UserInfo, error) {
+	hash := sha256.Sum256([]byte(username + "\x00" + password))
+	if v, ok := a.ldapCredentials.Load(username); ok {
+		cred := v.(ldapCredential)
+		if time.Now().After(cred.expiresAt) {
+			a.ldapCredentials.Delete(username)
+		} else if subtle.ConstantTimeCompare(cred.hash[:], hash[:]) == 1 {
+			if user, err := a.userRepo.GetByUserName(ctx, username); err == nil && user.AuthSource == models.AuthSourceLDAP {
+				return user, nil
+			}
+		}
+	}
+
+	user, err := a.ldap.Login(ctx, username, password)
+	if err != nil {
+		if errors.Is(err, auth.ErrLDAPUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("用户名或密码错误")
+	}
+	a.ldapCredentials.Store(username, ldapCredential{hash: hash, expiresAt: time.Now().Add(ldapCredentialTTL)})
+	return user, nil
+}
+
+// checkTwoFactorPolicy 开启两步验证（或所在组要求开启）的用户只能使用应用专用密码
+// 同步协议无法进行二次验证，登录密码与 API Key 泄露时不应绕过两步验证；
+// 应用专用密码只能在已通过两步验证的会话中创建，且可随时单独吊销
+func (a *Authenticator) checkTwoFactorPolicy(ctx context.Context, user *models.UserInfo) error {
+	required, err := a.twoFactor.RequireAppPassword(ctx, user)
+	if err != nil {
+		logger.LOG.Error("查询两步验证状态失败", "user_id", user.ID, "error", err)
+		return fmt.Errorf("认证失败")
+	}
+	if required {
+		logger.LOG.Warn("认证失败：已开启两步验证，需使用应用专用密码", "username", user.UserName)
+		return fmt.Errorf("已开启两步验证,请使用应用专用密码")
+	}
+	return nil
+}
+
+// verifyApiKey 校验 API Key 是否有效且属于该用户
+// 限制了权限或目录的 API Key 只能用于 HTTP 接口，同步协议请使用应用专用密码
+func (a *Authenticator) verifyApiKey(ctx context.Context, user *models.UserInfo, key, ip string) error {
+	apiKeyRecord, err := a.apiKeyRepo.GetByKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("API Key 无效")
+	}
+
+	// 验证 API Key 是否属于该用户
 	if apiKeyRecord.UserID != user.ID {
-		logger.LOG.Warn("WebDAV 认证失败：API Key 与用户不匹配",
-			"username", username,
+		logger.LOG.Warn("认证失败：API Key 与用户不匹配",
+			"username", user.UserName,
 			"api_key_user_id", apiKeyRecord.UserID,
 			"request_user_id", user.ID,
 		)
-		return nil, fmt.Errorf("API Key 与用户不匹配")
+		return fmt.Errorf("API Key 与用户不匹配")
 	}
 
-	// 4. 检查 API Key 是否过期
+	// 检查 API Key 是否过期
 	if !apiKeyRecord.ExpiresAt.IsZero() && time.Time(apiKeyRecord.ExpiresAt).Before(time.Now()) {
-		logger.LOG.Warn("WebDAV 认证失败：API Key 已过期",
-			"username", username,
+		logger.LOG.Warn("认证失败：API Key 已过期",
+			"username", user.UserName,
 			"expires_at", apiKeyRecord.ExpiresAt,
 		)
-		return nil, fmt.Errorf("API Key 已过期")
+		return fmt.Errorf("API Key 已过期")
 	}
 
-	// 5. 检查用户状态
-	if user.State == 1 {
-		logger.LOG.Warn("WebDAV 认证失败：用户已被禁用", "username", username, "user_id", user.ID)
-		return nil, fmt.Errorf("用户已被禁用")
+	if !auth.IPAllowed(apiKeyRecord.AllowedIPs, ip) {
+		logger.LOG.Warn("认证失败：当前 IP 不允许使用该 API Key", "username", user.UserName, "ip", ip)
+		return fmt.Errorf("当前 IP 不允许使用该 API Key")
+	}
+	if auth.NewAPIKeyScope(apiKeyRecord).Restricted() {
+		logger.LOG.Warn("认证失败：受限的 API Key 不能用于同步协议", "username", user.UserName, "api_key_id", apiKeyRecord.ID)
+		return fmt.Errorf("受限的 API Key 不能用于 WebDAV / SFTP,请使用应用专用密码")
 	}
 
-	logger.LOG.Info("WebDAV 认证成功", "username", username, "user_id", user.ID)
-	return user, nil
+	// 与应用专用密码一样，同一 IP 在间隔内只记录一次
+	if apiKeyRecord.LastUsedIP != ip || time.Since(time.Time(apiKeyRecord.LastUsedAt)) > appPasswordTouchInterval {
+		if err := a.apiKeyRepo.RecordUsage(ctx, apiKeyRecord.ID, ip); err != nil {
+			logger.LOG.Warn("更新 API Key 使用记录失败", "api_key_id", apiKeyRecord.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// RemoteIP 从 "host:port" 格式的远程地址中提取 IP
+func RemoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
 }
 
-// CheckPermission 检查用户是否有 WebDAV 访问权限
+// CheckPermission 检查用户是否拥有指定权限（如 webdav:access、sftp:access）
 func (a *Authenticator) CheckPermission(userID string, groupID int, permission string) (bool, error) {
 	ctx := context.Background()
 
@@ -106,7 +343,7 @@ func (a *Authenticator) CheckPermission(userID string, groupID int, permission s
 		}
 	}
 
-	logger.LOG.Warn("用户无 WebDAV 权限",
+	logger.LOG.Warn("用户缺少访问权限",
 		"user_id", userID,
 		"group_id", groupID,
 		"required_permission", permission,
@@ -0,0 +1,183 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"myobj/src/pkg/filecopy"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// handleCopy 处理 COPY 请求
+// 与网页端复制一致，通过 filecopy 新建文件记录指向同一个文件，不读取和重新上传文件内容；
+// Depth: 0 只复制目录本身，Overwrite: F 时目标已存在返回 412，覆盖时目标先移入回收站
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request, user *models.UserInfo, fs *MyObjFileSystem) error {
+	status, err := s.copyResource(r, user, fs)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			logger.LOG.Error("WebDAV 复制失败", "user_id", user.ID, "path", r.URL.Path, "error", err)
+			http.Error(w, "复制失败", status)
+		} else {
+			http.Error(w, err.Error(), status)
+		}
+		return err
+	}
+	logger.LOG.Info("WebDAV 操作", "user_id", user.ID, "method", r.Method, "path", r.URL.Path,
+		"destination", r.Header.Get("Destination"))
+	w.WriteHeader(status)
+	return nil
+}
+
+// copyResource 执行复制，返回响应状态码
+func (s *Server) copyResource(r *http.Request, user *models.UserInfo, fs *MyObjFileSystem) (int, error) {
+	ctx := r.Context()
+	if fs.readOnly {
+		return http.StatusForbidden, ErrReadOnly
+	}
+
+	// 1. 解析请求头
+	src := lock.Path(r.URL.Path)
+	dst, status, err := copyDestination(r)
+	if err != nil {
+		return status, err
+	}
+	if src == dst || lock.IsDescendant(src, dst) || lock.IsDescendant(dst, src) {
+		return http.StatusForbidden, errors.New("源路径与目标路径不能相同或互相包含")
+	}
+	shallow := false
+	switch strings.ToLower(r.Header.Get("Depth")) {
+	case "", "infinity":
+	case "0":
+		shallow = true
+	default:
+		return http.StatusBadRequest, errors.New("无效的 Depth 头")
+	}
+	overwrite := true
+	switch strings.ToUpper(r.Header.Get("Overwrite")) {
+	case "", "T":
+	case "F":
+		overwrite = false
+	default:
+		return http.StatusBadRequest, errors.New("无效的 Overwrite 头")
+	}
+
+	// 2. 解析源与目标
+	srcName, dstName := fs.cleanPath(src), fs.cleanPath(dst)
+	if srcName == "" || dstName == "" || fs.isRoot(srcName) || fs.isRoot(dstName) {
+		return http.StatusForbidden, errors.New("不能复制根目录")
+	}
+	source := filecopy.Source{Name: path.Base(dstName), Shallow: shallow}
+	if userFile, err := fs.getUserFileByPath(ctx, srcName); err == nil {
+		source.FileID = userFile.UfID
+	} else if dir, err := fs.resolveDir(ctx, srcName); err == nil {
+		source.DirID = dir.ID
+	} else {
+		return http.StatusNotFound, errors.New("源文件或目录不存在")
+	}
+	parent, err := fs.resolveDir(ctx, path.Dir(dstName))
+	if err != nil {
+		return http.StatusConflict, errors.New("目标父目录不存在")
+	}
+	_, statErr := fs.Stat(ctx, dst)
+	exists := statErr == nil
+	if exists && !overwrite {
+		return http.StatusPreconditionFailed, errors.New("目标已存在")
+	}
+
+	// 3. 目标（及覆盖时被替换的资源）不能被他人锁定
+	var tokens []string
+	if token := ifHeaderToken(r.Header.Get("If")); token != "" {
+		tokens = append(tokens, token)
+	}
+	if err := lock.NewManager(s.factory).Confirm(ctx, user.ID, []string{path.Join(fs.scopeRoot(), dst)}, tokens); err != nil {
+		switch {
+		case errors.Is(err, lock.ErrLocked):
+			return webdav.StatusLocked, err
+		case errors.Is(err, lock.ErrNoSuchLock):
+			return http.StatusPreconditionFailed, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	// 4. 校验配额后再替换目标，避免空间不足时目标已被删除
+	copier := filecopy.NewCopier(s.factory, user.ID)
+	plan, err := copier.Plan(ctx, []filecopy.Source{source}, parent.ID)
+	if err != nil {
+		return copyErrorStatus(err), err
+	}
+	if err := copier.CheckQuota(ctx, plan); err != nil {
+		return copyErrorStatus(err), err
+	}
+	if exists {
+		if err := fs.RemoveAll(ctx, dst); err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("替换目标失败: %w", err)
+		}
+	}
+	if _, err := copier.Copy(ctx, plan, filecopy.ConflictRename); err != nil {
+		return copyErrorStatus(err), err
+	}
+	fs.copyProps(ctx, srcName, dstName)
+
+	if exists {
+		return http.StatusNoContent, nil
+	}
+	return http.StatusCreated, nil
+}
+
+// copyDestination 解析 Destination 头，返回去掉前缀后的目标路径
+func copyDestination(r *http.Request) (string, int, error) {
+	header := r.Header.Get("Destination")
+	if header == "" {
+		return "", http.StatusBadRequest, errors.New("缺少 Destination 头")
+	}
+	u, err := url.Parse(header)
+	if err != nil {
+		return "", http.StatusBadRequest, errors.New("无效的 Destination 头")
+	}
+	if u.Host != "" && u.Host != r.Host {
+		return "", http.StatusBadGateway, errors.New("不支持复制到其他服务器")
+	}
+	prefix := davPrefix()
+	if u.Path != prefix && !strings.HasPrefix(u.Path, prefix+"/") {
+		return "", http.StatusForbidden, errors.New("目标路径不在 WebDAV 目录内")
+	}
+	return lock.Path(strings.TrimPrefix(u.Path, prefix)), 0, nil
+}
+
+// copyErrorStatus 复制错误对应的 HTTP 状态码
+func copyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, filecopy.ErrInsufficientSpace):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, filecopy.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, filecopy.ErrIntoSelf):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// copyProps 复制资源本身的自定义属性（子资源的属性不复制）
+func (fs *MyObjFileSystem) copyProps(ctx context.Context, srcName, dstName string) {
+	props, err := fs.factory.WebDAVProperty().ListByPath(ctx, fs.user.ID, lock.Path(srcName))
+	if err != nil {
+		logger.LOG.Warn("WebDAV 读取自定义属性失败", "path", srcName, "error", err)
+		return
+	}
+	for _, p := range props {
+		prop := *p
+		prop.ID = 0
+		prop.Path = lock.Path(dstName)
+		if err := fs.factory.WebDAVProperty().Save(ctx, &prop); err != nil {
+			logger.LOG.Warn("WebDAV 复制自定义属性失败", "path", dstName, "error", err)
+		}
+	}
+}
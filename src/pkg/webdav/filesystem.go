@@ -2,16 +2,24 @@ package webdav
 
 import (
 	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/filemove"
+	"myobj/src/pkg/lock"
 	"myobj/src/pkg/logger"
 	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
 	"myobj/src/pkg/repository"
 	"myobj/src/pkg/upload"
+	"myobj/src/pkg/util"
 	"os"
 	"path"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
@@ -27,6 +35,10 @@ type MyObjFileSystem struct {
 	virtualPathRepo repository.VirtualPathRepository
 	diskRepo        repository.DiskRepository
 	factory         *impl.RepositoryFactory
+	// 访问范围根目录（清理后的路径，如 "文档/照片"），为空表示整个空间
+	root string
+	// 是否只读
+	readOnly bool
 }
 
 // NewMyObjFileSystem 创建文件系统实例
@@ -41,36 +53,86 @@ func NewMyObjFileSystem(user *models.UserInfo, factory *impl.RepositoryFactory)
 	}
 }
 
+// NewScopedFileSystem 创建受应用专用密码限制的文件系统实例，根目录在创建时解析为路径
+// appPassword 为 nil 时与 NewMyObjFileSystem 相同；否则只能访问其指定的目录，只读密码不允许任何写操作
+func NewScopedFileSystem(ctx context.Context, user *models.UserInfo, factory *impl.RepositoryFactory, appPassword *models.AppPassword) (*MyObjFileSystem, error) {
+	fs := NewMyObjFileSystem(user, factory).(*MyObjFileSystem)
+	if appPassword == nil {
+		return fs, nil
+	}
+	fs.readOnly = appPassword.ReadOnly
+	if appPassword.RootDirID != 0 {
+		dir, err := factory.VirtualPath().GetByID(ctx, appPassword.RootDirID)
+		if err != nil || dir.UserID != user.ID {
+			return nil, fmt.Errorf("应用专用密码的访问目录不存在")
+		}
+		root, err := lock.NewManager(factory).DirPath(ctx, appPassword.RootDirID)
+		if err != nil {
+			return nil, fmt.Errorf("解析应用专用密码的访问目录失败: %w", err)
+		}
+		fs.root = strings.Trim(root, "/")
+	}
+	return fs, nil
+}
+
+// scopeRoot 访问范围根目录在锁命名空间中的路径，不受限时为空字符串
+func (fs *MyObjFileSystem) scopeRoot() string {
+	if fs.root == "" {
+		return ""
+	}
+	return "/" + fs.root
+}
+
+// LockPath 获取请求路径在锁命名空间中的完整路径（受限访问时拼接访问范围根目录）
+func (fs *MyObjFileSystem) LockPath(name string) string {
+	return lock.Path(fs.cleanPath(name))
+}
+
+// isRoot 判断清理后的路径是否为（访问范围的）根目录，根目录不允许创建、删除和移动
+func (fs *MyObjFileSystem) isRoot(name string) bool {
+	return name == "/" || name == "" || name == fs.root
+}
+
+// checkWritable 只读访问时拒绝写操作
+func (fs *MyObjFileSystem) checkWritable(ctx context.Context) error {
+	if fs.readOnly {
+		return requestError(ctx, ErrReadOnly)
+	}
+	return nil
+}
+
 // Mkdir 创建目录
 func (fs *MyObjFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
 	logger.LOG.Info("WebDAV Mkdir", "user_id", fs.user.ID, "path", name)
+	if err := fs.checkWritable(ctx); err != nil {
+		return err
+	}
 
 	// 清理路径
 	name = fs.cleanPath(name)
-	if name == "/" || name == "" {
+	if fs.isRoot(name) {
 		return os.ErrExist
 	}
 
 	// 检查父目录是否存在
-	parentPath := path.Dir(name)
-	if parentPath != "/" && parentPath != "" {
-		_, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, parentPath)
-		if err != nil {
-			return fmt.Errorf("父目录不存在")
-		}
+	parent, err := fs.resolveDir(ctx, path.Dir(name))
+	if err != nil {
+		return fmt.Errorf("父目录不存在")
 	}
 
 	// 检查目录是否已存在
-	existing, _ := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, name)
-	if existing != nil {
+	if _, err := fs.findSubDir(ctx, parent.ID, path.Base(name)); err == nil {
 		return os.ErrExist
 	}
 
-	// 创建虚拟目录
+	// 创建虚拟目录（与网页端保持一致：Path 存储 "/目录名"，ParentLevel 存储父目录ID）
 	vpath := &models.VirtualPath{
-		UserID: fs.user.ID,
-		Path:   name,
-		IsDir:  true,
+		UserID:      fs.user.ID,
+		Path:        "/" + path.Base(name),
+		IsDir:       true,
+		ParentLevel: fmt.Sprintf("%d", parent.ID),
+		CreatedTime: custom_type.Now(),
+		UpdateTime:  custom_type.Now(),
 	}
 
 	if err := fs.virtualPathRepo.Create(ctx, vpath); err != nil {
@@ -90,88 +152,48 @@ func (fs *MyObjFileSystem) OpenFile(ctx context.Context, name string, flag int,
 	if name == "" {
 		return nil, os.ErrNotExist
 	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if err := fs.checkWritable(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	// 如果是根目录
 	if name == "/" {
 		return &davDir{
+			ctx:  ctx,
 			fs:   fs,
 			path: "", // 根目录使用空字符串
 			name: "/",
 		}, nil
 	}
 
-	// 如果是创建模式，直接尝试创建文件（避免锁冲突）
-	if flag&os.O_CREATE != 0 {
-		// 先检查文件是否已存在
-		_, err := fs.getUserFileByPath(ctx, name)
-		if err != nil {
-			// 文件不存在，创建新文件
-			logger.LOG.Info("WebDAV 创建新文件", "path", name)
-			return fs.createFile(ctx, name, flag, perm)
+	// 写入模式：新内容统一走上传流程（配额检查、加密、分片存储），不直接改写已入库的文件
+	// 不带 O_CREATE / O_TRUNC 的 O_RDWR（如 PROPPATCH）只修改属性，按只读方式打开
+	if flag&(os.O_CREATE|os.O_TRUNC) != 0 {
+		existing, err := fs.getUserFileByPath(ctx, name)
+		if err == nil {
+			if flag&os.O_EXCL != 0 {
+				return nil, os.ErrExist
+			}
+			// 覆盖写入：上传成功后旧文件移入回收站
+			logger.LOG.Info("WebDAV 覆盖文件", "path", name)
+			return fs.createFile(ctx, name, existing)
 		}
-	}
-
-	// 尝试作为目录打开
-	vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, name)
-	if err != nil {
-		logger.LOG.Info("WebDAV OpenFile - 第一次查询失败", "path", name, "error", err)
-		// 尝试带 / 前缀的版本
-		vpath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+name)
-		if err != nil {
-			logger.LOG.Info("WebDAV OpenFile - 第二次查询失败", "path", "/"+name, "error", err)
-			logger.LOG.Info("WebDAV OpenFile - 检查第三次查询条件", "name", name, "contains_slash", strings.Contains(name, "/"))
-			// 如果路径包含 /，尝试只用最后一部分（文件夹名）查询
-			if strings.Contains(name, "/") {
-				folderName := path.Base(name)
-				parentPath := path.Dir(name)
-				if parentPath == "." {
-					parentPath = ""
-				}
-				logger.LOG.Info("WebDAV OpenFile - 尝试第三次查询", "folderName", folderName, "parentPath", parentPath)
-				// 先查询父目录 ID
-				var parentID int
-				if parentPath == "" || parentPath == "/" {
-					// 根目录
-					rootPath, err := fs.virtualPathRepo.GetRootPath(ctx, fs.user.ID)
-					if err == nil {
-						parentID = rootPath.ID
-					}
-				} else {
-					// 查询父目录
-					parentVPath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, parentPath)
-					if err != nil {
-						parentVPath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+parentPath)
-					}
-					if err == nil {
-						parentID = parentVPath.ID
-					}
-				}
-				// 如果找到父目录，查询子目录
-				if parentID > 0 {
-					allDirs, _ := fs.virtualPathRepo.GetPathByUser(ctx, fs.user.ID)
-					parentIDStr := fmt.Sprintf("%d", parentID)
-					for _, dir := range allDirs {
-						if dir.ParentLevel == parentIDStr && dir.IsDir {
-							// 比较文件夹名
-							dirName := strings.TrimPrefix(dir.Path, "/")
-							if strings.Contains(dirName, "/") {
-								dirName = path.Base(dir.Path)
-							}
-							if dirName == folderName {
-								vpath = dir
-								err = nil
-								logger.LOG.Info("WebDAV OpenFile - 第三次查询成功", "folderName", folderName, "vpath.ID", vpath.ID)
-								break
-							}
-						}
-					}
-				}
+		if flag&os.O_CREATE != 0 {
+			if _, err := fs.resolveDir(ctx, name); err != nil {
+				// 文件不存在，创建新文件
+				logger.LOG.Info("WebDAV 创建新文件", "path", name)
+				return fs.createFile(ctx, name, nil)
 			}
 		}
 	}
-	if err == nil && vpath.IsDir {
-		logger.LOG.Info("WebDAV OpenFile - 找到目录", "path", name, "vpath.Path", vpath.Path, "vpath.ID", vpath.ID)
+
+	// 尝试作为目录打开
+	if _, err := fs.resolveDir(ctx, name); err == nil {
+		logger.LOG.Info("WebDAV OpenFile - 找到目录", "path", name)
 		return &davDir{
+			ctx:  ctx,
 			fs:   fs,
 			path: name, // 使用标准化后的路径（不带前缀 /）
 			name: path.Base(name),
@@ -187,110 +209,125 @@ func (fs *MyObjFileSystem) OpenFile(ctx context.Context, name string, flag int,
 			return nil, err
 		}
 
-		// 打开物理文件
-		f, err := os.OpenFile(fileInfo.Path, flag, perm)
-		if err != nil {
-			logger.LOG.Error("WebDAV 打开文件失败", "path", name, "physical_path", fileInfo.Path, "error", err)
-			return nil, err
-		}
-
+		// PROPFIND 等只需要文件信息，物理文件在首次读取时再打开
 		return &davFile{
-			file:      f,
+			ctx:       ctx,
+			fs:        fs,
+			path:      name,
 			name:      path.Base(name),
 			fileInfo:  fileInfo,
 			userFiles: userFiles,
 		}, nil
 	}
 
-	// 文件/目录不存在，如果是创建模式
-	if flag&os.O_CREATE != 0 {
-		return fs.createFile(ctx, name, flag, perm)
-	}
-
 	return nil, os.ErrNotExist
 }
 
 // RemoveAll 删除文件或目录
 func (fs *MyObjFileSystem) RemoveAll(ctx context.Context, name string) error {
 	logger.LOG.Info("WebDAV RemoveAll", "user_id", fs.user.ID, "path", name)
+	if err := fs.checkWritable(ctx); err != nil {
+		return err
+	}
 
 	name = fs.cleanPath(name)
-	if name == "/" || name == "" {
+	if fs.isRoot(name) {
 		return os.ErrPermission
 	}
 
-	// 尝试删除文件
+	// 尝试删除文件（与网页端一致：软删除 user_files 并创建回收站记录）
 	userFiles, err := fs.getUserFileByPath(ctx, name)
 	if err == nil {
-		// 移到回收站
-		recycled := &models.Recycled{
-			UserID: fs.user.ID,
-			FileID: userFiles.FileID,
-		}
-		recycledRepo := fs.factory.Recycled()
-		if err := recycledRepo.Create(ctx, recycled); err != nil {
-			logger.LOG.Error("WebDAV 移入回收站失败", "error", err)
+		if err := fs.recycleUserFile(ctx, userFiles); err != nil {
+			logger.LOG.Error("WebDAV 移入回收站失败", "error", err, "path", name)
 			return err
 		}
-
-		// 删除 user_files 记录
-		if err := fs.userFilesRepo.Delete(ctx, fs.user.ID, userFiles.FileID); err != nil {
-			logger.LOG.Error("WebDAV 删除文件记录失败", "error", err)
-			return err
-		}
-
+		fs.removeProps(ctx, name)
 		return nil
 	}
 
-	// 尝试删除目录
-	vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, name)
+	// 尝试删除目录（连同其中的子目录与文件整体移入回收站）
+	vpath, err := fs.resolveDir(ctx, name)
 	if err == nil {
-		return fs.virtualPathRepo.Delete(ctx, vpath.ID)
+		if _, _, err := recycle.NewBin(fs.factory).RecycleDir(ctx, fs.user.ID, vpath); err != nil {
+			logger.LOG.Error("WebDAV 目录移入回收站失败", "error", err, "path", name)
+			return err
+		}
+		fs.removeProps(ctx, name)
+		return nil
 	}
 
 	return os.ErrNotExist
 }
 
+// recycleUserFile 将用户文件移入回收站（软删除 user_files 并创建回收站记录）
+func (fs *MyObjFileSystem) recycleUserFile(ctx context.Context, userFiles *models.UserFiles) error {
+	_, err := recycle.NewBin(fs.factory).RecycleFile(ctx, fs.user.ID, userFiles)
+	return err
+}
+
 // Rename 重命名/移动文件或目录
 func (fs *MyObjFileSystem) Rename(ctx context.Context, oldName, newName string) error {
 	logger.LOG.Info("WebDAV Rename", "user_id", fs.user.ID, "old", oldName, "new", newName)
+	return fs.rename(ctx, oldName, newName, "")
+}
+
+// RenameOverwrite 重命名/移动文件或目录，目标位置的同名文件与移动在同一事务中移入回收站
+// 移动失败时目标文件保持不变
+func (fs *MyObjFileSystem) RenameOverwrite(ctx context.Context, oldName, newName string) error {
+	logger.LOG.Info("WebDAV RenameOverwrite", "user_id", fs.user.ID, "old", oldName, "new", newName)
+	return fs.rename(ctx, oldName, newName, filemove.ConflictOverwrite)
+}
+
+// rename 通过 filemove 移动文件或目录，conflict 为目标位置存在同名文件或目录时的处理方式
+func (fs *MyObjFileSystem) rename(ctx context.Context, oldName, newName, conflict string) error {
+	if err := fs.checkWritable(ctx); err != nil {
+		return err
+	}
 
 	oldName = fs.cleanPath(oldName)
 	newName = fs.cleanPath(newName)
+	if fs.isRoot(oldName) || fs.isRoot(newName) {
+		return os.ErrPermission
+	}
 
-	// 尝试重命名文件
-	userFiles, err := fs.getUserFileByPath(ctx, oldName)
-	if err == nil {
-		userFiles.FileName = path.Base(newName)
-		// 获取新目录的 virtual_path ID
-		newDir := path.Dir(newName)
-		if newDir == "." {
-			newDir = ""
-		}
-		if newDir == "" || newDir == "/" {
-			userFiles.VirtualPath = "0"
-		} else {
-			// 同时尝试带 / 和不带 / 的版本
-			vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, newDir)
-			if err != nil {
-				vpath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+newDir)
-			}
-			if err != nil {
-				return fmt.Errorf("目标目录不存在")
-			}
-			userFiles.VirtualPath = fmt.Sprintf("%d", vpath.ID)
-		}
-		return fs.userFilesRepo.Update(ctx, userFiles)
+	// 获取新目录
+	newParent, err := fs.resolveDir(ctx, path.Dir(newName))
+	if err != nil {
+		return fmt.Errorf("目标目录不存在")
 	}
 
-	// 尝试重命名目录
-	vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, oldName)
+	// 与网页端批量移动一致，通过 filemove 移动文件或整个目录（目录只修改上级目录）
+	source := filemove.Source{Name: path.Base(newName)}
+	if userFiles, err := fs.getUserFileByPath(ctx, oldName); err == nil {
+		source.FileID = userFiles.UfID
+	} else if vpath, err := fs.resolveDir(ctx, oldName); err == nil {
+		source.DirID = vpath.ID
+	} else {
+		return os.ErrNotExist
+	}
+	mover := filemove.NewMover(fs.factory, fs.user.ID)
+	plan, err := mover.Plan(ctx, []filemove.Source{source}, newParent.ID)
 	if err == nil {
-		vpath.Path = newName
-		return fs.virtualPathRepo.Update(ctx, vpath)
+		_, err = mover.Move(ctx, plan, conflict)
 	}
-
-	return os.ErrNotExist
+	switch {
+	case err == nil:
+	case errors.Is(err, filemove.ErrConflict):
+		return os.ErrExist
+	case errors.Is(err, filemove.ErrIntoSelf):
+		return os.ErrInvalid
+	case errors.Is(err, filemove.ErrNotFound):
+		return os.ErrNotExist
+	default:
+		return err
+	}
+	if len(plan.Conflicts) > 0 {
+		// 被覆盖的文件已移入回收站
+		fs.removeProps(ctx, newName)
+	}
+	fs.moveProps(ctx, oldName, newName)
+	return nil
 }
 
 // Stat 获取文件/目录信息
@@ -308,62 +345,12 @@ func (fs *MyObjFileSystem) Stat(ctx context.Context, name string) (os.FileInfo,
 	}
 
 	// 尝试查找目录
-	vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, name)
-	if err != nil {
-		// 尝试带 / 前缀的版本
-		vpath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+name)
-		if err != nil && strings.Contains(name, "/") {
-			// 如果路径包含 /，尝试只用最后一部分（文件夹名）查询
-			folderName := path.Base(name)
-			parentPath := path.Dir(name)
-			if parentPath == "." {
-				parentPath = ""
-			}
-			// 先查询父目录 ID
-			var parentID int
-			if parentPath == "" || parentPath == "/" {
-				// 根目录
-				rootPath, err := fs.virtualPathRepo.GetRootPath(ctx, fs.user.ID)
-				if err == nil {
-					parentID = rootPath.ID
-				}
-			} else {
-				// 查询父目录
-				parentVPath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, parentPath)
-				if err != nil {
-					parentVPath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+parentPath)
-				}
-				if err == nil {
-					parentID = parentVPath.ID
-				}
-			}
-			// 如果找到父目录，查询子目录
-			if parentID > 0 {
-				allDirs, _ := fs.virtualPathRepo.GetPathByUser(ctx, fs.user.ID)
-				parentIDStr := fmt.Sprintf("%d", parentID)
-				for _, dir := range allDirs {
-					if dir.ParentLevel == parentIDStr && dir.IsDir {
-						// 比较文件夹名
-						dirName := strings.TrimPrefix(dir.Path, "/")
-						if strings.Contains(dirName, "/") {
-							dirName = path.Base(dir.Path)
-						}
-						if dirName == folderName {
-							vpath = dir
-							err = nil
-							break
-						}
-					}
-				}
-			}
-		}
-	}
-	if err == nil && vpath.IsDir {
+	if vpath, err := fs.resolveDir(ctx, name); err == nil {
 		return &davFileInfo{
 			name:    path.Base(name),
 			size:    0,
 			isDir:   true,
-			modTime: time.Now(),
+			modTime: time.Time(vpath.UpdateTime),
 		}, nil
 	}
 
@@ -373,12 +360,7 @@ func (fs *MyObjFileSystem) Stat(ctx context.Context, name string) (os.FileInfo,
 		// 获取文件信息以获取大小
 		fileInfo, err := fs.fileRepo.GetByID(ctx, userFiles.FileID)
 		if err == nil {
-			return &davFileInfo{
-				name:    path.Base(name),
-				size:    int64(fileInfo.Size),
-				isDir:   false,
-				modTime: time.Time(userFiles.CreatedAt),
-			}, nil
+			return newFileInfo(path.Base(name), fileInfo, userFiles), nil
 		}
 		return &davFileInfo{
 			name:    path.Base(name),
@@ -392,9 +374,13 @@ func (fs *MyObjFileSystem) Stat(ctx context.Context, name string) (os.FileInfo,
 }
 
 // cleanPath 清理路径
+// 受限访问时路径相对于访问范围根目录，返回拼接后的完整路径（根目录返回 fs.root）
 func (fs *MyObjFileSystem) cleanPath(p string) string {
 	p = path.Clean("/" + p)
 	if p == "/" {
+		if fs.root != "" {
+			return fs.root
+		}
 		return "/"
 	}
 	// 移除前缀斜杠
@@ -403,43 +389,22 @@ func (fs *MyObjFileSystem) cleanPath(p string) string {
 	if strings.ToLower(p) == "desktop.ini" || strings.HasSuffix(strings.ToLower(p), "/desktop.ini") {
 		return "" // 返回空表示忽略
 	}
+	if fs.root != "" {
+		return fs.root + "/" + p
+	}
 	return p
 }
 
 // getUserFileByPath 根据虚拟路径获取用户文件
 func (fs *MyObjFileSystem) getUserFileByPath(ctx context.Context, fullPath string) (*models.UserFiles, error) {
-	dir := path.Dir(fullPath)
-	name := path.Base(fullPath)
-
-	if dir == "." {
-		dir = ""
-	}
-
-	// 根据目录路径查找 virtual_path ID
-	var pathID string
-	if dir == "" || dir == "/" {
-		// 根目录，查询根目录 ID
-		rootPath, err := fs.virtualPathRepo.GetRootPath(ctx, fs.user.ID)
-		if err != nil {
-			return nil, os.ErrNotExist
-		}
-		pathID = fmt.Sprintf("%d", rootPath.ID)
-	} else {
-		// 查询该目录对应的 virtual_path ID
-		// 同时尝试带 / 和不带 / 的版本
-		vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, dir)
-		if err != nil {
-			// 尝试带 / 前缀的版本
-			vpath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+dir)
-		}
-		if err != nil {
-			return nil, os.ErrNotExist
-		}
-		pathID = fmt.Sprintf("%d", vpath.ID)
+	dir, err := fs.resolveDir(ctx, path.Dir(fullPath))
+	if err != nil {
+		return nil, os.ErrNotExist
 	}
+	name := path.Base(fullPath)
 
 	// 查询该目录下的所有文件
-	files, err := fs.userFilesRepo.ListByVirtualPath(ctx, fs.user.ID, pathID, 0, 1000)
+	files, err := fs.userFilesRepo.ListByVirtualPath(ctx, fs.user.ID, fmt.Sprintf("%d", dir.ID), 0, 1000)
 	if err != nil {
 		return nil, err
 	}
@@ -454,54 +419,105 @@ func (fs *MyObjFileSystem) getUserFileByPath(ctx context.Context, fullPath strin
 	return nil, os.ErrNotExist
 }
 
-// createFile 创建新文件
-func (fs *MyObjFileSystem) createFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+// resolveDir 从用户根目录开始逐级解析虚拟目录
+// name 为清理后的路径，空字符串、"." 或 "/" 表示根目录
+func (fs *MyObjFileSystem) resolveDir(ctx context.Context, name string) (*models.VirtualPath, error) {
+	current, err := fs.virtualPathRepo.GetRootPath(ctx, fs.user.ID)
+	if err != nil {
+		logger.LOG.Error("WebDAV 获取根目录失败", "error", err)
+		return nil, os.ErrNotExist
+	}
+	name = strings.Trim(name, "/")
+	if name == "" || name == "." {
+		return current, nil
+	}
+	for _, part := range strings.Split(name, "/") {
+		child, err := fs.findSubDir(ctx, current.ID, part)
+		if err != nil {
+			return nil, err
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// findSubDir 在指定父目录下按名称查找子目录
+func (fs *MyObjFileSystem) findSubDir(ctx context.Context, parentID int, name string) (*models.VirtualPath, error) {
+	dirs, err := fs.virtualPathRepo.ListSubFoldersByParentID(ctx, fs.user.ID, parentID, 0, 10000)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if dirDisplayName(dir.Path) == name {
+			return dir, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// dirDisplayName 获取虚拟目录的显示名称
+// 兼容 "目录名" 与 "/目录名" 两种存储格式
+func dirDisplayName(p string) string {
+	return path.Base("/" + strings.TrimPrefix(p, "/"))
+}
+
+// createFile 创建上传文件，内容写入临时文件，关闭时通过上传流程入库
+// replace 不为空时表示覆盖写入，上传成功后旧文件移入回收站
+func (fs *MyObjFileSystem) createFile(ctx context.Context, name string, replace *models.UserFiles) (webdav.File, error) {
 	logger.LOG.Info("WebDAV 创建文件", "user_id", fs.user.ID, "path", name)
+	opts := getRequestOptions(ctx)
 
 	// 1. 获取目标目录的 virtual_path ID
-	dir := path.Dir(name)
-	if dir == "." {
-		dir = ""
+	dir, err := fs.resolveDir(ctx, path.Dir(name))
+	if err != nil {
+		logger.LOG.Error("WebDAV 目标目录不存在", "path", name, "error", err)
+		return nil, os.ErrNotExist
 	}
+	virtualPathID := dir.ID
 
-	var virtualPathID int
-	if dir == "" || dir == "/" {
-		// 根目录
-		rootPath, err := fs.virtualPathRepo.GetRootPath(ctx, fs.user.ID)
-		if err != nil {
-			logger.LOG.Error("WebDAV 获取根目录失败", "error", err)
-			return nil, os.ErrNotExist
+	// 2. 根据 Content-Length 预先检查用户配额（重新读取用户，避免长连接中配额过期）
+	user, err := fs.factory.User().GetByID(ctx, fs.user.ID)
+	if err != nil {
+		logger.LOG.Error("WebDAV 获取用户信息失败", "error", err)
+		return nil, err
+	}
+	limit := int64(-1)
+	if user.Space > 0 {
+		limit = user.FreeSpace
+		if opts.contentLength > limit {
+			logger.LOG.Warn("WebDAV 上传超出用户配额", "user_id", user.ID, "size", opts.contentLength, "free_space", user.FreeSpace)
+			return nil, requestError(ctx, ErrInsufficientStorage)
 		}
-		virtualPathID = rootPath.ID
-	} else {
-		// 检查 dir 是否为纯数字（virtual_path ID）
-		if id, err := strconv.Atoi(dir); err == nil {
-			// dir 是数字，直接使用为 virtual_path ID
-			logger.LOG.Info("WebDAV 目标目录为 ID", "dir", dir, "virtualPathID", id)
-			virtualPathID = id
-		} else {
-			// dir 是路径名，查询目录
-			vpath, err := fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, dir)
-			if err != nil {
-				// 尝试带 / 前缀的版本
-				vpath, err = fs.virtualPathRepo.GetByPath(ctx, fs.user.ID, "/"+dir)
-			}
-			if err != nil {
-				logger.LOG.Error("WebDAV 目标目录不存在", "dir", dir, "error", err)
-				return nil, os.ErrNotExist
-			}
-			virtualPathID = vpath.ID
+	}
+
+	// 3. 检查写入加密策略
+	isEnc, err := fs.requiresEncryption(ctx, dir)
+	if err != nil {
+		logger.LOG.Error("WebDAV 查询写入加密策略失败", "error", err)
+		return nil, err
+	}
+	if isEnc {
+		if opts.filePassword == "" {
+			return nil, requestError(ctx, ErrFilePasswordRequired)
+		}
+		if user.FilePassword == "" || !util.CheckPassword(user.FilePassword, opts.filePassword) {
+			logger.LOG.Warn("WebDAV 写入加密目录的文件密码错误", "user_id", user.ID, "path", name)
+			return nil, requestError(ctx, ErrFilePasswordInvalid)
 		}
 	}
 
-	// 2. 选择最大剩余空间的磁盘
+	// 4. 选择最大剩余空间的磁盘
 	bestDisk, err := fs.diskRepo.GetBigDisk(ctx)
 	if err != nil {
 		logger.LOG.Error("WebDAV 获取磁盘失败", "error", err)
 		return nil, fmt.Errorf("无可用磁盘")
 	}
+	if opts.contentLength > int64(bestDisk.Size)*1024*1024*1024 {
+		logger.LOG.Warn("WebDAV 上传超出磁盘容量", "size", opts.contentLength, "disk_size_gb", bestDisk.Size)
+		return nil, requestError(ctx, ErrInsufficientStorage)
+	}
 
-	// 3. 创建临时目录：{DiskPath}/temp/{fileName}_{sessionID}/
+	// 5. 创建临时目录：{DiskPath}/temp/{fileName}_{sessionID}/
 	fileName := path.Base(name)
 	sessionID := uuid.Must(uuid.NewV7()).String()[:8]
 	fileNameWithoutExt := fileName
@@ -514,7 +530,7 @@ func (fs *MyObjFileSystem) createFile(ctx context.Context, name string, flag int
 		return nil, fmt.Errorf("创建临时目录失败: %w", err)
 	}
 
-	// 4. 创建临时文件
+	// 6. 创建临时文件
 	tempFilePath := filepath.Join(tempBaseDir, "upload.tmp")
 	tempFile, err := os.Create(tempFilePath)
 	if err != nil {
@@ -525,8 +541,9 @@ func (fs *MyObjFileSystem) createFile(ctx context.Context, name string, flag int
 
 	logger.LOG.Info("WebDAV 临时文件已创建", "path", tempFilePath, "diskPath", bestDisk.DataPath)
 
-	// 5. 返回上传文件对象
+	// 7. 返回上传文件对象
 	return &davUploadFile{
+		ctx:           ctx,
 		file:          tempFile,
 		name:          fileName,
 		tempFilePath:  tempFilePath,
@@ -534,11 +551,16 @@ func (fs *MyObjFileSystem) createFile(ctx context.Context, name string, flag int
 		virtualPathID: virtualPathID,
 		userID:        fs.user.ID,
 		fs:            fs,
+		limit:         limit,
+		isEnc:         isEnc,
+		filePassword:  opts.filePassword,
+		replace:       replace,
 	}, nil
 }
 
 // davDir 目录对象
 type davDir struct {
+	ctx  context.Context
 	fs   *MyObjFileSystem
 	path string
 	name string
@@ -569,52 +591,22 @@ func (d *davDir) Readdir(count int) ([]os.FileInfo, error) {
 	logger.LOG.Info("WebDAV Readdir", "user_id", d.fs.user.ID, "virtual_path", virtualPath)
 
 	// 首先查询当前路径对应的 virtual_path ID
-	var currentPathID int
-	if virtualPath == "" {
-		// 根目录，查询用户根目录
-		rootPath, err := d.fs.virtualPathRepo.GetRootPath(ctx, d.fs.user.ID)
-		if err != nil {
-			logger.LOG.Error("WebDAV 获取根目录失败", "error", err)
-			return infos, nil
-		}
-		currentPathID = rootPath.ID
-	} else {
-		// 查询该路径对应的 virtual_path ID
-		// 同时尝试带 / 和不带 / 的版本
-		vpath, err := d.fs.virtualPathRepo.GetByPath(ctx, d.fs.user.ID, virtualPath)
-		if err != nil {
-			// 尝试带 / 前缀的版本
-			vpath, err = d.fs.virtualPathRepo.GetByPath(ctx, d.fs.user.ID, "/"+virtualPath)
-			if err != nil {
-				logger.LOG.Warn("WebDAV 路径不存在", "virtual_path", virtualPath)
-				return infos, nil
-			}
-		}
-		currentPathID = vpath.ID
+	current, err := d.fs.resolveDir(ctx, virtualPath)
+	if err != nil {
+		logger.LOG.Warn("WebDAV 路径不存在", "virtual_path", virtualPath)
+		return infos, nil
 	}
+	currentPathID := current.ID
 
-	// 查询子文件夹：直接查询 parent_level = currentPathID 的所有目录
-	parentIDStr := fmt.Sprintf("%d", currentPathID)
-	allDirs, _ := d.fs.virtualPathRepo.GetPathByUser(ctx, d.fs.user.ID)
-	for _, dir := range allDirs {
-		if !dir.IsDir {
-			continue
-		}
-		// 只显示 parent_level 等于 currentPathID 的目录
-		if dir.ParentLevel == parentIDStr {
-			// 只返回文件夹名称，不是完整路径
-			folderName := strings.TrimPrefix(dir.Path, "/")
-			if strings.Contains(folderName, "/") {
-				// 如果还有 /，取最后一部分
-				folderName = path.Base(dir.Path)
-			}
-			logger.LOG.Info("WebDAV Readdir - 添加子文件夹", "originalPath", dir.Path, "folderName", folderName)
-			infos = append(infos, &davFileInfo{
-				name:    folderName,
-				isDir:   true,
-				modTime: time.Time(dir.CreatedTime),
-			})
-		}
+	// 查询子文件夹：parent_level = currentPathID 的所有目录
+	subDirs, _ := d.fs.virtualPathRepo.ListSubFoldersByParentID(ctx, d.fs.user.ID, currentPathID, 0, 10000)
+	for _, dir := range subDirs {
+		// 只返回文件夹名称，不是完整路径
+		infos = append(infos, &davFileInfo{
+			name:    dirDisplayName(dir.Path),
+			isDir:   true,
+			modTime: time.Time(dir.CreatedTime),
+		})
 	}
 	logger.LOG.Info("WebDAV Readdir - 子文件夹数量", "count", len(infos))
 
@@ -630,7 +622,7 @@ func (d *davDir) Readdir(count int) ([]os.FileInfo, error) {
 		fileInfo, err := d.fs.fileRepo.GetByID(ctx, f.FileID)
 		size := int64(0)
 		if err == nil {
-			size = int64(fileInfo.Size)
+			size = plainSize(fileInfo)
 		}
 		logger.LOG.Info("WebDAV Readdir - 添加文件", "name", f.FileName, "size", size, "modTime", time.Time(f.CreatedAt))
 		infos = append(infos, &davFileInfo{
@@ -659,33 +651,126 @@ func (d *davDir) Write(p []byte) (int, error) {
 	return 0, os.ErrPermission
 }
 
-// StatFS 返回文件系统空间信息（用于显示磁盘容量）
-func (d *davDir) StatFS() (total, used, avail int64) {
-	// 返回用户的存储空间信息
-	total = d.fs.user.Space
-	used = d.fs.user.Space - d.fs.user.FreeSpace
-	avail = d.fs.user.FreeSpace
-	return
+// DeadProps 返回目录的自定义属性及配额属性（RFC 4331）
+func (d *davDir) DeadProps() (map[xml.Name]webdav.Property, error) {
+	props, err := d.fs.deadProps(d.ctx, d.path)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := d.fs.quotaProps(d.ctx)
+	if err != nil {
+		logger.LOG.Warn("WebDAV 计算配额属性失败", "user_id", d.fs.user.ID, "error", err)
+		return props, nil
+	}
+	for n, p := range quota {
+		props[n] = p
+	}
+	return props, nil
 }
 
-// davFile 文件对象
+// Patch 修改目录的自定义属性
+func (d *davDir) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return d.fs.patchProps(d.ctx, d.path, patches)
+}
+
+// davFile 文件对象（只读）
+// 按存储方式组合读取器：普通文件直接读取，分片文件按偏移量定位分片，加密文件按 CTR 计数器随机解密，
+// Range 请求无需合并或解密出临时文件
 type davFile struct {
-	file      *os.File
+	ctx       context.Context
+	fs        *MyObjFileSystem
+	path      string
 	name      string
 	fileInfo  *models.FileInfo
 	userFiles *models.UserFiles
+	// 首次 Read/Seek 时打开
+	reader io.ReadSeekCloser
+}
+
+// open 根据文件存储方式打开读取器
+func (f *davFile) open() error {
+	if f.reader != nil {
+		return nil
+	}
+
+	// 加密文件需要校验文件密码
+	var encryptionKey string
+	if f.fileInfo.IsEnc {
+		opts := getRequestOptions(f.ctx)
+		if opts.filePassword == "" {
+			return requestError(f.ctx, ErrFilePasswordRequired)
+		}
+		user, err := f.fs.factory.User().GetByID(f.ctx, f.fs.user.ID)
+		if err != nil {
+			return err
+		}
+		if user.FilePassword == "" || !util.CheckPassword(user.FilePassword, opts.filePassword) {
+			logger.LOG.Warn("WebDAV 读取加密文件的文件密码错误", "user_id", user.ID, "path", f.path)
+			return requestError(f.ctx, ErrFilePasswordInvalid)
+		}
+		encryptionKey = util.DeriveEncryptionKey(opts.filePassword, f.fs.user.ID)
+	}
+
+	// 打开存储数据（分片文件组合为连续数据流）
+	var blob io.ReadSeekCloser
+	if f.fileInfo.IsChunk {
+		chunks, err := f.fs.factory.FileChunk().GetByFileID(f.ctx, f.fileInfo.ID)
+		if err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return fmt.Errorf("未找到分片文件")
+		}
+		sort.Slice(chunks, func(i, j int) bool {
+			return chunks[i].ChunkIndex < chunks[j].ChunkIndex
+		})
+		paths := make([]string, len(chunks))
+		sizes := make([]int64, len(chunks))
+		for i, c := range chunks {
+			paths[i], sizes[i] = c.ChunkPath, int64(c.ChunkSize)
+		}
+		blob = util.NewChunkedReader(paths, sizes)
+	} else {
+		file, err := os.Open(f.fileInfo.Path)
+		if err != nil {
+			logger.LOG.Error("WebDAV 打开文件失败", "path", f.path, "physical_path", f.fileInfo.Path, "error", err)
+			return err
+		}
+		blob = file
+	}
+
+	if !f.fileInfo.IsEnc {
+		f.reader = blob
+		return nil
+	}
+	reader, err := util.NewDecryptReader(blob, int64(f.fileInfo.Size), encryptionKey)
+	if err != nil {
+		blob.Close()
+		return err
+	}
+	f.reader = reader
+	return nil
 }
 
 func (f *davFile) Close() error {
-	return f.file.Close()
+	if f.reader != nil {
+		return f.reader.Close()
+	}
+	return nil
 }
 
 func (f *davFile) Read(p []byte) (int, error) {
-	return f.file.Read(p)
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.reader.Read(p)
 }
 
 func (f *davFile) Seek(offset int64, whence int) (int64, error) {
-	return f.file.Seek(offset, whence)
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.reader.Seek(offset, whence)
 }
 
 func (f *davFile) Readdir(count int) ([]os.FileInfo, error) {
@@ -693,15 +778,45 @@ func (f *davFile) Readdir(count int) ([]os.FileInfo, error) {
 }
 
 func (f *davFile) Stat() (os.FileInfo, error) {
-	return &davFileInfo{
-		name:    f.name,
-		size:    int64(f.fileInfo.Size),
-		modTime: time.Time(f.userFiles.CreatedAt),
-	}, nil
+	return newFileInfo(f.name, f.fileInfo, f.userFiles), nil
 }
 
 func (f *davFile) Write(p []byte) (int, error) {
-	return f.file.Write(p)
+	return 0, os.ErrPermission
+}
+
+// DeadProps 返回文件的自定义属性
+func (f *davFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	return f.fs.deadProps(f.ctx, f.path)
+}
+
+// Patch 修改文件的自定义属性
+func (f *davFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	return f.fs.patchProps(f.ctx, f.path, patches)
+}
+
+// plainSize 文件内容大小（加密文件扣除文件头）
+func plainSize(fileInfo *models.FileInfo) int64 {
+	size := int64(fileInfo.Size)
+	if fileInfo.IsEnc && size >= util.EncHeaderLength {
+		size -= util.EncHeaderLength
+	}
+	return size
+}
+
+// newFileInfo 根据文件记录创建文件信息
+func newFileInfo(name string, fileInfo *models.FileInfo, userFiles *models.UserFiles) *davFileInfo {
+	etag := ""
+	if fileInfo.FileHash != "" {
+		etag = `"` + fileInfo.FileHash + `"`
+	}
+	return &davFileInfo{
+		name:        name,
+		size:        plainSize(fileInfo),
+		modTime:     time.Time(userFiles.CreatedAt),
+		contentType: fileInfo.Mime,
+		etag:        etag,
+	}
 }
 
 // davFileInfo 文件信息
@@ -710,6 +825,9 @@ type davFileInfo struct {
 	size    int64
 	isDir   bool
 	modTime time.Time
+	// 已知的 MIME 类型与 ETag，避免 PROPFIND 时读取文件内容探测（加密文件无法探测）
+	contentType string
+	etag        string
 }
 
 func (fi *davFileInfo) Name() string { return fi.name }
@@ -724,8 +842,25 @@ func (fi *davFileInfo) ModTime() time.Time { return fi.modTime }
 func (fi *davFileInfo) IsDir() bool        { return fi.isDir }
 func (fi *davFileInfo) Sys() interface{}   { return nil }
 
+// ContentType 实现 webdav.ContentTyper
+func (fi *davFileInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.contentType == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return fi.contentType, nil
+}
+
+// ETag 实现 webdav.ETager
+func (fi *davFileInfo) ETag(ctx context.Context) (string, error) {
+	if fi.etag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return fi.etag, nil
+}
+
 // davUploadFile 上传文件对象
 type davUploadFile struct {
+	ctx           context.Context
 	file          *os.File
 	name          string
 	tempFilePath  string
@@ -733,9 +868,22 @@ type davUploadFile struct {
 	virtualPathID int
 	userID        string
 	fs            *MyObjFileSystem
+	// 用户剩余配额（-1 表示不限制）
+	limit int64
+	// 是否加密存储及加密密码
+	isEnc        bool
+	filePassword string
+	// 覆盖写入时被替换的旧文件
+	replace *models.UserFiles
+	// 写入失败（如超出配额），关闭时不入库
+	failed bool
 }
 
 func (f *davUploadFile) Close() error {
+	if f.failed {
+		return f.Abort()
+	}
+
 	// 1. 关闭文件句柄
 	if err := f.file.Close(); err != nil {
 		logger.LOG.Error("WebDAV 关闭临时文件失败", "error", err)
@@ -754,26 +902,30 @@ func (f *davUploadFile) Close() error {
 	fileSize := fileInfo.Size()
 	logger.LOG.Info("WebDAV 文件上传完成", "name", f.name, "size", fileSize, "tempPath", f.tempFilePath)
 
-	// 如果文件大小为 0，说明没有数据写入，可能是 LOCK 导致的，直接清理临时文件
-	if fileSize == 0 {
-		logger.LOG.Warn("WebDAV 文件大小为 0，可能被 LOCK 阻止，不处理此文件", "name", f.name)
-		os.RemoveAll(f.tempDir)
-		return nil // 返回 nil 避免报错
+	// 3. 写入完成后再次检查配额（写入期间其他上传可能已占用空间）
+	if f.limit >= 0 {
+		user, err := f.fs.factory.User().GetByID(f.ctx, f.userID)
+		if err == nil && user.Space > 0 && user.FreeSpace < fileSize {
+			os.RemoveAll(f.tempDir)
+			return requestError(f.ctx, ErrInsufficientStorage)
+		}
 	}
 
-	// 3. 获取虚拟路径 ID（upload.ProcessUploadedFile 期望的是 ID 字符串）
+	// 4. 获取虚拟路径 ID（upload.ProcessUploadedFile 期望的是 ID 字符串）
 	virtualPathIDStr := fmt.Sprintf("%d", f.virtualPathID)
 	logger.LOG.Info("WebDAV 上传到虚拟路径", "virtualPathID", f.virtualPathID, "virtualPathIDStr", virtualPathIDStr)
 
-	// 4. 调用上传处理
+	// 5. 调用上传处理
+	// 内容已完整写入单个临时文件，无需合并上传分片；超过大文件阈值时 ProcessUploadedFile 会自动分片存储
 	uploadData := &upload.FileUploadData{
 		TempFilePath: f.tempFilePath,
 		FileName:     f.name,
 		FileSize:     fileSize,
 		VirtualPath:  virtualPathIDStr, // 传递 ID 字符串
 		UserID:       f.userID,
-		IsEnc:        false, // WebDAV 不支持加密
-		IsChunk:      false, // WebDAV 不支持分片
+		IsEnc:        f.isEnc,
+		IsChunk:      false,
+		FilePassword: f.filePassword,
 	}
 
 	fileID, err := upload.ProcessUploadedFile(uploadData, f.fs.factory)
@@ -784,11 +936,25 @@ func (f *davUploadFile) Close() error {
 		return fmt.Errorf("文件上传失败: %w", err)
 	}
 
-	// 5. 上传成功，ProcessUploadedFile 会自动清理临时文件
-	logger.LOG.Info("WebDAV 文件上传成功", "name", f.name, "fileID", fileID)
+	// 6. 上传成功，ProcessUploadedFile 会自动清理临时文件
+	logger.LOG.Info("WebDAV 文件上传成功", "name", f.name, "fileID", fileID, "is_enc", f.isEnc)
+
+	// 7. 覆盖写入：旧文件移入回收站
+	if f.replace != nil {
+		if err := f.fs.recycleUserFile(f.ctx, f.replace); err != nil {
+			logger.LOG.Error("WebDAV 旧文件移入回收站失败", "error", err, "uf_id", f.replace.UfID)
+		}
+	}
 	return nil
 }
 
+// Abort 放弃本次上传并清理临时文件（传输中断时调用，替代 Close）
+func (f *davUploadFile) Abort() error {
+	f.file.Close()
+	logger.LOG.Warn("上传已中断，清理临时文件", "name", f.name, "tempDir", f.tempDir)
+	return os.RemoveAll(f.tempDir)
+}
+
 func (f *davUploadFile) Read(p []byte) (int, error) {
 	return 0, os.ErrInvalid
 }
@@ -802,14 +968,35 @@ func (f *davUploadFile) Readdir(count int) ([]os.FileInfo, error) {
 }
 
 func (f *davUploadFile) Stat() (os.FileInfo, error) {
+	var size int64
+	if info, err := f.file.Stat(); err == nil {
+		size = info.Size()
+	}
 	return &davFileInfo{
 		name:    f.name,
-		size:    0,
+		size:    size,
 		isDir:   false,
 		modTime: time.Now(),
 	}, nil
 }
 
 func (f *davUploadFile) Write(p []byte) (int, error) {
-	return f.file.Write(p)
+	if f.limit >= 0 {
+		pos, err := f.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			f.failed = true
+			return 0, err
+		}
+		if pos+int64(len(p)) > f.limit {
+			// 超出配额立即中断，避免继续接收数据
+			f.failed = true
+			logger.LOG.Warn("WebDAV 上传超出用户配额", "user_id", f.userID, "name", f.name, "free_space", f.limit)
+			return 0, requestError(f.ctx, ErrInsufficientStorage)
+		}
+	}
+	n, err := f.file.Write(p)
+	if err != nil {
+		f.failed = true
+	}
+	return n, err
 }
@@ -0,0 +1,109 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"myobj/src/pkg/lock"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/webdav"
+)
+
+// requestTokenPrefix 写操作前冲突检查返回的一次性令牌前缀（不落库）
+const requestTokenPrefix = "request:"
+
+// requestLockSystem 将 lock.Manager 适配为 x/net/webdav 的 LockSystem（每个请求一个实例）
+// LOCK 请求由 Server 自行处理（x/net/webdav 不支持共享锁），这里只负责：
+//   - Create：未携带 If 头的写操作前的冲突检查，不落库，返回一次性令牌
+//   - Confirm：携带 If 头时校验锁令牌
+//   - Unlock：UNLOCK 请求释放锁
+type requestLockSystem struct {
+	ctx     context.Context
+	userID  string
+	manager *lock.Manager
+	prefix  string
+	// 访问范围根目录（应用专用密码限制访问目录时），请求中的路径相对于该目录
+	root string
+}
+
+func (ls *requestLockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (func(), error) {
+	var tokens []string
+	for _, c := range conditions {
+		if c.Token != "" && !c.Not {
+			tokens = append(tokens, c.Token)
+		}
+	}
+	err := ls.manager.Confirm(ls.ctx, ls.userID, []string{ls.fullPath(name0), ls.fullPath(name1)}, tokens)
+	if err != nil {
+		if errors.Is(err, lock.ErrLocked) || errors.Is(err, lock.ErrNoSuchLock) {
+			return nil, webdav.ErrConfirmationFailed
+		}
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+func (ls *requestLockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	if err := ls.manager.CheckUnlocked(ls.ctx, ls.userID, ls.fullPath(details.Root)); err != nil {
+		if errors.Is(err, lock.ErrLocked) {
+			return "", webdav.ErrLocked
+		}
+		return "", err
+	}
+	return requestTokenPrefix + uuid.Must(uuid.NewRandom()).String(), nil
+}
+
+func (ls *requestLockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	l, err := ls.manager.Refresh(ls.ctx, ls.userID, token, duration)
+	if err != nil {
+		if errors.Is(err, lock.ErrNoSuchLock) {
+			return webdav.LockDetails{}, webdav.ErrNoSuchLock
+		}
+		return webdav.LockDetails{}, err
+	}
+	return webdav.LockDetails{
+		Root:      scopedPath(l.Root, ls.root),
+		Duration:  time.Duration(l.Timeout) * time.Second,
+		OwnerXML:  l.OwnerXML,
+		ZeroDepth: l.ZeroDepth,
+	}, nil
+}
+
+func (ls *requestLockSystem) Unlock(now time.Time, token string) error {
+	if strings.HasPrefix(token, requestTokenPrefix) {
+		return nil
+	}
+	if err := ls.manager.Unlock(ls.ctx, ls.userID, token); err != nil {
+		if errors.Is(err, lock.ErrNoSuchLock) {
+			return webdav.ErrNoSuchLock
+		}
+		return err
+	}
+	return nil
+}
+
+// fullPath 将请求中的路径转换为锁命名空间中的完整路径
+// If 头中的资源标记为完整 URL，其路径带有 WebDAV 前缀，需要去掉；受限访问时再拼接访问范围根目录
+func (ls *requestLockSystem) fullPath(name string) string {
+	if ls.prefix != "" && strings.HasPrefix(name, ls.prefix+"/") {
+		name = strings.TrimPrefix(name, ls.prefix)
+	}
+	if name == "" || ls.root == "" {
+		return name
+	}
+	return path.Join(ls.root, name)
+}
+
+// scopedPath 将锁命名空间中的完整路径转换为相对于访问范围根目录的路径
+func scopedPath(p, root string) string {
+	if root == "" {
+		return p
+	}
+	if p == root {
+		return "/"
+	}
+	return strings.TrimPrefix(p, root)
+}
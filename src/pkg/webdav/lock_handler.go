@@ -0,0 +1,205 @@
+package webdav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// lockInfo LOCK 请求体
+type lockInfo struct {
+	XMLName   xml.Name  `xml:"lockinfo"`
+	Exclusive *struct{} `xml:"lockscope>exclusive"`
+	Shared    *struct{} `xml:"lockscope>shared"`
+	Write     *struct{} `xml:"locktype>write"`
+	Owner     struct {
+		InnerXML string `xml:",innerxml"`
+	} `xml:"owner"`
+}
+
+// handleLock 处理 LOCK 请求（创建或刷新锁）
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request, user *models.UserInfo, fs *MyObjFileSystem) {
+	ctx := r.Context()
+	manager := lock.NewManager(s.factory)
+	if fs.readOnly {
+		http.Error(w, ErrReadOnly.Error(), http.StatusForbidden)
+		return
+	}
+	root := fs.scopeRoot()
+
+	duration, err := parseLockTimeout(r.Header.Get("Timeout"))
+	if err != nil {
+		http.Error(w, "无效的 Timeout 头", http.StatusBadRequest)
+		return
+	}
+	info, err := readLockInfo(r.Body)
+	if err != nil {
+		http.Error(w, "无效的 LOCK 请求体", http.StatusBadRequest)
+		return
+	}
+
+	var l *models.WebDAVLock
+	created := false
+	if info == nil {
+		// 空请求体表示刷新锁，令牌从 If 头中获取
+		token := ifHeaderToken(r.Header.Get("If"))
+		if token == "" {
+			http.Error(w, "缺少锁令牌", http.StatusBadRequest)
+			return
+		}
+		l, err = manager.Refresh(ctx, user.ID, token, duration)
+		if err != nil {
+			if errors.Is(err, lock.ErrNoSuchLock) {
+				http.Error(w, "锁不存在或已过期", http.StatusPreconditionFailed)
+				return
+			}
+			http.Error(w, "刷新锁失败", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if info.Write == nil || (info.Exclusive == nil) == (info.Shared == nil) {
+			http.Error(w, "不支持的锁类型", http.StatusBadRequest)
+			return
+		}
+		// 未指定 Depth 时按 infinity 处理，LOCK 只允许 0 或 infinity
+		zeroDepth := false
+		switch strings.ToLower(r.Header.Get("Depth")) {
+		case "", "infinity":
+		case "0":
+			zeroDepth = true
+		default:
+			http.Error(w, "无效的 Depth 头", http.StatusBadRequest)
+			return
+		}
+
+		reqPath := lock.Path(r.URL.Path)
+		if _, err := fs.Stat(ctx, reqPath); err != nil {
+			// 允许锁定尚不存在的文件（客户端通常先 LOCK 再 PUT），但父目录必须存在
+			if _, err := fs.Stat(ctx, path.Dir(reqPath)); err != nil {
+				http.Error(w, "父目录不存在", http.StatusConflict)
+				return
+			}
+			created = true
+		}
+
+		l, err = manager.Create(ctx, user.ID, lock.Request{
+			Root:      path.Join(root, reqPath),
+			Shared:    info.Shared != nil,
+			ZeroDepth: zeroDepth,
+			OwnerXML:  info.Owner.InnerXML,
+			Duration:  duration,
+		})
+		if err != nil {
+			if errors.Is(err, lock.ErrLocked) {
+				http.Error(w, "资源已被锁定", webdav.StatusLocked)
+				return
+			}
+			http.Error(w, "创建锁失败", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Lock-Token", "<"+l.Token+">")
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := writeLockDiscovery(w, l, root); err != nil {
+		logger.LOG.Error("WebDAV 写入锁信息失败", "error", err)
+	}
+}
+
+// readLockInfo 解析 LOCK 请求体，请求体为空时返回 nil（表示刷新锁）
+func readLockInfo(r io.Reader) (*lockInfo, error) {
+	var info lockInfo
+	if err := xml.NewDecoder(r).Decode(&info); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &info, nil
+}
+
+// parseLockTimeout 解析 Timeout 头（如 "Second-3600"、"Infinite"），取第一个可识别的值
+func parseLockTimeout(header string) (time.Duration, error) {
+	if header == "" {
+		return lock.DefaultTimeout, nil
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if strings.EqualFold(v, "Infinite") {
+			return lock.DefaultTimeout, nil
+		}
+		if strings.HasPrefix(v, "Second-") {
+			n, err := strconv.ParseInt(strings.TrimPrefix(v, "Second-"), 10, 64)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("无效的超时时间: %s", v)
+			}
+			if n > int64(lock.MaxTimeout/time.Second) {
+				return lock.MaxTimeout, nil
+			}
+			return time.Duration(n) * time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("无效的超时时间: %s", header)
+}
+
+// ifHeaderToken 从 If 头中提取锁令牌
+func ifHeaderToken(header string) string {
+	for {
+		start := strings.Index(header, "<"+lock.TokenPrefix)
+		if start < 0 {
+			return ""
+		}
+		header = header[start+1:]
+		if end := strings.IndexByte(header, '>'); end >= 0 {
+			return header[:end]
+		}
+	}
+}
+
+// writeLockDiscovery 输出 lockdiscovery 响应，root 为访问范围根目录（lockroot 相对于该目录输出）
+func writeLockDiscovery(w io.Writer, l *models.WebDAVLock, root string) error {
+	scope, depth := "exclusive", "infinity"
+	if l.Shared {
+		scope = "shared"
+	}
+	if l.ZeroDepth {
+		depth = "0"
+	}
+	href := (&url.URL{Path: path.Join(davPrefix(), scopedPath(l.Root, root))}).EscapedPath()
+	_, err := fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n"+
+		"<D:prop xmlns:D=\"DAV:\"><D:lockdiscovery><D:activelock>\n"+
+		"\t<D:locktype><D:write/></D:locktype>\n"+
+		"\t<D:lockscope><D:%s/></D:lockscope>\n"+
+		"\t<D:depth>%s</D:depth>\n"+
+		"\t<D:owner>%s</D:owner>\n"+
+		"\t<D:timeout>Second-%d</D:timeout>\n"+
+		"\t<D:locktoken><D:href>%s</D:href></D:locktoken>\n"+
+		"\t<D:lockroot><D:href>%s</D:href></D:lockroot>\n"+
+		"</D:activelock></D:lockdiscovery></D:prop>",
+		scope, depth, l.OwnerXML, l.Timeout, xmlEscape(l.Token), xmlEscape(href),
+	)
+	return err
+}
+
+// xmlEscape 转义 XML 文本
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// FilePasswordHeader 读写加密文件时用于提供文件密码的请求头
+const FilePasswordHeader = "X-File-Password"
+
+var (
+	// ErrInsufficientStorage 用户配额或磁盘空间不足
+	ErrInsufficientStorage = errors.New("存储空间不足")
+	// ErrFilePasswordRequired 读取加密文件或写入加密目录时未提供文件密码
+	ErrFilePasswordRequired = errors.New("需要文件密码，请通过 " + FilePasswordHeader + " 请求头提供")
+	// ErrFilePasswordInvalid 文件密码错误或未设置
+	ErrFilePasswordInvalid = errors.New("文件密码错误或未设置文件密码")
+	// ErrReadOnly 只读应用专用密码尝试写操作
+	ErrReadOnly = fmt.Errorf("当前凭据为只读权限: %w", os.ErrPermission)
+)
+
+// requestOptions 单次请求的读写参数与结果
+type requestOptions struct {
+	// 文件密码（明文，仅读写加密文件时使用）
+	filePassword string
+	// 请求声明的内容长度（未知时为 -1）
+	contentLength int64
+	// 读写过程中出现的配额/加密/只读错误，用于生成对应的响应状态码
+	err error
+}
+
+type requestOptionsKey struct{}
+
+// withRequestOptions 将请求参数写入上下文
+func withRequestOptions(ctx context.Context, opts *requestOptions) context.Context {
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// getRequestOptions 获取上下文中的请求参数（SFTP 等没有请求参数时返回默认值）
+func getRequestOptions(ctx context.Context) *requestOptions {
+	if opts, ok := ctx.Value(requestOptionsKey{}).(*requestOptions); ok {
+		return opts
+	}
+	return &requestOptions{contentLength: -1}
+}
+
+// requestError 记录请求错误并原样返回
+func requestError(ctx context.Context, err error) error {
+	getRequestOptions(ctx).err = err
+	return err
+}
+
+// requestErrorStatus 请求错误对应的 HTTP 状态码
+func requestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInsufficientStorage):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, ErrFilePasswordRequired), errors.Is(err, ErrFilePasswordInvalid), errors.Is(err, ErrReadOnly):
+		return http.StatusForbidden
+	}
+	return 0
+}
+
+// errorStatusWriter 将请求过程中记录的配额/加密错误转换为 507 / 403 响应
+// x/net/webdav 对读写失败统一返回 404/405/500，无法区分空间不足和权限问题
+type errorStatusWriter struct {
+	http.ResponseWriter
+	opts       *requestOptions
+	overridden bool
+}
+
+func (w *errorStatusWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && w.opts.err != nil {
+		if status := requestErrorStatus(w.opts.err); status != 0 {
+			w.overridden = true
+			w.Header().Del("Content-Length")
+			w.Header().Del("Content-Range")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.ResponseWriter.WriteHeader(status)
+			w.ResponseWriter.Write([]byte(w.opts.err.Error()))
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *errorStatusWriter) Write(p []byte) (int, error) {
+	if w.overridden {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,140 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/webdav"
+	"gorm.io/gorm"
+)
+
+// RFC 4331 配额属性，由服务端根据用户空间计算，不允许客户端修改
+var (
+	quotaAvailableName = xml.Name{Space: "DAV:", Local: "quota-available-bytes"}
+	quotaUsedName      = xml.Name{Space: "DAV:", Local: "quota-used-bytes"}
+)
+
+// isProtectedProp 判断属性是否由服务端维护
+func isProtectedProp(name xml.Name) bool {
+	return name == quotaAvailableName || name == quotaUsedName
+}
+
+// deadProps 读取资源的自定义属性
+func (fs *MyObjFileSystem) deadProps(ctx context.Context, name string) (map[xml.Name]webdav.Property, error) {
+	props, err := fs.factory.WebDAVProperty().ListByPath(ctx, fs.user.ID, lock.Path(name))
+	if err != nil {
+		logger.LOG.Error("WebDAV 读取自定义属性失败", "path", name, "error", err)
+		return nil, err
+	}
+	result := make(map[xml.Name]webdav.Property, len(props))
+	for _, p := range props {
+		n := xml.Name{Space: p.Namespace, Local: p.Name}
+		result[n] = webdav.Property{XMLName: n, Lang: p.Lang, InnerXML: []byte(p.InnerXML)}
+	}
+	return result, nil
+}
+
+// patchProps 写入/删除资源的自定义属性，所有修改在同一事务中完成
+func (fs *MyObjFileSystem) patchProps(ctx context.Context, name string, patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	// 配额等受保护属性不允许修改，此时整个请求失败（RFC 4918 要求 PROPPATCH 原子执行）
+	forbidden := webdav.Propstat{
+		Status:   http.StatusForbidden,
+		XMLError: `<D:cannot-modify-protected-property xmlns:D="DAV:"/>`,
+	}
+	failedDep := webdav.Propstat{Status: webdav.StatusFailedDependency}
+	ok := webdav.Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			if isProtectedProp(p.XMLName) {
+				forbidden.Props = append(forbidden.Props, webdav.Property{XMLName: p.XMLName})
+			} else {
+				failedDep.Props = append(failedDep.Props, webdav.Property{XMLName: p.XMLName})
+				ok.Props = append(ok.Props, webdav.Property{XMLName: p.XMLName})
+			}
+		}
+	}
+	if len(forbidden.Props) > 0 {
+		if len(failedDep.Props) == 0 {
+			return []webdav.Propstat{forbidden}, nil
+		}
+		return []webdav.Propstat{forbidden, failedDep}, nil
+	}
+
+	p := lock.Path(name)
+	err := fs.factory.DB().Transaction(func(tx *gorm.DB) error {
+		repo := fs.factory.WithTx(tx).WebDAVProperty()
+		for _, patch := range patches {
+			for _, prop := range patch.Props {
+				if patch.Remove {
+					if err := repo.Delete(ctx, fs.user.ID, p, prop.XMLName.Space, prop.XMLName.Local); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := repo.Save(ctx, &models.WebDAVProperty{
+					UserID:    fs.user.ID,
+					Path:      p,
+					Namespace: prop.XMLName.Space,
+					Name:      prop.XMLName.Local,
+					Lang:      prop.Lang,
+					InnerXML:  string(prop.InnerXML),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.LOG.Error("WebDAV 写入自定义属性失败", "path", name, "error", err)
+		return nil, err
+	}
+	return []webdav.Propstat{ok}, nil
+}
+
+// quotaProps 计算 RFC 4331 配额属性
+// 有限空间用户按配额计算；无限空间用户的已用空间为文件总大小，可用空间为最大磁盘容量
+func (fs *MyObjFileSystem) quotaProps(ctx context.Context) (map[xml.Name]webdav.Property, error) {
+	user, err := fs.factory.User().GetByID(ctx, fs.user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var used, avail int64
+	if user.Space > 0 {
+		used = user.Space - user.FreeSpace
+		avail = user.FreeSpace
+	} else {
+		if used, err = fs.userFilesRepo.SumSizeByUserID(ctx, fs.user.ID); err != nil {
+			return nil, err
+		}
+		if disk, err := fs.diskRepo.GetBigDisk(ctx); err == nil {
+			avail = int64(disk.Size) * 1024 * 1024 * 1024
+		}
+	}
+	if avail < 0 {
+		avail = 0
+	}
+	return map[xml.Name]webdav.Property{
+		quotaAvailableName: {XMLName: quotaAvailableName, InnerXML: []byte(strconv.FormatInt(avail, 10))},
+		quotaUsedName:      {XMLName: quotaUsedName, InnerXML: []byte(strconv.FormatInt(used, 10))},
+	}, nil
+}
+
+// moveProps 资源移动后同步自定义属性的路径
+func (fs *MyObjFileSystem) moveProps(ctx context.Context, oldName, newName string) {
+	if err := fs.factory.WebDAVProperty().MovePath(ctx, fs.user.ID, lock.Path(oldName), lock.Path(newName)); err != nil {
+		logger.LOG.Warn("WebDAV 同步自定义属性路径失败", "old", oldName, "new", newName, "error", err)
+	}
+}
+
+// removeProps 资源删除后清理自定义属性
+func (fs *MyObjFileSystem) removeProps(ctx context.Context, name string) {
+	if err := fs.factory.WebDAVProperty().DeleteByPath(ctx, fs.user.ID, lock.Path(name)); err != nil {
+		logger.LOG.Warn("WebDAV 清理自定义属性失败", "path", name, "error", err)
+	}
+}
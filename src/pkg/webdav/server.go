@@ -1,34 +1,62 @@
 package webdav
 
 import (
+	"errors"
 	"fmt"
 	"myobj/src/config"
 	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/lock"
 	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/net/webdav"
 )
 
+// auditActions 需要记录审计日志的写操作
+var auditActions = map[string]string{
+	http.MethodPut:    audit.ActionWebDAVPut,
+	http.MethodDelete: audit.ActionWebDAVDelete,
+	"MKCOL":           audit.ActionWebDAVMkcol,
+	"MOVE":            audit.ActionWebDAVMove,
+	"COPY":            audit.ActionWebDAVCopy,
+	"PROPPATCH":       audit.ActionWebDAVProppatch,
+}
+
 // Server WebDAV 服务器
 type Server struct {
 	auth    *Authenticator
 	factory *impl.RepositoryFactory
+	audit   *audit.Recorder
 }
 
 // NewServer 创建 WebDAV 服务器实例
-func NewServer(factory *impl.RepositoryFactory) *Server {
-	auth := NewAuthenticator(
+// cacheStore 用于保存登录失败次数（与 HTTP 服务共用，同一账户在各协议中统一计数）
+func NewServer(factory *impl.RepositoryFactory, cacheStore cache.Cache) *Server {
+	authenticator := NewAuthenticator(
 		factory.ApiKey(),
+		factory.AppPassword(),
 		factory.User(),
 		factory.Power(),
 		factory.SysConfig(),
+		factory.TwoFactor(),
+		factory.Group(),
+		factory.VirtualPath(),
+		factory.UserSession(),
+		auth.NewLoginLimiter(cacheStore, factory.SecurityEvent(), config.CONFIG.Auth.LoginLimit),
 	)
 
 	return &Server{
-		auth:    auth,
+		auth:    authenticator,
 		factory: factory,
+		audit:   audit.NewRecorder(factory.AuditLog(), config.CONFIG.Audit),
 	}
 }
 
@@ -44,15 +72,22 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2. 验证用户
-	user, err := s.auth.Authenticate(username, password)
+	user, appPassword, err := s.auth.Authenticate(username, password, RemoteIP(r.RemoteAddr))
 	if err != nil {
 		logger.LOG.Warn("WebDAV 认证失败",
 			"username", username,
 			"ip", r.RemoteAddr,
 			"error", err,
 		)
+		// 失败次数过多时返回 429 与等待时间，其他原因不向客户端透露具体错误
+		var limitErr *auth.LimitError
+		if errors.As(err, &limitErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int((limitErr.RetryAfter+time.Second-1)/time.Second)))
+			http.Error(w, limitErr.Error(), http.StatusTooManyRequests)
+			return
+		}
 		w.Header().Set("WWW-Authenticate", `Basic realm="MyObj WebDAV"`)
-		http.Error(w, "认证失败: "+err.Error(), http.StatusUnauthorized)
+		http.Error(w, "认证失败", http.StatusUnauthorized)
 		return
 	}
 
@@ -68,13 +103,49 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. 创建用户专属的文件系统
-	fs := NewMyObjFileSystem(user, s.factory)
+	// 4. 创建用户专属的文件系统（应用专用密码限制访问目录与只读），读写参数（文件密码、内容长度）通过请求上下文传递
+	fs, err := NewScopedFileSystem(r.Context(), user, s.factory, appPassword)
+	if err != nil {
+		logger.LOG.Warn("WebDAV 创建文件系统失败", "user_id", user.ID, "error", err)
+		http.Error(w, "无权限访问 WebDAV", http.StatusForbidden)
+		return
+	}
+	opts := &requestOptions{
+		filePassword:  r.Header.Get(FilePasswordHeader),
+		contentLength: r.ContentLength,
+	}
+	r = r.WithContext(withRequestOptions(r.Context(), opts))
 
-	// 5. 创建 WebDAV Handler
+	// 5. LOCK 请求由锁管理器处理（支持共享锁与持久化）
+	if r.Method == "LOCK" {
+		s.handleLock(w, r, user, fs)
+		return
+	}
+	// COPY 请求直接复制文件记录，不经过 x/net/webdav 的逐个读取再上传
+	if r.Method == "COPY" {
+		err := s.handleCopy(w, r, user, fs)
+		s.recordAudit(r, user.ID, user.UserName, appPassword, err)
+		return
+	}
+
+	// 6. 创建 WebDAV Handler
+	// Start 中已通过 StripPrefix 去掉前缀，这里还原完整路径并设置 Handler.Prefix，
+	// 使 PROPFIND 返回的 href 带有前缀，MOVE/COPY 的 Destination 头也能正确解析
+	prefix := davPrefix()
+	u := *r.URL
+	u.Path = prefix + u.Path
+	u.RawPath = ""
+	r.URL = &u
 	handler := &webdav.Handler{
+		Prefix:     prefix,
 		FileSystem: fs,
-		LockSystem: newPermissiveLockSystem(), // 使用自定义锁系统
+		LockSystem: &requestLockSystem{
+			ctx:     r.Context(),
+			userID:  user.ID,
+			manager: lock.NewManager(s.factory),
+			prefix:  prefix,
+			root:    fs.scopeRoot(),
+		},
 		Logger: func(r *http.Request, err error) {
 			if err != nil {
 				logger.LOG.Error("WebDAV 操作错误",
@@ -92,11 +163,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 					"path", r.URL.Path,
 				)
 			}
+			s.recordAudit(r, user.ID, user.UserName, appPassword, err)
 		},
 	}
 
-	// 6. 处理请求
-	handler.ServeHTTP(w, r)
+	// 7. 处理请求（配额/加密错误转换为 507 / 403）
+	handler.ServeHTTP(&errorStatusWriter{ResponseWriter: w, opts: opts}, r)
 }
 
 // Start 启动 WebDAV 服务器
@@ -113,10 +185,7 @@ func (s *Server) Start() error {
 	)
 
 	// 注册处理器
-	prefix := config.CONFIG.WebDAV.Prefix
-	if prefix == "" {
-		prefix = "/dav"
-	}
+	prefix := davPrefix()
 
 	http.Handle(prefix+"/", http.StripPrefix(prefix, s))
 
@@ -133,31 +202,37 @@ func (s *Server) Start() error {
 	return nil
 }
 
-// permissiveLockSystem 允许锁定不存在的文件的自定义锁系统
-type permissiveLockSystem struct {
-	ls webdav.LockSystem
-}
-
-func newPermissiveLockSystem() webdav.LockSystem {
-	return &permissiveLockSystem{
-		ls: webdav.NewMemLS(),
+// davPrefix 获取 WebDAV 路径前缀
+func davPrefix() string {
+	prefix := config.CONFIG.WebDAV.Prefix
+	if prefix == "" {
+		prefix = "/dav"
 	}
+	return prefix
 }
 
-func (pls *permissiveLockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (release func(), err error) {
-	// 总是返回成功，允许所有操作（单用户场景，不需要真正的锁）
-	return func() {}, nil
-}
-
-func (pls *permissiveLockSystem) Create(now time.Time, details webdav.LockDetails) (token string, err error) {
-	// 允许创建锁，即使文件不存在
-	return pls.ls.Create(now, details)
-}
-
-func (pls *permissiveLockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
-	return pls.ls.Refresh(now, token, duration)
-}
-
-func (pls *permissiveLockSystem) Unlock(now time.Time, token string) error {
-	return pls.ls.Unlock(now, token)
+// recordAudit 记录写操作的审计日志（对象为去掉前缀后的路径，MOVE/COPY 附带目标路径）
+func (s *Server) recordAudit(r *http.Request, userID, userName string, appPassword *models.AppPassword, err error) {
+	action, ok := auditActions[r.Method]
+	if !ok {
+		return
+	}
+	prefix := davPrefix()
+	var details []string
+	if destination := r.Header.Get("Destination"); destination != "" {
+		if u, parseErr := url.Parse(destination); parseErr == nil {
+			destination = u.Path
+		}
+		details = append(details, "目标: "+strings.TrimPrefix(destination, prefix))
+	}
+	if appPassword != nil {
+		details = append(details, "应用专用密码: "+appPassword.Name)
+	}
+	actor := audit.Actor{
+		UserID:   userID,
+		UserName: userName,
+		IP:       RemoteIP(r.RemoteAddr),
+		Source:   audit.SourceWebDAV,
+	}
+	s.audit.Record(actor, action, audit.TargetPath, strings.TrimPrefix(r.URL.Path, prefix), strings.Join(details, "; "), err)
 }
@@ -0,0 +1,52 @@
+package webdav
+
+import (
+	"context"
+	"myobj/src/pkg/models"
+	"path"
+	"strconv"
+)
+
+// RequiresEncryption 判断写入指定路径是否命中写入加密策略（上级目录不存在时返回 false）
+// 供无法携带文件密码的协议（SFTP）在写入前拒绝
+func (fs *MyObjFileSystem) RequiresEncryption(ctx context.Context, name string) (bool, error) {
+	dir, err := fs.resolveDir(ctx, path.Dir(fs.cleanPath(name)))
+	if err != nil {
+		return false, nil
+	}
+	return fs.requiresEncryption(ctx, dir)
+}
+
+// requiresEncryption 判断写入目录是否命中写入加密策略（用户级策略，或目录及其上级目录的策略）
+func (fs *MyObjFileSystem) requiresEncryption(ctx context.Context, dir *models.VirtualPath) (bool, error) {
+	policies, err := fs.factory.EncryptPolicy().ListByUserID(ctx, fs.user.ID)
+	if err != nil || len(policies) == 0 {
+		return false, err
+	}
+	dirIDs := make(map[int]bool, len(policies))
+	for _, p := range policies {
+		if p.DirID == 0 {
+			return true, nil
+		}
+		dirIDs[p.DirID] = true
+	}
+
+	current := dir
+	// 限制层级深度，防止异常数据导致死循环
+	for depth := 0; depth < 256; depth++ {
+		if dirIDs[current.ID] {
+			return true, nil
+		}
+		if current.ParentLevel == "" {
+			return false, nil
+		}
+		parentID, err := strconv.Atoi(current.ParentLevel)
+		if err != nil {
+			return false, nil
+		}
+		if current, err = fs.virtualPathRepo.GetByID(ctx, parentID); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
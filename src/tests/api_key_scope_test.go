@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/models"
+	"testing"
+)
+
+// TestAPIKeyIPAllowed 测试 API Key 的 IP 白名单（IP 地址或 CIDR 网段）
+func TestAPIKeyIPAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist string
+		ip        string
+		want      bool
+	}{
+		{"未设置白名单", "", "203.0.113.7", true},
+		{"IP 匹配", "10.0.0.1, 10.0.0.2", "10.0.0.2", true},
+		{"网段匹配", "192.168.1.0/24", "192.168.1.200", true},
+		{"网段外", "192.168.1.0/24", "192.168.2.1", false},
+		{"IPv6 网段", "2001:db8::/32", "2001:db8::1", true},
+		{"无效的来源 IP", "10.0.0.1", "unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auth.IPAllowed(tt.allowlist, tt.ip); got != tt.want {
+				t.Errorf("IPAllowed(%q, %q) = %v, want %v", tt.allowlist, tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	if err := auth.ValidateIPAllowlist([]string{"10.0.0.0/8", "::1"}); err != nil {
+		t.Errorf("合法的白名单校验失败: %v", err)
+	}
+	if err := auth.ValidateIPAllowlist([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("非法网段应校验失败")
+	}
+}
+
+// TestAPIKeyScopeFilterPowers 测试 API Key 权限与用户组权限取交集
+func TestAPIKeyScopeFilterPowers(t *testing.T) {
+	powers := []*models.Power{
+		{Characteristic: "file:upload"},
+		{Characteristic: "file:preview"},
+		{Characteristic: "file:delete"},
+	}
+
+	full := auth.NewAPIKeyScope(&models.ApiKey{ID: 1})
+	if full.Restricted() || len(full.FilterPowers(powers)) != 3 || !full.Allows("file:delete") {
+		t.Error("未设置权限范围时应继承用户组全部权限")
+	}
+
+	scoped := auth.NewAPIKeyScope(&models.ApiKey{ID: 2, Scopes: "file:upload, user:update,file:upload"})
+	if !scoped.Restricted() {
+		t.Error("设置了权限范围时应为受限 API Key")
+	}
+	filtered := scoped.FilterPowers(powers)
+	if len(filtered) != 1 || filtered[0].Characteristic != "file:upload" {
+		t.Errorf("权限交集错误: %v", filtered)
+	}
+	if scoped.Allows("file:delete") {
+		t.Error("未授予的权限不应允许")
+	}
+
+	if !auth.NewAPIKeyScope(&models.ApiKey{ID: 3, RootDirID: 5}).Restricted() {
+		t.Error("限制了访问目录时应为受限 API Key")
+	}
+}
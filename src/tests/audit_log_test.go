@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"myobj/src/config"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryAuditLogRepo 内存中的审计日志仓储
+type memoryAuditLogRepo struct {
+	mu   sync.Mutex
+	logs []*models.AuditLog
+}
+
+func (r *memoryAuditLogRepo) Create(ctx context.Context, log *models.AuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log.ID = len(r.logs) + 1
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *memoryAuditLogRepo) GetLast(ctx context.Context) (*models.AuditLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.logs) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.logs[len(r.logs)-1], nil
+}
+
+func (r *memoryAuditLogRepo) List(ctx context.Context, filter repository.AuditLogFilter, offset, limit int) ([]*models.AuditLog, error) {
+	return nil, nil
+}
+
+func (r *memoryAuditLogRepo) Count(ctx context.Context, filter repository.AuditLogFilter) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int64(len(r.logs)), nil
+}
+
+func (r *memoryAuditLogRepo) ListAfter(ctx context.Context, filter repository.AuditLogFilter, afterID, limit int) ([]*models.AuditLog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*models.AuditLog
+	for _, log := range r.logs {
+		if log.ID > afterID && len(result) < limit {
+			result = append(result, log)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryAuditLogRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// TestAuditHashChain 测试审计日志哈希链的写入与篡改、删除检测
+func TestAuditHashChain(t *testing.T) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	repo := &memoryAuditLogRepo{}
+	actor := audit.Actor{UserID: "u1", UserName: "alice", IP: "10.0.0.1", Source: audit.SourceWeb}
+
+	// 未启用哈希链时写入的记录不参与校验
+	audit.NewRecorder(repo, config.Audit{Enable: true}).Record(actor, audit.ActionUserLogin, audit.TargetUser, "alice", "", nil)
+	recorder := audit.NewRecorder(repo, config.Audit{Enable: true, HashChain: true})
+	recorder.Record(actor, audit.ActionShareCreate, audit.TargetFile, "f1", "", nil)
+	recorder.Record(actor, audit.ActionRecycledDelete, audit.TargetRecycled, "r1", "", errors.New("文件不存在"))
+	recorder.Record(actor, audit.ActionGroupUpdate, audit.TargetGroup, "2", "", nil)
+
+	if repo.logs[2].Result != models.AuditResultFailure || !strings.Contains(repo.logs[2].Detail, "文件不存在") {
+		t.Errorf("失败操作应记录结果与错误信息: %+v", repo.logs[2])
+	}
+	result, err := audit.Verify(ctx, repo)
+	if err != nil || result.BrokenID != 0 || result.Checked != 4 || result.Chained != 3 {
+		t.Fatalf("未篡改时应校验通过: %+v, %v", result, err)
+	}
+
+	// 修改记录内容
+	repo.logs[2].Detail = "tampered"
+	if result, _ := audit.Verify(ctx, repo); result.BrokenID != 3 {
+		t.Errorf("修改记录后应在该记录处校验失败: %+v", result)
+	}
+
+	// 删除中间记录
+	repo.logs = append(repo.logs[:2], repo.logs[3:]...)
+	if result, _ := audit.Verify(ctx, repo); result.BrokenID != 4 {
+		t.Errorf("删除记录后应在下一条记录处校验失败: %+v", result)
+	}
+
+	// 未启用时不记录
+	audit.NewRecorder(repo, config.Audit{}).Record(actor, audit.ActionUserLogin, audit.TargetUser, "alice", "", nil)
+	if n, _ := repo.Count(ctx, repository.AuditLogFilter{}); n != 3 {
+		t.Errorf("未启用时不应写入审计日志, 实际 %d 条", n)
+	}
+}
+
+// TestAuditExportCSV 测试导出 CSV 时转义公式
+func TestAuditExportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := audit.NewExporter(&buf, audit.FormatCSV)
+	if err != nil {
+		t.Fatalf("创建导出器失败: %v", err)
+	}
+	err = exporter.Write([]*models.AuditLog{{ID: 1, Action: audit.ActionFileRename, Detail: "=HYPERLINK(\"x\")"}})
+	if err != nil || exporter.Close() != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	if !strings.Contains(buf.String(), "'=HYPERLINK") {
+		t.Errorf("以 = 开头的内容应转义: %s", buf.String())
+	}
+	if _, err := audit.NewExporter(&buf, "xml"); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"myobj/src/pkg/convert"
+	"myobj/src/pkg/util"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func targetFormats(targets []convert.Target) map[string]bool {
+	formats := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		formats[target.Format] = true
+	}
+	return formats
+}
+
+// TestConvertImage 测试图片格式转换：目标格式排除源格式，透明 PNG 转 JPEG 合成到白色背景
+func TestConvertImage(t *testing.T) {
+	formats := targetFormats(convert.Targets("image/png"))
+	if formats["png"] || !formats["jpeg"] || !formats["gif"] {
+		t.Fatalf("PNG 的目标格式错误: %v", formats)
+	}
+	if formats := targetFormats(convert.Targets("image/x-ms-bmp")); !formats["png"] {
+		t.Errorf("BMP 别名应能转换为 PNG: %v", formats)
+	}
+
+	dir := t.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.NRGBA{R: 255, A: 255})
+	src := filepath.Join(dir, "src.png")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dst := filepath.Join(dir, "dst")
+	target, err := convert.Convert(context.Background(), "image/png", "jpeg", src, dst)
+	if err != nil || target.Ext != ".jpg" {
+		t.Fatalf("转换为 JPEG 失败: %v", err)
+	}
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	decoded, err := jpeg.Decode(out)
+	if err != nil {
+		t.Fatalf("JPEG 解码失败: %v", err)
+	}
+	if r, g, b, _ := decoded.At(3, 3).RGBA(); r>>8 < 240 || g>>8 < 240 || b>>8 < 240 {
+		t.Errorf("透明像素应合成为白色: %d %d %d", r>>8, g>>8, b>>8)
+	}
+	if _, err := convert.Convert(context.Background(), "image/png", "png", src, dst); err != convert.ErrUnsupported {
+		t.Errorf("转换为源格式应返回 ErrUnsupported: %v", err)
+	}
+}
+
+// TestConvertText 测试文本编码转换与 CSV/XLSX 往返转换
+func TestConvertText(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if formats := targetFormats(convert.Targets("text/plain; charset=utf-8")); formats["utf8"] || !formats["gbk"] {
+		t.Errorf("UTF-8 文本的目标格式错误: %v", formats)
+	}
+
+	content := "名称,数量,编号\n苹果,12,007\n\"含,逗号\",,1.5\n"
+	gbk, err := util.EncodeText(content, util.CharsetGBK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(src, gbk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	utf8Path := filepath.Join(dir, "utf8.csv")
+	if _, err := convert.Convert(ctx, "text/csv; charset=gb18030", "utf8", src, utf8Path); err != nil {
+		t.Fatalf("GBK 转 UTF-8 失败: %v", err)
+	}
+	if data, _ := os.ReadFile(utf8Path); string(data) != content {
+		t.Errorf("UTF-8 转换结果错误: %q", data)
+	}
+
+	// GBK 编码的 CSV 转 XLSX 再转回 CSV，内容不变（CSV 带 UTF-8 BOM）
+	cache := convert.NewCache(filepath.Join(dir, "cache"))
+	xlsxPath, target, err := cache.Get(ctx, "hash-csv", "text/csv", "xlsx", src)
+	if err != nil || target.Ext != ".xlsx" {
+		t.Fatalf("CSV 转 XLSX 失败: %v", err)
+	}
+	if again, _, err := cache.Get(ctx, "hash-csv", "text/csv", "xlsx", filepath.Join(dir, "missing.csv")); err != nil || again != xlsxPath {
+		t.Errorf("第二次转换应命中缓存: %v", err)
+	}
+	csvPath := filepath.Join(dir, "back.csv")
+	if _, err := convert.Convert(ctx, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "csv", xlsxPath, csvPath); err != nil {
+		t.Fatalf("XLSX 转 CSV 失败: %v", err)
+	}
+	if data, _ := os.ReadFile(csvPath); string(data) != "\xEF\xBB\xBF"+content {
+		t.Errorf("往返转换结果错误: %q", data)
+	}
+
+	if removed, err := cache.Cleanup(0); err != nil || removed != 1 {
+		t.Errorf("清理缓存结果错误: %d, %v", removed, err)
+	}
+}
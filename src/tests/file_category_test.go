@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/task"
+	"myobj/src/pkg/util"
+	"testing"
+	"time"
+)
+
+// TestFileCategory 测试按 MIME 类型与文件名划分文件分类
+func TestFileCategory(t *testing.T) {
+	cases := []struct {
+		name, mime, want string
+	}{
+		{"a.jpg", "image/jpeg", util.CategoryImage},
+		{"a.svg", "image/svg+xml", util.CategoryImage},
+		{"a.mp4", "video/mp4", util.CategoryVideo},
+		{"a.ts", "video/mp2t", util.CategoryVideo},
+		{"a.flac", "audio/flac", util.CategoryAudio},
+		{"a.pdf", "application/pdf", util.CategoryDocument},
+		{"a.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", util.CategoryDocument},
+		{"a.txt", "text/plain; charset=utf-8", util.CategoryDocument},
+		{"a.csv", "text/csv", util.CategoryDocument},
+		{"a.zip", "application/zip", util.CategoryArchive},
+		{"a.tar.gz", "application/gzip", util.CategoryArchive},
+		{"a.jar", "application/jar", util.CategoryArchive},
+		{"a.py", "text/x-python", util.CategoryCode},
+		{"a.json", "application/json", util.CategoryCode},
+		{"main.go", "text/plain; charset=utf-8", util.CategoryCode},
+		{"a.exe", "application/vnd.microsoft.portable-executable", util.CategoryOther},
+		{"a.bin", "application/octet-stream", util.CategoryOther},
+	}
+	for _, c := range cases {
+		if got := util.FileCategory(c.name, c.mime); got != c.want {
+			t.Errorf("%s (%s): 期望 %s，实际 %s", c.name, c.mime, c.want, got)
+		}
+	}
+	if util.NormalizeFileCategory("doc") != util.CategoryDocument || util.NormalizeFileCategory("Code") != util.CategoryCode ||
+		util.NormalizeFileCategory("unknown") != "" {
+		t.Error("分类名称规范化错误")
+	}
+}
+
+// TestCategoryStats 测试分类统计与未分类文件的补齐（setupRecycleDB 已创建 a.txt、b.txt 两个未划分分类的文件）
+func TestCategoryStats(t *testing.T) {
+	factory, dirs := setupRecycleDB(t)
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	createFilterFile(t, factory, "p1", "photo.jpg", "image/jpeg", 3000, false, false, base, dirs["docs"])
+	createFilterFile(t, factory, "p2", "main.go", "text/plain; charset=utf-8", 50, false, false, base, dirs["sub"])
+	createFilterFile(t, factory, "p3", "photo2.png", "image/png", 1000, false, false, base, dirs["sub"])
+	// 已删除的文件不计入统计
+	if err := factory.UserFiles().Delete(ctx, recycleUser, "p3"); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+
+	stats, err := factory.UserFiles().StatByCategory(ctx, recycleUser)
+	if err != nil {
+		t.Fatalf("统计分类失败: %v", err)
+	}
+	got := make(map[string]repository.CategoryStat)
+	for _, stat := range stats {
+		got[stat.Category] = *stat
+	}
+	want := map[string]repository.CategoryStat{
+		util.CategoryImage: {Category: util.CategoryImage, Count: 1, Size: 3000},
+		util.CategoryCode:  {Category: util.CategoryCode, Count: 1, Size: 50},
+		util.CategoryOther: {Category: util.CategoryOther, Count: 2, Size: 300},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("统计结果错误: %v", got)
+	}
+	for category, stat := range want {
+		if got[category] != stat {
+			t.Errorf("%s: 期望 %v，实际 %v", category, stat, got[category])
+		}
+	}
+
+	if err := task.NewFileCategoryTask(factory).BackfillCategories(); err != nil {
+		t.Fatalf("补齐分类失败: %v", err)
+	}
+	if files, err := factory.FileInfo().ListUncategorized(ctx, 10); err != nil || len(files) != 0 {
+		t.Fatalf("补齐后仍有未分类文件: %d, %v", len(files), err)
+	}
+	fileInfo, err := factory.FileInfo().GetByID(ctx, "fi-f1")
+	if err != nil || fileInfo.Category != util.CategoryOther {
+		t.Fatalf("补齐的分类错误: %v", err)
+	}
+	if ids := filterIDs(t, factory, repository.FileFilter{Categories: []string{util.CategoryCode}}); len(ids) != 1 || ids[0] != "p2" {
+		t.Errorf("按分类查询错误: %v", ids)
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/filecopy"
+	"myobj/src/pkg/models"
+	"strconv"
+	"testing"
+)
+
+// copyUser 复制测试的用户
+const copyUser = "copy-user"
+
+// setupCopyDB 创建复制测试数据：用户空间 1000、剩余 700，根目录下 /docs（a.txt，100），/docs 下 /sub（b.txt，200）
+func setupCopyDB(t *testing.T) (*impl.RepositoryFactory, map[string]*models.VirtualPath) {
+	old := config.CONFIG
+	t.Cleanup(func() { config.CONFIG = old })
+	config.CONFIG = &config.MyObjConfig{File: config.File{CopyQuota: filecopy.QuotaFull, CopyAsyncThreshold: 200}}
+	factory := openFileDB(t, &models.UserInfo{})
+	dirs := map[string]*models.VirtualPath{}
+	dirs["root"] = createTestDir(t, factory, copyUser, "home", nil)
+	dirs["docs"] = createTestDir(t, factory, copyUser, "/docs", dirs["root"])
+	dirs["sub"] = createTestDir(t, factory, copyUser, "/sub", dirs["docs"])
+	createTestFile(t, factory, copyUser, "f1", "a.txt", dirs["docs"], 100)
+	createTestFile(t, factory, copyUser, "f2", "b.txt", dirs["sub"], 200)
+	user := &models.UserInfo{ID: copyUser, Name: "test", UserName: "test", CreatedAt: custom_type.Now(), Space: 1000, FreeSpace: 700}
+	if err := factory.User().Create(context.Background(), user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return factory, dirs
+}
+
+func copySources(factory *impl.RepositoryFactory, sources []filecopy.Source, targetID int, conflict string) (*filecopy.Result, error) {
+	copier := filecopy.NewCopier(factory, copyUser)
+	plan, err := copier.Plan(context.Background(), sources, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return copier.Copy(context.Background(), plan, conflict)
+}
+
+func freeSpace(t *testing.T, factory *impl.RepositoryFactory) int64 {
+	user, err := factory.User().GetByID(context.Background(), copyUser)
+	if err != nil {
+		t.Fatalf("获取用户失败: %v", err)
+	}
+	return user.FreeSpace
+}
+
+// TestCopyDirTree 测试目录树复制：按原层级新建目录，文件记录指向同一文件信息并扣除配额
+func TestCopyDirTree(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupCopyDB(t)
+
+	if _, err := copySources(factory, []filecopy.Source{{DirID: dirs["docs"].ID}}, dirs["sub"].ID, ""); !errors.Is(err, filecopy.ErrIntoSelf) {
+		t.Errorf("复制到自身子目录中应返回 ErrIntoSelf: %v", err)
+	}
+
+	// 复制到原位置时与源目录重名，重命名为 "docs (1)"
+	result, err := copySources(factory, []filecopy.Source{{DirID: dirs["docs"].ID}}, dirs["root"].ID, "")
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if result.Files != 2 || result.Dirs != 2 || result.Size != 300 || result.Renamed != 1 || result.Items[0].Name != "docs (1)" {
+		t.Fatalf("复制结果错误: %+v", result)
+	}
+	copied := result.Items[0].DirID
+	files, err := factory.UserFiles().ListByVirtualPath(ctx, copyUser, strconv.Itoa(copied), 0, 10)
+	if err != nil || len(files) != 1 || files[0].FileName != "a.txt" || files[0].FileID != "fi-f1" || files[0].UfID == "f1" {
+		t.Fatalf("复制的文件应新建记录并指向同一文件信息: %v", err)
+	}
+	subs, err := factory.VirtualPath().ListSubFoldersByParentID(ctx, copyUser, copied, 0, 10)
+	if err != nil || len(subs) != 1 || subs[0].Path != "/sub" {
+		t.Fatalf("子目录应按原层级复制: %v", err)
+	}
+	if names := liveFileNames(t, factory, copyUser, subs[0].ID); len(names) != 1 || names[0] != "b.txt" {
+		t.Errorf("子目录中的文件应已复制: %v", names)
+	}
+	if free := freeSpace(t, factory); free != 400 {
+		t.Errorf("复制后应扣除 300 配额: %d", free)
+	}
+	// 修改原文件不能影响共享同一文件信息的副本
+	original, err := factory.UserFiles().GetByUserIDAndUfID(ctx, copyUser, "f1")
+	if err != nil {
+		t.Fatalf("获取原文件失败: %v", err)
+	}
+	original.FileName = "renamed.txt"
+	if err := factory.UserFiles().Update(ctx, original); err != nil {
+		t.Fatalf("更新原文件失败: %v", err)
+	}
+	if names := liveFileNames(t, factory, copyUser, copied); len(names) != 1 || names[0] != "a.txt" {
+		t.Errorf("副本不应随原文件更新: %v", names)
+	}
+	// 原文件仍被复制出的文件引用，永久删除时不能删除物理文件
+	if count, _ := factory.Recycled().CountSharedReferences(ctx, "fi-f1", "f1"); count != 1 {
+		t.Errorf("共享引用数错误: %d", count)
+	}
+}
+
+// TestCopyFileConflict 测试同名文件的跳过、覆盖与配额不足
+func TestCopyFileConflict(t *testing.T) {
+	factory, dirs := setupCopyDB(t)
+	docs := dirs["docs"].ID
+
+	result, err := copySources(factory, []filecopy.Source{{FileID: "f1"}}, docs, filecopy.ConflictSkip)
+	if err != nil || result.Skipped != 1 || result.Files != 0 {
+		t.Fatalf("同名文件应跳过: %+v, %v", result, err)
+	}
+	// 同名文件就是源文件本身时不能覆盖，改为重命名
+	result, err = copySources(factory, []filecopy.Source{{FileID: "f1"}}, docs, filecopy.ConflictOverwrite)
+	if err != nil || result.Renamed != 1 || result.Items[0].Name != "a (1).txt" {
+		t.Fatalf("覆盖源文件本身时应重命名: %+v, %v", result, err)
+	}
+	// 将 b.txt 以 a.txt 为名复制到 docs，原 a.txt 移入回收站
+	result, err = copySources(factory, []filecopy.Source{{FileID: "f2", Name: "a.txt"}}, docs, filecopy.ConflictOverwrite)
+	if err != nil || result.Overwritten != 1 {
+		t.Fatalf("覆盖结果错误: %+v, %v", result, err)
+	}
+	if names := liveFileNames(t, factory, copyUser, docs); len(names) != 2 || names[0] != "a (1).txt" || names[1] != "a.txt" {
+		t.Errorf("覆盖后的文件列表错误: %v", names)
+	}
+	if count, _ := factory.Recycled().Count(context.Background(), copyUser); count != 1 {
+		t.Errorf("被覆盖的文件应移入回收站: %d", count)
+	}
+	if free := freeSpace(t, factory); free != 400 {
+		t.Errorf("跳过的文件不应扣除配额: %d", free)
+	}
+
+	if _, err := copySources(factory, []filecopy.Source{{DirID: docs}}, dirs["root"].ID, ""); !errors.Is(err, filecopy.ErrInsufficientSpace) {
+		t.Errorf("空间不足时应返回 ErrInsufficientSpace: %v", err)
+	}
+	config.CONFIG.File.CopyQuota = filecopy.QuotaNone
+	if _, err := copySources(factory, []filecopy.Source{{DirID: docs}}, dirs["root"].ID, ""); err != nil {
+		t.Errorf("不占用配额时应能复制: %v", err)
+	}
+	if free := freeSpace(t, factory); free != 400 {
+		t.Errorf("不占用配额时不应扣除配额: %d", free)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/util"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// createFilterFile 创建指定类型、大小与时间的用户文件
+func createFilterFile(t *testing.T, factory *impl.RepositoryFactory, id, name, mime string, size int, enc, public bool, created time.Time, dir *models.VirtualPath) {
+	ctx := context.Background()
+	if err := factory.FileInfo().Create(ctx, &models.FileInfo{ID: "fi-" + id, Name: name, Mime: mime, Category: util.FileCategory(name, mime), Size: size, IsEnc: enc,
+		CreatedAt: custom_type.JsonTime(created), UpdatedAt: custom_type.JsonTime(created.Add(time.Hour))}); err != nil {
+		t.Fatalf("创建文件信息失败: %v", err)
+	}
+	if err := factory.UserFiles().Create(ctx, &models.UserFiles{UserID: recycleUser, FileID: "fi-" + id, FileName: name,
+		VirtualPath: strconv.Itoa(dir.ID), IsPublic: public, CreatedAt: custom_type.JsonTime(created), UfID: id}); err != nil {
+		t.Fatalf("创建用户文件失败: %v", err)
+	}
+}
+
+func filterIDs(t *testing.T, factory *impl.RepositoryFactory, filter repository.FileFilter) []string {
+	filter.UserID = recycleUser
+	files, _, err := factory.UserFiles().ListByFilter(context.Background(), filter,
+		[]repository.FileSort{{Field: repository.FileSortName}}, nil, 0, 100)
+	if err != nil {
+		t.Fatalf("查询文件失败: %v", err)
+	}
+	count, err := factory.UserFiles().CountByFilter(context.Background(), filter)
+	if err != nil || count != int64(len(files)) {
+		t.Fatalf("统计数量与列表不一致: %d, %d, %v", count, len(files), err)
+	}
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.UfID)
+	}
+	return ids
+}
+
+// TestFileFilter 测试结构化过滤条件（setupRecycleDB 已创建 a.txt、b.txt 两个未划分分类的文件）
+func TestFileFilter(t *testing.T) {
+	factory, dirs := setupRecycleDB(t)
+	if err := factory.DB().AutoMigrate(&models.Share{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	createFilterFile(t, factory, "p1", "photo.JPG", "image/jpeg", 3000, false, true, base, dirs["docs"])
+	createFilterFile(t, factory, "p2", "report.pdf", "application/pdf", 5000, true, false, base.AddDate(0, 1, 0), dirs["docs"])
+	createFilterFile(t, factory, "p3", "backup.zip", "application/zip", 9000, false, false, base.AddDate(0, 2, 0), dirs["sub"])
+	createFilterFile(t, factory, "p4", "notes.txt", "text/plain", 10, false, true, base.AddDate(0, 3, 0), dirs["sub"])
+	if err := factory.Share().Create(context.Background(), &models.Share{UserID: recycleUser, FileID: "fi-p3", Token: "t1",
+		ExpiresAt: custom_type.JsonTime(time.Now().Add(time.Hour)), CreatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+	yes, no := true, false
+	start, end := base.AddDate(0, 1, 0), base.AddDate(0, 2, 0)
+
+	cases := []struct {
+		name   string
+		filter repository.FileFilter
+		want   []string
+	}{
+		{"分类", repository.FileFilter{Categories: []string{"document", "archive"}}, []string{"p3", "p4", "p2"}},
+		{"未分类的文件属于其他", repository.FileFilter{Categories: []string{"other"}}, []string{"f1", "f2"}},
+		{"扩展名不区分大小写", repository.FileFilter{Exts: []string{"jpg", "txt"}}, []string{"f1", "f2", "p4", "p1"}},
+		{"大小范围", repository.FileFilter{MinSize: 3000, MaxSize: 9000}, []string{"p3", "p1", "p2"}},
+		{"创建时间", repository.FileFilter{CreatedStart: &start, CreatedEnd: &end}, []string{"p3", "p2"}},
+		{"修改时间", repository.FileFilter{UpdatedStart: &end}, []string{"f1", "f2", "p3", "p4"}},
+		{"加密", repository.FileFilter{Encrypted: &yes}, []string{"p2"}},
+		{"公开", repository.FileFilter{Public: &yes}, []string{"p4", "p1"}},
+		{"已分享", repository.FileFilter{Shared: &yes}, []string{"p3"}},
+		{"未分享且未加密", repository.FileFilter{Shared: &no, Encrypted: &no}, []string{"f1", "f2", "p4", "p1"}},
+		{"关键词与目录", repository.FileFilter{Keyword: "o", VirtualPaths: []string{strconv.Itoa(dirs["sub"].ID)}}, []string{"p4"}},
+	}
+	for _, c := range cases {
+		if ids := filterIDs(t, factory, c.filter); !reflect.DeepEqual(ids, c.want) {
+			t.Errorf("%s: 期望 %v，实际 %v", c.name, c.want, ids)
+		}
+	}
+}
+
+// TestFileFilterCursor 测试多字段排序与游标分页：每个文件恰好出现一次且顺序与一次查询全部一致
+func TestFileFilterCursor(t *testing.T) {
+	factory, dirs := setupRecycleDB(t)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 9; i++ {
+		// 大小与时间都有重复，依赖 uf_id 保证顺序稳定
+		createFilterFile(t, factory, "c"+strconv.Itoa(i), "file"+strconv.Itoa(i%3)+".txt", "text/plain",
+			100*(i%2), false, false, base.Add(time.Duration(i%4)*time.Minute+time.Duration(i)*time.Microsecond), dirs["docs"])
+	}
+	filter := repository.FileFilter{UserID: recycleUser}
+	for _, sorts := range [][]repository.FileSort{
+		{{Field: repository.FileSortSize, Desc: true}, {Field: repository.FileSortName}},
+		{{Field: repository.FileSortCreated, Desc: true}},
+		{{Field: repository.FileSortUpdated}, {Field: repository.FileSortType}},
+	} {
+		all, next, err := factory.UserFiles().ListByFilter(context.Background(), filter, sorts, nil, 0, 100)
+		if err != nil || next != nil || len(all) != 11 {
+			t.Fatalf("查询全部文件失败: %d, %v, %v", len(all), next, err)
+		}
+		var paged []*models.UserFiles
+		var cursor *repository.FileCursor
+		for page := 0; page < 10; page++ {
+			files, next, err := factory.UserFiles().ListByFilter(context.Background(), filter, sorts, cursor, 0, 2)
+			if err != nil {
+				t.Fatalf("游标分页失败: %v", err)
+			}
+			paged = append(paged, files...)
+			if next == nil {
+				break
+			}
+			cursor = next
+		}
+		if len(paged) != len(all) {
+			t.Fatalf("排序 %v: 游标分页数量错误: %d", sorts, len(paged))
+		}
+		for i := range all {
+			if paged[i].UfID != all[i].UfID {
+				t.Fatalf("排序 %v: 第 %d 个文件不一致: %s, %s", sorts, i, paged[i].UfID, all[i].UfID)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// openFileDB 创建内存数据库中的文件相关表（目录、用户文件、文件信息、回收站），extra 为各测试额外需要的表
+func openFileDB(t *testing.T, extra ...interface{}) *impl.RepositoryFactory {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	if err := db.AutoMigrate(append([]interface{}{&models.VirtualPath{}, &models.Recycled{}, &models.FileInfo{}}, extra...)...); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	// user_files 与 sql 脚本一致，deleted_at 允许为空
+	if err := db.Exec(`CREATE TABLE user_files (user_id VARCHAR NOT NULL, file_id VARCHAR NOT NULL, file_name TEXT,
+		virtual_path TEXT, public BOOLEAN NOT NULL, created_at DATETIME NOT NULL, deleted_at DATETIME, uf_id VARCHAR(64))`).Error; err != nil {
+		t.Fatalf("failed to create user_files: %v", err)
+	}
+	return impl.NewRepositoryFactory(db)
+}
+
+// createTestDir 创建虚拟目录，parent 为空时创建用户根目录
+func createTestDir(t *testing.T, factory *impl.RepositoryFactory, userID, path string, parent *models.VirtualPath) *models.VirtualPath {
+	dir := &models.VirtualPath{UserID: userID, Path: path, IsDir: true, CreatedTime: custom_type.Now(), UpdateTime: custom_type.Now()}
+	if parent != nil {
+		dir.ParentLevel = strconv.Itoa(parent.ID)
+	}
+	if err := factory.VirtualPath().Create(context.Background(), dir); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	return dir
+}
+
+// createTestFile 创建文件信息 "fi-{id}" 与指向它的用户文件 id
+func createTestFile(t *testing.T, factory *impl.RepositoryFactory, userID, id, name string, dir *models.VirtualPath, size int) {
+	ctx := context.Background()
+	if err := factory.FileInfo().Create(ctx, &models.FileInfo{ID: "fi-" + id, Name: name, Size: size, CreatedAt: custom_type.Now(), UpdatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建文件信息失败: %v", err)
+	}
+	if err := factory.UserFiles().Create(ctx, &models.UserFiles{UserID: userID, FileID: "fi-" + id, FileName: name,
+		VirtualPath: strconv.Itoa(dir.ID), CreatedAt: custom_type.Now(), UfID: id}); err != nil {
+		t.Fatalf("创建用户文件失败: %v", err)
+	}
+}
+
+// liveFileNames 目录下未删除的文件名
+func liveFileNames(t *testing.T, factory *impl.RepositoryFactory, userID string, dirID int) []string {
+	files, err := factory.UserFiles().ListByVirtualPath(context.Background(), userID, strconv.Itoa(dirID), 0, 100)
+	if err != nil {
+		t.Fatalf("查询文件失败: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.FileName)
+	}
+	sort.Strings(names)
+	return names
+}
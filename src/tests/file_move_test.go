@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/filemove"
+	"strconv"
+	"testing"
+)
+
+func moveSources(factory *impl.RepositoryFactory, sources []filemove.Source, targetID int, conflict string) (*filemove.Result, error) {
+	mover := filemove.NewMover(factory, recycleUser)
+	plan, err := mover.Plan(context.Background(), sources, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return mover.Move(context.Background(), plan, conflict)
+}
+
+// TestMoveBatch 测试目录整体移动、循环检测与同名冲突
+func TestMoveBatch(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupRecycleDB(t)
+	other := createTestDir(t, factory, recycleUser, "/other", dirs["root"])
+	createTestFile(t, factory, recycleUser, "f3", "a.txt", other, 50)
+
+	if _, err := moveSources(factory, []filemove.Source{{DirID: dirs["docs"].ID}}, dirs["sub"].ID, ""); !errors.Is(err, filemove.ErrIntoSelf) {
+		t.Errorf("移动到自身子目录中应返回 ErrIntoSelf: %v", err)
+	}
+	if _, err := moveSources(factory, []filemove.Source{{DirID: dirs["root"].ID}}, other.ID, ""); !errors.Is(err, filemove.ErrRoot) {
+		t.Errorf("移动根目录应返回 ErrRoot: %v", err)
+	}
+
+	// 存在同名文件且未指定处理方式时整批不移动
+	sources := []filemove.Source{{DirID: dirs["sub"].ID}, {FileID: "f1"}}
+	if _, err := moveSources(factory, sources, other.ID, ""); !errors.Is(err, filemove.ErrConflict) {
+		t.Fatalf("同名冲突应返回 ErrConflict: %v", err)
+	}
+	if subs, _ := factory.VirtualPath().ListSubFoldersByParentID(ctx, recycleUser, dirs["docs"].ID, 0, 10); len(subs) != 1 {
+		t.Fatalf("冲突时不应移动任何目录")
+	}
+
+	result, err := moveSources(factory, sources, other.ID, filemove.ConflictRename)
+	if err != nil || result.Dirs != 1 || result.Files != 1 || result.Renamed != 1 {
+		t.Fatalf("移动结果错误: %+v, %v", result, err)
+	}
+	if names := liveFileNames(t, factory, recycleUser, other.ID); len(names) != 2 || names[0] != "a (1).txt" || names[1] != "a.txt" {
+		t.Errorf("重命名后的文件列表错误: %v", names)
+	}
+	// 目录只修改上级目录，其中的文件随之移动
+	sub, err := factory.VirtualPath().GetByID(ctx, dirs["sub"].ID)
+	if err != nil || sub.ParentLevel != strconv.Itoa(other.ID) {
+		t.Fatalf("目录应移动到目标目录下: %v", err)
+	}
+	if names := liveFileNames(t, factory, recycleUser, sub.ID); len(names) != 1 || names[0] != "b.txt" {
+		t.Errorf("目录中的文件应随目录移动: %v", names)
+	}
+}
+
+// TestMoveOverwriteMerge 测试覆盖模式下同名目录合并、同名文件移入回收站
+func TestMoveOverwriteMerge(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupRecycleDB(t)
+	other := createTestDir(t, factory, recycleUser, "/other", dirs["root"])
+	otherSub := createTestDir(t, factory, recycleUser, "/sub", other)
+	createTestFile(t, factory, recycleUser, "f3", "b.txt", otherSub, 50)
+
+	result, err := moveSources(factory, []filemove.Source{{DirID: dirs["sub"].ID}}, other.ID, filemove.ConflictOverwrite)
+	if err != nil || result.Merged != 1 || result.Overwritten != 1 || result.Items[0].DirID != otherSub.ID {
+		t.Fatalf("合并结果错误: %+v, %v", result, err)
+	}
+	files, err := factory.UserFiles().ListByVirtualPath(ctx, recycleUser, strconv.Itoa(otherSub.ID), 0, 10)
+	if err != nil || len(files) != 1 || files[0].UfID != "f2" {
+		t.Fatalf("同名文件应被覆盖: %v", err)
+	}
+	if count, _ := factory.Recycled().Count(ctx, recycleUser); count != 1 {
+		t.Errorf("被覆盖的文件应移入回收站: %d", count)
+	}
+	if _, err := factory.VirtualPath().GetByID(ctx, dirs["sub"].ID); err == nil {
+		t.Errorf("合并后的源目录应已删除")
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"myobj/src/config"
+	"myobj/src/core/domain/request"
+	"myobj/src/core/domain/response"
+	"myobj/src/core/service"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/audit"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/filecopy"
+	"myobj/src/pkg/hash"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestTextCharset 测试文本编码的检测与编解码
+func TestTextCharset(t *testing.T) {
+	text := "文本编辑 text"
+	for _, charset := range []string{util.CharsetUTF8, util.CharsetUTF8BOM, util.CharsetUTF16LE, util.CharsetUTF16BE, util.CharsetGB18030} {
+		data, err := util.EncodeText(text, charset)
+		if err != nil {
+			t.Fatalf("%s 编码失败: %v", charset, err)
+		}
+		detected, err := util.DetectCharset(data)
+		if err != nil || detected != charset {
+			t.Errorf("%s: 检测结果 %s, %v", charset, detected, err)
+		}
+		if decoded, err := util.DecodeText(data, detected); err != nil || decoded != text {
+			t.Errorf("%s: 解码结果 %q, %v", charset, decoded, err)
+		}
+	}
+	// 截断在字符中间的 UTF-8 内容仍按 UTF-8 识别
+	if charset, _ := util.DetectCharset([]byte(text)[:4]); charset != util.CharsetUTF8 {
+		t.Errorf("截断的 UTF-8 内容检测为 %s", charset)
+	}
+	if _, err := util.DetectCharset([]byte{0x89, 'P', 'N', 'G', 0x00}); !errors.Is(err, util.ErrNotText) {
+		t.Errorf("二进制内容应返回 ErrNotText: %v", err)
+	}
+	if _, err := util.EncodeText("😀", util.CharsetGBK); err == nil {
+		t.Error("GBK 无法表示的字符应返回错误")
+	}
+}
+
+// setupTextDB 在复制测试数据的基础上创建存储磁盘与 GBK 编码的文本文件 t1（uf_id）
+func setupTextDB(t *testing.T) (*impl.RepositoryFactory, *service.FileService, *models.FileInfo) {
+	factory, dirs := setupCopyDB(t)
+	old := config.CONFIG
+	t.Cleanup(func() { config.CONFIG = old })
+	config.CONFIG = &config.MyObjConfig{File: config.File{CopyQuota: filecopy.QuotaFull, BigFileThreshold: 1, BigChunkSize: 1, TextEditMaxSize: 1}}
+	if err := factory.DB().AutoMigrate(&models.Disk{}, &models.FileChunk{}, &models.Share{}, &models.WebDAVLock{},
+		&models.SearchContent{}, &models.SearchDoc{}, &models.SearchPosting{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+	dataPath := t.TempDir()
+	if err := factory.Disk().Create(ctx, &models.Disk{ID: "d1", Size: 10, DiskPath: dataPath, DataPath: dataPath}); err != nil {
+		t.Fatalf("创建磁盘失败: %v", err)
+	}
+
+	data, err := util.EncodeText("第一行\n第二行\n", util.CharsetGBK)
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	filePath := filepath.Join(dataPath, "notes.data")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	fileHash, _, err := hash.NewFastBlake3Hasher().ComputeFileHash(filePath)
+	if err != nil {
+		t.Fatalf("计算hash失败: %v", err)
+	}
+	fileInfo := &models.FileInfo{ID: "fi-t1", Name: "notes.txt", Size: len(data), Mime: "text/plain", Path: filePath,
+		FileHash: fileHash, HasFullHash: true, CreatedAt: custom_type.Now(), UpdatedAt: custom_type.Now()}
+	if err := factory.FileInfo().Create(ctx, fileInfo); err != nil {
+		t.Fatalf("创建文件信息失败: %v", err)
+	}
+	if err := factory.UserFiles().Create(ctx, &models.UserFiles{UserID: copyUser, FileID: fileInfo.ID, FileName: "notes.txt",
+		VirtualPath: strconv.Itoa(dirs["docs"].ID), CreatedAt: custom_type.Now(), UfID: "t1"}); err != nil {
+		t.Fatalf("创建用户文件失败: %v", err)
+	}
+	return factory, service.NewFileService(factory, nil), fileInfo
+}
+
+func readText(t *testing.T, svc *service.FileService, ufID string) *response.TextFileResponse {
+	res, err := svc.ReadTextFile(&request.TextFileReadRequest{FileID: ufID}, copyUser)
+	if err != nil || res.Code != 200 {
+		t.Fatalf("读取文本文件失败: %v, %+v", err, res)
+	}
+	return res.Data.(*response.TextFileResponse)
+}
+
+func saveText(t *testing.T, svc *service.FileService, ufID, content, etag string) *models.JsonResponse {
+	res, err := svc.SaveTextFile(&request.TextFileSaveRequest{FileID: ufID, Content: content, ETag: etag, Charset: util.CharsetGBK},
+		copyUser, audit.Actor{})
+	if err != nil {
+		t.Fatalf("保存文本文件失败: %v", err)
+	}
+	return res
+}
+
+// TestSaveTextFile 测试保存文本文件：未共享时原地替换内容，与其他用户文件共享时写时复制，etag 过期时拒绝保存
+func TestSaveTextFile(t *testing.T) {
+	ctx := context.Background()
+	factory, svc, original := setupTextDB(t)
+
+	text := readText(t, svc, "t1")
+	if text.Charset != util.CharsetGB18030 || text.Content != "第一行\n第二行\n" || text.ETag != original.FileHash {
+		t.Fatalf("读取结果错误: %+v", text)
+	}
+
+	// 未共享：原地替换，文件信息ID不变，旧的存储文件被删除
+	res := saveText(t, svc, "t1", "第一行\n修改后\n", text.ETag)
+	saved, ok := res.Data.(*response.TextFileSaveResponse)
+	if res.Code != 200 || !ok || saved.Copied {
+		t.Fatalf("原地保存结果错误: %+v", res)
+	}
+	fileInfo, err := factory.FileInfo().GetByID(ctx, original.ID)
+	if err != nil || fileInfo.FileHash != saved.ETag || fileInfo.Path == original.Path {
+		t.Fatalf("文件信息应原地更新: %v", err)
+	}
+	if _, err := os.Stat(original.Path); !os.IsNotExist(err) {
+		t.Errorf("旧的存储文件应已删除: %v", err)
+	}
+	if free := freeSpace(t, factory); free != 700+int64(original.Size-fileInfo.Size) {
+		t.Errorf("剩余空间应按内容大小变化调整: %d", free)
+	}
+	if res := saveText(t, svc, "t1", "过期的修改", text.ETag); res.Code != 409 {
+		t.Errorf("etag 过期时应返回 409: %+v", res)
+	}
+
+	// 与 t2 共享同一文件信息：写时复制，只有 t1 指向新的文件信息
+	if err := factory.UserFiles().Create(ctx, &models.UserFiles{UserID: copyUser, FileID: original.ID, FileName: "copy.txt",
+		VirtualPath: "1", CreatedAt: custom_type.Now(), UfID: "t2"}); err != nil {
+		t.Fatalf("创建用户文件失败: %v", err)
+	}
+	res = saveText(t, svc, "t1", "只修改副本\n", saved.ETag)
+	if copied, ok := res.Data.(*response.TextFileSaveResponse); res.Code != 200 || !ok || !copied.Copied {
+		t.Fatalf("写时复制结果错误: %+v", res)
+	}
+	t1, err := factory.UserFiles().GetByUserIDAndUfID(ctx, copyUser, "t1")
+	if err != nil || t1.FileID == original.ID {
+		t.Fatalf("t1 应指向新的文件信息: %v", err)
+	}
+	if text := readText(t, svc, "t1"); text.Content != "只修改副本\n" {
+		t.Errorf("t1 内容错误: %q", text.Content)
+	}
+	if text := readText(t, svc, "t2"); text.Content != "第一行\n修改后\n" || text.ETag != saved.ETag {
+		t.Errorf("共享的 t2 内容不应改变: %q", text.Content)
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/preview"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestPNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeOrientedJPEG 写入带 EXIF 方向标签的 JPEG（APP1 段插入在 SOI 之后）
+func writeOrientedJPEG(t *testing.T, path string, w, h int, orientation uint16) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x01\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], orientation)
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), append(entry, 0, 0, 0, 0)...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+
+	data := buf.Bytes()
+	out := append(append(append([]byte{}, data[:2]...), app1...), payload...)
+	out = append(out, data[2:]...)
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func decodedSize(t *testing.T, path string) (int, int) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("解码衍生图失败: %v", err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+// TestImageDerivativeResize 测试 contain/cover 缩放尺寸、不放大原图以及 EXIF 方向校正
+func TestImageDerivativeResize(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.png")
+	writeTestPNG(t, src, 400, 200)
+
+	cases := []struct {
+		opts   preview.DerivativeOptions
+		w, h   int
+		reason string
+	}{
+		{preview.DerivativeOptions{Width: 100, Height: 100, Fit: preview.FitContain}, 100, 50, "contain 保持宽高比"},
+		{preview.DerivativeOptions{Width: 100, Height: 100, Fit: preview.FitCover}, 100, 100, "cover 裁剪为目标尺寸"},
+		{preview.DerivativeOptions{Height: 50}, 100, 50, "只指定高度时等比缩放"},
+		{preview.DerivativeOptions{Width: 1024}, 400, 200, "不放大原图"},
+	}
+	for _, tc := range cases {
+		tc.opts.Format = preview.FormatPNG
+		out := filepath.Join(dir, tc.opts.Key()+tc.opts.Ext())
+		if err := preview.GenerateImageDerivative(ctx, src, out, tc.opts); err != nil {
+			t.Fatalf("%s: %v", tc.reason, err)
+		}
+		if w, h := decodedSize(t, out); w != tc.w || h != tc.h {
+			t.Errorf("%s: 期望 %dx%d，实际 %dx%d", tc.reason, tc.w, tc.h, w, h)
+		}
+	}
+
+	// 方向 6（顺时针旋转 90 度）的横向原图校正后为竖向
+	oriented := filepath.Join(dir, "oriented.jpg")
+	writeOrientedJPEG(t, oriented, 200, 100, 6)
+	if o := preview.ReadOrientation(oriented); o != 6 {
+		t.Fatalf("读取 EXIF 方向错误: %d", o)
+	}
+	out := filepath.Join(dir, "oriented_out.jpg")
+	opts := preview.DerivativeOptions{Width: 64, Fit: preview.FitContain, Quality: 80, Format: preview.FormatJPEG}
+	if err := preview.GenerateImageDerivative(ctx, oriented, out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodedSize(t, out); w != 64 || h != 128 {
+		t.Errorf("EXIF 方向校正后尺寸错误: %dx%d", w, h)
+	}
+}
+
+// TestDerivativeCacheEviction 测试衍生图缓存命中与超过上限时淘汰最久未使用的衍生图
+func TestDerivativeCacheEviction(t *testing.T) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.png")
+	writeTestPNG(t, src, 256, 256)
+
+	opts := preview.DerivativeOptions{Width: 256, Fit: preview.FitContain, Format: preview.FormatPNG}
+	probe := preview.NewDerivativeCache(filepath.Join(dir, "probe"), 0)
+	first, err := probe.Get(ctx, "probe", src, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(first)
+
+	// 上限约为两张衍生图的大小，写入第三张时淘汰最久未使用的一张
+	cache := preview.NewDerivativeCache(filepath.Join(dir, "cache"), info.Size()*2+info.Size()/2)
+	paths := make([]string, 3)
+	for i, hash := range []string{"aa01", "bb02", "cc03"} {
+		if paths[i], err = cache.Get(ctx, hash, src, opts); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if i < 2 {
+			_ = os.Chtimes(paths[i], old, old)
+		}
+		if i == 1 {
+			// 命中缓存会更新使用时间，aa01 变为最近使用
+			if again, err := cache.Get(ctx, "aa01", filepath.Join(dir, "missing.png"), opts); err != nil || again != paths[0] {
+				t.Fatalf("应命中缓存: %v", err)
+			}
+		}
+	}
+	if _, err := os.Stat(paths[1]); !os.IsNotExist(err) {
+		t.Errorf("最久未使用的衍生图应被淘汰")
+	}
+	for _, path := range []string{paths[0], paths[2]} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("最近使用的衍生图不应被淘汰: %v", err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"myobj/src/config"
+	"myobj/src/pkg/auth"
+	"testing"
+)
+
+// TestLDAPMapGroup 测试 LDAP 组映射（按配置顺序匹配 DN 或 CN，不区分大小写）
+func TestLDAPMapGroup(t *testing.T) {
+	provider := auth.NewLDAPProvider(config.LDAP{
+		URL: "ldap://127.0.0.1:389",
+		GroupMapping: []config.LDAPGroupMapping{
+			{LDAPGroup: "cn=MyObj-Admins,ou=groups,dc=example,dc=com", GroupID: 1},
+			{LDAPGroup: "staff", GroupID: 3},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		groups []string
+		want   int
+		ok     bool
+	}{
+		{"DN 匹配", []string{"CN=myobj-admins,OU=groups,DC=example,DC=com"}, 1, true},
+		{"CN 匹配", []string{"cn=Staff,ou=groups,dc=example,dc=com"}, 3, true},
+		{"按配置顺序优先", []string{"cn=staff,ou=groups,dc=example,dc=com", "cn=myobj-admins,ou=groups,dc=example,dc=com"}, 1, true},
+		{"未命中", []string{"cn=others,ou=groups,dc=example,dc=com"}, 0, false},
+		{"无组", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := provider.MapGroup(tt.groups)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("MapGroup(%v) = %d, %v, want %d, %v", tt.groups, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
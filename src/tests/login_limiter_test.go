@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"myobj/src/config"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memorySecurityEventRepo 内存中的安全事件仓储
+type memorySecurityEventRepo struct {
+	mu     sync.Mutex
+	events []*models.SecurityEvent
+}
+
+func (r *memorySecurityEventRepo) Create(ctx context.Context, event *models.SecurityEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = len(r.events) + 1
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memorySecurityEventRepo) GetLatest(ctx context.Context, eventType, target string) (*models.SecurityEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if r.events[i].Type == eventType && r.events[i].Target == target {
+			return r.events[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySecurityEventRepo) List(ctx context.Context, eventType, target string, offset, limit int) ([]*models.SecurityEvent, error) {
+	return nil, nil
+}
+
+func (r *memorySecurityEventRepo) Count(ctx context.Context, eventType, target string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, event := range r.events {
+		if (eventType == "" || event.Type == eventType) && (target == "" || event.Target == target) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memorySecurityEventRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// TestLoginLimiter 测试失败退避、锁定、锁定时长加倍与跨进程解锁
+func TestLoginLimiter(t *testing.T) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	events := &memorySecurityEventRepo{}
+	cfg := config.LoginLimit{Enable: true, MaxAttempts: 2, IPMaxAttempts: 100, ShareMaxAttempts: 1, Window: 15, LockMinutes: 10, MaxLockMinutes: 15}
+	limiter := auth.NewLoginLimiter(cache.NewLocalCache(), events, cfg)
+
+	var limitErr *auth.LimitError
+	if err := limiter.CheckLogin(ctx, "alice", "10.0.0.1"); err != nil {
+		t.Fatalf("没有失败记录时不应限制: %v", err)
+	}
+
+	// 第一次失败后需要等待
+	limiter.LoginFailed(ctx, auth.LimitChannelWeb, "alice", "10.0.0.1", "密码错误")
+	err := limiter.CheckLogin(ctx, "alice", "10.0.0.2")
+	if !errors.As(err, &limitErr) || limitErr.Locked || limitErr.RetryAfter > time.Second {
+		t.Fatalf("第一次失败后应退避 1 秒: %v", err)
+	}
+
+	// 达到上限后锁定（用户名不区分大小写）
+	limiter.LoginFailed(ctx, auth.LimitChannelWeb, "ALICE", "10.0.0.3", "密码错误")
+	err = limiter.CheckLogin(ctx, "alice", "10.0.0.4")
+	if !errors.As(err, &limitErr) || !limitErr.Locked || limitErr.RetryAfter <= 9*time.Minute {
+		t.Fatalf("达到上限后应锁定 10 分钟: %v", err)
+	}
+	if n, _ := events.Count(ctx, models.SecurityEventLocked, "ALICE"); n != 1 {
+		t.Errorf("应记录一条锁定事件, 实际 %d", n)
+	}
+
+	// 再次锁定时锁定时长加倍，但不超过上限
+	limiter.LoginFailed(ctx, auth.LimitChannelWeb, "alice", "10.0.0.5", "密码错误")
+	limiter.LoginFailed(ctx, auth.LimitChannelWeb, "alice", "10.0.0.5", "密码错误")
+	err = limiter.CheckLogin(ctx, "alice", "10.0.0.6")
+	if !errors.As(err, &limitErr) || limitErr.RetryAfter <= 14*time.Minute || limitErr.RetryAfter > 15*time.Minute {
+		t.Fatalf("再次锁定时长应为 15 分钟: %v", err)
+	}
+
+	// 其他进程（如 CLI）使用独立缓存解锁，通过解锁事件生效
+	other := auth.NewLoginLimiter(cache.NewLocalCache(), events, cfg)
+	if err := other.Unlock(ctx, "Alice", auth.LimitChannelCLI, "myobj-cli"); err != nil {
+		t.Fatalf("解锁失败: %v", err)
+	}
+	if err := limiter.CheckLogin(ctx, "alice", "10.0.0.6"); err != nil {
+		t.Fatalf("解锁后应允许登录: %v", err)
+	}
+
+	// 分享密码错误按分享计数
+	limiter.ShareFailed(ctx, "share-token", "10.0.1.1")
+	err = limiter.CheckShare(ctx, "share-token", "10.0.1.2")
+	if !errors.As(err, &limitErr) || !limitErr.Locked {
+		t.Fatalf("分享密码错误达到上限后应锁定: %v", err)
+	}
+
+	// 未启用时不限制
+	cfg.Enable = false
+	disabled := auth.NewLoginLimiter(cache.NewLocalCache(), events, cfg)
+	disabled.LoginFailed(ctx, auth.LimitChannelWeb, "bob", "10.0.0.7", "密码错误")
+	if err := disabled.CheckLogin(ctx, "bob", "10.0.0.7"); err != nil {
+		t.Errorf("未启用时不应限制: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"mime"
+	"myobj/src/config"
+	"myobj/src/pkg/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestMailer(t *testing.T) (*mail.Mailer, string) {
+	file := filepath.Join(t.TempDir(), "mail.log")
+	return mail.NewMailer(config.Mail{
+		Enable:      true,
+		Transport:   "file",
+		From:        "noreply@example.com",
+		FromName:    "MyObj",
+		FilePath:    file,
+		TemplateDir: "../../templates/mail",
+		SiteURL:     "https://pan.example.com/",
+	}), file
+}
+
+// TestMailTemplates 测试内置邮件模板均可渲染且包含标题与链接
+func TestMailTemplates(t *testing.T) {
+	mailer, _ := newTestMailer(t)
+	link := mailer.Link("/", url.Values{"reset_token": {"abc"}})
+	if link != "https://pan.example.com/?reset_token=abc" {
+		t.Fatalf("链接拼接错误: %s", link)
+	}
+	data := map[string]any{
+		"UserName":      "<alice>",
+		"InviterName":   "admin",
+		"GroupName":     "研发组",
+		"Link":          link,
+		"ExpireHours":   24,
+		"ExpireMinutes": 30,
+		"ExpireDays":    7,
+	}
+	for _, name := range []string{mail.TemplateVerifyEmail, mail.TemplateResetPassword, mail.TemplateInvitation} {
+		msg, err := mailer.Render("alice@example.com", name, data)
+		if err != nil {
+			t.Fatalf("渲染模板 %s 失败: %v", name, err)
+		}
+		if !strings.Contains(msg.Subject, "MyObj") || strings.Contains(msg.Subject, "\n") {
+			t.Errorf("模板 %s 标题错误: %q", name, msg.Subject)
+		}
+		if !strings.Contains(msg.HTML, "reset_token=abc") {
+			t.Errorf("模板 %s 缺少链接", name)
+		}
+		if strings.Contains(msg.HTML, "<alice>") {
+			t.Errorf("模板 %s 未转义用户输入", name)
+		}
+	}
+}
+
+// TestMailFileTransport 测试 file 发送方式写入完整的 MIME 邮件
+func TestMailFileTransport(t *testing.T) {
+	mailer, file := newTestMailer(t)
+	err := mailer.Send("bob@example.com", mail.TemplateVerifyEmail, map[string]any{
+		"UserName":    "bob",
+		"Link":        "https://pan.example.com/api/user/email/verify?token=xyz",
+		"ExpireHours": 24,
+	})
+	if err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if err := mailer.Send("not-an-address", mail.TemplateVerifyEmail, nil); err == nil {
+		t.Error("无效的收件人应返回错误")
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("读取邮件文件失败: %v", err)
+	}
+	text := string(content)
+	for _, want := range []string{"To: bob@example.com\r\n", "From: \"MyObj\" <noreply@example.com>\r\n", "Content-Type: text/html; charset=UTF-8", "token=xyz"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("邮件内容缺少 %q", want)
+		}
+	}
+	var subject string
+	for _, line := range strings.Split(text, "\r\n") {
+		if strings.HasPrefix(line, "Subject: ") {
+			subject, _ = new(mime.WordDecoder).DecodeHeader(strings.TrimPrefix(line, "Subject: "))
+		}
+	}
+	if subject != "验证您的邮箱 - MyObj" {
+		t.Errorf("邮件标题错误: %q", subject)
+	}
+
+	disabled := mail.NewMailer(config.Mail{Transport: "file", FilePath: file})
+	if err := disabled.Send("bob@example.com", mail.TemplateVerifyEmail, nil); err == nil {
+		t.Error("未启用邮件时发送应返回错误")
+	}
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"math"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/metadata"
+	"myobj/src/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// ifdEntry 写入 TIFF 的 IFD 条目，value 不超过 4 字节时写在条目内
+func ifdEntry(tag, typ uint16, count uint32, value uint32) []byte {
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:], tag)
+	binary.LittleEndian.PutUint16(entry[2:], typ)
+	binary.LittleEndian.PutUint32(entry[4:], count)
+	binary.LittleEndian.PutUint32(entry[8:], value)
+	return entry
+}
+
+func rationals(values ...[2]uint32) []byte {
+	out := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		out = binary.LittleEndian.AppendUint32(out, v[0])
+		out = binary.LittleEndian.AppendUint32(out, v[1])
+	}
+	return out
+}
+
+// writeGPSJPEG 写入带相机、拍摄时间与 GPS 坐标（北纬 39.9042，东经 116.4074）的 JPEG
+func writeGPSJPEG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// 布局：头(8) IFD0(8) Make(50) ExifIFD(56) 时间(74) GPS IFD(94) 纬度(148) 经度(172)
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = append(tiff, 3, 0)
+	tiff = append(tiff, ifdEntry(0x010F, 2, 6, 50)...)
+	tiff = append(tiff, ifdEntry(0x8769, 4, 1, 56)...)
+	tiff = append(tiff, ifdEntry(0x8825, 4, 1, 94)...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, "Canon\x00"...)
+	tiff = append(tiff, 1, 0)
+	tiff = append(tiff, ifdEntry(0x9003, 2, 20, 74)...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, "2023:05:01 10:20:30\x00"...)
+	tiff = append(tiff, 4, 0)
+	tiff = append(tiff, ifdEntry(1, 2, 2, 'N')...)
+	tiff = append(tiff, ifdEntry(2, 5, 3, 148)...)
+	tiff = append(tiff, ifdEntry(3, 2, 2, 'E')...)
+	tiff = append(tiff, ifdEntry(4, 5, 3, 172)...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, rationals([2]uint32{39, 1}, [2]uint32{54, 1}, [2]uint32{1512, 100})...)
+	tiff = append(tiff, rationals([2]uint32{116, 1}, [2]uint32{24, 1}, [2]uint32{2664, 100})...)
+	if len(tiff) != 196 {
+		t.Fatalf("EXIF 布局错误: %d", len(tiff))
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+	data := buf.Bytes()
+	out := append(append(append([]byte{}, data[:2]...), app1...), payload...)
+	out = append(out, data[2:]...)
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readEXIF(t *testing.T, path string) *metadata.EXIF {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := metadata.ReadJPEGEXIF(f)
+	if err != nil {
+		t.Fatalf("读取 EXIF 失败: %v", err)
+	}
+	return info
+}
+
+// TestEXIFAndStripGPS 测试 EXIF 解析、照片元数据提取与去除位置信息
+func TestEXIFAndStripGPS(t *testing.T) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.jpg")
+	writeGPSJPEG(t, src, 64, 32)
+
+	info := readEXIF(t, src)
+	if info.Make != "Canon" || info.DateTime != "2023:05:01 10:20:30" || !info.HasGPS {
+		t.Fatalf("EXIF 解析错误: %+v", info)
+	}
+	if info.Latitude == nil || math.Abs(*info.Latitude-39.9042) > 1e-6 || math.Abs(*info.Longitude-116.4074) > 1e-6 {
+		t.Fatalf("GPS 坐标解析错误: %v, %v", info.Latitude, info.Longitude)
+	}
+
+	fileInfo := &models.FileInfo{ID: "photo", Path: src, Mime: "image/jpeg", CreatedAt: custom_type.JsonTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local))}
+	m, err := metadata.Extract(context.Background(), nil, fileInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != models.FileMetadataOK || m.Kind != models.FileMetadataImage || m.Width != 64 || m.Height != 32 {
+		t.Fatalf("图片元数据错误: %+v", m)
+	}
+	if m.TakenDate != "2023-05-01" || m.CameraMake != "Canon" || m.Latitude == nil {
+		t.Fatalf("拍摄日期应取 EXIF 时间: %+v", m)
+	}
+
+	// 去除位置信息后其他 EXIF 与图像数据保留
+	dst := filepath.Join(dir, "stripped.jpg")
+	stripped, err := metadata.StripGPS(src, dst)
+	if err != nil || !stripped {
+		t.Fatalf("去除位置信息失败: %v, %v", stripped, err)
+	}
+	info = readEXIF(t, dst)
+	if info.HasGPS || info.Latitude != nil {
+		t.Fatalf("位置信息未去除: %+v", info)
+	}
+	if info.Make != "Canon" || info.DateTime != "2023:05:01 10:20:30" {
+		t.Fatalf("其他 EXIF 信息丢失: %+v", info)
+	}
+	if w, h := decodedSize(t, dst); w != 64 || h != 32 {
+		t.Fatalf("图像数据损坏: %dx%d", w, h)
+	}
+
+	// 没有位置信息的照片不生成副本
+	again := filepath.Join(dir, "again.jpg")
+	if stripped, err := metadata.StripGPS(dst, again); err != nil || stripped {
+		t.Fatalf("没有位置信息时不应处理: %v, %v", stripped, err)
+	}
+	if _, err := os.Stat(again); !os.IsNotExist(err) {
+		t.Fatal("没有位置信息时不应创建文件")
+	}
+}
+
+// id3Frame ID3v2.3 帧
+func id3Frame(id string, data []byte) []byte {
+	frame := append([]byte(id), 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// writeTestMP3 写入带 ID3v2、Xing 头（100 帧）与 ID3v1 标签的 MP3
+func writeTestMP3(t *testing.T, path string) {
+	t.Helper()
+	artist := []byte{1, 0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune("歌手")) {
+		artist = binary.LittleEndian.AppendUint16(artist, u)
+	}
+	frames := append(id3Frame("TIT2", []byte("\x00Hello")), id3Frame("TPE1", artist)...)
+	size := len(frames)
+	header := []byte{'I', 'D', '3', 3, 0, 0, byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+
+	// MPEG1 层III 128kbps 44100Hz 立体声，Xing 头位于 32 字节边信息之后
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	copy(frame[36:], "Xing")
+	binary.BigEndian.PutUint32(frame[40:], 1)
+	binary.BigEndian.PutUint32(frame[44:], 100)
+
+	v1 := make([]byte, 128)
+	copy(v1, "TAG")
+	copy(v1[63:], "Album1")
+	v1[127] = 17
+	out := append(append(append(header, frames...), frame...), v1...)
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestFLAC 写入 44100Hz、441000 个采样（10 秒）并带 Vorbis 注释的 FLAC
+func writeTestFLAC(t *testing.T, path string) {
+	t.Helper()
+	info := make([]byte, 34)
+	info[10], info[11], info[12] = 0x0A, 0xC4, 0x40|1<<1
+	binary.BigEndian.PutUint32(info[14:], 441000)
+	comment := binary.LittleEndian.AppendUint32(nil, 4)
+	comment = append(comment, "test"...)
+	comment = binary.LittleEndian.AppendUint32(comment, 2)
+	for _, c := range []string{"TITLE=流行", "TRACKNUMBER=3/12"} {
+		comment = binary.LittleEndian.AppendUint32(comment, uint32(len(c)))
+		comment = append(comment, c...)
+	}
+	out := []byte("fLaC")
+	out = append(out, 0, 0, 0, 34)
+	out = append(out, info...)
+	out = append(out, 0x84, byte(len(comment)>>16), byte(len(comment)>>8), byte(len(comment)))
+	out = append(out, comment...)
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestExtractAudioTags 测试 MP3（ID3v2、ID3v1、Xing）与 FLAC（STREAMINFO、Vorbis 注释）的标签与时长
+func TestExtractAudioTags(t *testing.T) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	mp3 := filepath.Join(dir, "song.mp3")
+	writeTestMP3(t, mp3)
+	m, err := metadata.Extract(ctx, nil, &models.FileInfo{ID: "mp3", Path: mp3, Mime: "audio/mpeg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Status != models.FileMetadataOK || m.Title != "Hello" || m.Artist != "歌手" || m.Album != "Album1" || m.Genre != "Rock" {
+		t.Fatalf("MP3 标签解析错误: %+v", m)
+	}
+	if want := 100 * 1152 / 44100.0; math.Abs(m.Duration-want) > 1e-6 {
+		t.Fatalf("MP3 时长错误: %v，期望 %v", m.Duration, want)
+	}
+	if m.TakenDate != "" {
+		t.Fatalf("音频不应归入时间线: %q", m.TakenDate)
+	}
+
+	flac := filepath.Join(dir, "song.flac")
+	writeTestFLAC(t, flac)
+	m, err = metadata.Extract(ctx, nil, &models.FileInfo{ID: "flac", Path: flac, Mime: "audio/flac"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Title != "流行" || m.Track != 3 || math.Abs(m.Duration-10) > 1e-6 {
+		t.Fatalf("FLAC 解析错误: %+v", m)
+	}
+
+	// 加密文件不读取内容
+	m, err = metadata.Extract(ctx, nil, &models.FileInfo{ID: "enc", Path: flac, Mime: "audio/flac", IsEnc: true})
+	if err != nil || m.Status != models.FileMetadataEncrypted || m.Title != "" {
+		t.Fatalf("加密文件不应提取元数据: %+v, %v", m, err)
+	}
+}
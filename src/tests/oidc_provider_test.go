@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"myobj/src/config"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCProvider 进程内模拟的 OIDC 身份提供方（发现文档、JWKS、令牌端点与 UserInfo 端点）
+type mockOIDCProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	audience string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthRequest
+}
+
+// mockAuthRequest 授权请求中需要在令牌端点校验的参数
+type mockAuthRequest struct {
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	m := &mockOIDCProvider{key: key, audience: "myobj", codes: map[string]mockAuthRequest{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"userinfo_endpoint":                     m.server.URL + "/userinfo",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		req, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+			w.WriteHeader(400)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, map[string]any{
+			"access_token": "access-alice",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token": m.sign(t, jwt.MapClaims{
+				"sub":                "alice-sub",
+				"preferred_username": "alice",
+				"name":               "Alice",
+				"email":              "alice@example.com",
+				"email_verified":     true,
+				"nonce":              req.nonce,
+			}),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-alice" {
+			w.WriteHeader(401)
+			return
+		}
+		writeJSON(w, map[string]any{"sub": "alice-sub", "groups": []string{"/staff", "/myobj-admins"}})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// sign 签发 ID Token（issuer、audience 与有效期可被 claims 覆盖）
+func (m *mockOIDCProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	base := jwt.MapClaims{
+		"iss": m.server.URL,
+		"aud": m.audience,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatalf("签发 ID Token 失败: %v", err)
+	}
+	return signed
+}
+
+// authorize 模拟用户在身份提供方完成登录，返回授权码
+func (m *mockOIDCProvider) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("解析授权地址失败: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("授权地址缺少 PKCE 参数: %s", authURL)
+	}
+	code := "code-" + q.Get("state")
+	m.mu.Lock()
+	m.codes[code] = mockAuthRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	m.mu.Unlock()
+	return code
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// TestOIDCProviderExchange 测试授权码 + PKCE 流程与 ID Token 校验
+func TestOIDCProviderExchange(t *testing.T) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := newMockOIDCProvider(t)
+	provider := auth.NewOIDCProvider(config.OIDC{
+		Enable:        true,
+		Issuer:        mock.server.URL,
+		ClientID:      "myobj",
+		RedirectURL:   "http://localhost/api/user/oidc/callback",
+		Scopes:        []string{"profile", "email"},
+		UsernameClaim: "preferred_username",
+		NameClaim:     "name",
+		EmailClaim:    "email",
+		GroupsClaim:   "groups",
+		GroupMapping: []config.OIDCGroupMapping{
+			{Group: "myobj-admins", GroupID: 1},
+		},
+	})
+	ctx := context.Background()
+
+	t.Run("正常登录", func(t *testing.T) {
+		authURL, err := provider.AuthCodeURL(ctx, "s1", "n1", "verifier-verifier-verifier-verifier-verifier")
+		if err != nil {
+			t.Fatalf("生成授权地址失败: %v", err)
+		}
+		code := mock.authorize(t, authURL)
+		claims, err := provider.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", "n1")
+		if err != nil {
+			t.Fatalf("换取令牌失败: %v", err)
+		}
+		if claims.Subject != "alice-sub" || claims.Username != "alice" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+			t.Errorf("声明映射错误: %+v", claims)
+		}
+		// ID Token 中没有组声明，从 UserInfo 端点补充
+		if groupID, ok := provider.MapGroup(claims.Groups); !ok || groupID != 1 {
+			t.Errorf("组映射错误: groups=%v, group_id=%d", claims.Groups, groupID)
+		}
+	})
+
+	t.Run("PKCE 校验码错误", func(t *testing.T) {
+		authURL, _ := provider.AuthCodeURL(ctx, "s2", "n2", "verifier-verifier-verifier-verifier-verifier")
+		code := mock.authorize(t, authURL)
+		if _, err := provider.Exchange(ctx, code, "another-verifier-another-verifier-another", "n2"); err == nil {
+			t.Error("PKCE 校验码错误时应失败")
+		}
+	})
+
+	t.Run("nonce 不匹配", func(t *testing.T) {
+		authURL, _ := provider.AuthCodeURL(ctx, "s3", "n3", "verifier-verifier-verifier-verifier-verifier")
+		code := mock.authorize(t, authURL)
+		if _, err := provider.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", "other-nonce"); err == nil {
+			t.Error("nonce 不匹配时应失败")
+		}
+	})
+
+	t.Run("audience 不匹配", func(t *testing.T) {
+		mock.audience = "other-client"
+		defer func() { mock.audience = "myobj" }()
+		authURL, _ := provider.AuthCodeURL(ctx, "s4", "n4", "verifier-verifier-verifier-verifier-verifier")
+		code := mock.authorize(t, authURL)
+		if _, err := provider.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", "n4"); err == nil {
+			t.Error("audience 不匹配时应失败")
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPasswordPolicy(t *testing.T) *auth.PasswordPolicyManager {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SysConfig{}, &models.PasswordHistory{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return auth.NewPasswordPolicyManager(impl.NewSysConfigRepository(db), impl.NewPasswordHistoryRepository(db))
+}
+
+func TestPasswordPolicyStrength(t *testing.T) {
+	policy := &auth.PasswordPolicy{
+		MinLength:      8,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		DisallowCommon: true,
+	}
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"Ab1", false},
+		{"abcdefg1", false},
+		{"ABCDEFG1", false},
+		{"Abcdefgh", false},
+		{"Password1", false}, // 常见弱密码（不区分大小写）
+		{"Summer7Rain", true},
+		{"Passw0rd", false},
+		{"Tr0ub4dor", true},
+	}
+	for _, tt := range tests {
+		err := policy.CheckStrength(tt.password)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckStrength(%q) = %v, want ok=%v", tt.password, err, tt.ok)
+		}
+	}
+	if err := (&auth.PasswordPolicy{MinLength: 0}).Validate(); err == nil {
+		t.Error("MinLength 为 0 的策略应校验失败")
+	}
+}
+
+func TestPasswordPolicyHistory(t *testing.T) {
+	ctx := context.Background()
+	manager := setupPasswordPolicy(t)
+
+	// 未配置时使用默认策略
+	if err := manager.Check(ctx, models.PasswordKindLogin, nil, "12345"); err == nil {
+		t.Fatal("默认策略应要求至少 6 位")
+	}
+	if err := manager.SavePolicy(ctx, models.PasswordKindLogin, &auth.PasswordPolicy{MinLength: 6, HistoryCount: 2}); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+
+	user := &models.UserInfo{ID: "user001"}
+	for _, psw := range []string{"first-1", "second-2", "third-3"} {
+		hash, err := util.GeneratePassword(psw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Password = hash
+		if err := manager.Record(ctx, models.PasswordKindLogin, user.ID, hash); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	// 当前密码与最近 2 次历史密码不能重复使用，更早的密码已被清理
+	for _, psw := range []string{"third-3", "second-2"} {
+		if err := manager.Check(ctx, models.PasswordKindLogin, user, psw); !errors.Is(err, auth.ErrPasswordReused) {
+			t.Errorf("Check(%q) = %v, want ErrPasswordReused", psw, err)
+		}
+	}
+	if err := manager.Check(ctx, models.PasswordKindLogin, user, "first-1"); err != nil {
+		t.Errorf("超出历史数量的密码应允许使用: %v", err)
+	}
+
+	// 文件密码使用独立的策略与历史
+	if err := manager.Check(ctx, models.PasswordKindFile, user, "third-3"); err != nil {
+		t.Errorf("文件密码不应受登录密码历史限制: %v", err)
+	}
+}
+
+func TestPasswordPolicyExpired(t *testing.T) {
+	ctx := context.Background()
+	manager := setupPasswordPolicy(t)
+	if err := manager.SavePolicy(ctx, models.PasswordKindLogin, &auth.PasswordPolicy{MinLength: 6, MaxAgeDays: 30}); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+
+	old := custom_type.JsonTime(time.Now().AddDate(0, 0, -31))
+	user := &models.UserInfo{ID: "user001", CreatedAt: old}
+	if expired, _ := manager.Expired(ctx, models.PasswordKindLogin, user); !expired {
+		t.Error("未记录修改时间时应按创建时间判断过期")
+	}
+	user.PasswordChangedAt = custom_type.Now()
+	if expired, _ := manager.Expired(ctx, models.PasswordKindLogin, user); expired {
+		t.Error("刚修改的密码不应过期")
+	}
+	user.PasswordChangedAt = old
+	user.AuthSource = models.AuthSourceLDAP
+	if expired, _ := manager.Expired(ctx, models.PasswordKindLogin, user); expired {
+		t.Error("目录账户的密码不由本系统管理,不应过期")
+	}
+	// 文件密码未设置有效期
+	user.FilePassword = "x"
+	if expired, _ := manager.Expired(ctx, models.PasswordKindFile, user); expired {
+		t.Error("文件密码策略未设置有效期,不应过期")
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"myobj/src/pkg/util"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// splitFile 将文件按固定大小拆分为多个分片，返回分片路径与大小
+func splitFile(t *testing.T, src string, chunkSize int) ([]string, []int64) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("读取文件失败: %v", err)
+	}
+	var paths []string
+	var sizes []int64
+	for i := 0; len(data) > 0; i++ {
+		n := chunkSize
+		if n > len(data) {
+			n = len(data)
+		}
+		p := filepath.Join(filepath.Dir(src), filepath.Base(src)+"_"+string(rune('a'+i)))
+		if err := os.WriteFile(p, data[:n], 0644); err != nil {
+			t.Fatalf("写入分片失败: %v", err)
+		}
+		paths = append(paths, p)
+		sizes = append(sizes, int64(n))
+		data = data[n:]
+	}
+	return paths, sizes
+}
+
+// readRange 定位到 offset 后读取 length 字节
+func readRange(t *testing.T, r io.ReadSeeker, offset, length int64) []byte {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		t.Fatalf("Seek 失败: %v", err)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("读取失败 [offset=%d]: %v", offset, err)
+	}
+	return buf
+}
+
+// TestChunkedReader_Range 测试分片文件随机读取
+func TestChunkedReader_Range(t *testing.T) {
+	tempDir := t.TempDir()
+	src := filepath.Join(tempDir, "plain.data")
+	testData := make([]byte, 100*1024+37)
+	rand.Read(testData)
+	if err := os.WriteFile(src, testData, 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+
+	paths, sizes := splitFile(t, src, 32*1024)
+	r := util.NewChunkedReader(paths, sizes)
+	defer r.Close()
+
+	if size, _ := r.Seek(0, io.SeekEnd); size != int64(len(testData)) {
+		t.Fatalf("大小不一致: %d != %d", size, len(testData))
+	}
+	// 跨分片读取
+	for _, c := range [][2]int64{{0, 10}, {32*1024 - 5, 10}, {64 * 1024, 32 * 1024}, {int64(len(testData)) - 7, 7}} {
+		if got := readRange(t, r, c[0], c[1]); !bytes.Equal(got, testData[c[0]:c[0]+c[1]]) {
+			t.Fatalf("分片读取数据不一致 [offset=%d]", c[0])
+		}
+	}
+	// 完整读取
+	r.Seek(0, io.SeekStart)
+	all, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(all, testData) {
+		t.Fatal("分片完整读取数据不一致")
+	}
+}
+
+// TestDecryptReader_Range 测试加密文件（含分片存储）随机解密读取
+func TestDecryptReader_Range(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.txt")
+	encryptedPath := filepath.Join(tempDir, "encrypted.bin")
+	testData := make([]byte, 200*1024+13)
+	rand.Read(testData)
+	if err := os.WriteFile(inputPath, testData, 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+
+	key := util.DeriveEncryptionKey("file-password", "user-1")
+	if err := util.NewFileCrypto(key).EncryptFile(inputPath, encryptedPath); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	info, _ := os.Stat(encryptedPath)
+
+	cases := [][2]int64{{0, 1}, {15, 2}, {16, 16}, {1000, 5000}, {150*1024 + 3, 50 * 1024}, {int64(len(testData)) - 1, 1}}
+
+	// 普通加密文件
+	f, _ := os.Open(encryptedPath)
+	r, err := util.NewDecryptReader(f, info.Size(), key)
+	if err != nil {
+		t.Fatalf("创建解密读取器失败: %v", err)
+	}
+	defer r.Close()
+	if r.Size() != int64(len(testData)) {
+		t.Fatalf("明文大小不一致: %d != %d", r.Size(), len(testData))
+	}
+	for _, c := range cases {
+		if got := readRange(t, r, c[0], c[1]); !bytes.Equal(got, testData[c[0]:c[0]+c[1]]) {
+			t.Fatalf("解密数据不一致 [offset=%d]", c[0])
+		}
+	}
+
+	// 先加密后分片存储的文件
+	paths, sizes := splitFile(t, encryptedPath, 64*1024)
+	cr, err := util.NewDecryptReader(util.NewChunkedReader(paths, sizes), info.Size(), key)
+	if err != nil {
+		t.Fatalf("创建分片解密读取器失败: %v", err)
+	}
+	defer cr.Close()
+	for _, c := range cases {
+		if got := readRange(t, cr, c[0], c[1]); !bytes.Equal(got, testData[c[0]:c[0]+c[1]]) {
+			t.Fatalf("分片解密数据不一致 [offset=%d]", c[0])
+		}
+	}
+	cr.Seek(0, io.SeekStart)
+	all, err := io.ReadAll(cr)
+	if err != nil || !bytes.Equal(all, testData) {
+		t.Fatal("分片完整解密数据不一致")
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
+	"strconv"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+const recycleUser = "user001"
+
+// setupRecycleDB 创建目录回收站测试数据：根目录下 /docs，/docs 下 /sub，两个目录各有一个文件
+func setupRecycleDB(t *testing.T) (*impl.RepositoryFactory, map[string]*models.VirtualPath) {
+	factory := openFileDB(t)
+	dirs := map[string]*models.VirtualPath{}
+	for _, d := range []struct{ key, path, parent string }{
+		{"root", "home", ""}, {"docs", "/docs", "root"}, {"sub", "/sub", "docs"},
+	} {
+		dirs[d.key] = createTestDir(t, factory, recycleUser, d.path, dirs[d.parent])
+	}
+	createTestFile(t, factory, recycleUser, "f1", "a.txt", dirs["docs"], 100)
+	createTestFile(t, factory, recycleUser, "f2", "b.txt", dirs["sub"], 200)
+	return factory, dirs
+}
+
+// TestRecycleDirTree 测试目录整体移入回收站、统计与按原层级还原
+func TestRecycleDirTree(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupRecycleDB(t)
+	bin := recycle.NewBin(factory)
+
+	entry, summary, err := bin.RecycleDir(ctx, recycleUser, dirs["docs"])
+	if err != nil {
+		t.Fatalf("RecycleDir failed: %v", err)
+	}
+	if summary.Dirs != 1 || summary.Files != 2 || entry.OriginalPath != "/docs" {
+		t.Fatalf("目录树统计错误: %+v, path=%s", summary, entry.OriginalPath)
+	}
+	if _, err := factory.VirtualPath().GetByID(ctx, dirs["sub"].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("子目录应已移入回收站: %v", err)
+	}
+	if names := liveFileNames(t, factory, recycleUser, dirs["sub"].ID); len(names) != 0 {
+		t.Errorf("子目录中的文件应已移入回收站: %v", names)
+	}
+	if list, _ := factory.Recycled().ListByUserID(ctx, recycleUser, 0, 10); len(list) != 1 || !list[0].IsDir() {
+		t.Fatalf("回收站应只有一个目录条目: %d", len(list))
+	}
+	if _, size, err := bin.Stat(ctx, entry); err != nil || size != 300 {
+		t.Errorf("目录条目的文件总大小错误: %d, %v", size, err)
+	}
+
+	result, err := bin.Restore(ctx, entry, "")
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.DirID != dirs["docs"].ID || result.ToRoot || result.Merged {
+		t.Errorf("还原结果错误: %+v", result)
+	}
+	if sub, err := factory.VirtualPath().GetByID(ctx, dirs["sub"].ID); err != nil || sub.ParentLevel != strconv.Itoa(dirs["docs"].ID) {
+		t.Errorf("子目录应按原层级还原: %v", err)
+	}
+	if names := liveFileNames(t, factory, recycleUser, dirs["sub"].ID); len(names) != 1 || names[0] != "b.txt" {
+		t.Errorf("子目录中的文件应已还原: %v", names)
+	}
+	if count, _ := factory.Recycled().Count(ctx, recycleUser); count != 0 {
+		t.Errorf("还原后回收站应为空: %d", count)
+	}
+}
+
+// TestRecycleDirConflict 测试原位置存在同名目录时的重命名与合并
+func TestRecycleDirConflict(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupRecycleDB(t)
+	bin := recycle.NewBin(factory)
+
+	entry, _, err := bin.RecycleDir(ctx, recycleUser, dirs["docs"])
+	if err != nil {
+		t.Fatalf("RecycleDir failed: %v", err)
+	}
+	docs := createTestDir(t, factory, recycleUser, "/docs", dirs["root"])
+	createTestFile(t, factory, recycleUser, "f3", "a.txt", docs, 50)
+
+	if _, err := bin.Restore(ctx, entry, ""); !errors.Is(err, recycle.ErrConflict) {
+		t.Fatalf("未指定处理方式时应返回 ErrConflict: %v", err)
+	}
+
+	result, err := bin.Restore(ctx, entry, recycle.ConflictMerge)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if !result.Merged || result.DirID != docs.ID || result.Renamed != 1 {
+		t.Errorf("合并结果错误: %+v", result)
+	}
+	if names := liveFileNames(t, factory, recycleUser, docs.ID); len(names) != 2 || names[0] != "a (1).txt" || names[1] != "a.txt" {
+		t.Errorf("同名文件应重命名后合并: %v", names)
+	}
+	if sub, err := factory.VirtualPath().GetByID(ctx, dirs["sub"].ID); err != nil || sub.ParentLevel != strconv.Itoa(docs.ID) {
+		t.Errorf("子目录应合并到已存在的目录下: %v", err)
+	}
+	if _, err := factory.VirtualPath().GetByIDUnscoped(ctx, dirs["docs"].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("已合并的目录记录应被删除: %v", err)
+	}
+
+	// 再次删除并以重命名方式还原
+	entry, _, err = bin.RecycleDir(ctx, recycleUser, docs)
+	if err != nil {
+		t.Fatalf("RecycleDir failed: %v", err)
+	}
+	createTestDir(t, factory, recycleUser, "/docs", dirs["root"])
+	result, err = bin.Restore(ctx, entry, recycle.ConflictRename)
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if result.Name != "docs (1)" {
+		t.Errorf("重命名结果错误: %+v", result)
+	}
+	if dir, err := factory.VirtualPath().GetByID(ctx, docs.ID); err != nil || dir.Path != "/docs (1)" {
+		t.Errorf("目录应以新名称还原: %v", err)
+	}
+}
+
+// TestRecycleDirManyFiles 测试目录中的文件超过单页查询数量时全部移入回收站
+func TestRecycleDirManyFiles(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupRecycleDB(t)
+	const count = 1200
+	for i := 0; i < count; i++ {
+		createTestFile(t, factory, recycleUser, "bulk"+strconv.Itoa(i), "bulk"+strconv.Itoa(i)+".txt", dirs["sub"], 1)
+	}
+
+	_, summary, err := recycle.NewBin(factory).RecycleDir(ctx, recycleUser, dirs["docs"])
+	if err != nil {
+		t.Fatalf("RecycleDir failed: %v", err)
+	}
+	if summary.Files != count+2 {
+		t.Errorf("移入回收站的文件数错误: %d", summary.Files)
+	}
+	var live int64
+	if err := factory.DB().Model(&models.UserFiles{}).Where("virtual_path = ?", strconv.Itoa(dirs["sub"].ID)).Count(&live).Error; err != nil || live != 0 {
+		t.Errorf("已删除目录下不应残留文件: %d, %v", live, err)
+	}
+}
@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/recycle"
+	"myobj/src/pkg/repository"
+	"myobj/src/pkg/search"
+	"myobj/src/pkg/util"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestSearchTokenize 测试中英文混合分词、查询词条与高亮
+func TestSearchTokenize(t *testing.T) {
+	if terms := search.QueryTerms("全文检索 Go-Lang"); !reflect.DeepEqual(terms, []string{"全文", "文检", "检索", "go", "lang"}) {
+		t.Errorf("查询词条错误: %v", terms)
+	}
+	if terms := search.QueryTerms("库"); !reflect.DeepEqual(terms, []string{"库"}) {
+		t.Errorf("单字查询应使用单字词条: %v", terms)
+	}
+	if got := search.Highlight("Hello 全文检索 <World>", "检索 world", 0); got != "Hello 全文<mark>检索</mark> &lt;<mark>World</mark>&gt;" {
+		t.Errorf("高亮结果错误: %s", got)
+	}
+	long := strings.Repeat("无关内容。", 100) + "目标关键词" + strings.Repeat("其他文字。", 100)
+	got := search.Highlight(long, "关键词", 60)
+	if !strings.Contains(got, "<mark>关键词</mark>") || !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("摘要应截取命中位置附近的内容: %s", got)
+	}
+}
+
+// searchUser 全文检索测试的用户
+const searchUser = "search-user"
+
+// setupSearchDB 创建全文索引表与空目录：根目录下 /docs，/docs 下 /sub
+func setupSearchDB(t *testing.T) (*impl.RepositoryFactory, map[string]*models.VirtualPath) {
+	old := config.CONFIG
+	t.Cleanup(func() { config.CONFIG = old })
+	config.CONFIG = &config.MyObjConfig{Search: config.Search{Enable: true, MaxFileSize: 20, MaxTextSize: 1024}}
+	factory := openFileDB(t, &models.SearchContent{}, &models.SearchDoc{}, &models.SearchPosting{})
+	dirs := map[string]*models.VirtualPath{}
+	dirs["root"] = createTestDir(t, factory, searchUser, "home", nil)
+	dirs["docs"] = createTestDir(t, factory, searchUser, "/docs", dirs["root"])
+	dirs["sub"] = createTestDir(t, factory, searchUser, "/sub", dirs["docs"])
+	return factory, dirs
+}
+
+// createSearchFile 创建带有实际文件内容的用户文件
+func createSearchFile(t *testing.T, factory *impl.RepositoryFactory, id, name, mime string, data []byte, dir *models.VirtualPath) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), id)
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := factory.FileInfo().Create(ctx, &models.FileInfo{ID: "fi-" + id, Name: name, Mime: mime, Category: util.FileCategory(name, mime), Size: len(data), Path: filePath,
+		CreatedAt: custom_type.Now(), UpdatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建文件信息失败: %v", err)
+	}
+	if err := factory.UserFiles().Create(ctx, &models.UserFiles{UserID: searchUser, FileID: "fi-" + id, FileName: name,
+		VirtualPath: strconv.Itoa(dir.ID), CreatedAt: custom_type.Now(), UfID: id}); err != nil {
+		t.Fatalf("创建用户文件失败: %v", err)
+	}
+}
+
+// buildDocx 生成只包含正文的 docx
+func buildDocx(t *testing.T, paragraphs ...string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatalf("创建 docx 失败: %v", err)
+	}
+	body := ""
+	for _, p := range paragraphs {
+		body += `<w:p><w:r><w:t>` + p + `</w:t></w:r></w:p>`
+	}
+	fmt.Fprintf(w, `<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// buildPDF 生成一页使用标准字体的 PDF
+func buildPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 712 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func searchIDs(t *testing.T, index *search.Index, keyword string, filter repository.FileFilter) []string {
+	filter.UserID = searchUser
+	result, err := index.Search(context.Background(), search.Query{Keyword: keyword, Filter: filter, Limit: 20})
+	if err != nil {
+		t.Fatalf("检索失败: %v", err)
+	}
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.UfID)
+	}
+	return ids
+}
+
+// TestSearchIndex 测试内容提取、BM25 排序、过滤条件与增量更新
+func TestSearchIndex(t *testing.T) {
+	ctx := context.Background()
+	factory, dirs := setupSearchDB(t)
+	createSearchFile(t, factory, "s1", "report.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		buildDocx(t, "Quarterly revenue report", "季度销售报告与分析"), dirs["docs"])
+	createSearchFile(t, factory, "s2", "notes.md", "text/markdown",
+		[]byte("# 会议纪要\n讨论季度销售目标，revenue revenue revenue"), dirs["sub"])
+	createSearchFile(t, factory, "s3", "invoice.pdf", "application/pdf", buildPDF("Invoice number 42 for consulting"), dirs["root"])
+
+	index := search.NewIndex(factory)
+	if indexed, _, err := index.Sync(ctx); err != nil || indexed != 3 {
+		t.Fatalf("同步索引失败: %d, %v", indexed, err)
+	}
+
+	if ids := searchIDs(t, index, "consulting", repository.FileFilter{}); !reflect.DeepEqual(ids, []string{"s3"}) {
+		t.Errorf("应能检索 PDF 内容: %v", ids)
+	}
+	// 词频更高的文档排在前面
+	if ids := searchIDs(t, index, "revenue", repository.FileFilter{}); !reflect.DeepEqual(ids, []string{"s2", "s1"}) {
+		t.Errorf("BM25 排序错误: %v", ids)
+	}
+	if ids := searchIDs(t, index, "季度销售", repository.FileFilter{}); len(ids) != 2 {
+		t.Errorf("应能检索中文内容: %v", ids)
+	}
+	// docx 与 markdown 都属于文档
+	if ids := searchIDs(t, index, "季度销售", repository.FileFilter{Categories: []string{util.CategoryDocument}}); len(ids) != 2 {
+		t.Errorf("类型过滤错误: %v", ids)
+	}
+	if ids := searchIDs(t, index, "季度销售", repository.FileFilter{Categories: []string{util.CategoryCode, util.CategoryArchive}}); len(ids) != 0 {
+		t.Errorf("类型过滤错误: %v", ids)
+	}
+	if ids := searchIDs(t, index, "季度销售", repository.FileFilter{VirtualPaths: []string{strconv.Itoa(dirs["sub"].ID)}}); !reflect.DeepEqual(ids, []string{"s2"}) {
+		t.Errorf("目录过滤错误: %v", ids)
+	}
+	result, err := index.Search(ctx, search.Query{Keyword: "销售报告", Filter: repository.FileFilter{UserID: searchUser}, Limit: 10})
+	if err != nil || len(result.Hits) != 1 || !strings.Contains(result.Hits[0].Highlight, "<mark>销售报告</mark>") {
+		t.Fatalf("内容摘要高亮错误: %+v, %v", result, err)
+	}
+
+	// 改名后按新文件名检索，移入回收站后不再返回
+	userFile, _ := factory.UserFiles().GetByUserIDAndUfID(ctx, searchUser, "s3")
+	userFile.FileName = "账单.pdf"
+	if err := factory.UserFiles().Update(ctx, userFile); err != nil {
+		t.Fatalf("重命名失败: %v", err)
+	}
+	if _, err := recycle.NewBin(factory).RecycleFile(ctx, searchUser, &models.UserFiles{UfID: "s2"}); err != nil {
+		t.Fatalf("移入回收站失败: %v", err)
+	}
+	if ids := searchIDs(t, index, "revenue", repository.FileFilter{}); !reflect.DeepEqual(ids, []string{"s1"}) {
+		t.Errorf("已删除的文件不应返回: %v", ids)
+	}
+	if _, removed, err := index.Sync(ctx); err != nil || removed != 1 {
+		t.Fatalf("同步应清理已删除文件的索引: %d, %v", removed, err)
+	}
+	if ids := searchIDs(t, index, "账单", repository.FileFilter{}); !reflect.DeepEqual(ids, []string{"s3"}) {
+		t.Errorf("改名后应按新文件名检索: %v", ids)
+	}
+}
+
+// TestSearchExtractLimits 测试解压炸弹与超长文本的提取上限
+func TestSearchExtractLimits(t *testing.T) {
+	ctx := context.Background()
+	// 解压额度为文件大小上限的 4 倍（4MB）
+	old := config.CONFIG
+	t.Cleanup(func() { config.CONFIG = old })
+	config.CONFIG = &config.MyObjConfig{Search: config.Search{Enable: true, MaxFileSize: 1, MaxTextSize: 1}}
+	extract := func(name, mime string, data []byte) (string, string) {
+		filePath := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+		status, text, err := search.Extract(ctx, nil, &models.FileInfo{Name: name, Mime: mime, Size: len(data), Path: filePath}, name)
+		if err != nil {
+			t.Fatalf("提取 %s 失败: %v", name, err)
+		}
+		return status, text
+	}
+
+	padding := strings.Repeat(" ", 8<<20)
+	if status, _ := extract("bomb.docx", "", buildDocx(t, "开头"+padding)); status != models.SearchContentTooLarge {
+		t.Errorf("解压后超过上限的 docx 应标记为过大: %s", status)
+	}
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	zw.Write([]byte("BT /F1 12 Tf (bomb) Tj ET" + padding))
+	zw.Close()
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	for i, obj := range []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+	} {
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	if status, _ := extract("bomb.pdf", "application/pdf", pdf.Bytes()); status != models.SearchContentTooLarge {
+		t.Errorf("解压后超过上限的 PDF 应标记为过大: %s", status)
+	}
+
+	paragraphs := make([]string, 2000)
+	for i := range paragraphs {
+		paragraphs[i] = fmt.Sprintf("第%d段", i)
+	}
+	status, text := extract("long.docx", "", buildDocx(t, paragraphs...))
+	if status != models.SearchContentOK || len(text) > 1024 || !strings.HasPrefix(text, "第0段") {
+		t.Errorf("超长文本应截断到文本上限: %s %d", status, len(text))
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSessionManager(t *testing.T) (*auth.SessionManager, *gorm.DB) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	old := config.CONFIG
+	t.Cleanup(func() { config.CONFIG = old })
+	config.CONFIG = &config.MyObjConfig{Auth: config.Auth{JwtExpire: 2, AccessTokenExpire: 15, SessionMaxAge: 24, RememberMeDays: 30}}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.UserSession{}, &models.RefreshToken{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return auth.NewSessionManager(impl.NewUserSessionRepository(db), impl.NewRefreshTokenRepository(db)), db
+}
+
+// TestSessionRefreshRotation 测试刷新令牌轮换、旧访问令牌失效与重复使用检测
+func TestSessionRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	sessions, db := setupSessionManager(t)
+
+	session, refresh1, err := sessions.Create(ctx, "user001", "access-1", "10.0.0.1", "curl/8.0", false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := sessions.Validate(ctx, "access-1", "10.0.0.1"); err != nil {
+		t.Fatalf("新会话的访问令牌应有效: %v", err)
+	}
+
+	_, refresh2, err := sessions.Refresh(ctx, refresh1, "access-2", "10.0.0.1")
+	if err != nil || refresh2 == "" || refresh2 == refresh1 {
+		t.Fatalf("刷新应返回新的刷新令牌: %q, %v", refresh2, err)
+	}
+	if _, err := sessions.Validate(ctx, "access-1", "10.0.0.1"); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("刷新后旧访问令牌应失效: %v", err)
+	}
+	if _, err := sessions.Validate(ctx, "access-2", "10.0.0.1"); err != nil {
+		t.Errorf("刷新后新访问令牌应有效: %v", err)
+	}
+
+	// 宽限期内的并发请求使用旧令牌：放行但不签发新令牌
+	got, _, err := sessions.Refresh(ctx, refresh1, "access-x", "10.0.0.1")
+	if !errors.Is(err, auth.ErrRefreshTokenRotated) || got == nil || got.ID != session.ID {
+		t.Fatalf("宽限期内重复使用应返回 ErrRefreshTokenRotated: %v", err)
+	}
+
+	// 超过宽限期再次使用已轮换的令牌，整个会话被注销
+	db.Model(&models.RefreshToken{}).Where("rotated_at IS NOT NULL").Update("rotated_at", time.Now().Add(-time.Minute))
+	if _, _, err := sessions.Refresh(ctx, refresh1, "access-y", "10.0.0.2"); !errors.Is(err, auth.ErrRefreshTokenReused) {
+		t.Fatalf("超过宽限期重复使用应返回 ErrRefreshTokenReused: %v", err)
+	}
+	if _, err := sessions.Validate(ctx, "access-2", "10.0.0.1"); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("检测到重复使用后会话应被注销: %v", err)
+	}
+	if _, _, err := sessions.Refresh(ctx, refresh2, "access-z", "10.0.0.1"); !errors.Is(err, auth.ErrRefreshTokenInvalid) {
+		t.Errorf("会话注销后最新的刷新令牌也应失效: %v", err)
+	}
+}
+
+// TestSessionAbsoluteExpiry 测试空闲超时、"记住我"与绝对有效期
+func TestSessionAbsoluteExpiry(t *testing.T) {
+	ctx := context.Background()
+	sessions, db := setupSessionManager(t)
+
+	session, refresh, err := sessions.Create(ctx, "user001", "access-1", "10.0.0.1", "", false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if d := time.Until(time.Time(session.ExpiresAt)); d <= time.Hour || d > 2*time.Hour {
+		t.Errorf("普通会话应按空闲超时过期, 剩余 %v", d)
+	}
+	if d := time.Until(time.Time(session.AbsoluteExpiresAt)); d <= 23*time.Hour || d > 24*time.Hour {
+		t.Errorf("普通会话绝对有效期应为 24 小时, 剩余 %v", d)
+	}
+
+	// 接近绝对有效期时，刷新不能把会话延长到绝对有效期之后
+	absolute := time.Now().Add(30 * time.Minute)
+	db.Model(&models.UserSession{}).Where("id = ?", session.ID).Update("absolute_expires_at", absolute)
+	refreshed, _, err := sessions.Refresh(ctx, refresh, "access-2", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if time.Time(refreshed.ExpiresAt).After(absolute.Add(time.Second)) {
+		t.Errorf("刷新后的过期时间 %v 不应超过绝对有效期 %v", time.Time(refreshed.ExpiresAt), absolute)
+	}
+
+	remembered, _, err := sessions.Create(ctx, "user001", "access-3", "10.0.0.1", "", true)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !remembered.RememberMe || time.Until(time.Time(remembered.ExpiresAt)) <= 29*24*time.Hour {
+		t.Errorf("记住我会话应在 30 天后过期, 实际 %v", time.Time(remembered.ExpiresAt))
+	}
+}
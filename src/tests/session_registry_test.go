@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"myobj/src/pkg/auth"
+	"testing"
+)
+
+// TestSessionParseDevice 测试根据 User-Agent 识别设备
+func TestSessionParseDevice(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Edg/120.0":                   "Edge / Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1": "Safari / iOS",
+		"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Mobile Safari/537.36":                                "Chrome / Android",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.0; rv:121.0) Gecko/20100101 Firefox/121.0":                                                     "Firefox / macOS",
+		"curl/8.0": "curl",
+		"":         "未知设备",
+	}
+	for ua, want := range cases {
+		if got := auth.ParseDevice(ua); got != want {
+			t.Errorf("ParseDevice(%q) = %q, 应为 %q", ua, got, want)
+		}
+	}
+}
+
+// TestSessionRevoke 测试会话列表、注销指定会话与注销其他全部会话
+func TestSessionRevoke(t *testing.T) {
+	ctx := context.Background()
+	sessions, _ := setupSessionManager(t)
+
+	current, _, err := sessions.Create(ctx, "user001", "access-1", "10.0.0.1", "curl/8.0", false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	phone, phoneRefresh, err := sessions.Create(ctx, "user001", "access-2", "10.0.0.2", "", false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, _, err := sessions.Create(ctx, "user001", "access-3", "10.0.0.3", "", true); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	other, _, err := sessions.Create(ctx, "user002", "access-4", "10.0.0.4", "", false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	list, err := sessions.List(ctx, "user001")
+	if err != nil || len(list) != 3 {
+		t.Fatalf("user001 应有 3 个会话, 实际 %d: %v", len(list), err)
+	}
+	for _, s := range list {
+		if s.ID == current.ID && s.Device != "curl" {
+			t.Errorf("会话设备应为 curl, 实际为 %q", s.Device)
+		}
+	}
+
+	// 只能注销自己的会话
+	if err := sessions.Revoke(ctx, "user001", other.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("注销其他用户的会话应返回会话不存在: %v", err)
+	}
+	if _, err := sessions.Validate(ctx, "access-4", "10.0.0.4"); err != nil {
+		t.Errorf("其他用户的会话不应被注销: %v", err)
+	}
+
+	// 注销后访问令牌与刷新令牌立即失效
+	if err := sessions.Revoke(ctx, "user001", phone.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := sessions.Validate(ctx, "access-2", "10.0.0.2"); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("已注销会话的访问令牌应失效: %v", err)
+	}
+	if _, _, err := sessions.Refresh(ctx, phoneRefresh, "access-5", "10.0.0.2"); !errors.Is(err, auth.ErrRefreshTokenInvalid) {
+		t.Errorf("已注销会话的刷新令牌应失效: %v", err)
+	}
+	if err := sessions.Revoke(ctx, "user001", phone.ID); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("重复注销应返回会话不存在: %v", err)
+	}
+
+	// 注销其他全部会话时保留当前会话
+	count, err := sessions.RevokeAll(ctx, "user001", current.ID)
+	if err != nil || count != 1 {
+		t.Fatalf("应注销 1 个会话, 实际 %d: %v", count, err)
+	}
+	if _, err := sessions.Validate(ctx, "access-1", "10.0.0.1"); err != nil {
+		t.Errorf("当前会话应保留: %v", err)
+	}
+	if _, err := sessions.Validate(ctx, "access-3", "10.0.0.3"); !errors.Is(err, auth.ErrSessionRevoked) {
+		t.Errorf("其他会话应被注销: %v", err)
+	}
+	if list, _ := sessions.List(ctx, "user001"); len(list) != 1 || list[0].ID != current.ID {
+		t.Errorf("注销后只应保留当前会话: %d", len(list))
+	}
+}
@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/sftp"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	pkgsftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// startSFTPServer 在 WebDAV 测试数据的基础上启动 SFTP 服务（用户 alice 拥有 sftp:access 权限）
+// 返回服务、Serve 的返回值通道与使用指定密码建立 SSH 连接的函数
+func startSFTPServer(t *testing.T) (*sftp.Server, <-chan error, func(password string) (*ssh.Client, error), *impl.RepositoryFactory) {
+	_, factory, _ := setupWebDAVServer(t)
+	if err := factory.DB().Model(&models.Power{}).Where("id = ?", 1).Update("characteristic", "sftp:access").Error; err != nil {
+		t.Fatalf("更新权限失败: %v", err)
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成主机密钥失败: %v", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("生成主机密钥失败: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	server := sftp.NewServer(factory, cache.NewLocalCache())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener, hostKey) }()
+	t.Cleanup(func() { server.Stop() })
+
+	addr := listener.Addr().String()
+	dial := func(password string) (*ssh.Client, error) {
+		return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+			User:            "alice",
+			Auth:            []ssh.AuthMethod{ssh.Password(password)},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			Timeout:         5 * time.Second,
+		})
+	}
+	return server, done, dial, factory
+}
+
+// TestSFTPServer 测试 SFTP 认证、子系统请求、文件读写与停止服务
+func TestSFTPServer(t *testing.T) {
+	server, done, dial, _ := startSFTPServer(t)
+	if _, err := dial("wrong"); err == nil {
+		t.Error("密码错误时应认证失败")
+	}
+	client, err := dial(davPassword)
+	if err != nil {
+		t.Fatalf("SSH 连接失败: %v", err)
+	}
+	defer client.Close()
+
+	// 只接受 sftp 子系统，shell 与其他子系统请求被拒绝
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if err := session.Shell(); err == nil {
+		t.Error("shell 请求应被拒绝")
+	}
+	if err := session.RequestSubsystem("other"); err == nil {
+		t.Error("非 sftp 子系统请求应被拒绝")
+	}
+	session.Close()
+
+	sftpClient, err := pkgsftp.NewClient(client)
+	if err != nil {
+		t.Fatalf("SFTP 会话失败: %v", err)
+	}
+	defer sftpClient.Close()
+	entries, err := sftpClient.ReadDir("/docs")
+	if err != nil {
+		t.Fatalf("列出目录失败: %v", err)
+	}
+	names := map[string]bool{}
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+	if !names["a.txt"] || !names["sub"] {
+		t.Errorf("目录内容错误: %v", names)
+	}
+
+	f, err := sftpClient.Create("/hello.txt")
+	if err != nil {
+		t.Fatalf("创建文件失败: %v", err)
+	}
+	if _, err := f.Write([]byte("hello sftp")); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("关闭文件失败: %v", err)
+	}
+	r, err := sftpClient.Open("/hello.txt")
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != "hello sftp" {
+		t.Errorf("读取内容错误: %q, %v", data, err)
+	}
+
+	// POSIX 重命名：移动失败时目标文件保持不变，成功时覆盖目标文件
+	if err := sftpClient.PosixRename("/missing.txt", "/hello.txt"); err == nil {
+		t.Error("源文件不存在时重命名应失败")
+	}
+	if _, err := sftpClient.Stat("/hello.txt"); err != nil {
+		t.Errorf("重命名失败后目标文件应保留: %v", err)
+	}
+	if err := sftpClient.PosixRename("/docs/a.txt", "/hello.txt"); err != nil {
+		t.Fatalf("POSIX 重命名失败: %v", err)
+	}
+	if _, err := sftpClient.Stat("/docs/a.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("重命名后源文件应不存在: %v", err)
+	}
+	if info, err := sftpClient.Stat("/hello.txt"); err != nil || info.Size() == int64(len("hello sftp")) {
+		t.Errorf("目标文件应被覆盖: %v", err)
+	}
+
+	// 停止后 Serve 正常返回，不再接受新连接
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("停止后 Serve 应返回 nil: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("停止后 Serve 未返回")
+	}
+	if _, err := dial(davPassword); err == nil {
+		t.Error("停止后不应接受新连接")
+	}
+}
+
+// TestSFTPLocked 测试被锁定的文件与目录不能通过 SFTP 写入、重命名或删除
+func TestSFTPLocked(t *testing.T) {
+	_, _, dial, factory := startSFTPServer(t)
+	if _, err := lock.NewManager(factory).Create(context.Background(), recycleUser, lock.Request{Root: "/docs"}); err != nil {
+		t.Fatalf("创建锁失败: %v", err)
+	}
+	client, err := dial(davPassword)
+	if err != nil {
+		t.Fatalf("SSH 连接失败: %v", err)
+	}
+	defer client.Close()
+	sftpClient, err := pkgsftp.NewClient(client)
+	if err != nil {
+		t.Fatalf("SFTP 会话失败: %v", err)
+	}
+	defer sftpClient.Close()
+
+	for _, name := range []string{"/docs/a.txt", "/docs/new.txt"} {
+		if _, err := sftpClient.Create(name); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("写入被锁定目录中的 %s 应返回权限不足: %v", name, err)
+		}
+	}
+	if err := sftpClient.Rename("/docs/a.txt", "/a.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("重命名被锁定的文件应返回权限不足: %v", err)
+	}
+	if err := sftpClient.PosixRename("/docs/a.txt", "/a.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("POSIX 重命名被锁定的文件应返回权限不足: %v", err)
+	}
+	if err := sftpClient.Remove("/docs/a.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("删除被锁定的文件应返回权限不足: %v", err)
+	}
+	if err := sftpClient.Mkdir("/docs/new-dir"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("在被锁定的目录中建目录应返回权限不足: %v", err)
+	}
+	if _, err := sftpClient.Stat("/docs/a.txt"); err != nil {
+		t.Errorf("被锁定的文件应保持不变: %v", err)
+	}
+
+	// 未被锁定的路径不受影响
+	f, err := sftpClient.Create("/other.txt")
+	if err != nil {
+		t.Fatalf("创建未锁定的文件失败: %v", err)
+	}
+	if _, err := f.Write([]byte("hi")); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("未锁定的文件应上传成功: %v", err)
+	}
+}
+
+// TestSFTPEncryptedDir 测试写入开启了写入加密的目录时直接返回权限不足
+func TestSFTPEncryptedDir(t *testing.T) {
+	_, _, dial, factory := startSFTPServer(t)
+	if err := factory.EncryptPolicy().Create(context.Background(), &models.EncryptPolicy{UserID: recycleUser, CreatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建加密策略失败: %v", err)
+	}
+	client, err := dial(davPassword)
+	if err != nil {
+		t.Fatalf("SSH 连接失败: %v", err)
+	}
+	defer client.Close()
+	sftpClient, err := pkgsftp.NewClient(client)
+	if err != nil {
+		t.Fatalf("SFTP 会话失败: %v", err)
+	}
+	defer sftpClient.Close()
+
+	if _, err := sftpClient.Create("/docs/secret.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("写入加密目录应返回权限不足: %v", err)
+	}
+	if _, err := sftpClient.ReadDir("/docs"); err != nil {
+		t.Errorf("浏览加密目录不受影响: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/auth"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA-1 测试向量的密钥 "12345678901234567890"（Base32 编码）
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestTOTPCodeRFC6238 测试验证码与 RFC 6238 SHA-1 测试向量一致（取 8 位结果的后 6 位）
+func TestTOTPCodeRFC6238(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		code, err := util.TOTPCode(rfc6238Secret, util.TOTPStep(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode failed: %v", err)
+		}
+		if code != v.code {
+			t.Errorf("T=%d 的验证码应为 %s，实际为 %s", v.unix, v.code, code)
+		}
+	}
+	if _, err := util.TOTPCode("not base32!", 1); err == nil {
+		t.Error("无效的密钥应返回错误")
+	}
+}
+
+// TestVerifyTOTPSkew 测试前后各一个周期的时间偏差
+func TestVerifyTOTPSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := util.TOTPStep(now)
+	for offset := int64(-2); offset <= 2; offset++ {
+		code, _ := util.TOTPCode(rfc6238Secret, current+offset)
+		step, ok := util.VerifyTOTP(rfc6238Secret, code, now)
+		if want := offset >= -1 && offset <= 1; ok != want {
+			t.Errorf("偏差 %d 个周期的验证结果应为 %t", offset, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("应返回命中的时间步 %d，实际为 %d", current+offset, step)
+		}
+	}
+	if _, ok := util.VerifyTOTP(rfc6238Secret, "12345", now); ok {
+		t.Error("位数不正确的验证码应校验失败")
+	}
+}
+
+// setupTwoFactor 创建内存数据库中的两步验证管理器
+func setupTwoFactor(t *testing.T) (*auth.TwoFactorManager, *gorm.DB) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.UserTwoFactor{}, &models.Group{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return auth.NewTwoFactorManager(impl.NewTwoFactorRepository(db), impl.NewGroupRepository(db)), db
+}
+
+// TestTwoFactorReplayAndRecovery 测试验证码不能重复使用、恢复码只能使用一次
+func TestTwoFactorReplayAndRecovery(t *testing.T) {
+	ctx := context.Background()
+	manager, db := setupTwoFactor(t)
+	user := &models.UserInfo{ID: "user001", UserName: "alice"}
+
+	secret, uri, err := manager.Setup(ctx, user)
+	if err != nil || secret == "" || uri == "" {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	// 避免测试过程中跨越时间步
+	if time.Now().Unix()%util.TOTPPeriod >= util.TOTPPeriod-2 {
+		time.Sleep(3 * time.Second)
+	}
+	current := util.TOTPStep(time.Now())
+	code, _ := util.TOTPCode(secret, current-1)
+	recoveryCodes, err := manager.Enable(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+
+	// 启用时使用过的验证码不能再用于登录
+	if _, err := manager.Verify(ctx, user.ID, code); !errors.Is(err, auth.ErrTwoFactorCodeInvalid) {
+		t.Errorf("启用时使用过的验证码应被拒绝: %v", err)
+	}
+	code, _ = util.TOTPCode(secret, current)
+	if recovery, err := manager.Verify(ctx, user.ID, code); err != nil || recovery {
+		t.Fatalf("新时间步的验证码应通过: %v", err)
+	}
+	if _, err := manager.Verify(ctx, user.ID, code); !errors.Is(err, auth.ErrTwoFactorCodeInvalid) {
+		t.Errorf("同一验证码重复使用应被拒绝: %v", err)
+	}
+	// 已使用过更新的时间步后，更早时间步的验证码即使仍在偏差范围内也应被拒绝
+	code, _ = util.TOTPCode(secret, current-1)
+	if _, err := manager.Verify(ctx, user.ID, code); !errors.Is(err, auth.ErrTwoFactorCodeInvalid) {
+		t.Errorf("更早时间步的验证码应被拒绝: %v", err)
+	}
+	var tf models.UserTwoFactor
+	db.Where("user_id = ?", user.ID).First(&tf)
+	if tf.LastStep != current {
+		t.Errorf("LastStep 应为 %d，实际为 %d", current, tf.LastStep)
+	}
+
+	// 恢复码忽略大小写与分隔符，使用一次后作废
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("应生成 10 个恢复码，实际为 %d", len(recoveryCodes))
+	}
+	if recovery, err := manager.Verify(ctx, user.ID, " "+recoveryCodes[0]+" "); err != nil || !recovery {
+		t.Fatalf("恢复码应通过: %v", err)
+	}
+	if _, err := manager.Verify(ctx, user.ID, recoveryCodes[0]); !errors.Is(err, auth.ErrTwoFactorCodeInvalid) {
+		t.Errorf("已使用的恢复码应被拒绝: %v", err)
+	}
+	if _, _, remaining, _ := manager.Status(ctx, user.ID); remaining != 9 {
+		t.Errorf("剩余恢复码应为 9 个，实际为 %d", remaining)
+	}
+}
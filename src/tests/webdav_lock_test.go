@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/lock"
+	"myobj/src/pkg/logger"
+	"myobj/src/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLockManager 创建内存数据库中的锁管理器（创建锁时需要锁定用户记录）
+func setupLockManager(t *testing.T) (*lock.Manager, *gorm.DB) {
+	logger.LOG = slog.New(slog.NewTextHandler(io.Discard, nil))
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.UserInfo{}, &models.WebDAVLock{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	for _, id := range []string{"user001", "user002"} {
+		if err := db.Create(&models.UserInfo{ID: id, UserName: id, CreatedAt: custom_type.Now()}).Error; err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	return lock.NewManager(impl.NewRepositoryFactory(db)), db
+}
+
+// TestLockConflicts 测试独占锁、共享锁与锁定深度的冲突规则
+func TestLockConflicts(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := setupLockManager(t)
+
+	dir, err := manager.Create(ctx, "user001", lock.Request{Root: "/docs"})
+	if err != nil {
+		t.Fatalf("创建目录锁失败: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/docs/a.txt"}); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("子树已被独占锁定时应冲突: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/", ZeroDepth: true}); err != nil {
+		t.Errorf("只锁定根目录本身不应与子目录的锁冲突: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/"}); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("锁定整个根目录应与子目录的锁冲突: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/docs2"}); err != nil {
+		t.Errorf("前缀相同的同级目录不应冲突: %v", err)
+	}
+	// 锁只作用于持有者自己的命名空间
+	if _, err := manager.Create(ctx, "user002", lock.Request{Root: "/docs"}); err != nil {
+		t.Errorf("其他用户的同名路径不应冲突: %v", err)
+	}
+
+	if err := manager.Unlock(ctx, "user002", dir.Token); !errors.Is(err, lock.ErrNoSuchLock) {
+		t.Errorf("不能释放其他用户的锁: %v", err)
+	}
+	if err := manager.Unlock(ctx, "user001", dir.Token); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/docs/a.txt"}); err != nil {
+		t.Errorf("锁释放后应能重新锁定: %v", err)
+	}
+}
+
+// TestLockShared 测试共享锁之间不冲突、与独占锁冲突
+func TestLockShared(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := setupLockManager(t)
+
+	first, err := manager.Create(ctx, "user001", lock.Request{Root: "/a.txt", Shared: true})
+	if err != nil {
+		t.Fatalf("创建共享锁失败: %v", err)
+	}
+	second, err := manager.Create(ctx, "user001", lock.Request{Root: "/a.txt", Shared: true})
+	if err != nil {
+		t.Fatalf("共享锁之间不应冲突: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/a.txt"}); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("已有共享锁时不能创建独占锁: %v", err)
+	}
+
+	// 共享锁只需提供其中任一令牌
+	if err := manager.Confirm(ctx, "user001", []string{"/a.txt"}, nil); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("未提供令牌时应拒绝写入: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user001", []string{"/a.txt"}, []string{second.Token}); err != nil {
+		t.Errorf("提供任一共享锁令牌时应允许写入: %v", err)
+	}
+	if err := manager.Unlock(ctx, "user001", first.Token); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if err := manager.Unlock(ctx, "user001", second.Token); err != nil {
+		t.Fatalf("释放锁失败: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/a.txt"}); err != nil {
+		t.Errorf("共享锁全部释放后应能创建独占锁: %v", err)
+	}
+}
+
+// TestLockConfirm 测试 If 头中的锁令牌校验
+func TestLockConfirm(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := setupLockManager(t)
+
+	dir, err := manager.Create(ctx, "user001", lock.Request{Root: "/docs"})
+	if err != nil {
+		t.Fatalf("创建锁失败: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user001", []string{"/docs/a.txt"}, nil); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("修改被锁定目录下的文件时应要求令牌: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user001", []string{"/docs/a.txt"}, []string{dir.Token}); err != nil {
+		t.Errorf("提供目录锁令牌时应允许修改: %v", err)
+	}
+	// 移动到被锁定的目录时，源与目标都需要满足
+	if err := manager.Confirm(ctx, "user001", []string{"/b.txt", "/docs/b.txt"}, nil); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("目标位于被锁定的目录时应拒绝: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user001", []string{"/"}, nil); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("删除上级目录时应检查子树中的锁: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user001", []string{"/other.txt"}, nil); err != nil {
+		t.Errorf("未被锁定的资源不需要令牌: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user001", []string{"/other.txt"}, []string{lock.TokenPrefix + "unknown"}); !errors.Is(err, lock.ErrNoSuchLock) {
+		t.Errorf("携带无效令牌时应返回锁不存在: %v", err)
+	}
+	if err := manager.Confirm(ctx, "user002", []string{"/docs/a.txt"}, []string{dir.Token}); !errors.Is(err, lock.ErrNoSuchLock) {
+		t.Errorf("其他用户的令牌无效: %v", err)
+	}
+}
+
+// TestLockExpiry 测试过期的锁不再生效、刷新与清理
+func TestLockExpiry(t *testing.T) {
+	ctx := context.Background()
+	manager, db := setupLockManager(t)
+
+	l, err := manager.Create(ctx, "user001", lock.Request{Root: "/a.txt", Duration: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("创建锁失败: %v", err)
+	}
+	if l.Timeout != int64(lock.MaxTimeout/time.Second) {
+		t.Errorf("超时时间应限制为 %v，实际为 %d 秒", lock.MaxTimeout, l.Timeout)
+	}
+	if l, err = manager.Refresh(ctx, "user001", l.Token, 0); err != nil || l.Timeout != int64(lock.DefaultTimeout/time.Second) {
+		t.Fatalf("刷新锁失败: %v", err)
+	}
+
+	past := custom_type.JsonTime(time.Now().Add(-time.Minute))
+	if err := db.Model(&models.WebDAVLock{}).Where("token = ?", l.Token).Update("expires_at", past).Error; err != nil {
+		t.Fatalf("更新过期时间失败: %v", err)
+	}
+	if err := manager.CheckUnlocked(ctx, "user001", "/a.txt"); err != nil {
+		t.Errorf("过期的锁不应生效: %v", err)
+	}
+	if _, err := manager.Refresh(ctx, "user001", l.Token, time.Hour); !errors.Is(err, lock.ErrNoSuchLock) {
+		t.Errorf("过期的锁不能刷新: %v", err)
+	}
+	if _, err := manager.Create(ctx, "user001", lock.Request{Root: "/a.txt"}); err != nil {
+		t.Errorf("锁过期后应能重新锁定: %v", err)
+	}
+	if n, err := manager.CleanupExpired(ctx); err != nil || n != 1 {
+		t.Errorf("应清理 1 个过期的锁，实际为 %d: %v", n, err)
+	}
+}
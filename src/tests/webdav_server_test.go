@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"context"
+	"myobj/src/config"
+	"myobj/src/internal/repository/impl"
+	"myobj/src/pkg/cache"
+	"myobj/src/pkg/custom_type"
+	"myobj/src/pkg/models"
+	"myobj/src/pkg/util"
+	"myobj/src/pkg/webdav"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// davPassword 测试用户 user001（alice）的应用专用密码
+const davPassword = "app-password-0001"
+
+// setupWebDAVServer 在回收站测试数据的基础上创建 WebDAV 服务：用户 alice 拥有 webdav:access 权限，使用 davPassword 认证
+func setupWebDAVServer(t *testing.T) (*webdav.Server, *impl.RepositoryFactory, map[string]*models.VirtualPath) {
+	factory, dirs := setupRecycleDB(t)
+	old := config.CONFIG
+	t.Cleanup(func() { config.CONFIG = old })
+	config.CONFIG = &config.MyObjConfig{File: config.File{BigFileThreshold: 1, BigChunkSize: 1}}
+	if err := factory.DB().AutoMigrate(&models.UserInfo{}, &models.Group{}, &models.Power{}, &models.GroupPower{},
+		&models.AppPassword{}, &models.ApiKey{}, &models.SysConfig{}, &models.UserTwoFactor{}, &models.UserSession{},
+		&models.SecurityEvent{}, &models.AuditLog{}, &models.Disk{}, &models.FileChunk{}, &models.EncryptPolicy{},
+		&models.WebDAVLock{}, &models.WebDAVProperty{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+	db := factory.DB()
+	if err := db.Create(&models.UserInfo{ID: recycleUser, UserName: "alice", GroupID: 1, CreatedAt: custom_type.Now()}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	if err := db.Create(&models.Group{ID: 1, Name: "users", CreatedAt: custom_type.Now()}).Error; err != nil {
+		t.Fatalf("创建用户组失败: %v", err)
+	}
+	if err := db.Create(&models.Power{ID: 1, Name: "WebDAV", Description: "WebDAV", Characteristic: "webdav:access", CreatedAt: custom_type.Now()}).Error; err != nil {
+		t.Fatalf("创建权限失败: %v", err)
+	}
+	if err := db.Create(&models.GroupPower{GroupID: 1, PowerID: 1}).Error; err != nil {
+		t.Fatalf("创建组权限失败: %v", err)
+	}
+	if err := factory.AppPassword().Create(ctx, &models.AppPassword{UserID: recycleUser, Name: "sync",
+		PasswordHash: util.HashAppPassword(davPassword), CreatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建应用专用密码失败: %v", err)
+	}
+	dataPath := t.TempDir()
+	if err := factory.Disk().Create(ctx, &models.Disk{ID: "d1", Size: 10, DiskPath: dataPath, DataPath: dataPath}); err != nil {
+		t.Fatalf("创建磁盘失败: %v", err)
+	}
+	return webdav.NewServer(factory, cache.NewLocalCache()), factory, dirs
+}
+
+// davRequest 使用指定密码发送 WebDAV 请求（路径不含 /dav 前缀），contentLength 为 -1 时按分块传输发送
+func davRequest(server *webdav.Server, method, target, password, body string, contentLength int64, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.ContentLength = contentLength
+	req.SetBasicAuth("alice", password)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestWebDAVQuotaAndEncryptionStatus 测试超出配额返回 507，写入加密目录缺少或提供错误的文件密码返回 403
+func TestWebDAVQuotaAndEncryptionStatus(t *testing.T) {
+	ctx := context.Background()
+	server, factory, dirs := setupWebDAVServer(t)
+	db := factory.DB()
+	if err := db.Model(&models.UserInfo{}).Where("id = ?", recycleUser).
+		Updates(map[string]interface{}{"space": 100, "free_space": 10}).Error; err != nil {
+		t.Fatalf("设置配额失败: %v", err)
+	}
+
+	body := strings.Repeat("x", 20)
+	if rec := davRequest(server, http.MethodPut, "/big.txt", davPassword, body, int64(len(body)), nil); rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("声明的大小超出配额时应返回 507，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, http.MethodPut, "/stream.txt", davPassword, body, -1, nil); rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("写入过程中超出配额时应返回 507，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, http.MethodPut, "/small.txt", davPassword, "hello", 5, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("配额内的上传应成功，实际为 %d: %s", rec.Code, rec.Body.String())
+	}
+	var user models.UserInfo
+	db.Where("id = ?", recycleUser).First(&user)
+	if user.FreeSpace != 5 {
+		t.Errorf("上传后剩余空间应为 5，实际为 %d", user.FreeSpace)
+	}
+	if rec := davRequest(server, http.MethodGet, "/big.txt", davPassword, "", 0, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("超出配额的上传不应入库，实际为 %d", rec.Code)
+	}
+
+	// /docs 命中写入加密策略
+	if err := factory.EncryptPolicy().Create(ctx, &models.EncryptPolicy{UserID: recycleUser, DirID: dirs["docs"].ID, CreatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建加密策略失败: %v", err)
+	}
+	if rec := davRequest(server, http.MethodPut, "/docs/secret.txt", davPassword, "hi", 2, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("写入加密目录未提供文件密码时应返回 403，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, http.MethodPut, "/docs/sub/secret.txt", davPassword, "hi", 2,
+		map[string]string{webdav.FilePasswordHeader: "wrong"}); rec.Code != http.StatusForbidden {
+		t.Errorf("写入加密目录的子目录且文件密码错误时应返回 403，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, http.MethodPut, "/plain.txt", davPassword, "hi", 2, nil); rec.Code != http.StatusCreated {
+		t.Errorf("未命中加密策略的目录不需要文件密码，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, http.MethodPut, "/docs/a.txt", "wrong", "hi", 2, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("认证失败应返回 401，实际为 %d", rec.Code)
+	}
+}
+
+// TestWebDAVAppPasswordScope 测试应用专用密码的访问目录与只读限制，以及开启两步验证后只接受应用专用密码
+func TestWebDAVAppPasswordScope(t *testing.T) {
+	ctx := context.Background()
+	server, factory, dirs := setupWebDAVServer(t)
+	for _, p := range []*models.AppPassword{
+		{UserID: recycleUser, Name: "scoped", PasswordHash: util.HashAppPassword("scoped-password"), RootDirID: dirs["docs"].ID},
+		{UserID: recycleUser, Name: "readonly", PasswordHash: util.HashAppPassword("readonly-password"), ReadOnly: true},
+	} {
+		p.CreatedAt = custom_type.Now()
+		if err := factory.AppPassword().Create(ctx, p); err != nil {
+			t.Fatalf("创建应用专用密码失败: %v", err)
+		}
+	}
+	propfind := map[string]string{"Depth": "1"}
+
+	// 限制目录的密码只能看到 /docs 下的内容，根目录即为 /docs
+	rec := davRequest(server, "PROPFIND", "/", "scoped-password", "", 0, propfind)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND 应返回 207，实际为 %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "a.txt") || !strings.Contains(body, "/dav/sub/") || strings.Contains(body, "docs") {
+		t.Errorf("访问范围应为 /docs: %s", body)
+	}
+	if rec := davRequest(server, "PROPFIND", "/../sub/", "scoped-password", "", 0, propfind); rec.Code != http.StatusMultiStatus {
+		t.Errorf("上级路径应被限制在访问范围内，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, http.MethodPut, "/new.txt", "scoped-password", "hi", 2, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("访问范围内的上传应成功，实际为 %d", rec.Code)
+	}
+	files, err := factory.UserFiles().ListByVirtualPath(ctx, recycleUser, strconv.Itoa(dirs["docs"].ID), 0, 10)
+	if err != nil || len(files) != 2 {
+		t.Errorf("文件应写入 /docs，实际 %d 个文件: %v", len(files), err)
+	}
+	if rec := davRequest(server, http.MethodDelete, "/", "scoped-password", "", 0, nil); rec.Code < 400 {
+		t.Errorf("不能删除访问范围的根目录，实际为 %d", rec.Code)
+	}
+
+	// 只读密码可以读取，任何写操作返回 403
+	if rec := davRequest(server, "PROPFIND", "/docs/", "readonly-password", "", 0, propfind); rec.Code != http.StatusMultiStatus {
+		t.Errorf("只读密码应能列出目录，实际为 %d", rec.Code)
+	}
+	for method, target := range map[string]string{http.MethodPut: "/docs/new.txt", http.MethodDelete: "/docs/a.txt", "MKCOL": "/docs/new-dir", "LOCK": "/docs/a.txt"} {
+		if rec := davRequest(server, method, target, "readonly-password", "", 0, nil); rec.Code != http.StatusForbidden {
+			t.Errorf("只读密码的 %s 请求应返回 403，实际为 %d", method, rec.Code)
+		}
+	}
+
+	// 开启两步验证后 API Key 不能用于同步协议，应用专用密码仍然可用
+	if err := factory.ApiKey().Create(ctx, &models.ApiKey{ID: 1, UserID: recycleUser, Key: "api-key-0001", CreatedAt: custom_type.Now()}); err != nil {
+		t.Fatalf("创建 API Key 失败: %v", err)
+	}
+	if rec := davRequest(server, "PROPFIND", "/", "api-key-0001", "", 0, propfind); rec.Code != http.StatusMultiStatus {
+		t.Fatalf("未开启两步验证时 API Key 应可用，实际为 %d", rec.Code)
+	}
+	if err := factory.DB().Create(&models.UserTwoFactor{UserID: recycleUser, Secret: "SECRET", Enabled: true}).Error; err != nil {
+		t.Fatalf("开启两步验证失败: %v", err)
+	}
+	if rec := davRequest(server, "PROPFIND", "/", "api-key-0001", "", 0, propfind); rec.Code != http.StatusUnauthorized {
+		t.Errorf("开启两步验证后 API Key 应被拒绝，实际为 %d", rec.Code)
+	}
+	if rec := davRequest(server, "PROPFIND", "/", davPassword, "", 0, propfind); rec.Code != http.StatusMultiStatus {
+		t.Errorf("开启两步验证后应用专用密码应可用，实际为 %d", rec.Code)
+	}
+}